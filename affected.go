@@ -0,0 +1,106 @@
+package gomu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// changedModules returns the directories of modules in the git repo at dir with files changed in commitRange,
+// e.g. origin/master...HEAD, or since a single rev including uncommitted changes
+func changedModules(dir, commitRange string) (modules sort.StringArray, err error) {
+	repo := com.FileWrapper{Path: dir}
+	top, err := repo.CmdOutput("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		err = fmt.Errorf("%s isn't a git repository", dir)
+		return
+	}
+	top = filepath.Clean(top)
+
+	output, err := repo.CmdOutput("git", "diff", "--name-only", commitRange)
+	if err != nil {
+		err = fmt.Errorf("unable to diff %s: %v", commitRange, err)
+		return
+	}
+
+	found := make(map[string]bool)
+	for _, name := range strings.Split(output, "\n") {
+		if len(name) == 0 {
+			continue
+		}
+
+		// Changed files belong to the nearest module above them, deleted directories included
+		for moduleDir := filepath.Dir(filepath.Join(top, filepath.FromSlash(name))); ; moduleDir = filepath.Dir(moduleDir) {
+			if _, statErr := os.Stat(filepath.Join(moduleDir, "go.mod")); statErr == nil {
+				if !found[moduleDir] {
+					found[moduleDir] = true
+					modules = append(modules, moduleDir)
+				}
+				break
+			}
+
+			if moduleDir == top || moduleDir == filepath.Dir(moduleDir) {
+				// Not within a module
+				break
+			}
+		}
+	}
+
+	return
+}
+
+// resolveChanged adds modules changed in mu.Options.ChangedRange to the sync libs whose dependents are affected.
+// Returns false if there's nothing to act on
+func (mu *MU) resolveChanged() (ok bool) {
+	if len(mu.Options.ChangedRange) > 0 {
+		dir := mu.Options.ChangedRepo
+		if len(dir) == 0 {
+			dir = "."
+		}
+
+		modules, err := changedModules(dir, mu.Options.ChangedRange)
+		if err != nil {
+			mu.configError(err)
+			return
+		}
+
+		if len(modules) == 0 && len(mu.Options.FilterDependencies) == 0 {
+			com.Println("\nNo modules changed in", mu.Options.ChangedRange)
+			return
+		}
+
+		mu.Options.FilterDependencies = append(mu.Options.FilterDependencies, modules...)
+	}
+
+	if len(mu.Options.FilterDependencies) == 0 {
+		mu.configError(fmt.Errorf("no changed modules or commit range provided"))
+		return
+	}
+
+	for _, module := range mu.Options.FilterDependencies {
+		// Versions don't matter, only which libs changed
+		dep := &com.FileWrapper{Path: strings.Split(module, "@")[0]}
+		mu.changed = append(mu.changed, dep)
+		com.Println("Changed:", dep.GetGoURL())
+	}
+
+	ok = true
+	return
+}
+
+// affected lists lib as affected by the changed modules, then tests it if requested
+func (mu *MU) affected(lib Library, fileHead *sort.FileNode) {
+	line := lib.File.GetGoURL()
+	if lib.File.MatchesAny(mu.changed) {
+		line += " (changed)"
+	}
+	mu.addStat(&mu.Stats.AffectedCount, &mu.Stats.AffectedOutput, line+"\n")
+
+	if mu.Options.AffectedTest {
+		mu.test(lib, fileHead)
+	}
+}
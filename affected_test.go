@@ -0,0 +1,69 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestChangedModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-affected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a/go.mod", "a/pkg/util.go", "b/go.mod"} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &com.Recorder{}
+	recorder.Reply("git rev-parse --show-toplevel", com.Reply{Stdout: dir + "\n"})
+	// The removed directory of a belongs to it, README.md isn't within a module
+	recorder.Reply("git diff --name-only origin/master...HEAD", com.Reply{Stdout: "a/pkg/util.go\na/removed/old.go\nb/go.mod\nREADME.md\n"})
+	com.SetRunner(recorder)
+	defer com.SetRunner(nil)
+
+	modules, err := changedModules(dir, "origin/master...HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (sort.StringArray{filepath.Join(dir, "a"), filepath.Join(dir, "b")}); !reflect.DeepEqual(modules, expected) {
+		t.Errorf("expected %v, got %v", expected, modules)
+	}
+}
+
+func TestResolveChangedRequiresModules(t *testing.T) {
+	mu := &MU{Options: Options{Action: "affected"}}
+	if mu.resolveChanged() || mu.ExitCode() != ExitConfigError {
+		t.Errorf("expected a config error without changed modules, got %v", mu.Errors)
+	}
+}
+
+func TestAffected(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	dependent, _ := recordedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "affected", FilterDependencies: sort.StringArray{lib.File.Path + "@v1.2.0"}}}
+	if !mu.resolveChanged() {
+		t.Fatalf("expected changed module to be resolved, got %v", mu.Errors)
+	}
+	mu.affected(*lib, nil)
+	mu.affected(*dependent, nil)
+
+	expected := "1) " + lib.File.GetGoURL() + " (changed)\n2) " + dependent.File.GetGoURL() + "\n"
+	if mu.Stats.AffectedCount != 2 || mu.Stats.AffectedOutput != expected {
+		t.Errorf("expected %q, got %q", expected, mu.Stats.AffectedOutput)
+	}
+}
@@ -0,0 +1,146 @@
+package gomu
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// SyncResult represents the outcome of syncing a library's mod files
+type SyncResult struct {
+	// Updated is true if mod files were refreshed and pushed
+	Updated bool `json:"updated"`
+	// Commit is the new commit, if mod files changed
+	Commit string `json:"commit,omitempty"`
+}
+
+// CommitResult represents the outcome of committing a library's local changes
+type CommitResult struct {
+	Committed bool   `json:"committed"`
+	Commit    string `json:"commit,omitempty"`
+	Signed    bool   `json:"signed"`
+}
+
+// TagResult represents the outcome of tagging a library
+type TagResult struct {
+	// Tag is the new tag if created, otherwise the latest existing tag
+	Tag     string `json:"tag,omitempty"`
+	Created bool   `json:"created"`
+	Signed  bool   `json:"signed"`
+}
+
+// PRResult represents the outcome of opening a pull request for a library
+type PRResult struct {
+	Opened bool `json:"opened"`
+	Draft  bool `json:"draft"`
+	// AutoMerge is true if the pull request will merge once checks pass
+	AutoMerge bool `json:"autoMerge"`
+	// Checks is the state checks settled on when waited for: success, failure or pending if timed out
+	Checks string `json:"checks,omitempty"`
+	// Merged is true if the pull request was merged once checks passed
+	Merged bool `json:"merged"`
+	// Exists is true if a pull request was already open for the branch
+	Exists bool   `json:"exists"`
+	URL    string `json:"url,omitempty"`
+	Number int    `json:"number,omitempty"`
+}
+
+// Sync updates lib's mod files to the versions of its updated deps, then commits and pushes the result.
+// Uses mu.Options for branch and commit message. Deps can be added with lib.AddDep
+func (mu *MU) Sync(lib Library) (result SyncResult, err error) {
+	commitTitle, commitMessage := mu.getCommitDetails(lib)
+	return mu.sync(lib, commitTitle, commitMessage)
+}
+
+// Commit commits lib's local changes, excluding mod files
+func (mu *MU) Commit(lib Library) (result CommitResult, err error) {
+	result = mu.commitLibrary(lib)
+	return
+}
+
+// Tag tags lib with mu.Options.SetVersion, or the next version from mu.Options.BumpStrategy if lib has changed since its latest tag
+func (mu *MU) Tag(lib Library) (result TagResult, err error) {
+	return mu.tagLibrary(lib)
+}
+
+// OpenPR opens a pull request from the branch set in options (or the current branch) to lib's default branch
+func (mu *MU) OpenPR(lib Library) (result PRResult, err error) {
+	commitTitle, commitMessage := mu.getCommitDetails(lib)
+	return mu.openPR(lib, mu.branch(lib), commitTitle, commitMessage)
+}
+
+// PerformAction performs a single action on lib alone, without searching target directories or sorting deps, e.g.
+// to pull or tag one library from another program. Uses mu.Options for everything but the action. Returns lib's
+// result, and its first error of the action if it failed.
+// Note: sync only sets deps added with lib.AddDep
+func (mu *MU) PerformAction(action string, lib Library) (result LibraryResult, err error) {
+	if mu.ctx == nil {
+		mu.ctx, mu.cancel = context.WithCancel(context.Background())
+	}
+
+	switch action {
+	case "exec":
+		if len(strings.TrimSpace(mu.Options.ExecCommand)) == 0 {
+			return result, fmt.Errorf("no command provided to exec")
+		}
+	case "grep":
+		if err = mu.loadGrepPattern(); err != nil {
+			return
+		}
+	case "audit", "clean", "commit", "licenses", "outdated", "pull", "reset", "status", "sync", "tag", "test", "tidy", "vendor":
+	default:
+		return result, fmt.Errorf("action %s can't be performed on a single library", action)
+	}
+
+	mu.startLibrary(lib)
+	lib.File.Step = action
+	failures := len(lib.File.Failures)
+	started := time.Now()
+
+	// Deps of lib alone
+	fileHead := &sort.FileNode{File: lib.File}
+	switch action {
+	case "audit":
+		mu.audit(lib)
+	case "clean":
+		mu.clean(lib)
+	case "commit":
+		mu.commit(lib)
+	case "exec":
+		mu.exec(lib, 1)
+	case "grep":
+		mu.grep(lib)
+	case "licenses":
+		mu.licenses(lib)
+	case "outdated":
+		mu.outdated(lib)
+	case "pull":
+		mu.pull(lib)
+	case "reset":
+		mu.reset(lib)
+	case "status":
+		mu.status(lib, fileHead)
+	case "sync":
+		mu.syncLibrary(lib)
+	case "tag":
+		mu.tag(lib)
+	case "test":
+		if testErr := mu.test(lib, fileHead); testErr != nil && len(lib.File.Failures) == failures {
+			err = testErr
+		}
+	case "tidy":
+		mu.tidy(lib)
+	case "vendor":
+		mu.vendor(lib)
+	}
+
+	mu.finishLibrary(lib, started)
+	if failed := lib.File.Failures[failures:]; len(failed) > 0 {
+		err = failed[0]
+	}
+
+	return libraryResult(lib.File), err
+}
@@ -0,0 +1,106 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestSyncResult(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+
+	mu := &MU{}
+	result, err := mu.Sync(*lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Head is unchanged as commands are recorded
+	if expected := (SyncResult{Updated: true}); result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestCommitResult(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-parse HEAD", com.Reply{Stdout: "abc123\n"})
+
+	mu := &MU{}
+	result, err := mu.Commit(*lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (CommitResult{Committed: true, Commit: "abc123"}); result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	// Nothing to commit
+	recorder.Reply("git commit", com.Reply{ExitCode: 1})
+	if result, _ = mu.Commit(*lib); result.Committed {
+		t.Errorf("expected nothing to be committed, got %+v", result)
+	}
+}
+
+func TestTagResult(t *testing.T) {
+	// Tags are recorded to the operation log
+	lib, _ := loggedLibrary(t)
+
+	mu := &MU{Options: Options{SetVersion: "v1.2.0"}}
+	result, err := mu.Tag(*lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (TagResult{Tag: "v1.2.0", Created: true}); result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+
+	// Already tagged
+	if result, _ = mu.Tag(*lib); result.Created || result.Tag != "v1.2.0" {
+		t.Errorf("expected existing tag, got %+v", result)
+	}
+}
+
+func TestPerformAction(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	mu := &MU{}
+	for _, action := range []string{"list", "sync-all", "exec", "grep"} {
+		if _, err := mu.PerformAction(action, *lib); err == nil {
+			t.Errorf("expected %s to fail without a lib it can be performed on alone", action)
+		}
+	}
+	if len(recorder.Commands()) > 0 {
+		t.Errorf("expected nothing to be run, got %v", commandLines(recorder))
+	}
+
+	result, err := mu.PerformAction("pull", *lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Updated || result.Library != lib.File.GetGoURL() || !recorder.Ran("git pull") {
+		t.Errorf("expected lib to be pulled, got %+v", result)
+	}
+
+	mu.Options.Pattern = "^module "
+	if _, err = mu.PerformAction("grep", *lib); err != nil {
+		t.Fatal(err)
+	}
+	if len(mu.Stats.Matches) != 1 || mu.Stats.Matches[0].File != "go.mod" {
+		t.Errorf("expected the module line to match, got %+v", mu.Stats.Matches)
+	}
+
+	// The action's failure is returned
+	recorder.Reply("go mod tidy", com.Reply{ExitCode: 1})
+	if result, err = mu.PerformAction("tidy", *lib); err == nil || !strings.Contains(err.Error(), "go mod tidy failed") {
+		t.Errorf("expected tidy to fail, got %v", err)
+	}
+	if len(result.Failures) != 1 {
+		t.Errorf("expected the failure in the result, got %+v", result.Failures)
+	}
+}
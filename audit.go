@@ -0,0 +1,183 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	gosort "sort"
+	"strings"
+)
+
+// Vulnerability represents a govulncheck finding within a dependency of a library
+type Vulnerability struct {
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+
+	Module       string `json:"module"`
+	Version      string `json:"version,omitempty"`
+	FixedVersion string `json:"fixedVersion,omitempty"`
+}
+
+// Matches returns true if id is the vulnerability id or one of its aliases (e.g. a CVE)
+func (vuln Vulnerability) Matches(id string) bool {
+	if strings.EqualFold(vuln.ID, id) {
+		return true
+	}
+
+	for _, alias := range vuln.Aliases {
+		if strings.EqualFold(alias, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VulnerabilityReport represents a vulnerability and every library affected by it
+type VulnerabilityReport struct {
+	Vulnerability
+	Libraries []string `json:"libraries"`
+}
+
+// govulncheckMessage represents a single message of govulncheck's json output
+type govulncheckMessage struct {
+	OSV *struct {
+		ID      string   `json:"id"`
+		Aliases []string `json:"aliases"`
+	} `json:"osv"`
+
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module  string `json:"module"`
+			Version string `json:"version"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+// Audit runs govulncheck on lib and returns vulnerabilities found in its dependencies
+func (lib *Library) Audit() (vulns []Vulnerability, err error) {
+	output, err := lib.File.CmdOutput("govulncheck", "-json", "./...")
+	if err != nil {
+		return
+	}
+
+	aliases := make(map[string][]string)
+	found := make(map[string]bool)
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var message govulncheckMessage
+		if err = decoder.Decode(&message); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse govulncheck output: %v", err)
+			return
+		}
+
+		if message.OSV != nil {
+			aliases[message.OSV.ID] = message.OSV.Aliases
+		}
+
+		if message.Finding == nil || len(message.Finding.Trace) == 0 {
+			continue
+		}
+
+		// Findings repeat at module, package and symbol level
+		frame := message.Finding.Trace[0]
+		key := message.Finding.OSV + " " + frame.Module
+		if found[key] {
+			continue
+		}
+		found[key] = true
+
+		vulns = append(vulns, Vulnerability{
+			ID:           message.Finding.OSV,
+			Module:       frame.Module,
+			Version:      frame.Version,
+			FixedVersion: message.Finding.FixedVersion,
+		})
+	}
+
+	// OSV entries may be reported after findings
+	for i := range vulns {
+		vulns[i].Aliases = aliases[vulns[i].ID]
+	}
+
+	return
+}
+
+func (mu *MU) audit(lib Library) {
+	lib.File.Output("Scanning for vulnerabilities...")
+
+	vulns, err := lib.Audit()
+	if err != nil {
+		lib.File.Error("Audit failed :( " + err.Error())
+		return
+	}
+
+	var matched []string
+	for _, vuln := range vulns {
+		if len(mu.Options.AuditID) > 0 && !vuln.Matches(mu.Options.AuditID) {
+			continue
+		}
+
+		matched = append(matched, vuln.ID)
+		mu.addVulnerability(lib, vuln)
+	}
+
+	if len(matched) == 0 {
+		lib.File.Output("No vulnerabilities found!")
+		return
+	}
+
+	lib.File.Vulnerabilities = matched
+	lib.File.Output("Found " + strings.Join(matched, ", "))
+	mu.addStat(&mu.Stats.VulnerableCount, &mu.Stats.VulnerableOutput, lib.File.GetGoURL()+"\n")
+}
+
+// addVulnerability aggregates vuln by dependency in stats
+func (mu *MU) addVulnerability(lib Library, vuln Vulnerability) {
+	mu.statsMux.Lock()
+	defer mu.statsMux.Unlock()
+
+	if mu.Stats.Vulnerabilities == nil {
+		mu.Stats.Vulnerabilities = make(map[string]*VulnerabilityReport)
+	}
+
+	key := vuln.ID + " " + vuln.Module + "@" + vuln.Version
+	report, ok := mu.Stats.Vulnerabilities[key]
+	if !ok {
+		report = &VulnerabilityReport{Vulnerability: vuln}
+		mu.Stats.Vulnerabilities[key] = report
+	}
+
+	report.Libraries = append(report.Libraries, lib.File.GetGoURL())
+}
+
+// formatVulnerabilities returns each vulnerability and the libraries it affects
+func (stats ActionStats) formatVulnerabilities() (output string) {
+	keys := make([]string, 0, len(stats.Vulnerabilities))
+	for key := range stats.Vulnerabilities {
+		keys = append(keys, key)
+	}
+	gosort.Strings(keys)
+
+	for _, key := range keys {
+		report := stats.Vulnerabilities[key]
+
+		output += report.ID
+		if len(report.Aliases) > 0 {
+			output += " (" + strings.Join(report.Aliases, ", ") + ")"
+		}
+		output += " in " + report.Module + "@" + report.Version
+		if len(report.FixedVersion) > 0 {
+			output += " fixed in " + report.FixedVersion
+		}
+		output += ":\n  " + strings.Join(report.Libraries, "\n  ") + "\n"
+	}
+
+	return
+}
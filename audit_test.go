@@ -0,0 +1,61 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// govulncheckOutput reports GO-2023-0001 at module, package and symbol level, with its osv entry after the findings
+const govulncheckOutput = `{"config": {"scanner_name": "govulncheck"}}
+{"finding": {"osv": "GO-2023-0001", "fixed_version": "v0.3.8", "trace": [{"module": "golang.org/x/text", "version": "v0.3.7"}]}}
+{"finding": {"osv": "GO-2023-0001", "fixed_version": "v0.3.8", "trace": [{"module": "golang.org/x/text", "version": "v0.3.7", "package": "golang.org/x/text/language"}]}}
+{"finding": {"osv": "GO-2023-0002", "trace": [{"module": "golang.org/x/net", "version": "v0.1.0"}]}}
+{"osv": {"id": "GO-2023-0001", "aliases": ["CVE-2022-32149"]}}
+`
+
+func TestAudit(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("govulncheck -json ./...", com.Reply{Stdout: govulncheckOutput})
+
+	vulns, err := lib.Audit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Vulnerability{
+		{ID: "GO-2023-0001", Aliases: []string{"CVE-2022-32149"}, Module: "golang.org/x/text", Version: "v0.3.7", FixedVersion: "v0.3.8"},
+		{ID: "GO-2023-0002", Module: "golang.org/x/net", Version: "v0.1.0"},
+	}
+	if !reflect.DeepEqual(vulns, expected) {
+		t.Errorf("expected %+v, got %+v", expected, vulns)
+	}
+}
+
+func TestAuditID(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("govulncheck -json ./...", com.Reply{Stdout: govulncheckOutput})
+
+	// Matched by alias
+	mu := &MU{Options: Options{AuditID: "cve-2022-32149"}}
+	mu.audit(*lib)
+
+	if !reflect.DeepEqual(lib.File.Vulnerabilities, []string{"GO-2023-0001"}) || mu.Stats.VulnerableCount != 1 {
+		t.Errorf("expected only GO-2023-0001 to be reported, got %q", lib.File.Vulnerabilities)
+	}
+
+	expected := "GO-2023-0001 (CVE-2022-32149) in golang.org/x/text@v0.3.7 fixed in v0.3.8:\n  " + lib.File.GetGoURL() + "\n"
+	if output := mu.Stats.formatVulnerabilities(); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestAuditInvalidOutput(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("govulncheck -json ./...", com.Reply{Stdout: "{"})
+
+	if _, err := lib.Audit(); err == nil {
+		t.Error("expected error parsing invalid govulncheck output")
+	}
+}
@@ -0,0 +1,50 @@
+package gomu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// baseBranch returns the branch lib's pull requests are opened against, set by the longest module path prefix of
+// mu.Options.BaseBranches matching it, or mu.Options.BaseBranch. Empty if the repo's default branch is targeted
+func (mu *MU) baseBranch(lib Library) string {
+	base := mu.Options.BaseBranch
+	url := lib.File.GetGoURL()
+	longest := -1
+	for prefix, override := range mu.Options.BaseBranches {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if url != prefix && !strings.HasPrefix(url, prefix+"/") {
+			continue
+		}
+
+		if len(prefix) > longest {
+			longest = len(prefix)
+			base = override
+		}
+	}
+
+	return base
+}
+
+// checkBaseBranch fails lib if the base branch its pull requests target doesn't exist, before changes are pushed.
+// Returns an error if lib shouldn't be synced
+func (mu *MU) checkBaseBranch(lib Library) (err error) {
+	base := mu.baseBranch(lib)
+	if !mu.libraryOptions(lib).PullRequest || len(base) == 0 || mu.Options.DryRun {
+		// Nothing is pushed in dry runs
+		return
+	}
+
+	exists, err := lib.File.RemoteBranchExists(base)
+	if err != nil {
+		err = fmt.Errorf("unable to check base branch %s: %v", base, err)
+	} else if !exists {
+		err = fmt.Errorf("base branch %s doesn't exist, create it or target another branch", base)
+	}
+
+	if err != nil {
+		mu.libraryError(lib, err)
+	}
+
+	return
+}
@@ -0,0 +1,42 @@
+package gomu
+
+import (
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestSyncMissingBaseBranch(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	// No refs match the base branch
+	recorder.Reply("git ls-remote --exit-code --heads origin develop", com.Reply{ExitCode: 2})
+
+	mu := &MU{Options: Options{Branch: "deps", BaseBranch: "develop", PullRequest: true, Commit: true}}
+	mu.syncLibrary(*lib)
+
+	if !recorder.Ran("git ls-remote --exit-code --heads origin develop") {
+		t.Fatalf("expected base branch to be checked, got %q", commandLines(recorder))
+	}
+
+	// Skipped before the branch is created or anything is pushed
+	if recorder.Ran("git push") || recorder.Ran("git checkout") || recorder.Ran("git commit") {
+		t.Errorf("expected nothing created or pushed, got %q", commandLines(recorder))
+	}
+
+	if len(lib.File.Errors) != 1 || len(mu.Errors) != 1 {
+		t.Errorf("expected missing base branch error, got %v", mu.Errors)
+	}
+}
+
+func TestSyncExistingBaseBranch(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{Options: Options{Branch: "deps", BaseBranch: "develop", PullRequest: true}}
+	if err := mu.checkoutBranch(*lib); err != nil {
+		t.Fatal(err)
+	}
+
+	if !recorder.Ran("git checkout deps") || len(lib.File.Errors) > 0 {
+		t.Errorf("expected branch to be checked out, got %q", commandLines(recorder))
+	}
+}
@@ -0,0 +1,120 @@
+package gomu
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// batchedPR represents a pull request held back until every lib is synced
+type batchedPR struct {
+	lib    Library
+	branch string
+
+	commitTitle   string
+	commitMessage string
+
+	// Set once opened, the body excludes the batch section
+	title  string
+	body   string
+	url    string
+	number int
+}
+
+// requestPR opens a pull request for lib, or holds it back for the batch if batching
+func (mu *MU) requestPR(lib Library, branch, commitTitle, commitMessage string) (err error) {
+	if !mu.Options.BatchPR {
+		_, err = mu.openPR(lib, branch, commitTitle, commitMessage)
+		return
+	}
+
+	lib.File.Output("Holding pull request until every lib is synced...")
+
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	mu.batch = append(mu.batch, &batchedPR{lib: lib, branch: branch, commitTitle: commitTitle, commitMessage: commitMessage})
+	return
+}
+
+// openBatchPRs opens the held back pull requests in dependency order, then links each to the others
+func (mu *MU) openBatchPRs(fileHead *sort.FileNode) {
+	if len(mu.batch) == 0 {
+		return
+	}
+
+	// Libs within a level sync concurrently, restore dependency order
+	var ordered []*batchedPR
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		for _, pr := range mu.batch {
+			if pr.lib.File == itr.File {
+				ordered = append(ordered, pr)
+			}
+		}
+	}
+	mu.batch = ordered
+
+	for _, pr := range mu.batch {
+		if mu.isClosed() {
+			return
+		}
+
+		mu.openPR(pr.lib, pr.branch, pr.commitTitle, pr.commitMessage)
+	}
+
+	var opened []*batchedPR
+	for _, pr := range mu.batch {
+		if pr.number > 0 {
+			opened = append(opened, pr)
+		}
+	}
+
+	if len(opened) < 2 {
+		// Nothing to link, drop the pending entries
+		for _, pr := range opened {
+			if len(mu.batch) > 1 {
+				pr.lib.File.UpdatePullRequest(pr.number, pr.title, pr.body)
+			}
+		}
+		return
+	}
+
+	for _, pr := range opened {
+		pr.lib.File.Output("Linking pull request to the rest of the batch...")
+		if err := pr.lib.File.UpdatePullRequest(pr.number, pr.title, pr.body+mu.batchSection(pr, opened)); err != nil {
+			pr.lib.File.Error("Unable to link pull request: " + err.Error())
+		}
+	}
+}
+
+// batchedFor returns the batched pull request of lib, nil if not batched
+func (mu *MU) batchedFor(lib Library) *batchedPR {
+	for _, pr := range mu.batch {
+		if pr.lib.File == lib.File {
+			return pr
+		}
+	}
+
+	return nil
+}
+
+// batchSection returns a markdown list of the pull requests in batch, in the order they should be merged
+func (mu *MU) batchSection(current *batchedPR, batch []*batchedPR) string {
+	lines := []string{"", "", "---", "Part of a batch of " + strconv.Itoa(len(batch)) + " pull requests, merge them in order:"}
+	for i, pr := range batch {
+		line := strconv.Itoa(i+1) + ". " + pr.lib.File.GetGoURL()
+		switch {
+		case pr == current:
+			line += " (this pull request)"
+		case len(pr.url) > 0:
+			line += " " + pr.url
+		default:
+			line += " (pending)"
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package gomu
+
+import (
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestRequestPRHeldBack(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "sync", PullRequest: true, BatchPR: true}}
+	if err := mu.pullRequest(*lib, "gomu-sync", "Sync deps", "Updated deps"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorder.Commands()) > 0 || mu.Stats.PRCount > 0 {
+		t.Errorf("expected pull request to be held back, ran %q", commandLines(recorder))
+	}
+	if pr := mu.batchedFor(*lib); pr == nil || pr.branch != "gomu-sync" || pr.commitTitle != "Sync deps" {
+		t.Errorf("expected pull request to be batched, got %+v", pr)
+	}
+}
+
+func TestBatchSection(t *testing.T) {
+	a := &com.FileWrapper{Path: "github.com/org/a"}
+	b := &com.FileWrapper{Path: "github.com/org/b"}
+	c := &com.FileWrapper{Path: "github.com/org/c"}
+	batch := []*batchedPR{
+		{lib: Library{File: a}, url: "https://github.com/org/a/pull/1"},
+		{lib: Library{File: b}},
+		{lib: Library{File: c}},
+	}
+
+	expected := "\n\n---\nPart of a batch of 3 pull requests, merge them in order:\n" +
+		"1. github.com/org/a https://github.com/org/a/pull/1\n" +
+		"2. github.com/org/b (this pull request)\n" +
+		"3. github.com/org/c (pending)"
+	if section := (&MU{}).batchSection(batch[1], batch); section != expected {
+		t.Errorf("expected %q, got %q", expected, section)
+	}
+}
+
+func TestOpenBatchPRsInOrder(t *testing.T) {
+	a, _ := recordedLibrary(t)
+	b, _ := recordedLibrary(t)
+
+	// b synced first, but depends on a
+	mu := &MU{Options: Options{Action: "sync", PullRequest: true, BatchPR: true}}
+	mu.requestPR(*b, "gomu-sync", "Sync deps", "")
+	mu.requestPR(*a, "gomu-sync", "Sync deps", "")
+	mu.openBatchPRs(&sort.FileNode{File: a.File, Next: &sort.FileNode{File: b.File}})
+
+	if len(mu.batch) != 2 || mu.batch[0].lib.File != a.File || mu.batch[1].lib.File != b.File {
+		t.Errorf("expected pull requests to be opened in dependency order, got %+v", mu.batch)
+	}
+}
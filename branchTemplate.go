@@ -0,0 +1,136 @@
+package gomu
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// BranchDateLayout formats the date available to branch templates
+const BranchDateLayout = "2006-01-02"
+
+// BranchTemplateData represents the values available to branch name templates, e.g. deps/{{.Date}}-{{.Filter}}
+type BranchTemplateData struct {
+	// Date the run started, formatted with BranchDateLayout
+	Date   string
+	Action string
+	// Names of the libs synced, joined with -. Empty if none are set
+	Filter string
+	// Ticket id set in options
+	Ticket string
+
+	lib     string
+	usedLib bool
+}
+
+// Lib returns the name of the library the branch is for, which makes the branch resolved per lib
+func (data *BranchTemplateData) Lib() string {
+	data.usedLib = true
+	return data.lib
+}
+
+// invalidBranchChars matches sequences git doesn't allow in branch names
+var invalidBranchChars = regexp.MustCompile(`[\s~^:?*\[\\]+|\.\.+|@\{`)
+
+// branchTemplated is true if the branch option is a template
+func (mu *MU) branchTemplated() bool {
+	return strings.Contains(mu.Options.Branch, "{{")
+}
+
+// resolveBranch renders the branch option if it's a template. Unless it uses the lib, the branch is set once for
+// the run, otherwise the template is kept to be rendered for each lib
+func (mu *MU) resolveBranch() (err error) {
+	if !mu.branchTemplated() {
+		return
+	}
+
+	if mu.branchTemplate, err = template.New("branch").Funcs(commitTemplateFuncs).Parse(mu.Options.Branch); err != nil {
+		return fmt.Errorf("unable to parse branch template: %v", err)
+	}
+
+	var names []string
+	for _, lib := range mu.Options.FilterDependencies {
+		names = append(names, path.Base(strings.SplitN(lib, "@", 2)[0]))
+	}
+
+	mu.branchData = BranchTemplateData{
+		Date:   mu.started.Format(BranchDateLayout),
+		Action: mu.Options.Action,
+		Filter: strings.Join(names, "-"),
+		Ticket: mu.Options.Ticket,
+	}
+
+	branch, usedLib, err := mu.renderBranch("")
+	if err != nil {
+		return
+	}
+
+	if usedLib {
+		mu.libBranches = make(map[string]string)
+		return
+	}
+
+	mu.Options.Branch = branch
+	mu.branchTemplate = nil
+	return
+}
+
+// renderBranch renders the branch template for the lib named, replacing characters git doesn't allow with -
+func (mu *MU) renderBranch(lib string) (branch string, usedLib bool, err error) {
+	data := mu.branchData
+	data.lib = lib
+
+	var output bytes.Buffer
+	if err = mu.branchTemplate.Execute(&output, &data); err != nil {
+		err = fmt.Errorf("unable to render branch template: %v", err)
+		return
+	}
+
+	branch = invalidBranchChars.ReplaceAllString(strings.TrimSpace(output.String()), "-")
+	branch = strings.Trim(strings.TrimSuffix(branch, ".lock"), "/.-")
+	if len(branch) == 0 && (!data.usedLib || len(lib) > 0) {
+		err = fmt.Errorf("branch template %q rendered an empty branch name", mu.Options.Branch)
+	}
+
+	return branch, data.usedLib, err
+}
+
+// runBranch returns the branch every lib is synced on, empty if the branch template is rendered for each lib
+func (mu *MU) runBranch() string {
+	if mu.branchTemplate != nil {
+		return ""
+	}
+
+	return mu.Options.Branch
+}
+
+// branch returns the branch checked out or created in lib, set by its library options or rendered for it if the branch
+// template uses the lib
+func (mu *MU) branch(lib Library) string {
+	if override := mu.libraryOptions(lib).Branch; override != mu.Options.Branch {
+		// Set by the lib's options
+		return override
+	}
+
+	if mu.branchTemplate == nil {
+		return mu.Options.Branch
+	}
+
+	url := lib.File.GetGoURL()
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	branch, ok := mu.libBranches[url]
+	if !ok {
+		// Rendered successfully without a lib when resolved, so only the name differs
+		branch, _, _ = mu.renderBranch(path.Base(com.ModuleBase(url)))
+		mu.libBranches[url] = branch
+	}
+
+	return branch
+}
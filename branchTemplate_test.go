@@ -0,0 +1,67 @@
+package gomu
+
+import (
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLibBranchRecorded(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "sync", Branch: "deps/{{.Lib}}"}}
+	if err := mu.resolveBranch(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "deps/" + path.Base(lib.File.GetGoURL())
+	if branch := mu.runBranch(); len(branch) > 0 {
+		t.Errorf("expected no run branch for a template rendered per lib, got %q", branch)
+	}
+
+	if _, _, err := mu.updateOrCreateBranch(*lib); err != nil {
+		t.Fatal(err)
+	}
+	if lib.File.Branch != expected {
+		t.Errorf("expected lib to be on %s, got %q", expected, lib.File.Branch)
+	}
+
+	mu.beginLibrary(1, *lib)
+	if recovery := mu.recovery(); recovery.Branches[lib.File.Path] != expected {
+		t.Errorf("expected recovery to record %s for the lib, got %+v", expected, recovery)
+	}
+
+	mu.loadCheckpoint()
+	mu.saveCheckpoint(*lib, false)
+	if state := mu.checkpoint.Libraries[lib.File.OriginalPath()]; state.Branch != expected {
+		t.Errorf("expected checkpoint to record %s for the lib, got %+v", expected, state)
+	}
+
+	mu.Stats.Options = &mu.Options
+	mu.Stats.Results = []LibraryResult{libraryResult(lib.File), {Branch: "deps/b"}}
+	if summary := mu.Stats.Summary(nil); len(summary.Branch) > 0 || summary.Libraries[0].Branch != expected {
+		t.Errorf("expected the branch of each lib in the summary, got %q and %+v", summary.Branch, summary.Libraries)
+	}
+	if title := mu.Stats.reportTitle(); !strings.HasSuffix(title, " on "+expected+", deps/b") {
+		t.Errorf("expected title to list each branch, got %q", title)
+	}
+}
+
+func TestCheckpointResumesLibBranch(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "sync", Branch: "deps/{{.Date}}-{{.Lib}}"}}
+	if err := mu.resolveBranch(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rendered on an earlier date by the interrupted run
+	mu.checkpoint = &Checkpoint{Libraries: map[string]LibraryState{
+		lib.File.OriginalPath(): {Branch: "deps/2020-01-01", Branched: true},
+	}}
+	if mu.restoreCheckpoint(*lib) {
+		t.Fatal("expected incomplete lib not to be skipped")
+	}
+	if branch := mu.branch(*lib); branch != "deps/2020-01-01" {
+		t.Errorf("expected lib to continue on its recorded branch, got %q", branch)
+	}
+}
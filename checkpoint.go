@@ -0,0 +1,164 @@
+package gomu
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// CheckpointName is the file progress is saved to during a sync
+const CheckpointName = ".gomu-state.json"
+
+// Checkpoint represents the progress of a sync, persisted so a failed run can be resumed
+type Checkpoint struct {
+	Action string `json:"action"`
+	// Branch option of the run, the template if it's rendered for each lib
+	Branch string `json:"branch"`
+
+	// Progress of each library, keyed by path
+	Libraries map[string]LibraryState `json:"libraries"`
+}
+
+// LibraryState represents the progress of a single library within a checkpoint
+type LibraryState struct {
+	Version string `json:"version,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+
+	Branched  bool   `json:"branched"`
+	Updated   bool   `json:"updated"`
+	Committed bool   `json:"committed"`
+	Tagged    bool   `json:"tagged"`
+	PROpened  bool   `json:"prOpened"`
+	PRURL     string `json:"prURL,omitempty"`
+
+	// Completed is true once every step has finished for the library
+	Completed bool `json:"completed"`
+}
+
+// LoadCheckpoint reads a checkpoint from disk
+func LoadCheckpoint(checkpointPath string) (checkpoint Checkpoint, err error) {
+	data, err := ioutil.ReadFile(checkpointPath)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &checkpoint)
+	return
+}
+
+// Save writes checkpoint to disk
+func (checkpoint *Checkpoint) Save(checkpointPath string) (err error) {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(checkpointPath, data, 0644)
+}
+
+// loadCheckpoint prepares the checkpoint for this run, restoring the previous one if resuming
+func (mu *MU) loadCheckpoint() {
+	mu.checkpoint = &Checkpoint{
+		Action:    mu.Options.Action,
+		Branch:    mu.Options.Branch,
+		Libraries: make(map[string]LibraryState),
+	}
+
+	if !mu.Options.Resume {
+		return
+	}
+
+	previous, err := LoadCheckpoint(CheckpointName)
+	if err != nil {
+		com.Println("\nNo checkpoint found to resume. Starting from the beginning...")
+		return
+	}
+
+	if previous.Action != mu.Options.Action || previous.Branch != mu.Options.Branch {
+		com.Println("\nCheckpoint was for", previous.Action, "on <"+previous.Branch+">. Starting from the beginning...")
+		return
+	}
+
+	if previous.Libraries != nil {
+		mu.checkpoint.Libraries = previous.Libraries
+	}
+
+	com.Println("\nResuming from checkpoint...")
+}
+
+// restoreCheckpoint applies saved progress to lib, returning true if lib was already completed
+func (mu *MU) restoreCheckpoint(lib Library) (completed bool) {
+	if mu.checkpoint == nil {
+		return
+	}
+
+	state, ok := mu.checkpoint.Libraries[lib.File.OriginalPath()]
+	if ok && len(state.Branch) > 0 && mu.branchTemplate != nil {
+		// Continue on the branch rendered for lib by the previous run, e.g. if the date changed since
+		mu.libraryMux.Lock()
+		mu.libBranches[lib.File.GetGoURL()] = state.Branch
+		mu.libraryMux.Unlock()
+	}
+
+	if !ok || !state.Completed {
+		return
+	}
+
+	// Restore state so dependents are updated correctly
+	lib.File.Version = state.Version
+	lib.File.Updated = state.Updated
+	lib.File.Committed = state.Committed
+	lib.File.Tagged = state.Tagged
+	lib.File.PROpened = state.PROpened
+	lib.File.PRURL = state.PRURL
+
+	lib.File.Output("Already completed in previous run. Skipping.")
+	return true
+}
+
+// saveCheckpoint records progress for lib
+func (mu *MU) saveCheckpoint(lib Library, completed bool) {
+	if mu.checkpoint == nil || mu.Options.DryRun {
+		return
+	}
+
+	branch := mu.branch(lib)
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	mu.checkpoint.Libraries[lib.File.OriginalPath()] = LibraryState{
+		Version: lib.File.Version,
+		Branch:  branch,
+
+		Branched:  lib.File.BranchCreated,
+		Updated:   lib.File.Updated,
+		Committed: lib.File.Committed,
+		Tagged:    lib.File.Tagged,
+		PROpened:  lib.File.PROpened,
+		PRURL:     lib.File.PRURL,
+
+		Completed: completed,
+	}
+
+	if err := mu.checkpoint.Save(CheckpointName); err != nil {
+		lib.File.Output("Unable to save checkpoint :( " + err.Error())
+	}
+}
+
+// clearCheckpoint removes the checkpoint once every library has completed
+func (mu *MU) clearCheckpoint() {
+	if mu.checkpoint == nil || mu.Options.DryRun {
+		return
+	}
+
+	for _, state := range mu.checkpoint.Libraries {
+		if !state.Completed {
+			// Keep for resume
+			return
+		}
+	}
+
+	os.Remove(CheckpointName)
+}
@@ -0,0 +1,68 @@
+package gomu
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+	lib.File.Version = "v1.2.0"
+	lib.File.Tagged = true
+
+	mu := &MU{Options: Options{Action: "sync", Branch: "deps"}}
+	mu.loadCheckpoint()
+	mu.saveCheckpoint(*lib, true)
+
+	resumed := &MU{Options: Options{Action: "sync", Branch: "deps", Resume: true}}
+	resumed.loadCheckpoint()
+
+	restored := LibraryFromPath(lib.File.Path)
+	if !resumed.restoreCheckpoint(*restored) {
+		t.Fatal("expected lib completed in the previous run to be skipped")
+	}
+	if restored.File.Version != "v1.2.0" || !restored.File.Tagged {
+		t.Errorf("expected progress to be restored, got version %q and tagged %v", restored.File.Version, restored.File.Tagged)
+	}
+
+	resumed.clearCheckpoint()
+	if _, err := os.Stat(CheckpointName); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint to be removed once every lib completed, got %v", err)
+	}
+}
+
+func TestCheckpointOtherRun(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "sync", Branch: "deps"}}
+	mu.loadCheckpoint()
+	mu.saveCheckpoint(*lib, true)
+
+	for _, options := range []Options{
+		{Action: "sync", Branch: "other", Resume: true},
+		{Action: "tidy", Branch: "deps", Resume: true},
+		{Action: "sync", Branch: "deps"},
+	} {
+		resumed := &MU{Options: options}
+		resumed.loadCheckpoint()
+		if resumed.restoreCheckpoint(*lib) {
+			t.Errorf("expected lib not to be skipped resuming with %+v", options)
+		}
+	}
+}
+
+func TestCheckpointIncomplete(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "sync", Resume: true}}
+	mu.loadCheckpoint()
+	mu.saveCheckpoint(*lib, false)
+	if mu.restoreCheckpoint(*lib) {
+		t.Error("expected incomplete lib not to be skipped")
+	}
+
+	mu.clearCheckpoint()
+	if _, err := os.Stat(CheckpointName); err != nil {
+		t.Errorf("expected checkpoint to be kept for resume, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package gomu
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+const (
+	// DefaultChecksTimeout is the longest checks are waited for if options don't set one
+	DefaultChecksTimeout = 30 * time.Minute
+	// DefaultChecksInterval is how often checks are polled if options don't set it
+	DefaultChecksInterval = 30 * time.Second
+)
+
+// waitsForChecks returns true if checks of opened pull requests are waited for before tagging
+func (mu *MU) waitsForChecks() bool {
+	return mu.Options.WaitChecks || mu.Options.MergeGreen
+}
+
+// mergeMethod returns the validated merge method of the options, defaulting to merge
+func (mu *MU) mergeMethod(lib Library) (method string, ok bool) {
+	method = mu.Options.MergeMethod
+	switch method {
+	case "":
+		method = com.MergeMethodMerge
+	case com.MergeMethodMerge, com.MergeMethodSquash, com.MergeMethodRebase:
+	default:
+		lib.File.Error("Unknown merge method " + method + ", expected merge, squash or rebase.")
+		return
+	}
+
+	return method, true
+}
+
+// waitChecks polls checks of pr until they pass, fail or time out, returning the final state.
+// Libs whose checks don't pass are failed, so they and libs depending on them aren't tagged
+func (mu *MU) waitChecks(lib Library, pr com.PRResponse) (state string) {
+	timeout, interval := mu.Options.ChecksTimeout, mu.Options.ChecksInterval
+	if timeout <= 0 {
+		timeout = DefaultChecksTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultChecksInterval
+	}
+
+	lib.File.Output("Waiting for checks of " + pr.URL + "...")
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var err error
+		if state, err = lib.File.CheckStatus(pr); err != nil {
+			lib.File.ChecksFailed = true
+			mu.libraryError(lib, fmt.Errorf("unable to get checks of %s: %v", pr.URL, err))
+			return
+		}
+
+		if state != com.CheckPending {
+			break
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			lib.File.ChecksFailed = true
+			mu.libraryError(lib, fmt.Errorf("checks of %s still pending after %s", pr.URL, timeout))
+			mu.addStat(&mu.Stats.ChecksFailedCount, &mu.Stats.ChecksOutput, pr.URL+" (timed out)\n")
+			return
+		}
+
+		select {
+		case <-mu.ctx.Done():
+			lib.File.ChecksFailed = true
+			return
+		case <-time.After(interval):
+		}
+	}
+
+	if state == com.CheckFailure {
+		lib.File.ChecksFailed = true
+		mu.libraryError(lib, fmt.Errorf("checks of %s failed", pr.URL))
+		mu.addStat(&mu.Stats.ChecksFailedCount, &mu.Stats.ChecksOutput, pr.URL+" (failed)\n")
+		return
+	}
+
+	lib.File.Output("Checks passed!")
+	mu.addStat(&mu.Stats.ChecksPassedCount, &mu.Stats.ChecksOutput, pr.URL+" (passed)\n")
+	return
+}
+
+// mergeGreen merges pr once its checks passed, so libs depending on lib sync against merged changes
+func (mu *MU) mergeGreen(lib Library, pr com.PRResponse) bool {
+	method, ok := mu.mergeMethod(lib)
+	if !ok {
+		return false
+	}
+
+	lib.File.Output("Merging " + pr.URL + " (" + method + ")...")
+	if err := lib.File.MergePR(pr, method); err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to merge %s: %v", pr.URL, err))
+		return false
+	}
+
+	lib.File.Output("Merged!")
+	return true
+}
+
+// checksBlockTag returns true if lib or a dep it was synced to didn't pass checks, so lib shouldn't be tagged
+func (mu *MU) checksBlockTag(lib Library) bool {
+	if !mu.waitsForChecks() {
+		return false
+	}
+
+	if lib.File.ChecksFailed {
+		lib.File.Output("Checks didn't pass, skipping tag.")
+		return true
+	}
+
+	for itr := lib.updatedDeps; itr != nil; itr = itr.Next {
+		if itr.File.ChecksFailed {
+			lib.File.Output("Checks didn't pass on " + itr.File.GetGoURL() + ", skipping tag.")
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package gomu
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestMergeMethod(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	for method, expected := range map[string]string{"": com.MergeMethodMerge, "squash": com.MergeMethodSquash, "rebase": com.MergeMethodRebase} {
+		mu := &MU{Options: Options{MergeMethod: method}}
+		if merge, ok := mu.mergeMethod(*lib); !ok || merge != expected {
+			t.Errorf("expected %q to merge with %s, got %s", method, expected, merge)
+		}
+	}
+
+	mu := &MU{Options: Options{MergeMethod: "fast-forward"}}
+	if _, ok := mu.mergeMethod(*lib); ok {
+		t.Error("expected unknown merge method to be rejected")
+	}
+}
+
+func TestChecksBlockTag(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	dep, _ := recordedLibrary(t)
+	lib.updatedDeps = &sort.FileNode{File: dep.File}
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	mu := &MU{Options: Options{WaitChecks: true}}
+	if mu.checksBlockTag(*lib) {
+		t.Error("expected lib to be tagged once checks passed")
+	}
+
+	dep.File.ChecksFailed = true
+	if !mu.checksBlockTag(*lib) {
+		t.Error("expected failed checks of a dep to block tagging")
+	}
+
+	mu = &MU{}
+	if mu.checksBlockTag(*lib) {
+		t.Error("expected checks to be ignored unless waited for")
+	}
+}
+
+func TestWaitChecksDryRun(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git remote get-url origin", com.Reply{Stdout: "git@github.com:org/a.git\n"})
+	com.SetDryRun(true)
+	com.SetConsoleOutput(ioutil.Discard)
+	defer func() {
+		com.SetDryRun(false)
+		com.SetConsoleOutput(nil)
+	}()
+
+	mu := &MU{Options: Options{MergeGreen: true}, ctx: context.Background()}
+	pr := com.PRResponse{URL: "https://github.com/org/a/pull/7", Number: 7}
+	if state := mu.waitChecks(*lib, pr); state != com.CheckSuccess || lib.File.ChecksFailed {
+		t.Errorf("expected checks to pass in a dry run, got %s", state)
+	}
+	if mu.Stats.ChecksPassedCount != 1 {
+		t.Errorf("expected passed checks to be counted, got %d", mu.Stats.ChecksPassedCount)
+	}
+	if !mu.mergeGreen(*lib, pr) {
+		t.Errorf("expected green pull request to be merged, got %v", mu.Errors)
+	}
+}
@@ -0,0 +1,129 @@
+package gomu
+
+import (
+	"strings"
+)
+
+// Prefix of branches created by gomu, in addition to the configured sync branch
+const cleanBranchPrefix = "gomu-"
+
+// clean removes stashes, branches and worktrees left in lib by interrupted runs
+// Note: only lists them if mu.Options.ListOnly is set
+func (mu *MU) clean(lib Library) {
+	lib.File.Output("Checking for leftover gomu stashes, branches and worktrees...")
+
+	found := 0
+	found += mu.cleanWorktrees(lib)
+	found += mu.cleanStashes(lib)
+	found += mu.cleanBranches(lib)
+
+	if found == 0 {
+		lib.File.Output("Nothing to clean!")
+	}
+}
+
+// cleanWorktrees removes temporary worktrees gomu created for lib
+func (mu *MU) cleanWorktrees(lib Library) (found int) {
+	dirs, err := lib.File.GomuWorktrees()
+	if err != nil {
+		lib.File.Error("Unable to list worktrees :( " + err.Error())
+		return
+	}
+
+	for _, dir := range dirs {
+		found++
+		if mu.Options.ListOnly {
+			lib.File.Output("Found worktree " + dir)
+			mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" worktree "+dir+"\n")
+			continue
+		}
+
+		if err = lib.File.RunCmd("git", "worktree", "remove", "--force", dir); err != nil {
+			lib.File.Error("Unable to remove worktree " + dir + " :( " + err.Error())
+			continue
+		}
+
+		lib.File.Output("Removed worktree " + dir)
+		mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" worktree "+dir+"\n")
+	}
+
+	if len(dirs) > 0 && !mu.Options.ListOnly {
+		// Drop records of worktree directories already deleted from the temp dir
+		lib.File.RunCmd("git", "worktree", "prune")
+	}
+
+	return
+}
+
+// cleanStashes restores the newest stash gomu created in lib, leaving any others for the user
+func (mu *MU) cleanStashes(lib Library) (found int) {
+	refs, err := lib.File.GomuStashes()
+	if err != nil {
+		lib.File.Error("Unable to list stashes :( " + err.Error())
+		return
+	}
+
+	for i, ref := range refs {
+		found++
+		if mu.Options.ListOnly {
+			lib.File.Output("Found stash " + ref)
+			mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" stash "+ref+"\n")
+			continue
+		}
+
+		if i > 0 || lib.File.HasChanges() {
+			// Popping onto local changes could conflict, and older stashes would be applied over newer ones
+			lib.File.Output("Warning - Leaving stash " + ref + ", restore it with git stash pop " + ref)
+			continue
+		}
+
+		if err = lib.File.RunCmd("git", "stash", "pop", ref); err != nil {
+			lib.File.Error("Unable to restore stash " + ref + " :( " + err.Error())
+			continue
+		}
+
+		lib.File.Output("Restored stash " + ref)
+		mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" stash "+ref+"\n")
+	}
+
+	return
+}
+
+// cleanBranches deletes merged sync branches in lib, other than the current branch
+func (mu *MU) cleanBranches(lib Library) (found int) {
+	output, err := lib.File.CmdOutput("git", "for-each-ref", "--format=%(refname:short)", "refs/heads/")
+	if err != nil {
+		lib.File.Error("Unable to list branches :( " + err.Error())
+		return
+	}
+
+	current, _ := lib.File.CurrentBranch()
+	for _, branch := range strings.Split(output, "\n") {
+		branch = strings.TrimSpace(branch)
+		if len(branch) == 0 || branch == current {
+			continue
+		}
+
+		if branch != mu.branch(lib) && !strings.HasPrefix(branch, cleanBranchPrefix) {
+			continue
+		}
+
+		found++
+		if mu.Options.ListOnly {
+			lib.File.Output("Found branch " + branch)
+			mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" branch "+branch+"\n")
+			continue
+		}
+
+		// Not forced, unmerged work is kept
+		if err = lib.File.RunCmd("git", "branch", "-d", branch); err != nil {
+			lib.File.Output("Warning - Leaving unmerged branch " + branch)
+			continue
+		}
+
+		lib.File.Output("Deleted branch " + branch)
+		mu.addStat(&mu.Stats.CleanCount, &mu.Stats.CleanOutput, lib.File.GetGoURL()+" branch "+branch+"\n")
+	}
+
+	return
+}
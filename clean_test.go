@@ -0,0 +1,45 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestClean(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git for-each-ref", com.Reply{Stdout: "master\ngomu-sync\nfeature\n"})
+	recorder.Reply("git branch --show-current", com.Reply{Stdout: "master\n"})
+
+	mu := &MU{}
+	mu.clean(*lib)
+
+	// Only branches gomu created are deleted, not forced so unmerged work is kept
+	expected := []string{
+		"git worktree list --porcelain",
+		"git stash list --format=%gd%x1f%s",
+		"git for-each-ref --format=%(refname:short) refs/heads/",
+		"git branch --show-current",
+		"git branch -d gomu-sync",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if mu.Stats.CleanCount != 1 {
+		t.Errorf("expected 1 branch to be cleaned, got %d", mu.Stats.CleanCount)
+	}
+}
+
+func TestCleanListOnly(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git for-each-ref", com.Reply{Stdout: "master\ngomu-sync\n"})
+
+	mu := &MU{Options: Options{ListOnly: true}}
+	mu.clean(*lib)
+
+	if recorder.Ran("git branch -d") || mu.Stats.CleanCount != 1 {
+		t.Errorf("expected branch to be listed only, ran %q", commandLines(recorder))
+	}
+}
@@ -0,0 +1,136 @@
+package gomu
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	gosort "sort"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// missingDeps returns modules required by libs which match the include patterns, or filter deps if there are none,
+// and aren't found on disk
+func (mu *MU) missingDeps(libs sort.StringArray) (missing []string, err error) {
+	patterns, err := compilePatterns(mu.Options.IncludePatterns)
+	if err != nil {
+		return
+	}
+
+	if len(patterns) == 0 && len(mu.Options.FilterDependencies) == 0 {
+		err = fmt.Errorf("clone missing needs include patterns or filter deps matching your modules")
+		return
+	}
+
+	ours := func(module string) bool {
+		for _, pattern := range patterns {
+			if pattern.matches(module) {
+				return true
+			}
+		}
+
+		for _, filter := range mu.Options.FilterDependencies {
+			if module == strings.Split(filter, "@")[0] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	present := make(map[string]bool, len(libs))
+	required := make(map[string]bool)
+	for _, lib := range libs {
+		file := com.FileWrapper{Path: lib}
+		present[file.GetGoURL()] = true
+
+		deps := file.Deps()
+		modules := deps.Recursive
+		if mu.Options.DirectImport {
+			modules = deps.Direct
+		}
+
+		for module := range modules {
+			required[module] = true
+		}
+	}
+
+	for module := range required {
+		if !present[module] && ours(module) {
+			missing = append(missing, module)
+		}
+	}
+
+	gosort.Strings(missing)
+	return
+}
+
+// moduleRepo splits a module path into its repo (host/owner/name) and the module's directory within it
+func moduleRepo(module string) (repo, dir string) {
+	comps := strings.Split(module, "/")
+	size := 3
+	if comps[0] == com.AzureHost {
+		// dev.azure.com/org/project/name.git
+		size = 4
+	}
+
+	if len(comps) <= size {
+		return module, ""
+	}
+
+	return strings.Join(comps[:size], "/"), strings.Join(comps[size:], "/")
+}
+
+// cloneDir returns the directory to clone repo into within target. Targets within go/src keep go-style directories
+// so go urls can be read from the path
+func cloneDir(target, repo string) string {
+	comps := strings.Split(target, filepath.Join("go", "src"))
+	if len(comps) == 2 {
+		return filepath.Join(comps[0], "go", "src", filepath.FromSlash(repo))
+	}
+
+	return filepath.Join(target, path.Base(repo))
+}
+
+// cloneMissingDeps clones repos of missing deps into the first target directory, returning libs with the clones added
+func (mu *MU) cloneMissingDeps(libs sort.StringArray) (cloned sort.StringArray, ok bool) {
+	missing, err := mu.missingDeps(libs)
+	if err != nil {
+		mu.Errors = append(mu.Errors, err)
+		return
+	}
+
+	target := "."
+	if len(mu.Options.TargetDirectories) > 0 {
+		target = mu.Options.TargetDirectories[0]
+	}
+
+	cloned = libs
+	repos := make(map[string]string)
+	for _, module := range missing {
+		repo, dir := moduleRepo(module)
+		repoDir, found := repos[repo]
+		if !found {
+			repoDir = cloneDir(target, repo)
+			entry := ManifestEntry{Path: repoDir, URL: "https://" + repo + ".git"}
+			if strings.HasSuffix(repo, ".git") {
+				entry.URL = "https://" + repo
+			}
+
+			if err = mu.cloneLibrary(entry); err != nil {
+				// Libs depending on it are still synced, as if it hadn't been found
+				com.Println("Unable to clone missing dep", module+":", err)
+				continue
+			}
+
+			repos[repo] = repoDir
+		}
+
+		cloned = append(cloned, filepath.Join(repoDir, dir))
+		mu.addStat(&mu.Stats.ClonedCount, &mu.Stats.ClonedOutput, module+" => "+filepath.Join(repoDir, dir)+"\n")
+	}
+
+	return cloned, true
+}
@@ -0,0 +1,98 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// goSrcLibrary returns a temporary go/src directory with lib github.com/org/a, requiring github.com/org/b/api,
+// github.com/org/c and github.com/other/d
+func goSrcLibrary(t *testing.T) (src, lib string) {
+	dir, err := ioutil.TempDir("", "gomu-clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	src = filepath.Join(dir, "go", "src")
+	lib = filepath.Join(src, "github.com", "org", "a")
+	if err = os.MkdirAll(lib, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	mod := "module github.com/org/a\n\nrequire (\n\tgithub.com/org/b/api v1.0.0\n\tgithub.com/org/c v1.0.0\n\tgithub.com/other/d v1.0.0\n)\n"
+	sum := "github.com/org/b/api v1.0.0 h1:abc=\ngithub.com/org/c v1.0.0 h1:def=\ngithub.com/other/d v1.0.0 h1:ghi=\n"
+	if err = ioutil.WriteFile(filepath.Join(lib, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(lib, "go.sum"), []byte(sum), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestModuleRepo(t *testing.T) {
+	for module, expected := range map[string][2]string{
+		"github.com/org/lib":                    {"github.com/org/lib", ""},
+		"github.com/org/lib/services/api":       {"github.com/org/lib", "services/api"},
+		"dev.azure.com/org/project/lib.git/sub": {"dev.azure.com/org/project/lib.git", "sub"},
+		"dev.azure.com/org/project/lib.git":     {"dev.azure.com/org/project/lib.git", ""},
+	} {
+		if repo, dir := moduleRepo(module); repo != expected[0] || dir != expected[1] {
+			t.Errorf("expected %s to be %q in %q, got %q in %q", module, expected[1], expected[0], dir, repo)
+		}
+	}
+
+	if dir := cloneDir("/home/me/go/src/github.com/org", "github.com/org/lib"); dir != filepath.FromSlash("/home/me/go/src/github.com/org/lib") {
+		t.Errorf("expected go-style directory, got %s", dir)
+	}
+	if dir := cloneDir("/libs", "github.com/org/lib"); dir != filepath.FromSlash("/libs/lib") {
+		t.Errorf("expected repo name within target, got %s", dir)
+	}
+}
+
+func TestMissingDeps(t *testing.T) {
+	_, lib := goSrcLibrary(t)
+
+	mu := &MU{Options: Options{IncludePatterns: []string{"github.com/org/*", "github.com/org/*/*"}}}
+	missing, err := mu.missingDeps(sort.StringArray{lib})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"github.com/org/b/api", "github.com/org/c"}; !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %q, got %q", expected, missing)
+	}
+
+	// Every dep would be cloned
+	if _, err = (&MU{}).missingDeps(sort.StringArray{lib}); err == nil {
+		t.Error("expected missing deps without patterns or filters to fail")
+	}
+}
+
+func TestCloneMissingDeps(t *testing.T) {
+	src, lib := goSrcLibrary(t)
+	recorder := &com.Recorder{}
+	com.SetRunner(recorder)
+	t.Cleanup(func() { com.SetRunner(nil) })
+
+	mu := &MU{Options: Options{TargetDirectories: []string{src}, FilterDependencies: []string{"github.com/org/b/api"}}}
+	libs, ok := mu.cloneMissingDeps(sort.StringArray{lib})
+	if !ok {
+		t.Fatalf("expected missing deps to be cloned, got %v", mu.Errors)
+	}
+
+	repo := filepath.Join(src, "github.com", "org", "b")
+	if expected := (sort.StringArray{lib, filepath.Join(repo, "api")}); !reflect.DeepEqual(libs, expected) {
+		t.Errorf("expected nested module to be added, got %q", libs)
+	}
+	if !recorder.Ran("git clone --filter=blob:none https://github.com/org/b.git b") || mu.Stats.ClonedCount != 1 {
+		t.Errorf("expected repo to be cloned, got %v", recorder.Commands())
+	}
+}
@@ -0,0 +1,443 @@
+package com
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// AzureHost is the host of azure devops remotes and go urls
+const AzureHost = "dev.azure.com"
+
+// Version of the azure devops rest api
+const azureAPIVersion = "7.0"
+
+// azureProvider opens pull requests with the azure devops rest api
+type azureProvider struct {
+	host string
+}
+
+// azurePullRequest represents azure devops' pull request request and response
+type azurePullRequest struct {
+	SourceRefName string `json:"sourceRefName,omitempty"`
+	TargetRefName string `json:"targetRefName,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Description   string `json:"description,omitempty"`
+	IsDraft       bool   `json:"isDraft,omitempty"`
+
+	PullRequestID int `json:"pullRequestId,omitempty"`
+	Repository    *struct {
+		WebURL string `json:"webUrl"`
+	} `json:"repository,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+// splitAzureRepo splits an azure devops repo (org/project/_git/name) into its components
+func splitAzureRepo(repo string) (org, project, name string, err error) {
+	comps := strings.Split(strings.Trim(repo, "/"), "/")
+	if len(comps) == 4 && comps[2] == "_git" {
+		comps = append(comps[:2], comps[3])
+	}
+
+	if len(comps) != 3 {
+		err = fmt.Errorf("unable to parse azure devops repo %s, expected org/project/_git/name", repo)
+		return
+	}
+
+	return comps[0], comps[1], strings.TrimSuffix(comps[2], ".git"), nil
+}
+
+// azureGoURL normalizes an azure devops go url to the form go get expects (dev.azure.com/org/project/name.git)
+func azureGoURL(goURL string) string {
+	comps := strings.Split(goURL, "/")
+	if len(comps) > 4 && comps[3] == "_git" {
+		// Copied from a clone url
+		comps = append(comps[:3], comps[4:]...)
+	}
+
+	if len(comps) > 3 && !strings.HasSuffix(comps[3], ".git") {
+		comps[3] += ".git"
+	}
+
+	return strings.Join(comps, "/")
+}
+
+// parseAzureRemote normalizes ssh and legacy visualstudio.com remotes to dev.azure.com and org/project/_git/name
+func parseAzureRemote(host, repo string) (string, string) {
+	switch {
+	case host == "ssh."+AzureHost:
+		// git@ssh.dev.azure.com:v3/org/project/name
+		comps := strings.Split(strings.TrimPrefix(repo, "v3/"), "/")
+		if len(comps) == 3 {
+			return AzureHost, comps[0] + "/" + comps[1] + "/_git/" + comps[2]
+		}
+	case strings.HasSuffix(host, ".visualstudio.com"):
+		// https://org.visualstudio.com/project/_git/name
+		return AzureHost, strings.TrimSuffix(host, ".visualstudio.com") + "/" + strings.TrimPrefix(repo, "DefaultCollection/")
+	}
+
+	return host, repo
+}
+
+func (provider *azureProvider) Name() string {
+	return "Azure DevOps"
+}
+
+// apiURL returns the url of resource on repo
+func (provider *azureProvider) apiURL(repo, resource, query string) (urlStr string, err error) {
+	org, project, name, err := splitAzureRepo(repo)
+	if err != nil {
+		return
+	}
+
+	if len(query) > 0 {
+		query += "&"
+	}
+
+	urlStr = "https://" + provider.host + "/" + url.PathEscape(org) + "/" + url.PathEscape(project) +
+		"/_apis/git/repositories/" + url.PathEscape(name) + resource + "?" + query + "api-version=" + azureAPIVersion
+	return
+}
+
+func (provider *azureProvider) headers() (headers map[string]string, err error) {
+	auth, err := getHostAuth(provider.host)
+	if err != nil {
+		return
+	}
+
+	// Personal access tokens use basic auth with any user
+	credentials := base64.StdEncoding.EncodeToString([]byte(auth.User + ":" + auth.Token))
+	headers = map[string]string{"Authorization": "Basic " + credentials}
+	return
+}
+
+// response converts an azure devops pull request to a pr response
+func (pr azurePullRequest) response() (status PRResponse) {
+	status.Number = pr.PullRequestID
+	status.Title = pr.Title
+	if pr.Repository != nil && pr.PullRequestID > 0 {
+		status.URL = pr.Repository.WebURL + "/pullrequest/" + strconv.Itoa(pr.PullRequestID)
+	}
+	if len(pr.Message) > 0 {
+		status.Errors = append(status.Errors, PRResponseError{Message: pr.Message})
+	}
+
+	return
+}
+
+// CreatePR opens a pull request on repo
+func (provider *azureProvider) CreatePR(repo string, request PRRequest) (status *PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	if len(request.HeadRepo) > 0 {
+		err = fmt.Errorf("pull requests from forks aren't supported on azure devops")
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests", "")
+	if err != nil {
+		return
+	}
+
+	post := azurePullRequest{
+		SourceRefName: "refs/heads/" + request.Head,
+		TargetRefName: "refs/heads/" + request.Base,
+		Title:         request.Title,
+		Description:   request.Body,
+		IsDraft:       request.Draft,
+	}
+
+	var payload azurePullRequest
+	httpStatus, err := apiRequest("POST", urlStr, headers, post, &payload)
+
+	response := payload.response()
+	response.HTTPStatus = httpStatus
+	status = &response
+	return
+}
+
+// ListPRs returns active pull requests on repo
+func (provider *azureProvider) ListPRs(repo string) (prs []PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests", "searchCriteria.status=active")
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		Value []azurePullRequest `json:"value"`
+	}
+
+	if _, err = apiRequest("GET", urlStr, headers, nil, &payload); err != nil {
+		return
+	}
+
+	for _, pr := range payload.Value {
+		prs = append(prs, pr.response())
+	}
+
+	return
+}
+
+// GetDefaultBranch returns the default branch of repo
+func (provider *azureProvider) GetDefaultBranch(repo string) (branch string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "", "")
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		DefaultBranch string `json:"defaultBranch"`
+	}
+
+	_, err = apiRequest("GET", urlStr, headers, nil, &payload)
+	branch = strings.TrimPrefix(payload.DefaultBranch, "refs/heads/")
+	return
+}
+
+// ClosePR abandons pull request number on repo
+func (provider *azureProvider) ClosePR(repo string, number int) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(number), "")
+	if err != nil {
+		return
+	}
+
+	_, err = apiRequest("PATCH", urlStr, headers, map[string]string{"status": "abandoned"}, nil)
+	return
+}
+
+// UpdatePR replaces the title and description of pull request number on repo
+func (provider *azureProvider) UpdatePR(repo string, number int, title, body string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(number), "")
+	if err != nil {
+		return
+	}
+
+	_, err = apiRequest("PATCH", urlStr, headers, map[string]string{"title": title, "description": body}, nil)
+	return
+}
+
+// EnableAutoMerge sets pull request pr to complete with method once policies pass
+func (provider *azureProvider) EnableAutoMerge(repo string, pr PRResponse, method string) (err error) {
+	strategy, ok := map[string]string{
+		MergeMethodMerge:  "noFastForward",
+		MergeMethodSquash: "squash",
+		MergeMethodRebase: "rebase",
+	}[method]
+	if !ok {
+		err = fmt.Errorf("unknown merge method %s", method)
+		return
+	}
+
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	org, _, _, err := splitAzureRepo(repo)
+	if err != nil {
+		return
+	}
+
+	// Auto-complete is set on behalf of a user
+	var connection struct {
+		AuthenticatedUser struct {
+			ID string `json:"id"`
+		} `json:"authenticatedUser"`
+	}
+
+	connectionURL := "https://" + provider.host + "/" + url.PathEscape(org) + "/_apis/connectionData"
+	if _, err = apiRequest("GET", connectionURL, headers, nil, &connection); err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(pr.Number), "")
+	if err != nil {
+		return
+	}
+
+	patch := map[string]interface{}{
+		"autoCompleteSetBy": map[string]string{"id": connection.AuthenticatedUser.ID},
+		"completionOptions": map[string]interface{}{"mergeStrategy": strategy, "deleteSourceBranch": true},
+	}
+	_, err = apiRequest("PATCH", urlStr, headers, patch, nil)
+	return
+}
+
+// PushBlocked returns true if an enabled, blocking policy applies to branch. Azure devops rejects direct pushes to
+// branches with any such policy, e.g. required reviewers
+func (provider *azureProvider) PushBlocked(repo, branch string) (blocked bool, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	org, project, _, err := splitAzureRepo(repo)
+	if err != nil {
+		return
+	}
+
+	// Policies are listed by repository id
+	urlStr, err := provider.apiURL(repo, "", "")
+	if err != nil {
+		return
+	}
+
+	var repository struct {
+		ID string `json:"id"`
+	}
+
+	if _, err = apiRequest("GET", urlStr, headers, nil, &repository); err != nil {
+		return
+	}
+
+	query := url.Values{}
+	query.Set("repositoryId", repository.ID)
+	query.Set("refName", "refs/heads/"+branch)
+	query.Set("api-version", azureAPIVersion)
+	urlStr = "https://" + provider.host + "/" + url.PathEscape(org) + "/" + url.PathEscape(project) +
+		"/_apis/git/policy/configurations?" + query.Encode()
+
+	var policies struct {
+		Value []struct {
+			IsEnabled  bool `json:"isEnabled"`
+			IsBlocking bool `json:"isBlocking"`
+		} `json:"value"`
+	}
+
+	if _, err = apiRequest("GET", urlStr, headers, nil, &policies); err != nil {
+		return
+	}
+
+	for _, policy := range policies.Value {
+		if policy.IsEnabled && policy.IsBlocking {
+			return true, nil
+		}
+	}
+
+	return
+}
+
+// CreateRelease is unsupported, azure devops repos have no releases
+func (provider *azureProvider) CreateRelease(repo, tag, notes string) (releaseURL string, err error) {
+	err = fmt.Errorf("%s does not support releases", provider.Name())
+	return
+}
+
+// UploadReleaseAsset is unsupported, azure devops repos have no releases
+func (provider *azureProvider) UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error) {
+	err = fmt.Errorf("%s does not support releases", provider.Name())
+	return
+}
+
+// CheckStatus aggregates the latest statuses of pr, posted by builds and other services
+func (provider *azureProvider) CheckStatus(repo string, pr PRResponse) (state string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(pr.Number)+"/statuses", "")
+	if err != nil {
+		return
+	}
+
+	var statuses struct {
+		Value []struct {
+			State   string `json:"state"`
+			Context struct {
+				Name  string `json:"name"`
+				Genre string `json:"genre"`
+			} `json:"context"`
+		} `json:"value"`
+	}
+	if _, err = apiRequest("GET", urlStr, headers, nil, &statuses); err != nil {
+		return
+	}
+
+	// Statuses are listed oldest first, only the latest of each context counts
+	latest := make(map[string]string)
+	for _, status := range statuses.Value {
+		latest[status.Context.Genre+"/"+status.Context.Name] = status.State
+	}
+
+	state = CheckSuccess
+	for _, status := range latest {
+		switch status {
+		case "succeeded", "notApplicable":
+		case "pending", "notSet":
+			state = CheckPending
+		default:
+			// Failed or error
+			return CheckFailure, nil
+		}
+	}
+
+	return
+}
+
+// MergePR completes pr with method, merging its latest source commit
+func (provider *azureProvider) MergePR(repo string, pr PRResponse, method string) (err error) {
+	strategy, ok := map[string]string{
+		MergeMethodMerge:  "noFastForward",
+		MergeMethodSquash: "squash",
+		MergeMethodRebase: "rebase",
+	}[method]
+	if !ok {
+		err = fmt.Errorf("unknown merge method %s", method)
+		return
+	}
+
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	urlStr, err := provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(pr.Number), "")
+	if err != nil {
+		return
+	}
+
+	// Completing needs the source commit, so pushes since aren't merged unseen
+	var payload struct {
+		LastMergeSourceCommit struct {
+			CommitID string `json:"commitId"`
+		} `json:"lastMergeSourceCommit"`
+	}
+	if _, err = apiRequest("GET", urlStr, headers, nil, &payload); err != nil {
+		return
+	}
+
+	patch := map[string]interface{}{
+		"status":                "completed",
+		"lastMergeSourceCommit": map[string]string{"commitId": payload.LastMergeSourceCommit.CommitID},
+		"completionOptions":     map[string]interface{}{"mergeStrategy": strategy, "deleteSourceBranch": true},
+	}
+	_, err = apiRequest("PATCH", urlStr, headers, patch, nil)
+	return
+}
@@ -0,0 +1,72 @@
+package com
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAzureGoURL(t *testing.T) {
+	for goURL, expected := range map[string]string{
+		"dev.azure.com/org/project/_git/lib":    "dev.azure.com/org/project/lib.git",
+		"dev.azure.com/org/project/lib":         "dev.azure.com/org/project/lib.git",
+		"dev.azure.com/org/project/lib.git/sub": "dev.azure.com/org/project/lib.git/sub",
+	} {
+		if normalized := azureGoURL(goURL); normalized != expected {
+			t.Errorf("expected %s to be %s, got %s", goURL, expected, normalized)
+		}
+	}
+}
+
+func TestParseAzureRemote(t *testing.T) {
+	for _, test := range []struct{ host, repo string }{
+		{"ssh.dev.azure.com", "v3/org/project/lib"},
+		{"org.visualstudio.com", "project/_git/lib"},
+		{"org.visualstudio.com", "DefaultCollection/project/_git/lib"},
+		{"dev.azure.com", "org/project/_git/lib"},
+	} {
+		if host, repo := parseAzureRemote(test.host, test.repo); host != AzureHost || repo != "org/project/_git/lib" {
+			t.Errorf("expected %s %s to be normalized, got %s %s", test.host, test.repo, host, repo)
+		}
+	}
+
+	if _, _, _, err := splitAzureRepo("org/lib"); err == nil {
+		t.Error("expected repo without a project to fail")
+	}
+}
+
+func TestAzureCreatePR(t *testing.T) {
+	setEnv(t, "AZURE_DEVOPS_EXT_PAT", "azure-token")
+
+	var request azurePullRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://dev.azure.com/org/project/_apis/git/repositories/lib/pullrequests?api-version="+azureAPIVersion {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "Basic "+base64.StdEncoding.EncodeToString([]byte(":azure-token")) {
+			t.Errorf("expected basic auth with the token, got %q", auth)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"pullRequestId": 12, "title": "Sync deps", "repository": {"webUrl": "https://dev.azure.com/org/project/_git/lib"}}`
+	})
+
+	provider, err := ProviderFor(AzureHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := provider.CreatePR("org/project/_git/lib", PRRequest{Title: "Sync deps", Head: "sync", Base: "master", Draft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.SourceRefName != "refs/heads/sync" || request.TargetRefName != "refs/heads/master" || !request.IsDraft {
+		t.Errorf("unexpected pull request %+v", request)
+	}
+	if status.Number != 12 || status.URL != "https://dev.azure.com/org/project/_git/lib/pullrequest/12" {
+		t.Errorf("unexpected response %+v", status)
+	}
+}
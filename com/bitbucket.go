@@ -0,0 +1,292 @@
+package com
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+// bitbucketProvider opens pull requests with the bitbucket cloud api
+type bitbucketProvider struct {
+	host string
+}
+
+// bitbucketBranch represents a branch reference in bitbucket's api
+type bitbucketBranch struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	// Set when the branch is on a fork
+	Repository *struct {
+		FullName string `json:"full_name"`
+	} `json:"repository,omitempty"`
+}
+
+// bitbucketPullRequest represents bitbucket's pull request request and response
+type bitbucketPullRequest struct {
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Source      *bitbucketBranch `json:"source,omitempty"`
+	Destination *bitbucketBranch `json:"destination,omitempty"`
+	Draft       bool             `json:"draft,omitempty"`
+
+	ID    int `json:"id,omitempty"`
+	Links *struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links,omitempty"`
+
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (provider *bitbucketProvider) Name() string {
+	return "Bitbucket"
+}
+
+func (provider *bitbucketProvider) apiURL(repo, resource string) string {
+	return "https://api." + provider.host + "/2.0/repositories/" + repo + resource
+}
+
+func (provider *bitbucketProvider) headers() (headers map[string]string, err error) {
+	auth, err := getHostAuth(provider.host)
+	if err != nil {
+		return
+	}
+
+	// App passwords use basic auth
+	credentials := base64.StdEncoding.EncodeToString([]byte(auth.User + ":" + auth.Token))
+	headers = map[string]string{"Authorization": "Basic " + credentials}
+	return
+}
+
+// response converts a bitbucket pull request to a pr response
+func (pr bitbucketPullRequest) response() (status PRResponse) {
+	status.Number = pr.ID
+	status.Title = pr.Title
+	if pr.Links != nil {
+		status.URL = pr.Links.HTML.Href
+	}
+	if pr.Error != nil {
+		status.Errors = append(status.Errors, PRResponseError{Message: pr.Error.Message})
+	}
+
+	return
+}
+
+// CreatePR opens a pull request on repo
+func (provider *bitbucketProvider) CreatePR(repo string, request PRRequest) (status *PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := bitbucketPullRequest{
+		Title:       request.Title,
+		Description: request.Body,
+		Source:      &bitbucketBranch{},
+		Destination: &bitbucketBranch{},
+		Draft:       request.Draft,
+	}
+	post.Source.Branch.Name = request.Head
+	if len(request.HeadRepo) > 0 {
+		post.Source.Repository = &struct {
+			FullName string `json:"full_name"`
+		}{request.HeadRepo}
+	}
+	post.Destination.Branch.Name = request.Base
+
+	var payload bitbucketPullRequest
+	httpStatus, err := apiRequest("POST", provider.apiURL(repo, "/pullrequests"), headers, post, &payload)
+
+	response := payload.response()
+	response.HTTPStatus = httpStatus
+	status = &response
+	return
+}
+
+// ListPRs returns open pull requests on repo
+func (provider *bitbucketProvider) ListPRs(repo string) (prs []PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		Values []bitbucketPullRequest `json:"values"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/pullrequests?state=OPEN"), headers, nil, &payload); err != nil {
+		return
+	}
+
+	for _, pr := range payload.Values {
+		prs = append(prs, pr.response())
+	}
+
+	return
+}
+
+// GetDefaultBranch returns the main branch of repo
+func (provider *bitbucketProvider) GetDefaultBranch(repo string) (branch string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		MainBranch struct {
+			Name string `json:"name"`
+		} `json:"mainbranch"`
+	}
+
+	_, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &payload)
+	branch = payload.MainBranch.Name
+	return
+}
+
+// ClosePR declines pull request number on repo
+func (provider *bitbucketProvider) ClosePR(repo string, number int) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	_, err = apiRequest("POST", provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(number)+"/decline"), headers, nil, nil)
+	return
+}
+
+// UpdatePR replaces the title and description of pull request number on repo
+func (provider *bitbucketProvider) UpdatePR(repo string, number int, title, body string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]string{"title": title, "description": body}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(number)), headers, put, nil)
+	return
+}
+
+// EnableAutoMerge is unsupported, bitbucket cloud has no auto-merge api
+func (provider *bitbucketProvider) EnableAutoMerge(repo string, pr PRResponse, method string) (err error) {
+	err = fmt.Errorf("%s does not support auto-merge", provider.Name())
+	return
+}
+
+// CreateRelease is unsupported, bitbucket has no releases
+func (provider *bitbucketProvider) CreateRelease(repo, tag, notes string) (releaseURL string, err error) {
+	err = fmt.Errorf("%s does not support releases", provider.Name())
+	return
+}
+
+// UploadReleaseAsset is unsupported, bitbucket has no releases
+func (provider *bitbucketProvider) UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error) {
+	err = fmt.Errorf("%s does not support releases", provider.Name())
+	return
+}
+
+// PushBlocked returns true if a push restriction matching branch doesn't list the user.
+// Access granted through groups isn't resolved, so those pushes are treated as blocked
+func (provider *bitbucketProvider) PushBlocked(repo, branch string) (blocked bool, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var restrictions struct {
+		Values []struct {
+			Pattern string `json:"pattern"`
+			Users   []struct {
+				UUID string `json:"uuid"`
+			} `json:"users"`
+		} `json:"values"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/branch-restrictions?kind=push"), headers, nil, &restrictions); err != nil {
+		return
+	}
+
+	var user struct {
+		UUID string `json:"uuid"`
+	}
+
+	for _, restriction := range restrictions.Values {
+		if matched, _ := path.Match(restriction.Pattern, branch); !matched {
+			continue
+		}
+
+		if len(user.UUID) == 0 {
+			if _, err = apiRequest("GET", "https://api."+provider.host+"/2.0/user", headers, nil, &user); err != nil {
+				return
+			}
+		}
+
+		allowed := false
+		for _, restricted := range restriction.Users {
+			allowed = allowed || restricted.UUID == user.UUID
+		}
+
+		if !allowed {
+			return true, nil
+		}
+	}
+
+	return
+}
+
+// CheckStatus aggregates build statuses of pr
+func (provider *bitbucketProvider) CheckStatus(repo string, pr PRResponse) (state string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var statuses struct {
+		Values []struct {
+			State string `json:"state"`
+		} `json:"values"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(pr.Number)+"/statuses"), headers, nil, &statuses); err != nil {
+		return
+	}
+
+	state = CheckSuccess
+	for _, status := range statuses.Values {
+		switch status.State {
+		case "SUCCESSFUL":
+		case "INPROGRESS":
+			state = CheckPending
+		default:
+			// Failed or stopped
+			return CheckFailure, nil
+		}
+	}
+
+	return
+}
+
+// MergePR merges pr with method
+func (provider *bitbucketProvider) MergePR(repo string, pr PRResponse, method string) (err error) {
+	strategy, ok := map[string]string{
+		MergeMethodMerge:  "merge_commit",
+		MergeMethodSquash: "squash",
+		MergeMethodRebase: "fast_forward",
+	}[method]
+	if !ok {
+		err = fmt.Errorf("unknown merge method %s", method)
+		return
+	}
+
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]string{"merge_strategy": strategy}
+	_, err = apiRequest("POST", provider.apiURL(repo, "/pullrequests/"+strconv.Itoa(pr.Number)+"/merge"), headers, post, nil)
+	return
+}
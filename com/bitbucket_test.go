@@ -0,0 +1,61 @@
+package com
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestBitbucketCreatePR(t *testing.T) {
+	setEnv(t, "BITBUCKET_USERNAME", "user")
+	setEnv(t, "BITBUCKET_APP_PASSWORD", "app-password")
+
+	provider, err := ProviderFor("bitbucket.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request bitbucketPullRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://api.bitbucket.org/2.0/repositories/org/a/pullrequests" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:app-password")) {
+			t.Errorf("expected basic auth with the app password, got %q", auth)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"id": 5, "title": "Sync deps", "links": {"html": {"href": "https://bitbucket.org/org/a/pull-requests/5"}}}`
+	})
+
+	status, err := provider.CreatePR("org/a", PRRequest{Title: "Sync deps", Body: "Updated deps", Head: "sync", Base: "master", HeadRepo: "fork/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Title != "Sync deps" || request.Source.Branch.Name != "sync" || request.Source.Repository == nil ||
+		request.Source.Repository.FullName != "fork/a" || request.Destination.Branch.Name != "master" {
+		t.Errorf("unexpected pull request %+v", request)
+	}
+	if status.Number != 5 || status.URL != "https://bitbucket.org/org/a/pull-requests/5" || status.HTTPStatus != http.StatusCreated {
+		t.Errorf("unexpected response %+v", status)
+	}
+}
+
+func TestBitbucketCreatePRError(t *testing.T) {
+	setEnv(t, "BITBUCKET_USERNAME", "user")
+	setEnv(t, "BITBUCKET_APP_PASSWORD", "app-password")
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		return http.StatusBadRequest, `{"error": {"message": "There are no changes to be pulled"}}`
+	})
+
+	provider := &bitbucketProvider{host: "bitbucket.org"}
+	status, _ := provider.CreatePR("org/a", PRRequest{Title: "Sync deps", Head: "sync", Base: "master"})
+	if status == nil || len(status.Errors) != 1 || status.Errors[0].Message != "There are no changes to be pulled" {
+		t.Errorf("expected bitbucket error, got %+v", status)
+	}
+}
@@ -0,0 +1,203 @@
+package com
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ModDeps represents the modules with versions in a file's go.mod and go.sum
+type ModDeps struct {
+	// Direct modules are listed in go.mod
+	Direct map[string]bool `json:"direct"`
+	// Recursive modules are listed in go.sum
+	Recursive map[string]bool `json:"recursive"`
+	// Indirect modules are listed in go.mod, but only required by other deps
+	Indirect map[string]bool `json:"indirect"`
+}
+
+// depCacheVersion is hashed with mod files, entries cached in an older format aren't read
+const depCacheVersion = "4"
+
+// majorSuffix matches the major version suffix of module paths from v2 on, e.g. /v2 of github.com/org/lib/v2
+var majorSuffix = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+// gopkgInModule matches gopkg.in module paths, capturing the path without its major version suffix, e.g. gopkg.in/yaml
+// of gopkg.in/yaml.v2. Every major is suffixed, from v0 on
+var gopkgInModule = regexp.MustCompile(`^(gopkg\.in/.+)\.v[0-9]+$`)
+
+// GopkgInPrefix is the prefix of gopkg.in module paths, whose majors are suffixed with .v2 rather than /v2
+const GopkgInPrefix = "gopkg.in/"
+
+// Global directory parsed deps are cached in, empty if caching is off
+var depCacheDir = ""
+
+// SetDepCache sets the directory parsed deps are cached in globally. Empty turns caching off
+func SetDepCache(dir string) {
+	depCacheDir = dir
+}
+
+// DefaultDepCacheDir returns gomu's directory within the user cache directory (~/.cache/gomu on linux)
+func DefaultDepCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "gomu")
+}
+
+// ModuleBase returns modulePath without its major version suffix (github.com/org/lib/v2 -> github.com/org/lib,
+// gopkg.in/yaml.v2 -> gopkg.in/yaml)
+func ModuleBase(modulePath string) string {
+	if match := gopkgInModule.FindStringSubmatch(modulePath); match != nil {
+		return match[1]
+	}
+
+	return majorSuffix.ReplaceAllString(modulePath, "")
+}
+
+// MajorModulePath returns the module path of the major version of the module at base (github.com/org/lib, 2 ->
+// github.com/org/lib/v2, gopkg.in/yaml, 2 -> gopkg.in/yaml.v2)
+func MajorModulePath(base string, major int) string {
+	if strings.HasPrefix(base, GopkgInPrefix) {
+		return base + ".v" + strconv.Itoa(major)
+	}
+
+	if major < 2 {
+		return base
+	}
+
+	return base + "/v" + strconv.Itoa(major)
+}
+
+// parseModules returns each module followed by a version in content, e.g. github.com/org/lib in github.com/org/lib v1.2.3.
+// Modules from v2 on are also returned without their major suffix, so libs are found whichever major they're required at
+func parseModules(content string) (modules map[string]bool) {
+	modules = make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if version := fields[i+1]; len(version) > 1 && version[0] == 'v' && version[1] >= '0' && version[1] <= '9' {
+				modules[fields[i]] = true
+				modules[ModuleBase(fields[i])] = true
+			}
+		}
+	}
+
+	return
+}
+
+// parseIndirect returns each module followed by a version on lines marked // indirect in content
+func parseIndirect(content string) (modules map[string]bool) {
+	modules = make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if index := strings.Index(line, "//"); index >= 0 && strings.TrimSpace(line[index+2:]) == "indirect" {
+			for module := range parseModules(line[:index]) {
+				modules[module] = true
+			}
+		}
+	}
+
+	return
+}
+
+// LoadDeps reads the file's go.mod and go.sum once, so dependency checks don't read them again.
+// Parsed deps are cached by content hash if a cache directory is set.
+// Note: call again after mod files change
+func (file *FileWrapper) LoadDeps() {
+	// Missing files have no deps
+	mod, _ := ioutil.ReadFile(filepath.Join(file.Path, "go.mod"))
+	sum, _ := ioutil.ReadFile(filepath.Join(file.Path, "go.sum"))
+
+	hash := sha256.New()
+	hash.Write([]byte(depCacheVersion))
+	hash.Write(mod)
+	hash.Write([]byte{0})
+	hash.Write(sum)
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	if deps, ok := loadCachedDeps(key); ok {
+		file.deps = deps
+		return
+	}
+
+	file.deps = &ModDeps{Direct: parseModules(string(mod)), Recursive: parseModules(string(sum)), Indirect: parseIndirect(string(mod))}
+	if err := saveCachedDeps(key, file.deps); err != nil {
+		file.Debug("Unable to cache deps: " + err.Error())
+	}
+}
+
+// Deps returns deps parsed from the file's go.mod and go.sum, loading them if not yet loaded
+func (file *FileWrapper) Deps() *ModDeps {
+	if file.deps == nil {
+		file.LoadDeps()
+	}
+
+	return file.deps
+}
+
+// depCachePath returns the cache file of deps parsed from mod files with hash key
+func depCachePath(key string) string {
+	return filepath.Join(depCacheDir, "deps", key+".json")
+}
+
+// loadCachedDeps returns deps parsed from mod files with hash key, false if not cached
+func loadCachedDeps(key string) (deps *ModDeps, ok bool) {
+	if len(depCacheDir) == 0 {
+		return
+	}
+
+	data, err := ioutil.ReadFile(depCachePath(key))
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(data, &deps); err != nil || deps == nil {
+		return nil, false
+	}
+
+	return deps, true
+}
+
+// saveCachedDeps caches deps parsed from mod files with hash key
+func saveCachedDeps(key string, deps *ModDeps) (err error) {
+	if len(depCacheDir) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(deps)
+	if err != nil {
+		return
+	}
+
+	cachePath := depCachePath(key)
+	if err = os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return
+	}
+
+	// Written then renamed, concurrent runs never read a partial file
+	tempFile, err := ioutil.TempFile(filepath.Dir(cachePath), key+".tmp-")
+	if err != nil {
+		return
+	}
+
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), cachePath)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+	}
+
+	return
+}
@@ -0,0 +1,81 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModuleBase(t *testing.T) {
+	for _, test := range []struct {
+		modulePath string
+		base       string
+		major      int
+	}{
+		{"github.com/org/lib", "github.com/org/lib", 1},
+		{"github.com/org/lib/v2", "github.com/org/lib", 2},
+		{"github.com/org/lib/v10", "github.com/org/lib", 10},
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml", 2},
+		{"gopkg.in/yaml.v1", "gopkg.in/yaml", 1},
+	} {
+		if base := ModuleBase(test.modulePath); base != test.base {
+			t.Errorf("%s: expected base %s, got %s", test.modulePath, test.base, base)
+		}
+
+		if modulePath := MajorModulePath(test.base, test.major); modulePath != test.modulePath {
+			t.Errorf("%s: expected module path %s, got %s", test.base, test.modulePath, modulePath)
+		}
+	}
+
+	// Majors below v2 aren't suffixed, so neither is v1 stripped
+	if base := ModuleBase("github.com/org/lib/v1"); base != "github.com/org/lib/v1" {
+		t.Errorf("expected v1 kept, got %s", base)
+	}
+}
+
+func TestLoadDeps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-deps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, "a")
+	if err = os.Mkdir(lib, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mod := "module github.com/org/a\n\nrequire (\n\tgithub.com/org/b/v2 v2.0.0\n\tgithub.com/org/c v1.0.0 // indirect\n)\n"
+	sum := "github.com/org/b/v2 v2.0.0 h1:abc=\ngithub.com/org/c v1.0.0 h1:def=\ngithub.com/org/d v1.0.0/go.mod h1:ghi=\n"
+	if err = ioutil.WriteFile(filepath.Join(lib, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(lib, "go.sum"), []byte(sum), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	SetDepCache(filepath.Join(dir, "cache"))
+	defer SetDepCache("")
+
+	file := &FileWrapper{Path: lib}
+	deps := file.Deps()
+	if !deps.Direct["github.com/org/b/v2"] || !deps.Direct["github.com/org/b"] || deps.Direct["github.com/org/d"] {
+		t.Errorf("expected go.mod's modules to be direct, got %v", deps.Direct)
+	}
+	if !deps.Recursive["github.com/org/d"] || !deps.Indirect["github.com/org/c"] || deps.Indirect["github.com/org/b"] {
+		t.Errorf("expected go.sum's modules to be recursive and marked modules indirect, got %v and %v", deps.Recursive, deps.Indirect)
+	}
+
+	cached, err := filepath.Glob(filepath.Join(dir, "cache", "deps", "*.json"))
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("expected deps to be cached, got %q", cached)
+	}
+
+	// Libs with the same mod files read the cache
+	if err = ioutil.WriteFile(cached[0], []byte(`{"direct": {"github.com/org/cached": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if file = (&FileWrapper{Path: lib}); !file.Deps().Direct["github.com/org/cached"] {
+		t.Errorf("expected cached deps, got %v", file.Deps().Direct)
+	}
+}
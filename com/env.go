@@ -0,0 +1,91 @@
+package com
+
+import (
+	"fmt"
+	"os"
+	gosort "sort"
+	"strings"
+)
+
+// Global env vars set for commands, and vars of libs keyed by module path prefix
+var (
+	commandEnv = map[string]string{}
+	libraryEnv = map[string]map[string]string{}
+)
+
+// SetEnv sets env vars for all commands run globally, e.g. GOFLAGS: -mod=mod, and vars of libs within module path
+// prefixes, e.g. github.com/org/private: {GOPRIVATE: github.com/org/*}. The parent process env is left unchanged
+func SetEnv(env map[string]string, libEnv map[string]map[string]string) (err error) {
+	if err = validateEnv(env); err != nil {
+		return
+	}
+
+	for prefix, vars := range libEnv {
+		if err = validateEnv(vars); err != nil {
+			return fmt.Errorf("%v for %s", err, prefix)
+		}
+	}
+
+	commandEnv = env
+	libraryEnv = libEnv
+	return
+}
+
+// validateEnv returns an error if a var name is empty or contains =
+func validateEnv(env map[string]string) error {
+	for name := range env {
+		if len(name) == 0 || strings.Contains(name, "=") {
+			return fmt.Errorf("invalid env var name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// Environ returns the environment of commands not run for a lib, with global vars set
+func Environ() []string {
+	return appendEnv(os.Environ(), commandEnv)
+}
+
+// Environ returns the environment of commands run for the file, with global vars and vars of module path prefixes
+// matching it set. Longer prefixes override shorter ones
+func (file *FileWrapper) Environ() (env []string) {
+	env = Environ()
+	if len(libraryEnv) == 0 {
+		return
+	}
+
+	url := file.GetGoURL()
+	var prefixes []string
+	for prefix := range libraryEnv {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if url == trimmed || strings.HasPrefix(url, trimmed+"/") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	gosort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) < len(prefixes[j])
+	})
+
+	for _, prefix := range prefixes {
+		env = appendEnv(env, libraryEnv[prefix])
+	}
+
+	return
+}
+
+// appendEnv appends vars to env in name order, later values overriding earlier ones when commands run
+func appendEnv(env []string, vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	gosort.Strings(names)
+
+	for _, name := range names {
+		env = append(env, name+"="+vars[name])
+	}
+
+	return env
+}
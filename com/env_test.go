@@ -0,0 +1,84 @@
+package com
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// setCommandEnv sets env vars for commands until the test finishes
+func setCommandEnv(t *testing.T, env map[string]string, libEnv map[string]map[string]string) {
+	if err := SetEnv(env, libEnv); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetEnv(nil, nil) })
+}
+
+// envValue returns the value env sets name to last, as commands see it
+func envValue(env []string, name string) (value string, ok bool) {
+	for _, v := range env {
+		if strings.HasPrefix(v, name+"=") {
+			value, ok = v[len(name)+1:], true
+		}
+	}
+
+	return
+}
+
+func TestSetEnvValidates(t *testing.T) {
+	for _, env := range []map[string]string{{"": "x"}, {"A=B": "x"}} {
+		if err := SetEnv(env, nil); err == nil {
+			t.Errorf("expected %v to be invalid", env)
+		}
+		if err := SetEnv(nil, map[string]map[string]string{"github.com/org": env}); err == nil || !strings.Contains(err.Error(), "github.com/org") {
+			t.Errorf("expected %v to be invalid for the prefix, got %v", env, err)
+		}
+	}
+}
+
+func TestLibraryEnviron(t *testing.T) {
+	setCommandEnv(t, map[string]string{"GOFLAGS": "-mod=mod", "GOPRIVATE": "none"}, map[string]map[string]string{
+		"github.com/org":          {"GOPRIVATE": "github.com/org/*"},
+		"github.com/org/private/": {"GOPRIVATE": "github.com/org/private", "GONOSUMDB": "github.com/org/private"},
+		"github.com/org/lib":      {"GOFLAGS": "-mod=vendor"},
+	})
+
+	for url, expected := range map[string]map[string]string{
+		"github.com/other/a":       {"GOFLAGS": "-mod=mod", "GOPRIVATE": "none"},
+		"github.com/org/a":         {"GOFLAGS": "-mod=mod", "GOPRIVATE": "github.com/org/*"},
+		"github.com/org/private/a": {"GOPRIVATE": "github.com/org/private", "GONOSUMDB": "github.com/org/private"},
+		"github.com/org/lib":       {"GOFLAGS": "-mod=vendor"},
+		"github.com/org/library":   {"GOFLAGS": "-mod=mod"},
+	} {
+		file := &FileWrapper{Path: url, goURL: url}
+		env := file.Environ()
+		for name, value := range expected {
+			if v, _ := envValue(env, name); v != value {
+				t.Errorf("%s: expected %s=%s, got %q", url, name, value, v)
+			}
+		}
+	}
+
+	if value, _ := os.LookupEnv("GOFLAGS"); value == "-mod=vendor" {
+		t.Error("expected the parent process env to be left unchanged")
+	}
+}
+
+func TestCommandsGetEnv(t *testing.T) {
+	setCommandEnv(t, map[string]string{"GOFLAGS": "-mod=mod"}, nil)
+
+	recorder := &Recorder{}
+	file := &FileWrapper{Path: "github.com/org/a", goURL: "github.com/org/a"}
+	file.SetRunner(recorder)
+	if _, err := file.CmdOutput("go", "list"); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := recorder.Commands()
+	if len(commands) != 1 {
+		t.Fatalf("expected a command, got %v", commands)
+	}
+	if value, _ := envValue(commands[0].Env, "GOFLAGS"); value != "-mod=mod" {
+		t.Errorf("expected command to get GOFLAGS=-mod=mod, got %q", value)
+	}
+}
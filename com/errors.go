@@ -0,0 +1,47 @@
+package com
+
+import "encoding/json"
+
+// LibraryError represents an error a library encountered at a step of an action
+type LibraryError struct {
+	Library string
+	// Step of the action which failed, e.g. commit or tag
+	Step  string
+	Cause error
+}
+
+func (err *LibraryError) Error() string {
+	if len(err.Step) == 0 {
+		return err.Library + ": " + err.Cause.Error()
+	}
+
+	return err.Library + ": " + err.Step + ": " + err.Cause.Error()
+}
+
+// Unwrap returns the cause, so errors.Is and errors.As can inspect it
+func (err *LibraryError) Unwrap() error {
+	return err.Cause
+}
+
+// MarshalJSON encodes the cause as its message
+func (err *LibraryError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Library string `json:"library"`
+		Step    string `json:"step,omitempty"`
+		Error   string `json:"error"`
+	}{err.Library, err.Step, err.Cause.Error()})
+}
+
+// Fail records cause as an error of the file at its current step, returning the attributed error
+func (file *FileWrapper) Fail(cause error) (err *LibraryError) {
+	var label = file.goURL
+	if file.goURL == "" {
+		label = file.Path
+	}
+
+	err = &LibraryError{Library: label, Step: file.Step, Cause: cause}
+	file.Errors = append(file.Errors, cause.Error())
+	file.Failures = append(file.Failures, err)
+	file.log(ERROR, cause.Error())
+	return
+}
@@ -0,0 +1,40 @@
+package com
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFail(t *testing.T) {
+	SetConsoleOutput(ioutil.Discard)
+	t.Cleanup(func() { SetConsoleOutput(nil) })
+
+	file := &FileWrapper{Path: "/libs/a", Step: "tag"}
+	err := file.Fail(os.ErrPermission)
+
+	if err.Library != "/libs/a" || err.Step != "tag" || err.Error() != "/libs/a: tag: "+os.ErrPermission.Error() {
+		t.Errorf("expected error attributed to the lib's step, got %q", err)
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Error("expected cause to be unwrapped")
+	}
+	if len(file.Errors) != 1 || len(file.Failures) != 1 || file.Failures[0] != err {
+		t.Errorf("expected error to be recorded on the file, got %q and %v", file.Errors, file.Failures)
+	}
+
+	data, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	if expected := `{"library":"/libs/a","step":"tag","error":"permission denied"}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	// Errors outside of a step
+	if err = (&LibraryError{Library: "/libs/a", Cause: os.ErrPermission}); err.Error() != "/libs/a: permission denied" {
+		t.Errorf("expected error without a step, got %q", err)
+	}
+}
@@ -0,0 +1,153 @@
+package com
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ShellArgs returns the argv running command with the platform's shell, sh or cmd on Windows
+func ShellArgs(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+
+	return []string{"sh", "-c", command}
+}
+
+// cleanOutput trims command output and normalizes Windows line endings, so output can be split on \n
+func cleanOutput(output []byte) string {
+	return strings.TrimSpace(strings.Replace(string(output), "\r\n", "\n", -1))
+}
+
+// resolve returns name relative to the file's path, unless it's absolute
+func (file *FileWrapper) resolve(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(file.Path, filepath.FromSlash(name))
+}
+
+// RemoveFile deletes name, relative to the file's path
+// Note: in dry run mode the removal is printed and not executed
+func (file *FileWrapper) RemoveFile(name string) (err error) {
+	tag := "rm " + name
+	file.Debug(tag)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	if err = os.Remove(file.resolve(name)); err != nil {
+		return file.handleError(tag, err)
+	}
+
+	return
+}
+
+// RenameFile moves from to to, relative to the file's path. Existing files at to are replaced
+// Note: in dry run mode the move is printed and not executed
+func (file *FileWrapper) RenameFile(from, to string) (err error) {
+	tag := "mv " + from + " " + to
+	file.Debug(tag)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	if err = os.Rename(file.resolve(from), file.resolve(to)); err != nil {
+		return file.handleError(tag, err)
+	}
+
+	return
+}
+
+// CopyFile copies source to dest, relative to the file's path
+// Note: in dry run mode the copy is printed and not executed
+func (file *FileWrapper) CopyFile(source, dest string) (err error) {
+	tag := "cp " + source + " " + dest
+	file.Debug(tag)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	if err = copyFile(file.resolve(source), file.resolve(dest)); err != nil {
+		return file.handleError(tag, err)
+	}
+
+	return
+}
+
+// MakeDir creates name and any missing parents, relative to the file's path
+// Note: in dry run mode the creation is printed and not executed
+func (file *FileWrapper) MakeDir(name string) (err error) {
+	tag := "mkdir -p " + name
+	file.Debug(tag)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	if err = os.MkdirAll(file.resolve(name), 0755); err != nil {
+		return file.handleError(tag, err)
+	}
+
+	return
+}
+
+// WriteFile writes data to name, relative to the file's path, keeping the permissions of existing files
+// Note: in dry run mode the write is printed and not executed
+func (file *FileWrapper) WriteFile(name string, data []byte) (err error) {
+	tag := "write " + name
+	file.Debug(tag)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	if err = ioutil.WriteFile(file.resolve(name), data, 0644); err != nil {
+		return file.handleError(tag, err)
+	}
+
+	return
+}
+
+func copyFile(source, dest string) (err error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return
+	}
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return
+	}
+
+	return out.Close()
+}
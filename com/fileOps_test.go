@@ -0,0 +1,93 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestFileOps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-fileops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := &FileWrapper{Path: dir}
+	if err = file.MakeDir("a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.WriteFile("a/b/go.mod", []byte("module a\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chmod(filepath.Join(dir, "a", "b", "go.mod"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.CopyFile("a/b/go.mod", "go.mod.bak"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "go.mod.bak"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected copy to keep permissions 0600, got %v", info.Mode().Perm())
+	}
+
+	if err = file.RenameFile("go.mod.bak", filepath.Join(dir, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod")); err != nil || string(data) != "module a\n" {
+		t.Errorf("expected renamed copy, got %q (%v)", data, err)
+	}
+
+	if err = file.RemoveFile("go.mod"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "go.mod")); !os.IsNotExist(err) {
+		t.Errorf("expected go.mod to be removed, got %v", err)
+	}
+	if err = file.RemoveFile("go.mod"); err == nil {
+		t.Error("expected removing a missing file to fail")
+	}
+}
+
+func TestFileOpsDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-fileops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	SetDryRun(true)
+	defer SetDryRun(false)
+
+	file := &FileWrapper{Path: dir}
+	file.MakeDir("a")
+	file.WriteFile("go.mod", []byte("module a\n"))
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("expected nothing to be written in dry run mode, got %d entries", len(entries))
+	}
+}
+
+func TestCleanOutput(t *testing.T) {
+	if output := cleanOutput([]byte("a\r\nb\r\n")); output != "a\nb" {
+		t.Errorf("expected Windows line endings to be normalized, got %q", output)
+	}
+
+}
+
+func TestShellArgs(t *testing.T) {
+	expected := []string{"sh", "-c", "echo hi"}
+	if runtime.GOOS == "windows" {
+		expected = []string{"cmd", "/C", "echo hi"}
+	}
+
+	if shell := ShellArgs("echo hi"); !reflect.DeepEqual(shell, expected) {
+		t.Errorf("expected %q, got %q", expected, shell)
+	}
+}
@@ -1,16 +1,33 @@
 package com
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
-	"path"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 // FileWrapper represents a file object in a double link list, also contains status update info
 type FileWrapper struct {
 	// Private cached values
-	absPath string
-	goURL   string
+	absPath   string
+	goURL     string
+	moduleDir *string
+
+	// Deps parsed by LoadDeps, mod files are read on each check if nil
+	deps *ModDeps
+	// Package manifest parsed by Manifest
+	manifest *PackageManifest
+
+	// Original path while using a temporary worktree
+	repoPath string
+
+	// Bounds commands run for the file
+	ctx context.Context
+	// Runs the file's commands, the global runner if nil
+	runner Runner
 
 	// Relative or absolute path to file from working dir
 	Path string
@@ -18,58 +35,86 @@ type FileWrapper struct {
 	// Optional value to set or match
 	Version string
 
+	// Step of the action being performed, errors are attributed to it
+	Step string
+
 	// Status flags
 	Updated       bool
 	Tagged        bool
+	TagSigned     bool
 	Committed     bool
+	CommitSigned  bool
 	PROpened      bool
 	BranchCreated bool
 	TestFailed    bool
+	TimedOut      bool
+	ChecksFailed  bool // Checks of the opened pull request didn't pass
+	Executed      bool
+
+	// Status details
+	PreviousVersion string
+	CommitSHA       string
+	Branch          string // Branch checked out or created for the action
+	ProtectedBranch string // Branch which blocked pushes, changes were synced on a fallback branch instead
+	ExitCode        int
+	Retries         int
+	Duration        time.Duration // Time spent performing the action on the file
+	PRURL           string
+	ReleaseURL      string
+	UpdatedDeps     []string
+	Vulnerabilities []string
+	Errors          []string
+	Failures        []*LibraryError
 }
 
-// Debug prints a message to stdout if debug is true
+// Error records message as an error of the file at its current step
 func (file *FileWrapper) Error(message string) {
-	var label = file.goURL
-	if file.goURL == "" {
-		label = file.Path
-	}
-
-	Errorln(label, ":ERROR:", message)
+	file.Fail(errors.New(message))
 }
 
 // Output prints a message to stdout
 func (file *FileWrapper) Output(message string) {
-	var label = file.goURL
-	if file.goURL == "" {
-		label = file.Path
-	}
+	file.log(NORMAL, message)
+}
 
-	Println(label, "::", message)
+// DryRun prints a simulated action to stdout
+func (file *FileWrapper) DryRun(message string) {
+	file.log(NORMAL, message, DryRunField, true)
 }
 
 // Debug prints a message to stdout if debug is true
 func (file *FileWrapper) Debug(message string) {
-	var label = file.goURL
-	if file.goURL == "" {
-		label = file.Path
-	}
-
-	Debugln(label, ":DEBUG:", message)
+	file.log(DEBUG, message)
 }
 
 func (file *FileWrapper) containedIn(modfileContent string) bool {
-	return strings.Contains(modfileContent, file.GetGoURL()+" v")
+	return parseModules(modfileContent)[file.GetGoURL()]
 }
 
 // AbsPath returns the current absolute directory of the calling lib
 func (file *FileWrapper) AbsPath() string {
 	if len(file.absPath) == 0 {
-		file.absPath, _ = file.CmdOutput("pwd")
+		// Symlinks are resolved, empty if the directory doesn't exist
+		if abs, err := filepath.Abs(file.Path); err == nil {
+			file.absPath, _ = filepath.EvalSymlinks(abs)
+		}
 	}
 
 	return file.absPath
 }
 
+// ModuleDir returns the directory of the module relative to the root of its repository (services/a),
+// or an empty string if the module is at the root
+func (file *FileWrapper) ModuleDir() string {
+	if file.moduleDir == nil {
+		prefix, _ := file.CmdOutput("git", "rev-parse", "--show-prefix")
+		prefix = strings.Trim(prefix, "/")
+		file.moduleDir = &prefix
+	}
+
+	return *file.moduleDir
+}
+
 // GetGoURL will return the format of the dependency version <github.com/hatchify/mod-utils>
 func (file *FileWrapper) GetGoURL() string {
 	if len(file.goURL) > 0 {
@@ -79,7 +124,7 @@ func (file *FileWrapper) GetGoURL() string {
 	dir := file.AbsPath()
 
 	// Parse go/src out of absolute path
-	components := strings.Split(dir, path.Join("go", "src"))
+	components := strings.Split(dir, filepath.Join("go", "src"))
 
 	if len(components) != 2 {
 		// We have a problem.. No go url found
@@ -87,25 +132,49 @@ func (file *FileWrapper) GetGoURL() string {
 		return file.Path
 	}
 
-	file.goURL = strings.Trim(components[1], "/")
+	file.goURL = strings.Trim(filepath.ToSlash(components[1]), "/")
+	if strings.HasPrefix(file.goURL, AzureHost+"/") {
+		file.goURL = azureGoURL(file.goURL)
+	}
+
 	return file.goURL
 }
 
 // DirectlyImports is used to determine direct dependencies.
 // returns true if file/go.mod contains any dep version
 func (file *FileWrapper) DirectlyImports(dep *FileWrapper) bool {
+	if file.deps != nil {
+		return file.deps.Direct[dep.GetGoURL()]
+	}
+
 	// Read library/go.mod
-	if libMod, err := ioutil.ReadFile(path.Join(file.Path, "go.mod")); err == nil {
+	if libMod, err := ioutil.ReadFile(filepath.Join(file.Path, "go.mod")); err == nil {
 		return dep.containedIn(string(libMod))
 	}
 
 	return false
 }
 
+// DirectlyRequires returns true if file's go.mod requires dep, other than as an indirect dependency
+func (file *FileWrapper) DirectlyRequires(dep *FileWrapper) bool {
+	deps := file.Deps()
+	return deps.Direct[dep.GetGoURL()] && !deps.Indirect[dep.GetGoURL()]
+}
+
 // DirectlyImportsAny returns true if file depends on any of the filter deps. Returns false if slice is empty
 func (file *FileWrapper) DirectlyImportsAny(deps []*FileWrapper) bool {
+	if file.deps != nil {
+		for _, dep := range deps {
+			if file.deps.Direct[dep.GetGoURL()] {
+				return true
+			}
+		}
+
+		return false
+	}
+
 	// Read library/go.sum once
-	if libMod, err := ioutil.ReadFile(path.Join(file.Path, "go.mod")); err == nil {
+	if libMod, err := ioutil.ReadFile(filepath.Join(file.Path, "go.mod")); err == nil {
 		// Parse sum once
 		goMod := string(libMod)
 
@@ -124,8 +193,12 @@ func (file *FileWrapper) DirectlyImportsAny(deps []*FileWrapper) bool {
 // DependsOn is used to determine sort order.
 // returns true if file/go.sum contains any dep version
 func (file *FileWrapper) DependsOn(dep *FileWrapper) bool {
+	if file.deps != nil {
+		return file.deps.Recursive[dep.GetGoURL()]
+	}
+
 	// Read library/go.sum
-	if libSum, err := ioutil.ReadFile(path.Join(file.Path, "go.sum")); err == nil {
+	if libSum, err := ioutil.ReadFile(filepath.Join(file.Path, "go.sum")); err == nil {
 		return dep.containedIn(string(libSum))
 	}
 
@@ -134,8 +207,18 @@ func (file *FileWrapper) DependsOn(dep *FileWrapper) bool {
 
 // DependsOnAny returns true if file depends on any of the filter deps. Returns false if slice is empty
 func (file *FileWrapper) DependsOnAny(deps []*FileWrapper) bool {
+	if file.deps != nil {
+		for _, dep := range deps {
+			if file.deps.Recursive[dep.GetGoURL()] {
+				return true
+			}
+		}
+
+		return false
+	}
+
 	// Read library/go.sum once
-	if libSum, err := ioutil.ReadFile(path.Join(file.Path, "go.sum")); err == nil {
+	if libSum, err := ioutil.ReadFile(filepath.Join(file.Path, "go.sum")); err == nil {
 		// Parse sum once
 		goSum := string(libSum)
 
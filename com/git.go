@@ -1,14 +1,14 @@
 package com
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
-	"net/url"
+	"os"
 	"os/user"
 	"path"
+	"path/filepath"
+	gosort "sort"
+	"strconv"
 	"strings"
 )
 
@@ -45,7 +45,59 @@ func (file *FileWrapper) CheckoutOrCreateBranch(branch string) (switched, create
 
 // Fetch calls git fetch in provided dir
 func (file *FileWrapper) Fetch() (err error) {
-	return file.RunCmd("git", "fetch", "--all", "--tags", "--prune", "--prune-tags", "--force")
+	return file.RunCmdWithRetry("git", "fetch", "--all", "--tags", "--prune", "--prune-tags", "--force")
+}
+
+// Clone clones remoteURL into the file's path, creating parent directories as needed. Flags are passed to git clone,
+// e.g. --filter=blob:none
+func (file *FileWrapper) Clone(remoteURL string, flags ...string) (err error) {
+	parent := FileWrapper{Path: filepath.Dir(file.Path), ctx: file.ctx}
+	if !dryRun {
+		if err = os.MkdirAll(parent.Path, 0755); err != nil {
+			return
+		}
+	}
+
+	args := append([]string{"git", "clone"}, flags...)
+	err = parent.RunCmdWithRetry(append(args, remoteURL, filepath.Base(file.Path))...)
+	file.Retries += parent.Retries
+	return
+}
+
+// IsShallow returns true if the file's repo is a shallow clone, missing history and older tags
+func (file *FileWrapper) IsShallow() bool {
+	output, err := file.CmdOutput("git", "rev-parse", "--is-shallow-repository")
+	return err == nil && output == "true"
+}
+
+// Unshallow fetches the complete history and tags of a shallow clone
+func (file *FileWrapper) Unshallow() (err error) {
+	return file.RunCmdWithRetry("git", "fetch", "--unshallow", "--tags")
+}
+
+// IsRemoteURL returns true if value is a clone url (https, ssh or scp-style) rather than a local path
+func IsRemoteURL(value string) bool {
+	if strings.Contains(value, "://") {
+		return true
+	}
+
+	// git@host:owner/name, colons before any slash
+	colon := strings.Index(value, ":")
+	return colon > 0 && !strings.Contains(value[:colon], "/")
+}
+
+// RemoteDir returns the go-style directory of a clone url relative to a workspace, e.g. github.com/org/lib
+func RemoteDir(remoteURL string) string {
+	host, repo := parseRemote(remoteURL)
+	if len(host) == 0 || len(repo) == 0 {
+		return ""
+	}
+
+	if host == AzureHost {
+		return azureGoURL(host + "/" + repo)
+	}
+
+	return host + "/" + repo
 }
 
 // Merge merges other branch into current branch
@@ -55,31 +107,93 @@ func (file *FileWrapper) Merge(otherBranch string) error {
 
 // Pull calls git pull in provided dir
 func (file *FileWrapper) Pull() (err error) {
-	return file.RunCmd("git", "pull")
+	return file.RunCmdWithRetry("git", "pull")
 }
 
-// Push calls git push in provided dir
+// Push calls git push to the push remote in provided dir
 func (file *FileWrapper) Push() (err error) {
-	return file.RunCmd("git", "push", "-u", "origin")
+	return file.RunCmdWithRetry("git", "push", "-u", pushRemote)
 }
 
 // Stash calls git stash in provided dir
 func (file *FileWrapper) Stash() (err error) {
-	return file.RunCmd("git", "stash")
+	if file.InWorktree() {
+		// Stashes are shared with the original working copy
+		return
+	}
+
+	previous := file.stashTop()
+	if err = file.RunCmd("git", "stash", "push", "-m", StashMessage); err != nil {
+		return
+	}
+
+	file.recordNewStash(previous)
+	return
+}
+
+// CommitWIP commits all local changes, untracked files included, to a new branch, then returns to the previous branch
+// (or commit) with a clean working tree. Local changes are left as they were if unable to
+func (file *FileWrapper) CommitWIP(branch, message string) (err error) {
+	if err = file.RunCmd("git", "checkout", "-b", branch); err != nil {
+		return
+	}
+
+	if err = file.Add("-A"); err == nil {
+		err = file.RunCmd("git", "commit", "--no-verify", "-m", message)
+	}
+
+	if err != nil {
+		// Changes come back with the previous branch
+		file.RunCmd("git", "checkout", "-")
+		file.RunCmd("git", "branch", "-D", branch)
+		return
+	}
+
+	return file.RunCmd("git", "checkout", "-")
+}
+
+// StashMessage marks stashes created by gomu, so they can be told apart from the user's own
+const StashMessage = "gomu-stash"
+
+// GomuStashes returns refs of stashes created by gomu, newest first
+func (file *FileWrapper) GomuStashes() (refs []string, err error) {
+	output, err := file.CmdOutput("git", "stash", "list", "--format=%gd%x1f%s")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x1f", 2)
+		if len(fields) == 2 && strings.HasSuffix(fields[1], ": "+StashMessage) {
+			refs = append(refs, fields[0])
+		}
+	}
+
+	return
 }
 
 // StashPop calls git stash pop in provided dir
 func (file *FileWrapper) StashPop() (localChanges bool) {
+	if file.InWorktree() {
+		// Stashes are shared with the original working copy
+		return file.HasChanges()
+	}
+
+	if top, err := file.CmdOutput("git", "stash", "list", "-1", "--format=%s"); err != nil || !strings.HasSuffix(top, ": "+StashMessage) {
+		// Nothing was stashed, don't pop the user's own stash
+		return file.HasChanges()
+	}
+
 	// Hide mod file changes to prevent stash pop issues
-	file.RunCmd("mv", "go.mod", "go.mod.bak")
-	file.RunCmd("mv", "go.sum", "go.sum.bak")
+	file.RenameFile("go.mod", "go.mod.bak")
+	file.RenameFile("go.sum", "go.sum.bak")
 
 	// Pop
 	file.RunCmd("git", "stash", "pop")
 
 	// Hide mod file changes to prevent stash pop issues
-	file.RunCmd("mv", "go.mod.bak", "go.mod")
-	file.RunCmd("mv", "go.sum.bak", "go.sum")
+	file.RenameFile("go.mod.bak", "go.mod")
+	file.RenameFile("go.sum.bak", "go.sum")
 
 	// Handle conflicts
 	localChanges = file.HasChanges()
@@ -89,6 +203,12 @@ func (file *FileWrapper) StashPop() (localChanges bool) {
 
 // HasChanges is true if files are able to be committed
 func (file *FileWrapper) HasChanges() bool {
+	if dryRun {
+		// Can't commit to check, inspect working tree instead
+		status, err := file.CmdOutput("git", "status", "--porcelain")
+		return err == nil && len(status) > 0
+	}
+
 	file.Add(".")
 
 	if file.Commit("revert me") == nil {
@@ -99,6 +219,12 @@ func (file *FileWrapper) HasChanges() bool {
 	return false
 }
 
+// HasChangesIn is true if any files within path differ from the last commit, including untracked files
+func (file *FileWrapper) HasChangesIn(path string) bool {
+	status, err := file.CmdOutput("git", "status", "--porcelain", "--", path)
+	return err == nil && len(status) > 0
+}
+
 // Add calls git add on each filename proved in provided dir
 func (file *FileWrapper) Add(filename ...string) (err error) {
 	var args = []string{"git", "add"}
@@ -112,12 +238,39 @@ func (file *FileWrapper) Commit(message string) (err error) {
 	return file.RunCmd("git", "commit", "-m", message)
 }
 
+// SignedCommit calls git commit with provided message, signed with key, in provided dir
+func (file *FileWrapper) SignedCommit(message, key string) (err error) {
+	return file.RunCmd("git", "commit", "-S"+key, "-m", message)
+}
+
 // Reset calls git reset with provided args in provieded in provided dir
 func (file *FileWrapper) Reset(args ...string) (err error) {
 	params := append([]string{"git", "reset"}, args...)
 	return file.RunCmd(params...)
 }
 
+// AheadBehind returns the number of commits HEAD is ahead of and behind its upstream branch, as of the last fetch.
+// Returns an error if there's no upstream
+func (file *FileWrapper) AheadBehind() (ahead, behind int, err error) {
+	output, err := file.CmdOutput("git", "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return
+	}
+
+	counts := strings.Fields(output)
+	if len(counts) != 2 {
+		err = fmt.Errorf("unexpected rev-list output %q", output)
+		return
+	}
+
+	if ahead, err = strconv.Atoi(counts[0]); err != nil {
+		return
+	}
+
+	behind, err = strconv.Atoi(counts[1])
+	return
+}
+
 // CurrentBranch returns current branch for a given file or an error if it can't be determined
 func (file *FileWrapper) CurrentBranch() (branch string, err error) {
 	branch, err = file.CmdOutput("git", "branch", "--show-current")
@@ -125,94 +278,250 @@ func (file *FileWrapper) CurrentBranch() (branch string, err error) {
 	return
 }
 
-// AddSecret will set a secret for the repository
-func (file *FileWrapper) AddSecret(name, secret string) (err error) {
-	var comps = strings.Split(file.GetGoURL(), "/")
+// HeadCommit returns the sha of the current commit
+func (file *FileWrapper) HeadCommit() (sha string) {
+	sha, _ = file.CmdOutput("git", "rev-parse", "HEAD")
+	return
+}
 
-	switch comps[0] {
-	case "github.com":
-		// Supported
-	default:
-		// Not Supported
-		err = fmt.Errorf("%s currently not supported for secrets", comps[0])
+// VerifyTag checks the gpg signature of tag, returning the fingerprints and key id of the key which signed it.
+// Returns an error if the tag isn't signed, the signature is bad, or the key is unknown, expired or revoked
+func (file *FileWrapper) VerifyTag(tag string) (keys []string, err error) {
+	output, exitCode, err := file.CmdCombinedOutput("git", "verify-tag", "--raw", tag)
+	if err != nil {
 		return
 	}
 
-	var apiURL = "https://api." + comps[0]
-	var resource = "/repos/" + strings.Join(comps[1:], "/") + "/actions/secrets/" + name
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != "[GNUPG:]" {
+			continue
+		}
+
+		switch fields[1] {
+		case "VALIDSIG":
+			// Signing key fingerprint, then primary key fingerprint last
+			keys = append(keys, fields[2], fields[len(fields)-1])
+		case "GOODSIG":
+			keys = append(keys, fields[2])
+		case "NO_PUBKEY":
+			err = fmt.Errorf("tag %s is signed by unknown key %s", tag, fields[2])
+		case "BADSIG":
+			err = fmt.Errorf("tag %s has a bad signature", tag)
+		case "EXPKEYSIG", "REVKEYSIG":
+			err = fmt.Errorf("tag %s is signed by expired or revoked key %s", tag, fields[2])
+		}
+	}
+
+	if err == nil && (exitCode != 0 || len(keys) == 0) {
+		err = fmt.Errorf("tag %s isn't signed", tag)
+	}
+
+	if err != nil {
+		keys = nil
+	}
+
+	return
+}
+
+// ClosePullRequest closes pull request number on the file's remote
+func (file *FileWrapper) ClosePullRequest(number int) (err error) {
+	provider, repo, err := file.Provider()
+	if err != nil {
+		return
+	}
 
-	var u *url.URL
-	if u, err = url.ParseRequestURI(apiURL); err != nil {
-		err = fmt.Errorf("Unable to parse url %s", apiURL)
+	if dryRun {
+		file.DryRun("Close " + provider.Name() + " pull request #" + strconv.Itoa(number) + " on " + repo)
 		return
 	}
 
-	// Get auth token
-	authObject, err := getAuth()
+	return provider.ClosePR(repo, number)
+}
+
+// UpdatePullRequest replaces the title and body of pull request number on the file's remote
+func (file *FileWrapper) UpdatePullRequest(number int, title, body string) (err error) {
+	provider, repo, err := file.Provider()
 	if err != nil {
-		// Get new creds
-		return fmt.Errorf("needs github credentials for PR")
+		return
 	}
 
-	file.Output("Getting encryption key...")
-	id, key, err := authObject.GetPublicKey(file.GetGoURL())
+	if dryRun {
+		file.DryRun("Update " + provider.Name() + " pull request #" + strconv.Itoa(number) + " on " + repo)
+		return
+	}
+
+	return provider.UpdatePR(repo, number, title, body)
+}
+
+// EnableAutoMerge merges pull request pr on the file's remote with method once checks pass
+func (file *FileWrapper) EnableAutoMerge(pr PRResponse, method string) (err error) {
+	provider, repo, err := file.Provider()
+	if err != nil {
+		return
+	}
+
+	if dryRun {
+		file.DryRun("Enable " + method + " auto-merge for " + provider.Name() + " pull request #" + strconv.Itoa(pr.Number) + " on " + repo)
+		return
+	}
+
+	return provider.EnableAutoMerge(repo, pr, method)
+}
+
+// CheckStatus returns the aggregate state of checks on pull request pr on the file's remote
+func (file *FileWrapper) CheckStatus(pr PRResponse) (state string, err error) {
+	provider, repo, err := file.Provider()
 	if err != nil {
-		file.Output("Error getting public key :(")
-		err = fmt.Errorf("Unable get github public key")
 		return
 	}
 
-	file.Output("Encrypting secret...")
-	encrypted, err := authObject.Encrypt(secret, key)
+	if dryRun {
+		// Nothing was opened to check
+		return CheckSuccess, nil
+	}
+
+	return provider.CheckStatus(repo, pr)
+}
+
+// MergePR merges pull request pr on the file's remote with method
+func (file *FileWrapper) MergePR(pr PRResponse, method string) (err error) {
+	provider, repo, err := file.Provider()
 	if err != nil {
-		err = fmt.Errorf("Unable to encrypt secret")
 		return
 	}
 
-	post := &secretRequest{Encrypted: encrypted, KeyID: id}
-	data, err := json.Marshal(post)
+	if dryRun {
+		file.DryRun("Merge (" + method + ") " + provider.Name() + " pull request #" + strconv.Itoa(pr.Number) + " on " + repo)
+		return
+	}
+
+	return provider.MergePR(repo, pr, method)
+}
+
+// CreateRelease publishes a release with notes for tag on the file's push remote, where tags are pushed, returning its url
+func (file *FileWrapper) CreateRelease(tag, notes string) (releaseURL string, err error) {
+	host, repo := file.Remote()
+	provider, err := ProviderFor(host)
 	if err != nil {
-		err = fmt.Errorf("Unable to parse secret request")
 		return
 	}
 
-	// Make request
-	u.Path = resource
-	urlStr := u.String()
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", urlStr, bytes.NewBuffer(data))
+	if dryRun {
+		file.DryRun("Publish " + provider.Name() + " release " + tag + " on " + repo + "\n" + notes)
+		return
+	}
+
+	return provider.CreateRelease(repo, tag, notes)
+}
+
+// UploadReleaseAsset attaches the file at path, relative to the file's path, to the release of tag on the push remote,
+// returning its download url
+func (file *FileWrapper) UploadReleaseAsset(tag, path string) (assetURL string, err error) {
+	host, repo := file.Remote()
+	provider, err := ProviderFor(host)
 	if err != nil {
 		return
 	}
 
-	req.Header.Add("Authorization", "token "+authObject.Token)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
+	name := filepath.Base(path)
+	if dryRun {
+		file.DryRun("Upload " + name + " to " + provider.Name() + " release " + tag + " on " + repo)
+		return
+	}
 
-	// Execute Request
-	file.Output("Setting repository secret...")
-	resp, err := client.Do(req)
+	data, err := ioutil.ReadFile(file.resolve(path))
 	if err != nil {
 		return
 	}
-	resp.Body.Close()
 
-	// Return status
-	if resp.StatusCode == 200 {
-		file.Output("Successfully set repository secret!")
-	} else {
-		err = fmt.Errorf("Http error %d", resp.StatusCode)
+	return provider.UploadReleaseAsset(repo, tag, name, data)
+}
+
+// AddSecret will set a secret for the repository
+func (file *FileWrapper) AddSecret(name, secret string) (err error) {
+	return file.AddSecrets(map[string]string{name: secret})
+}
+
+// CreateRepository creates repo (owner/name) on host for the file, private or public, returning its clone url
+func (file *FileWrapper) CreateRepository(host, repo string, private bool) (cloneURL string, err error) {
+	provider, err := ProviderFor(host)
+	if err != nil {
+		return
+	}
+
+	github, ok := provider.(*gitHubProvider)
+	if !ok {
+		err = fmt.Errorf("%s currently not supported for creating repositories", provider.Name())
+		return
+	}
+
+	if dryRun {
+		file.DryRun("Create " + provider.Name() + " repository " + repo)
+		return "https://" + host + "/" + repo + ".git", nil
+	}
+
+	return github.createRepository(repo, private)
+}
+
+// AddSecrets creates or updates actions secrets of the repository, keyed by name. Values are encrypted with the
+// repository's public key, so they're never sent in plain text
+func (file *FileWrapper) AddSecrets(secrets map[string]string) (err error) {
+	provider, repo, err := file.Provider()
+	if err != nil {
+		return
+	}
+
+	github, ok := provider.(*gitHubProvider)
+	if !ok {
+		err = fmt.Errorf("%s currently not supported for secrets", provider.Name())
+		return
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	gosort.Strings(names)
+
+	if dryRun {
+		for _, name := range names {
+			file.DryRun("PUT " + github.apiURL(repo, "/actions/secrets/"+name) + " (secret " + name + ")")
+		}
+		return
 	}
 
+	file.Output("Getting encryption key...")
+	id, key, err := github.secretsPublicKey(repo)
+	if err != nil {
+		err = fmt.Errorf("unable to get public key of %s: %v", repo, err)
+		return
+	}
+
+	for _, name := range names {
+		var encrypted string
+		if encrypted, err = encryptSecret(secrets[name], key); err != nil {
+			err = fmt.Errorf("unable to encrypt secret %s: %v", name, err)
+			return
+		}
+
+		file.Output("Setting repository secret " + name + "...")
+		if err = github.setSecret(repo, name, encrypted, id); err != nil {
+			err = fmt.Errorf("unable to set secret %s: %v", name, err)
+			return
+		}
+	}
+
+	file.Output("Successfully set repository secrets!")
 	return
 }
 
 // AddGitWorkflow will set an example yml file for the repo
 func (file *FileWrapper) AddGitWorkflow(exampleYmlPath string) (err error) {
 	// Get source dir and template
-	sourceDir, ymlTemplate := path.Split(exampleYmlPath)
+	sourceDir, ymlTemplate := filepath.Split(exampleYmlPath)
 	ymlSource := &FileWrapper{Path: sourceDir}
-	templateSouce := path.Join(ymlSource.AbsPath(), ymlTemplate)
+	templateSouce := filepath.Join(ymlSource.AbsPath(), ymlTemplate)
 
 	// Ignore auto tag for un-tagged libs
 	if ymlTemplate == "auto-tag.yml" {
@@ -225,13 +534,12 @@ func (file *FileWrapper) AddGitWorkflow(exampleYmlPath string) (err error) {
 
 	// Prep workflow dir
 	workflowPath := path.Join(".github", "workflows")
-	file.RunCmd("mkdir", ".github")
-	file.RunCmd("mkdir", workflowPath)
+	file.MakeDir(workflowPath)
 	newWorkflow := path.Join(workflowPath, ymlTemplate)
 
 	file.Output("Copying " + exampleYmlPath + " to " + workflowPath + "...")
 	// Copy example yml file to workflow dir
-	if file.RunCmd("cp", templateSouce, newWorkflow) != nil {
+	if file.CopyFile(templateSouce, newWorkflow) != nil {
 		err = fmt.Errorf("Unable to copy %s to %s", exampleYmlPath, workflowPath)
 		return
 	}
@@ -252,36 +560,22 @@ func (file *FileWrapper) AddGitWorkflow(exampleYmlPath string) (err error) {
 	return
 }
 
-// PullRequest opens a PR for the specified url on the specified branch
-func (file *FileWrapper) PullRequest(title, message, branch, target string) (status *PRResponse, err error) {
+// PullRequest opens a PR for the specified url on the specified branch, as a draft if requested
+func (file *FileWrapper) PullRequest(title, message, branch, target string, draft bool) (status *PRResponse, err error) {
 	if branch == target {
 		err = fmt.Errorf("Cannot create PR from " + branch + " to " + target)
 		return
 	}
 
-	if err = file.RunCmd("git", "push", "-u", "origin", branch); err != nil {
+	if err = file.RunCmdWithRetry("git", "push", "-u", pushRemote, branch); err != nil {
 		err = fmt.Errorf("Unable to set upstream for branch " + branch + " :( Check repo permissions?")
 		return
 	}
 
-	// Get git host
-	comps := strings.Split(file.GetGoURL(), "/")
-	switch comps[0] {
-	case "github.com":
-		// Supported
-	default:
-		// Not Supported
-		err = fmt.Errorf("%s currently not supported for pull requests", comps[0])
-		return
-	}
-
-	// GitHub api url
-	apiURL := "https://api." + comps[0]
-	resource := "/repos/" + strings.Join(comps[1:], "/") + "/pulls"
-
-	var u *url.URL
-	if u, err = url.ParseRequestURI(apiURL); err != nil {
-		err = fmt.Errorf("Unable to parse url %s", apiURL)
+	// Get git host, opening pull requests of forks on the upstream repo
+	host, repo, fork := file.Upstream()
+	provider, err := ProviderFor(host)
+	if err != nil {
 		return
 	}
 
@@ -294,66 +588,98 @@ func (file *FileWrapper) PullRequest(title, message, branch, target string) (sta
 		}
 	}
 
-	post := &prRequest{title, message, branch, target}
-	data, err := json.Marshal(post)
-	if err != nil {
-		err = fmt.Errorf("Unable to parse pull request params")
-		return
+	post := PRRequest{Title: title, Body: message, Head: branch, Base: target, Draft: draft}
+	if fork {
+		_, post.HeadRepo = file.Remote()
 	}
 
-	// Get auth token
-	authObject, err := getAuth()
-	if err != nil {
-		err = fmt.Errorf("needs github credentials for PR")
+	if dryRun {
+		// Show the request that would have been made
+		kind := "pull request"
+		if draft {
+			kind = "draft " + kind
+		}
+		head := branch
+		if fork {
+			head = post.HeadRepo + ":" + branch
+		}
+		file.DryRun("Open " + provider.Name() + " " + kind + " on " + repo + " " + head + " => " + target + "\n" + title + "\n" + message)
+		status = &PRResponse{URL: "https://" + host + "/" + repo}
 		return
 	}
 
-	// Make request
-	u.Path = resource
-	urlStr := u.String()
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", urlStr, bytes.NewBuffer(data))
-	if err != nil {
+	err = file.retry(provider.Name()+" pull request", func() (retryable bool, err error) {
+		status, err = provider.CreatePR(repo, post)
+		return err != nil && status != nil && retryableStatus(status.HTTPStatus), err
+	})
+	if status == nil {
 		return
 	}
 
-	req.Header.Add("Authorization", "token "+authObject.Token)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Content-Type", "application/json")
+	if status.HTTPStatus >= 300 && len(status.Errors) > 0 {
+		file.Output(fmt.Sprintf("Http Error %d: %s", status.HTTPStatus, status.Errors[0].Message))
+	}
 
-	// Execute Request
-	resp, err := client.Do(req)
-	if err != nil {
+	if status.HTTPStatus == 401 {
+		// Bad credentials.. clear file
+		usr, _ := user.Current()
+		file.RemoveFile(filepath.Join(usr.HomeDir, configName))
+		file.Output("Bad credentials cleared.")
+		// Try again
+		file.PullRequest(title, message, branch, target, draft)
+	}
+
+	return
+}
+
+// PushBlocked returns true if the provider's protection rules prevent pushing directly to branch.
+// Only checked with saved credentials, pushes shouldn't ask for new ones
+func (file *FileWrapper) PushBlocked(branch string) (blocked bool, err error) {
+	host, repo := file.Remote()
+	if !hasSavedAuth(host) {
+		err = fmt.Errorf("no saved %s credentials", host)
 		return
 	}
 
-	// Read response
-	var body []byte
-	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+	provider, err := ProviderFor(host)
+	if err != nil {
 		return
 	}
-	resp.Body.Close()
 
-	payload := &PRResponse{}
-	err = json.Unmarshal(body, payload)
+	return provider.PushBlocked(repo, branch)
+}
 
-	// Return status
-	payload.HTTPStatus = resp.StatusCode
-	status = payload
-	if status.HTTPStatus >= 300 {
-		err = fmt.Errorf("Http error %d", status.HTTPStatus)
-		if len(status.Errors) > 0 {
-			file.Output(fmt.Sprintf("Http Error %d: %s", status.HTTPStatus, status.Errors[0].Message))
+// DefaultBranch returns the default branch of the file's remote, or master if it can't be determined
+func (file *FileWrapper) DefaultBranch() (branch string) {
+	if provider, repo, err := file.Provider(); err == nil {
+		if branch, err = provider.GetDefaultBranch(repo); err == nil && len(branch) > 0 {
+			return
 		}
 	}
 
-	if status.HTTPStatus == 401 {
-		// Bad credentials.. clear file
-		usr, _ := user.Current()
-		file.RunCmd("rm", path.Join(usr.HomeDir, configName))
-		file.Output("Bad credentials cleared.")
-		// Try again
-		file.PullRequest(title, message, branch, target)
+	return "master"
+}
+
+// RemoteBranchExists returns true if branch exists on the remote pull requests are opened on, the upstream remote
+// if the push remote is a fork
+func (file *FileWrapper) RemoteBranchExists(branch string) (exists bool, err error) {
+	remote := pushRemote
+	if _, _, fork := file.Upstream(); fork {
+		remote = UpstreamRemote
+	}
+
+	// Exits 2 if no refs match
+	_, exitCode, err := file.CmdResult("git", "ls-remote", "--exit-code", "--heads", remote, branch)
+	if err != nil {
+		return
+	}
+
+	switch exitCode {
+	case 0:
+		exists = true
+	case 2:
+	default:
+		err = fmt.Errorf("unable to list branches of %s, exit code %d", remote, exitCode)
 	}
 
 	return
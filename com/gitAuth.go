@@ -2,6 +2,9 @@ package com
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,12 +12,23 @@ import (
 	"net/url"
 	"os"
 	"os/user"
-	"path"
+	"path/filepath"
 	"strings"
+
+	"golang.org/x/crypto/nacl/box"
 )
 
 var configName = ".gomurc"
 
+// Global, false if there's nobody to ask for missing credentials
+var interactive = true
+
+// SetInteractive sets globally whether missing credentials are asked for on stdin. If not, they fail instead, e.g. in
+// a server where reading stdin would block every run
+func SetInteractive(enabled bool) {
+	interactive = enabled
+}
+
 type secretRequest struct {
 	Encrypted string `json:"encrypted_value,omitempty"`
 
@@ -25,17 +39,24 @@ type secretRequest struct {
 	Errors     []PRResponseError `json:"errors,omitempty"`
 }
 
-type prRequest struct {
+// PRRequest represents the parameters of a new pull request
+type PRRequest struct {
 	Title string `json:"title"`
 	Body  string `json:"body"`
 	Head  string `json:"head"`
 	Base  string `json:"base"`
+	Draft bool   `json:"draft,omitempty"`
+	// HeadRepo (owner/name) the head branch was pushed to if a fork, empty if the same repo
+	HeadRepo string `json:"-"`
 }
 
 // PRResponse returns the value of github's api response
 type PRResponse struct {
 	HTTPStatus int    `json:"httpStatus,omitempty"`
 	URL        string `json:"html_url,omitempty"`
+	Number     int    `json:"number,omitempty"`
+	Title      string `json:"title,omitempty"`
+	NodeID     string `json:"node_id,omitempty"` // GraphQL id, github only
 
 	Errors []PRResponseError `json:"errors,omitempty"`
 }
@@ -45,29 +66,52 @@ type PRResponseError struct {
 	Message string `json:"message,omitempty"`
 }
 
+// Sources credentials are loaded from, in order of precedence
+const (
+	AuthSourceEnv  = "env"  // GITHUB_TOKEN or GH_TOKEN, and host specific variables
+	AuthSourceGH   = "gh"   // gh cli credentials
+	AuthSourceFile = "file" // Credentials saved to ~/.gomurc
+)
+
 // GitAuthObject represents authentication credentials
 type GitAuthObject struct {
 	User  string `json:"user"`
 	Token string `json:"token"`
+
+	// Source the github credentials were loaded from
+	Source string `json:"-"`
+
+	// Credentials for git hosts other than github, keyed by host
+	Hosts map[string]HostAuth `json:"hosts,omitempty"`
 }
 
-// LoadAuth will Read credentials from disk
+// HostAuth represents authentication credentials for a single git host
+type HostAuth struct {
+	User  string `json:"user,omitempty"`
+	Token string `json:"token"`
+}
+
+// LoadAuth will read github credentials from the environment (GITHUB_TOKEN or GH_TOKEN),
+// the gh cli, or disk, in that order. Only saved credentials need a username
 func LoadAuth() (authObject GitAuthObject, err error) {
-	usr, err := user.Current()
-	if err != nil {
+	if token := envToken("GITHUB_TOKEN", "GH_TOKEN"); len(token) > 0 {
+		authObject.User = os.Getenv("GITHUB_ACTOR")
+		authObject.Token = token
+		authObject.Source = AuthSourceEnv
 		return
 	}
 
-	file, err := ioutil.ReadFile(path.Join(usr.HomeDir, configName))
-	if err != nil {
+	if token := ghToken("github.com"); len(token) > 0 {
+		authObject.Token = token
+		authObject.Source = AuthSourceGH
 		return
 	}
 
-	err = json.Unmarshal(file, &authObject)
-	if err != nil {
+	if authObject, err = loadAuthObject(); err != nil {
 		return
 	}
 
+	authObject.Source = AuthSourceFile
 	if len(authObject.User) == 0 || len(authObject.Token) == 0 {
 		err = fmt.Errorf("auth object missing credentials")
 		return
@@ -76,6 +120,44 @@ func LoadAuth() (authObject GitAuthObject, err error) {
 	return
 }
 
+// envToken returns the first non-empty environment variable of names
+func envToken(names ...string) string {
+	for _, name := range names {
+		if token := strings.TrimSpace(os.Getenv(name)); len(token) > 0 {
+			return token
+		}
+	}
+
+	return ""
+}
+
+// ghToken returns the gh cli token for host, empty if gh isn't installed or logged in
+func ghToken(host string) string {
+	cmd := Command{Name: "gh", Args: []string{"auth", "token", "--hostname", host}, Env: Environ()}
+	output, err := GlobalRunner().Output(context.Background(), cmd)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// loadAuthObject reads credentials from disk without validating them
+func loadAuthObject() (authObject GitAuthObject, err error) {
+	usr, err := user.Current()
+	if err != nil {
+		return
+	}
+
+	file, err := ioutil.ReadFile(filepath.Join(usr.HomeDir, configName))
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(file, &authObject)
+	return
+}
+
 // Save credentials to disk
 func (authObject *GitAuthObject) Save() (err error) {
 	data, err := json.Marshal(authObject)
@@ -88,13 +170,47 @@ func (authObject *GitAuthObject) Save() (err error) {
 		return
 	}
 
-	return ioutil.WriteFile(path.Join(usr.HomeDir, configName), data, os.ModePerm)
+	// Holds tokens and app passwords, so only the user may read it
+	if err = os.MkdirAll(usr.HomeDir, 0700); err != nil {
+		return
+	}
+
+	configPath := filepath.Join(usr.HomeDir, configName)
+	if err = ioutil.WriteFile(configPath, data, 0600); err != nil {
+		return
+	}
+
+	// Files saved before were readable by anyone, and WriteFile keeps the mode of existing files
+	return os.Chmod(configPath, 0600)
 }
 
-// Encrypt will salt a secret using sodium lib, and return the encrypted value
+// Encrypt will seal a secret for the base64 encoded public key of a repo, and return the base64 encrypted value
 func (authObject *GitAuthObject) Encrypt(secret, key string) (encrypted string, err error) {
-	// TODO: Sodium encrypt https://help.github.com/actions/automating-your-workflow-with-github-actions/creating-and-using-encrypted-secrets
+	return encryptSecret(secret, key)
+}
+
+// encryptSecret seals secret in a libsodium sealed box for the base64 encoded public key, as github requires
+// https://docs.github.com/en/rest/actions/secrets#create-or-update-a-repository-secret
+func encryptSecret(secret, key string) (encrypted string, err error) {
+	publicKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		err = fmt.Errorf("unable to decode public key: %v", err)
+		return
+	}
+
+	if len(publicKey) != 32 {
+		err = fmt.Errorf("invalid public key length %d, expected 32", len(publicKey))
+		return
+	}
+
+	var recipient [32]byte
+	copy(recipient[:], publicKey)
+	sealed, err := box.SealAnonymous(nil, []byte(secret), &recipient, rand.Reader)
+	if err != nil {
+		return
+	}
 
+	encrypted = base64.StdEncoding.EncodeToString(sealed)
 	return
 }
 
@@ -122,7 +238,7 @@ func (authObject *GitAuthObject) GetPublicKey(goURL string) (id, key string, err
 	}
 
 	// Check auth token
-	if err != nil || len(authObject.User) == 0 || len(authObject.Token) == 0 {
+	if err != nil || len(authObject.Token) == 0 {
 		// Get new creds
 		err = fmt.Errorf("Unable to parse github username and token")
 		return
@@ -131,7 +247,6 @@ func (authObject *GitAuthObject) GetPublicKey(goURL string) (id, key string, err
 	// Make request
 	u.Path = resource
 	urlStr := u.String()
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
 		return
@@ -141,7 +256,7 @@ func (authObject *GitAuthObject) GetPublicKey(goURL string) (id, key string, err
 	req.Header.Add("Content-Type", "application/json")
 
 	// Execute Request
-	resp, err := client.Do(req)
+	resp, err := apiClient.Do(req)
 	if err != nil {
 		return
 	}
@@ -171,7 +286,7 @@ func (authObject *GitAuthObject) GetPublicKey(goURL string) (id, key string, err
 // Setup configures credentials from user input
 // TODO: Move this to CLI? Need to handle differently for plugin...
 func (authObject *GitAuthObject) Setup() (err error) {
-	if logLevel <= SILENT {
+	if logLevel <= SILENT || !interactive {
 		err = fmt.Errorf("unable to read credentials. auth token or user name not found")
 		return
 	}
@@ -224,6 +339,121 @@ func (authObject *GitAuthObject) Setup() (err error) {
 	return
 }
 
+// SetupHost configures a token for a non-github host from user input.
+// Hosts which authenticate with app passwords (bitbucket) also ask for a username
+func (authObject *GitAuthObject) SetupHost(host string) (err error) {
+	if logLevel <= SILENT || !interactive {
+		err = fmt.Errorf("unable to read credentials. auth token for %s not found", host)
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	var user string
+	for err == nil && len(user) == 0 && hostNeedsUser(host) {
+		fmt.Print("Enter " + host + " username: ")
+		user, err = reader.ReadString('\n')
+		user = strings.TrimSpace(user)
+	}
+
+	tokenName := "personal access token"
+	if hostNeedsUser(host) {
+		tokenName = "app password"
+	}
+
+	var token string
+	for err == nil && len(token) == 0 {
+		fmt.Print("Enter " + host + " " + tokenName + ": ")
+		token, err = reader.ReadString('\n')
+		token = strings.TrimSpace(token)
+	}
+
+	if err != nil {
+		Println("Nevermind then... :(")
+		return
+	}
+
+	if authObject.Hosts == nil {
+		authObject.Hosts = make(map[string]HostAuth)
+	}
+	authObject.Hosts[host] = HostAuth{User: user, Token: token}
+
+	if err = authObject.Save(); err != nil {
+		fmt.Println("Error saving credentials :(\n", err)
+	} else {
+		fmt.Println("Saved Credentials!")
+	}
+
+	return
+}
+
+// LoadHostAuth will read credentials for a non-github host from the environment
+// (GITLAB_TOKEN, GITEA_TOKEN, AZURE_DEVOPS_EXT_PAT, or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD), or disk
+func LoadHostAuth(host string) (auth HostAuth, err error) {
+	if hostNeedsUser(host) {
+		auth = HostAuth{User: os.Getenv("BITBUCKET_USERNAME"), Token: envToken("BITBUCKET_APP_PASSWORD")}
+	} else if providerName(host) == ProviderGitLab {
+		auth = HostAuth{Token: envToken("GITLAB_TOKEN")}
+	} else if providerName(host) == ProviderGitea {
+		auth = HostAuth{Token: envToken("GITEA_TOKEN")}
+	} else if host == AzureHost {
+		// Same variable as the az devops cli
+		auth = HostAuth{Token: envToken("AZURE_DEVOPS_EXT_PAT", "AZURE_DEVOPS_TOKEN")}
+	}
+
+	if len(auth.Token) > 0 && (!hostNeedsUser(host) || len(auth.User) > 0) {
+		return
+	}
+
+	authObject, err := loadAuthObject()
+	if err != nil {
+		return
+	}
+
+	auth = authObject.Hosts[host]
+	if len(auth.Token) == 0 || (hostNeedsUser(host) && len(auth.User) == 0) {
+		err = fmt.Errorf("auth object missing credentials for %s", host)
+	}
+
+	return
+}
+
+// hostNeedsUser returns true if host authenticates with a username and app password rather than a token
+func hostNeedsUser(host string) bool {
+	return host == "bitbucket.org"
+}
+
+// hasSavedAuth returns true if credentials for host can be loaded without asking for new ones
+func hasSavedAuth(host string) bool {
+	if host == "github.com" {
+		_, err := LoadAuth()
+		return err == nil
+	}
+
+	_, err := LoadHostAuth(host)
+	return err == nil
+}
+
+// getHostAuth returns saved credentials for a non-github host, or asks for new credentials
+func getHostAuth(host string) (auth HostAuth, err error) {
+	if auth, err = LoadHostAuth(host); err == nil {
+		// Auth is valid
+		return
+	}
+
+	// Ignore missing file, credentials will be created
+	err = nil
+	authObject, _ := loadAuthObject()
+
+	if err = authObject.SetupHost(host); err != nil {
+		err = fmt.Errorf("needs %s credentials for PR", host)
+		return
+	}
+
+	auth = authObject.Hosts[host]
+	return
+}
+
 func getAuth() (authObject GitAuthObject, err error) {
 	if authObject, err = LoadAuth(); err == nil {
 		// Auth is valid
@@ -232,12 +462,12 @@ func getAuth() (authObject GitAuthObject, err error) {
 
 	// Reset err
 	err = nil
-	err = getNewCredentials(authObject)
+	err = getNewCredentials(&authObject)
 
 	return
 }
 
-func getNewCredentials(authObject GitAuthObject) (err error) {
+func getNewCredentials(authObject *GitAuthObject) (err error) {
 	// Get new creds
 	if err = authObject.Setup(); err != nil {
 		return fmt.Errorf("Unable to parse github username and token")
@@ -0,0 +1,69 @@
+package com
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	publicKey, secretKey, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := encryptSecret("secret", base64.StdEncoding.EncodeToString(publicKey[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, ok := box.OpenAnonymous(nil, sealed, publicKey, secretKey)
+	if !ok {
+		t.Fatal("unable to open sealed secret")
+	}
+	if string(opened) != "secret" {
+		t.Errorf("expected %q, got %q", "secret", opened)
+	}
+
+	if _, err = encryptSecret("secret", base64.StdEncoding.EncodeToString(publicKey[:31])); err == nil {
+		t.Error("expected error for a short public key")
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	recordedRepo(t)
+
+	authObject, err := LoadAuth()
+	if err != nil || authObject.Token != "gh-token" || authObject.Source != AuthSourceGH {
+		t.Errorf("expected gh cli token, got %+v (%v)", authObject, err)
+	}
+
+	// The environment takes precedence
+	setEnv(t, "GH_TOKEN", " env-token\n")
+	setEnv(t, "GITHUB_ACTOR", "me")
+	authObject, err = LoadAuth()
+	if err != nil || authObject.Token != "env-token" || authObject.User != "me" || authObject.Source != AuthSourceEnv {
+		t.Errorf("expected GH_TOKEN, got %+v (%v)", authObject, err)
+	}
+}
+
+func TestLoadHostAuth(t *testing.T) {
+	setEnv(t, "GITLAB_TOKEN", "gitlab-token")
+	if auth, err := LoadHostAuth("gitlab.com"); err != nil || auth.Token != "gitlab-token" {
+		t.Errorf("expected GITLAB_TOKEN, got %+v (%v)", auth, err)
+	}
+
+	// App passwords need a username
+	setEnv(t, "BITBUCKET_USERNAME", "me")
+	setEnv(t, "BITBUCKET_APP_PASSWORD", "app-password")
+	if auth, err := LoadHostAuth("bitbucket.org"); err != nil || auth.User != "me" || auth.Token != "app-password" {
+		t.Errorf("expected bitbucket app password, got %+v (%v)", auth, err)
+	}
+}
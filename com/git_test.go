@@ -0,0 +1,284 @@
+package com
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// stubAPI sends api requests to handler instead of the provider, still rate limited
+func stubAPI(t *testing.T, handler func(req *http.Request) (status int, body string)) {
+	transport := apiClient.Transport.(*rateLimitTransport)
+	previous := transport.base
+	t.Cleanup(func() { transport.base = previous })
+
+	transport.base = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		status, body := handler(req)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+// unsetEnv unsets names until the test finishes
+func unsetEnv(t *testing.T, names ...string) {
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			t.Cleanup(func() { os.Setenv(name, value) })
+		}
+	}
+}
+
+// recordedRepo returns a file whose commands are recorded, with a github origin and credentials from the gh cli
+func recordedRepo(t *testing.T) (file *FileWrapper, recorder *Recorder) {
+	unsetEnv(t, "GITHUB_TOKEN", "GH_TOKEN")
+
+	recorder = &Recorder{}
+	recorder.Reply("git remote get-url origin", Reply{Stdout: "git@github.com:org/a.git\n"})
+	recorder.Reply("git remote get-url upstream", Reply{ExitCode: 2})
+	recorder.Reply("gh auth token --hostname github.com", Reply{Stdout: "gh-token\n"})
+
+	// Credentials are loaded without a file, through the global runner
+	SetRunner(recorder)
+	t.Cleanup(func() { SetRunner(nil) })
+
+	dir, err := ioutil.TempDir("", "gomu-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return &FileWrapper{Path: dir}, recorder
+}
+
+func TestPullRequest(t *testing.T) {
+	file, recorder := recordedRepo(t)
+
+	var request PRRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://api.github.com/repos/org/a/pulls" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "token gh-token" {
+			t.Errorf("expected gh cli token, got %q", auth)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"html_url": "https://github.com/org/a/pull/7", "number": 7}`
+	})
+
+	status, err := file.PullRequest("Sync deps", "Updated deps", "sync", "master", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !recorder.Ran("git push -u origin sync") {
+		t.Errorf("expected branch to be pushed, ran %v", recorder.Commands())
+	}
+	if request.Title != "Sync deps" || request.Body != "Updated deps" || request.Head != "sync" || request.Base != "master" || !request.Draft {
+		t.Errorf("unexpected pull request %+v", request)
+	}
+	if status.URL != "https://github.com/org/a/pull/7" || status.Number != 7 {
+		t.Errorf("unexpected response %+v", status)
+	}
+}
+
+func TestPullRequestPushFails(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git push", Reply{ExitCode: 1})
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		return http.StatusInternalServerError, ""
+	})
+
+	if _, err := file.PullRequest("Sync deps", "Updated deps", "sync", "master", false); err == nil {
+		t.Error("expected error when the branch can't be pushed")
+	}
+}
+
+func TestPullRequestSameBranch(t *testing.T) {
+	file, recorder := recordedRepo(t)
+
+	if _, err := file.PullRequest("Sync deps", "Updated deps", "master", "master", false); err == nil {
+		t.Error("expected error opening a pull request from master to master")
+	}
+	if len(recorder.Commands()) > 0 {
+		t.Errorf("expected no commands, ran %v", recorder.Commands())
+	}
+}
+
+func TestPullRequestFromFork(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git remote get-url upstream", Reply{Stdout: "https://github.com/upstream/a.git\n"})
+
+	var request PRRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://api.github.com/repos/upstream/a/pulls" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"html_url": "https://github.com/upstream/a/pull/9", "number": 9}`
+	})
+
+	if _, err := file.PullRequest("Sync deps", "Updated deps", "sync", "master", false); err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.Ran("git push -u origin sync") || request.Head != "org:sync" {
+		t.Errorf("expected fork's branch to be opened on upstream, got head %q", request.Head)
+	}
+}
+
+func TestPushRemote(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git remote get-url fork", Reply{Stdout: "git@github.com:me/a.git\n"})
+	SetRemote("fork")
+	defer SetRemote("")
+
+	if err := file.Push(); err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.Ran("git push -u fork") {
+		t.Errorf("expected push to the fork remote, ran %v", recorder.Commands())
+	}
+
+	// The upstream remote doesn't exist, so origin isn't forked from
+	if host, repo, fork := file.Upstream(); host != "github.com" || repo != "me/a" || fork {
+		t.Errorf("expected pull requests on the push remote me/a, got %s/%s (fork %v)", host, repo, fork)
+	}
+}
+
+func TestPullRequestDryRun(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	SetDryRun(true)
+	t.Cleanup(func() { SetDryRun(false) })
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		return http.StatusInternalServerError, ""
+	})
+
+	status, err := file.PullRequest("Sync deps", "Updated deps", "sync", "master", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder.Ran("git push") {
+		t.Errorf("expected branch not to be pushed, ran %v", recorder.Commands())
+	}
+	if status.URL != "https://github.com/org/a" {
+		t.Errorf("expected repo url, got %+v", status)
+	}
+}
+
+func TestUpdatePullRequest(t *testing.T) {
+	file, _ := recordedRepo(t)
+
+	var patch map[string]string
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "PATCH" || req.URL.String() != "https://api.github.com/repos/org/a/pulls/7" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&patch); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusOK, `{"number": 7}`
+	})
+
+	if err := file.UpdatePullRequest(7, "Sync deps", "Updated deps\n\nPart of a batch"); err != nil {
+		t.Fatal(err)
+	}
+	if patch["title"] != "Sync deps" || patch["body"] != "Updated deps\n\nPart of a batch" {
+		t.Errorf("unexpected update %v", patch)
+	}
+}
+
+func TestEnableAutoMerge(t *testing.T) {
+	file, _ := recordedRepo(t)
+
+	var variables map[string]string
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		switch req.Method + " " + req.URL.String() {
+		case "GET https://api.github.com/repos/org/a/pulls/7":
+			// The node id is needed when the pr wasn't just opened
+			return http.StatusOK, `{"number": 7, "node_id": "PR_7"}`
+		case "POST https://api.github.com/graphql":
+			var post struct {
+				Variables map[string]string `json:"variables"`
+			}
+			if err := json.NewDecoder(req.Body).Decode(&post); err != nil {
+				t.Error(err)
+			}
+			variables = post.Variables
+			return http.StatusOK, `{"data": {"enablePullRequestAutoMerge": {"clientMutationId": null}}}`
+		}
+
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		return http.StatusNotFound, ""
+	})
+
+	if err := file.EnableAutoMerge(PRResponse{Number: 7}, MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+	if variables["id"] != "PR_7" || variables["method"] != "SQUASH" {
+		t.Errorf("expected squash auto-merge of PR_7, got %v", variables)
+	}
+}
+
+func TestEnableAutoMergeFails(t *testing.T) {
+	file, _ := recordedRepo(t)
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		// Graphql errors are returned with a 200
+		return http.StatusOK, `{"errors": [{"message": "Auto merge is not allowed for this repository"}]}`
+	})
+
+	err := file.EnableAutoMerge(PRResponse{Number: 7, NodeID: "PR_7"}, MergeMethodMerge)
+	if err == nil || err.Error() != "Auto merge is not allowed for this repository" {
+		t.Errorf("expected graphql error, got %v", err)
+	}
+}
+
+func TestVerifyTag(t *testing.T) {
+	const fingerprint = "0123456789ABCDEF0123456789ABCDEF01234567"
+	for _, test := range []struct {
+		name   string
+		reply  Reply
+		keys   []string
+		failed bool
+	}{
+		{"valid", Reply{Stdout: "[GNUPG:] GOODSIG 89ABCDEF01234567 Dev <dev@example.com>\n[GNUPG:] VALIDSIG 1111 2020-01-01 0 4 0 1 10 00 " + fingerprint + "\n"},
+			[]string{"89ABCDEF01234567", "1111", fingerprint}, false},
+		{"unknown key", Reply{Stdout: "[GNUPG:] NO_PUBKEY 89ABCDEF01234567\n", ExitCode: 1}, nil, true},
+		{"bad signature", Reply{Stdout: "[GNUPG:] BADSIG 89ABCDEF01234567 Dev\n", ExitCode: 1}, nil, true},
+		{"unsigned", Reply{Stdout: "error: no signature found\n", ExitCode: 1}, nil, true},
+	} {
+		file, recorder := recordedRepo(t)
+		recorder.Reply("git verify-tag --raw v1.0.0", test.reply)
+
+		keys, err := file.VerifyTag("v1.0.0")
+		if (err != nil) != test.failed || !reflect.DeepEqual(keys, test.keys) {
+			t.Errorf("%s: expected keys %q (failed %v), got %q (%v)", test.name, test.keys, test.failed, keys, err)
+		}
+	}
+}
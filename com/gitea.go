@@ -0,0 +1,266 @@
+package com
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// giteaProvider opens pull requests with the gitea api, used by self-hosted gitea and forgejo servers
+type giteaProvider struct {
+	host string
+}
+
+// giteaPullRequest represents gitea's pull request request and response
+type giteaPullRequest struct {
+	Head  string `json:"head,omitempty"`
+	Base  string `json:"base,omitempty"`
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+
+	Number  int    `json:"number,omitempty"`
+	HTMLURL string `json:"html_url,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+func (provider *giteaProvider) Name() string {
+	return "Gitea"
+}
+
+func (provider *giteaProvider) apiURL(repo, resource string) string {
+	return "https://" + provider.host + "/api/v1/repos/" + repo + resource
+}
+
+func (provider *giteaProvider) headers() (headers map[string]string, err error) {
+	auth, err := getHostAuth(provider.host)
+	if err != nil {
+		return
+	}
+
+	headers = map[string]string{"Authorization": "token " + auth.Token}
+	return
+}
+
+// CreatePR opens a pull request on repo
+func (provider *giteaProvider) CreatePR(repo string, request PRRequest) (status *PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := giteaPullRequest{
+		Head:  forkHead(request),
+		Base:  request.Base,
+		Title: request.Title,
+		Body:  request.Body,
+	}
+	if request.Draft {
+		// Gitea marks work in progress by title
+		post.Title = "WIP: " + post.Title
+	}
+
+	var payload giteaPullRequest
+	status = &PRResponse{}
+	status.HTTPStatus, err = apiRequest("POST", provider.apiURL(repo, "/pulls"), headers, post, &payload)
+
+	status.URL = payload.HTMLURL
+	status.Number = payload.Number
+	status.Title = payload.Title
+	if len(payload.Message) > 0 {
+		status.Errors = append(status.Errors, PRResponseError{Message: payload.Message})
+	}
+
+	return
+}
+
+// ListPRs returns open pull requests on repo
+func (provider *giteaProvider) ListPRs(repo string) (prs []PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload []giteaPullRequest
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/pulls?state=open"), headers, nil, &payload); err != nil {
+		return
+	}
+
+	for _, pr := range payload {
+		prs = append(prs, PRResponse{URL: pr.HTMLURL, Number: pr.Number, Title: pr.Title})
+	}
+
+	return
+}
+
+// GetDefaultBranch returns the default branch of repo
+func (provider *giteaProvider) GetDefaultBranch(repo string) (branch string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	_, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &payload)
+	branch = payload.DefaultBranch
+	return
+}
+
+// ClosePR closes pull request number on repo
+func (provider *giteaProvider) ClosePR(repo string, number int) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	patch := map[string]string{"state": "closed"}
+	_, err = apiRequest("PATCH", provider.apiURL(repo, "/pulls/"+strconv.Itoa(number)), headers, patch, nil)
+	return
+}
+
+// UpdatePR replaces the title and body of pull request number on repo
+func (provider *giteaProvider) UpdatePR(repo string, number int, title, body string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	patch := map[string]string{"title": title, "body": body}
+	_, err = apiRequest("PATCH", provider.apiURL(repo, "/pulls/"+strconv.Itoa(number)), headers, patch, nil)
+	return
+}
+
+// EnableAutoMerge schedules pull request pr to merge with method once its status checks succeed
+func (provider *giteaProvider) EnableAutoMerge(repo string, pr PRResponse, method string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]interface{}{"Do": method, "merge_when_checks_succeed": true}
+	_, err = apiRequest("POST", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)+"/merge"), headers, post, nil)
+	return
+}
+
+// CreateRelease publishes a release for tag on repo
+func (provider *giteaProvider) CreateRelease(repo, tag, notes string) (releaseURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]string{"tag_name": tag, "name": tag, "body": notes}
+
+	var payload struct {
+		HTMLURL string `json:"html_url"`
+	}
+
+	_, err = apiRequest("POST", provider.apiURL(repo, "/releases"), headers, post, &payload)
+	releaseURL = payload.HTMLURL
+	return
+}
+
+// UploadReleaseAsset attaches data as file name to the release of tag on repo
+func (provider *giteaProvider) UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var release struct {
+		ID int `json:"id"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/releases/tags/"+url.PathEscape(tag)), headers, nil, &release); err != nil {
+		return
+	}
+
+	contentType, body, err := multipartFile("attachment", name, data)
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+
+	resource := "/releases/" + strconv.Itoa(release.ID) + "/assets?name=" + url.QueryEscape(name)
+	_, err = uploadRequest("POST", provider.apiURL(repo, resource), contentType, headers, body, &payload)
+	assetURL = payload.BrowserDownloadURL
+	return
+}
+
+// PushBlocked returns true if branch protection doesn't allow the user to push to branch
+func (provider *giteaProvider) PushBlocked(repo, branch string) (blocked bool, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		Protected   bool `json:"protected"`
+		UserCanPush bool `json:"user_can_push"`
+	}
+
+	status, err := apiRequest("GET", provider.apiURL(repo, "/branches/"+url.PathEscape(branch)), headers, nil, &payload)
+	if status == http.StatusNotFound {
+		err = fmt.Errorf("branch %s not found", branch)
+		return
+	} else if err != nil {
+		return
+	}
+
+	return payload.Protected && !payload.UserCanPush, nil
+}
+
+// CheckStatus returns the combined commit status of the head of pr
+func (provider *giteaProvider) CheckStatus(repo string, pr PRResponse) (state string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var pull struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)), headers, nil, &pull); err != nil {
+		return
+	}
+
+	var status struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/commits/"+pull.Head.SHA+"/status"), headers, nil, &status); err != nil {
+		return
+	}
+
+	switch {
+	case status.TotalCount == 0 || status.State == "success" || status.State == "warning":
+		state = CheckSuccess
+	case status.State == "pending":
+		state = CheckPending
+	default:
+		state = CheckFailure
+	}
+
+	return
+}
+
+// MergePR merges pr with method
+func (provider *giteaProvider) MergePR(repo string, pr PRResponse, method string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]string{"Do": method}
+	_, err = apiRequest("POST", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)+"/merge"), headers, post, nil)
+	return
+}
@@ -0,0 +1,74 @@
+package com
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// setProviderHosts maps hosts to providers until the test finishes
+func setProviderHosts(t *testing.T, hosts map[string]string) {
+	if err := SetProviderHosts(hosts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetProviderHosts(map[string]string{}) })
+}
+
+func TestProviderHosts(t *testing.T) {
+	setProviderHosts(t, map[string]string{"git.example.com": ProviderGitea, "code.example.com": ProviderGitLab})
+
+	for host, expected := range map[string]string{
+		"git.example.com":    "Gitea",
+		"code.example.com":   "GitLab",
+		"gitea.example.com":  "Gitea",
+		"gitlab.example.com": "GitLab",
+		"github.com":         "GitHub",
+	} {
+		if provider, err := ProviderFor(host); err != nil || provider.Name() != expected {
+			t.Errorf("expected %s provider for %s, got %v (%v)", expected, host, provider, err)
+		}
+	}
+
+	if _, err := ProviderFor("git.other.com"); err == nil {
+		t.Error("expected unmapped host to have no provider")
+	}
+	if err := SetProviderHosts(map[string]string{"git.example.com": "svn"}); err == nil {
+		t.Error("expected unknown provider to fail")
+	}
+}
+
+func TestGiteaCreatePR(t *testing.T) {
+	setEnv(t, "GITEA_TOKEN", "gitea-token")
+	setProviderHosts(t, map[string]string{"git.example.com": ProviderGitea})
+
+	var request giteaPullRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://git.example.com/api/v1/repos/org/a/pulls" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if auth := req.Header.Get("Authorization"); auth != "token gitea-token" {
+			t.Errorf("expected GITEA_TOKEN, got %q", auth)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"number": 4, "html_url": "https://git.example.com/org/a/pulls/4", "title": "WIP: Sync deps"}`
+	})
+
+	provider, err := ProviderFor("git.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := provider.CreatePR("org/a", PRRequest{Title: "Sync deps", Head: "sync", Base: "master", Draft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Title != "WIP: Sync deps" || request.Head != "sync" || request.Base != "master" {
+		t.Errorf("unexpected pull request %+v", request)
+	}
+	if status.Number != 4 || status.URL != "https://git.example.com/org/a/pulls/4" {
+		t.Errorf("unexpected response %+v", status)
+	}
+}
@@ -0,0 +1,341 @@
+package com
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// gitHubProvider opens pull requests with the github api
+type gitHubProvider struct {
+	host string
+}
+
+func (provider *gitHubProvider) Name() string {
+	return "GitHub"
+}
+
+func (provider *gitHubProvider) apiURL(repo, resource string) string {
+	return "https://api." + provider.host + "/repos/" + repo + resource
+}
+
+func (provider *gitHubProvider) headers() (headers map[string]string, err error) {
+	// Get auth token
+	authObject, err := getAuth()
+	if err != nil {
+		err = fmt.Errorf("needs github credentials for PR")
+		return
+	}
+
+	headers = map[string]string{"Authorization": "token " + authObject.Token}
+	return
+}
+
+// CreatePR opens a pull request on repo
+func (provider *gitHubProvider) CreatePR(repo string, request PRRequest) (status *PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	request.Head = forkHead(request)
+	status = &PRResponse{}
+	status.HTTPStatus, err = apiRequest("POST", provider.apiURL(repo, "/pulls"), headers, request, status)
+	return
+}
+
+// ListPRs returns open pull requests on repo
+func (provider *gitHubProvider) ListPRs(repo string) (prs []PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	_, err = apiRequest("GET", provider.apiURL(repo, "/pulls?state=open"), headers, nil, &prs)
+	return
+}
+
+// GetDefaultBranch returns the default branch of repo
+func (provider *gitHubProvider) GetDefaultBranch(repo string) (branch string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	_, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &payload)
+	branch = payload.DefaultBranch
+	return
+}
+
+// ClosePR closes pull request number on repo
+func (provider *gitHubProvider) ClosePR(repo string, number int) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	patch := map[string]string{"state": "closed"}
+	_, err = apiRequest("PATCH", provider.apiURL(repo, "/pulls/"+strconv.Itoa(number)), headers, patch, nil)
+	return
+}
+
+// UpdatePR replaces the title and body of pull request number on repo
+func (provider *gitHubProvider) UpdatePR(repo string, number int, title, body string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	patch := map[string]string{"title": title, "body": body}
+	_, err = apiRequest("PATCH", provider.apiURL(repo, "/pulls/"+strconv.Itoa(number)), headers, patch, nil)
+	return
+}
+
+// EnableAutoMerge enables auto-merge on pull request pr, which github merges with method once checks pass
+func (provider *gitHubProvider) EnableAutoMerge(repo string, pr PRResponse, method string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	if len(pr.NodeID) == 0 {
+		// Auto-merge is only available through graphql, which needs the node id
+		var payload PRResponse
+		if _, err = apiRequest("GET", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)), headers, nil, &payload); err != nil {
+			return
+		}
+		pr.NodeID = payload.NodeID
+	}
+
+	post := map[string]interface{}{
+		"query": `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+	enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`,
+		"variables": map[string]string{"id": pr.NodeID, "method": strings.ToUpper(method)},
+	}
+
+	// Graphql errors are returned with a 200
+	var payload struct {
+		Errors []PRResponseError `json:"errors"`
+	}
+
+	if _, err = apiRequest("POST", "https://api."+provider.host+"/graphql", headers, post, &payload); err != nil {
+		return
+	}
+
+	if len(payload.Errors) > 0 {
+		err = fmt.Errorf("%s", payload.Errors[0].Message)
+	}
+
+	return
+}
+
+// CreateRelease publishes a release for tag on repo
+func (provider *gitHubProvider) CreateRelease(repo, tag, notes string) (releaseURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]string{"tag_name": tag, "name": tag, "body": notes}
+
+	var payload struct {
+		HTMLURL string `json:"html_url"`
+	}
+
+	_, err = apiRequest("POST", provider.apiURL(repo, "/releases"), headers, post, &payload)
+	releaseURL = payload.HTMLURL
+	return
+}
+
+// UploadReleaseAsset attaches data as file name to the release of tag on repo
+func (provider *gitHubProvider) UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var release struct {
+		UploadURL string `json:"upload_url"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/releases/tags/"+url.PathEscape(tag)), headers, nil, &release); err != nil {
+		return
+	}
+
+	// Upload url is a template, e.g. https://uploads.github.com/repos/owner/name/releases/1/assets{?name,label}
+	uploadURL := strings.Split(release.UploadURL, "{")[0] + "?name=" + url.QueryEscape(name)
+
+	var payload struct {
+		BrowserDownloadURL string `json:"browser_download_url"`
+	}
+
+	_, err = uploadRequest("POST", uploadURL, "application/octet-stream", headers, data, &payload)
+	assetURL = payload.BrowserDownloadURL
+	return
+}
+
+// PushBlocked returns true if branch is protected by required reviews, push restrictions or a lock.
+// Protection details need admin access, so protected branches are assumed blocked if they can't be read
+func (provider *gitHubProvider) PushBlocked(repo, branch string) (blocked bool, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		Protected bool `json:"protected"`
+	}
+
+	resource := "/branches/" + branch
+	if _, err = apiRequest("GET", provider.apiURL(repo, resource), headers, nil, &payload); err != nil || !payload.Protected {
+		return
+	}
+
+	var protection struct {
+		RequiredPullRequestReviews *struct{} `json:"required_pull_request_reviews"`
+		Restrictions               *struct{} `json:"restrictions"`
+		LockBranch                 struct {
+			Enabled bool `json:"enabled"`
+		} `json:"lock_branch"`
+	}
+
+	if _, protectionErr := apiRequest("GET", provider.apiURL(repo, resource+"/protection"), headers, nil, &protection); protectionErr != nil {
+		return true, nil
+	}
+
+	blocked = protection.RequiredPullRequestReviews != nil || protection.Restrictions != nil || protection.LockBranch.Enabled
+	return
+}
+
+// secretsPublicKey returns the id and base64 encoded key actions secrets of repo are encrypted with
+func (provider *gitHubProvider) secretsPublicKey(repo string) (id, key string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	payload := &secretRequest{}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/actions/secrets/public-key"), headers, nil, payload); err != nil {
+		return
+	}
+
+	return payload.KeyID, payload.PublicKey, nil
+}
+
+// setSecret creates or updates the actions secret name of repo, encrypted with the key of keyID
+func (provider *gitHubProvider) setSecret(repo, name, encrypted, keyID string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := &secretRequest{Encrypted: encrypted, KeyID: keyID}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/actions/secrets/"+name), headers, put, nil)
+	return
+}
+
+// CheckStatus aggregates check runs and commit statuses of the head of pr
+func (provider *gitHubProvider) CheckStatus(repo string, pr PRResponse) (state string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var pull struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)), headers, nil, &pull); err != nil {
+		return
+	}
+
+	var runs struct {
+		CheckRuns []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/commits/"+pull.Head.SHA+"/check-runs?per_page=100"), headers, nil, &runs); err != nil {
+		return
+	}
+
+	var statuses struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/commits/"+pull.Head.SHA+"/status"), headers, nil, &statuses); err != nil {
+		return
+	}
+
+	state = CheckSuccess
+	for _, run := range runs.CheckRuns {
+		switch {
+		case run.Status != "completed":
+			state = CheckPending
+		case run.Conclusion == "success" || run.Conclusion == "neutral" || run.Conclusion == "skipped":
+		default:
+			return CheckFailure, nil
+		}
+	}
+
+	if statuses.TotalCount > 0 {
+		switch statuses.State {
+		case "success":
+		case "pending":
+			state = CheckPending
+		default:
+			return CheckFailure, nil
+		}
+	}
+
+	return
+}
+
+// MergePR merges pr with method
+func (provider *gitHubProvider) MergePR(repo string, pr PRResponse, method string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]string{"merge_method": method}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/pulls/"+strconv.Itoa(pr.Number)+"/merge"), headers, put, nil)
+	return
+}
+
+// createRepository creates repo (owner/name) in the owner's organization, or for the authenticated user if owner isn't
+// an organization, returning its clone url
+func (provider *gitHubProvider) createRepository(repo string, private bool) (cloneURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	comps := strings.SplitN(repo, "/", 2)
+	if len(comps) != 2 {
+		err = fmt.Errorf("expected owner/name, got %s", repo)
+		return
+	}
+
+	post := map[string]interface{}{"name": comps[1], "private": private}
+	var payload struct {
+		CloneURL string `json:"clone_url"`
+	}
+	status, err := apiRequest("POST", "https://api."+provider.host+"/orgs/"+comps[0]+"/repos", headers, post, &payload)
+	if status == 404 {
+		// Owner is a user, repos can only be created for the authenticated one
+		status, err = apiRequest("POST", "https://api."+provider.host+"/user/repos", headers, post, &payload)
+	}
+	if err != nil {
+		return
+	}
+
+	return payload.CloneURL, nil
+}
@@ -0,0 +1,140 @@
+package com
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestGitHubPushBlocked(t *testing.T) {
+	setEnv(t, "GITHUB_TOKEN", "gh-token")
+	provider := &gitHubProvider{host: "github.com"}
+
+	for _, test := range []struct {
+		name       string
+		branch     string
+		protection func() (int, string)
+		blocked    bool
+	}{
+		{"unprotected", `{"protected": false}`, nil, false},
+		{"status checks only", `{"protected": true}`, func() (int, string) {
+			return http.StatusOK, `{"required_status_checks": {"strict": true}, "lock_branch": {"enabled": false}}`
+		}, false},
+		{"required reviews", `{"protected": true}`, func() (int, string) {
+			return http.StatusOK, `{"required_pull_request_reviews": {"required_approving_review_count": 1}}`
+		}, true},
+		{"locked", `{"protected": true}`, func() (int, string) { return http.StatusOK, `{"lock_branch": {"enabled": true}}` }, true},
+		// Protection needs admin access to read
+		{"unreadable protection", `{"protected": true}`, func() (int, string) {
+			return http.StatusNotFound, `{"message": "Not Found"}`
+		}, true},
+	} {
+		stubAPI(t, func(req *http.Request) (status int, body string) {
+			switch req.URL.String() {
+			case "https://api.github.com/repos/org/a/branches/master":
+				return http.StatusOK, test.branch
+			case "https://api.github.com/repos/org/a/branches/master/protection":
+				if test.protection != nil {
+					return test.protection()
+				}
+			}
+
+			t.Errorf("%s: unexpected request %s %s", test.name, req.Method, req.URL)
+			return http.StatusNotFound, ""
+		})
+
+		if blocked, err := provider.PushBlocked("org/a", "master"); err != nil || blocked != test.blocked {
+			t.Errorf("%s: expected blocked %v, got %v (%v)", test.name, test.blocked, blocked, err)
+		}
+	}
+}
+
+func TestGitHubCheckStatus(t *testing.T) {
+	setEnv(t, "GITHUB_TOKEN", "gh-token")
+	provider := &gitHubProvider{host: "github.com"}
+
+	for _, test := range []struct {
+		name     string
+		runs     string
+		statuses string
+		state    string
+	}{
+		{"no checks", `{"check_runs": []}`, `{"state": "pending", "total_count": 0}`, CheckSuccess},
+		{"passed", `{"check_runs": [{"status": "completed", "conclusion": "success"}, {"status": "completed", "conclusion": "skipped"}]}`, `{"state": "success", "total_count": 1}`, CheckSuccess},
+		{"running", `{"check_runs": [{"status": "in_progress"}, {"status": "completed", "conclusion": "success"}]}`, `{"total_count": 0}`, CheckPending},
+		{"pending status", `{"check_runs": []}`, `{"state": "pending", "total_count": 1}`, CheckPending},
+		{"failed run", `{"check_runs": [{"status": "in_progress"}, {"status": "completed", "conclusion": "failure"}]}`, `{"total_count": 0}`, CheckFailure},
+		{"failed status", `{"check_runs": [{"status": "completed", "conclusion": "success"}]}`, `{"state": "error", "total_count": 1}`, CheckFailure},
+	} {
+		stubAPI(t, func(req *http.Request) (status int, body string) {
+			switch req.URL.String() {
+			case "https://api.github.com/repos/org/a/pulls/7":
+				return http.StatusOK, `{"head": {"sha": "abc123"}}`
+			case "https://api.github.com/repos/org/a/commits/abc123/check-runs?per_page=100":
+				return http.StatusOK, test.runs
+			case "https://api.github.com/repos/org/a/commits/abc123/status":
+				return http.StatusOK, test.statuses
+			}
+
+			t.Errorf("%s: unexpected request %s %s", test.name, req.Method, req.URL)
+			return http.StatusNotFound, ""
+		})
+
+		if state, err := provider.CheckStatus("org/a", PRResponse{Number: 7}); err != nil || state != test.state {
+			t.Errorf("%s: expected %s, got %s (%v)", test.name, test.state, state, err)
+		}
+	}
+}
+
+func TestGitHubMergePR(t *testing.T) {
+	setEnv(t, "GITHUB_TOKEN", "gh-token")
+	provider := &gitHubProvider{host: "github.com"}
+
+	var merge map[string]string
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "PUT" || req.URL.String() != "https://api.github.com/repos/org/a/pulls/7/merge" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&merge); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusOK, `{"merged": true}`
+	})
+
+	if err := provider.MergePR("org/a", PRResponse{Number: 7}, MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+	if merge["merge_method"] != MergeMethodSquash {
+		t.Errorf("expected a squash merge, got %v", merge)
+	}
+}
+
+func TestGitHubUploadReleaseAsset(t *testing.T) {
+	setEnv(t, "GITHUB_TOKEN", "gh-token")
+	provider := &gitHubProvider{host: "github.com"}
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		switch req.Method + " " + req.URL.String() {
+		case "GET https://api.github.com/repos/org/a/releases/tags/v1.3.0":
+			return http.StatusOK, `{"upload_url": "https://uploads.github.com/repos/org/a/releases/1/assets{?name,label}"}`
+		case "POST https://uploads.github.com/repos/org/a/releases/1/assets?name=a+linux.tar.gz":
+			if contentType := req.Header.Get("Content-Type"); contentType != "application/octet-stream" {
+				t.Errorf("expected a binary upload, got %s", contentType)
+			}
+			if data, _ := ioutil.ReadAll(req.Body); string(data) != "archive" {
+				t.Errorf("expected the asset's data, got %q", data)
+			}
+			return http.StatusCreated, `{"browser_download_url": "https://github.com/org/a/releases/download/v1.3.0/a.linux.tar.gz"}`
+		}
+
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		return http.StatusNotFound, ""
+	})
+
+	assetURL, err := provider.UploadReleaseAsset("org/a", "v1.3.0", "a linux.tar.gz", []byte("archive"))
+	if err != nil || assetURL != "https://github.com/org/a/releases/download/v1.3.0/a.linux.tar.gz" {
+		t.Errorf("expected the asset's download url, got %q (%v)", assetURL, err)
+	}
+}
@@ -0,0 +1,357 @@
+package com
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// gitLabProvider opens merge requests with the gitlab api
+type gitLabProvider struct {
+	host string
+}
+
+// gitLabMergeRequest represents gitlab's merge request request and response
+type gitLabMergeRequest struct {
+	SourceBranch string `json:"source_branch,omitempty"`
+	TargetBranch string `json:"target_branch,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	// Set when opened from a fork
+	TargetProjectID int `json:"target_project_id,omitempty"`
+
+	IID    int    `json:"iid,omitempty"`
+	WebURL string `json:"web_url,omitempty"`
+
+	// Error message may be a string or list of strings
+	Message json.RawMessage `json:"message,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func (provider *gitLabProvider) Name() string {
+	return "GitLab"
+}
+
+func (provider *gitLabProvider) apiURL(repo, resource string) string {
+	return "https://" + provider.host + "/api/v4/projects/" + url.PathEscape(repo) + resource
+}
+
+func (provider *gitLabProvider) headers() (headers map[string]string, err error) {
+	auth, err := getHostAuth(provider.host)
+	if err != nil {
+		return
+	}
+
+	headers = map[string]string{"PRIVATE-TOKEN": auth.Token}
+	return
+}
+
+// CreatePR opens a merge request on repo
+func (provider *gitLabProvider) CreatePR(repo string, request PRRequest) (status *PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := gitLabMergeRequest{
+		SourceBranch: request.Head,
+		TargetBranch: request.Base,
+		Title:        request.Title,
+		Description:  request.Body,
+	}
+	if request.Draft {
+		// Gitlab marks drafts by title
+		post.Title = "Draft: " + post.Title
+	}
+
+	source := repo
+	if len(request.HeadRepo) > 0 {
+		// Merge requests of forks are opened on the fork, targeting the upstream project
+		if post.TargetProjectID, err = provider.projectID(repo, headers); err != nil {
+			return
+		}
+		source = request.HeadRepo
+	}
+
+	var payload gitLabMergeRequest
+	status = &PRResponse{}
+	status.HTTPStatus, err = apiRequest("POST", provider.apiURL(source, "/merge_requests"), headers, post, &payload)
+
+	status.URL = payload.WebURL
+	status.Number = payload.IID
+	status.Title = payload.Title
+	status.Errors = payload.errors()
+	return
+}
+
+// projectID returns the numeric id of repo
+func (provider *gitLabProvider) projectID(repo string, headers map[string]string) (id int, err error) {
+	var payload struct {
+		ID int `json:"id"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &payload); err != nil {
+		return
+	}
+
+	if id = payload.ID; id == 0 {
+		err = fmt.Errorf("unable to get gitlab project id of %s", repo)
+	}
+
+	return
+}
+
+// ListPRs returns open merge requests on repo
+func (provider *gitLabProvider) ListPRs(repo string) (prs []PRResponse, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload []gitLabMergeRequest
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/merge_requests?state=opened"), headers, nil, &payload); err != nil {
+		return
+	}
+
+	for _, mr := range payload {
+		prs = append(prs, PRResponse{URL: mr.WebURL, Number: mr.IID, Title: mr.Title})
+	}
+
+	return
+}
+
+// GetDefaultBranch returns the default branch of repo
+func (provider *gitLabProvider) GetDefaultBranch(repo string) (branch string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+
+	_, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &payload)
+	branch = payload.DefaultBranch
+	return
+}
+
+// ClosePR closes merge request number on repo
+func (provider *gitLabProvider) ClosePR(repo string, number int) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]string{"state_event": "close"}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/merge_requests/"+strconv.Itoa(number)), headers, put, nil)
+	return
+}
+
+// UpdatePR replaces the title and description of merge request number on repo
+func (provider *gitLabProvider) UpdatePR(repo string, number int, title, body string) (err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]string{"title": title, "description": body}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/merge_requests/"+strconv.Itoa(number)), headers, put, nil)
+	return
+}
+
+// EnableAutoMerge sets merge request pr to merge with method once its pipeline succeeds
+func (provider *gitLabProvider) EnableAutoMerge(repo string, pr PRResponse, method string) (err error) {
+	if method == MergeMethodRebase {
+		err = fmt.Errorf("%s merge method is set per project on gitlab", method)
+		return
+	}
+
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]bool{"merge_when_pipeline_succeeds": true, "squash": method == MergeMethodSquash}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/merge_requests/"+strconv.Itoa(pr.Number)+"/merge"), headers, put, nil)
+	return
+}
+
+// errors normalizes gitlab error messages into pr response errors
+func (mr gitLabMergeRequest) errors() (errs []PRResponseError) {
+	if len(mr.Error) > 0 {
+		errs = append(errs, PRResponseError{Message: mr.Error})
+	}
+
+	var message string
+	var messages []string
+	if json.Unmarshal(mr.Message, &message) == nil && len(message) > 0 {
+		messages = append(messages, message)
+	} else {
+		json.Unmarshal(mr.Message, &messages)
+	}
+
+	for _, message := range messages {
+		errs = append(errs, PRResponseError{Message: message})
+	}
+
+	return
+}
+
+// CreateRelease publishes a release for tag on repo
+func (provider *gitLabProvider) CreateRelease(repo, tag, notes string) (releaseURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	post := map[string]string{"tag_name": tag, "name": tag, "description": notes}
+
+	var payload struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+
+	_, err = apiRequest("POST", provider.apiURL(repo, "/releases"), headers, post, &payload)
+	releaseURL = payload.Links.Self
+	return
+}
+
+// UploadReleaseAsset uploads data as file name to repo, then links it to the release of tag
+func (provider *gitLabProvider) UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	contentType, body, err := multipartFile("file", name, data)
+	if err != nil {
+		return
+	}
+
+	var upload struct {
+		URL      string `json:"url"`
+		FullPath string `json:"full_path"`
+	}
+
+	if _, err = uploadRequest("POST", provider.apiURL(repo, "/uploads"), contentType, headers, body, &upload); err != nil {
+		return
+	}
+
+	// Full path is only returned by newer versions
+	assetURL = "https://" + provider.host + upload.FullPath
+	if len(upload.FullPath) == 0 {
+		assetURL = "https://" + provider.host + "/" + repo + upload.URL
+	}
+
+	post := map[string]string{"name": name, "url": assetURL}
+	_, err = apiRequest("POST", provider.apiURL(repo, "/releases/"+url.PathEscape(tag)+"/assets/links"), headers, post, nil)
+	return
+}
+
+// PushBlocked returns true if branch is protected and the user's access level isn't allowed to push
+func (provider *gitLabProvider) PushBlocked(repo, branch string) (blocked bool, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var protection struct {
+		PushAccessLevels []struct {
+			AccessLevel int `json:"access_level"`
+		} `json:"push_access_levels"`
+	}
+
+	status, err := apiRequest("GET", provider.apiURL(repo, "/protected_branches/"+url.PathEscape(branch)), headers, nil, &protection)
+	if status == http.StatusNotFound {
+		// Not protected
+		return false, nil
+	} else if err != nil {
+		return
+	}
+
+	var project struct {
+		Permissions struct {
+			ProjectAccess *struct {
+				AccessLevel int `json:"access_level"`
+			} `json:"project_access"`
+			GroupAccess *struct {
+				AccessLevel int `json:"access_level"`
+			} `json:"group_access"`
+		} `json:"permissions"`
+	}
+
+	if _, err = apiRequest("GET", provider.apiURL(repo, ""), headers, nil, &project); err != nil {
+		return
+	}
+
+	level := 0
+	if access := project.Permissions.ProjectAccess; access != nil && access.AccessLevel > level {
+		level = access.AccessLevel
+	}
+	if access := project.Permissions.GroupAccess; access != nil && access.AccessLevel > level {
+		level = access.AccessLevel
+	}
+
+	// Levels are 0 (no one), 30 (developers) and 40 (maintainers)
+	for _, push := range protection.PushAccessLevels {
+		if push.AccessLevel > 0 && push.AccessLevel <= level {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// CheckStatus returns the state of the head pipeline of merge request pr
+func (provider *gitLabProvider) CheckStatus(repo string, pr PRResponse) (state string, err error) {
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	var payload struct {
+		HeadPipeline *struct {
+			Status string `json:"status"`
+		} `json:"head_pipeline"`
+	}
+	if _, err = apiRequest("GET", provider.apiURL(repo, "/merge_requests/"+strconv.Itoa(pr.Number)), headers, nil, &payload); err != nil {
+		return
+	}
+
+	if payload.HeadPipeline == nil {
+		return CheckSuccess, nil
+	}
+
+	switch payload.HeadPipeline.Status {
+	case "success", "skipped":
+		state = CheckSuccess
+	case "failed", "canceled":
+		state = CheckFailure
+	default:
+		// Created, waiting, preparing, pending, running, scheduled or manual
+		state = CheckPending
+	}
+
+	return
+}
+
+// MergePR merges merge request pr, squashing if method is squash. Rebasing is set per project on gitlab
+func (provider *gitLabProvider) MergePR(repo string, pr PRResponse, method string) (err error) {
+	if method == MergeMethodRebase {
+		err = fmt.Errorf("%s merge method is set per project on gitlab", method)
+		return
+	}
+
+	headers, err := provider.headers()
+	if err != nil {
+		return
+	}
+
+	put := map[string]bool{"squash": method == MergeMethodSquash}
+	_, err = apiRequest("PUT", provider.apiURL(repo, "/merge_requests/"+strconv.Itoa(pr.Number)+"/merge"), headers, put, nil)
+	return
+}
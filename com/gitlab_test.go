@@ -0,0 +1,132 @@
+package com
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// setEnv sets name to value until the test finishes
+func setEnv(t *testing.T, name, value string) {
+	previous, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func TestGitLabCreatePR(t *testing.T) {
+	setEnv(t, "GITLAB_TOKEN", "gitlab-token")
+
+	provider, err := ProviderFor("gitlab.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Name() != "GitLab" {
+		t.Fatalf("expected gitlab provider, got %s", provider.Name())
+	}
+
+	var request gitLabMergeRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "POST" || req.URL.String() != "https://gitlab.example.com/api/v4/projects/org%2Fa/merge_requests" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if token := req.Header.Get("PRIVATE-TOKEN"); token != "gitlab-token" {
+			t.Errorf("expected GITLAB_TOKEN, got %q", token)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusCreated, `{"iid": 3, "web_url": "https://gitlab.example.com/org/a/-/merge_requests/3", "title": "Draft: Sync deps"}`
+	})
+
+	status, err := provider.CreatePR("org/a", PRRequest{Title: "Sync deps", Body: "Updated deps", Head: "sync", Base: "master", Draft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Title != "Draft: Sync deps" || request.SourceBranch != "sync" || request.TargetBranch != "master" || request.Description != "Updated deps" {
+		t.Errorf("unexpected merge request %+v", request)
+	}
+	if status.Number != 3 || status.URL != "https://gitlab.example.com/org/a/-/merge_requests/3" {
+		t.Errorf("unexpected response %+v", status)
+	}
+}
+
+func TestGitLabCreatePRErrors(t *testing.T) {
+	setEnv(t, "GITLAB_TOKEN", "gitlab-token")
+
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		return http.StatusConflict, `{"message": ["Another open merge request already exists for this source branch"]}`
+	})
+
+	provider := &gitLabProvider{host: "gitlab.com"}
+	status, _ := provider.CreatePR("org/a", PRRequest{Title: "Sync deps", Head: "sync", Base: "master"})
+	if status == nil || status.HTTPStatus != http.StatusConflict || len(status.Errors) != 1 ||
+		status.Errors[0].Message != "Another open merge request already exists for this source branch" {
+		t.Errorf("expected conflict error, got %+v", status)
+	}
+}
+
+func TestGitLabCreatePRFromFork(t *testing.T) {
+	setEnv(t, "GITLAB_TOKEN", "gitlab-token")
+
+	var request gitLabMergeRequest
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		switch req.Method + " " + req.URL.String() {
+		case "GET https://gitlab.com/api/v4/projects/upstream%2Fa":
+			return http.StatusOK, `{"id": 42}`
+		case "POST https://gitlab.com/api/v4/projects/me%2Fa/merge_requests":
+			if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			return http.StatusCreated, `{"iid": 5, "web_url": "https://gitlab.com/upstream/a/-/merge_requests/5"}`
+		}
+
+		t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		return http.StatusNotFound, ""
+	})
+
+	provider := &gitLabProvider{host: "gitlab.com"}
+	status, err := provider.CreatePR("upstream/a", PRRequest{Title: "Sync deps", Head: "sync", Base: "master", HeadRepo: "me/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if request.TargetProjectID != 42 || request.SourceBranch != "sync" || status.Number != 5 {
+		t.Errorf("expected merge request from the fork targeting project 42, got %+v", request)
+	}
+}
+
+func TestGitLabEnableAutoMerge(t *testing.T) {
+	setEnv(t, "GITLAB_TOKEN", "gitlab-token")
+
+	var put map[string]bool
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.Method != "PUT" || req.URL.String() != "https://gitlab.com/api/v4/projects/org%2Fa/merge_requests/3/merge" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&put); err != nil {
+			t.Error(err)
+		}
+
+		return http.StatusOK, `{}`
+	})
+
+	provider := &gitLabProvider{host: "gitlab.com"}
+	if err := provider.EnableAutoMerge("org/a", PRResponse{Number: 3}, MergeMethodSquash); err != nil {
+		t.Fatal(err)
+	}
+	if !put["merge_when_pipeline_succeeds"] || !put["squash"] {
+		t.Errorf("expected squash merge when the pipeline succeeds, got %v", put)
+	}
+
+	if err := provider.EnableAutoMerge("org/a", PRResponse{Number: 3}, MergeMethodRebase); err == nil {
+		t.Error("expected rebase auto-merge to fail, it's set per project")
+	}
+}
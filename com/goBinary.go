@@ -0,0 +1,67 @@
+package com
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultGoBinary is the go command run when none is set
+const DefaultGoBinary = "go"
+
+// Global go binary, and binaries of libs keyed by module path prefix
+var (
+	goBinary   = DefaultGoBinary
+	goBinaries = map[string]string{}
+)
+
+// SetGoBinary sets the go binary run for libs globally, e.g. go1.21.5, and binaries of libs within module path
+// prefixes, e.g. github.com/org/legacy: go1.19.13. Default is go if empty
+func SetGoBinary(binary string, binaries map[string]string) (err error) {
+	if len(binary) == 0 {
+		binary = DefaultGoBinary
+	}
+
+	if _, err = exec.LookPath(binary); err != nil {
+		return fmt.Errorf("go binary %s not found: %v", binary, err)
+	}
+
+	for prefix, override := range binaries {
+		if _, err = exec.LookPath(override); err != nil {
+			return fmt.Errorf("go binary %s for %s not found: %v", override, prefix, err)
+		}
+	}
+
+	goBinary = binary
+	goBinaries = binaries
+	return
+}
+
+// GoBinary returns the global go binary
+func GoBinary() string {
+	return goBinary
+}
+
+// GoBinary returns the go binary run for the file, set by the longest module path prefix matching it
+func (file *FileWrapper) GoBinary() string {
+	binary := goBinary
+	if len(goBinaries) == 0 {
+		return binary
+	}
+
+	url := file.GetGoURL()
+	longest := -1
+	for prefix, override := range goBinaries {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if url != prefix && !strings.HasPrefix(url, prefix+"/") {
+			continue
+		}
+
+		if len(prefix) > longest {
+			longest = len(prefix)
+			binary = override
+		}
+	}
+
+	return binary
+}
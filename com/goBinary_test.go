@@ -0,0 +1,53 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGoBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shims are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "gomu-gobinary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"go1.21.5", "go1.19.13", "go1.20.1"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setEnv(t, "PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	err = SetGoBinary("go1.21.5", map[string]string{"github.com/org/legacy": "go1.19.13", "github.com/org/legacy/newer/": "go1.20.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetGoBinary("", nil)
+
+	for url, expected := range map[string]string{
+		"github.com/org/a":                  "go1.21.5",
+		"github.com/org/legacy":             "go1.19.13",
+		"github.com/org/legacy/tools":       "go1.19.13",
+		"github.com/org/legacy/newer/v2":    "go1.20.1",
+		"github.com/org/legacy-replacement": "go1.21.5",
+	} {
+		if binary := (&FileWrapper{Path: url, goURL: url}).GoBinary(); binary != expected {
+			t.Errorf("expected %s for %s, got %s", expected, url, binary)
+		}
+	}
+
+	if err = SetGoBinary("go1.21.5", map[string]string{"github.com/org/b": "go0.missing"}); err == nil {
+		t.Error("expected a binary missing from PATH to fail")
+	}
+	if GoBinary() != "go1.21.5" || (&FileWrapper{goURL: "github.com/org/legacy"}).GoBinary() != "go1.19.13" {
+		t.Error("expected binaries to be kept when setting them fails")
+	}
+}
@@ -0,0 +1,106 @@
+package com
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AnyHost keys the concurrency limit of hosts without their own
+const AnyHost = "*"
+
+// networkCommands are git subcommands talking to a remote, limited per host
+var networkCommands = map[string]bool{"clone": true, "fetch": true, "ls-remote": true, "pull": true, "push": true}
+
+// Global limits of concurrent network commands keyed by host, and the semaphores enforcing them
+var (
+	hostLimits     map[string]int
+	hostSemaphores = map[string]chan struct{}{}
+	hostMux        sync.Mutex
+)
+
+// SetHostConcurrency limits git commands talking to remotes on each host globally, on top of MaxConcurrency,
+// e.g. {"github.com": 4} so pulls don't hammer a single server. AnyHost limits hosts not listed, 0 is unlimited
+func SetHostConcurrency(limits map[string]int) error {
+	for host, limit := range limits {
+		if limit < 0 {
+			return fmt.Errorf("concurrency of %s can't be negative, got %d", host, limit)
+		}
+	}
+
+	hostMux.Lock()
+	defer hostMux.Unlock()
+
+	hostLimits = limits
+	hostSemaphores = map[string]chan struct{}{}
+	return nil
+}
+
+// hostSemaphore returns the semaphore limiting commands against host, nil if unlimited
+func hostSemaphore(host string) chan struct{} {
+	hostMux.Lock()
+	defer hostMux.Unlock()
+
+	limit, ok := hostLimits[host]
+	if !ok {
+		limit = hostLimits[AnyHost]
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	semaphore, ok := hostSemaphores[host]
+	if !ok {
+		semaphore = make(chan struct{}, limit)
+		hostSemaphores[host] = semaphore
+	}
+
+	return semaphore
+}
+
+// acquireHost waits for a slot of the host a git network command talks to, returning a func releasing it.
+// Other commands aren't limited. Fails if the file's context is done while waiting
+func (file *FileWrapper) acquireHost(name string, params []string) (release func(), err error) {
+	release = func() {}
+	if name != "git" || len(params) == 0 || !networkCommands[params[0]] || len(hostLimits) == 0 {
+		return
+	}
+
+	host := file.commandHost(params)
+	semaphore := hostSemaphore(host)
+	if semaphore == nil {
+		return
+	}
+
+	select {
+	case semaphore <- struct{}{}:
+	default:
+		file.Debug("Waiting for a " + host + " slot...")
+		select {
+		case semaphore <- struct{}{}:
+		case <-file.Context().Done():
+			return release, file.Context().Err()
+		}
+	}
+
+	return func() { <-semaphore }, nil
+}
+
+// commandHost returns the host a git network command talks to: the clone url's, the upstream remote's if named,
+// otherwise the push remote's
+func (file *FileWrapper) commandHost(params []string) (host string) {
+	for _, param := range params[1:] {
+		if params[0] == "clone" && IsRemoteURL(param) {
+			host, _ = parseRemote(param)
+			return
+		}
+
+		if param == UpstreamRemote {
+			if host, _ = file.remoteRepo(UpstreamRemote); len(host) > 0 {
+				return
+			}
+		}
+	}
+
+	host, _ = file.Remote()
+	return
+}
@@ -0,0 +1,85 @@
+package com
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// setHostConcurrency sets limits until the test finishes
+func setHostConcurrency(t *testing.T, limits map[string]int) {
+	if err := SetHostConcurrency(limits); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetHostConcurrency(nil) })
+}
+
+func TestSetHostConcurrency(t *testing.T) {
+	if err := SetHostConcurrency(map[string]int{"github.com": -1}); err == nil {
+		t.Error("expected negative concurrency to be rejected")
+	}
+
+	setHostConcurrency(t, map[string]int{"github.com": 2, AnyHost: 1})
+	for host, capacity := range map[string]int{"github.com": 2, "gitlab.com": 1} {
+		if semaphore := hostSemaphore(host); cap(semaphore) != capacity {
+			t.Errorf("expected %s to be limited to %d, got %d", host, capacity, cap(semaphore))
+		}
+	}
+
+	setHostConcurrency(t, map[string]int{"github.com": 0})
+	if semaphore := hostSemaphore("github.com"); semaphore != nil {
+		t.Error("expected 0 to be unlimited")
+	}
+}
+
+func TestCommandHost(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	for _, test := range []struct {
+		params []string
+		host   string
+	}{
+		{[]string{"clone", "--depth", "1", "https://gitlab.com/org/b.git", "b"}, "gitlab.com"},
+		{[]string{"fetch", "upstream"}, "github.com"},
+		{[]string{"push", "origin", "master"}, "github.com"},
+	} {
+		if host := file.commandHost(test.params); host != test.host {
+			t.Errorf("%v: expected %s, got %s", test.params, test.host, host)
+		}
+	}
+
+	recorder.Reply("git remote get-url upstream", Reply{Stdout: "git@gitea.example.com:org/a.git\n"})
+	if host := file.commandHost([]string{"fetch", "upstream"}); host != "gitea.example.com" {
+		t.Errorf("expected the upstream's host, got %s", host)
+	}
+}
+
+func TestAcquireHost(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	setHostConcurrency(t, map[string]int{"github.com": 1})
+
+	release, err := file.acquireHost("git", []string{"fetch", "origin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands not talking to a remote aren't limited
+	if err = file.RunCmd("git", "status"); err != nil {
+		t.Errorf("expected status to run while the host is busy, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	file.SetContext(ctx)
+	if err = file.RunCmd("git", "pull", "origin", "master"); err == nil {
+		t.Error("expected pull to wait for the busy host until timed out")
+	}
+	if recorder.Ran("git pull") {
+		t.Error("expected pull not to run without a slot")
+	}
+
+	release()
+	file.SetContext(context.Background())
+	if err = file.RunCmd("git", "pull", "origin", "master"); err != nil || !recorder.Ran("git pull") {
+		t.Errorf("expected pull to run once the slot was released, got %v", err)
+	}
+}
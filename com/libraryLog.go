@@ -0,0 +1,142 @@
+package com
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Global directory each library's messages and command output are written to, disabled if empty
+var logDir string
+
+// Library logs opened this run, keyed by go url
+var (
+	libraryLogs   = map[string]*libraryLog{}
+	libraryLogMux sync.Mutex
+)
+
+// libraryLog serializes writes to a library's log file
+type libraryLog struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+func (log *libraryLog) Write(p []byte) (n int, err error) {
+	log.mux.Lock()
+	defer log.mux.Unlock()
+
+	return log.file.Write(p)
+}
+
+// SetLogDir writes each library's messages and full command output to <dir>/<library>.log globally, keeping only
+// errors on the console. Empty disables. Logs of a previous run are closed
+func SetLogDir(dir string) (err error) {
+	CloseLibraryLogs()
+
+	if len(dir) > 0 {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("unable to create log directory %s: %v", dir, err)
+		}
+	}
+
+	logDir = dir
+	return
+}
+
+// CloseLibraryLogs closes the log files of every library
+func CloseLibraryLogs() {
+	libraryLogMux.Lock()
+	defer libraryLogMux.Unlock()
+
+	for url, log := range libraryLogs {
+		log.file.Close()
+		delete(libraryLogs, url)
+	}
+}
+
+// LogPath returns the path of the file's log, empty if library logs are disabled
+func (file *FileWrapper) LogPath() string {
+	if len(logDir) == 0 {
+		return ""
+	}
+
+	// One flat file per library, e.g. github.com_org_lib.log
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(file.logURL())
+	return filepath.Join(logDir, name+".log")
+}
+
+// logURL returns the go url the file's log is named after
+func (file *FileWrapper) logURL() string {
+	if len(file.goURL) > 0 {
+		return file.goURL
+	}
+
+	// Not cached, wrappers are reused for other paths while stashing
+	return (&FileWrapper{Path: file.Path}).GetGoURL()
+}
+
+// logOutput writes output of a command to the file's log if enabled
+func (file *FileWrapper) logOutput(output []byte) {
+	if writer := file.logWriter(); writer != nil && len(output) > 0 {
+		writer.Write(output)
+		if output[len(output)-1] != '\n' {
+			writer.Write([]byte("\n"))
+		}
+	}
+}
+
+// logWriter returns the file's log, opened (and truncated) on first use this run. Nil if library logs are disabled
+func (file *FileWrapper) logWriter() io.Writer {
+	logPath := file.LogPath()
+	if len(logPath) == 0 {
+		return nil
+	}
+
+	libraryLogMux.Lock()
+	defer libraryLogMux.Unlock()
+
+	url := file.logURL()
+	if log, ok := libraryLogs[url]; ok {
+		return log
+	}
+
+	f, err := os.Create(logPath)
+	if err != nil {
+		// Keep output on the console instead
+		return nil
+	}
+
+	log := &libraryLog{file: f}
+	libraryLogs[url] = log
+	return log
+}
+
+// writeLog writes message to the file's log at level, returning false if library logs are disabled
+func (file *FileWrapper) writeLog(level LogLevel, message string) bool {
+	writer := file.logWriter()
+	if writer == nil {
+		return false
+	}
+
+	fmt.Fprintf(writer, "%s %s %s\n", time.Now().Format("15:04:05"), level, message)
+	return true
+}
+
+// log sends message to the file's log if enabled, then to the logger if it's an error or library logs are disabled
+func (file *FileWrapper) log(level LogLevel, message string, fields ...interface{}) {
+	if file.writeLog(level, message) && level != ERROR {
+		// Console only shows errors and status lines
+		return
+	}
+
+	var label = file.goURL
+	if file.goURL == "" {
+		label = file.Path
+	}
+
+	Logln(level, message, append([]interface{}{LibraryField, label}, fields...)...)
+}
@@ -0,0 +1,65 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLibraryLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err = SetLogDir(filepath.Join(dir, "logs")); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogDir("")
+
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+
+	recorder := &Recorder{}
+	recorder.Reply("git status --porcelain", Reply{Stdout: " M go.mod\n"})
+	file := &FileWrapper{Path: "/libs/a", goURL: "github.com/org/a"}
+	file.SetRunner(recorder)
+
+	file.Output("Checking for changes...")
+	file.CmdOutput("git", "status", "--porcelain")
+	file.Error("Push failed")
+	CloseLibraryLogs()
+
+	logPath := filepath.Join(dir, "logs", "github.com_org_a.log")
+	if file.LogPath() != logPath {
+		t.Errorf("expected log at %s, got %s", logPath, file.LogPath())
+	}
+
+	data, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// Commands are logged at debug level, then their output
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], "NORMAL Checking for changes...") || !strings.HasSuffix(lines[1], "DEBUG git status --porcelain") ||
+		lines[2] != " M go.mod" || !strings.HasSuffix(lines[3], "ERROR Push failed") {
+		t.Errorf("expected messages and command output in the log, got %q", lines)
+	}
+
+	// Only errors reach the console
+	if expected := []string{"error Push failed library=github.com/org/a"}; !reflect.DeepEqual(logger.messages, expected) {
+		t.Errorf("expected %q, got %q", expected, logger.messages)
+	}
+}
+
+func TestLibraryLogDisabled(t *testing.T) {
+	file := &FileWrapper{Path: "/libs/a"}
+	if len(file.LogPath()) > 0 || file.logWriter() != nil {
+		t.Errorf("expected no log without a log directory, got %s", file.LogPath())
+	}
+}
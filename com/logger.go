@@ -0,0 +1,120 @@
+package com
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Field keys set on messages logged for a library
+const (
+	// LibraryField is the go url or path of the library a message refers to
+	LibraryField = "library"
+	// DryRunField is set on messages describing commands skipped in dry-run
+	DryRunField = "dryRun"
+)
+
+// Logger receives all gomu output. Fields are alternating key/value pairs, e.g. "library", "github.com/org/lib"
+// Note: messages are filtered by the log level before reaching the logger
+type Logger interface {
+	Debug(message string, fields ...interface{})
+	Info(message string, fields ...interface{})
+	Warn(message string, fields ...interface{})
+	Error(message string, fields ...interface{})
+}
+
+// Global logger
+var logger Logger = ConsoleLogger{}
+
+// Global writer console output is printed to
+var consoleOutput io.Writer = os.Stdout
+
+// SetConsoleOutput sets where the console logger and progress print globally, e.g. stderr so stdout can be piped.
+// Nil restores stdout
+func SetConsoleOutput(w io.Writer) {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	consoleMux.Lock()
+	defer consoleMux.Unlock()
+
+	consoleOutput = w
+}
+
+// SetLogger sets the logger output is delegated to globally. Nil restores the console logger
+func SetLogger(l Logger) {
+	if l == nil {
+		l = ConsoleLogger{}
+	}
+
+	logger = l
+}
+
+// GetLogger returns the current logger
+func GetLogger() Logger {
+	return logger
+}
+
+// ConsoleLogger prints messages to the console output, stdout by default, prefixed with their library if set
+type ConsoleLogger struct{}
+
+// Serializes console output from concurrent libs
+var consoleMux sync.Mutex
+
+// Debug prints a debug message
+func (ConsoleLogger) Debug(message string, fields ...interface{}) {
+	printFields(":DEBUG:", message, fields)
+}
+
+// Info prints a message
+func (ConsoleLogger) Info(message string, fields ...interface{}) {
+	printFields("::", message, fields)
+}
+
+// Warn prints a warning message
+func (ConsoleLogger) Warn(message string, fields ...interface{}) {
+	printFields(":WARN:", message, fields)
+}
+
+// Error prints an error message
+func (ConsoleLogger) Error(message string, fields ...interface{}) {
+	printFields(":ERROR:", message, fields)
+}
+
+// printFields prints "library separator message key=value..."
+func printFields(separator, message string, fields []interface{}) {
+	var label string
+	var extra []string
+	for i := 0; i+1 < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		switch key {
+		case LibraryField:
+			label = fmt.Sprint(fields[i+1])
+		case DryRunField:
+			separator = ":DRY-RUN:"
+		default:
+			extra = append(extra, key+"="+fmt.Sprint(fields[i+1]))
+		}
+	}
+
+	if len(label) > 0 {
+		message = label + " " + separator + " " + message
+	}
+	if len(extra) > 0 {
+		message += " " + strings.Join(extra, " ")
+	}
+
+	consoleMux.Lock()
+	defer consoleMux.Unlock()
+
+	if progress != nil && progress.render {
+		// Keep status line below output
+		fmt.Fprint(consoleOutput, "\r\033[K"+message+"\n"+progress.line())
+		return
+	}
+
+	fmt.Fprintln(consoleOutput, message)
+}
@@ -0,0 +1,82 @@
+package com
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordingLogger records messages as "level message key=value..."
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) record(level, message string, fields []interface{}) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		message += fmt.Sprintf(" %v=%v", fields[i], fields[i+1])
+	}
+	l.messages = append(l.messages, level+" "+message)
+}
+
+func (l *recordingLogger) Debug(message string, fields ...interface{}) {
+	l.record("debug", message, fields)
+}
+
+func (l *recordingLogger) Info(message string, fields ...interface{}) {
+	l.record("info", message, fields)
+}
+
+func (l *recordingLogger) Warn(message string, fields ...interface{}) {
+	l.record("warn", message, fields)
+}
+
+func (l *recordingLogger) Error(message string, fields ...interface{}) {
+	l.record("error", message, fields)
+}
+
+func TestSetLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	SetLogger(logger)
+	t.Cleanup(func() { SetLogger(nil) })
+
+	file := &FileWrapper{Path: "/libs/a"}
+	file.Output("Updating refs...")
+	file.DryRun("git push")
+	// Filtered by the log level
+	file.Debug("git fetch")
+	Warnln("Careful")
+	Errorln("Failed")
+
+	expected := []string{
+		"info Updating refs... library=/libs/a",
+		"info git push library=/libs/a dryRun=true",
+		"warn Careful",
+		"error Failed",
+	}
+	if !reflect.DeepEqual(logger.messages, expected) {
+		t.Errorf("expected %q, got %q", expected, logger.messages)
+	}
+
+	if _, ok := GetLogger().(*recordingLogger); !ok {
+		t.Errorf("expected recording logger, got %T", GetLogger())
+	}
+	if SetLogger(nil); GetLogger() != (ConsoleLogger{}) {
+		t.Errorf("expected console logger to be restored, got %T", GetLogger())
+	}
+}
+
+func TestConsoleLogger(t *testing.T) {
+	var output bytes.Buffer
+	SetConsoleOutput(&output)
+	t.Cleanup(func() { SetConsoleOutput(nil) })
+
+	ConsoleLogger{}.Info("Updating refs...", LibraryField, "github.com/org/a", "attempt", 2)
+	ConsoleLogger{}.Info("git push", LibraryField, "github.com/org/a", DryRunField, true)
+	ConsoleLogger{}.Error("Failed")
+
+	expected := "github.com/org/a :: Updating refs... attempt=2\ngithub.com/org/a :DRY-RUN: git push\nFailed\n"
+	if output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+}
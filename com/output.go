@@ -1,6 +1,9 @@
 package com
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Global log level
 var logLevel = NORMAL
@@ -70,26 +73,64 @@ func GetLogLevel() LogLevel {
 
 // Outputln will println if level and setting match nameOnly, or if level is at or below logLevel
 func Outputln(level LogLevel, a ...interface{}) (n int, err error) {
-	if logLevel == SILENT {
-		// Ignore
-	} else if logLevel == NAMEONLY {
-		// Only print NAMEONLY when level matches exact
-		if logLevel == level {
-			return fmt.Println(a...)
-		}
-	} else if level <= logLevel {
-		return fmt.Println(a...)
+	message := strings.TrimSuffix(fmt.Sprintln(a...), "\n")
+	if err = Logln(level, message); err == nil {
+		n = len(message) + 1
+	}
+
+	return
+}
+
+// Logln will send message and fields to the logger if level and setting match nameOnly, or if level is at or below logLevel
+func Logln(level LogLevel, message string, fields ...interface{}) (err error) {
+	if !shouldLog(level) {
+		err = fmt.Errorf("Log level <%s> skips output at level: %s", logLevel, level)
+		return
+	}
+
+	switch level {
+	case ERROR:
+		logger.Error(message, fields...)
+	case DEBUG:
+		logger.Debug(message, fields...)
+	default:
+		logger.Info(message, fields...)
 	}
 
-	err = fmt.Errorf("Log level <"+logLevel.String()+"> skips output at level:", level)
 	return
 }
 
-// Errorln will print output at debug level
+// shouldLog returns true if output at level is shown at the current log level
+func shouldLog(level LogLevel) bool {
+	switch logLevel {
+	case SILENT:
+		return false
+	case NAMEONLY:
+		// Only print NAMEONLY when level matches exact
+		return level == NAMEONLY
+	default:
+		return level <= logLevel
+	}
+}
+
+// Errorln will print output at error level
 func Errorln(a ...interface{}) (n int, err error) {
 	return Outputln(ERROR, a...)
 }
 
+// Warnln will print a warning at normal level
+func Warnln(a ...interface{}) (n int, err error) {
+	message := strings.TrimSuffix(fmt.Sprintln(a...), "\n")
+	if !shouldLog(NORMAL) {
+		err = fmt.Errorf("Log level <%s> skips output at level: %s", logLevel, NORMAL)
+		return
+	}
+
+	logger.Warn(message)
+	n = len(message) + 1
+	return
+}
+
 // Println will print output at normal level
 func Println(a ...interface{}) (n int, err error) {
 	return Outputln(NORMAL, a...)
@@ -0,0 +1,174 @@
+package com
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	gosort "sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of package manifests libs are detected by
+const (
+	KindGo      = "go"
+	KindNPM     = "npm"
+	KindGeneric = "generic" // Repos without a known manifest, only git operations apply
+)
+
+// PackageManifest represents the manifest declaring a lib's package, e.g. go.mod or package.json
+type PackageManifest struct {
+	Kind    string
+	Name    string
+	Version string
+
+	// Packages the lib requires, informational for kinds other than go. Only go deps are sorted
+	Deps []string
+}
+
+// ManifestParser reads a kind of package manifest
+type ManifestParser interface {
+	// Kind returns the kind of manifest parsed, e.g. npm
+	Kind() string
+	// Parse returns the manifest of the lib at dir, ok is false if dir has none of this kind
+	Parse(dir string) (manifest PackageManifest, ok bool, err error)
+}
+
+// Global parsers libs are detected with, in order
+var (
+	manifestParsers = []ManifestParser{goModParser{}, npmParser{}}
+	manifestMux     sync.Mutex
+)
+
+// RegisterManifestParser adds parser globally, tried before parsers already registered so it can claim libs they would,
+// e.g. a Cargo.toml or pom.xml parser
+func RegisterManifestParser(parser ManifestParser) {
+	manifestMux.Lock()
+	defer manifestMux.Unlock()
+
+	manifestParsers = append([]ManifestParser{parser}, manifestParsers...)
+}
+
+// ParseManifest returns the manifest of the lib at dir from the first parser detecting one, generic if none do
+func ParseManifest(dir string) (manifest PackageManifest, err error) {
+	manifestMux.Lock()
+	parsers := manifestParsers
+	manifestMux.Unlock()
+
+	for _, parser := range parsers {
+		var ok bool
+		if manifest, ok, err = parser.Parse(dir); err != nil {
+			err = fmt.Errorf("unable to parse %s manifest: %v", parser.Kind(), err)
+			return
+		} else if ok {
+			manifest.Kind = parser.Kind()
+			return
+		}
+	}
+
+	return PackageManifest{Kind: KindGeneric}, nil
+}
+
+// Manifest returns the file's package manifest, parsed once. Unparsable manifests are logged and treated as generic.
+// Names default to the go url
+func (file *FileWrapper) Manifest() PackageManifest {
+	if file.manifest != nil {
+		return *file.manifest
+	}
+
+	manifest, err := ParseManifest(file.Path)
+	if err != nil {
+		file.Debug(err.Error())
+		manifest = PackageManifest{Kind: KindGeneric}
+	}
+
+	if len(manifest.Name) == 0 {
+		manifest.Name = file.GetGoURL()
+	}
+
+	file.manifest = &manifest
+	return manifest
+}
+
+// IsGo returns true if the file is a go module, so mod files can be synced
+func (file *FileWrapper) IsGo() bool {
+	return file.Manifest().Kind == KindGo
+}
+
+// goModParser detects go modules by their go.mod
+type goModParser struct{}
+
+func (goModParser) Kind() string {
+	return KindGo
+}
+
+func (goModParser) Parse(dir string) (manifest PackageManifest, ok bool, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if os.IsNotExist(err) {
+		return manifest, false, nil
+	} else if err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "module" {
+			manifest.Name = strings.Trim(fields[1], `"`)
+			break
+		}
+	}
+
+	for module := range parseModules(string(data)) {
+		manifest.Deps = append(manifest.Deps, module)
+	}
+	gosort.Strings(manifest.Deps)
+
+	return manifest, true, nil
+}
+
+// npmParser detects node packages by their package.json
+type npmParser struct{}
+
+func (npmParser) Kind() string {
+	return KindNPM
+}
+
+func (npmParser) Parse(dir string) (manifest PackageManifest, ok bool, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "package.json"))
+	if os.IsNotExist(err) {
+		return manifest, false, nil
+	} else if err != nil {
+		return
+	}
+
+	var pkg struct {
+		Name                 string            `json:"name"`
+		Version              string            `json:"version"`
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+
+	if err = json.Unmarshal(data, &pkg); err != nil {
+		return
+	}
+
+	manifest.Name, manifest.Version = pkg.Name, pkg.Version
+	seen := make(map[string]bool)
+	for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.PeerDependencies, pkg.OptionalDependencies} {
+		for name := range deps {
+			if !seen[name] {
+				seen[name] = true
+				manifest.Deps = append(manifest.Deps, name)
+			}
+		}
+	}
+	gosort.Strings(manifest.Deps)
+
+	return manifest, true, nil
+}
@@ -0,0 +1,89 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// manifestLib returns a temporary lib containing files
+func manifestLib(t *testing.T, files map[string]string) (dir string) {
+	dir, err := ioutil.TempDir("", "gomu-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return
+}
+
+// cargoParser claims libs with a Cargo.toml
+type cargoParser struct{}
+
+func (cargoParser) Kind() string {
+	return "cargo"
+}
+
+func (cargoParser) Parse(dir string) (manifest PackageManifest, ok bool, err error) {
+	_, err = os.Stat(filepath.Join(dir, "Cargo.toml"))
+	return manifest, err == nil, nil
+}
+
+func TestParseManifest(t *testing.T) {
+	for _, test := range []struct {
+		files    map[string]string
+		manifest PackageManifest
+	}{
+		{map[string]string{"go.mod": "module \"github.com/org/a\"\n\nrequire (\n\tgithub.com/org/c v1.0.0\n\tgithub.com/org/b v1.2.3\n)\n"},
+			PackageManifest{Kind: KindGo, Name: "github.com/org/a", Deps: []string{"github.com/org/b", "github.com/org/c"}}},
+		{map[string]string{"package.json": `{"name": "@org/ui", "version": "2.1.0", "dependencies": {"react": "^18.0.0"}, "devDependencies": {"jest": "^29.0.0"}, "peerDependencies": {"react": "^18.0.0"}}`},
+			PackageManifest{Kind: KindNPM, Name: "@org/ui", Version: "2.1.0", Deps: []string{"jest", "react"}}},
+		// go.mod wins over package.json
+		{map[string]string{"go.mod": "module github.com/org/a\n", "package.json": `{"name": "a"}`},
+			PackageManifest{Kind: KindGo, Name: "github.com/org/a"}},
+		{map[string]string{"README.md": "# a\n"}, PackageManifest{Kind: KindGeneric}},
+	} {
+		manifest, err := ParseManifest(manifestLib(t, test.files))
+		if err != nil || !reflect.DeepEqual(manifest, test.manifest) {
+			t.Errorf("%v: expected %+v, got %+v (%v)", test.files, test.manifest, manifest, err)
+		}
+	}
+
+	if _, err := ParseManifest(manifestLib(t, map[string]string{"package.json": "{"})); err == nil {
+		t.Error("expected invalid package.json to fail")
+	}
+}
+
+func TestRegisterManifestParser(t *testing.T) {
+	previous := manifestParsers
+	t.Cleanup(func() { manifestParsers = previous })
+
+	dir := manifestLib(t, map[string]string{"Cargo.toml": "[package]\n", "package.json": `{"name": "a"}`})
+	RegisterManifestParser(cargoParser{})
+	if manifest, err := ParseManifest(dir); err != nil || manifest.Kind != "cargo" {
+		t.Errorf("expected the registered parser to claim the lib, got %+v (%v)", manifest, err)
+	}
+}
+
+func TestFileManifest(t *testing.T) {
+	file := &FileWrapper{Path: manifestLib(t, map[string]string{"package.json": "{"})}
+	if manifest := file.Manifest(); manifest.Kind != KindGeneric || manifest.Name != file.GetGoURL() || file.IsGo() {
+		t.Errorf("expected unparsable manifest to be generic and named by url, got %+v", manifest)
+	}
+
+	// Parsed once
+	if err := ioutil.WriteFile(filepath.Join(file.Path, "go.mod"), []byte("module github.com/org/a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if file.IsGo() {
+		t.Error("expected the parsed manifest to be kept")
+	}
+}
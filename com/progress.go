@@ -0,0 +1,188 @@
+package com
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Libraries averaged when estimating time remaining
+const progressWindow = 10
+
+// Width of the rendered progress bar
+const progressBarWidth = 20
+
+// Progress tracks libraries finished during a run and estimates the time remaining.
+// On a terminal it renders a status line below console output, otherwise it prints a line as each library starts
+type Progress struct {
+	total     int
+	completed int
+	failed    int
+
+	// Libraries in progress, in start order
+	running []string
+	started map[string]time.Time
+
+	// Durations of the most recently finished libraries
+	durations []time.Duration
+
+	render bool
+	mux    sync.Mutex
+}
+
+// Global progress, redrawn below console output
+var progress *Progress
+
+// NewProgress returns progress for total libraries, rendering a status line if render is true
+func NewProgress(total int, render bool) *Progress {
+	return &Progress{total: total, render: render, started: make(map[string]time.Time)}
+}
+
+// SetProgress sets the progress drawn below console output. Nil clears the status line
+func SetProgress(p *Progress) {
+	consoleMux.Lock()
+	defer consoleMux.Unlock()
+
+	if progress != nil && progress.render && shouldLog(NORMAL) {
+		fmt.Fprint(consoleOutput, "\r\033[K")
+	}
+
+	progress = p
+}
+
+// IsTerminal returns true if stdout is a terminal
+func IsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Start marks the library at index (1-based) as in progress
+func (p *Progress) Start(index int, name string) {
+	p.mux.Lock()
+	p.running = append(p.running, name)
+	p.started[name] = time.Now()
+	eta, ok := p.eta()
+	p.mux.Unlock()
+
+	if p.render {
+		p.draw()
+		return
+	}
+
+	line := "( " + strconv.Itoa(index) + " / " + strconv.Itoa(p.total) + " ) " + name
+	if ok {
+		line += " [" + p.status(eta) + "]"
+	}
+
+	// Separate output
+	Println("")
+	Println(line)
+}
+
+// Done marks the library as finished
+func (p *Progress) Done(name string, failed bool) {
+	p.mux.Lock()
+	for i, running := range p.running {
+		if running == name {
+			p.running = append(p.running[:i], p.running[i+1:]...)
+			break
+		}
+	}
+
+	if started, ok := p.started[name]; ok {
+		if p.durations = append(p.durations, time.Since(started)); len(p.durations) > progressWindow {
+			p.durations = p.durations[1:]
+		}
+		delete(p.started, name)
+	}
+
+	p.completed++
+	if failed {
+		p.failed++
+	}
+	p.mux.Unlock()
+
+	if p.render {
+		p.draw()
+	}
+}
+
+// eta returns the estimated time remaining from the average duration of recent libraries
+// Note: p.mux must be held
+func (p *Progress) eta() (eta time.Duration, ok bool) {
+	if len(p.durations) == 0 {
+		return
+	}
+
+	var sum time.Duration
+	for _, duration := range p.durations {
+		sum += duration
+	}
+
+	// Libraries in progress run concurrently
+	workers := len(p.running)
+	if workers == 0 {
+		workers = 1
+	}
+
+	remaining := p.total - p.completed
+	return sum / time.Duration(len(p.durations)) * time.Duration(remaining) / time.Duration(workers), true
+}
+
+// status returns the failure count and time remaining
+func (p *Progress) status(eta time.Duration) string {
+	status := "ETA <1s"
+	if eta >= time.Second {
+		status = "ETA " + eta.Round(time.Second).String()
+	}
+	if p.failed > 0 {
+		status = strconv.Itoa(p.failed) + " failed, " + status
+	}
+
+	return status
+}
+
+// line returns the status line, e.g. [=====>      ] 3/58 1 failed, ETA 2m10s :: github.com/org/lib
+func (p *Progress) line() string {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	filled := 0
+	if p.total > 0 {
+		filled = progressBarWidth * p.completed / p.total
+	}
+
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	line := "[" + bar + "] " + strconv.Itoa(p.completed) + "/" + strconv.Itoa(p.total)
+	if eta, ok := p.eta(); ok {
+		line += " " + p.status(eta)
+	} else if p.failed > 0 {
+		line += " " + strconv.Itoa(p.failed) + " failed"
+	}
+
+	if len(p.running) > 0 {
+		line += " :: " + p.running[0]
+		if len(p.running) > 1 {
+			line += " (+" + strconv.Itoa(len(p.running)-1) + ")"
+		}
+	}
+
+	return line
+}
+
+// draw replaces the status line
+func (p *Progress) draw() {
+	consoleMux.Lock()
+	defer consoleMux.Unlock()
+
+	if progress == p && shouldLog(NORMAL) {
+		fmt.Fprint(consoleOutput, "\r\033[K"+p.line())
+	}
+}
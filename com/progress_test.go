@@ -0,0 +1,50 @@
+package com
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	p := NewProgress(4, true)
+	if line := p.line(); line != "[>                   ] 0/4" {
+		t.Errorf("expected empty bar, got %q", line)
+	}
+
+	p.Start(1, "github.com/org/a")
+	p.Start(2, "github.com/org/b")
+	p.Done("github.com/org/a", true)
+	// Three libs left, taking 10s each one at a time
+	p.durations = []time.Duration{10 * time.Second}
+
+	if line, expected := p.line(), "[=====>              ] 1/4 1 failed, ETA 30s :: github.com/org/b"; line != expected {
+		t.Errorf("expected %q, got %q", expected, line)
+	}
+
+	p.Start(3, "github.com/org/c")
+	if line := p.line(); !strings.HasSuffix(line, "ETA 15s :: github.com/org/b (+1)") {
+		t.Errorf("expected libs running concurrently to shorten the estimate, got %q", line)
+	}
+}
+
+func TestProgressLines(t *testing.T) {
+	var output bytes.Buffer
+	SetConsoleOutput(&output)
+	t.Cleanup(func() { SetConsoleOutput(nil) })
+
+	// Off a terminal, a line is printed as each lib starts
+	p := NewProgress(2, false)
+	SetProgress(p)
+	t.Cleanup(func() { SetProgress(nil) })
+
+	p.Start(1, "github.com/org/a")
+	p.Done("github.com/org/a", false)
+	p.Start(2, "github.com/org/b")
+
+	expected := "\n( 1 / 2 ) github.com/org/a\n\n( 2 / 2 ) github.com/org/b [ETA <1s]\n"
+	if output.String() != expected {
+		t.Errorf("expected %q, got %q", expected, output.String())
+	}
+}
@@ -0,0 +1,318 @@
+package com
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// Provider represents a git host capable of managing pull requests
+type Provider interface {
+	// Name returns the display name of the provider
+	Name() string
+	// CreatePR opens a pull request on repo (owner/name)
+	CreatePR(repo string, request PRRequest) (status *PRResponse, err error)
+	// ListPRs returns open pull requests on repo (owner/name)
+	ListPRs(repo string) (prs []PRResponse, err error)
+	// GetDefaultBranch returns the default branch of repo (owner/name)
+	GetDefaultBranch(repo string) (branch string, err error)
+	// ClosePR closes pull request number on repo (owner/name)
+	ClosePR(repo string, number int) (err error)
+	// UpdatePR replaces the title and body of pull request number on repo (owner/name)
+	UpdatePR(repo string, number int, title, body string) (err error)
+	// EnableAutoMerge merges pull request pr on repo (owner/name) with method once checks pass
+	EnableAutoMerge(repo string, pr PRResponse, method string) (err error)
+	// CreateRelease publishes a release for an existing tag on repo (owner/name), returning its url
+	CreateRelease(repo, tag, notes string) (releaseURL string, err error)
+	// UploadReleaseAsset attaches data as file name to the release of tag on repo (owner/name), returning its download url
+	UploadReleaseAsset(repo, tag, name string, data []byte) (assetURL string, err error)
+	// PushBlocked returns true if protection rules on repo (owner/name) prevent pushing directly to branch
+	PushBlocked(repo, branch string) (blocked bool, err error)
+	// CheckStatus returns the aggregate state of checks on pull request pr of repo (owner/name): CheckPending,
+	// CheckSuccess or CheckFailure. Pull requests without checks succeed
+	CheckStatus(repo string, pr PRResponse) (state string, err error)
+	// MergePR merges pull request pr on repo (owner/name) with method
+	MergePR(repo string, pr PRResponse, method string) (err error)
+}
+
+// Aggregate states of pull request checks
+const (
+	CheckPending = "pending"
+	CheckSuccess = "success"
+	CheckFailure = "failure"
+)
+
+// Merge methods used when auto-merging pull requests
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+// Self-hosted providers git hosts can be mapped to
+const (
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+)
+
+// Global providers of self-hosted git hosts, keyed by host
+var providerHosts = map[string]string{}
+
+// SetProviderHosts sets the providers of self-hosted git hosts globally, e.g. git.example.com: gitea
+func SetProviderHosts(hosts map[string]string) (err error) {
+	for host, name := range hosts {
+		switch name {
+		case ProviderGitLab, ProviderGitea:
+		default:
+			err = fmt.Errorf("unknown provider %q for %s, expected gitlab or gitea", name, host)
+			return
+		}
+	}
+
+	providerHosts = hosts
+	return
+}
+
+// providerName returns the provider host is mapped to, or detected from its name. Empty if unknown
+func providerName(host string) string {
+	if name, ok := providerHosts[host]; ok {
+		return name
+	}
+
+	switch {
+	case strings.Contains(host, "gitlab"):
+		return ProviderGitLab
+	case strings.Contains(host, "gitea"):
+		return ProviderGitea
+	default:
+		return ""
+	}
+}
+
+// ProviderFor returns the pull request provider for a git host
+func ProviderFor(host string) (provider Provider, err error) {
+	switch name := providerName(host); {
+	case name == ProviderGitLab:
+		return &gitLabProvider{host: host}, nil
+	case name == ProviderGitea:
+		return &giteaProvider{host: host}, nil
+	case host == "github.com":
+		return &gitHubProvider{host: host}, nil
+	case host == "bitbucket.org":
+		return &bitbucketProvider{host: host}, nil
+	case host == AzureHost:
+		return &azureProvider{host: host}, nil
+	default:
+		err = fmt.Errorf("%s currently not supported for pull requests", host)
+		return
+	}
+}
+
+// Remotes branches and tags are pushed to, and pull requests of forks are opened on
+const (
+	DefaultRemote  = "origin"
+	UpstreamRemote = "upstream"
+)
+
+// Global remote branches and tags are pushed to
+var pushRemote = DefaultRemote
+
+// SetRemote sets the remote branches and tags are pushed to globally, origin if empty
+func SetRemote(name string) {
+	if len(name) == 0 {
+		name = DefaultRemote
+	}
+
+	pushRemote = name
+}
+
+// PushRemote returns the remote branches and tags are pushed to
+func PushRemote() string {
+	return pushRemote
+}
+
+// Provider returns the pull request provider and repo (owner/name) pull requests are opened on,
+// the upstream repo if the push remote is a fork
+func (file *FileWrapper) Provider() (provider Provider, repo string, err error) {
+	host, repo, _ := file.Upstream()
+	provider, err = ProviderFor(host)
+	return
+}
+
+// Remote returns the host and repo (owner/name) of the push remote.
+// Falls back to the go url if the remote can't be parsed
+func (file *FileWrapper) Remote() (host, repo string) {
+	if host, repo = file.remoteRepo(pushRemote); len(host) > 0 {
+		return
+	}
+
+	comps := strings.Split(file.GetGoURL(), "/")
+	return comps[0], strings.Join(comps[1:], "/")
+}
+
+// Upstream returns the host and repo (owner/name) of the upstream remote if the push remote is a fork of it
+// on the same host, otherwise those of the push remote
+func (file *FileWrapper) Upstream() (host, repo string, fork bool) {
+	host, repo = file.Remote()
+	if pushRemote == UpstreamRemote {
+		return
+	}
+
+	upstreamHost, upstreamRepo := file.remoteRepo(UpstreamRemote)
+	if upstreamHost != host || len(upstreamRepo) == 0 || upstreamRepo == repo {
+		return
+	}
+
+	return upstreamHost, upstreamRepo, true
+}
+
+// remoteRepo returns the host and repo (owner/name) of remote name, empty if it doesn't exist or can't be parsed
+func (file *FileWrapper) remoteRepo(name string) (host, repo string) {
+	remoteURL, err := file.CmdOutput("git", "remote", "get-url", name)
+	if err != nil {
+		return
+	}
+
+	if host, repo = parseRemote(remoteURL); len(host) == 0 || len(repo) == 0 {
+		return "", ""
+	}
+
+	return
+}
+
+// forkHead returns the head of request as owner:branch if pushed to a fork, as github and gitea expect
+func forkHead(request PRRequest) string {
+	if len(request.HeadRepo) == 0 {
+		return request.Head
+	}
+
+	return strings.SplitN(request.HeadRepo, "/", 2)[0] + ":" + request.Head
+}
+
+// parseRemote parses host and repo from https, ssh and scp-style remote urls
+func parseRemote(remoteURL string) (host, repo string) {
+	remoteURL = strings.TrimSuffix(strings.TrimSpace(remoteURL), ".git")
+
+	if index := strings.Index(remoteURL, "://"); index >= 0 {
+		// https://host/owner/name or ssh://git@host:port/owner/name
+		remoteURL = remoteURL[index+3:]
+	} else if index := strings.Index(remoteURL, ":"); index >= 0 {
+		// git@host:owner/name
+		remoteURL = remoteURL[:index] + "/" + remoteURL[index+1:]
+	}
+
+	// Strip user info
+	if index := strings.Index(remoteURL, "@"); index >= 0 {
+		remoteURL = remoteURL[index+1:]
+	}
+
+	comps := strings.SplitN(remoteURL, "/", 2)
+	if len(comps) != 2 {
+		return
+	}
+
+	// Strip port
+	host = strings.Split(comps[0], ":")[0]
+	repo = strings.Trim(comps[1], "/")
+	return parseAzureRemote(host, repo)
+}
+
+// PostJSON posts body as json to urlStr, e.g. a webhook
+func PostJSON(urlStr string, body interface{}) (err error) {
+	_, err = apiRequest("POST", urlStr, nil, body, nil)
+	return
+}
+
+// PostJSONWithHeaders posts body as json to urlStr with headers set, e.g. credentials of a telemetry collector
+func PostJSONWithHeaders(urlStr string, headers map[string]string, body interface{}) (err error) {
+	_, err = apiRequest("POST", urlStr, headers, body, nil)
+	return
+}
+
+// apiRequest sends a json request and decodes the json response into payload, returning the http status
+func apiRequest(method, urlStr string, headers map[string]string, body, payload interface{}) (status int, err error) {
+	var reader *bytes.Buffer
+	if body != nil {
+		var data []byte
+		if data, err = json.Marshal(body); err != nil {
+			err = fmt.Errorf("Unable to parse request params")
+			return
+		}
+		reader = bytes.NewBuffer(data)
+	} else {
+		reader = bytes.NewBuffer(nil)
+	}
+
+	return sendRequest(method, urlStr, "application/json", headers, reader, payload)
+}
+
+// uploadRequest sends data with contentType, e.g. a file, and decodes the json response into payload, returning the http status
+func uploadRequest(method, urlStr, contentType string, headers map[string]string, data []byte, payload interface{}) (status int, err error) {
+	return sendRequest(method, urlStr, contentType, headers, bytes.NewReader(data), payload)
+}
+
+// multipartFile returns a multipart form with data as file name in field, and its content type
+func multipartFile(field, name string, data []byte) (contentType string, body []byte, err error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	part, err := writer.CreateFormFile(field, name)
+	if err != nil {
+		return
+	}
+
+	if _, err = part.Write(data); err != nil {
+		return
+	}
+
+	if err = writer.Close(); err != nil {
+		return
+	}
+
+	return writer.FormDataContentType(), buffer.Bytes(), nil
+}
+
+// sendRequest sends body with contentType and decodes the json response into payload, returning the http status
+func sendRequest(method, urlStr, contentType string, headers map[string]string, body io.Reader, payload interface{}) (status int, err error) {
+	req, err := http.NewRequest(method, urlStr, body)
+	if err != nil {
+		return
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+
+	// Execute Request
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	var respBody []byte
+	if respBody, err = ioutil.ReadAll(resp.Body); err != nil {
+		return
+	}
+
+	status = resp.StatusCode
+	if payload != nil && len(respBody) > 0 {
+		if jsonErr := json.Unmarshal(respBody, payload); jsonErr != nil && status < 300 {
+			err = jsonErr
+		}
+	}
+
+	if status >= 300 && err == nil {
+		err = fmt.Errorf("Http error %d", status)
+	}
+
+	return
+}
@@ -0,0 +1,152 @@
+package com
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ProxyOff disables resolving versions from module proxies
+const ProxyOff = "off"
+
+// Global module proxies queried for latest versions, in GOPROXY syntax, and module path patterns bypassing them
+var (
+	proxyList string
+	noProxy   string
+)
+
+// errProxyNotFound is returned when no proxy knows a module, or it bypasses proxies
+var errProxyNotFound = fmt.Errorf("not found in module proxies")
+
+// SetProxy sets the module proxies latest versions are resolved from globally, e.g. https://athens.example.com,direct.
+// Defaults to go env GOPROXY if empty. Modules matching GONOPROXY, or GOPRIVATE if unset, are never looked up
+func SetProxy(proxy string) {
+	env := goEnv("GOPROXY", "GONOPROXY", "GOPRIVATE")
+	if len(proxy) == 0 {
+		proxy = env["GOPROXY"]
+	}
+
+	noProxy = env["GONOPROXY"]
+	if len(noProxy) == 0 {
+		noProxy = env["GOPRIVATE"]
+	}
+
+	proxyList = proxy
+}
+
+// goEnv returns the go env variables named, set by the environment or go env -w
+func goEnv(names ...string) (env map[string]string) {
+	env = make(map[string]string)
+	cmd := Command{Name: GoBinary(), Args: append([]string{"env"}, names...), Env: Environ()}
+	output, err := GlobalRunner().Output(context.Background(), cmd)
+	if err != nil {
+		return
+	}
+
+	values := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	for i, name := range names {
+		if i < len(values) {
+			env[name] = strings.TrimSpace(values[i])
+		}
+	}
+
+	return
+}
+
+// matchesModulePatterns is true if modulePath or one of its parents matches a comma separated glob in patterns,
+// e.g. github.com/org/* matches github.com/org/lib/v2 (as GOPRIVATE does)
+func matchesModulePatterns(patterns, modulePath string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		if len(pattern) == 0 {
+			continue
+		}
+
+		// Match against the prefix with as many elements as the pattern
+		elements := strings.Split(modulePath, "/")
+		count := strings.Count(pattern, "/") + 1
+		if count > len(elements) {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, strings.Join(elements[:count], "/")); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// escapeModulePath escapes uppercase letters as ! followed by the lowercase letter, as proxies expect
+func escapeModulePath(modulePath string) string {
+	var escaped strings.Builder
+	for _, r := range modulePath {
+		if r >= 'A' && r <= 'Z' {
+			escaped.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		escaped.WriteRune(r)
+	}
+
+	return escaped.String()
+}
+
+// ProxyLatest returns the latest version of modulePath known to the module proxies, trying each in order.
+// Returns an error if proxies are off, the module is private, or no proxy before direct knows it
+func ProxyLatest(modulePath string) (version string, err error) {
+	if len(proxyList) == 0 || proxyList == ProxyOff || matchesModulePatterns(noProxy, modulePath) {
+		return "", errProxyNotFound
+	}
+
+	// Proxies separated by commas are only skipped if they don't know the module, by pipes on any error
+	list := proxyList
+	for len(list) > 0 {
+		proxy := list
+		fallback := false
+		if i := strings.IndexAny(list, ",|"); i >= 0 {
+			proxy, fallback, list = list[:i], list[i] == '|', list[i+1:]
+		} else {
+			list = ""
+		}
+
+		switch proxy = strings.TrimSpace(proxy); proxy {
+		case "direct", ProxyOff:
+			// Remaining proxies aren't consulted, versions are read from git
+			return "", errProxyNotFound
+		case "":
+			continue
+		}
+
+		var info struct {
+			Version string
+		}
+
+		var status int
+		latestURL := strings.TrimSuffix(proxy, "/") + "/" + escapeModulePath(modulePath) + "/@latest"
+		status, err = apiRequest("GET", latestURL, nil, nil, &info)
+		switch {
+		case status == http.StatusNotFound || status == http.StatusGone:
+			// Try the next proxy
+			err = errProxyNotFound
+			continue
+		case err == nil && len(info.Version) > 0:
+			return info.Version, nil
+		case err == nil:
+			err = fmt.Errorf("%s returned no version for %s", proxy, modulePath)
+		default:
+			err = fmt.Errorf("unable to query %s for %s: %v", proxy, modulePath, err)
+		}
+
+		if !fallback {
+			return
+		}
+	}
+
+	if err == nil {
+		err = errProxyNotFound
+	}
+
+	return
+}
@@ -0,0 +1,86 @@
+package com
+
+import (
+	"net/http"
+	"testing"
+)
+
+// setProxy sets the module proxies and module patterns bypassing them until the test finishes
+func setProxy(t *testing.T, proxies, bypass string) {
+	previousProxies, previousBypass := proxyList, noProxy
+	proxyList, noProxy = proxies, bypass
+	t.Cleanup(func() { proxyList, noProxy = previousProxies, previousBypass })
+}
+
+func TestMatchesModulePatterns(t *testing.T) {
+	for modulePath, expected := range map[string]bool{
+		"github.com/org/lib":    true,
+		"github.com/org/lib/v2": true,
+		"gitlab.com/corp":       true,
+		"github.com/other/lib":  false,
+		"github.com":            false,
+	} {
+		if matched := matchesModulePatterns("github.com/org/*, gitlab.com/corp/", modulePath); matched != expected {
+			t.Errorf("expected %s matched %v, got %v", modulePath, expected, matched)
+		}
+	}
+
+	if escaped := escapeModulePath("github.com/BurntSushi/toml"); escaped != "github.com/!burnt!sushi/toml" {
+		t.Errorf("expected uppercase letters to be escaped, got %s", escaped)
+	}
+}
+
+func TestProxyLatest(t *testing.T) {
+	setProxy(t, "https://a.example.com, https://b.example.com|https://c.example.com/,direct", "github.com/private/*")
+
+	var requested []string
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		requested = append(requested, req.URL.Host)
+		switch req.URL.Host {
+		case "a.example.com":
+			return http.StatusNotFound, "not found"
+		case "b.example.com":
+			return http.StatusBadGateway, `{"message": "unavailable"}`
+		}
+
+		if req.URL.Path != "/github.com/!org/lib/@latest" {
+			t.Errorf("unexpected request %s", req.URL)
+		}
+		return http.StatusOK, `{"Version": "v1.4.0", "Time": "2020-01-02T15:04:05Z"}`
+	})
+
+	if version, err := ProxyLatest("github.com/Org/lib"); err != nil || version != "v1.4.0" {
+		t.Errorf("expected v1.4.0 from the last proxy, got %q (%v)", version, err)
+	}
+	if len(requested) != 3 {
+		t.Errorf("expected each proxy to be tried in order, got %q", requested)
+	}
+
+	// Private modules are never looked up
+	requested = nil
+	if _, err := ProxyLatest("github.com/private/lib"); err != errProxyNotFound || len(requested) > 0 {
+		t.Errorf("expected private module not to be looked up, got %v after %q", err, requested)
+	}
+}
+
+func TestProxyLatestFails(t *testing.T) {
+	// Errors only fall back to the next proxy after a pipe
+	setProxy(t, "https://b.example.com,https://c.example.com", "")
+	stubAPI(t, func(req *http.Request) (status int, body string) {
+		if req.URL.Host != "b.example.com" {
+			t.Errorf("unexpected request %s", req.URL)
+		}
+		return http.StatusBadGateway, `{"message": "unavailable"}`
+	})
+
+	if _, err := ProxyLatest("github.com/org/lib"); err == nil || err == errProxyNotFound {
+		t.Errorf("expected proxy error, got %v", err)
+	}
+
+	for _, proxies := range []string{"", ProxyOff, "direct"} {
+		setProxy(t, proxies, "")
+		if _, err := ProxyLatest("github.com/org/lib"); err != errProxyNotFound {
+			t.Errorf("expected no proxy lookup with %q, got %v", proxies, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package com
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// Push authentication modes
+const (
+	// PushAuthDefault leaves authentication to git's own config (credential helpers, ssh remotes)
+	PushAuthDefault = ""
+	// PushAuthSSH pushes https remotes over ssh, authenticating with the ssh agent
+	PushAuthSSH = "ssh"
+	// PushAuthToken pushes https remotes with the loaded api token (environment, gh cli or saved)
+	PushAuthToken = "token"
+)
+
+// Global push authentication mode
+var pushAuth = PushAuthDefault
+
+// SetPushAuth sets how pushes are authenticated globally
+func SetPushAuth(mode string) (err error) {
+	switch mode {
+	case PushAuthDefault, PushAuthSSH, PushAuthToken:
+		pushAuth = mode
+	default:
+		err = fmt.Errorf("unknown push auth %q, expected ssh or token", mode)
+	}
+
+	return
+}
+
+// GetPushAuth returns how pushes are authenticated
+func GetPushAuth() string {
+	return pushAuth
+}
+
+// pushAuthArgs returns git config args authenticating a push to the file's remote
+func (file *FileWrapper) pushAuthArgs() (args []string) {
+	if pushAuth == PushAuthDefault {
+		return
+	}
+
+	host, _ := file.Remote()
+	switch pushAuth {
+	case PushAuthSSH:
+		if host == AzureHost {
+			// Azure ssh paths differ from https paths (no _git), a prefix can't be rewritten
+			file.Debug("Ssh push auth unsupported for " + host + ", pushing with git credentials")
+			return
+		}
+
+		// Only rewrites push urls, fetches are unaffected
+		return []string{"-c", "url.git@" + host + ":.pushInsteadOf=https://" + host + "/"}
+	case PushAuthToken:
+		user, token := pushCredentials(host)
+		if len(token) == 0 {
+			file.Debug("No token found for " + host + ", pushing with git credentials")
+			return
+		}
+
+		credentials := base64.StdEncoding.EncodeToString([]byte(user + ":" + token))
+		return []string{"-c", "http.https://" + host + "/.extraheader=AUTHORIZATION: basic " + credentials}
+	}
+
+	return
+}
+
+// pushCredentials returns the basic auth user and token for pushing to host, without prompting
+func pushCredentials(host string) (user, token string) {
+	if host == "github.com" {
+		authObject, err := LoadAuth()
+		if err != nil {
+			return
+		}
+
+		// Github accepts any user with a token
+		return "x-access-token", authObject.Token
+	}
+
+	auth, err := LoadHostAuth(host)
+	if err != nil {
+		return
+	}
+
+	if user = auth.User; len(user) == 0 {
+		user = "oauth2"
+	}
+
+	return user, auth.Token
+}
@@ -0,0 +1,47 @@
+package com
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+// setPushAuth sets the push auth mode until the test finishes
+func setPushAuth(t *testing.T, mode string) {
+	previous := GetPushAuth()
+	if err := SetPushAuth(mode); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPushAuth(previous) })
+}
+
+func TestPushAuthArgs(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git remote get-url origin", Reply{Stdout: "https://github.com/org/a.git\n"})
+
+	if args := file.pushAuthArgs(); len(args) > 0 {
+		t.Errorf("expected git's own authentication by default, got %q", args)
+	}
+
+	setPushAuth(t, PushAuthSSH)
+	expected := []string{"-c", "url.git@github.com:.pushInsteadOf=https://github.com/"}
+	if args := file.pushAuthArgs(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+
+	setPushAuth(t, PushAuthToken)
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:gh-token"))
+	expected = []string{"-c", "http.https://github.com/.extraheader=AUTHORIZATION: basic " + credentials}
+	if args := file.pushAuthArgs(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+}
+
+func TestSetPushAuthUnknown(t *testing.T) {
+	if err := SetPushAuth("password"); err == nil {
+		t.Error("expected unknown push auth to fail")
+	}
+	if GetPushAuth() != PushAuthDefault {
+		t.Errorf("expected push auth to be unchanged, got %q", GetPushAuth())
+	}
+}
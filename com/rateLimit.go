@@ -0,0 +1,297 @@
+package com
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy configures how api requests are paced and backed off when hosts rate limit them
+type RateLimitPolicy struct {
+	// Interval is the min delay between mutating requests (POST, PATCH, PUT, DELETE) to a host, which are queued
+	// so bursts of pull requests don't trip abuse detection. Defaults to DefaultAPIInterval, negative disables spacing
+	Interval time.Duration
+	// MaxWait caps how long a request waits for a rate limit before failing. Defaults to DefaultRateLimitWait
+	MaxWait time.Duration
+}
+
+// RateLimitStats counts api requests and time spent waiting on rate limits
+type RateLimitStats struct {
+	Requests int           `json:"requests"`
+	Waits    int           `json:"waits"`    // Requests delayed until a rate limit reset or backoff ended
+	Backoffs int           `json:"backoffs"` // Requests retried after being rate limited
+	Waited   time.Duration `json:"waited"`
+}
+
+// Defaults of rate limit policies
+const (
+	DefaultAPIInterval   = time.Second
+	DefaultRateLimitWait = 5 * time.Minute
+)
+
+// secondaryBackoff is the first delay after a secondary rate limit without a Retry-After header, as github recommends
+const secondaryBackoff = time.Minute
+
+// hostLimiter paces requests to a single api host
+type hostLimiter struct {
+	// Held by mutating requests while sent, queueing them
+	queue sync.Mutex
+
+	mux          sync.Mutex
+	resumeAt     time.Time // No requests are sent until then
+	lastMutation time.Time
+}
+
+// Global rate limit policy, limiters keyed by host and stats
+var (
+	rateLimitPolicy = RateLimitPolicy{Interval: DefaultAPIInterval, MaxWait: DefaultRateLimitWait}
+	limiters        = map[string]*hostLimiter{}
+	rateLimitStats  RateLimitStats
+	rateLimitMux    sync.Mutex
+)
+
+// apiClient sends every api request, respecting rate limits of each host
+var apiClient = &http.Client{Transport: &rateLimitTransport{base: http.DefaultTransport}}
+
+// SetRateLimitPolicy sets the rate limit policy globally, resetting limiters and stats
+func SetRateLimitPolicy(policy RateLimitPolicy) {
+	if policy.Interval == 0 {
+		policy.Interval = DefaultAPIInterval
+	}
+	if policy.MaxWait == 0 {
+		policy.MaxWait = DefaultRateLimitWait
+	}
+
+	rateLimitMux.Lock()
+	defer rateLimitMux.Unlock()
+
+	rateLimitPolicy = policy
+	limiters = map[string]*hostLimiter{}
+	rateLimitStats = RateLimitStats{}
+}
+
+// GetRateLimitStats returns api requests sent and time spent waiting on rate limits since the policy was set
+func GetRateLimitStats() RateLimitStats {
+	rateLimitMux.Lock()
+	defer rateLimitMux.Unlock()
+
+	return rateLimitStats
+}
+
+// limiterFor returns the limiter of host, creating it if needed
+func limiterFor(host string) *hostLimiter {
+	rateLimitMux.Lock()
+	defer rateLimitMux.Unlock()
+
+	limiter, ok := limiters[host]
+	if !ok {
+		limiter = &hostLimiter{}
+		limiters[host] = limiter
+	}
+
+	return limiter
+}
+
+// countRequest records a request sent, and how long it waited on a rate limit first
+func countRequest(waited time.Duration, limited, backoff bool) {
+	rateLimitMux.Lock()
+	defer rateLimitMux.Unlock()
+
+	rateLimitStats.Requests++
+	if limited {
+		rateLimitStats.Waits++
+		rateLimitStats.Waited += waited
+	}
+	if backoff {
+		rateLimitStats.Backoffs++
+	}
+}
+
+// delay returns how long a request must wait before it's sent, limited if waiting on a rate limit rather than spacing
+func (limiter *hostLimiter) delay(mutating bool) (wait time.Duration, limited bool) {
+	limiter.mux.Lock()
+	defer limiter.mux.Unlock()
+
+	now := time.Now()
+	wait = limiter.resumeAt.Sub(now)
+	limited = wait > 0
+	if mutating {
+		if spacing := limiter.lastMutation.Add(rateLimitPolicy.Interval).Sub(now); spacing > wait {
+			wait, limited = spacing, false
+		}
+	}
+
+	return
+}
+
+// pause stops requests being sent until resumeAt, unless already paused for longer
+func (limiter *hostLimiter) pause(resumeAt time.Time) {
+	limiter.mux.Lock()
+	defer limiter.mux.Unlock()
+
+	if resumeAt.After(limiter.resumeAt) {
+		limiter.resumeAt = resumeAt
+	}
+}
+
+// sent records a request was sent, spacing mutating requests after it
+func (limiter *hostLimiter) sent(mutating bool) {
+	if !mutating {
+		return
+	}
+
+	limiter.mux.Lock()
+	limiter.lastMutation = time.Now()
+	limiter.mux.Unlock()
+}
+
+// rateLimitHeader returns the first of the github/gitea (X-RateLimit-) or gitlab (RateLimit-) header name set
+func rateLimitHeader(header http.Header, name string) string {
+	if value := header.Get("X-RateLimit-" + name); len(value) > 0 {
+		return value
+	}
+
+	return header.Get("RateLimit-" + name)
+}
+
+// rateLimitReset returns when the rate limit of a response resets, zero if it isn't exhausted
+func rateLimitReset(header http.Header) (reset time.Time) {
+	if rateLimitHeader(header, "Remaining") != "0" {
+		return
+	}
+
+	seconds, err := strconv.ParseInt(rateLimitHeader(header, "Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	return time.Unix(seconds, 0)
+}
+
+// retryAfter parses the Retry-After header as seconds or an http date. Zero if unset
+func retryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// rateLimited returns true if resp was rejected by a rate limit, and how long to wait before retrying (zero if unknown).
+// Github rejects secondary rate limits with a 403 rather than a 429, so their bodies are checked
+func rateLimited(resp *http.Response) (wait time.Duration, limited bool) {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		limited = true
+	case http.StatusForbidden:
+		if reset := rateLimitReset(resp.Header); !reset.IsZero() {
+			return time.Until(reset), true
+		}
+
+		// Restore the body for the caller once read
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		message := strings.ToLower(string(body))
+		limited = len(resp.Header.Get("Retry-After")) > 0 || strings.Contains(message, "rate limit") || strings.Contains(message, "abuse")
+	}
+
+	if limited {
+		wait = retryAfter(resp.Header)
+	}
+
+	return
+}
+
+// rateLimitTransport sends requests once rate limits of their host allow, queueing mutating requests and retrying
+// those rejected by rate limits after they reset or with backoff
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip sends req, waiting at most the policy's max wait for rate limits
+func (transport *rateLimitTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	host := req.URL.Host
+	limiter := limiterFor(host)
+	mutating := req.Method != "GET" && req.Method != "HEAD"
+	if mutating {
+		limiter.queue.Lock()
+		defer limiter.queue.Unlock()
+	}
+
+	deadline := time.Now().Add(rateLimitPolicy.MaxWait)
+	backoff := secondaryBackoff
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			if attemptReq, err = retryRequest(req); err != nil {
+				return
+			}
+		}
+
+		wait, limited := limiter.delay(mutating)
+		if wait > 0 {
+			if time.Now().Add(wait).After(deadline) {
+				err = fmt.Errorf("rate limited by %s for %s, longer than the max wait of %s", host, wait.Round(time.Second), rateLimitPolicy.MaxWait)
+				return
+			}
+
+			if limited {
+				Println("Rate limited by", host+", waiting", wait.Round(time.Second).String()+"...")
+			}
+			time.Sleep(wait)
+		}
+
+		resp, err = transport.base.RoundTrip(attemptReq)
+		limiter.sent(mutating)
+		countRequest(wait, limited, attempt > 0)
+		if err != nil {
+			return
+		}
+
+		if reset := rateLimitReset(resp.Header); !reset.IsZero() {
+			// Hold further requests until the limit resets
+			limiter.pause(reset)
+		}
+
+		retryWait, limited := rateLimited(resp)
+		if !limited {
+			return
+		}
+
+		if retryWait <= 0 {
+			retryWait = backoff
+			backoff *= 2
+		}
+
+		if time.Now().Add(retryWait).After(deadline) {
+			// Leave the rejection to the caller
+			return
+		}
+
+		resp.Body.Close()
+		limiter.pause(time.Now().Add(retryWait))
+	}
+}
+
+// retryRequest returns a copy of req with a fresh body, so it can be sent again
+func retryRequest(req *http.Request) (retry *http.Request, err error) {
+	retry = req.Clone(req.Context())
+	if req.Body == nil || req.GetBody == nil {
+		return
+	}
+
+	retry.Body, err = req.GetBody()
+	return
+}
@@ -0,0 +1,138 @@
+package com
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRateLimitPolicy sets policy until the test finishes
+func setRateLimitPolicy(t *testing.T, policy RateLimitPolicy) {
+	SetRateLimitPolicy(policy)
+	t.Cleanup(func() { SetRateLimitPolicy(RateLimitPolicy{}) })
+}
+
+// rateLimitedTransport replies with each response in turn, counting requests sent
+func rateLimitedTransport(sent *int, responses ...*http.Response) *rateLimitTransport {
+	return &rateLimitTransport{base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := responses[*sent]
+		*sent++
+		resp.Request = req
+		return resp, nil
+	})}
+}
+
+func response(status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(strings.NewReader(body))}
+}
+
+func TestRateLimited(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	for _, test := range []struct {
+		name    string
+		resp    *http.Response
+		limited bool
+		wait    bool
+	}{
+		{"too many requests", response(http.StatusTooManyRequests, nil, ""), true, false},
+		{"retry after", response(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"30"}}, ""), true, true},
+		{"exhausted", response(http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": []string{"0"}, "X-Ratelimit-Reset": []string{reset}}, ""), true, true},
+		{"gitlab exhausted", response(http.StatusForbidden, http.Header{"Ratelimit-Remaining": []string{"0"}, "Ratelimit-Reset": []string{reset}}, ""), true, true},
+		{"secondary", response(http.StatusForbidden, nil, `{"message": "You have exceeded a secondary rate limit"}`), true, false},
+		{"forbidden", response(http.StatusForbidden, nil, `{"message": "Resource not accessible"}`), false, false},
+		{"ok", response(http.StatusOK, nil, ""), false, false},
+	} {
+		wait, limited := rateLimited(test.resp)
+		if limited != test.limited || (wait > 0) != test.wait {
+			t.Errorf("%s: expected limited %v with wait %v, got %v with %s", test.name, test.limited, test.wait, limited, wait)
+		}
+	}
+
+	// The body is left for the caller
+	resp := response(http.StatusForbidden, nil, "denied")
+	rateLimited(resp)
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "denied" {
+		t.Errorf("expected body to be restored, got %q", body)
+	}
+}
+
+func TestRateLimitRetries(t *testing.T) {
+	setRateLimitPolicy(t, RateLimitPolicy{Interval: -1, MaxWait: 10 * time.Second})
+	SetConsoleOutput(ioutil.Discard)
+	defer SetConsoleOutput(nil)
+
+	var sent int
+	transport := rateLimitedTransport(&sent,
+		response(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}}, ""),
+		response(http.StatusOK, nil, ""),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/repos", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || sent != 2 {
+		t.Errorf("expected request to succeed once retried, got %d after %d requests", resp.StatusCode, sent)
+	}
+
+	stats := GetRateLimitStats()
+	if stats.Requests != 2 || stats.Backoffs != 1 || stats.Waits != 1 || stats.Waited <= 0 {
+		t.Errorf("expected the wait to be counted, got %+v", stats)
+	}
+}
+
+func TestRateLimitMaxWait(t *testing.T) {
+	setRateLimitPolicy(t, RateLimitPolicy{Interval: -1, MaxWait: time.Second})
+
+	// Backing off a secondary rate limit takes longer than the max wait
+	var sent int
+	transport := rateLimitedTransport(&sent, response(http.StatusForbidden, nil, "abuse detection"))
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/pulls", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden || sent != 1 {
+		t.Errorf("expected rejection to be left to the caller, got %d after %d requests", resp.StatusCode, sent)
+	}
+
+	// Held until the reset, which is too far off
+	limiterFor("api.example.com").pause(time.Now().Add(time.Hour))
+	if _, err = transport.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "max wait") {
+		t.Errorf("expected request to fail rather than wait for the reset, got %v", err)
+	}
+}
+
+func TestRateLimitSpacesMutations(t *testing.T) {
+	setRateLimitPolicy(t, RateLimitPolicy{Interval: 100 * time.Millisecond})
+
+	var sent int
+	transport := rateLimitedTransport(&sent, response(http.StatusOK, nil, ""), response(http.StatusOK, nil, ""), response(http.StatusOK, nil, ""))
+
+	start := time.Now()
+	get, _ := http.NewRequest(http.MethodGet, "https://api.example.com/repos", nil)
+	post, _ := http.NewRequest(http.MethodPost, "https://api.example.com/pulls", nil)
+	for _, req := range []*http.Request{post, get} {
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("expected reads not to be spaced, took %s", elapsed)
+	}
+
+	if _, err := transport.RoundTrip(post); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected mutations to be spaced by the interval, took %s", elapsed)
+	}
+}
@@ -0,0 +1,100 @@
+package com
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy configures retries of network operations (fetch, pull, push and pull request api calls)
+type RetryPolicy struct {
+	// Retries is the max retries per operation. Zero disables retries
+	Retries int
+	// Backoff is the delay before the first retry, doubled after each attempt
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. Zero is uncapped
+	MaxBackoff time.Duration
+	// Budget is the max retries across all operations. Zero is unlimited
+	Budget int
+}
+
+// DefaultRetryBackoff is used if a policy has retries but no backoff
+const DefaultRetryBackoff = time.Second
+
+// Global retry policy
+var retryPolicy RetryPolicy
+
+// Retries used from the budget
+var retriesUsed int64
+
+// SetRetryPolicy sets the retry policy globally and resets the retry budget
+func SetRetryPolicy(policy RetryPolicy) {
+	if policy.Retries > 0 && policy.Backoff <= 0 {
+		policy.Backoff = DefaultRetryBackoff
+	}
+
+	retryPolicy = policy
+	atomic.StoreInt64(&retriesUsed, 0)
+}
+
+// GetRetryPolicy returns the current retry policy
+func GetRetryPolicy() RetryPolicy {
+	return retryPolicy
+}
+
+// RunCmdWithRetry executes a shell command at the file's path, retrying failures according to the retry policy
+// Note: timeouts are not retried
+func (file *FileWrapper) RunCmdWithRetry(args ...string) (err error) {
+	return file.retry(args[0]+" "+args[1], func() (retryable bool, err error) {
+		err = file.RunCmd(args...)
+		return err != nil && !file.TimedOut, err
+	})
+}
+
+// retry calls attempt until it succeeds, fails with a non-retryable error, or retries are exhausted
+func (file *FileWrapper) retry(operation string, attempt func() (retryable bool, err error)) (err error) {
+	backoff := retryPolicy.Backoff
+	for retries := 0; ; retries++ {
+		var retryable bool
+		if retryable, err = attempt(); err == nil || !retryable {
+			return
+		}
+
+		if retries >= retryPolicy.Retries || !takeRetry() {
+			return
+		}
+
+		file.Output("Retrying " + operation + " in " + backoff.String() + " (" + strconv.Itoa(retries+1) + "/" + strconv.Itoa(retryPolicy.Retries) + ")...")
+
+		select {
+		case <-time.After(backoff):
+		case <-file.Context().Done():
+			// Don't wait past a timeout
+			return
+		}
+
+		file.Retries++
+		if backoff *= 2; retryPolicy.MaxBackoff > 0 && backoff > retryPolicy.MaxBackoff {
+			backoff = retryPolicy.MaxBackoff
+		}
+	}
+}
+
+// takeRetry uses a retry from the budget, returning false if the budget is spent
+func takeRetry() bool {
+	if retryPolicy.Budget <= 0 {
+		return true
+	}
+
+	if atomic.AddInt64(&retriesUsed, 1) > int64(retryPolicy.Budget) {
+		Println("Retry budget of", retryPolicy.Budget, "spent. Not retrying.")
+		return false
+	}
+
+	return true
+}
+
+// retryableStatus returns true for http statuses which may succeed if retried
+func retryableStatus(status int) bool {
+	return status == 0 || status == 429 || status >= 500
+}
@@ -0,0 +1,65 @@
+package com
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// setRetryPolicy sets policy until the test finishes
+func setRetryPolicy(t *testing.T, policy RetryPolicy) {
+	SetRetryPolicy(policy)
+	t.Cleanup(func() { SetRetryPolicy(RetryPolicy{}) })
+}
+
+func TestRunCmdWithRetry(t *testing.T) {
+	setRetryPolicy(t, RetryPolicy{Retries: 2, Backoff: time.Millisecond})
+
+	recorder := &Recorder{}
+	recorder.Reply("git push", Reply{ExitCode: 128})
+	file := &FileWrapper{Path: "/libs/a"}
+	file.SetRunner(recorder)
+
+	if err := file.RunCmdWithRetry("git", "push", "origin", "v1.2.0"); err == nil {
+		t.Error("expected push to fail once retries are exhausted")
+	}
+	if attempts := len(recorder.Commands()); attempts != 3 || file.Retries != 2 {
+		t.Errorf("expected 3 attempts and 2 retries, got %d attempts and %d retries", attempts, file.Retries)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	setRetryPolicy(t, RetryPolicy{Retries: 3, Backoff: time.Millisecond, Budget: 1})
+
+	file := &FileWrapper{}
+	attempts := 0
+	failing := func() (retryable bool, err error) {
+		attempts++
+		return true, errors.New("connection reset")
+	}
+
+	file.retry("fetch", failing)
+	file.retry("fetch", failing)
+	// One retry of the first operation, none of the second once the budget is spent
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	setRetryPolicy(t, RetryPolicy{Retries: 3, Backoff: time.Millisecond})
+
+	file := &FileWrapper{}
+	attempts := 0
+	file.retry("pull request", func() (retryable bool, err error) {
+		attempts++
+		return retryableStatus(422), errors.New("validation failed")
+	})
+
+	if attempts != 1 {
+		t.Errorf("expected client errors not to be retried, got %d attempts", attempts)
+	}
+	if !retryableStatus(502) || !retryableStatus(429) || retryableStatus(404) {
+		t.Error("expected only rate limits, server errors and failed connections to be retryable")
+	}
+}
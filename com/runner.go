@@ -0,0 +1,240 @@
+package com
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Command is a command run for a file
+type Command struct {
+	Name string
+	Args []string
+	Dir  string
+	Env  []string
+
+	// Read as the command's input, nil reads nothing
+	Stdin io.Reader
+
+	// Output is written to these by Run, nil discards it. Output only writes stderr
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// String returns the command line, e.g. "git status"
+func (cmd Command) String() string {
+	return strings.TrimSpace(cmd.Name + " " + strings.Join(cmd.Args, " "))
+}
+
+// Runner executes commands for files. Commands exiting non-zero return an error with an ExitCode() int method,
+// as *exec.ExitError has
+type Runner interface {
+	// Run runs cmd until it exits or ctx is done, writing output to cmd's writers
+	Run(ctx context.Context, cmd Command) error
+	// Output runs cmd until it exits or ctx is done, returning stdout
+	Output(ctx context.Context, cmd Command) ([]byte, error)
+}
+
+// ExecRunner runs commands as processes, the default runner
+type ExecRunner struct{}
+
+// Run runs cmd as a process
+func (ExecRunner) Run(ctx context.Context, cmd Command) error {
+	return execCommand(ctx, cmd).Run()
+}
+
+// Output runs cmd as a process, returning stdout
+func (ExecRunner) Output(ctx context.Context, cmd Command) ([]byte, error) {
+	return execCommand(ctx, cmd).Output()
+}
+
+func execCommand(ctx context.Context, command Command) (cmd *exec.Cmd) {
+	cmd = exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Dir = command.Dir
+	cmd.Env = command.Env
+	cmd.Stdin = command.Stdin
+	cmd.Stdout, cmd.Stderr = command.Stdout, command.Stderr
+	return
+}
+
+// Global command runner, ExecRunner if nil
+var runner Runner
+
+// SetRunner sets the runner executing commands of files without their own globally, e.g. a Recorder in tests.
+// Nil restores ExecRunner
+func SetRunner(r Runner) {
+	runner = r
+}
+
+// SetRunner sets the runner executing the file's commands, overriding the global runner. Nil restores it
+func (file *FileWrapper) SetRunner(r Runner) {
+	file.runner = r
+}
+
+// GlobalRunner returns the runner executing commands which aren't run for a file, e.g. go env
+func GlobalRunner() Runner {
+	if runner != nil {
+		return runner
+	}
+
+	return ExecRunner{}
+}
+
+// Runner returns the runner executing the file's commands
+func (file *FileWrapper) Runner() Runner {
+	if file.runner != nil {
+		return file.runner
+	}
+
+	return GlobalRunner()
+}
+
+// combinedOutput runs cmd with r, returning stdout and stderr interleaved
+func combinedOutput(ctx context.Context, r Runner, cmd Command) ([]byte, error) {
+	var combined bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &combined, &combined
+	err := r.Run(ctx, cmd)
+	return combined.Bytes(), err
+}
+
+// exitStatus returns the code err exited with, ok is false if the command didn't run or exit
+func exitStatus(err error) (code int, ok bool) {
+	exitErr, ok := err.(interface{ ExitCode() int })
+	if !ok || exitErr.ExitCode() < 0 {
+		// Failed to start, or killed
+		return 0, false
+	}
+
+	return exitErr.ExitCode(), true
+}
+
+// ExitError is returned by a Recorder for commands exiting non-zero
+type ExitError struct {
+	Code int
+}
+
+func (err *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", err.Code)
+}
+
+// ExitCode returns the code the command exited with
+func (err *ExitError) ExitCode() int {
+	return err.Code
+}
+
+// Reply is a Recorder's canned result of commands starting with a prefix
+type Reply struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	// Err fails the command as if it couldn't run, overriding ExitCode
+	Err error
+}
+
+// Recorder is a fake Runner recording commands instead of running them, for tests of actions without real repos.
+// Commands succeed with no output unless a reply is set for them
+type Recorder struct {
+	mux      sync.Mutex
+	replies  []recordedReply
+	commands []Command
+}
+
+type recordedReply struct {
+	prefix string
+	reply  Reply
+}
+
+// Reply sets the result of commands starting with prefix, e.g. "git rev-parse". The longest matching prefix wins,
+// the latest set if tied
+func (recorder *Recorder) Reply(prefix string, reply Reply) {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	recorder.replies = append(recorder.replies, recordedReply{prefix: prefix, reply: reply})
+}
+
+// Commands returns the commands recorded, in the order they were run
+func (recorder *Recorder) Commands() (commands []Command) {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	return append(commands, recorder.commands...)
+}
+
+// Ran returns true if a command starting with prefix was recorded
+func (recorder *Recorder) Ran(prefix string) bool {
+	for _, cmd := range recorder.Commands() {
+		if strings.HasPrefix(cmd.String(), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run records cmd, writing the reply's output to cmd's writers
+func (recorder *Recorder) Run(ctx context.Context, cmd Command) (err error) {
+	reply, err := recorder.record(ctx, cmd)
+	if err != nil {
+		return
+	}
+
+	if cmd.Stdout != nil {
+		io.WriteString(cmd.Stdout, reply.Stdout)
+	}
+	if cmd.Stderr != nil {
+		io.WriteString(cmd.Stderr, reply.Stderr)
+	}
+
+	return reply.err()
+}
+
+// Output records cmd, returning the reply's stdout
+func (recorder *Recorder) Output(ctx context.Context, cmd Command) (stdout []byte, err error) {
+	reply, err := recorder.record(ctx, cmd)
+	if err != nil {
+		return
+	}
+
+	if cmd.Stderr != nil {
+		io.WriteString(cmd.Stderr, reply.Stderr)
+	}
+
+	return []byte(reply.Stdout), reply.err()
+}
+
+// record appends cmd and returns the reply to it, failing if ctx is done as a killed process would
+func (recorder *Recorder) record(ctx context.Context, cmd Command) (reply Reply, err error) {
+	recorder.mux.Lock()
+	defer recorder.mux.Unlock()
+
+	recorder.commands = append(recorder.commands, cmd)
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	line, matched := cmd.String(), -1
+	for _, candidate := range recorder.replies {
+		if strings.HasPrefix(line, candidate.prefix) && len(candidate.prefix) >= matched {
+			reply, matched = candidate.reply, len(candidate.prefix)
+		}
+	}
+
+	return
+}
+
+func (reply Reply) err() error {
+	if reply.Err != nil {
+		return reply.Err
+	}
+
+	if reply.ExitCode != 0 {
+		return &ExitError{Code: reply.ExitCode}
+	}
+
+	return nil
+}
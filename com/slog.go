@@ -0,0 +1,43 @@
+//go:build go1.21
+// +build go1.21
+
+package com
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger delegates output to a slog.Logger
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger which writes to logger, or slog.Default() if nil
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SlogLogger{Logger: logger}
+}
+
+// Debug logs at slog.LevelDebug
+func (l *SlogLogger) Debug(message string, fields ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelDebug, message, fields...)
+}
+
+// Info logs at slog.LevelInfo
+func (l *SlogLogger) Info(message string, fields ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelInfo, message, fields...)
+}
+
+// Warn logs at slog.LevelWarn
+func (l *SlogLogger) Warn(message string, fields ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelWarn, message, fields...)
+}
+
+// Error logs at slog.LevelError
+func (l *SlogLogger) Error(message string, fields ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelError, message, fields...)
+}
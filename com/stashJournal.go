@@ -0,0 +1,116 @@
+package com
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StashRecord represents a stash gomu created, recorded so it can be recovered if it's never popped
+type StashRecord struct {
+	// Absolute path of the working copy
+	Path string `json:"path"`
+	// Stash commit, which can be applied even once dropped until garbage collected
+	Commit string `json:"commit"`
+	// Hash of the stashed content (the stash commit's tree)
+	Hash    string    `json:"hash"`
+	Created time.Time `json:"created"`
+}
+
+// Global file stashes are recorded to, empty if not recording
+var stashJournal = ""
+
+// Guards concurrent updates to the stash journal
+var stashJournalMux sync.Mutex
+
+// SetStashJournal sets the file stashes are recorded to globally. Empty turns recording off
+func SetStashJournal(journalPath string) {
+	stashJournal = journalPath
+}
+
+// LoadStashRecords reads stash records from journalPath. Returns no records if the file doesn't exist
+func LoadStashRecords(journalPath string) (records []StashRecord, err error) {
+	data, err := ioutil.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &records)
+	return
+}
+
+// SaveStashRecords writes records to journalPath, removing it if there are none
+func SaveStashRecords(journalPath string, records []StashRecord) (err error) {
+	if len(records) == 0 {
+		if err = os.Remove(journalPath); os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(journalPath, data, 0644)
+}
+
+// recordStash appends record to the stash journal
+func recordStash(record StashRecord) (err error) {
+	stashJournalMux.Lock()
+	defer stashJournalMux.Unlock()
+
+	records, err := LoadStashRecords(stashJournal)
+	if err != nil {
+		return
+	}
+
+	return SaveStashRecords(stashJournal, append(records, record))
+}
+
+// stashTop returns the commit of the newest stash, empty if there are none
+func (file *FileWrapper) stashTop() string {
+	output, _, _ := file.CmdResult("git", "rev-parse", "-q", "--verify", "refs/stash")
+	return output
+}
+
+// recordNewStash records the newest stash if it's not previous, the newest stash before stashing
+func (file *FileWrapper) recordNewStash(previous string) {
+	if len(stashJournal) == 0 {
+		return
+	}
+
+	commit := file.stashTop()
+	if len(commit) == 0 || commit == previous {
+		// Nothing was stashed
+		return
+	}
+
+	record := StashRecord{Path: file.AbsPath(), Commit: commit, Created: time.Now()}
+	record.Hash, _ = file.CmdOutput("git", "rev-parse", commit+"^{tree}")
+	if err := recordStash(record); err != nil {
+		file.Output("Warning - Unable to record stash " + commit + ": " + err.Error())
+	}
+}
+
+// StashListed returns the ref of commit in the file's stash list (stash@{1}), empty if it's not listed
+func (file *FileWrapper) StashListed(commit string) (ref string, err error) {
+	output, err := file.CmdOutput("git", "stash", "list", "--format=%gd%x1f%H")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.SplitN(line, "\x1f", 2); len(fields) == 2 && fields[1] == commit {
+			return fields[0], nil
+		}
+	}
+
+	return
+}
@@ -0,0 +1,63 @@
+package com
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordNewStash(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	journal := filepath.Join(file.Path, "stashes.json")
+	SetStashJournal(journal)
+	defer SetStashJournal("")
+
+	recorder.Reply("git rev-parse -q --verify refs/stash", Reply{Stdout: "abc123\n"})
+	recorder.Reply("git rev-parse abc123^{tree}", Reply{Stdout: "def456\n"})
+
+	// Already the newest stash before stashing
+	file.recordNewStash("abc123")
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be recorded, got %v", err)
+	}
+
+	file.recordNewStash("")
+	records, err := LoadStashRecords(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Path != file.AbsPath() || records[0].Commit != "abc123" || records[0].Hash != "def456" {
+		t.Fatalf("expected stash abc123 to be recorded, got %+v", records)
+	}
+
+	if err = SaveStashRecords(journal, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("expected journal without records to be removed, got %v", err)
+	}
+}
+
+func TestStashListed(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git stash list", Reply{Stdout: "stash@{0}\x1fabc123\nstash@{1}\x1fdef456\n"})
+
+	for commit, expected := range map[string]string{"def456": "stash@{1}", "fed654": ""} {
+		if ref, err := file.StashListed(commit); err != nil || ref != expected {
+			t.Errorf("expected %q for %s, got %q (%v)", expected, commit, ref, err)
+		}
+	}
+}
+
+func TestLoadStashRecordsMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-stashes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if records, err := LoadStashRecords(filepath.Join(dir, "stashes.json")); err != nil || len(records) > 0 {
+		t.Errorf("expected no records without a journal, got %+v (%v)", records, err)
+	}
+}
@@ -1,12 +1,63 @@
 package com
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"runtime"
 	"strings"
+	"time"
 )
 
+// Global dry run setting
+var dryRun = false
+
+// SetDryRun turns command simulation on and off globally
+func SetDryRun(enabled bool) {
+	dryRun = enabled
+}
+
+// IsDryRun returns true if commands are being simulated
+func IsDryRun() bool {
+	return dryRun
+}
+
+// CommandObserver is called once each command run for a file finishes, with err set if it failed
+type CommandObserver func(file *FileWrapper, command string, started time.Time, err error)
+
+// Global command observer, nil if commands aren't observed
+var commandObserver CommandObserver
+
+// SetCommandObserver sets the func called once each command finishes globally, e.g. to trace commands. Nil disables
+func SetCommandObserver(observer CommandObserver) {
+	commandObserver = observer
+}
+
+// observeCommand reports a finished command to the observer, if set
+func (file *FileWrapper) observeCommand(command string, started time.Time, err error) {
+	if commandObserver != nil {
+		commandObserver(file, command, started, err)
+	}
+}
+
+// Global max libs worked on concurrently, GOMAXPROCS if 0
+var maxConcurrency = 0
+
+// SetMaxConcurrency sets the max libs worked on concurrently globally. 0 uses GOMAXPROCS
+func SetMaxConcurrency(limit int) {
+	maxConcurrency = limit
+}
+
+// MaxConcurrency returns the max libs worked on concurrently
+func MaxConcurrency() int {
+	if maxConcurrency > 0 {
+		return maxConcurrency
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
 // RunCmd executes a shell command at the file's path
+// Note: in dry run mode the command is printed and not executed
 func (file *FileWrapper) RunCmd(args ...string) (err error) {
 	name := args[0]
 	params := args[1:]
@@ -14,15 +65,65 @@ func (file *FileWrapper) RunCmd(args ...string) (err error) {
 	tag := name + " " + strings.Join(params, " ")
 	file.Debug(tag)
 
-	cmd := exec.Command(name, params...)
-	cmd.Dir = file.Path
-	if err = cmd.Run(); err != nil {
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(tag)
+		return
+	}
+
+	release, err := file.acquireHost(name, params)
+	if err != nil {
+		return file.handleError(tag, err)
+	}
+	defer release()
+
+	if name == "git" && len(params) > 0 && params[0] == "push" {
+		// Added after logging to keep tokens out of output
+		params = append(file.pushAuthArgs(), params...)
+	}
+
+	// Full output is only kept in the library's log
+	writer := file.logWriter()
+	cmd := Command{Name: name, Args: params, Dir: file.Path, Env: file.Environ(), Stdout: writer, Stderr: writer}
+	started := time.Now()
+	err = file.Runner().Run(file.Context(), cmd)
+	file.observeCommand(tag, started, err)
+	if err != nil {
 		return file.handleError(tag, err)
 	}
 
 	return
 }
 
+// RunShell runs command with sh (cmd on Windows) at the file's path, with env appended to the environment.
+// Returns combined output and the exit code, err is only set if the command couldn't run or exit
+// Note: in dry run mode the command is printed and not executed
+func (file *FileWrapper) RunShell(command string, env ...string) (output string, exitCode int, err error) {
+	file.Debug(command)
+
+	if dryRun {
+		// No-op, just show what would have happened
+		file.DryRun(command)
+		return
+	}
+
+	shell := ShellArgs(command)
+	cmd := Command{Name: shell[0], Args: shell[1:], Dir: file.Path, Env: append(file.Environ(), env...)}
+
+	started := time.Now()
+	combined, runErr := combinedOutput(file.Context(), file.Runner(), cmd)
+	file.observeCommand(command, started, runErr)
+	output = cleanOutput(combined)
+	if code, exited := exitStatus(runErr); exited {
+		exitCode = code
+	} else if runErr != nil {
+		// Failed to start, or killed
+		err = file.handleError(command, runErr)
+	}
+
+	return
+}
+
 // CmdOutput returns output of a shell command at the file's path
 func (file *FileWrapper) CmdOutput(args ...string) (output string, err error) {
 	name := args[0]
@@ -31,18 +132,110 @@ func (file *FileWrapper) CmdOutput(args ...string) (output string, err error) {
 	tag := name + " " + strings.Join(params, " ")
 	file.Debug(tag)
 
-	cmd := exec.Command(name, params...)
-	cmd.Dir = file.Path
-	stdout, err := cmd.Output()
+	release, err := file.acquireHost(name, params)
+	if err != nil {
+		err = file.handleError(tag, err)
+		return
+	}
+	defer release()
+
+	cmd := Command{Name: name, Args: params, Dir: file.Path, Env: file.Environ(), Stderr: file.logWriter()}
+	started := time.Now()
+	stdout, err := file.Runner().Output(file.Context(), cmd)
+	file.observeCommand(tag, started, err)
+	file.logOutput(stdout)
+	if err != nil {
+		err = file.handleError(tag, err)
+		return
+	}
+
+	output = cleanOutput(stdout)
+	return
+}
+
+// CmdResult returns stdout of a command at the file's path and its exit code, even if it failed.
+// Err is only set if the command couldn't run or exit
+func (file *FileWrapper) CmdResult(args ...string) (output string, exitCode int, err error) {
+	name := args[0]
+	params := args[1:]
+
+	tag := name + " " + strings.Join(params, " ")
+	file.Debug(tag)
+
+	release, err := file.acquireHost(name, params)
+	if err != nil {
+		err = file.handleError(tag, err)
+		return
+	}
+	defer release()
+
+	cmd := Command{Name: name, Args: params, Dir: file.Path, Env: file.Environ(), Stderr: file.logWriter()}
+	started := time.Now()
+	stdout, runErr := file.Runner().Output(file.Context(), cmd)
+	file.observeCommand(tag, started, runErr)
+	file.logOutput(stdout)
+	output = cleanOutput(stdout)
+	if code, exited := exitStatus(runErr); exited {
+		exitCode = code
+	} else if runErr != nil {
+		// Failed to start, or killed
+		err = file.handleError(tag, runErr)
+	}
+
+	return
+}
+
+// CmdCombinedOutput returns stdout and stderr of a command at the file's path and its exit code, even if it failed.
+// Err is only set if the command couldn't run or exit
+func (file *FileWrapper) CmdCombinedOutput(args ...string) (output string, exitCode int, err error) {
+	name := args[0]
+	params := args[1:]
+
+	tag := name + " " + strings.Join(params, " ")
+	file.Debug(tag)
+
+	release, err := file.acquireHost(name, params)
 	if err != nil {
 		err = file.handleError(tag, err)
 		return
 	}
+	defer release()
+
+	cmd := Command{Name: name, Args: params, Dir: file.Path, Env: file.Environ()}
+	started := time.Now()
+	combined, runErr := combinedOutput(file.Context(), file.Runner(), cmd)
+	file.observeCommand(tag, started, runErr)
+	file.logOutput(combined)
+	output = cleanOutput(combined)
+	if code, exited := exitStatus(runErr); exited {
+		exitCode = code
+	} else if runErr != nil {
+		// Failed to start, or killed
+		err = file.handleError(tag, runErr)
+	}
 
-	output = strings.TrimSpace(string(stdout))
 	return
 }
 
+// SetContext bounds all commands run for the file by ctx
+func (file *FileWrapper) SetContext(ctx context.Context) {
+	file.ctx = ctx
+}
+
+// Context returns the context bounding commands run for the file
+func (file *FileWrapper) Context() context.Context {
+	if file.ctx == nil {
+		return context.Background()
+	}
+
+	return file.ctx
+}
+
 func (file *FileWrapper) handleError(command string, ierr error) (err error) {
+	if file.ctx != nil && file.ctx.Err() == context.DeadlineExceeded {
+		file.TimedOut = true
+		return fmt.Errorf("Timed out running command `" + command + "`")
+	}
+
 	return fmt.Errorf("Error running command `" + command + "` - " + ierr.Error())
 }
@@ -0,0 +1,318 @@
+package com
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionScheme parses and increments versions tagged on libs
+type VersionScheme interface {
+	// Parse returns true if version follows the scheme
+	Parse(version string) (ok bool)
+	// Next returns the version after latest, incrementing the "major", "minor" or "patch" counter where the scheme has one
+	Next(latest, bump string) (next string, err error)
+}
+
+// Built in version schemes
+const (
+	// SchemeSemver tags v1.2.3
+	SchemeSemver = "semver"
+	// SchemeCalver tags v2024.6.0, the counter resets each month
+	SchemeCalver = "calver"
+)
+
+// Templates of the built in version schemes
+const (
+	semverTemplate = "v{major}.{minor}.{patch}"
+	calverTemplate = "v{year}.{month}.{patch}"
+)
+
+// Counters and dates a version template may contain, counters in order of significance
+var (
+	templateCounters = []string{"major", "minor", "patch"}
+	templateDates    = []string{"year", "month", "day"}
+	templateField    = regexp.MustCompile(`\{([a-z]+)\}`)
+)
+
+// Global prefix of version tags
+var tagPrefix = ""
+
+// Global version scheme, semver unless set
+var versioning VersionScheme = mustTemplateScheme(semverTemplate)
+
+// Global prerelease channel, empty for stable releases
+var prerelease = ""
+
+// prereleaseChannel matches channels which are valid semver prerelease identifiers
+var prereleaseChannel = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+// SetVersioning sets the prefix of version tags (release/) and the version scheme globally.
+// Scheme is semver (default), calver or a template of {major}, {minor}, {patch}, {year}, {month} and {day}, e.g. v{major}.{minor}
+func SetVersioning(prefix, scheme string) (err error) {
+	var parsed VersionScheme
+	if parsed, err = SchemeFor(scheme); err != nil {
+		return
+	}
+
+	tagPrefix = prefix
+	versioning = parsed
+	return
+}
+
+// SchemeFor returns the version scheme named scheme, or parsed from a template
+func SchemeFor(scheme string) (VersionScheme, error) {
+	switch {
+	case len(scheme) == 0 || scheme == SchemeSemver:
+		return newTemplateScheme(semverTemplate)
+	case scheme == SchemeCalver:
+		return newTemplateScheme(calverTemplate)
+	case strings.Contains(scheme, "{"):
+		return newTemplateScheme(scheme)
+	default:
+		return nil, fmt.Errorf("unknown version scheme %q, expected semver, calver or a template like v{major}.{minor}", scheme)
+	}
+}
+
+// TagPrefix returns the prefix of version tags, e.g. release/
+func TagPrefix() string {
+	return tagPrefix
+}
+
+// Versioning returns the version scheme libs are tagged with
+func Versioning() VersionScheme {
+	return versioning
+}
+
+// SetPrerelease sets the channel versions are released on globally, e.g. beta tags v1.4.0-beta.1. Empty releases stable versions
+func SetPrerelease(channel string) (err error) {
+	if len(channel) > 0 && !prereleaseChannel.MatchString(channel) {
+		return fmt.Errorf("invalid prerelease channel %q, expected letters, digits and hyphens", channel)
+	}
+
+	prerelease = channel
+	return
+}
+
+// Prerelease returns the channel versions are released on, empty for stable releases
+func Prerelease() string {
+	return prerelease
+}
+
+// CustomVersioning returns true if tags are prefixed, prereleases or don't follow semver, which git-tagger can't increment
+func CustomVersioning() bool {
+	scheme, ok := versioning.(*templateScheme)
+	return len(tagPrefix) > 0 || len(prerelease) > 0 || !ok || scheme.template != semverTemplate
+}
+
+// ParseTag returns the version tagged by tag, without the tag prefix. False if tag isn't a version tag.
+// Prerelease versions (v1.4.0-beta.1) are version tags
+func ParseTag(tag string) (version string, ok bool) {
+	if !strings.HasPrefix(tag, tagPrefix) {
+		return
+	}
+
+	version = strings.TrimPrefix(tag, tagPrefix)
+	release, channel, number := SplitPrerelease(version)
+	if !versioning.Parse(release) || (len(channel) > 0 && number == 0) {
+		return "", false
+	}
+
+	return version, true
+}
+
+// SplitPrerelease splits version into its release and prerelease channel and number (v1.4.0-beta.2 -> v1.4.0, beta, 2).
+// Channel is empty for stable versions, number is 0 if version isn't a numbered prerelease
+func SplitPrerelease(version string) (release, channel string, number int) {
+	index := strings.LastIndex(version, "-")
+	if index < 0 || versioning.Parse(version) {
+		// Templates may contain hyphens
+		return version, "", 0
+	}
+
+	release, channel = version[:index], version[index+1:]
+	if dot := strings.LastIndex(channel, "."); dot >= 0 {
+		if parsed, err := strconv.Atoi(channel[dot+1:]); err == nil && parsed > 0 {
+			channel, number = channel[:dot], parsed
+		}
+	}
+
+	return
+}
+
+// NextVersion returns the version after latest per the version scheme. On a prerelease channel the release is
+// bumped and numbered from 1 (v1.3.0 -> v1.4.0-beta.1), then the number is incremented (v1.4.0-beta.2).
+// Otherwise prereleases are released (v1.4.0-beta.2 -> v1.4.0)
+func NextVersion(latest, bump string) (next string, err error) {
+	release, channel, number := SplitPrerelease(latest)
+	switch {
+	case len(channel) > 0 && len(prerelease) == 0:
+		return release, nil
+	case len(channel) > 0 && channel == prerelease:
+		return release + "-" + prerelease + "." + strconv.Itoa(number+1), nil
+	case len(channel) > 0:
+		// Switching channel of the same release, e.g. alpha to beta
+		return release + "-" + prerelease + ".1", nil
+	}
+
+	if next, err = versioning.Next(release, bump); err != nil || len(prerelease) == 0 {
+		return
+	}
+
+	return next + "-" + prerelease + ".1", nil
+}
+
+// templateScheme formats versions from a template of counters and dates
+type templateScheme struct {
+	template string
+	pattern  *regexp.Regexp
+
+	// Fields in order of appearance
+	fields []string
+}
+
+func newTemplateScheme(template string) (scheme *templateScheme, err error) {
+	scheme = &templateScheme{template: template}
+
+	expression := "^"
+	last := 0
+	for _, match := range templateField.FindAllStringSubmatchIndex(template, -1) {
+		field := template[match[2]:match[3]]
+		if !isTemplateField(field) {
+			err = fmt.Errorf("unknown version template field {%s}, expected major, minor, patch, year, month or day", field)
+			return
+		}
+
+		expression += regexp.QuoteMeta(template[last:match[0]]) + `(\d+)`
+		scheme.fields = append(scheme.fields, field)
+		last = match[1]
+	}
+	expression += regexp.QuoteMeta(template[last:]) + "$"
+
+	if len(scheme.fields) == 0 {
+		err = fmt.Errorf("version template %q has no fields", template)
+		return
+	}
+
+	scheme.pattern, err = regexp.Compile(expression)
+	return
+}
+
+func mustTemplateScheme(template string) *templateScheme {
+	scheme, err := newTemplateScheme(template)
+	if err != nil {
+		panic(err)
+	}
+
+	return scheme
+}
+
+func isTemplateField(field string) bool {
+	for _, name := range append(templateCounters, templateDates...) {
+		if field == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// values returns the value of each field in version
+func (scheme *templateScheme) values(version string) (values map[string]int, ok bool) {
+	match := scheme.pattern.FindStringSubmatch(version)
+	if match == nil {
+		return
+	}
+
+	values = make(map[string]int, len(scheme.fields))
+	for i, field := range scheme.fields {
+		var err error
+		if values[field], err = strconv.Atoi(match[i+1]); err != nil {
+			return nil, false
+		}
+	}
+
+	return values, true
+}
+
+// Parse returns true if version matches the template
+func (scheme *templateScheme) Parse(version string) (ok bool) {
+	_, ok = scheme.values(version)
+	return
+}
+
+// Next returns the version after latest. Dates are set to today, counters reset when a date changes,
+// otherwise the bumped counter is incremented
+func (scheme *templateScheme) Next(latest, bump string) (next string, err error) {
+	values, ok := scheme.values(latest)
+	if !ok {
+		err = fmt.Errorf("%q doesn't match version template %s", latest, scheme.template)
+		return
+	}
+
+	now := time.Now()
+	today := map[string]int{"year": now.Year(), "month": int(now.Month()), "day": now.Day()}
+
+	dateChanged := false
+	for _, field := range scheme.fields {
+		if date, ok := today[field]; ok && values[field] != date {
+			values[field] = date
+			dateChanged = true
+		}
+	}
+
+	if dateChanged {
+		for _, counter := range templateCounters {
+			values[counter] = 0
+		}
+	} else if err = scheme.bump(values, bump); err != nil {
+		return
+	}
+
+	next = templateField.ReplaceAllStringFunc(scheme.template, func(field string) string {
+		return strconv.Itoa(values[strings.Trim(field, "{}")])
+	})
+	return
+}
+
+// bump increments the bump counter, resetting less significant counters. Templates without it increment
+// the next less significant counter, or the least significant counter if there's none (minor for v{major}.{minor} patches)
+func (scheme *templateScheme) bump(values map[string]int, bump string) (err error) {
+	index := -1
+	for i, counter := range templateCounters {
+		if counter == bump {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		return fmt.Errorf("unknown version bump %q, expected major, minor or patch", bump)
+	}
+
+	var candidates []int
+	for i := index; i < len(templateCounters); i++ {
+		candidates = append(candidates, i)
+	}
+	for i := index - 1; i >= 0; i-- {
+		candidates = append(candidates, i)
+	}
+
+	for _, i := range candidates {
+		if _, ok := values[templateCounters[i]]; !ok {
+			continue
+		}
+
+		values[templateCounters[i]]++
+		for _, lower := range templateCounters[i+1:] {
+			if _, ok := values[lower]; ok {
+				values[lower] = 0
+			}
+		}
+
+		return
+	}
+
+	return fmt.Errorf("version template %s has no counter to increment", scheme.template)
+}
@@ -0,0 +1,184 @@
+package com
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewTemplateScheme(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		valid    bool
+	}{
+		{semverTemplate, true},
+		{calverTemplate, true},
+		{"v{major}.{minor}", true},
+		{"release-{year}{month}{day}.{patch}", true},
+		{"v{major}.{minor}.{build}", false},
+		{"v{Major}", false},
+		{"latest", false},
+		{"v{}", false},
+	} {
+		scheme, err := newTemplateScheme(test.template)
+		if test.valid && (err != nil || scheme.pattern == nil) {
+			t.Errorf("%s: expected valid template, got %v", test.template, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: expected error", test.template)
+		}
+	}
+}
+
+func TestTemplateSchemeParse(t *testing.T) {
+	for _, test := range []struct {
+		template string
+		version  string
+		ok       bool
+	}{
+		{semverTemplate, "v1.2.3", true},
+		{semverTemplate, "v10.20.30", true},
+		{semverTemplate, "1.2.3", false},
+		{semverTemplate, "v1.2", false},
+		{semverTemplate, "v1.2.3.4", false},
+		{semverTemplate, "v1.2.x", false},
+		{semverTemplate, "v1.2.3-beta.1", false},
+		{"v{major}.{minor}", "v1.2", true},
+		{"v{major}.{minor}", "v1.2.3", false},
+		// Literal parts are matched exactly, not as patterns
+		{"v{major}.{minor}", "v1x2", false},
+		{"release-{year}.{month}-{patch}", "release-2024.6-3", true},
+		{"release-{year}.{month}-{patch}", "2024.6-3", false},
+	} {
+		scheme := mustTemplateScheme(test.template)
+		if ok := scheme.Parse(test.version); ok != test.ok {
+			t.Errorf("%s %s: expected %v, got %v", test.template, test.version, test.ok, ok)
+		}
+	}
+}
+
+func TestTemplateSchemeNext(t *testing.T) {
+	now := time.Now()
+	thisMonth := fmt.Sprintf("v%d.%d", now.Year(), int(now.Month()))
+
+	for _, test := range []struct {
+		template string
+		latest   string
+		bump     string
+		expected string
+		valid    bool
+	}{
+		{semverTemplate, "v1.2.3", "patch", "v1.2.4", true},
+		{semverTemplate, "v1.2.3", "minor", "v1.3.0", true},
+		{semverTemplate, "v1.2.3", "major", "v2.0.0", true},
+		{semverTemplate, "v1.2.3", "build", "", false},
+		{semverTemplate, "v1.2", "patch", "", false},
+		// Templates without the bumped counter increment the next less significant one, or the least significant
+		{"v{major}.{minor}", "v1.2", "patch", "v1.3", true},
+		{"v{major}.{minor}", "v1.2", "major", "v2.0", true},
+		{"v{minor}.{patch}", "v1.2", "major", "v2.0", true},
+		// Calver counters increment within a month, and reset when it changes
+		{calverTemplate, thisMonth + ".4", "patch", thisMonth + ".5", true},
+		{calverTemplate, "v2000.1.4", "patch", thisMonth + ".0", true},
+		{"v{year}.{month}", thisMonth, "patch", "", false},
+	} {
+		next, err := mustTemplateScheme(test.template).Next(test.latest, test.bump)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s %s %s: expected error, got %s", test.template, test.latest, test.bump, next)
+			}
+			continue
+		}
+
+		if err != nil || next != test.expected {
+			t.Errorf("%s %s %s: expected %s, got %s (%v)", test.template, test.latest, test.bump, test.expected, next, err)
+		}
+	}
+}
+
+// setVersioning sets the tag prefix, scheme and prerelease channel for the test
+func setVersioning(t *testing.T, prefix, scheme, channel string) {
+	t.Helper()
+	previousPrefix, previousScheme, previousChannel := tagPrefix, versioning, prerelease
+	t.Cleanup(func() {
+		tagPrefix, versioning, prerelease = previousPrefix, previousScheme, previousChannel
+	})
+
+	if err := SetVersioning(prefix, scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPrerelease(channel); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchemeFor(t *testing.T) {
+	for scheme, valid := range map[string]bool{
+		"":                 true,
+		SchemeSemver:       true,
+		SchemeCalver:       true,
+		"v{major}.{minor}": true,
+		"v{build}":         false,
+		"sequential":       false,
+	} {
+		if _, err := SchemeFor(scheme); (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got %v", scheme, valid, err)
+		}
+	}
+}
+
+func TestNextVersionPrerelease(t *testing.T) {
+	for _, test := range []struct {
+		channel  string
+		latest   string
+		expected string
+	}{
+		// Stable releases are bumped as usual, and prereleases are released
+		{"", "v1.3.0", "v1.3.1"},
+		{"", "v1.4.0-beta.2", "v1.4.0"},
+		// On a channel the release is bumped and numbered from 1, then the number is incremented
+		{"beta", "v1.3.0", "v1.3.1-beta.1"},
+		{"beta", "v1.4.0-beta.2", "v1.4.0-beta.3"},
+		// Switching channel keeps the release
+		{"rc", "v1.4.0-beta.2", "v1.4.0-rc.1"},
+	} {
+		setVersioning(t, "", SchemeSemver, test.channel)
+		if next, err := NextVersion(test.latest, "patch"); err != nil || next != test.expected {
+			t.Errorf("%s %s: expected %s, got %s (%v)", test.channel, test.latest, test.expected, next, err)
+		}
+	}
+}
+
+func TestSetPrereleaseInvalid(t *testing.T) {
+	setVersioning(t, "", SchemeSemver, "")
+	for _, channel := range []string{"beta.1", "be ta", "beta/1"} {
+		if err := SetPrerelease(channel); err == nil {
+			t.Errorf("%q: expected error", channel)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	for _, test := range []struct {
+		prefix   string
+		scheme   string
+		tag      string
+		expected string
+		ok       bool
+	}{
+		{"", SchemeSemver, "v1.2.3", "v1.2.3", true},
+		{"", SchemeSemver, "v1.4.0-beta.1", "v1.4.0-beta.1", true},
+		// Prereleases need a number
+		{"", SchemeSemver, "v1.4.0-beta", "", false},
+		{"", SchemeSemver, "latest", "", false},
+		{"release/", SchemeSemver, "release/v1.2.3", "v1.2.3", true},
+		{"release/", SchemeSemver, "v1.2.3", "", false},
+		// Templates may contain hyphens, which aren't prereleases
+		{"", "v{year}-{month}-{patch}", "v2024-06-1", "v2024-06-1", true},
+		{"", "v{year}-{month}-{patch}", "v2024-06-1-rc.1", "v2024-06-1-rc.1", true},
+	} {
+		setVersioning(t, test.prefix, test.scheme, "")
+		if version, ok := ParseTag(test.tag); ok != test.ok || version != test.expected {
+			t.Errorf("%s%s %s: expected %q %v, got %q %v", test.prefix, test.scheme, test.tag, test.expected, test.ok, version, ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package com
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// AddWorktree checks out the file's current commit into a new worktree at dir, then runs all commands for the file there.
+// The original working copy, including local changes, is left untouched
+func (file *FileWrapper) AddWorktree(dir string) (err error) {
+	// Cache values derived from the original path
+	file.GetGoURL()
+	moduleDir := file.ModuleDir()
+
+	if err = file.RunCmd("git", "worktree", "add", "--detach", dir, "HEAD"); err != nil {
+		return
+	}
+
+	// Nested modules are within the worktree
+	file.repoPath = file.Path
+	file.Path = filepath.Join(dir, filepath.FromSlash(moduleDir))
+	file.absPath = ""
+	return
+}
+
+// RemoveWorktree deletes the worktree created by AddWorktree and restores the original path
+func (file *FileWrapper) RemoveWorktree() (err error) {
+	if !file.InWorktree() {
+		return
+	}
+
+	dir := file.Path
+	if moduleDir := file.ModuleDir(); len(moduleDir) > 0 {
+		dir = strings.TrimSuffix(dir, string(filepath.Separator)+filepath.FromSlash(moduleDir))
+	}
+	file.Path = file.repoPath
+	file.repoPath = ""
+	file.absPath = ""
+
+	// Run without the file's context, cleanup must happen even if timed out
+	cmd := Command{Name: "git", Args: []string{"worktree", "remove", "--force", dir}, Dir: file.Path, Env: file.Environ()}
+	if err = file.Runner().Run(context.Background(), cmd); err != nil {
+		err = file.handleError("git worktree remove --force "+dir, err)
+	}
+
+	return
+}
+
+// WorktreePrefix names the temporary directories gomu creates worktrees in
+const WorktreePrefix = "gomu-worktrees-"
+
+// GomuWorktrees returns worktrees of the file's repo created by gomu
+func (file *FileWrapper) GomuWorktrees() (dirs []string, err error) {
+	output, err := file.CmdOutput("git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if dir := strings.TrimPrefix(line, "worktree "); dir != line && strings.HasPrefix(filepath.Base(filepath.Dir(filepath.FromSlash(dir))), WorktreePrefix) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return
+}
+
+// InWorktree returns true if commands are run in a temporary worktree
+func (file *FileWrapper) InWorktree() bool {
+	return len(file.repoPath) > 0
+}
+
+// OriginalPath returns the path of the working copy, even while using a temporary worktree
+func (file *FileWrapper) OriginalPath() string {
+	if file.InWorktree() {
+		return file.repoPath
+	}
+
+	return file.Path
+}
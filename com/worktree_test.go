@@ -0,0 +1,61 @@
+package com
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorktree(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git rev-parse --show-prefix", Reply{Stdout: "services/a/\n"})
+	repo := file.Path
+	worktree := filepath.Join(repo+"-worktrees", "1-a")
+
+	if err := file.AddWorktree(worktree); err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.Ran("git worktree add --detach " + worktree + " HEAD") {
+		t.Errorf("expected worktree to be added, got %v", recorder.Commands())
+	}
+	if expected := filepath.Join(worktree, "services", "a"); file.Path != expected || !file.InWorktree() {
+		t.Errorf("expected commands to run in the nested module within the worktree %s, got %s", expected, file.Path)
+	}
+	if file.OriginalPath() != repo {
+		t.Errorf("expected original path %s, got %s", repo, file.OriginalPath())
+	}
+
+	if err := file.RemoveWorktree(); err != nil {
+		t.Fatal(err)
+	}
+	commands := recorder.Commands()
+	if removed := commands[len(commands)-1]; removed.String() != "git worktree remove --force "+worktree || removed.Dir != repo {
+		t.Errorf("expected worktree to be removed from the repo, got %q in %s", removed, removed.Dir)
+	}
+	if file.Path != repo || file.InWorktree() {
+		t.Errorf("expected original path to be restored, got %s", file.Path)
+	}
+}
+
+func TestGomuWorktrees(t *testing.T) {
+	file, recorder := recordedRepo(t)
+	recorder.Reply("git worktree list --porcelain", Reply{Stdout: `worktree /home/me/a
+HEAD abc123
+branch refs/heads/master
+
+worktree /tmp/` + WorktreePrefix + `123/1-a
+HEAD abc123
+detached
+
+worktree /home/me/a-feature
+HEAD def456
+branch refs/heads/feature
+`})
+
+	dirs, err := file.GomuWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || dirs[0] != "/tmp/"+WorktreePrefix+"123/1-a" {
+		t.Errorf("expected only gomu's worktree, got %q", dirs)
+	}
+}
@@ -0,0 +1,125 @@
+package gomu
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// DefaultTicketPattern matches ticket ids in branch names, e.g. ABC-123 in feature/ABC-123-bump-deps
+const DefaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// CommitTemplateData represents the values available to commit message templates
+type CommitTemplateData struct {
+	// Go url of the library
+	Library string
+	Branch  string
+	// Ticket id found in the branch name, or set in options if none
+	Ticket string
+	// Commit message set in options, if any
+	Message string
+
+	// Deps set by the sync
+	Deps []CommitDep
+	// Deps set by the sync formatted as url@version
+	UpdatedDeps []string
+}
+
+// CommitDep represents a dep set by a sync
+type CommitDep struct {
+	Library string
+	Version string
+	// Updated is true if the dep was updated this run, false if only set to its existing version
+	Updated bool
+}
+
+// commitTemplateFuncs are the functions available to commit message templates
+var commitTemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// loadCommitTemplate parses the commit message template and ticket pattern set in options
+func (mu *MU) loadCommitTemplate() (err error) {
+	if mu.commitTemplate, err = template.New("commit").Funcs(commitTemplateFuncs).Parse(mu.Options.CommitTemplate); err != nil {
+		return fmt.Errorf("unable to parse commit template: %v", err)
+	}
+
+	pattern := mu.Options.TicketPattern
+	if len(pattern) == 0 {
+		pattern = DefaultTicketPattern
+	}
+
+	if mu.ticketPattern, err = regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("unable to parse ticket pattern %q: %v", pattern, err)
+	}
+
+	return
+}
+
+// ticket returns the ticket id in branch, the first submatch if the pattern has one. Empty if none
+func (mu *MU) ticket(branch string) string {
+	match := mu.ticketPattern.FindStringSubmatch(branch)
+	switch {
+	case len(match) == 0:
+		return ""
+	case len(match) > 1:
+		return match[1]
+	default:
+		return match[0]
+	}
+}
+
+// renderCommitTemplate returns the commit title (first line) and message (remaining lines) for lib
+func (mu *MU) renderCommitTemplate(lib Library) (title, message string, err error) {
+	mu.libraryMux.Lock()
+	if mu.commitTemplate == nil {
+		err = mu.loadCommitTemplate()
+	}
+	mu.libraryMux.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	branch := mu.branch(lib)
+	if len(branch) == 0 {
+		branch, _ = lib.File.CurrentBranch()
+	}
+
+	data := CommitTemplateData{
+		Library: lib.File.GetGoURL(),
+		Branch:  branch,
+		Ticket:  mu.ticket(branch),
+		Message: mu.Options.CommitMessage,
+	}
+	if len(data.Ticket) == 0 {
+		data.Ticket = mu.Options.Ticket
+	}
+
+	for itr := lib.updatedDeps; itr != nil; itr = itr.Next {
+		data.Deps = append(data.Deps, CommitDep{Library: itr.File.GetGoURL(), Version: itr.File.Version, Updated: itr.File.Updated})
+		data.UpdatedDeps = append(data.UpdatedDeps, itr.File.GetGoURL()+"@"+itr.File.Version)
+	}
+
+	var output bytes.Buffer
+	if err = mu.commitTemplate.Execute(&output, data); err != nil {
+		err = fmt.Errorf("unable to render commit template: %v", err)
+		return
+	}
+
+	comps := strings.SplitN(strings.TrimSpace(output.String()), "\n", 2)
+	title = strings.TrimSpace(comps[0])
+	if len(comps) > 1 {
+		message = "\n" + strings.TrimSpace(comps[1])
+	}
+
+	if len(title) == 0 {
+		err = fmt.Errorf("commit template rendered an empty title")
+	}
+
+	return
+}
@@ -0,0 +1,60 @@
+package gomu
+
+import (
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestRenderCommitTemplate(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	lib.updatedDeps = &sort.FileNode{
+		File: &com.FileWrapper{Path: "github.com/org/b", Version: "v1.3.0", Updated: true},
+		Next: &sort.FileNode{File: &com.FileWrapper{Path: "github.com/org/c", Version: "v0.2.0"}},
+	}
+
+	mu := &MU{Options: Options{
+		Action:         "sync",
+		Branch:         "feature/ABC-123-bump-deps",
+		CommitTemplate: "{{.Ticket}}: bump {{len .Deps}} deps\n\n{{join .UpdatedDeps \"\\n\"}}",
+	}}
+	title, message, err := mu.renderCommitTemplate(*lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "ABC-123: bump 2 deps"; title != expected {
+		t.Errorf("expected title %q, got %q", expected, title)
+	}
+	if expected := "\ngithub.com/org/b@v1.3.0\ngithub.com/org/c@v0.2.0"; message != expected {
+		t.Errorf("expected message %q, got %q", expected, message)
+	}
+}
+
+func TestCommitTemplateTicket(t *testing.T) {
+	mu := &MU{Options: Options{CommitTemplate: "{{.Ticket}}", TicketPattern: `deps/(\d+)`}}
+	if err := mu.loadCommitTemplate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for branch, expected := range map[string]string{"deps/42-bump": "42", "ABC-123": ""} {
+		if ticket := mu.ticket(branch); ticket != expected {
+			t.Errorf("expected ticket %q in %s, got %q", expected, branch, ticket)
+		}
+	}
+}
+
+func TestCommitTemplateFallback(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+
+	// Ticket isn't in the branch or options, so the title is empty
+	mu := &MU{Options: Options{Action: "sync", Branch: "gomu", CommitTemplate: "{{.Ticket}}", CommitMessage: "Bump deps"}}
+	if title, _ := mu.getCommitDetails(*lib); title != "gomu: Bump deps" {
+		t.Errorf("expected commit message from options, got %q", title)
+	}
+
+	mu = &MU{Options: Options{CommitTemplate: "{{.Missing"}}
+	if err := mu.loadCommitTemplate(); err == nil {
+		t.Error("expected invalid template to fail")
+	}
+}
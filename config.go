@@ -0,0 +1,713 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigName is the file name searched for when no config path is provided
+const ConfigName = ".gomu.yaml"
+
+// LoadConfig reads options from a yaml config file, applying the named profile on top of the base values.
+// If path is empty, .gomu.yaml is searched for in the working directory and then the home directory
+// Note: returns empty options and no error if no path is provided and no config file is found
+func LoadConfig(configPath, profile string) (options Options, err error) {
+	if len(configPath) == 0 {
+		if configPath = findConfig(); len(configPath) == 0 {
+			if len(profile) > 0 {
+				err = fmt.Errorf("no %s found for profile %s", ConfigName, profile)
+			}
+			return
+		}
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return
+	}
+
+	config, err := parseYAMLPlain(data)
+	if err != nil {
+		err = fmt.Errorf("unable to parse %s: %v", configPath, err)
+		return
+	}
+
+	// Separate profiles from base values
+	profiles, _ := config["profiles"].(map[string]interface{})
+	delete(config, "profiles")
+
+	if len(profile) > 0 {
+		values, ok := profiles[profile].(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("profile %s not found in %s", profile, configPath)
+			return
+		}
+
+		// Profile values override base values
+		for key, value := range values {
+			config[key] = value
+		}
+	}
+
+	// Decode through json to reuse the option tags, plain scalars typed by the option they set
+	if config, err = typeYAMLOptions(config); err != nil {
+		err = fmt.Errorf("invalid option in %s: %v", configPath, err)
+		return
+	}
+
+	data, err = json.Marshal(config)
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(data, &options); err != nil {
+		err = fmt.Errorf("invalid option in %s: %v", configPath, err)
+	}
+
+	return
+}
+
+// Merge sets any non-zero values from override onto the options. Used to apply cli flags on top of config values.
+// Options named in explicit by their json name, e.g. flags set on the command line, are set even if zero, so a flag
+// can disable a bool the config enables. Unknown names are ignored
+// Note: other bool values can only be enabled by override, not disabled
+func (o *Options) Merge(override Options, explicit ...string) {
+	defer o.mergeExplicit(override, explicit)
+
+	if len(override.Action) > 0 {
+		o.Action = override.Action
+	}
+	if len(override.Branch) > 0 {
+		o.Branch = override.Branch
+	}
+	if len(override.CommitMessage) > 0 {
+		o.CommitMessage = override.CommitMessage
+	}
+	if len(override.CommitTemplate) > 0 {
+		o.CommitTemplate = override.CommitTemplate
+	}
+	if len(override.TicketPattern) > 0 {
+		o.TicketPattern = override.TicketPattern
+	}
+	if len(override.Ticket) > 0 {
+		o.Ticket = override.Ticket
+	}
+	if len(override.PRTemplate) > 0 {
+		o.PRTemplate = override.PRTemplate
+	}
+	if len(override.ModulePath) > 0 {
+		o.ModulePath = override.ModulePath
+	}
+	if len(override.MergeMethod) > 0 {
+		o.MergeMethod = override.MergeMethod
+	}
+	if len(override.SetVersion) > 0 {
+		o.SetVersion = override.SetVersion
+	}
+	if len(override.BumpStrategy) > 0 {
+		o.BumpStrategy = override.BumpStrategy
+	}
+	if len(override.BumpRules) > 0 {
+		o.BumpRules = override.BumpRules
+	}
+	if len(override.SigningKey) > 0 {
+		o.SigningKey = override.SigningKey
+	}
+	if len(override.TagPrefix) > 0 {
+		o.TagPrefix = override.TagPrefix
+	}
+	if len(override.VersionScheme) > 0 {
+		o.VersionScheme = override.VersionScheme
+	}
+	if len(override.Prerelease) > 0 {
+		o.Prerelease = override.Prerelease
+	}
+	if len(override.GoVersion) > 0 {
+		o.GoVersion = override.GoVersion
+	}
+	if len(override.Toolchain) > 0 {
+		o.Toolchain = override.Toolchain
+	}
+	if len(override.DirtyPolicy) > 0 {
+		o.DirtyPolicy = override.DirtyPolicy
+	}
+	if len(override.Proxy) > 0 {
+		o.Proxy = override.Proxy
+	}
+	if len(override.BaseBranch) > 0 {
+		o.BaseBranch = override.BaseBranch
+	}
+	if len(override.GoBinary) > 0 {
+		o.GoBinary = override.GoBinary
+	}
+	if len(override.SourcePath) > 0 {
+		o.SourcePath = override.SourcePath
+	}
+	if len(override.TargetDirectories) > 0 {
+		o.TargetDirectories = override.TargetDirectories
+	}
+	if len(override.FilterDependencies) > 0 {
+		o.FilterDependencies = override.FilterDependencies
+	}
+	if len(override.PlanFile) > 0 {
+		o.PlanFile = override.PlanFile
+	}
+	if len(override.IncludePatterns) > 0 {
+		o.IncludePatterns = override.IncludePatterns
+	}
+	if len(override.ExcludePatterns) > 0 {
+		o.ExcludePatterns = override.ExcludePatterns
+	}
+	if len(override.SlackWebhook) > 0 {
+		o.SlackWebhook = override.SlackWebhook
+	}
+	if len(override.NotifyURL) > 0 {
+		o.NotifyURL = override.NotifyURL
+	}
+	if len(override.Report) > 0 {
+		o.Report = override.Report
+	}
+	if len(override.Output) > 0 {
+		o.Output = override.Output
+	}
+	if len(override.GraphFormat) > 0 {
+		o.GraphFormat = override.GraphFormat
+	}
+	if len(override.SkipDirs) > 0 {
+		o.SkipDirs = override.SkipDirs
+	}
+	if len(override.ReleaseBuild) > 0 {
+		o.ReleaseBuild = override.ReleaseBuild
+	}
+	if len(override.ReleaseAssets) > 0 {
+		o.ReleaseAssets = override.ReleaseAssets
+	}
+	if len(override.ModuleFilter) > 0 {
+		o.ModuleFilter = override.ModuleFilter
+	}
+	if len(override.Secrets) > 0 {
+		o.Secrets = override.Secrets
+	}
+	if len(override.Pattern) > 0 {
+		o.Pattern = override.Pattern
+	}
+	if len(override.LicenseDeny) > 0 {
+		o.LicenseDeny = override.LicenseDeny
+	}
+	if len(override.AuditID) > 0 {
+		o.AuditID = override.AuditID
+	}
+	if len(override.ExecCommand) > 0 {
+		o.ExecCommand = override.ExecCommand
+	}
+	if len(override.ChangedRange) > 0 {
+		o.ChangedRange = override.ChangedRange
+	}
+	if len(override.ChangedRepo) > 0 {
+		o.ChangedRepo = override.ChangedRepo
+	}
+	if len(override.LogDir) > 0 {
+		o.LogDir = override.LogDir
+	}
+	if len(override.HistoryFile) > 0 {
+		o.HistoryFile = override.HistoryFile
+	}
+	if len(override.HistoryAction) > 0 {
+		o.HistoryAction = override.HistoryAction
+	}
+	if len(override.JUnitReport) > 0 {
+		o.JUnitReport = override.JUnitReport
+	}
+	if len(override.CoverProfile) > 0 {
+		o.CoverProfile = override.CoverProfile
+	}
+	if len(override.ManifestPath) > 0 {
+		o.ManifestPath = override.ManifestPath
+	}
+	if len(override.WorkspaceDir) > 0 {
+		o.WorkspaceDir = override.WorkspaceDir
+	}
+	if len(override.PinsFile) > 0 {
+		o.PinsFile = override.PinsFile
+	}
+	for point, commands := range override.Hooks {
+		if o.Hooks == nil {
+			o.Hooks = make(map[string][]string)
+		}
+		o.Hooks[point] = commands
+	}
+	for from, to := range override.ModuleMoves {
+		if o.ModuleMoves == nil {
+			o.ModuleMoves = make(map[string]string)
+		}
+		o.ModuleMoves[from] = to
+	}
+	for name, command := range override.Plugins {
+		if o.Plugins == nil {
+			o.Plugins = make(map[string]string)
+		}
+		o.Plugins[name] = command
+	}
+	for prefix, base := range override.BaseBranches {
+		if o.BaseBranches == nil {
+			o.BaseBranches = make(map[string]string)
+		}
+		o.BaseBranches[prefix] = base
+	}
+	for prefix, options := range override.LibraryOptions {
+		if o.LibraryOptions == nil {
+			o.LibraryOptions = make(map[string]LibraryOverride)
+		}
+		o.LibraryOptions[prefix] = options
+	}
+	for host, limit := range override.HostConcurrency {
+		if o.HostConcurrency == nil {
+			o.HostConcurrency = make(map[string]int)
+		}
+		o.HostConcurrency[host] = limit
+	}
+	for prefix, binary := range override.GoBinaries {
+		if o.GoBinaries == nil {
+			o.GoBinaries = make(map[string]string)
+		}
+		o.GoBinaries[prefix] = binary
+	}
+	if len(override.OTLPEndpoint) > 0 {
+		o.OTLPEndpoint = override.OTLPEndpoint
+	}
+	for name, value := range override.OTLPHeaders {
+		if o.OTLPHeaders == nil {
+			o.OTLPHeaders = make(map[string]string)
+		}
+		o.OTLPHeaders[name] = value
+	}
+	for name, value := range override.Env {
+		if o.Env == nil {
+			o.Env = make(map[string]string)
+		}
+		o.Env[name] = value
+	}
+	for prefix, vars := range override.LibraryEnv {
+		if o.LibraryEnv == nil {
+			o.LibraryEnv = make(map[string]map[string]string)
+		}
+		if o.LibraryEnv[prefix] == nil {
+			o.LibraryEnv[prefix] = make(map[string]string)
+		}
+		for name, value := range vars {
+			o.LibraryEnv[prefix][name] = value
+		}
+	}
+	for host, provider := range override.Providers {
+		if o.Providers == nil {
+			o.Providers = make(map[string]string)
+		}
+		o.Providers[host] = provider
+	}
+	if override.LogLevel != 0 {
+		o.LogLevel = override.LogLevel
+	}
+	if override.LibraryTimeout != 0 {
+		o.LibraryTimeout = override.LibraryTimeout
+	}
+	if override.Retries != 0 {
+		o.Retries = override.Retries
+	}
+	if override.RetryBackoff != 0 {
+		o.RetryBackoff = override.RetryBackoff
+	}
+	if override.MaxConcurrency != 0 {
+		o.MaxConcurrency = override.MaxConcurrency
+	}
+	if override.MaxDepth != 0 {
+		o.MaxDepth = override.MaxDepth
+	}
+	if override.SearchDepth != 0 {
+		o.SearchDepth = override.SearchDepth
+	}
+	if override.ErrorThreshold != 0 {
+		o.ErrorThreshold = override.ErrorThreshold
+	}
+	if override.HistoryLimit != 0 {
+		o.HistoryLimit = override.HistoryLimit
+	}
+	if override.DiffRuns != 0 {
+		o.DiffRuns = override.DiffRuns
+	}
+	if len(override.WatchAction) > 0 {
+		o.WatchAction = override.WatchAction
+	}
+	if override.WatchInterval != 0 {
+		o.WatchInterval = override.WatchInterval
+	}
+	if override.RetryBudget != 0 {
+		o.RetryBudget = override.RetryBudget
+	}
+	if override.APIInterval != 0 {
+		o.APIInterval = override.APIInterval
+	}
+	if override.MaxVersionsBehind != 0 {
+		o.MaxVersionsBehind = override.MaxVersionsBehind
+	}
+	if override.MaxDaysBehind != 0 {
+		o.MaxDaysBehind = override.MaxDaysBehind
+	}
+	if override.ChecksTimeout != 0 {
+		o.ChecksTimeout = override.ChecksTimeout
+	}
+	if override.ChecksInterval != 0 {
+		o.ChecksInterval = override.ChecksInterval
+	}
+	if override.RateLimitWait != 0 {
+		o.RateLimitWait = override.RateLimitWait
+	}
+	if len(override.TrustedKeys) > 0 {
+		o.TrustedKeys = override.TrustedKeys
+	}
+	if len(override.OnUnverifiedTag) > 0 {
+		o.OnUnverifiedTag = override.OnUnverifiedTag
+	}
+	if len(override.OnProtected) > 0 {
+		o.OnProtected = override.OnProtected
+	}
+	if len(override.OnCycle) > 0 {
+		o.OnCycle = override.OnCycle
+	}
+	if len(override.PushAuth) > 0 {
+		o.PushAuth = override.PushAuth
+	}
+	if len(override.Remote) > 0 {
+		o.Remote = override.Remote
+	}
+	if !override.Deadline.IsZero() {
+		o.Deadline = override.Deadline
+	}
+
+	o.Commit = o.Commit || override.Commit
+	o.PullRequest = o.PullRequest || override.PullRequest
+	o.DraftPR = o.DraftPR || override.DraftPR
+	o.AutoMerge = o.AutoMerge || override.AutoMerge
+	o.WaitChecks = o.WaitChecks || override.WaitChecks
+	o.CreateRepo = o.CreateRepo || override.CreateRepo
+	o.PrivateRepo = o.PrivateRepo || override.PrivateRepo
+	o.MergeGreen = o.MergeGreen || override.MergeGreen
+	o.BatchPR = o.BatchPR || override.BatchPR
+	o.Tag = o.Tag || override.Tag
+	o.SignTags = o.SignTags || override.SignTags
+	o.SignCommits = o.SignCommits || override.SignCommits
+	o.VerifyTags = o.VerifyTags || override.VerifyTags
+	o.Changelog = o.Changelog || override.Changelog
+	o.CreateRelease = o.CreateRelease || override.CreateRelease
+	o.DirectImport = o.DirectImport || override.DirectImport
+	o.UseWorkspace = o.UseWorkspace || override.UseWorkspace
+	o.NestedModules = o.NestedModules || override.NestedModules
+	o.IgnoreWarning = o.IgnoreWarning || override.IgnoreWarning
+	o.NonInteractive = o.NonInteractive || override.NonInteractive
+	o.DryRun = o.DryRun || override.DryRun
+	o.Resume = o.Resume || override.Resume
+	o.ParallelSync = o.ParallelSync || override.ParallelSync
+	o.ParallelTest = o.ParallelTest || override.ParallelTest
+	o.Atomic = o.Atomic || override.Atomic
+	o.DeleteTags = o.DeleteTags || override.DeleteTags
+	o.Worktree = o.Worktree || override.Worktree
+	o.Progress = o.Progress || override.Progress
+	o.VerifyVendor = o.VerifyVendor || override.VerifyVendor
+	o.VerifyTidy = o.VerifyTidy || override.VerifyTidy
+	o.KeepGoing = o.KeepGoing || override.KeepGoing
+	o.AffectedTest = o.AffectedTest || override.AffectedTest
+	o.NoHistory = o.NoHistory || override.NoHistory
+	o.ListOnly = o.ListOnly || override.ListOnly
+	o.NoCache = o.NoCache || override.NoCache
+	o.CloneMissing = o.CloneMissing || override.CloneMissing
+	o.FullHistory = o.FullHistory || override.FullHistory
+	o.IgnoreSkipMarkers = o.IgnoreSkipMarkers || override.IgnoreSkipMarkers
+	o.VerifySums = o.VerifySums || override.VerifySums
+	o.DropReplace = o.DropReplace || override.DropReplace
+	o.RestoreReplace = o.RestoreReplace || override.RestoreReplace
+	o.Align = o.Align || override.Align
+	o.Source = o.Source || override.Source
+}
+
+// mergeExplicit sets the options named in explicit from override, whatever their value
+func (o *Options) mergeExplicit(override Options, explicit []string) {
+	target, source := reflect.ValueOf(o).Elem(), reflect.ValueOf(override)
+	for _, name := range explicit {
+		if field, ok := jsonField(target.Type(), name); ok {
+			target.FieldByIndex(field.Index).Set(source.FieldByIndex(field.Index))
+		}
+	}
+}
+
+// findConfig returns the path of the first config found in the working or home directory
+func findConfig() string {
+	if _, err := os.Stat(ConfigName); err == nil {
+		return ConfigName
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	homeConfig := filepath.Join(usr.HomeDir, ConfigName)
+	if _, err := os.Stat(homeConfig); err == nil {
+		return homeConfig
+	}
+
+	return ""
+}
+
+// yamlPlain represents an unquoted scalar, typed once it's known what it's read into
+type yamlPlain string
+
+// parseYAML parses a yaml config file of maps, lists and scalars. Plain scalars are read as bools or ints if they look
+// like one
+func parseYAML(data []byte) (config map[string]interface{}, err error) {
+	if config, err = parseYAMLPlain(data); err != nil {
+		return
+	}
+
+	config = guessYAML(config).(map[string]interface{})
+	return
+}
+
+// parseYAMLPlain parses yaml like parseYAML, leaving plain scalars as yamlPlain
+func parseYAMLPlain(data []byte) (config map[string]interface{}, err error) {
+	var document yaml.Node
+	if err = yaml.Unmarshal(data, &document); err != nil {
+		return
+	}
+
+	config = make(map[string]interface{})
+	if len(document.Content) == 0 {
+		// Empty or only comments
+		return
+	}
+
+	value, err := yamlValue(document.Content[0])
+	if err != nil {
+		return
+	}
+
+	var ok bool
+	if config, ok = value.(map[string]interface{}); !ok {
+		err = fmt.Errorf("expected map at top level")
+	}
+
+	return
+}
+
+// yamlValue returns the maps, lists and scalars of node. Scalars which aren't quoted, block or tagged as strings
+// are returned as yamlPlain
+func yamlValue(node *yaml.Node) (value interface{}, err error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		values := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, item := node.Content[i], node.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				err = fmt.Errorf("line %d: expected a scalar key", key.Line)
+				return
+			}
+
+			if values[key.Value], err = yamlValue(item); err != nil {
+				return
+			}
+		}
+		return values, nil
+	case yaml.SequenceNode:
+		values := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			if value, err = yamlValue(item); err != nil {
+				return
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!null" {
+			return nil, nil
+		}
+
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) != 0 ||
+			(node.Style&yaml.TaggedStyle != 0 && node.ShortTag() == "!!str") {
+			return node.Value, nil
+		}
+		return yamlPlain(node.Value), nil
+	}
+
+	return nil, fmt.Errorf("line %d: unsupported yaml node", node.Line)
+}
+
+// yamlBool returns the value of a plain scalar spelling a bool, ok is false if it doesn't
+func yamlBool(text yamlPlain) (value, ok bool) {
+	switch text {
+	case "true", "True", "TRUE", "yes", "Yes", "YES", "on", "On", "ON":
+		return true, true
+	case "false", "False", "FALSE", "no", "No", "NO", "off", "Off", "OFF":
+		return false, true
+	}
+
+	return false, false
+}
+
+// guessYAML types plain scalars within value as bools or ints if they look like one, otherwise strings
+func guessYAML(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = guessYAML(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = guessYAML(item)
+		}
+	case yamlPlain:
+		if boolean, ok := yamlBool(value); ok {
+			return boolean
+		}
+		if number, err := strconv.Atoi(string(value)); err == nil {
+			return number
+		}
+		return string(value)
+	}
+
+	return value
+}
+
+// durationType is typed from durations such as 5m as well as nanoseconds
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// typeYAMLOptions types the plain scalars of config by the options they set, so strings such as branch: no or
+// setVersion: 1 stay strings. Values of unknown options are guessed
+func typeYAMLOptions(config map[string]interface{}) (typed map[string]interface{}, err error) {
+	value, err := typeYAML(config, reflect.TypeOf(Options{}))
+	if err != nil {
+		return
+	}
+
+	return value.(map[string]interface{}), nil
+}
+
+// typeYAML types the plain scalars within value by t, the type value is decoded into
+func typeYAML(value interface{}, t reflect.Type) (typed interface{}, err error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			itemType := reflect.TypeOf((*interface{})(nil)).Elem()
+			if t.Kind() == reflect.Map {
+				itemType = t.Elem()
+			} else if field, ok := jsonField(t, key); ok {
+				itemType = field.Type
+			}
+
+			if value[key], err = typeYAML(item, itemType); err != nil {
+				err = fmt.Errorf("%s: %v", key, err)
+				return
+			}
+		}
+		return value, nil
+	case []interface{}:
+		itemType := reflect.TypeOf((*interface{})(nil)).Elem()
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			itemType = t.Elem()
+		}
+
+		for i, item := range value {
+			if value[i], err = typeYAML(item, itemType); err != nil {
+				return
+			}
+		}
+		return value, nil
+	case yamlPlain:
+		return typeYAMLScalar(value, t)
+	}
+
+	return value, nil
+}
+
+// typeYAMLScalar types a plain scalar by t
+func typeYAMLScalar(text yamlPlain, t reflect.Type) (value interface{}, err error) {
+	if t == durationType {
+		if _, err = strconv.ParseInt(string(text), 10, 64); err == nil {
+			return json.Number(text), nil
+		}
+
+		var duration time.Duration
+		if duration, err = time.ParseDuration(string(text)); err != nil {
+			err = fmt.Errorf("invalid duration %q", text)
+			return
+		}
+		return int64(duration), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return string(text), nil
+	case reflect.Bool:
+		boolean, ok := yamlBool(text)
+		if !ok {
+			err = fmt.Errorf("expected a bool, got %q", text)
+		}
+		return boolean, err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		if _, err = strconv.ParseFloat(string(text), 64); err != nil {
+			err = fmt.Errorf("expected a number, got %q", text)
+			return
+		}
+		return json.Number(text), nil
+	case reflect.Interface:
+		return guessYAML(text), nil
+	}
+
+	// Decoded from a string, e.g. a time
+	return string(text), nil
+}
+
+// jsonField returns the field of struct type t decoded from the json key name, matched like encoding/json does
+func jsonField(t reflect.Type, name string) (field reflect.StructField, ok bool) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field = t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if len(field.PkgPath) > 0 || tag == "-" {
+			// Unexported or ignored
+			continue
+		}
+
+		if len(tag) == 0 {
+			tag = field.Name
+		}
+
+		if strings.EqualFold(tag, name) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,175 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, config string) string {
+	dir, err := ioutil.TempDir("", "gomu-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	configPath := filepath.Join(dir, ConfigName)
+	if err = ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return configPath
+}
+
+func TestParseYAML(t *testing.T) {
+	config, err := parseYAML([]byte(`
+# comment
+---
+name: value # trailing comment
+quoted: "a # b"
+single: 'no'
+enabled: yes
+disabled: off
+count: 3
+empty: ~
+inline: [a, 2, "c"]
+commas: ["a,b", c]
+flow: {key: value, flag: on}
+block: |
+  line one
+  line two
+nested:
+  key: value
+  deeper:
+    flag: true
+list:
+- one
+- two
+indented:
+  - three
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"name":     "value",
+		"quoted":   "a # b",
+		"single":   "no",
+		"enabled":  true,
+		"disabled": false,
+		"count":    3,
+		"empty":    nil,
+		"inline":   []interface{}{"a", 2, "c"},
+		"commas":   []interface{}{"a,b", "c"},
+		"flow":     map[string]interface{}{"key": "value", "flag": true},
+		"block":    "line one\nline two\n",
+		"nested": map[string]interface{}{
+			"key":    "value",
+			"deeper": map[string]interface{}{"flag": true},
+		},
+		"list":     []interface{}{"one", "two"},
+		"indented": []interface{}{"three"},
+	}
+
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, config)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing colon":   "key value",
+		"bad indentation": "a: b\n  c: d",
+		"unclosed list":   "list: [a, b",
+		"top level list":  "- a\n- b",
+	} {
+		if _, err := parseYAML([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadConfigTypesByOption(t *testing.T) {
+	configPath := writeConfig(t, `
+branch: no
+setVersion: 1
+message: 2.0
+shouldTag: yes
+createPR: off
+maxConcurrency: 4
+libraryTimeout: 5m
+retryBackoff: 1000
+hostConcurrency:
+  github.com: 2
+libraryOptions:
+  github.com/org/legacy:
+    branch: true
+    shouldTag: no
+  github.com/org/flow: {shouldTag: false, createPR: true}
+profiles:
+  release:
+    setVersion: 2
+    draftPR: true
+`)
+
+	options, err := LoadConfig(configPath, "release")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Branch != "no" || options.SetVersion != "2" || options.CommitMessage != "2.0" {
+		t.Errorf("expected strings to stay strings, got %q, %q and %q", options.Branch, options.SetVersion, options.CommitMessage)
+	}
+	if !options.Tag || options.PullRequest || !options.DraftPR {
+		t.Errorf("expected bools to be typed, got %v, %v and %v", options.Tag, options.PullRequest, options.DraftPR)
+	}
+	if options.MaxConcurrency != 4 || options.HostConcurrency["github.com"] != 2 {
+		t.Errorf("expected ints to be typed, got %d and %v", options.MaxConcurrency, options.HostConcurrency)
+	}
+	if options.LibraryTimeout != 5*time.Minute || options.RetryBackoff != 1000 {
+		t.Errorf("expected durations to be typed, got %v and %v", options.LibraryTimeout, options.RetryBackoff)
+	}
+
+	override := options.LibraryOptions["github.com/org/legacy"]
+	if override.Branch != "true" || override.Tag == nil || *override.Tag {
+		t.Errorf("expected library options to be typed, got %+v", override)
+	}
+
+	flow := options.LibraryOptions["github.com/org/flow"]
+	if flow.Tag == nil || *flow.Tag || flow.PullRequest == nil || !*flow.PullRequest {
+		t.Errorf("expected flow library options to be typed, got %+v", flow)
+	}
+}
+
+func TestLoadConfigInvalidScalar(t *testing.T) {
+	for name, config := range map[string]string{
+		"bool":     "shouldTag: maybe",
+		"number":   "maxConcurrency: many",
+		"duration": "libraryTimeout: soon",
+	} {
+		if _, err := LoadConfig(writeConfig(t, config), ""); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMergeExplicit(t *testing.T) {
+	options := Options{Tag: true, DryRun: true, Branch: "sync", MaxConcurrency: 4}
+
+	options.Merge(Options{Branch: "other"})
+	if !options.Tag || !options.DryRun || options.Branch != "other" || options.MaxConcurrency != 4 {
+		t.Fatalf("expected zero values not to override, got %+v", options)
+	}
+
+	options.Merge(Options{}, "shouldTag", "maxconcurrency", "unknown")
+	if options.Tag || options.MaxConcurrency != 0 {
+		t.Errorf("expected explicit options to be set, got tag %v and concurrency %d", options.Tag, options.MaxConcurrency)
+	}
+	if !options.DryRun || options.Branch != "other" {
+		t.Errorf("expected other options to be kept, got dry run %v and branch %q", options.DryRun, options.Branch)
+	}
+}
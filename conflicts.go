@@ -0,0 +1,149 @@
+package gomu
+
+import (
+	"fmt"
+	gosort "sort"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// VersionConflict represents a third-party module required at different versions by libs
+type VersionConflict struct {
+	Module   string            `json:"module"`
+	Highest  string            `json:"highest"`
+	Versions []ConflictVersion `json:"versions"` // Oldest first
+}
+
+// ConflictVersion represents the libs requiring a module at a version
+type ConflictVersion struct {
+	Version   string   `json:"version"`
+	Libraries []string `json:"libraries"`
+}
+
+// versionLess is true if version is older than other. Releases are newer than their pre-releases, and versions which
+// aren't semantic are compared as strings
+func versionLess(version, other string) bool {
+	if semverLess(version, other) {
+		return true
+	} else if semverLess(other, version) {
+		return false
+	}
+
+	pre, otherPre := strings.Contains(version, "-"), strings.Contains(other, "-")
+	if pre != otherPre {
+		return pre
+	}
+
+	return version < other
+}
+
+// findConflicts returns third-party modules required at different versions by libs in the list, sorted by module.
+// Modules of the libs themselves are synced instead, so they're skipped
+func findConflicts(fileHead *sort.FileNode) (conflicts []VersionConflict) {
+	snapshot := snapshotDeps(fileHead)
+
+	byModule := make(map[string]map[string][]string)
+	for lib, versions := range snapshot {
+		for module, version := range versions {
+			if _, ok := snapshot[com.ModuleBase(module)]; ok {
+				continue
+			}
+
+			if byModule[module] == nil {
+				byModule[module] = make(map[string][]string)
+			}
+			byModule[module][version] = append(byModule[module][version], lib)
+		}
+	}
+
+	for module, libsByVersion := range byModule {
+		if len(libsByVersion) < 2 {
+			continue
+		}
+
+		conflict := VersionConflict{Module: module}
+		for version, libs := range libsByVersion {
+			gosort.Strings(libs)
+			conflict.Versions = append(conflict.Versions, ConflictVersion{Version: version, Libraries: libs})
+		}
+
+		gosort.Slice(conflict.Versions, func(i, j int) bool {
+			return versionLess(conflict.Versions[i].Version, conflict.Versions[j].Version)
+		})
+		conflict.Highest = conflict.Versions[len(conflict.Versions)-1].Version
+		conflicts = append(conflicts, conflict)
+	}
+
+	gosort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Module < conflicts[j].Module
+	})
+
+	return
+}
+
+// describe returns a one line description of the conflict
+func (conflict VersionConflict) describe() string {
+	versions := make([]string, len(conflict.Versions))
+	for i, version := range conflict.Versions {
+		versions[i] = version.Version + " in " + strings.Join(version.Libraries, ", ")
+	}
+
+	return conflict.Module + ": " + strings.Join(versions, "; ")
+}
+
+// alignments returns the highest version of each conflicting module, which libs are aligned to by sync
+func alignments(conflicts []VersionConflict) (versions map[string]string) {
+	versions = make(map[string]string, len(conflicts))
+	for _, conflict := range conflicts {
+		versions[conflict.Module] = conflict.Highest
+	}
+
+	return
+}
+
+// conflicts reports third-party modules required at different versions by libs, e.g. before aligning them with sync
+func (mu *MU) conflicts(fileHead *sort.FileNode) {
+	mu.Stats.Conflicts = findConflicts(fileHead)
+	for _, conflict := range mu.Stats.Conflicts {
+		mu.addStat(&mu.Stats.ConflictCount, &mu.Stats.ConflictOutput, conflict.describe()+"\n")
+	}
+}
+
+// ModAlign updates third-party modules required at older versions than other libs require to the highest version.
+// Pinned modules are held
+func (lib *Library) ModAlign() (err error) {
+	if len(lib.align) == 0 {
+		return
+	}
+
+	required, err := requiredVersions(lib.File.Path)
+	if err != nil {
+		return
+	}
+
+	modules := make([]string, 0, len(lib.align))
+	for module := range lib.align {
+		modules = append(modules, module)
+	}
+	gosort.Strings(modules)
+
+	for _, module := range modules {
+		highest := lib.align[module]
+		version, ok := required[module]
+		if !ok || !versionLess(version, highest) || lib.pinned(module, highest) {
+			continue
+		}
+
+		if lib.File.RunCmd(lib.File.GoBinary(), "get", "-d", module+"@"+highest) == nil {
+			lib.File.UpdatedDeps = append(lib.File.UpdatedDeps, module+"@"+highest)
+			lib.File.Output("Aligned " + module + " @ " + highest + " from " + version)
+		} else {
+			lib.File.Output("Error: Failed to align " + module + " @ " + highest)
+			err = fmt.Errorf("Unable to align dependency: " + module + " @ " + highest)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,117 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// requiringLibrary returns a recorded lib at module within the go/src directory src, whose go.mod requires modules at
+// versions
+func requiringLibrary(t *testing.T, src, module string, requires ...string) (lib *Library, recorder *com.Recorder) {
+	dir := filepath.Join(src, filepath.FromSlash(module))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	goMod := "module " + module + "\n\ngo 1.14\n\nrequire (\n"
+	for _, require := range requires {
+		goMod += "\t" + require + "\n"
+	}
+	goMod += ")\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder = &com.Recorder{}
+	lib = LibraryFromPath(dir)
+	lib.File.SetRunner(recorder)
+	return
+}
+
+// goSrc returns a temporary go/src directory
+func goSrc(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gomu-conflicts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "go", "src")
+}
+
+func TestVersionLess(t *testing.T) {
+	for _, test := range []struct {
+		version, other string
+		less           bool
+	}{
+		{"v1.0.0", "v1.0.1", true},
+		{"v1.9.0", "v1.10.0", true},
+		{"v2.0.0", "v1.10.0", false},
+		{"v1.0.0-rc.1", "v1.0.0", true},
+		{"v1.0.0", "v1.0.0-rc.1", false},
+		{"v1.0.0", "v1.0.0", false},
+		{"v0.0.0-20200101000000-abcdef123456", "v0.0.0-20210101000000-abcdef123456", true},
+	} {
+		if less := versionLess(test.version, test.other); less != test.less {
+			t.Errorf("expected %s < %s to be %v", test.version, test.other, test.less)
+		}
+	}
+}
+
+func TestFindConflicts(t *testing.T) {
+	src := goSrc(t)
+	a, _ := requiringLibrary(t, src, "github.com/org/a", "github.com/third/x v1.2.0", "github.com/third/y v0.1.0")
+	b, _ := requiringLibrary(t, src, "github.com/org/b", "github.com/third/x v1.10.0", "github.com/third/y v0.1.0")
+	c, _ := requiringLibrary(t, src, "github.com/org/c", "github.com/third/x v1.2.0")
+	// Libs are synced rather than aligned, at whichever major they're required
+	d, _ := requiringLibrary(t, src, "github.com/org/d", "github.com/org/a v1.0.0", "github.com/org/b/v2 v2.0.0")
+	e, _ := requiringLibrary(t, src, "github.com/org/e", "github.com/org/a v1.1.0", "github.com/org/b/v2 v2.1.0")
+
+	fileHead := &sort.FileNode{File: a.File, Next: &sort.FileNode{File: b.File, Next: &sort.FileNode{File: c.File,
+		Next: &sort.FileNode{File: d.File, Next: &sort.FileNode{File: e.File}}}}}
+	expected := []VersionConflict{{
+		Module:  "github.com/third/x",
+		Highest: "v1.10.0",
+		Versions: []ConflictVersion{
+			{Version: "v1.2.0", Libraries: []string{"github.com/org/a", "github.com/org/c"}},
+			{Version: "v1.10.0", Libraries: []string{"github.com/org/b"}},
+		},
+	}}
+
+	conflicts := findConflicts(fileHead)
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, conflicts)
+	}
+	if description := conflicts[0].describe(); description != "github.com/third/x: v1.2.0 in github.com/org/a, github.com/org/c; v1.10.0 in github.com/org/b" {
+		t.Errorf("unexpected description %q", description)
+	}
+	if versions := alignments(conflicts); !reflect.DeepEqual(versions, map[string]string{"github.com/third/x": "v1.10.0"}) {
+		t.Errorf("expected x to be aligned to v1.10.0, got %v", versions)
+	}
+}
+
+func TestModAlign(t *testing.T) {
+	lib, recorder := requiringLibrary(t, goSrc(t), "github.com/org/a", "github.com/third/x v1.2.0", "github.com/third/y v0.3.0")
+	lib.align = map[string]string{
+		"github.com/third/x": "v1.10.0",
+		// Already at or above the highest, or not required
+		"github.com/third/y": "v0.3.0",
+		"github.com/third/z": "v1.0.0",
+	}
+
+	if err := lib.ModAlign(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, []string{"go get -d github.com/third/x@v1.10.0"}) {
+		t.Errorf("expected only x to be aligned, got %v", lines)
+	}
+	if !reflect.DeepEqual(lib.File.UpdatedDeps, []string{"github.com/third/x@v1.10.0"}) {
+		t.Errorf("expected aligned module to be listed as updated, got %v", lib.File.UpdatedDeps)
+	}
+}
@@ -0,0 +1,178 @@
+package gomu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	gosort "sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// DepSnapshot maps each lib's go url to the versions of modules its go.mod requires
+type DepSnapshot map[string]map[string]string
+
+// DepChange represents a module whose required version changed in libs between snapshots.
+// From is empty if the module was added, To if it was removed
+type DepChange struct {
+	Module    string   `json:"module"`
+	From      string   `json:"from,omitempty"`
+	To        string   `json:"to,omitempty"`
+	Libraries []string `json:"libraries"`
+}
+
+// requiredVersions parses the versions of modules required by the go.mod in dir
+func requiredVersions(dir string) (versions map[string]string, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return
+	}
+
+	versions = make(map[string]string)
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock && len(fields) >= 2:
+			versions[fields[0]] = fields[1]
+		case fields[0] != "require":
+		case len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+		case len(fields) >= 3:
+			versions[fields[1]] = fields[2]
+		}
+	}
+
+	return
+}
+
+// snapshotDeps returns the versions required by each lib in the list, read from their original paths
+func snapshotDeps(fileHead *sort.FileNode) (snapshot DepSnapshot) {
+	snapshot = make(DepSnapshot)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		if versions, err := requiredVersions(itr.File.OriginalPath()); err == nil {
+			snapshot[itr.File.GetGoURL()] = versions
+		}
+	}
+
+	return
+}
+
+// diffSnapshots returns modules whose versions changed between previous and current, grouping libs which made the
+// same change. Only libs in both snapshots are compared
+func diffSnapshots(previous, current DepSnapshot) (changes []DepChange) {
+	type key struct {
+		module, from, to string
+	}
+
+	byChange := make(map[key][]string)
+	for lib, versions := range current {
+		previousVersions, ok := previous[lib]
+		if !ok {
+			continue
+		}
+
+		for module, version := range versions {
+			if from := previousVersions[module]; from != version {
+				change := key{module, from, version}
+				byChange[change] = append(byChange[change], lib)
+			}
+		}
+
+		for module, from := range previousVersions {
+			if _, ok := versions[module]; !ok {
+				change := key{module: module, from: from}
+				byChange[change] = append(byChange[change], lib)
+			}
+		}
+	}
+
+	for change, libs := range byChange {
+		gosort.Strings(libs)
+		changes = append(changes, DepChange{Module: change.module, From: change.from, To: change.to, Libraries: libs})
+	}
+
+	gosort.Slice(changes, func(i, j int) bool {
+		if changes[i].Module != changes[j].Module {
+			return changes[i].Module < changes[j].Module
+		}
+		if changes[i].From != changes[j].From {
+			return changes[i].From < changes[j].From
+		}
+		return changes[i].To < changes[j].To
+	})
+
+	return
+}
+
+// describe returns a one line description of the change
+func (change DepChange) describe() string {
+	libs := strings.Join(change.Libraries, ", ")
+	switch {
+	case len(change.From) == 0:
+		return change.Module + " added at " + change.To + " in " + libs
+	case len(change.To) == 0:
+		return change.Module + " " + change.From + " removed from " + libs
+	default:
+		return change.Module + " " + change.From + " -> " + change.To + " in " + libs
+	}
+}
+
+// previousSnapshot returns the run recorded with a snapshot the diff runs ago (the latest by default), of the history
+// action if set. False if there aren't as many runs
+func (mu *MU) previousSnapshot() (record HistoryRecord, ok bool, err error) {
+	records, err := LoadHistory(mu.historyFile())
+	if os.IsNotExist(err) {
+		return record, false, nil
+	} else if err != nil {
+		return
+	}
+
+	runs := mu.Options.DiffRuns
+	if runs == 0 {
+		runs = 1
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Deps == nil || (len(mu.Options.HistoryAction) > 0 && records[i].Action != mu.Options.HistoryAction) {
+			continue
+		}
+
+		if runs--; runs == 0 {
+			return records[i], true, nil
+		}
+	}
+
+	return
+}
+
+// diff lists modules whose versions changed in libs since a previous run's snapshot, e.g. for release announcements
+func (mu *MU) diff(fileHead *sort.FileNode) {
+	record, ok, err := mu.previousSnapshot()
+	if err != nil {
+		mu.configError(fmt.Errorf("unable to read run history %s: %v", mu.historyFile(), err))
+		return
+	} else if !ok {
+		com.Println("\nNo previous run with a dependency snapshot found in", mu.historyFile())
+		return
+	}
+
+	mu.Stats.DiffSince = record.describe()
+	mu.Stats.Changes = diffSnapshots(record.Deps, snapshotDeps(fileHead))
+	for _, change := range mu.Stats.Changes {
+		mu.addStat(&mu.Stats.DiffCount, &mu.Stats.DiffOutput, change.describe()+"\n")
+	}
+
+	com.Println("\nCompared", strconv.Itoa(len(record.Deps)), "lib(s) recorded by", record.describe())
+}
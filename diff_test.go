@@ -0,0 +1,119 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestRequiredVersions(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	goMod := `module github.com/org/a
+
+go 1.14
+
+require github.com/org/b v1.0.0
+
+require (
+	github.com/org/c v0.2.0 // indirect
+	// github.com/org/d v0.1.0
+	github.com/org/e v1.1.0
+)
+
+replace github.com/org/f => ../f
+`
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := requiredVersions(lib.File.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"github.com/org/b": "v1.0.0", "github.com/org/c": "v0.2.0", "github.com/org/e": "v1.1.0"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %v, got %v", expected, versions)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := DepSnapshot{
+		"github.com/org/a": {"github.com/org/c": "v1.0.0", "github.com/org/d": "v0.1.0"},
+		"github.com/org/b": {"github.com/org/c": "v1.0.0"},
+		"github.com/org/x": {"github.com/org/c": "v0.1.0"},
+	}
+	current := DepSnapshot{
+		"github.com/org/a": {"github.com/org/c": "v1.1.0", "github.com/org/e": "v0.3.0"},
+		"github.com/org/b": {"github.com/org/c": "v1.1.0"},
+		"github.com/org/y": {"github.com/org/c": "v2.0.0"},
+	}
+
+	expected := []DepChange{
+		{Module: "github.com/org/c", From: "v1.0.0", To: "v1.1.0", Libraries: []string{"github.com/org/a", "github.com/org/b"}},
+		{Module: "github.com/org/d", From: "v0.1.0", Libraries: []string{"github.com/org/a"}},
+		{Module: "github.com/org/e", To: "v0.3.0", Libraries: []string{"github.com/org/a"}},
+	}
+	changes := diffSnapshots(previous, current)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, changes)
+	}
+
+	for i, description := range []string{
+		"github.com/org/c v1.0.0 -> v1.1.0 in github.com/org/a, github.com/org/b",
+		"github.com/org/d v0.1.0 removed from github.com/org/a",
+		"github.com/org/e added at v0.3.0 in github.com/org/a",
+	} {
+		if changes[i].describe() != description {
+			t.Errorf("expected %q, got %q", description, changes[i].describe())
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	goMod := "module github.com/org/a\n\ngo 1.14\n\nrequire github.com/org/b v1.1.0\n"
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	url := lib.File.GetGoURL()
+
+	started := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	older := historyRecord(started, "sync", time.Minute)
+	older.Deps = DepSnapshot{url: {"github.com/org/b": "v0.9.0"}}
+	latest := historyRecord(started.Add(time.Hour), "sync", time.Minute)
+	latest.Deps = DepSnapshot{url: {"github.com/org/b": "v1.0.0"}}
+	// Without a snapshot, so not diffed against
+	untracked := historyRecord(started.Add(2*time.Hour), "test", time.Minute)
+
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	historyPath := historyFile(t, older, latest, untracked)
+	for runs, from := range map[int]string{0: "v1.0.0", 2: "v0.9.0"} {
+		mu := &MU{Options: Options{HistoryFile: historyPath, DiffRuns: runs}}
+		mu.diff(&sort.FileNode{File: lib.File})
+
+		expected := []DepChange{{Module: "github.com/org/b", From: from, To: "v1.1.0", Libraries: []string{url}}}
+		if !reflect.DeepEqual(mu.Stats.Changes, expected) {
+			t.Errorf("expected %+v diffing %d runs ago, got %+v", expected, runs, mu.Stats.Changes)
+		}
+		if mu.Stats.DiffCount != 1 || len(mu.Stats.DiffSince) == 0 {
+			t.Errorf("expected the change and run diffed against to be counted, got %d since %q", mu.Stats.DiffCount, mu.Stats.DiffSince)
+		}
+	}
+
+	mu := &MU{Options: Options{HistoryFile: historyPath, DiffRuns: 3}}
+	if _, ok, err := mu.previousSnapshot(); ok || err != nil {
+		t.Errorf("expected no snapshot that many runs ago, got %v (%v)", ok, err)
+	}
+
+	mu = &MU{Options: Options{HistoryFile: filepath.Join(lib.File.Path, "missing.jsonl")}}
+	if _, ok, err := mu.previousSnapshot(); ok || err != nil {
+		t.Errorf("expected no snapshot without a history, got %v (%v)", ok, err)
+	}
+}
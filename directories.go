@@ -1,46 +1,358 @@
 package gomu
 
 import (
-	"os/exec"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/gomuserver/mod-utils/com"
 	"github.com/gomuserver/mod-utils/sort"
 )
 
+// DiscoverOptions configures how Discover searches target directories for libs
+type DiscoverOptions struct {
+	// Use the libs listed in a target's go.work instead of searching it, if it has one
+	UseWorkspace bool
+	// Also search each repo for nested go.mod files
+	NestedModules bool
+	// Search symlinked directories, each real directory once
+	FollowSymlinks bool
+	// Also find module folders which aren't within a git repo. Note: gomu's actions require git
+	IncludeNonGit bool
+
+	// MaxDepth is how many directories below each target are searched for libs. Defaults to 1, the target's children.
+	// Libs aren't searched for further libs, see NestedModules
+	MaxDepth int
+	// Globs matching names of directories never searched, e.g. node_modules. Hidden directories are always skipped
+	SkipPatterns []string
+
+	// Globs, or regexes prefixed with "re:", matching module or filesystem paths of libs to include (or all if none) and skip
+	IncludePatterns []string
+	ExcludePatterns []string
+}
+
+// Discover returns the libs within targets in the order found: each target's libs, then the target itself if it's a lib.
+// Libs are git repos, or directories with a go.mod within one (or anywhere if IncludeNonGit is set)
+func Discover(targets []string, opts DiscoverOptions) (libs sort.FileList, err error) {
+	for _, pattern := range opts.SkipPatterns {
+		if _, globErr := path.Match(pattern, ""); globErr != nil {
+			err = fmt.Errorf("invalid skip glob %q: %v", pattern, globErr)
+			return
+		}
+	}
+
+	maxDepth := opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = 1
+	}
+
+	var paths sort.StringArray
+	for _, target := range targets {
+		if opts.UseWorkspace {
+			if workspaceLibs, ok := getLibsInWorkspace(target); ok {
+				paths = append(paths, workspaceLibs...)
+				continue
+			}
+		}
+
+		dirLibs := discoverIn(target, target, maxDepth, opts, make(map[string]bool))
+		if isLib(target, target, opts.IncludeNonGit) {
+			// The directory itself may be a lib
+			dirLibs = append(dirLibs, filepath.Join(target))
+		}
+		paths = append(paths, dirLibs...)
+
+		if opts.NestedModules {
+			for _, lib := range dirLibs {
+				paths = append(paths, GetNestedModules(lib)...)
+			}
+		}
+	}
+
+	if len(opts.IncludePatterns) > 0 || len(opts.ExcludePatterns) > 0 {
+		if paths, err = filterLibs(paths, opts.IncludePatterns, opts.ExcludePatterns); err != nil {
+			return
+		}
+	}
+
+	// Link libs in the order found, each once
+	var head, tail *sort.FileNode
+	found := make(map[string]bool, len(paths))
+	for _, lib := range paths {
+		if found[lib] {
+			continue
+		}
+		found[lib] = true
+
+		node := &sort.FileNode{File: &com.FileWrapper{Path: lib}, Last: tail}
+		if tail == nil {
+			head = node
+		} else {
+			tail.Next = node
+		}
+		tail = node
+	}
+
+	return &head, nil
+}
+
+// discoverIn returns the libs within dir, searching directories which aren't libs until depth levels below it.
+// Root is the target being searched. Visited holds real paths of directories already found
+func discoverIn(dir, root string, depth int, opts DiscoverOptions, visited map[string]bool) (libs sort.StringArray) {
+	readDir := dir
+	if len(readDir) == 0 {
+		readDir = "."
+	}
+
+	entries, err := ioutil.ReadDir(readDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || matchesAnyGlob(opts.SkipPatterns, name) {
+			// Ignore hidden and skipped directories
+			continue
+		}
+
+		child := filepath.Join(dir, name)
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if !opts.FollowSymlinks {
+				continue
+			}
+
+			if info, err := os.Stat(child); err != nil || !info.IsDir() {
+				continue
+			}
+		} else if !entry.IsDir() {
+			continue
+		}
+
+		// Symlinks may loop, or point at a directory found another way
+		real, err := filepath.EvalSymlinks(child)
+		if err != nil || visited[real] {
+			continue
+		}
+		visited[real] = true
+
+		if isLib(child, root, opts.IncludeNonGit) {
+			libs = append(libs, child)
+		} else if depth > 1 {
+			libs = append(libs, discoverIn(child, root, depth-1, opts, visited)...)
+		}
+	}
+
+	return
+}
+
+// isLib returns true if dir is a git repo, or has a go.mod within one at or below root (or anywhere if nonGit is set)
+func isLib(dir, root string, nonGit bool) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+		return false
+	}
+
+	return nonGit || sort.InRepo(dir, root)
+}
+
+// matchesAnyGlob returns true if name matches any of globs
+func matchesAnyGlob(globs []string, name string) bool {
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 // PopulateLibsFromTargets will aggregate all libs within all target dirs
 func (mu *MU) PopulateLibsFromTargets() {
-	libs := make(sort.StringArray, 0)
-	for index := range mu.Options.TargetDirectories {
-		libs = append(libs, GetLibsInDirectory(mu.Options.TargetDirectories[index])...)
+	libs, err := Discover(mu.Options.TargetDirectories, DiscoverOptions{
+		UseWorkspace:  mu.Options.UseWorkspace,
+		NestedModules: mu.Options.NestedModules,
+		// Symlinked libs have always been found
+		FollowSymlinks: true,
+
+		MaxDepth:     mu.Options.SearchDepth,
+		SkipPatterns: mu.Options.SkipDirs,
+
+		IncludePatterns: mu.Options.IncludePatterns,
+		ExcludePatterns: mu.Options.ExcludePatterns,
+	})
+	if err != nil {
+		// Don't act on unintended libs
+		com.Println("Unable to search libs:", err)
+		mu.configError(err)
+		mu.AllDirectories = sort.StringArray{}
+		return
+	}
+
+	mu.AllDirectories = (*libs).Paths()
+	return
+}
+
+// libPattern matches module or filesystem paths with a glob, or a regex if prefixed with "re:"
+type libPattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+func compilePatterns(patterns []string) (compiled []libPattern, err error) {
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "re:") {
+			regex, regexErr := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+			if regexErr != nil {
+				err = fmt.Errorf("invalid regex %q: %v", pattern, regexErr)
+				return
+			}
+
+			compiled = append(compiled, libPattern{regex: regex})
+			continue
+		}
+
+		if _, globErr := path.Match(pattern, ""); globErr != nil {
+			err = fmt.Errorf("invalid glob %q: %v", pattern, globErr)
+			return
+		}
+
+		compiled = append(compiled, libPattern{glob: pattern})
+	}
+
+	return
+}
+
+// matches returns true if any of values match the pattern
+func (pattern libPattern) matches(values ...string) bool {
+	for _, value := range values {
+		if pattern.regex != nil {
+			if pattern.regex.MatchString(value) {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern.glob, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterLibs returns libs whose module path or filesystem path matches any include pattern (or all if none) and no exclude pattern
+func filterLibs(libs sort.StringArray, includes, excludes []string) (filtered sort.StringArray, err error) {
+	includePatterns, err := compilePatterns(includes)
+	if err != nil {
+		return
+	}
+
+	excludePatterns, err := compilePatterns(excludes)
+	if err != nil {
+		return
+	}
+
+	filtered = make(sort.StringArray, 0, len(libs))
+	for _, lib := range libs {
+		file := com.FileWrapper{Path: lib}
+		values := []string{file.GetGoURL(), lib, filepath.ToSlash(filepath.Clean(lib))}
+
+		included := len(includePatterns) == 0
+		for _, pattern := range includePatterns {
+			if included = pattern.matches(values...); included {
+				break
+			}
+		}
+
+		for _, pattern := range excludePatterns {
+			if included && pattern.matches(values...) {
+				included = false
+			}
+		}
+
+		if included {
+			filtered = append(filtered, lib)
+		}
 	}
 
-	mu.AllDirectories = libs
 	return
 }
 
 // GetLibsInDirectory returns all libs a given directory
 func GetLibsInDirectory(dir string) (libs sort.StringArray) {
-	cmd := exec.Command("ls")
-	if len(dir) > 0 {
-		cmd.Dir = dir
+	readDir := dir
+	if len(readDir) == 0 {
+		readDir = "."
 	}
-	stdout, err := cmd.Output()
 
+	entries, err := ioutil.ReadDir(readDir)
 	if err != nil {
 		return
 	}
 
-	// Parse files from exec "ls"
-	libs = strings.Split(string(stdout), "\n")
-	for index := range libs {
-		switch libs[index] {
-		case ".", "..", dir:
-			// Ignore non-repositories
-		default:
-			libs[index] = path.Join(dir, libs[index])
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			// Ignore hidden files
+			continue
 		}
+
+		libs = append(libs, filepath.Join(dir, entry.Name()))
+	}
+
+	// The directory itself may be a lib
+	libs = append(libs, filepath.Join(dir))
+	return
+}
+
+// getLibsInWorkspace returns the libs listed in dir/go.work, or false if dir has no workspace
+func getLibsInWorkspace(dir string) (libs sort.StringArray, ok bool) {
+	workPath := filepath.Join(dir, WorkspaceName)
+	if _, err := os.Stat(workPath); err != nil {
+		return
+	}
+
+	libs, err := ParseWorkspace(workPath)
+	if err != nil {
+		com.Println("Unable to parse", workPath, "- searching directory instead")
+		return
+	}
+
+	return libs, true
+}
+
+// GetNestedModules returns directories within repo containing their own go.mod, e.g. repo/services/a.
+// Vendor, testdata, hidden and underscore-prefixed directories are ignored, as by the go command
+func GetNestedModules(repo string) (libs sort.StringArray) {
+	if len(repo) == 0 {
+		return
 	}
 
+	filepath.Walk(repo, func(dir string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			// Ignore unreadable files
+			return nil
+		}
+
+		if dir == repo {
+			return nil
+		}
+
+		name := info.Name()
+		if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			libs = append(libs, dir)
+		}
+
+		return nil
+	})
+
 	return
 }
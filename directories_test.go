@@ -0,0 +1,107 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestFilterLibs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Module paths are found from the libs' directories within go/src
+	var libs sort.StringArray
+	for _, module := range []string{"github.com/org/api", "github.com/org/api-legacy", "github.com/org/web", "gitlab.com/other/api"} {
+		lib := filepath.Join(dir, "go", "src", filepath.FromSlash(module))
+		if err = os.MkdirAll(lib, 0755); err != nil {
+			t.Fatal(err)
+		}
+		libs = append(libs, lib)
+	}
+
+	for _, test := range []struct {
+		includes, excludes []string
+		expected           sort.StringArray
+	}{
+		{nil, nil, libs},
+		{[]string{"github.com/org/*"}, nil, libs[:3]},
+		{[]string{"github.com/org/*"}, []string{"github.com/org/*-legacy"}, sort.StringArray{libs[0], libs[2]}},
+		{[]string{"re:/api$"}, nil, sort.StringArray{libs[0], libs[3]}},
+		{nil, []string{"re:^github\\.com/"}, libs[3:]},
+		{[]string{filepath.ToSlash(dir) + "/go/src/*/*/web"}, nil, libs[2:3]},
+	} {
+		filtered, err := filterLibs(libs, test.includes, test.excludes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(filtered, test.expected) {
+			t.Errorf("expected %q including %q and excluding %q, got %q", test.expected, test.includes, test.excludes, filtered)
+		}
+	}
+}
+
+func TestFilterLibsInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"[", "re:("} {
+		if _, err := filterLibs(sort.StringArray{"/libs/a"}, []string{pattern}, nil); err == nil {
+			t.Errorf("expected invalid include %q to fail", pattern)
+		}
+		if _, err := filterLibs(sort.StringArray{"/libs/a"}, nil, []string{pattern}); err == nil {
+			t.Errorf("expected invalid exclude %q to fail", pattern)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-discover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a/.git", "b/go.mod", "group/c/.git", "node_modules/d/.git", ".hidden/e/.git", "repo/.git", "repo/sub/go.mod"} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Found as a once already
+	if err = os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link")); err != nil {
+		t.Skip("symlinks unsupported:", err)
+	}
+
+	lib := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	for _, test := range []struct {
+		name     string
+		opts     DiscoverOptions
+		expected sort.StringArray
+	}{
+		{"default", DiscoverOptions{FollowSymlinks: true}, sort.StringArray{lib("a"), lib("repo")}},
+		{"deeper", DiscoverOptions{MaxDepth: 2, SkipPatterns: []string{"node_*"}}, sort.StringArray{lib("a"), lib("group/c"), lib("repo")}},
+		{"non git", DiscoverOptions{IncludeNonGit: true}, sort.StringArray{lib("a"), lib("b"), lib("repo")}},
+		{"nested", DiscoverOptions{NestedModules: true}, sort.StringArray{lib("a"), lib("repo"), lib("repo/sub")}},
+		{"excluded", DiscoverOptions{ExcludePatterns: []string{"re:/repo$"}}, sort.StringArray{lib("a")}},
+	} {
+		libs, err := Discover([]string{dir}, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paths := (*libs).Paths(); !reflect.DeepEqual(paths, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, paths)
+		}
+	}
+
+	if _, err = Discover([]string{dir}, DiscoverOptions{SkipPatterns: []string{"["}}); err == nil {
+		t.Error("expected invalid skip glob to fail")
+	}
+}
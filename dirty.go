@@ -0,0 +1,95 @@
+package gomu
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// How local changes of libs are handled before acting on them
+const (
+	// DirtyStash stashes local changes, restoring them once finished
+	DirtyStash = "stash"
+	// DirtySkip leaves libs with local changes out of the run
+	DirtySkip = "skip"
+	// DirtyFail aborts the run if any lib has local changes
+	DirtyFail = "fail"
+	// DirtyWIPCommit commits local changes to a WIPBranchPrefix branch, which is left for the user
+	DirtyWIPCommit = "wip-commit"
+)
+
+// WIPBranchPrefix prefixes branches local changes are committed to by the wip-commit dirty policy
+const WIPBranchPrefix = "gomu-wip/"
+
+// hideLocalChanges applies the dirty policy to libs so local changes don't interfere with searching/syncing,
+// returning the libs to act on. Returns false if the run should stop
+func (mu *MU) hideLocalChanges(libs sort.StringArray) (kept sort.StringArray, ok bool) {
+	if mu.Options.DirtyPolicy == "" || mu.Options.DirtyPolicy == DirtyStash {
+		var f com.FileWrapper
+		for _, lib := range libs {
+			f.Path = lib
+			f.Stash()
+		}
+
+		return libs, true
+	}
+
+	// One branch name per run, so a run's changes are found together
+	branch := WIPBranchPrefix + strconv.FormatInt(time.Now().Unix(), 10)
+
+	var dirty sort.StringArray
+	for _, lib := range libs {
+		f := com.FileWrapper{Path: lib}
+		if !f.HasChangesIn(".") {
+			kept = append(kept, lib)
+			continue
+		}
+
+		dirty = append(dirty, lib)
+		switch mu.Options.DirtyPolicy {
+		case DirtySkip:
+			f.Output("Has local changes. Skipping.")
+		case DirtyWIPCommit:
+			if err := f.CommitWIP(branch, "gomu: work in progress"); err != nil {
+				// Don't act on a lib whose changes may be lost
+				f.Output("Unable to commit local changes, skipping: " + err.Error())
+				continue
+			}
+
+			f.Output("Local changes committed to " + branch)
+			mu.wipBranches = append(mu.wipBranches, lib+" "+branch)
+			kept = append(kept, lib)
+		}
+	}
+
+	if mu.Options.DirtyPolicy == DirtyFail && len(dirty) > 0 {
+		com.Println("\nLocal changes found in", len(dirty), "lib(s):")
+		for _, lib := range dirty {
+			com.Println(" ", lib)
+		}
+
+		mu.Errors = append(mu.Errors, fmt.Errorf("%d lib(s) have local changes", len(dirty)))
+		return
+	}
+
+	if mu.Options.DirtyPolicy == DirtySkip && len(dirty) > 0 {
+		com.Println("\nSkipped", len(dirty), "lib(s) with local changes")
+	}
+
+	return kept, true
+}
+
+// printWIPBranches lists branches local changes were committed to, which are left for the user to restore
+func (mu *MU) printWIPBranches() {
+	if len(mu.wipBranches) == 0 {
+		return
+	}
+
+	com.Println("\nLocal changes were committed to work in progress branches:")
+	for _, line := range mu.wipBranches {
+		com.Println(" ", line)
+	}
+}
@@ -0,0 +1,109 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// dirtyLibrary returns a lib whose git status reports local changes, with commands recorded through the global runner
+func dirtyLibrary(t *testing.T) (lib string, recorder *com.Recorder) {
+	l, recorder := recordedLibrary(t)
+	recorder.Reply("git status --porcelain", com.Reply{Stdout: " M main.go\n"})
+	com.SetRunner(recorder)
+	com.SetConsoleOutput(ioutil.Discard)
+	t.Cleanup(func() {
+		com.SetRunner(nil)
+		com.SetConsoleOutput(nil)
+	})
+
+	return l.File.Path, recorder
+}
+
+func TestDirtyStash(t *testing.T) {
+	lib, recorder := dirtyLibrary(t)
+
+	mu := &MU{}
+	kept, ok := mu.hideLocalChanges(sort.StringArray{lib})
+	if !ok || len(kept) != 1 {
+		t.Fatalf("expected lib to be kept by default, got %v", kept)
+	}
+	if !recorder.Ran("git stash") {
+		t.Errorf("expected local changes to be stashed, got %v", commandLines(recorder))
+	}
+}
+
+func TestDirtySkip(t *testing.T) {
+	lib, recorder := dirtyLibrary(t)
+
+	mu := &MU{Options: Options{DirtyPolicy: DirtySkip}}
+	kept, ok := mu.hideLocalChanges(sort.StringArray{lib})
+	if !ok || len(kept) > 0 {
+		t.Errorf("expected lib with local changes to be skipped, got %v", kept)
+	}
+	if recorder.Ran("git stash") || recorder.Ran("git checkout") {
+		t.Errorf("expected local changes to be left alone, got %v", commandLines(recorder))
+	}
+
+	recorder.Reply("git status --porcelain", com.Reply{})
+	if kept, ok = mu.hideLocalChanges(sort.StringArray{lib}); !ok || len(kept) != 1 {
+		t.Errorf("expected clean lib to be kept, got %v", kept)
+	}
+}
+
+func TestDirtyFail(t *testing.T) {
+	lib, _ := dirtyLibrary(t)
+
+	mu := &MU{Options: Options{DirtyPolicy: DirtyFail}}
+	if _, ok := mu.hideLocalChanges(sort.StringArray{lib}); ok {
+		t.Error("expected run to stop on local changes")
+	}
+	if len(mu.Errors) != 1 || !strings.Contains(mu.Errors[0].Error(), "1 lib(s) have local changes") {
+		t.Errorf("expected an error counting dirty libs, got %v", mu.Errors)
+	}
+}
+
+func TestDirtyWIPCommit(t *testing.T) {
+	lib, recorder := dirtyLibrary(t)
+
+	mu := &MU{Options: Options{DirtyPolicy: DirtyWIPCommit}}
+	kept, ok := mu.hideLocalChanges(sort.StringArray{lib})
+	if !ok || len(kept) != 1 {
+		t.Fatalf("expected lib to be kept once its changes are committed, got %v", kept)
+	}
+
+	lines := commandLines(recorder)
+	expected := []string{"git checkout -b " + WIPBranchPrefix, "git add -A", "git commit --no-verify -m gomu: work in progress", "git checkout -"}
+	if len(lines) != len(expected)+1 {
+		t.Fatalf("expected status then %v, got %v", expected, lines)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(lines[i+1], prefix) {
+			t.Errorf("expected %q, got %q", prefix, lines[i+1])
+		}
+	}
+
+	if len(mu.wipBranches) != 1 || !strings.HasPrefix(mu.wipBranches[0], lib+" "+WIPBranchPrefix) {
+		t.Errorf("expected the wip branch to be listed for the lib, got %v", mu.wipBranches)
+	}
+}
+
+func TestDirtyWIPCommitFails(t *testing.T) {
+	lib, recorder := dirtyLibrary(t)
+	recorder.Reply("git commit", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{DirtyPolicy: DirtyWIPCommit}}
+	kept, ok := mu.hideLocalChanges(sort.StringArray{lib})
+	if !ok || len(kept) > 0 {
+		t.Errorf("expected lib to be skipped when its changes can't be committed, got %v", kept)
+	}
+	if !recorder.Ran("git branch -D " + WIPBranchPrefix) {
+		t.Errorf("expected the wip branch to be deleted, got %v", commandLines(recorder))
+	}
+	if len(mu.wipBranches) > 0 {
+		t.Errorf("expected no wip branch to be listed, got %v", mu.wipBranches)
+	}
+}
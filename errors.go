@@ -0,0 +1,111 @@
+package gomu
+
+import (
+	"errors"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// Steps of a sync errors are attributed to. Errors of other actions are attributed to the action
+const (
+	StepBranch      = "branch"
+	StepCommit      = "commit"
+	StepSync        = "sync"
+	StepPullRequest = "pull request"
+	StepTag         = "tag"
+)
+
+// Exit codes returned by ExitCode
+const (
+	ExitOK = 0
+	// ExitPartialFailure means the run finished but more libs failed than Options.ErrorThreshold allows
+	ExitPartialFailure = 1
+	// ExitAborted means the run stopped before finishing, e.g. the warning was declined, it was cancelled or timed
+	// out, or libs couldn't be sorted
+	ExitAborted = 2
+	// ExitConfigError means options were invalid, so nothing was performed
+	ExitConfigError = 3
+)
+
+// ErrAborted is recorded when the user declines the warning of an action
+var ErrAborted = errors.New("aborted by user")
+
+// ErrUnconfirmed is recorded when the warning of an action can't be shown for json output, and IgnoreWarning isn't set
+var ErrUnconfirmed = errors.New("warning not confirmed: output is json, set ignoreWarning to proceed")
+
+// ErrInterrupted is recorded when the run is interrupted by a signal
+var ErrInterrupted = errors.New("interrupted")
+
+// ConfigError represents invalid options, found before any lib is acted on
+type ConfigError struct {
+	Cause error
+}
+
+func (err *ConfigError) Error() string {
+	return err.Cause.Error()
+}
+
+// Unwrap returns the cause, so errors.Is and errors.As can inspect it
+func (err *ConfigError) Unwrap() error {
+	return err.Cause
+}
+
+// libraryError records cause as an error of lib at its current step, and as an error of the run
+func (mu *MU) libraryError(lib Library, cause error) {
+	err := lib.File.Fail(cause)
+
+	mu.statsMux.Lock()
+	mu.Errors = append(mu.Errors, err)
+	mu.statsMux.Unlock()
+}
+
+// configError records cause as invalid options of the run
+func (mu *MU) configError(cause error) {
+	mu.statsMux.Lock()
+	mu.Errors = append(mu.Errors, &ConfigError{Cause: cause})
+	mu.statsMux.Unlock()
+}
+
+// ExitCode returns the status a command running mu should exit with
+func (mu *MU) ExitCode() int {
+	return mu.Stats.exitCode(mu.Errors)
+}
+
+// exitCode returns ExitConfigError if any of errs is a config error, otherwise ExitAborted if any isn't attributed
+// to a lib, otherwise ExitPartialFailure if more libs failed than Options.ErrorThreshold allows
+func (stats ActionStats) exitCode(errs []error) int {
+	failed := make(map[string]bool)
+	aborted := false
+	for _, err := range errs {
+		var configErr *ConfigError
+		if errors.As(err, &configErr) {
+			return ExitConfigError
+		}
+
+		var libraryErr *com.LibraryError
+		if errors.As(err, &libraryErr) {
+			failed[libraryErr.Library] = true
+		} else {
+			aborted = true
+		}
+	}
+
+	if aborted {
+		return ExitAborted
+	}
+
+	for _, libraryErr := range stats.LibraryErrors {
+		failed[libraryErr.Library] = true
+	}
+
+	threshold := 0
+	if stats.Options != nil {
+		threshold = stats.Options.ErrorThreshold
+	}
+
+	if len(failed) > threshold {
+		return ExitPartialFailure
+	}
+
+	return ExitOK
+}
@@ -0,0 +1,63 @@
+package gomu
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestLibraryError(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	lib.File.Step = StepCommit
+
+	mu := &MU{}
+	cause := errors.New("nothing to commit")
+	mu.libraryError(*lib, cause)
+
+	var libraryErr *com.LibraryError
+	if len(mu.Errors) != 1 || !errors.As(mu.Errors[0], &libraryErr) {
+		t.Fatalf("expected a library error, got %v", mu.Errors)
+	}
+	if libraryErr.Library != lib.File.GetGoURL() || libraryErr.Step != StepCommit || !errors.Is(libraryErr, cause) {
+		t.Errorf("expected error attributed to the lib's commit, got %+v", libraryErr)
+	}
+	if len(lib.File.Failures) != 1 {
+		t.Errorf("expected error to be recorded on the lib, got %v", lib.File.Failures)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	failure := func(lib string) error { return &com.LibraryError{Library: lib, Cause: errors.New("failed")} }
+	for _, test := range []struct {
+		name      string
+		errs      []error
+		threshold int
+		expected  int
+	}{
+		{"no errors", nil, 0, ExitOK},
+		{"lib failed", []error{failure("a")}, 0, ExitPartialFailure},
+		{"within threshold", []error{failure("a"), failure("a")}, 1, ExitOK},
+		{"over threshold", []error{failure("a"), failure("b")}, 1, ExitPartialFailure},
+		{"aborted", []error{failure("a"), ErrAborted}, 5, ExitAborted},
+		{"config error", []error{ErrAborted, &ConfigError{Cause: errors.New("invalid")}}, 0, ExitConfigError},
+	} {
+		stats := ActionStats{Options: &Options{ErrorThreshold: test.threshold}}
+		if code := stats.exitCode(test.errs); code != test.expected {
+			t.Errorf("%s: expected exit code %d, got %d", test.name, test.expected, code)
+		}
+	}
+}
+
+func TestExitCodeConfigError(t *testing.T) {
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true, ErrorThreshold: -1})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	runContext(t, mu, context.Background())
+	if code := mu.ExitCode(); code != ExitConfigError || len(recorder.Commands()) > 0 {
+		t.Errorf("expected negative error threshold to exit %d before syncing, got %d and ran %v", ExitConfigError, code, recorder.Commands())
+	}
+}
@@ -0,0 +1,52 @@
+package gomu
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Environment variables exposed to commands run by the exec action
+const (
+	ExecLibPathEnv  = "GOMU_LIB_PATH"
+	ExecLibURLEnv   = "GOMU_LIB_URL"
+	ExecDepIndexEnv = "GOMU_DEP_INDEX"
+	ExecDepCountEnv = "GOMU_DEP_COUNT"
+)
+
+// exec runs the exec command in lib's directory, returning false if it failed
+func (mu *MU) exec(lib Library, index int) (ok bool) {
+	env := []string{
+		ExecLibPathEnv + "=" + lib.File.OriginalPath(),
+		ExecLibURLEnv + "=" + lib.File.GetGoURL(),
+		ExecDepIndexEnv + "=" + strconv.Itoa(index),
+		ExecDepCountEnv + "=" + strconv.Itoa(mu.Stats.DepCount),
+	}
+
+	lib.File.Output("Running `" + mu.Options.ExecCommand + "`...")
+	output, exitCode, err := lib.File.RunShell(mu.Options.ExecCommand, env...)
+	if len(output) > 0 {
+		lib.File.Output(output)
+	}
+
+	lib.File.Executed = err == nil
+	lib.File.ExitCode = exitCode
+
+	if err != nil {
+		err = fmt.Errorf("unable to run command: %v", err)
+	} else if exitCode != 0 {
+		err = fmt.Errorf("command failed with exit code %d", exitCode)
+	} else {
+		lib.File.Output("Command succeeded!")
+		mu.addStat(&mu.Stats.ExecCount, &mu.Stats.ExecOutput, lib.File.OriginalPath()+"\n")
+		return true
+	}
+
+	line := lib.File.OriginalPath()
+	if lib.File.Executed {
+		line += " (exit code " + strconv.Itoa(exitCode) + ")"
+	}
+	mu.addStat(&mu.Stats.ExecFailedCount, &mu.Stats.ExecFailedOutput, line+"\n")
+
+	mu.libraryError(lib, err)
+	return
+}
@@ -0,0 +1,58 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestExec(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{Options: Options{ExecCommand: "make test"}}
+	mu.Stats.DepCount = 3
+	if !mu.exec(*lib, 1) {
+		t.Fatal("expected command to succeed")
+	}
+
+	expected := []string{"sh -c make test"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	env := recorder.Commands()[0].Env
+	for _, variable := range []string{ExecLibURLEnv + "=" + lib.File.GetGoURL(), ExecDepIndexEnv + "=1", ExecDepCountEnv + "=3"} {
+		if !containsString(env, variable) {
+			t.Errorf("expected %s to be set, got %q", variable, env)
+		}
+	}
+
+	if !lib.File.Executed || mu.Stats.ExecCount != 1 {
+		t.Errorf("expected lib to be executed, got %d", mu.Stats.ExecCount)
+	}
+}
+
+func TestExecFails(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("sh -c", com.Reply{ExitCode: 2})
+
+	mu := &MU{Options: Options{ExecCommand: "make test"}}
+	if mu.exec(*lib, 0) {
+		t.Fatal("expected command to fail")
+	}
+
+	if lib.File.ExitCode != 2 || mu.Stats.ExecFailedCount != 1 {
+		t.Errorf("expected exit code 2 to be recorded, got %d and %d failures", lib.File.ExitCode, mu.Stats.ExecFailedCount)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
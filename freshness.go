@@ -0,0 +1,185 @@
+package gomu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// StaleDep represents an outdated module required by a library, with how far behind its latest version it is
+type StaleDep struct {
+	OutdatedDep
+
+	VersionsBehind int `json:"versionsBehind"` // Releases newer than the required version
+	DaysBehind     int `json:"daysBehind"`     // Days since the first newer release
+}
+
+// goListVersions represents a module in the output of go list -m -versions -json, or go list -m -json module@version
+type goListVersions struct {
+	Path     string     `json:"Path"`
+	Version  string     `json:"Version"`
+	Versions []string   `json:"Versions"`
+	Time     *time.Time `json:"Time"`
+}
+
+// Staleness returns outdated modules required in lib's go.mod, with how many releases and days behind they are as of
+// now. If prefixes are provided, only modules matching a prefix are checked
+func (lib *Library) Staleness(prefixes []string, now time.Time) (deps []StaleDep, err error) {
+	outdated, err := lib.Outdated(prefixes)
+	if err != nil || len(outdated) == 0 {
+		return
+	}
+
+	args := []string{lib.File.GoBinary(), "list", "-m", "-versions", "-json"}
+	for _, dep := range outdated {
+		args = append(args, dep.Module)
+	}
+
+	modules, err := lib.listModules(args)
+	if err != nil {
+		return
+	}
+
+	// Days are counted from the first release newer than the required version
+	args = []string{lib.File.GoBinary(), "list", "-m", "-json"}
+	deps = make([]StaleDep, len(outdated))
+	firstNewer := make(map[string]string, len(outdated))
+	for i, dep := range outdated {
+		deps[i].OutdatedDep = dep
+		for _, version := range modules[dep.Module].Versions {
+			if strings.Contains(version, "-") || !semverLess(dep.Version, version) {
+				// Pre-releases don't count
+				continue
+			}
+
+			if deps[i].VersionsBehind == 0 {
+				firstNewer[dep.Module] = version
+			}
+			deps[i].VersionsBehind++
+		}
+
+		if deps[i].VersionsBehind == 0 {
+			// Only a newer pre-release or pseudo-version is available
+			deps[i].VersionsBehind, firstNewer[dep.Module] = 1, dep.Latest
+		}
+
+		args = append(args, dep.Module+"@"+firstNewer[dep.Module])
+	}
+
+	if modules, err = lib.listModules(args); err != nil {
+		return
+	}
+
+	for i := range deps {
+		if released := modules[deps[i].Module].Time; released != nil && now.After(*released) {
+			deps[i].DaysBehind = int(now.Sub(*released).Hours() / 24)
+		}
+	}
+
+	return
+}
+
+// listModules runs go list -m -json with args, returning the listed modules by path
+func (lib *Library) listModules(args []string) (modules map[string]goListVersions, err error) {
+	// Queries the module proxy for versions
+	output, err := lib.File.CmdOutput(args...)
+	if err != nil {
+		err = fmt.Errorf("unable to list module versions: %v", err)
+		return
+	}
+
+	modules = make(map[string]goListVersions)
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var module goListVersions
+		if err = decoder.Decode(&module); err == io.EOF {
+			return modules, nil
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse go list output: %v", err)
+			return
+		}
+
+		modules[module.Path] = module
+	}
+}
+
+// validateFreshness returns an error unless the options set a valid freshness policy
+func (o *Options) validateFreshness() error {
+	if o.MaxVersionsBehind < 0 || o.MaxDaysBehind < 0 {
+		return fmt.Errorf("max versions and days behind can't be negative, got %d and %d", o.MaxVersionsBehind, o.MaxDaysBehind)
+	}
+
+	if o.MaxVersionsBehind == 0 && o.MaxDaysBehind == 0 {
+		return fmt.Errorf("check needs a policy, set max versions or days behind")
+	}
+
+	return nil
+}
+
+// violates returns true if dep is further behind than the policy of options allows
+func (dep StaleDep) violates(o *Options) bool {
+	return (o.MaxVersionsBehind > 0 && dep.VersionsBehind > o.MaxVersionsBehind) ||
+		(o.MaxDaysBehind > 0 && dep.DaysBehind > o.MaxDaysBehind)
+}
+
+// check fails lib if any module it requires within the module filter violates the freshness policy
+func (mu *MU) check(lib Library) {
+	lib.File.Output("Checking dependency freshness...")
+
+	deps, err := lib.Staleness(mu.Options.ModuleFilter, time.Now())
+	if err != nil {
+		lib.File.Error("Freshness check failed :( " + err.Error())
+		return
+	}
+
+	var stale []StaleDep
+	var violations []string
+	for _, dep := range deps {
+		if !dep.violates(&mu.Options) {
+			continue
+		}
+
+		stale = append(stale, dep)
+		violations = append(violations, dep.Module+" "+dep.Version+" -> "+dep.Latest)
+		lib.File.Output(dep.Module + " " + dep.Version + " is " + strconv.Itoa(dep.VersionsBehind) + " version(s) and " + strconv.Itoa(dep.DaysBehind) + " day(s) behind " + dep.Latest)
+	}
+
+	if len(stale) == 0 {
+		lib.File.Output("All deps within policy!")
+		return
+	}
+
+	mu.statsMux.Lock()
+	mu.Stats.Stale = append(mu.Stats.Stale, stale...)
+	mu.statsMux.Unlock()
+
+	mu.addStat(&mu.Stats.StaleCount, &mu.Stats.StaleOutput, lib.File.GetGoURL()+" ("+strconv.Itoa(len(stale))+" stale)\n")
+	mu.libraryError(lib, fmt.Errorf("deps behind policy: %s", strings.Join(violations, ", ")))
+}
+
+// formatStale returns a table of deps violating the freshness policy, sorted by library then module
+func (stats ActionStats) formatStale() string {
+	deps := append([]StaleDep(nil), stats.Stale...)
+	gosort.Slice(deps, func(i, j int) bool {
+		if deps[i].Library != deps[j].Library {
+			return deps[i].Library < deps[j].Library
+		}
+		return deps[i].Module < deps[j].Module
+	})
+
+	var output bytes.Buffer
+	writer := tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LIBRARY\tMODULE\tCURRENT\tLATEST\tVERSIONS\tDAYS")
+	for _, dep := range deps {
+		fmt.Fprintln(writer, dep.Library+"\t"+dep.Module+"\t"+dep.Version+"\t"+dep.Latest+"\t"+strconv.Itoa(dep.VersionsBehind)+"\t"+strconv.Itoa(dep.DaysBehind))
+	}
+	writer.Flush()
+
+	return output.String()
+}
@@ -0,0 +1,70 @@
+package gomu
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// staleLibrary returns a lib requiring github.com/org/b v1.2.3, three releases behind v1.4.0 with the first released
+// 2020-01-01
+func staleLibrary(t *testing.T) (lib *Library, recorder *com.Recorder) {
+	lib, recorder = recordedLibrary(t)
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Require": [{"Path": "github.com/org/b", "Version": "v1.2.3"}]}`})
+	recorder.Reply("go list -m -u -json", com.Reply{Stdout: `{"Path": "github.com/org/b", "Version": "v1.2.3", "Update": {"Version": "v1.4.0"}}`})
+	recorder.Reply("go list -m -versions -json", com.Reply{Stdout: `{"Path": "github.com/org/b", "Versions": ["v1.2.0", "v1.2.3", "v1.2.4", "v1.3.0", "v1.4.0-rc.1", "v1.4.0"]}`})
+	recorder.Reply("go list -m -json github.com/org/b@v1.2.4", com.Reply{Stdout: `{"Path": "github.com/org/b", "Version": "v1.2.4", "Time": "2020-01-01T00:00:00Z"}`})
+	return
+}
+
+func TestStaleness(t *testing.T) {
+	lib, _ := staleLibrary(t)
+
+	deps, err := lib.Staleness(nil, time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 || deps[0].Module != "github.com/org/b" || deps[0].VersionsBehind != 3 || deps[0].DaysBehind != 30 {
+		t.Errorf("expected b to be 3 releases and 30 days behind, got %+v", deps)
+	}
+}
+
+func TestValidateFreshness(t *testing.T) {
+	for _, test := range []struct {
+		options Options
+		valid   bool
+	}{
+		{Options{MaxVersionsBehind: 2}, true},
+		{Options{MaxDaysBehind: 90}, true},
+		{Options{}, false},
+		{Options{MaxVersionsBehind: -1, MaxDaysBehind: 90}, false},
+	} {
+		if err := test.options.validateFreshness(); (err == nil) != test.valid {
+			t.Errorf("%d versions, %d days: expected valid %v, got %v", test.options.MaxVersionsBehind, test.options.MaxDaysBehind, test.valid, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	lib, _ := staleLibrary(t)
+
+	mu := &MU{Options: Options{MaxVersionsBehind: 3}}
+	mu.check(*lib)
+	if len(mu.Errors) > 0 || mu.Stats.StaleCount > 0 {
+		t.Errorf("expected b to be within 3 versions, got %v", mu.Errors)
+	}
+
+	mu = &MU{Options: Options{MaxVersionsBehind: 2}}
+	mu.check(*lib)
+	if len(mu.Errors) != 1 || !strings.Contains(mu.Errors[0].Error(), "github.com/org/b v1.2.3 -> v1.4.0") {
+		t.Errorf("expected b to violate the policy, got %v", mu.Errors)
+	}
+	if mu.Stats.StaleCount != 1 || len(mu.Stats.Stale) != 1 {
+		t.Errorf("expected the stale dep to be counted, got %d", mu.Stats.StaleCount)
+	}
+	if table := mu.Stats.formatStale(); !strings.Contains(table, "github.com/org/b  v1.2.3   v1.4.0  3") {
+		t.Errorf("expected stale dep in the table, got\n%s", table)
+	}
+}
@@ -5,4 +5,6 @@ go 1.14
 require (
 	github.com/hatchify/closer v0.4.81
 	github.com/remeh/sizedwaitgroup v1.0.0
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
+	gopkg.in/yaml.v3 v3.0.1
 )
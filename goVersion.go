@@ -0,0 +1,233 @@
+package gomu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// goVersionPattern matches go directive versions, e.g. 1.21, 1.21.3 or 1.22rc1
+var goVersionPattern = regexp.MustCompile(`^1(\.\d+){1,2}((rc|beta)\d+)?$`)
+
+// goDirectives represents the go and toolchain directives of a go.mod
+type goDirectives struct {
+	Go        string
+	Toolchain string
+}
+
+// readGoDirectives parses the go and toolchain directives of the go.mod in dir
+func readGoDirectives(dir string) (directives goDirectives, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "go":
+			directives.Go = fields[1]
+		case "toolchain":
+			directives.Toolchain = fields[1]
+		}
+	}
+
+	return
+}
+
+// compareGoVersions returns -1, 0 or 1 if go version a is lower, equal or higher than b.
+// Release candidates precede their release (1.22rc1 < 1.22.0), and 1.22 equals 1.22.0
+func compareGoVersions(a, b string) int {
+	aParts, aPre := splitGoVersion(a)
+	bParts, bPre := splitGoVersion(b)
+	for i := range aParts {
+		switch {
+		case aParts[i] < bParts[i]:
+			return -1
+		case aParts[i] > bParts[i]:
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case len(aPre) == 0:
+		return 1
+	case len(bPre) == 0:
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// splitGoVersion returns the numbered parts of a go version and its prerelease suffix (1.22rc1 -> [1 22 0], rc1)
+func splitGoVersion(version string) (parts [3]int, prerelease string) {
+	version = strings.TrimPrefix(version, "go")
+	if index := strings.IndexAny(version, "abcdefghijklmnopqrstuvwxyz"); index >= 0 {
+		version, prerelease = version[:index], version[index:]
+	}
+
+	for i, comp := range strings.SplitN(version, ".", 3) {
+		parts[i], _ = strconv.Atoi(comp)
+	}
+
+	return
+}
+
+// orNone returns directive, or "none" if it's not set
+func orNone(directive string) string {
+	if len(directive) == 0 {
+		return "none"
+	}
+
+	return directive
+}
+
+// requiredGoVersion returns the highest go version of lib's deps earlier in the chain, set this run or read from go.mod
+func (mu *MU) requiredGoVersion(lib Library, fileHead *sort.FileNode) (version, dep string) {
+	for itr := fileHead; itr != nil && itr.File != lib.File; itr = itr.Next {
+		if !lib.File.DirectlyImports(itr.File) {
+			continue
+		}
+
+		depVersion, ok := mu.goVersions[itr.File.GetGoURL()]
+		if !ok {
+			directives, err := readGoDirectives(itr.File.Path)
+			if err != nil {
+				continue
+			}
+			depVersion = directives.Go
+		}
+
+		if len(version) == 0 || compareGoVersions(depVersion, version) > 0 {
+			version, dep = depVersion, itr.File.GetGoURL()
+		}
+	}
+
+	return
+}
+
+// goVersion sets lib's go directive to the configured version, raised to the highest of its deps so it never
+// requires a lower version than them, and sets its toolchain directive. Directives are never lowered
+func (mu *MU) goVersion(lib Library, fileHead *sort.FileNode) {
+	lib.File.Output("Checking go directive...")
+
+	directives, err := readGoDirectives(lib.File.Path)
+	if err != nil {
+		lib.File.Output("No mod file found. Skipping.")
+		return
+	}
+
+	target := directives.Go
+	if len(mu.Options.GoVersion) > 0 && compareGoVersions(mu.Options.GoVersion, target) > 0 {
+		target = mu.Options.GoVersion
+	}
+
+	if required, dep := mu.requiredGoVersion(lib, fileHead); len(required) > 0 && compareGoVersions(required, target) > 0 {
+		lib.File.Output("Raising to go " + required + " required by " + dep)
+		target = required
+	}
+
+	toolchain := directives.Toolchain
+	if len(mu.Options.Toolchain) > 0 && mu.Options.Toolchain != toolchain {
+		if compareGoVersions(mu.Options.Toolchain, target) < 0 {
+			lib.File.Output("Toolchain " + mu.Options.Toolchain + " is older than go " + target + ", leaving toolchain as is.")
+		} else {
+			toolchain = mu.Options.Toolchain
+		}
+	}
+
+	mu.libraryMux.Lock()
+	mu.goVersions[lib.File.GetGoURL()] = target
+	mu.libraryMux.Unlock()
+
+	if target == directives.Go && toolchain == directives.Toolchain {
+		lib.File.Output("Go directive up to date!")
+		return
+	}
+
+	if mu.Options.Commit {
+		if mu.checkoutBranch(lib) != nil {
+			// Don't make changes which can't be pushed
+			return
+		}
+	}
+
+	args := []string{lib.File.GoBinary(), "mod", "edit", "-go=" + target}
+	if toolchain != directives.Toolchain {
+		args = append(args, "-toolchain="+toolchain)
+	}
+	if err = lib.File.RunCmd(args...); err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to set go directive: %v", err))
+		return
+	}
+
+	var changes []string
+	if target != directives.Go {
+		changes = append(changes, "go "+orNone(directives.Go)+" -> "+target)
+	}
+	if toolchain != directives.Toolchain {
+		changes = append(changes, "toolchain "+orNone(directives.Toolchain)+" -> "+toolchain)
+	}
+	change := strings.Join(changes, ", ")
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+change+")\n")
+
+	if !mu.Options.Commit {
+		lib.File.Output("Go directive updated!")
+		return
+	}
+
+	commitTitle := mu.Options.CommitMessage
+	if len(commitTitle) == 0 {
+		commitTitle = "Set go " + target
+	}
+	commitTitle = "gomu: " + commitTitle
+
+	if err = mu.setCommitKey(&lib); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if lib.File.Add("go.mod") != nil || lib.commit(commitTitle+"\n\n"+change) != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to commit go directive"))
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	if err = lib.File.Push(); err != nil {
+		mu.libraryError(lib, fmt.Errorf("push failed, check local changes and commit status: %v", err))
+		return
+	}
+
+	lib.File.Committed = true
+	mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, lib.File.GetGoURL()+"\n")
+	lib.File.Output("Go directive committed!")
+
+	if len(lib.File.ProtectedBranch) == 0 {
+		mu.pullRequest(lib, mu.branch(lib), commitTitle, change)
+	} else {
+		// Changes to protected branches are only reviewed through a pull request
+		mu.requestPR(lib, FallbackBranch, commitTitle, change)
+	}
+
+	mu.removeBranchIfUnused(lib)
+}
@@ -0,0 +1,74 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestCompareGoVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.21", "1.21.0", 0},
+		{"1.21.3", "1.21", 1},
+		{"1.9", "1.21", -1},
+		{"1.22rc1", "1.22.0", -1},
+		{"1.22rc2", "1.22rc1", 1},
+		{"go1.22.1", "1.22", 1},
+	} {
+		if result := compareGoVersions(test.a, test.b); result != test.expected {
+			t.Errorf("expected %d comparing %s to %s, got %d", test.expected, test.a, test.b, result)
+		}
+	}
+}
+
+func TestReadGoDirectives(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	mod := "module github.com/org/a\n\ngo 1.21 // minimum\n\ntoolchain go1.22.1\n\nrequire github.com/org/go v1.0.0\n"
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if directives, err := readGoDirectives(lib.File.Path); err != nil || directives != (goDirectives{Go: "1.21", Toolchain: "go1.22.1"}) {
+		t.Errorf("expected go 1.21 and toolchain go1.22.1, got %+v (%v)", directives, err)
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	dep, _ := recordedLibrary(t)
+	lib, recorder := recordedLibrary(t)
+	mod := "module github.com/org/a\n\ngo 1.14\n\nrequire " + dep.File.GetGoURL() + " v1.0.0\n"
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, "go.mod"), []byte(mod), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dep was raised earlier in the run
+	mu := &MU{Options: Options{Action: "go-version", GoVersion: "1.20", Toolchain: "go1.22.0"}}
+	mu.goVersions = map[string]string{dep.File.GetGoURL(): "1.21"}
+	mu.goVersion(*lib, &sort.FileNode{File: dep.File, Next: &sort.FileNode{File: lib.File}})
+
+	expected := []string{"go mod edit -go=1.21 -toolchain=go1.22.0"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+	if mu.goVersions[lib.File.GetGoURL()] != "1.21" || mu.Stats.UpdateCount != 1 {
+		t.Errorf("expected lib to be raised to its dep's go 1.21, got %v", mu.goVersions)
+	}
+}
+
+func TestGoVersionNeverLowered(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{Options: Options{Action: "go-version", GoVersion: "1.13", Toolchain: "go1.12"}}
+	mu.goVersions = make(map[string]string)
+	mu.goVersion(*lib, &sort.FileNode{File: lib.File})
+
+	if lines := commandLines(recorder); len(lines) > 0 || mu.Stats.UpdateCount > 0 {
+		t.Errorf("expected go 1.14 to be left as is, ran %q", lines)
+	}
+}
@@ -1,11 +1,15 @@
 package gomu
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"runtime"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/gomuserver/mod-utils/com"
 	"github.com/gomuserver/mod-utils/sort"
@@ -25,20 +29,181 @@ type MU struct {
 	Errors []error
 
 	closer *closer.Closer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Cancels library timeouts once finished
+	libraryCancels []context.CancelFunc
+	libraryMux     sync.Mutex
+
+	// Guards stats updated by concurrent libs
+	statsMux sync.Mutex
+
+	checkpoint *Checkpoint
+	plan       *Plan
+	operations *OperationLog
+	// Operations earlier runs left in the log without undoing them, kept ahead of this run's
+	previousOperations []Operation
+	prTemplate         *template.Template
+	progress           *com.Progress
+	hooks              map[string][]HookFunc
+	actions            map[string]ActionFunc
+	pins               Pins
+	batch              []*batchedPR
+
+	// Go directive set in each lib by go-version, keyed by go url
+	goVersions map[string]string
+	// New majors released by major, keyed by go url
+	majors map[string]majorRelease
+	// Spans and counters exported once finished, nil if not exporting telemetry
+	telemetry *telemetry
+
+	// Workflow templates synced by workflow
+	workflowTemplates []string
+	// Secrets set by secret, keyed by name
+	secrets map[string]string
+	// Highest versions of conflicting third-party modules libs are aligned to by sync, keyed by module path
+	alignments map[string]string
+
+	commitTemplate *template.Template
+	ticketPattern  *regexp.Regexp
+	grepPattern    *regexp.Regexp
+
+	// Set if the branch template uses the lib, rendered branches are keyed by go url
+	branchTemplate *template.Template
+	branchData     BranchTemplateData
+	libBranches    map[string]string
+
+	// Options libs are performed with, merged with their library options and keyed by go url
+	libOptions map[string]Options
+
+	// Moved modules migrated by migrate-module keyed by previous path, and latest versions of their new paths
+	moves        map[string]ModuleMove
+	moveVersions map[string]string
+
+	// Modules whose dependents are listed by affected
+	changed []*com.FileWrapper
+
+	// Libs whose local changes were committed by the wip-commit dirty policy, and the branch
+	wipBranches []string
+
+	// Temporary directory containing lib worktrees
+	worktreeDir string
+
+	// Runs commands of libs, the default runner if nil
+	runner com.Runner
+
+	// When the run started, recorded to history
+	started time.Time
+
+	// Signals received, the second force quits
+	interrupts int32
+	// True if the caller handles signals itself, e.g. a server cancelling each run with its context
+	signalsHandled bool
+	// Branch of each lib being worked on keyed by path, guarded by libraryMux
+	inFlight map[string]string
+	// Closed once perform returns, so cleaning waits for in-flight libs
+	performed chan struct{}
 }
 
-var closed = false
+// SetRunner sets the runner executing commands of libs while running, e.g. a com.Recorder to test actions without
+// real repos. Nil runs commands as processes
+func (mu *MU) SetRunner(runner com.Runner) {
+	mu.runner = runner
+}
 
 // Run runs gomu with configured mu.Options
 func (mu *MU) Run() {
+	mu.RunContext(context.Background())
+}
+
+// RunContext runs gomu with configured mu.Options until finished or ctx is cancelled
+func (mu *MU) RunContext(ctx context.Context) {
+	mu.started = time.Now()
+	if mu.telemetry = newTelemetry(mu.Options, mu.started); mu.telemetry != nil {
+		// Commands are traced within the lib and step they're run for
+		com.SetCommandObserver(mu.telemetry.command)
+		defer com.SetCommandObserver(nil)
+	}
+
+	if mu.runner != nil {
+		com.SetRunner(mu.runner)
+		defer com.SetRunner(nil)
+	}
+
 	// Handle closures
 	mu.closer = closer.New()
+	if mu.Options.Deadline.IsZero() {
+		mu.ctx, mu.cancel = context.WithCancel(ctx)
+	} else {
+		mu.ctx, mu.cancel = context.WithDeadline(ctx, mu.Options.Deadline)
+	}
+
+	// Close early if parent context is cancelled
+	go func() {
+		<-mu.ctx.Done()
+		mu.closer.Close(ctx.Err())
+	}()
+
+	// First interrupt finishes in-flight libs then cleans, second quits immediately
+	stopSignals := func() {}
+	if !mu.signalsHandled {
+		stopSignals = mu.handleSignals()
+	}
+
+	if mu.Options.Action == "graph" {
+		// Status lines go to stderr so the graph on stdout can be piped
+		com.SetConsoleOutput(os.Stderr)
+		defer com.SetConsoleOutput(nil)
+	}
 
 	// Go do the thing
+	mu.performed = make(chan struct{})
 	go mu.performThenClose()
 
 	// Ensure clean is called
 	mu.waitThenClean()
+	stopSignals()
+	com.CloseLibraryLogs()
+	mu.recordHistory()
+
+	if len(mu.Options.Report) > 0 {
+		if err := mu.Stats.WriteReport(mu.Options.Report, mu.Errors); err != nil {
+			mu.Errors = append(mu.Errors, fmt.Errorf("unable to write report: %v", err))
+		}
+	}
+
+	if mu.testing() && len(mu.Options.JUnitReport) > 0 {
+		if err := mu.Stats.WriteJUnit(mu.Options.JUnitReport); err != nil {
+			mu.Errors = append(mu.Errors, fmt.Errorf("unable to write junit report: %v", err))
+		}
+	}
+
+	if mu.testing() && len(mu.Options.CoverProfile) > 0 {
+		if err := mu.Stats.WriteCoverProfile(mu.Options.CoverProfile); err != nil {
+			mu.Errors = append(mu.Errors, fmt.Errorf("unable to write coverage profile: %v", err))
+		}
+	}
+
+	mu.notify()
+
+	if mu.telemetry != nil {
+		if err := mu.telemetry.export(mu.Errors); err != nil {
+			mu.Errors = append(mu.Errors, err)
+		}
+	}
+
+	if mu.Options.Output == "json" {
+		// Print machine-readable summary regardless of log level
+		output, err := mu.Stats.JSON(mu.Errors)
+		if err != nil {
+			mu.Errors = append(mu.Errors, err)
+			return
+		}
+
+		fmt.Println(output)
+	}
 }
 
 // RunThen runs gomu with configured options and then calls closure
@@ -51,62 +216,335 @@ func (mu *MU) RunThen(complete func(mu *MU)) {
 
 // WaitThenClean handles cleanup
 func (mu *MU) waitThenClean() {
-	mu.closer.Wait()
+	err := mu.closer.Wait()
+	if err == nil {
+		// Passed the deadline, if set
+		err = mu.ctx.Err()
+	}
+
+	// Stop starting libs, then let in-flight libs finish so they aren't cleaned mid-step
+	mu.cancel()
+	<-mu.performed
+
+	// Recorded once perform is done appending errors
+	if err != nil {
+		mu.Errors = append(mu.Errors, err)
+	}
+
+	if mu.interrupted() {
+		mu.Errors = append(mu.Errors, ErrInterrupted)
+	}
 
 	if len(mu.Errors) > 0 {
 		com.Println("\nEncountered error! Cleaning...")
 
+	} else if mu.inPlace() {
+		com.Println("\nFinishing up...")
 	} else {
 		com.Println("\nFinishing up. Cleaning...")
 	}
 
+	if mu.Options.Worktree {
+		// Working copies were never stashed
+		mu.removeWorktrees()
+		return
+	}
+
+	if mu.inPlace() {
+		// Nothing was stashed
+		return
+	}
+
 	cleanupStash(mu.AllDirectories)
+	mu.verifyStashes()
+	mu.printWIPBranches()
+}
+
+// testing is true if the action tests libs, so test reports are written
+func (mu *MU) testing() bool {
+	return mu.Options.Action == "test" || (mu.Options.Action == "affected" && mu.Options.AffectedTest)
+}
+
+// inPlace is true if the action inspects or cleans working copies as they are, so they're never stashed or copied
+// into worktrees. Clean and recover-stash handle leftover stashes themselves, status reports local changes,
+// watch waits for them, history never touches them and the rest only read files, so skipping stashes keeps them
+// fast and can't lose local changes
+func (mu *MU) inPlace() bool {
+	if mu.isPlugin() {
+		// Plugins handle working copies themselves
+		return true
+	}
+
+	switch mu.Options.Action {
+	case "audit", "check", "clean", "conflicts", "diff", "graph", "grep", "history", "list", "outdated", "recover-stash", "secret", "status", "watch":
+		return true
+	default:
+		return false
+	}
 }
 
 // PerformThenClose executes whatever action is set in mu.Options
 func (mu *MU) performThenClose() {
 	mu.perform()
+	defer close(mu.performed)
 
-	if !mu.closer.Close(nil) {
+	if !mu.closer.Close(nil) && !mu.interrupted() {
 		mu.Errors = append(mu.Errors, fmt.Errorf("failed to close! Check for local changes and stashes in %v", mu.Options.TargetDirectories))
 	}
 }
 
 func (mu *MU) perform() {
 	com.SetLogLevel(mu.Options.LogLevel)
+	if mu.Options.Output == "json" {
+		// Text output would corrupt json summary, so warnings need IgnoreWarning to be confirmed
+		com.SetLogLevel(com.SILENT)
+	}
+	com.SetDryRun(mu.Options.DryRun)
+	if err := com.SetLogDir(mu.Options.LogDir); err != nil {
+		mu.configError(err)
+		return
+	}
+	if mu.Options.NoCache {
+		com.SetDepCache("")
+	} else {
+		com.SetDepCache(com.DefaultDepCacheDir())
+	}
+	com.SetRetryPolicy(com.RetryPolicy{
+		Retries: mu.Options.Retries,
+		Backoff: mu.Options.RetryBackoff,
+		Budget:  mu.Options.RetryBudget,
+	})
+	if mu.Options.RateLimitWait < 0 {
+		mu.configError(fmt.Errorf("rate limit wait can't be negative, got %s", mu.Options.RateLimitWait))
+		return
+	}
+	com.SetRateLimitPolicy(com.RateLimitPolicy{
+		Interval: mu.Options.APIInterval,
+		MaxWait:  mu.Options.RateLimitWait,
+	})
+	if mu.Options.MaxConcurrency < 0 {
+		mu.configError(fmt.Errorf("max concurrency can't be negative, got %d", mu.Options.MaxConcurrency))
+		return
+	}
+	com.SetMaxConcurrency(mu.Options.MaxConcurrency)
+	if err := com.SetHostConcurrency(mu.Options.HostConcurrency); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if mu.Options.MaxDepth < 0 {
+		mu.configError(fmt.Errorf("max depth can't be negative, got %d", mu.Options.MaxDepth))
+		return
+	}
+
+	if mu.Options.SearchDepth < 0 {
+		mu.configError(fmt.Errorf("search depth can't be negative, got %d", mu.Options.SearchDepth))
+		return
+	}
+
+	if mu.Options.ErrorThreshold < 0 {
+		mu.configError(fmt.Errorf("error threshold can't be negative, got %d", mu.Options.ErrorThreshold))
+		return
+	}
+
+	if !mu.Options.DryRun {
+		// Stashes are verified restored once finished, orphans can be recovered
+		com.SetStashJournal(StashJournalName)
+	}
+
+	com.SetInteractive(!mu.Options.NonInteractive)
+	if err := com.SetPushAuth(mu.Options.PushAuth); err != nil {
+		mu.configError(err)
+		return
+	}
+	com.SetRemote(mu.Options.Remote)
+
+	if err := com.SetEnv(mu.Options.Env, mu.Options.LibraryEnv); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if err := mu.validateLibraryOptions(); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if err := com.SetGoBinary(mu.Options.GoBinary, mu.Options.GoBinaries); err != nil {
+		mu.configError(err)
+		return
+	}
+	com.SetProxy(mu.Options.Proxy)
+
+	if err := com.SetProviderHosts(mu.Options.Providers); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if err := com.SetVersioning(mu.Options.TagPrefix, mu.Options.VersionScheme); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if err := com.SetPrerelease(mu.Options.Prerelease); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	for point := range mu.Options.Hooks {
+		if err := validateHookPoint(point); err != nil {
+			mu.configError(err)
+			return
+		}
+	}
+
+	if err := mu.loadPins(); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if err := mu.resolveBranch(); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if len(mu.Options.CommitTemplate) > 0 {
+		if err := mu.loadCommitTemplate(); err != nil {
+			mu.configError(err)
+			return
+		}
+	}
+
+	switch mu.Options.OnProtected {
+	case "", OnProtectedPR, OnProtectedFail:
+	default:
+		mu.configError(fmt.Errorf("unknown on protected %q, expected %s or %s", mu.Options.OnProtected, OnProtectedPR, OnProtectedFail))
+		return
+	}
+
+	switch mu.Options.DirtyPolicy {
+	case "", DirtyStash, DirtySkip, DirtyFail, DirtyWIPCommit:
+	default:
+		mu.configError(fmt.Errorf("unknown dirty policy %q, expected %s, %s, %s or %s", mu.Options.DirtyPolicy, DirtyStash, DirtySkip, DirtyFail, DirtyWIPCommit))
+		return
+	}
 
-	if mu.Options.PullRequest {
+	switch mu.Options.OnUnverifiedTag {
+	case "", OnUnverifiedSkip, OnUnverifiedWarn:
+	default:
+		mu.configError(fmt.Errorf("unknown on unverified tag %q, expected %s or %s", mu.Options.OnUnverifiedTag, OnUnverifiedSkip, OnUnverifiedWarn))
+		return
+	}
+
+	switch mu.Options.OnCycle {
+	case "", sort.OnCycleFail, sort.OnCycleBreak:
+	default:
+		mu.configError(fmt.Errorf("unknown on cycle %q, expected %s or %s", mu.Options.OnCycle, sort.OnCycleFail, sort.OnCycleBreak))
+		return
+	}
+
+	if mu.Options.HistoryLimit < 0 {
+		mu.configError(fmt.Errorf("history limit can't be negative, got %d", mu.Options.HistoryLimit))
+		return
+	}
+
+	if mu.Options.DiffRuns < 0 {
+		mu.configError(fmt.Errorf("diff runs can't be negative, got %d", mu.Options.DiffRuns))
+		return
+	}
+
+	if err := mu.validatePlugins(); err != nil {
+		mu.configError(err)
+		return
+	}
+
+	if mu.Options.Action == "history" {
+		// Past runs are read from the history file, no libs are needed
+		mu.history()
+		return
+	}
+
+	if mu.Options.Action == "init" {
+		// The module is created, no libs are searched
+		mu.initModule()
+		return
+	}
+
+	if mu.Options.DryRun {
+		com.Println("\nDry run: commands will be printed, not executed")
+	}
+
+	if mu.anyLibraryOptions(func(options Options) bool { return options.PullRequest }) && !mu.Options.DryRun {
 		authObject, err := com.LoadAuth()
-		if err != nil || len(authObject.User) == 0 || len(authObject.Token) == 0 {
+		if err != nil {
 			com.Println("")
 			com.Println("gomu :: I needs credentials for Pull Requests...")
-			if authObject.Setup() != nil {
+			if err = authObject.Setup(); err != nil {
 				com.Println("Error saving :(")
-				err = fmt.Errorf("Unable to parse github username and token")
+				mu.configError(fmt.Errorf("credentials for pull requests: %v", err))
 				return
 			}
-			err = nil
 			com.Println("Saved Credentials!")
 		}
 	}
 
-	if len(mu.Options.TargetDirectories) > 0 {
+	if len(mu.Options.ManifestPath) > 0 {
+		com.Println("\nReading", mu.Options.ManifestPath, "for git repositories...")
+	} else if len(mu.Options.TargetDirectories) > 0 {
 		com.Println("\nSearching", mu.Options.TargetDirectories, "for git repositories...")
 	} else {
 		com.Println("\nSearching for git repositories in current directory...")
 	}
 
+	if mu.Options.Action == "apply" {
+		// Sync exactly what was planned
+		if !mu.loadPlan() {
+			return
+		}
+	}
+
 	// Get all libs within target dirs
-	mu.PopulateLibsFromTargets()
+	if mu.plan != nil {
+		mu.AllDirectories = make(sort.StringArray, 0, len(mu.plan.Libraries))
+		for _, planned := range mu.plan.Libraries {
+			mu.AllDirectories = append(mu.AllDirectories, planned.Path)
+		}
+	} else if len(mu.Options.ManifestPath) > 0 {
+		if !mu.populateLibsFromManifest() {
+			return
+		}
+	} else {
+		mu.PopulateLibsFromTargets()
+	}
+
+	if mu.Options.CloneMissing && mu.plan == nil {
+		// Missing deps join the sync chain
+		var ok bool
+		if mu.AllDirectories, ok = mu.cloneMissingDeps(mu.AllDirectories); !ok {
+			return
+		}
+	}
+
+	if mu.plan == nil {
+		// Libs opting out of the action aren't synced, as if they hadn't been found
+		mu.AllDirectories = mu.removeIgnored(mu.AllDirectories)
+	}
+	mu.AllDirectories = mu.removeOtherKinds(mu.AllDirectories)
 	libs := mu.AllDirectories
 
 	com.Println("\nFound", len(libs)+1, "file(s). Scanning for dependencies...")
 
-	var f com.FileWrapper
-	for _, lib := range libs {
-		f.Path = lib
+	if mu.Options.VerifySums && !mu.verifySums(libs) {
+		// Don't sync from a corrupted module cache
+		return
+	}
+
+	if !mu.Options.Worktree && !mu.inPlace() {
 		// Hide local changes to prevent interference with searching/syncing
-		f.Stash()
+		var ok bool
+		if libs, ok = mu.hideLocalChanges(libs); !ok {
+			return
+		}
+		mu.AllDirectories = libs
 	}
 
 	branch := mu.Options.Branch
@@ -114,27 +552,144 @@ func (mu *MU) perform() {
 		branch = "\"current\""
 	}
 
+	if mu.Options.Action == "affected" && !mu.resolveChanged() {
+		// Nothing changed, or nothing to compare
+		return
+	}
+
 	// Sort libs
 	var fileHead *sort.FileNode
+	var err error
 	if mu.Options.DirectImport {
 		// Only check files in go.mod
-		fileHead, mu.Stats.DepCount = libs.SortedDirectDeps(mu.Options.FilterDependencies)
+		fileHead, mu.Stats.DepCount, err = libs.SortedDirectDeps(mu.Options.FilterDependencies, mu.Options.OnCycle)
 	} else {
 		// Check all files in go.sum
-		fileHead, mu.Stats.DepCount = libs.SortedRecursiveDeps(mu.Options.FilterDependencies)
+		fileHead, mu.Stats.DepCount, err = libs.SortedRecursiveDeps(mu.Options.FilterDependencies, mu.Options.OnCycle, mu.Options.MaxDepth)
+	}
+
+	if err != nil {
+		// Order is undefined, acting on it could sync libs before their deps
+		com.Println("\nUnable to sort libs:", err)
+		mu.Errors = append(mu.Errors, err)
+		return
 	}
 
-	if len(mu.Options.FilterDependencies) == 0 || len(mu.Options.FilterDependencies) == 0 {
+	// Collect results once finished, even if interrupted
+	mu.SortedLibraries = &fileHead
+	defer mu.Stats.collectResults(fileHead)
+	defer mu.cancelLibraries()
+
+	if mu.Options.Progress && mu.Options.Action != "list" {
+		mu.progress = com.NewProgress(mu.Stats.DepCount, com.IsTerminal())
+		com.SetProgress(mu.progress)
+		defer com.SetProgress(nil)
+	}
+
+	if len(mu.Options.FilterDependencies) == 0 {
 		com.Println("\nPerforming", mu.Options.Action, "on "+branch+" branch for", mu.Stats.DepCount, "lib(s)")
 	} else {
 		com.Println("\nPerforming", mu.Options.Action, "on "+branch+" branch for", mu.Stats.DepCount, "lib(s) depending on", mu.Options.FilterDependencies)
 	}
 
+	if mu.Options.Action != "migrate-module" && mu.Options.Action != "graph" {
+		// Suggest migrating libs still requiring modules which moved
+		mu.noticeMoves(fileHead)
+	}
+
+	if mu.isPlugin() {
+		// Extends the actions below, performed on every sorted lib at once
+		mu.runPlugin(fileHead)
+		return
+	}
+
 	// TODO: Also add check to warn/confirm before pushing? It'd be nice to have a chance to backout both before and after changes took place
-	// Eventual "undo" action possibly?
+	// Changes can be backed out after the fact with the "undo" action
 	// TODO: Move warning checks to client instead of utils lib, handle differently in plugin vs cli. Slack approval like release train?
 	switch mu.Options.Action {
+	case "graph":
+		// Nothing to perform per lib, just render
+		mu.graph(fileHead)
+		return
+	case "diff":
+		// Nothing to perform per lib, just compare with the previous snapshot
+		mu.diff(fileHead)
+		return
+	case "conflicts":
+		// Nothing to perform per lib, just compare mod files
+		mu.conflicts(fileHead)
+		return
+	case "undo":
+		// Operations are read from the log, not the sorted libs
+		mu.undo()
+		return
+	case "recover-stash":
+		// Stashes are read from the journal, not the sorted libs
+		mu.recoverStashes()
+		return
+	case "plan":
+		// Nothing is changed, just compute and save
+		mu.planSync(fileHead)
+		return
+	case "watch":
+		// Each change runs the watched action separately
+		mu.watch(fileHead)
+		return
+	case "check":
+		if err := mu.Options.validateFreshness(); err != nil {
+			mu.configError(err)
+			return
+		}
+	case "secret":
+		if err := mu.loadSecrets(); err != nil {
+			mu.configError(err)
+			return
+		}
+
+		if !mu.confirm("Secret", []string{"- set actions secrets " + strings.Join(mu.secretNames(), ", ") + " in each repo"}) {
+			return
+		}
+	case "workflow":
+		var err error
+		if mu.workflowTemplates, err = workflowTemplates(mu.Options.SourcePath); err != nil {
+			mu.configError(err)
+			return
+		}
+
+		if mu.Options.ListOnly {
+			break
+		}
+
+		warningActions := mu.Options.branchActions()
+		warningActions = append(warningActions, "- add or update "+strconv.Itoa(len(mu.workflowTemplates))+" workflow(s) from "+mu.Options.SourcePath)
+		warningActions = append(warningActions, "- commit and push workflow changes")
+		if mu.Options.PullRequest {
+			warningActions = append(warningActions, "- open pull requests")
+		}
+
+		if !mu.confirm("Workflow", warningActions) {
+			return
+		}
+	case "grep":
+		if err := mu.loadGrepPattern(); err != nil {
+			mu.configError(err)
+			return
+		}
+	case "exec":
+		if len(strings.TrimSpace(mu.Options.ExecCommand)) == 0 {
+			mu.configError(fmt.Errorf("no command provided to exec"))
+			return
+		}
 	case "sync":
+		if mu.plan != nil && !mu.verifyPlan(fileHead) {
+			return
+		}
+
+		if mu.Options.Align {
+			// Resolved before syncing, as each lib raises the versions it requires
+			mu.alignments = alignments(findConflicts(fileHead))
+		}
+
 		warningLibs := make([]string, mu.Stats.DepCount)
 		count := 0
 		for itr := fileHead; itr != nil; itr = itr.Next {
@@ -144,42 +699,166 @@ func (mu *MU) perform() {
 
 		com.Println(strings.Join(warningLibs, "\n"))
 
-		warningActions := []string{"Sync action will:"}
-		if mu.Options.Branch != "" {
-			warningActions = append(warningActions, "- checkout (or create) branch "+mu.Options.Branch)
+		warningActions := mu.Options.syncActions(strconv.Itoa(len(mu.alignments)) + " third-party module(s)")
+		if !mu.confirm("Sync", warningActions) {
+			return
+		}
+
+		mu.loadCheckpoint()
+	case "promote":
+		var warningActions []string
+		if len(mu.Options.Prerelease) > 0 {
+			warningActions = append(warningActions, "- tag the latest "+mu.Options.Prerelease+" prerelease of each lib as a stable release")
+		} else {
+			warningActions = append(warningActions, "- tag the latest prerelease of each lib as a stable release")
 		}
-		warningActions = append(warningActions, "- update mod files")
-		if mu.Options.Commit {
-			warningActions = append(warningActions, "- commit local changes (if any)")
+		warningActions = append(warningActions, "- commit promoted deps before tagging dependents")
+		if mu.Options.SignTags {
+			warningActions = append(warningActions, "- sign tags")
+		}
+
+		if !mu.confirm("Promote", warningActions) {
+			return
 		}
+	case "go-version":
+		if len(mu.Options.GoVersion) == 0 && len(mu.Options.Toolchain) == 0 {
+			mu.configError(fmt.Errorf("no go version or toolchain provided"))
+			return
+		} else if len(mu.Options.GoVersion) > 0 && !goVersionPattern.MatchString(mu.Options.GoVersion) {
+			mu.configError(fmt.Errorf("invalid go version %q, expected e.g. 1.22 or 1.22.3", mu.Options.GoVersion))
+			return
+		} else if len(mu.Options.Toolchain) > 0 && !goVersionPattern.MatchString(strings.TrimPrefix(mu.Options.Toolchain, "go")) {
+			mu.configError(fmt.Errorf("invalid toolchain %q, expected e.g. go1.22.3", mu.Options.Toolchain))
+			return
+		}
+
+		mu.goVersions = make(map[string]string)
+		if !mu.Options.Commit {
+			break
+		}
+
+		warningActions := mu.Options.branchActions()
+		if len(mu.Options.GoVersion) > 0 {
+			warningActions = append(warningActions, "- raise go directives to "+mu.Options.GoVersion+", or the highest go directive of their deps")
+		}
+		if len(mu.Options.Toolchain) > 0 {
+			warningActions = append(warningActions, "- set toolchain directives to "+mu.Options.Toolchain)
+		}
+		warningActions = append(warningActions, "- commit and push go.mod changes")
 		if mu.Options.PullRequest {
 			warningActions = append(warningActions, "- open pull request for changes (if any)")
 		}
+
+		if !mu.confirm("Go version", warningActions) {
+			return
+		}
+	case "major":
+		if len(mu.Options.VersionScheme) > 0 && mu.Options.VersionScheme != com.SchemeSemver {
+			mu.configError(fmt.Errorf("major versions require semver, got version scheme %q", mu.Options.VersionScheme))
+			return
+		}
+		if err := validateMajorLibs(mu.Options.FilterDependencies); err != nil {
+			mu.configError(err)
+			return
+		}
+
+		mu.majors = make(map[string]majorRelease)
+		if !mu.Options.Commit {
+			break
+		}
+
+		warningActions := mu.Options.branchActions()
+		warningActions = append(warningActions, "- move module paths and imports of "+strings.Join(mu.Options.FilterDependencies, ", ")+" to their new major")
+		warningActions = append(warningActions, "- tag the new majors")
+		warningActions = append(warningActions, "- move imports and requires of dependents to the new majors")
 		if mu.Options.Tag {
-			if len(mu.Options.SetVersion) > 0 {
-				warningActions = append(warningActions, "- tag all dependencies "+mu.Options.SetVersion)
-			} else {
-				warningActions = append(warningActions, "- increment tag version (if updated)")
-			}
+			warningActions = append(warningActions, "- increment tag version of updated dependents, requiring them in theirs")
+		}
+		warningActions = append(warningActions, "- commit and push changes")
+		if mu.Options.PullRequest {
+			warningActions = append(warningActions, "- open pull request for changes (if any)")
 		}
 
-		com.Println("\n" + strings.Join(warningActions, "\n  "))
+		if !mu.confirm("Major", warningActions) {
+			return
+		}
+	case "migrate-module":
+		if !mu.findMoves(fileHead) {
+			com.Println("\nNo moved modules found")
+			return
+		}
 
-		if !mu.Options.IgnoreWarning && !ShowWarning("\nIs this ok?") {
-			cleanupStash(libs)
-			os.Exit(-1)
+		com.Println("\nMoved modules:\n" + mu.Stats.MovedOutput)
+		if !mu.Options.Commit {
+			break
+		}
+
+		warningActions := mu.Options.branchActions()
+		warningActions = append(warningActions, "- move imports and requires of "+strconv.Itoa(mu.Stats.MovedCount)+" moved module(s) to their new paths")
+		if mu.Options.Tag {
+			warningActions = append(warningActions, "- increment tag version of updated libs, requiring them in their dependents")
+		}
+		warningActions = append(warningActions, "- commit and push changes")
+		if mu.Options.PullRequest {
+			warningActions = append(warningActions, "- open pull request for changes (if any)")
+		}
+
+		if !mu.confirm("Migrate module", warningActions) {
+			return
+		}
+	case "tidy":
+		if !mu.Options.Commit || mu.Options.VerifyTidy {
+			// Nothing is pushed
+			break
+		}
+
+		warningActions := mu.Options.branchActions()
+		warningActions = append(warningActions, "- run go mod tidy")
+		warningActions = append(warningActions, "- commit and push mod file changes")
+		if mu.Options.PullRequest {
+			warningActions = append(warningActions, "- open pull request for changes (if any)")
+		}
+
+		if !mu.confirm("Tidy", warningActions) {
+			return
 		}
 	default:
 		// No worries
 	}
 
+	if mu.Options.Worktree && !mu.Options.DryRun && !mu.inPlace() {
+		// Nothing is changed in dry run, use working copies as is
+		if !mu.createWorktrees(fileHead) {
+			return
+		}
+	}
+
+	if mu.Options.Action == "test" && mu.Options.ParallelTest {
+		// Test independent libs concurrently, one dependency level at a time
+		mu.testLevels(fileHead)
+		mu.printNames(fileHead)
+		return
+	}
+
+	if mu.Options.Action == "sync" && mu.Options.ParallelSync {
+		// Sync independent libs concurrently, one dependency level at a time
+		finished := mu.syncLevels(fileHead)
+		mu.openBatchPRs(fileHead)
+		if finished {
+			mu.clearCheckpoint()
+		}
+
+		mu.printNames(fileHead)
+		return
+	}
+
 	// Perform action on sorted libs
 	index := 0
-	waiter := sizedwaitgroup.New(runtime.GOMAXPROCS(0))
+	waiter := sizedwaitgroup.New(com.MaxConcurrency())
 	for itr := fileHead; itr != nil; itr = itr.Next {
 		index++
 
-		if closed {
+		if mu.isClosed() {
 			// Stop execution and clean up
 			waiter.Wait()
 			return
@@ -187,7 +866,11 @@ func (mu *MU) perform() {
 
 		if mu.Options.Action == "list" {
 			// If we're just listing, print 'n go ;)
-			com.Println("(", index, "/", mu.Stats.DepCount, ")", itr.File.Path)
+			if kind := itr.File.Manifest().Kind; kind != com.KindGo {
+				com.Println("(", index, "/", mu.Stats.DepCount, ")", itr.File.Path, "("+kind+")")
+			} else {
+				com.Println("(", index, "/", mu.Stats.DepCount, ")", itr.File.Path)
+			}
 			continue
 		}
 
@@ -197,122 +880,160 @@ func (mu *MU) perform() {
 
 		switch mu.Options.Action {
 		case "pull":
-			waiter.Add()
-			go func(index int, lib Library) {
-				// Separate output
-				com.Println("")
-				com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
-
+			mu.performConcurrently(&waiter, index, lib, func(lib Library) {
 				if len(lib.File.Version) > 0 {
 					lib.File.Output("Already has version set: " + lib.File.Version)
 				} else {
 					mu.pull(lib)
 				}
-
-				waiter.Done()
-			}(index, lib)
+			})
 			continue
 		case "replace":
-			// Separate output
-			com.Println("")
-			com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
 
 			mu.replace(lib, fileHead)
+			done()
 			continue
 		case "reset":
-			waiter.Add()
-			go func(index int, lib Library) {
-				// Separate output
-				com.Println("")
-				com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
-
-				mu.reset(lib)
-
-				waiter.Done()
-			}(index, lib)
+			mu.performConcurrently(&waiter, index, lib, mu.reset)
+			continue
+		case "promote":
+			// Dependents require promoted releases, one lib at a time
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.promote(lib, fileHead)
+			done()
+			continue
+		case "go-version":
+			// Dependents are raised to the go directive of their deps, one lib at a time
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.goVersion(lib, fileHead)
+			done()
+			continue
+		case "tidy":
+			// Dependents are tidied against their deps' tidied mod files, one lib at a time
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.tidy(lib)
+			done()
+			continue
+		case "major":
+			// Dependents require the new majors of their deps, one lib at a time
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.major(lib, fileHead)
+			done()
+			continue
+		case "migrate-module":
+			// Dependents migrate against their deps' migrated requires, one lib at a time
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.migrateModule(lib, fileHead)
+			done()
+			continue
+		case "vendor":
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.vendor(lib)
+			done()
 			continue
 		case "test":
-			// Separate output
-			com.Println("")
-			com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
 			mu.test(lib, fileHead)
+			done()
+			continue
+		case "affected":
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			mu.affected(lib, fileHead)
+			done()
+			continue
+		case "exec":
+			mu.startLibrary(lib)
+			done := mu.beginLibrary(index, lib)
+			ok := mu.exec(lib, index)
+			done()
+			if !ok && !mu.Options.KeepGoing {
+				// Later libs may depend on this one
+				com.Println("\nStopping, use keep going to run in remaining libs")
+				waiter.Wait()
+				return
+			}
+			continue
+		case "clean":
+			mu.performConcurrently(&waiter, index, lib, mu.clean)
+			continue
+		case "audit":
+			mu.performConcurrently(&waiter, index, lib, mu.audit)
+			continue
+		case "outdated":
+			mu.performConcurrently(&waiter, index, lib, mu.outdated)
+			continue
+		case "check":
+			mu.performConcurrently(&waiter, index, lib, mu.check)
+			continue
+		case "grep":
+			mu.performConcurrently(&waiter, index, lib, mu.grep)
+			continue
+		case "licenses":
+			mu.performConcurrently(&waiter, index, lib, mu.licenses)
+			continue
+		case "status":
+			mu.performConcurrently(&waiter, index, lib, func(lib Library) {
+				mu.status(lib, fileHead)
+			})
 			continue
 		case "workflow":
-			waiter.Add()
-			go func(index int, lib Library) {
-				// Separate output
-				com.Println("")
-				com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
-
-				if err := lib.File.AddGitWorkflow(mu.Options.SourcePath); err != nil {
-					lib.File.Output("Failed to add workflow " + err.Error() + " :(")
-				}
-
-				waiter.Done()
-			}(index, lib)
+			mu.performConcurrently(&waiter, index, lib, mu.workflow)
 			continue
 		case "secret":
-			//mu.addSecret(lib)
-			println("Secrets unsupported without salt :(")
-			return
-		}
-
-		// Separate output
-		com.Println("")
-		com.Println("(", index, "/", mu.Stats.DepCount, ")", itr.File.Path)
-
-		// Sync
-		if len(lib.File.Version) > 0 {
-			lib.File.Output("Already has version set: " + lib.File.Version)
+			mu.performConcurrently(&waiter, index, lib, mu.secret)
 			continue
 		}
 
-		// Handle branching
-		mu.updateOrCreateBranch(lib)
+		done := mu.beginLibrary(index, lib)
 
-		if closed {
-			// Stop execution and clean up
-			return
-		}
+		// Sync
+		mu.startLibrary(lib)
 
 		// Aggregate updated versions of previously parsed deps
 		lib.ModAddDeps(fileHead, false)
 
-		mu.commit(lib)
-
-		if closed {
+		finished := mu.syncLibrary(lib)
+		done()
+		if !finished {
 			// Stop execution and clean up
 			return
 		}
 
-		commitTitle, commitMessage := mu.getCommitDetails(lib)
-		mu.sync(lib, commitTitle, commitMessage)
-
-		if closed {
-			// Stop execution and clean up
+		if mu.Options.Atomic && syncFailed(lib) {
+			// Leave every lib as it was
+			mu.rollback(lib, fileHead)
 			return
 		}
+	}
 
-		// Create PR
-		mu.pullRequest(lib, mu.Options.Branch, commitTitle, commitMessage)
-
-		if closed {
-			// Stop execution and clean up
-			return
-		}
+	waiter.Wait()
 
-		mu.removeBranchIfUnused(lib)
+	if mu.Options.Action == "go-version" {
+		mu.openBatchPRs(fileHead)
+	}
 
-		if closed {
-			// Stop execution and clean up
-			return
-		}
+	if mu.Options.Action == "sync" {
+		mu.openBatchPRs(fileHead)
 
-		mu.tag(lib)
+		// Every lib was attempted, checkpoint no longer needed if all succeeded
+		mu.clearCheckpoint()
 	}
 
-	waiter.Wait()
+	mu.printNames(fileHead)
+}
 
+// printNames prints changed libs when log level is name-only
+func (mu *MU) printNames(fileHead *sort.FileNode) {
 	if com.GetLogLevel() == com.NAMEONLY {
 		// Print names and quit
 		for fileItr := fileHead; fileItr != nil; fileItr = fileItr.Next {
@@ -322,3 +1043,146 @@ func (mu *MU) perform() {
 		}
 	}
 }
+
+// syncLibrary performs each sync step on lib, returning false if the run was aborted before finishing
+// Note: lib.ModAddDeps should be called first to aggregate updated deps
+func (mu *MU) syncLibrary(lib Library) (finished bool) {
+	if len(lib.File.Version) > 0 {
+		lib.File.Output("Already has version set: " + lib.File.Version)
+		return true
+	}
+
+	if mu.restoreCheckpoint(lib) {
+		return true
+	}
+
+	// Handle branching
+	mu.setStep(lib, StepBranch)
+	protected, err := mu.checkBranches(lib)
+	if err != nil {
+		// Don't create branches or make changes which can't be pushed
+		mu.saveCheckpoint(lib, false)
+		return true
+	}
+
+	_, _, branchErr := mu.updateOrCreateBranch(lib)
+	mu.saveCheckpoint(lib, false)
+
+	if mu.isAborted() {
+		return
+	}
+
+	if len(protected) > 0 && mu.syncOnFallback(lib, protected) != nil {
+		mu.saveCheckpoint(lib, false)
+		return true
+	}
+
+	if mu.runHooks(HookPreSync, lib, "") != nil {
+		// Don't sync without the hook's changes
+		mu.saveCheckpoint(lib, false)
+		return true
+	}
+
+	head := lib.File.HeadCommit()
+	mu.setStep(lib, StepCommit)
+	mu.commit(lib)
+	mu.saveCheckpoint(lib, false)
+
+	if mu.isAborted() {
+		return
+	}
+
+	commitTitle, commitMessage := mu.getCommitDetails(lib)
+	mu.setStep(lib, StepSync)
+	mu.sync(lib, commitTitle, commitMessage)
+	mu.saveCheckpoint(lib, false)
+
+	if mu.isAborted() {
+		return
+	}
+
+	if lib.File.HeadCommit() != head {
+		mu.runHooks(HookPostCommit, lib, "")
+	}
+
+	// Create PR
+	mu.setStep(lib, StepPullRequest)
+	if len(lib.File.ProtectedBranch) == 0 {
+		mu.pullRequest(lib, mu.branch(lib), commitTitle, commitMessage)
+	} else if lib.File.HeadCommit() != head {
+		// Changes to protected branches are only reviewed through a pull request
+		mu.requestPR(lib, FallbackBranch, commitTitle, commitMessage)
+	}
+	mu.saveCheckpoint(lib, false)
+
+	if mu.isAborted() {
+		return
+	}
+
+	mu.removeBranchIfUnused(lib)
+
+	if mu.isAborted() {
+		return
+	}
+
+	mu.setStep(lib, StepTag)
+	if !mu.checksBlockTag(lib) {
+		mu.tag(lib)
+	}
+
+	// Failed libs will be retried on resume
+	mu.saveCheckpoint(lib, branchErr == nil && len(lib.File.Errors) == 0)
+	return true
+}
+
+// syncLevels syncs each dependency level in order, syncing libs within a level concurrently.
+// Returns false if the run was closed before finishing
+func (mu *MU) syncLevels(fileHead *sort.FileNode) (finished bool) {
+	levels := sort.Levels(fileHead, mu.Options.DirectImport)
+
+	index := 0
+	for depth, level := range levels {
+		com.Println("\nSyncing level", depth+1, "/", len(levels), "with", len(level), "lib(s)...")
+
+		// Aggregate deps before starting so libs in this level aren't read while syncing
+		libs := make([]Library, len(level))
+		for i, node := range level {
+			libs[i].File = node.File
+			libs[i].ModAddDeps(fileHead, false)
+		}
+
+		waiter := sizedwaitgroup.New(com.MaxConcurrency())
+		for _, lib := range libs {
+			index++
+			if waiter.AddWithContext(mu.ctx) != nil {
+				// Cancelled while waiting for a worker
+				break
+			}
+
+			go func(index int, lib Library) {
+				defer waiter.Done()
+				defer mu.beginLibrary(index, lib)()
+
+				mu.startLibrary(lib)
+				mu.syncLibrary(lib)
+			}(index, lib)
+		}
+
+		waiter.Wait()
+
+		if mu.Options.Atomic {
+			for _, lib := range libs {
+				if syncFailed(lib) {
+					mu.rollback(lib, fileHead)
+					return
+				}
+			}
+		}
+
+		if mu.isClosed() {
+			return
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,128 @@
+package gomu
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// targetLibrary returns a target directory holding a lib, with the working directory changed to a temporary one
+func targetLibrary(t *testing.T) (dir string) {
+	loggedLibrary(t)
+
+	dir, err := ioutil.TempDir("", "gomu-target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err = os.MkdirAll(filepath.Join(dir, "a", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "a", "go.mod"), []byte("module github.com/org/a\n\ngo 1.14\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+// runContext runs mu with ctx, failing if it doesn't return
+func runContext(t *testing.T, mu *MU, ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		mu.RunContext(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected run to return")
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	runContext(t, mu, context.Background())
+	if mu.Stats.DepCount != 1 || !recorder.Ran("git push -u origin") {
+		t.Errorf("expected lib to be synced, ran %v", recorder.Commands())
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runContext(t, mu, ctx)
+
+	for _, prefix := range []string{"go mod", "git commit -m gomu", "git push", "git-tagger"} {
+		if recorder.Ran(prefix) {
+			t.Errorf("expected cancelled run not to sync libs, ran %v", recorder.Commands())
+		}
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	t.Cleanup(func() { com.SetMaxConcurrency(0) })
+
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true, MaxConcurrency: -1})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	runContext(t, mu, context.Background())
+	if len(mu.Errors) != 1 || len(recorder.Commands()) > 0 {
+		t.Errorf("expected negative max concurrency to be rejected before syncing, got %v and ran %v", mu.Errors, recorder.Commands())
+	}
+
+	mu = New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true, MaxConcurrency: 2})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	runContext(t, mu, context.Background())
+	if com.MaxConcurrency() != 2 || mu.Stats.DepCount != 1 {
+		t.Errorf("expected lib to be synced 2 at a time, got %d", com.MaxConcurrency())
+	}
+
+	if com.SetMaxConcurrency(0); com.MaxConcurrency() != runtime.GOMAXPROCS(0) {
+		t.Errorf("expected GOMAXPROCS by default, got %d", com.MaxConcurrency())
+	}
+}
+
+func TestReadOnlyActionsSkipStash(t *testing.T) {
+	for _, action := range []string{"list", "graph", "audit", "outdated"} {
+		recorder := &com.Recorder{}
+		mu := New(Options{Action: action, TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true})
+		mu.SetRunner(recorder)
+		mu.signalsHandled = true
+
+		runContext(t, mu, context.Background())
+		if recorder.Ran("git stash") {
+			t.Errorf("expected %s not to stash, ran %v", action, commandLines(recorder))
+		}
+	}
+
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "sync", TargetDirectories: []string{targetLibrary(t)}, IgnoreWarning: true})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+
+	runContext(t, mu, context.Background())
+	if !recorder.Ran("git stash") {
+		t.Errorf("expected sync to stash local changes, ran %v", commandLines(recorder))
+	}
+}
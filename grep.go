@@ -0,0 +1,145 @@
+package gomu
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	gosort "sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// GrepMatch represents a line of a library file matching the grep pattern
+type GrepMatch struct {
+	Library string `json:"library"`
+	File    string `json:"file"` // Relative to the library
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+}
+
+// maxGrepLineLength is the max length of matched lines kept, longer lines are truncated
+const maxGrepLineLength = 200
+
+// loadGrepPattern compiles the grep pattern set in options
+func (mu *MU) loadGrepPattern() (err error) {
+	if len(mu.Options.Pattern) == 0 {
+		return fmt.Errorf("no pattern provided to grep")
+	}
+
+	if mu.grepPattern, err = regexp.Compile(mu.Options.Pattern); err != nil {
+		err = fmt.Errorf("unable to parse grep pattern %q: %v", mu.Options.Pattern, err)
+	}
+
+	return
+}
+
+// Grep returns lines of lib's mod files matching pattern, and of its go files if source is set
+func (lib *Library) Grep(pattern *regexp.Regexp, source bool) (matches []GrepMatch, err error) {
+	root := lib.File.Path
+	for _, name := range []string{"go.mod", "go.sum"} {
+		var fileMatches []GrepMatch
+		fileMatches, err = lib.grepFile(pattern, root, filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			err = nil
+		} else if err != nil {
+			return
+		}
+
+		matches = append(matches, fileMatches...)
+	}
+
+	if !source {
+		return
+	}
+
+	err = walkGoFiles(root, func(path string) error {
+		fileMatches, err := lib.grepFile(pattern, root, path)
+		matches = append(matches, fileMatches...)
+		return err
+	})
+
+	return
+}
+
+// grepFile returns lines of the file at path matching pattern
+func (lib *Library) grepFile(pattern *regexp.Regexp, root, path string) (matches []GrepMatch, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	name, _ := filepath.Rel(root, path)
+	scanner := bufio.NewScanner(file)
+	// Allow long lines, e.g. generated code
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if !pattern.MatchString(text) {
+			continue
+		}
+
+		if len(text) > maxGrepLineLength {
+			text = text[:maxGrepLineLength] + "..."
+		}
+
+		matches = append(matches, GrepMatch{
+			Library: lib.File.GetGoURL(),
+			File:    filepath.ToSlash(name),
+			Line:    line,
+			Text:    text,
+		})
+	}
+
+	err = scanner.Err()
+	return
+}
+
+func (mu *MU) grep(lib Library) {
+	matches, err := lib.Grep(mu.grepPattern, mu.Options.Source)
+	if err != nil {
+		mu.libraryError(lib, fmt.Errorf("grep failed: %v", err))
+		return
+	}
+
+	if len(matches) == 0 {
+		lib.File.Output("No matches")
+		return
+	}
+
+	for _, match := range matches {
+		lib.File.Output(match.File + ":" + strconv.Itoa(match.Line) + ": " + match.Text)
+	}
+
+	mu.statsMux.Lock()
+	mu.Stats.Matches = append(mu.Stats.Matches, matches...)
+	mu.statsMux.Unlock()
+
+	mu.addStat(&mu.Stats.GrepCount, &mu.Stats.GrepOutput, lib.File.GetGoURL()+" ("+strconv.Itoa(len(matches))+" match(es))\n")
+}
+
+// formatMatches returns grep matches grouped by library, in file and line order
+func (stats ActionStats) formatMatches() string {
+	matches := append([]GrepMatch(nil), stats.Matches...)
+	gosort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Library < matches[j].Library
+	})
+
+	var output bytes.Buffer
+	writer := tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	library := ""
+	for _, match := range matches {
+		if match.Library != library {
+			library = match.Library
+			fmt.Fprintln(writer, "\n"+library)
+		}
+
+		fmt.Fprintln(writer, "  "+match.File+":"+strconv.Itoa(match.Line)+"\t"+match.Text)
+	}
+	writer.Flush()
+
+	return output.String()
+}
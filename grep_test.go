@@ -0,0 +1,76 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadGrepPattern(t *testing.T) {
+	for pattern, valid := range map[string]bool{"": false, "github.com/(old": false, `github\.com/old/`: true} {
+		mu := &MU{Options: Options{Pattern: pattern}}
+		if err := mu.loadGrepPattern(); (err == nil) != valid {
+			t.Errorf("%q: expected valid %v, got %v", pattern, valid, err)
+		}
+	}
+}
+
+func TestGrep(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	root := lib.File.Path
+	for name, content := range map[string]string{
+		"go.mod":         "module github.com/org/a\n\ngo 1.14\n\nrequire github.com/old/lib v1.0.0\n",
+		"go.sum":         "github.com/old/lib v1.0.0 h1:abc=\ngithub.com/old/lib v1.0.0/go.mod h1:def=\n",
+		"a.go":           "package a\n\nimport _ \"github.com/old/lib\"\n\n// " + strings.Repeat("github.com/old/lib ", 20) + "\n",
+		"vendor/v.go":    "package v\n\nimport _ \"github.com/old/lib\"\n",
+		"docs/README.md": "github.com/old/lib\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pattern := regexp.MustCompile(`github\.com/old/lib`)
+	matches, err := lib.Grep(pattern, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, match := range matches {
+		found = append(found, match.File+":"+strconv.Itoa(match.Line))
+	}
+	if expected := []string{"go.mod:5", "go.sum:1", "go.sum:2"}; !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected mod file matches %v, got %v", expected, found)
+	}
+
+	if matches, err = lib.Grep(pattern, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 5 {
+		t.Fatalf("expected source matches outside vendored packages, got %+v", matches)
+	}
+	if text := matches[4].Text; matches[4].File != "a.go" || len(text) != maxGrepLineLength+3 || !strings.HasSuffix(text, "...") {
+		t.Errorf("expected long line of a.go to be truncated, got %s: %q", matches[4].File, text)
+	}
+}
+
+func TestFormatMatches(t *testing.T) {
+	stats := ActionStats{Matches: []GrepMatch{
+		{Library: "github.com/org/b", File: "go.mod", Line: 3, Text: "require x v1"},
+		{Library: "github.com/org/a", File: "go.mod", Line: 5, Text: "require x v1"},
+		{Library: "github.com/org/a", File: "a.go", Line: 12, Text: `import "x"`},
+	}}
+
+	expected := "\ngithub.com/org/a\n  go.mod:5  require x v1\n  a.go:12   import \"x\"\n\ngithub.com/org/b\n  go.mod:3  require x v1\n"
+	if output := stats.formatMatches(); output != expected {
+		t.Errorf("expected matches grouped by lib\n%q\ngot\n%q", expected, output)
+	}
+}
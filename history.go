@@ -0,0 +1,266 @@
+package gomu
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	gosort "sort"
+	"strconv"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// HistoryName is the file each run's summary is appended to, one json object per line
+const HistoryName = ".gomu-history.jsonl"
+
+// DefaultHistoryLimit is how many past runs history lists by default
+const DefaultHistoryLimit = 10
+
+// HistoryRecord represents a finished run
+type HistoryRecord struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+
+	// Versions required by each lib once finished, compared by diff
+	Deps DepSnapshot `json:"deps,omitempty"`
+
+	Summary
+}
+
+// LoadHistory reads every run recorded in historyPath, oldest first. Lines which can't be parsed are skipped
+func LoadHistory(historyPath string) (records []HistoryRecord, err error) {
+	f, err := os.Open(historyPath)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Summaries of large runs exceed the default line limit
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var record HistoryRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			// e.g. a line truncated by an interrupted write
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	err = scanner.Err()
+	return
+}
+
+// appendHistory appends record to historyPath as a single line
+func appendHistory(historyPath string, record HistoryRecord) (err error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if info, statErr := f.Stat(); statErr == nil && info.Size() > 0 {
+		// Start a new line after one truncated by an interrupted write, so this record isn't joined to it
+		last := make([]byte, 1)
+		if _, readErr := f.ReadAt(last, info.Size()-1); readErr == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	return
+}
+
+// historyFile returns the path runs are recorded to
+func (mu *MU) historyFile() string {
+	if len(mu.Options.HistoryFile) > 0 {
+		return mu.Options.HistoryFile
+	}
+
+	return HistoryName
+}
+
+// recordHistory appends this run's summary and a snapshot of its libs' deps to the history file.
+// Querying history or diffing against it isn't recorded
+func (mu *MU) recordHistory() {
+	if mu.Options.NoHistory || mu.Options.Action == "history" || mu.Options.Action == "diff" {
+		return
+	}
+
+	record := HistoryRecord{
+		Started:  mu.started,
+		Duration: time.Since(mu.started),
+		Summary:  mu.Stats.Summary(mu.Errors),
+	}
+	if mu.SortedLibraries != nil {
+		record.Deps = snapshotDeps(*mu.SortedLibraries)
+	}
+
+	if err := appendHistory(mu.historyFile(), record); err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to record run history: %v", err))
+	}
+}
+
+// failed is true if the lib had errors, failing tests or commands, or timed out
+func (result LibraryResult) failed() bool {
+	return len(result.Errors) > 0 || len(result.Failures) > 0 || result.TestFailed || result.TimedOut ||
+		(result.ExitCode != nil && *result.ExitCode != 0)
+}
+
+// history lists recent runs, how long each action takes and which libs fail most often
+func (mu *MU) history() {
+	historyPath := mu.historyFile()
+	records, err := LoadHistory(historyPath)
+	if os.IsNotExist(err) {
+		com.Println("\nNo run history found in", historyPath)
+		return
+	} else if err != nil {
+		mu.configError(fmt.Errorf("unable to read run history %s: %v", historyPath, err))
+		return
+	}
+
+	if len(mu.Options.HistoryAction) > 0 {
+		filtered := records[:0]
+		for _, record := range records {
+			if record.Action == mu.Options.HistoryAction {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
+
+	limit := mu.Options.HistoryLimit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	// Most recent first
+	recent := records
+	if len(recent) > limit {
+		recent = recent[len(recent)-limit:]
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		record := recent[i]
+		mu.addStat(&mu.Stats.HistoryCount, &mu.Stats.HistoryOutput, record.describe()+"\n")
+	}
+
+	mu.Stats.History = records
+	mu.Stats.HistoryDurationOutput = compareDurations(records)
+	mu.Stats.HistoryFailureOutput = mostFailed(records, limit)
+}
+
+// describe returns a one line description of the run
+func (record HistoryRecord) describe() string {
+	line := record.Started.Format("2006-01-02 15:04:05") + " " + record.Action
+	if len(record.Branch) > 0 {
+		line += " <" + record.Branch + ">"
+	}
+	if record.DryRun {
+		line += " (dry run)"
+	}
+
+	failed := 0
+	for _, result := range record.Libraries {
+		if result.failed() {
+			failed++
+		}
+	}
+
+	return line + " - " + strconv.Itoa(record.DepCount) + " lib(s), " + strconv.Itoa(failed) + " failed, exit " +
+		strconv.Itoa(record.ExitCode) + ", took " + roundDuration(record.Duration)
+}
+
+// compareDurations returns the average duration of each action's runs compared to its latest run
+func compareDurations(records []HistoryRecord) (output string) {
+	type durations struct {
+		total  time.Duration
+		count  int
+		latest time.Duration
+	}
+
+	byAction := make(map[string]*durations)
+	var actions []string
+	for _, record := range records {
+		d, ok := byAction[record.Action]
+		if !ok {
+			d = &durations{}
+			byAction[record.Action] = d
+			actions = append(actions, record.Action)
+		}
+
+		d.total += record.Duration
+		d.count++
+		d.latest = record.Duration
+	}
+
+	gosort.Strings(actions)
+	for _, action := range actions {
+		d := byAction[action]
+		average := d.total / time.Duration(d.count)
+		line := action + ": " + strconv.Itoa(d.count) + " run(s), average " + roundDuration(average) +
+			", latest " + roundDuration(d.latest)
+
+		if d.count > 1 && average > 0 {
+			// Latest run compared to the average
+			change := float64(d.latest-average) / float64(average) * 100
+			line += " (" + fmt.Sprintf("%+.0f", change) + "%)"
+		}
+
+		output += line + "\n"
+	}
+
+	return
+}
+
+// roundDuration formats d to the second, or millisecond if under a second
+func roundDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
+}
+
+// mostFailed returns up to limit libs which failed in the most runs, most first
+func mostFailed(records []HistoryRecord, limit int) (output string) {
+	failures := make(map[string]int)
+	runs := make(map[string]int)
+	for _, record := range records {
+		for _, result := range record.Libraries {
+			runs[result.Library]++
+			if result.failed() {
+				failures[result.Library]++
+			}
+		}
+	}
+
+	libs := make([]string, 0, len(failures))
+	for lib := range failures {
+		libs = append(libs, lib)
+	}
+
+	gosort.Slice(libs, func(i, j int) bool {
+		if failures[libs[i]] != failures[libs[j]] {
+			return failures[libs[i]] > failures[libs[j]]
+		}
+		return libs[i] < libs[j]
+	})
+
+	if len(libs) > limit {
+		libs = libs[:limit]
+	}
+
+	for i, lib := range libs {
+		output += strconv.Itoa(i+1) + ") " + lib + " failed " + strconv.Itoa(failures[lib]) + "/" + strconv.Itoa(runs[lib]) + " run(s)\n"
+	}
+
+	return
+}
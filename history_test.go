@@ -0,0 +1,106 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// historyFile writes records to a history file, followed by a truncated line
+func historyFile(t *testing.T, records ...HistoryRecord) string {
+	dir, err := ioutil.TempDir("", "gomu-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	historyPath := filepath.Join(dir, HistoryName)
+	for _, record := range records {
+		if err = appendHistory(historyPath, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.OpenFile(historyPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"started": "2020-01-0`)
+	f.Close()
+	return historyPath
+}
+
+func historyRecord(started time.Time, action string, duration time.Duration, failed ...string) HistoryRecord {
+	record := HistoryRecord{Started: started, Duration: duration, Summary: Summary{Action: action, DepCount: 2}}
+	for _, lib := range []string{"github.com/org/a", "github.com/org/b"} {
+		result := LibraryResult{Library: lib}
+		for _, failedLib := range failed {
+			result.TestFailed = result.TestFailed || failedLib == lib
+		}
+		record.Libraries = append(record.Libraries, result)
+	}
+
+	return record
+}
+
+func TestHistory(t *testing.T) {
+	started := time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)
+	historyPath := historyFile(t,
+		historyRecord(started, "test", 10*time.Second, "github.com/org/b"),
+		historyRecord(started.Add(time.Hour), "sync", time.Minute),
+		historyRecord(started.Add(2*time.Hour), "test", 20*time.Second, "github.com/org/a", "github.com/org/b"),
+	)
+
+	records, err := LoadHistory(historyPath)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("expected 3 runs, skipping the truncated line, got %d (%v)", len(records), err)
+	}
+
+	mu := &MU{Options: Options{Action: "history", HistoryFile: historyPath, HistoryLimit: 2}}
+	mu.history()
+
+	expected := "1) 2020-01-02 17:04:05 test - 2 lib(s), 2 failed, exit 0, took 20s\n" +
+		"2) 2020-01-02 16:04:05 sync - 2 lib(s), 0 failed, exit 0, took 1m0s\n"
+	if mu.Stats.HistoryOutput != expected {
+		t.Errorf("expected most recent runs first, got %q", mu.Stats.HistoryOutput)
+	}
+	if expected = "sync: 1 run(s), average 1m0s, latest 1m0s\ntest: 2 run(s), average 15s, latest 20s (+33%)\n"; mu.Stats.HistoryDurationOutput != expected {
+		t.Errorf("expected %q, got %q", expected, mu.Stats.HistoryDurationOutput)
+	}
+	if expected = "1) github.com/org/b failed 2/3 run(s)\n2) github.com/org/a failed 1/3 run(s)\n"; mu.Stats.HistoryFailureOutput != expected {
+		t.Errorf("expected %q, got %q", expected, mu.Stats.HistoryFailureOutput)
+	}
+
+	// Appended after the truncated line
+	if err = appendHistory(historyPath, historyRecord(started.Add(3*time.Hour), "sync", time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	mu = &MU{Options: Options{Action: "history", HistoryFile: historyPath, HistoryAction: "sync"}}
+	if mu.history(); mu.Stats.HistoryCount != 2 || !strings.Contains(mu.Stats.HistoryOutput, " sync ") {
+		t.Errorf("expected only the sync runs, including the one after the truncated line, got %q", mu.Stats.HistoryOutput)
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	historyPath := filepath.Join(dir, HistoryName)
+	mu := &MU{Options: Options{Action: "history", HistoryFile: historyPath}}
+	mu.Stats.Options = &mu.Options
+	mu.started = time.Now()
+	mu.recordHistory()
+	mu.Options.Action = "list"
+	mu.recordHistory()
+
+	if records, err := LoadHistory(historyPath); err != nil || len(records) != 1 || records[0].Action != "list" {
+		t.Errorf("expected only the list run to be recorded, got %+v (%v)", records, err)
+	}
+}
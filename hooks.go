@@ -0,0 +1,109 @@
+package gomu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Points in a library's sync at which hooks run
+const (
+	// HookPreSync runs once the branch is checked out, before local changes are committed and mod files synced
+	HookPreSync = "pre-sync"
+	// HookPostCommit runs if commits were made while syncing
+	HookPostCommit = "post-commit"
+	// HookPreTag runs before a library is tagged
+	HookPreTag = "pre-tag"
+	// HookPostPR runs after a pull request is opened
+	HookPostPR = "post-pr"
+)
+
+// Environment variables exposed to hook commands, in addition to the exec variables
+const (
+	HookEnv        = "GOMU_HOOK"
+	HookVersionEnv = "GOMU_LIB_VERSION" // Latest tag, or the tag to be created before tagging
+	HookPRURLEnv   = "GOMU_PR_URL"
+)
+
+// HookFunc is called at a hook point for lib. Errors from pre hooks skip the step.
+// Note: the version about to be tagged is only exposed to hook commands
+type HookFunc func(mu *MU, lib Library) error
+
+// validateHookPoint returns an error if point isn't a known hook point
+func validateHookPoint(point string) error {
+	switch point {
+	case HookPreSync, HookPostCommit, HookPreTag, HookPostPR:
+		return nil
+	}
+
+	return fmt.Errorf("unknown hook %q, expected %s, %s, %s or %s", point, HookPreSync, HookPostCommit, HookPreTag, HookPostPR)
+}
+
+// RegisterHook adds hook to run at point for each library, after any hook commands in mu.Options.Hooks
+func (mu *MU) RegisterHook(point string, hook HookFunc) (err error) {
+	if err = validateHookPoint(point); err != nil {
+		return
+	}
+
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	if mu.hooks == nil {
+		mu.hooks = make(map[string][]HookFunc)
+	}
+	mu.hooks[point] = append(mu.hooks[point], hook)
+	return
+}
+
+// runHooks runs the hook commands then registered hooks for point on lib, stopping at the first failure
+func (mu *MU) runHooks(point string, lib Library, version string) (err error) {
+	mu.libraryMux.Lock()
+	hooks := mu.hooks[point]
+	mu.libraryMux.Unlock()
+
+	commands := mu.Options.Hooks[point]
+	if len(commands) == 0 && len(hooks) == 0 {
+		return
+	}
+
+	if len(version) == 0 {
+		version = lib.File.Version
+	}
+
+	env := []string{
+		HookEnv + "=" + point,
+		ExecLibPathEnv + "=" + lib.File.OriginalPath(),
+		ExecLibURLEnv + "=" + lib.File.GetGoURL(),
+		HookVersionEnv + "=" + version,
+		HookPRURLEnv + "=" + lib.File.PRURL,
+	}
+
+	for _, command := range commands {
+		lib.File.Output("Running " + point + " hook `" + command + "`...")
+		output, exitCode, runErr := lib.File.RunShell(command, env...)
+		if len(output) > 0 {
+			lib.File.Output(output)
+		}
+
+		if runErr != nil {
+			err = runErr
+		} else if exitCode != 0 {
+			err = fmt.Errorf("exited with code %d", exitCode)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("%s hook `%s` failed: %v", point, strings.TrimSpace(command), err)
+			lib.File.Error(err.Error())
+			return
+		}
+	}
+
+	for _, hook := range hooks {
+		if err = hook(mu, lib); err != nil {
+			err = fmt.Errorf("%s hook failed: %v", point, err)
+			lib.File.Error(err.Error())
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,75 @@
+package gomu
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestRunHooks(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	var ran []string
+	mu := &MU{Options: Options{Hooks: map[string][]string{HookPreTag: {"make changelog", "make docs"}}}}
+	if err := mu.RegisterHook(HookPreTag, func(mu *MU, lib Library) error {
+		ran = append(ran, "registered")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mu.runHooks(HookPreTag, *lib, "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands run before registered hooks
+	expected := []string{"sh -c make changelog", "sh -c make docs"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) || len(ran) != 1 {
+		t.Errorf("expected %q then the registered hook, got %q and %q", expected, lines, ran)
+	}
+
+	env := recorder.Commands()[0].Env
+	for _, variable := range []string{HookEnv + "=" + HookPreTag, HookVersionEnv + "=v1.2.0", ExecLibURLEnv + "=" + lib.File.GetGoURL()} {
+		if !containsString(env, variable) {
+			t.Errorf("expected %s to be set, got %q", variable, env)
+		}
+	}
+
+	// Other points are unaffected
+	if err := mu.runHooks(HookPostPR, *lib, ""); err != nil || len(recorder.Commands()) != 2 {
+		t.Errorf("expected no post-pr hooks, got %v", err)
+	}
+}
+
+func TestRunHooksFails(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("sh -c make lint", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{Hooks: map[string][]string{HookPreSync: {"make lint", "make docs"}}}}
+	mu.RegisterHook(HookPreSync, func(mu *MU, lib Library) error {
+		t.Error("expected registered hook not to run after a failed command")
+		return nil
+	})
+
+	if err := mu.runHooks(HookPreSync, *lib, ""); err == nil || len(lib.File.Errors) != 1 {
+		t.Errorf("expected failed hook to be an error of the lib, got %v", err)
+	}
+	if recorder.Ran("sh -c make docs") {
+		t.Error("expected hooks to stop at the first failure")
+	}
+
+	mu = &MU{}
+	mu.RegisterHook(HookPostCommit, func(mu *MU, lib Library) error { return errors.New("no changelog") })
+	if err := mu.runHooks(HookPostCommit, *lib, ""); err == nil || err.Error() != "post-commit hook failed: no changelog" {
+		t.Errorf("expected registered hook's error, got %v", err)
+	}
+}
+
+func TestRegisterHookUnknown(t *testing.T) {
+	mu := &MU{}
+	if err := mu.RegisterHook("pre-push", func(mu *MU, lib Library) error { return nil }); err == nil {
+		t.Error("expected unknown hook point to fail")
+	}
+}
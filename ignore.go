@@ -0,0 +1,92 @@
+package gomu
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// IgnoreName is the file libs opt out of fleet-wide actions with. If empty every action skips the lib, otherwise each
+// line names an action skipping it, ignoring blank lines and # comments
+const IgnoreName = ".gomuignore"
+
+// skipMarker matches go.mod comments opting libs out, e.g. // gomu.skip=true, or // gomu.skip=sync,tag for some actions
+var skipMarker = regexp.MustCompile(`//\s*gomu\.skip\s*=\s*(\S+)`)
+
+// skippedActions returns the actions the lib at dir opts out of, with "*" for all, and where it opted out
+func skippedActions(dir string) (actions []string, marker string) {
+	if data, err := ioutil.ReadFile(filepath.Join(dir, IgnoreName)); err == nil {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); len(line) > 0 && !strings.HasPrefix(line, "#") {
+				actions = append(actions, line)
+			}
+		}
+
+		if len(actions) == 0 {
+			actions = []string{"*"}
+		}
+
+		return actions, IgnoreName
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return
+	}
+
+	match := skipMarker.FindSubmatch(data)
+	if match == nil {
+		return
+	}
+
+	switch value := string(match[1]); value {
+	case "false":
+		return
+	case "true":
+		actions = []string{"*"}
+	default:
+		actions = strings.Split(value, ",")
+	}
+
+	return actions, "go.mod"
+}
+
+// skips returns true if the lib at dir opts out of action, and where it opted out
+func skips(dir, action string) (skip bool, marker string) {
+	actions, marker := skippedActions(dir)
+	for _, skipped := range actions {
+		if skipped = strings.TrimSpace(skipped); skipped == "*" || skipped == action {
+			return true, marker
+		}
+	}
+
+	return false, ""
+}
+
+// removeIgnored returns libs which haven't opted out of the action with a skip marker, unless markers are ignored
+func (mu *MU) removeIgnored(libs sort.StringArray) (kept sort.StringArray) {
+	if mu.Options.IgnoreSkipMarkers {
+		return libs
+	}
+
+	kept = make(sort.StringArray, 0, len(libs))
+	for _, lib := range libs {
+		if skip, marker := skips(lib, mu.Options.Action); skip {
+			file := com.FileWrapper{Path: lib}
+			com.Println("Skipping", file.GetGoURL()+", opted out of", mu.Options.Action, "in", marker)
+			mu.addStat(&mu.Stats.IgnoredCount, &mu.Stats.IgnoredOutput, file.GetGoURL()+" ("+marker+")\n")
+			continue
+		}
+
+		kept = append(kept, lib)
+	}
+
+	return
+}
@@ -0,0 +1,76 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestSkips(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		files  map[string]string
+		action string
+		skip   bool
+		marker string
+	}{
+		{map[string]string{"go.mod": "module github.com/org/a\n"}, "sync", false, ""},
+		{map[string]string{IgnoreName: ""}, "sync", true, IgnoreName},
+		{map[string]string{IgnoreName: "# Released by hand\ntag\n\nsync\n"}, "sync", true, IgnoreName},
+		{map[string]string{IgnoreName: "# Released by hand\ntag\n"}, "sync", false, ""},
+		{map[string]string{"go.mod": "module github.com/org/a // gomu.skip=true\n"}, "list", true, "go.mod"},
+		{map[string]string{"go.mod": "module github.com/org/a\n\n// gomu.skip = sync,tag\n"}, "tag", true, "go.mod"},
+		{map[string]string{"go.mod": "module github.com/org/a\n\n// gomu.skip=sync,tag\n"}, "test", false, ""},
+		{map[string]string{"go.mod": "module github.com/org/a // gomu.skip=false\n"}, "sync", false, ""},
+		// The ignore file takes precedence
+		{map[string]string{IgnoreName: "tag\n", "go.mod": "module github.com/org/a // gomu.skip=true\n"}, "sync", false, ""},
+	} {
+		lib, err := ioutil.TempDir(dir, "lib")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range test.files {
+			if err = ioutil.WriteFile(filepath.Join(lib, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if skip, marker := skips(lib, test.action); skip != test.skip || marker != test.marker {
+			t.Errorf("%v: expected %s to be skipped %v in %q, got %v in %q", test.files, test.action, test.skip, test.marker, skip, marker)
+		}
+	}
+}
+
+func TestRemoveIgnored(t *testing.T) {
+	a, _ := recordedLibrary(t)
+	b, _ := recordedLibrary(t)
+	if err := ioutil.WriteFile(filepath.Join(b.File.Path, IgnoreName), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	libs := sort.StringArray{a.File.Path, b.File.Path}
+	mu := &MU{Options: Options{Action: "sync"}}
+	if kept := mu.removeIgnored(libs); !reflect.DeepEqual(kept, libs[:1]) {
+		t.Errorf("expected lib opting out to be removed, got %v", kept)
+	}
+	if mu.Stats.IgnoredCount != 1 {
+		t.Errorf("expected the skipped lib to be counted, got %d", mu.Stats.IgnoredCount)
+	}
+
+	mu = &MU{Options: Options{Action: "sync", IgnoreSkipMarkers: true}}
+	if kept := mu.removeIgnored(libs); !reflect.DeepEqual(kept, libs) {
+		t.Errorf("expected markers to be ignored, got %v", kept)
+	}
+}
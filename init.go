@@ -0,0 +1,397 @@
+package gomu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	gosort "sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// InitData is rendered into the templates of modules created by init
+type InitData struct {
+	// Module path, e.g. github.com/org/lib
+	Module string
+	// Last element of the module path, e.g. lib
+	Name string
+	// Owner of the repo, e.g. org
+	Owner string
+	Year  int
+	// Go directive set in go.mod, empty if left as go mod init set it
+	GoVersion string
+}
+
+// initTemplates are the files of modules created by init, keyed by slash-separated path. Templates in the source
+// path are added to them, replacing any of the same path. go.mod is created with go mod init unless templated
+var initTemplates = map[string]string{
+	"LICENSE": `MIT License
+
+Copyright (c) {{.Year}} {{.Owner}}
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`,
+	".github/workflows/ci.yml": `name: CI
+
+on:
+  push:
+  pull_request:
+
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version-file: go.mod
+      - run: go build ./...
+      - run: go vet ./...
+      - run: go test ./...
+`,
+	"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("Hello from {{.Name}}!")
+}
+`,
+}
+
+// loadInitTemplates parses the default templates and those in dir, if set. Files ending in .tmpl are written without
+// the extension
+func loadInitTemplates(dir string) (templates map[string]*template.Template, err error) {
+	sources := make(map[string]string, len(initTemplates))
+	for name, source := range initTemplates {
+		sources[name] = source
+	}
+
+	if len(dir) > 0 {
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil || info.IsDir() {
+				return walkErr
+			}
+
+			data, readErr := ioutil.ReadFile(path)
+			if readErr != nil {
+				return readErr
+			}
+
+			rel, _ := filepath.Rel(dir, path)
+			sources[strings.TrimSuffix(filepath.ToSlash(rel), ".tmpl")] = string(data)
+			return nil
+		})
+		if err != nil {
+			err = fmt.Errorf("unable to read init templates: %v", err)
+			return
+		}
+	}
+
+	templates = make(map[string]*template.Template, len(sources))
+	for name, source := range sources {
+		if templates[name], err = template.New(name).Parse(source); err != nil {
+			err = fmt.Errorf("unable to parse init template %s: %v", name, err)
+			return
+		}
+	}
+
+	return
+}
+
+// initModule creates the module at mu.Options.ModulePath in the workspace from templates, optionally creating its
+// github repository, then adds it to the manifest so following runs include it
+func (mu *MU) initModule() {
+	module := strings.Trim(mu.Options.ModulePath, "/")
+	comps := strings.Split(module, "/")
+	if len(module) == 0 || len(comps) < 2 {
+		mu.configError(fmt.Errorf("init needs a module path, e.g. github.com/org/lib"))
+		return
+	}
+
+	templates, err := loadInitTemplates(mu.Options.SourcePath)
+	if err != nil {
+		mu.configError(err)
+		return
+	}
+
+	var manifestWorkspace string
+	if len(mu.Options.ManifestPath) > 0 {
+		if _, manifestWorkspace, err = LoadManifest(mu.Options.ManifestPath); err != nil {
+			mu.configError(fmt.Errorf("unable to load manifest: %v", err))
+			return
+		}
+	}
+
+	dir := filepath.Join(mu.workspace(manifestWorkspace), filepath.FromSlash(module))
+	if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+		mu.configError(fmt.Errorf("%s already exists and isn't empty", dir))
+		return
+	}
+
+	if mu.Options.CreateRepo && len(comps) != 3 {
+		mu.configError(fmt.Errorf("creating a repository needs a module path of host/owner/name, got %s", module))
+		return
+	}
+
+	warningActions := []string{"- create " + module + " in " + dir}
+	if mu.Options.CreateRepo {
+		visibility := "public"
+		if mu.Options.PrivateRepo {
+			visibility = "private"
+		}
+		warningActions = append(warningActions, "- create "+visibility+" repository "+strings.Join(comps[1:], "/")+" on "+comps[0]+" and push")
+	}
+	if len(mu.Options.ManifestPath) > 0 {
+		warningActions = append(warningActions, "- add "+module+" to "+mu.Options.ManifestPath)
+	}
+	if !mu.confirm("Init", warningActions) {
+		return
+	}
+
+	lib := Library{File: &com.FileWrapper{Path: dir}}
+	lib.File.SetContext(mu.ctx)
+
+	data := InitData{Module: module, Name: comps[len(comps)-1], Owner: comps[len(comps)-2], Year: time.Now().Year(), GoVersion: mu.Options.GoVersion}
+	if err = mu.writeInitFiles(lib, templates, data); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	lib.File.Output("Committing...")
+	if err = mu.setCommitKey(&lib); err == nil {
+		err = lib.File.Add("-A")
+	}
+	if err == nil {
+		err = lib.commit("gomu: init " + module)
+	}
+	if err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to commit: %v", err))
+		return
+	}
+
+	entry := ManifestEntry{Path: dir}
+	line := module + " (" + dir + ")"
+	if mu.Options.CreateRepo {
+		lib.File.Output("Creating repository...")
+		if entry.URL, err = lib.File.CreateRepository(comps[0], strings.Join(comps[1:], "/"), mu.Options.PrivateRepo); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to create repository: %v", err))
+			return
+		}
+
+		if err = lib.File.RunCmd("git", "remote", "add", com.PushRemote(), entry.URL); err == nil {
+			err = lib.File.RunCmdWithRetry("git", "push", "-u", com.PushRemote(), "HEAD")
+		}
+		if err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to push to %s: %v", entry.URL, err))
+			return
+		}
+
+		line += " " + entry.URL
+	}
+
+	if len(mu.Options.ManifestPath) > 0 && mu.Options.DryRun {
+		lib.File.DryRun("Add " + module + " to " + mu.Options.ManifestPath)
+	} else if len(mu.Options.ManifestPath) > 0 {
+		lib.File.Output("Adding to " + mu.Options.ManifestPath + "...")
+		if err = addToManifest(mu.Options.ManifestPath, entry, data.Name); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to add to manifest: %v", err))
+			return
+		}
+	}
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, line+"\n")
+}
+
+// writeInitFiles renders templates into lib's directory with data, then initializes its repo and module
+func (mu *MU) writeInitFiles(lib Library, templates map[string]*template.Template, data InitData) (err error) {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	gosort.Strings(names)
+
+	if !mu.Options.DryRun {
+		if err = os.MkdirAll(lib.File.Path, 0755); err != nil {
+			return
+		}
+	}
+
+	if err = lib.File.RunCmd("git", "init"); err != nil {
+		return
+	}
+
+	if _, ok := templates["go.mod"]; !ok {
+		if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "init", data.Module); err != nil {
+			return
+		}
+		if len(data.GoVersion) > 0 {
+			if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "edit", "-go="+data.GoVersion); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, name := range names {
+		var rendered bytes.Buffer
+		if err = templates[name].Execute(&rendered, data); err != nil {
+			return fmt.Errorf("unable to render %s: %v", name, err)
+		}
+
+		if mu.Options.DryRun {
+			lib.File.DryRun("Write " + name)
+			continue
+		}
+
+		lib.File.Output("Writing " + name + "...")
+		path := filepath.Join(lib.File.Path, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return
+		}
+		if err = ioutil.WriteFile(path, rendered.Bytes(), 0644); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// addToManifest lists entry in the manifest at manifestPath, by name in yaml manifests listing libraries by name.
+// Entries already listed are left as is
+func addToManifest(manifestPath string, entry ManifestEntry, name string) (err error) {
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return
+	}
+
+	entries, _, err := LoadManifest(manifestPath)
+	if err != nil {
+		return
+	}
+	for _, listed := range entries {
+		if (len(entry.URL) > 0 && listed.URL == entry.URL) || (len(listed.Path) > 0 && listed.Path == entry.Path) {
+			return
+		}
+	}
+
+	// Paths are relative to the manifest, so it can be shared
+	value := entry.URL
+	if len(value) == 0 {
+		value = entry.Path
+		if abs, absErr := filepath.Abs(filepath.Dir(manifestPath)); absErr == nil {
+			if rel, relErr := filepath.Rel(abs, entry.Path); relErr == nil {
+				value = filepath.ToSlash(rel)
+			}
+		}
+	}
+
+	var updated string
+	switch strings.ToLower(filepath.Ext(manifestPath)) {
+	case ".yaml", ".yml":
+		if updated, err = addToYAMLManifest(string(data), value, name); err != nil {
+			return
+		}
+	default:
+		updated = string(data)
+		if len(updated) > 0 && !strings.HasSuffix(updated, "\n") {
+			updated += "\n"
+		}
+		updated += value + "\n"
+	}
+
+	return ioutil.WriteFile(manifestPath, []byte(updated), 0644)
+}
+
+// addToYAMLManifest adds value to the end of the libraries of a yaml manifest, named name if libraries are listed by name
+func addToYAMLManifest(data, value, name string) (updated string, err error) {
+	manifest, err := parseYAML([]byte(data))
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	start, end, indent, indented := -1, len(lines), "  ", false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Lists may be at the indentation of their key
+		key := line == trimmed && !strings.HasPrefix(trimmed, "-")
+		switch {
+		case key && strings.HasPrefix(line, "libraries:"):
+			start = i
+		case start < 0 || end < len(lines):
+		case key:
+			end = i
+		case !indented:
+			// Added libraries are indented as the first listed
+			indent, indented = line[:len(line)-len(strings.TrimLeft(line, " "))], true
+		}
+	}
+
+	var added []string
+	switch libraries := manifest["libraries"].(type) {
+	case map[string]interface{}:
+		if _, ok := libraries[name]; ok {
+			err = fmt.Errorf("a library named %s is already listed", name)
+			return
+		}
+
+		key := "path"
+		if com.IsRemoteURL(value) {
+			key = "url"
+		}
+		added = []string{indent + name + ":", indent + indent + key + ": " + value}
+	default:
+		// Lists paths or clone urls, or no libraries yet
+		added = []string{indent + "- " + value}
+	}
+
+	if start < 0 {
+		lines = append(lines, "libraries:")
+		start, end = len(lines)-1, len(lines)
+	}
+
+	// Insert after the last library, before blank lines and comments preceding the next key
+	insert := end
+	for insert > start+1 {
+		if trimmed := strings.TrimSpace(lines[insert-1]); len(trimmed) > 0 && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		insert--
+	}
+
+	lines = append(lines[:insert], append(added, lines[insert:]...)...)
+	updated = strings.Join(lines, "\n") + "\n"
+
+	// Don't write a manifest that can't be read back
+	if _, _, err = parseYAMLManifest([]byte(updated)); err != nil {
+		err = fmt.Errorf("unable to add %s: %v", value, err)
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+package gomu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestLoadInitTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-init")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"main.go.tmpl":   "package {{.Name}}\n",
+		"docs/README.md": "# {{.Module}}\n",
+	} {
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, err := loadInitTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != len(initTemplates)+1 {
+		t.Errorf("expected defaults with the added template, got %d templates", len(templates))
+	}
+
+	data := InitData{Module: "github.com/org/lib", Name: "lib"}
+	for name, expected := range map[string]string{"main.go": "package lib\n", "docs/README.md": "# github.com/org/lib\n"} {
+		var rendered bytes.Buffer
+		if templates[name] == nil {
+			t.Errorf("expected template %s", name)
+		} else if err = templates[name].Execute(&rendered, data); err != nil || rendered.String() != expected {
+			t.Errorf("expected %s to render %q, got %q (%v)", name, expected, rendered.String(), err)
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{.Name"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadInitTemplates(dir); err == nil {
+		t.Error("expected invalid template to fail")
+	}
+}
+
+func TestAddToManifest(t *testing.T) {
+	_, manifestPath := manifestDir(t, "libs.txt", "a")
+	entry := ManifestEntry{Path: filepath.Join(filepath.Dir(manifestPath), "src", "lib")}
+	for i := 0; i < 2; i++ {
+		if err := addToManifest(manifestPath, entry, "lib"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := ioutil.ReadFile(manifestPath); string(data) != "a\nsrc/lib\n" {
+		t.Errorf("expected relative path to be listed once, got %q", data)
+	}
+
+	for _, test := range []struct {
+		manifest, expected string
+	}{
+		{"workspace: src\nlibraries:\n    - git@github.com:org/a.git\n\n# Services\nconcurrency: 2\n",
+			"workspace: src\nlibraries:\n    - git@github.com:org/a.git\n    - git@github.com:org/lib.git\n\n# Services\nconcurrency: 2\n"},
+		{"libraries:\n  a:\n    url: git@github.com:org/a.git\n",
+			"libraries:\n  a:\n    url: git@github.com:org/a.git\n  lib:\n    url: git@github.com:org/lib.git\n"},
+		{"workspace: src\n", "workspace: src\nlibraries:\n  - git@github.com:org/lib.git\n"},
+	} {
+		_, manifestPath = manifestDir(t, "libs.yaml", test.manifest)
+		if err := addToManifest(manifestPath, ManifestEntry{URL: "git@github.com:org/lib.git"}, "lib"); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := ioutil.ReadFile(manifestPath); string(data) != test.expected {
+			t.Errorf("expected\n%s\ngot\n%s", test.expected, data)
+		}
+	}
+
+	_, manifestPath = manifestDir(t, "libs.yaml", "libraries:\n  lib:\n    path: other\n")
+	if err := addToManifest(manifestPath, ManifestEntry{URL: "git@github.com:org/lib.git"}, "lib"); err == nil {
+		t.Error("expected a library named as an existing one to fail")
+	}
+}
+
+func TestInitModule(t *testing.T) {
+	dir, manifestPath := manifestDir(t, "libs.txt", "a\n")
+	recorder := &com.Recorder{}
+	com.SetRunner(recorder)
+	com.SetConsoleOutput(ioutil.Discard)
+	defer func() {
+		com.SetRunner(nil)
+		com.SetConsoleOutput(nil)
+	}()
+
+	workspace := filepath.Join(dir, "src")
+	mu := &MU{Options: Options{Action: "init", ModulePath: "github.com/org/lib", ManifestPath: manifestPath, WorkspaceDir: workspace, GoVersion: "1.21", IgnoreWarning: true}}
+	mu.initModule()
+	if len(mu.Errors) > 0 {
+		t.Fatal(mu.Errors)
+	}
+
+	lib := filepath.Join(workspace, "github.com", "org", "lib")
+	license, err := ioutil.ReadFile(filepath.Join(lib, "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(license), "Copyright (c) "+strconv.Itoa(time.Now().Year())+" org") {
+		t.Errorf("expected license to be rendered for the owner, got %q", license)
+	}
+	for _, name := range []string{"main.go", filepath.Join(".github", "workflows", "ci.yml")} {
+		if _, err = os.Stat(filepath.Join(lib, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+
+	for _, command := range []string{"git init", "go mod init github.com/org/lib", "go mod edit -go=1.21", "git add -A", "git commit -m gomu: init github.com/org/lib"} {
+		if !recorder.Ran(command) {
+			t.Errorf("expected %q to run, got %v", command, commandLines(recorder))
+		}
+	}
+	if data, _ := ioutil.ReadFile(manifestPath); string(data) != "a\nsrc/github.com/org/lib\n" {
+		t.Errorf("expected module to be added to the manifest, got %q", data)
+	}
+
+	// Not created over existing files
+	mu = &MU{Options: mu.Options}
+	mu.initModule()
+	if len(mu.Errors) != 1 {
+		t.Errorf("expected init into an existing module to fail, got %v", mu.Errors)
+	}
+}
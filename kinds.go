@@ -0,0 +1,43 @@
+package gomu
+
+import (
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// anyKind is true if the action only needs git and the forge, so it acts on libs of every package manifest kind
+// (npm packages, repos without a manifest). Other actions read or sync mod files, so they only act on go modules
+func (mu *MU) anyKind() bool {
+	if mu.isPlugin() {
+		// Plugins are told each lib's kind
+		return true
+	}
+
+	switch mu.Options.Action {
+	case "clean", "exec", "list", "pull", "secret", "workflow":
+		return true
+	default:
+		return false
+	}
+}
+
+// removeOtherKinds returns libs the action acts on: every lib if it acts on any kind, otherwise go modules
+func (mu *MU) removeOtherKinds(libs sort.StringArray) (kept sort.StringArray) {
+	if mu.anyKind() {
+		return libs
+	}
+
+	kept = make(sort.StringArray, 0, len(libs))
+	for _, lib := range libs {
+		file := com.FileWrapper{Path: lib}
+		file.GetGoURL() // Labels output
+		if kind := file.Manifest().Kind; kind != com.KindGo {
+			file.Debug("Skipping " + kind + " lib, " + mu.Options.Action + " only acts on go modules")
+			continue
+		}
+
+		kept = append(kept, lib)
+	}
+
+	return
+}
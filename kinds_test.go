@@ -0,0 +1,43 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestRemoveOtherKinds(t *testing.T) {
+	goLib, _ := recordedLibrary(t)
+	libs := sort.StringArray{goLib.File.Path}
+	for _, files := range []map[string]string{{"package.json": `{"name": "b"}`}, {"README.md": "# c\n"}} {
+		dir, err := ioutil.TempDir("", "gomu-lib")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		for name, content := range files {
+			if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		libs = append(libs, dir)
+	}
+
+	for action, expected := range map[string]sort.StringArray{"sync": libs[:1], "tidy": libs[:1], "pull": libs, "exec": libs, "workflow": libs} {
+		mu := &MU{Options: Options{Action: action}}
+		if kept := mu.removeOtherKinds(libs); !reflect.DeepEqual(kept, expected) {
+			t.Errorf("%s: expected %v, got %v", action, expected, kept)
+		}
+	}
+
+	// Plugins are told each lib's kind
+	mu := &MU{Options: Options{Action: "release", Plugins: map[string]string{"release": "gomu-release"}}}
+	if kept := mu.removeOtherKinds(libs); !reflect.DeepEqual(kept, libs) {
+		t.Errorf("expected plugins to act on every kind, got %v", kept)
+	}
+}
@@ -10,6 +10,21 @@ type Library struct {
 	File *com.FileWrapper
 
 	updatedDeps *sort.FileNode
+
+	// Modules held at specific versions when syncing
+	pins Pins
+
+	// Keys dep tags must be signed by when syncing, nil if not verified
+	trust *TagTrust
+
+	// Highest versions third-party modules are aligned to when syncing, keyed by module path
+	align map[string]string
+
+	// Verify checksums after updating, before committing
+	verifySums bool
+
+	// Key commits are signed with, unsigned if empty
+	commitKey string
 }
 
 // LibraryFromPath returns a library reference for a filepath
@@ -0,0 +1,112 @@
+package gomu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LibraryOverride represents options set for libs within a module path prefix. Unset bools keep the run's value, so
+// overrides can disable options as well as enable them, e.g. never tagging a lib
+// Note: only the branch, tagging, pull requests, drafts and auto-merge can be overridden. How libs are tagged, i.e.
+// setVersion, signTags, changelog and createRelease, applies to the whole run
+type LibraryOverride struct {
+	Branch string `json:"branch"` // Used as is, branch templates aren't rendered
+
+	Tag         *bool `json:"shouldTag"`
+	PullRequest *bool `json:"createPR"`
+	DraftPR     *bool `json:"draftPR"`
+	AutoMerge   *bool `json:"autoMerge"`
+}
+
+// apply sets the values of the override on options
+func (override LibraryOverride) apply(options *Options) {
+	if len(override.Branch) > 0 {
+		options.Branch = override.Branch
+	}
+	if override.Tag != nil {
+		options.Tag = *override.Tag
+	}
+	if override.PullRequest != nil {
+		options.PullRequest = *override.PullRequest
+	}
+	if override.DraftPR != nil {
+		options.DraftPR = *override.DraftPR
+	}
+	if override.AutoMerge != nil {
+		options.AutoMerge = *override.AutoMerge
+	}
+}
+
+// validateLibraryOptions returns an error if an override in mu.Options.LibraryOptions can't be applied
+func (mu *MU) validateLibraryOptions() error {
+	for prefix, override := range mu.Options.LibraryOptions {
+		if len(strings.Trim(prefix, "/")) == 0 {
+			return fmt.Errorf("library options need a module path prefix")
+		}
+
+		if strings.Contains(override.Branch, "{{") {
+			return fmt.Errorf("library options of %s can't use a branch template, got %q", prefix, override.Branch)
+		}
+	}
+
+	return nil
+}
+
+// libraryOptions returns the options lib is performed with: mu.Options with the override of the longest module path
+// prefix of mu.Options.LibraryOptions matching it merged on top. Merged once per lib
+func (mu *MU) libraryOptions(lib Library) Options {
+	if len(mu.Options.LibraryOptions) == 0 {
+		return mu.Options
+	}
+
+	url := lib.File.GetGoURL()
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	if options, ok := mu.libOptions[url]; ok {
+		return options
+	}
+
+	var override *LibraryOverride
+	longest := -1
+	for prefix := range mu.Options.LibraryOptions {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if url != trimmed && !strings.HasPrefix(url, trimmed+"/") {
+			continue
+		}
+
+		if len(trimmed) > longest {
+			longest = len(trimmed)
+			matched := mu.Options.LibraryOptions[prefix]
+			override = &matched
+		}
+	}
+
+	options := mu.Options
+	if override != nil {
+		override.apply(&options)
+	}
+
+	if mu.libOptions == nil {
+		mu.libOptions = make(map[string]Options)
+	}
+	mu.libOptions[url] = options
+	return options
+}
+
+// anyLibraryOptions returns true if the run's options or any library's override enable the option read by enabled
+func (mu *MU) anyLibraryOptions(enabled func(options Options) bool) bool {
+	if enabled(mu.Options) {
+		return true
+	}
+
+	for _, override := range mu.Options.LibraryOptions {
+		options := mu.Options
+		override.apply(&options)
+		if enabled(options) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package gomu
+
+import (
+	"testing"
+)
+
+func TestLibraryOptions(t *testing.T) {
+	src := goSrc(t)
+	a, _ := requiringLibrary(t, src, "github.com/org/a")
+	tools, _ := requiringLibrary(t, src, "github.com/org/tools/lint")
+	other, _ := requiringLibrary(t, src, "github.com/other/b")
+
+	disabled, enabled := false, true
+	mu := &MU{Options: Options{Branch: "deps", Tag: true, PullRequest: true, LibraryOptions: map[string]LibraryOverride{
+		"github.com/org/":       {DraftPR: &enabled},
+		"github.com/org/tools/": {Branch: "tools-deps", Tag: &disabled, PullRequest: &disabled},
+	}}}
+
+	if options := mu.libraryOptions(*a); options.Branch != "deps" || !options.Tag || !options.PullRequest || !options.DraftPR {
+		t.Errorf("expected unset overrides to keep the run's options, got %+v", options)
+	}
+	// The longest prefix wins
+	if options := mu.libraryOptions(*tools); options.Branch != "tools-deps" || options.Tag || options.PullRequest || options.DraftPR {
+		t.Errorf("expected tools to be overridden, got %+v", options)
+	}
+	if options := mu.libraryOptions(*other); options.DraftPR {
+		t.Errorf("expected libs without overrides to keep the run's options, got %+v", options)
+	}
+	if branch := mu.branch(*tools); branch != "tools-deps" {
+		t.Errorf("expected the overridden branch, got %s", branch)
+	}
+
+	// Merged once per lib
+	mu.Options.LibraryOptions["github.com/org/"] = LibraryOverride{}
+	if options := mu.libraryOptions(*a); !options.DraftPR {
+		t.Error("expected the merged options to be kept")
+	}
+}
+
+func TestValidateLibraryOptions(t *testing.T) {
+	for _, test := range []struct {
+		overrides map[string]LibraryOverride
+		valid     bool
+	}{
+		{map[string]LibraryOverride{"github.com/org/a": {Branch: "release"}}, true},
+		{map[string]LibraryOverride{"/": {Branch: "release"}}, false},
+		{map[string]LibraryOverride{"github.com/org/a": {Branch: "deps-{{.Lib}}"}}, false},
+	} {
+		mu := &MU{Options: Options{LibraryOptions: test.overrides}}
+		if err := mu.validateLibraryOptions(); (err == nil) != test.valid {
+			t.Errorf("%v: expected valid %v, got %v", test.overrides, test.valid, err)
+		}
+	}
+}
+
+func TestAnyLibraryOptions(t *testing.T) {
+	enabled := true
+	pullRequest := func(options Options) bool { return options.PullRequest }
+
+	mu := &MU{}
+	if mu.anyLibraryOptions(pullRequest) {
+		t.Error("expected pull requests to be disabled")
+	}
+
+	mu.Options.LibraryOptions = map[string]LibraryOverride{"github.com/org/a": {PullRequest: &enabled}}
+	if !mu.anyLibraryOptions(pullRequest) {
+		t.Error("expected a lib's override to enable pull requests")
+	}
+}
@@ -0,0 +1,320 @@
+package gomu
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Licenses reported for modules whose license can't be identified
+const (
+	// LicenseNone is reported for modules without a license file
+	LicenseNone = "None"
+	// LicenseUnknown is reported for license files which don't match a known license
+	LicenseUnknown = "Unknown"
+)
+
+// ModuleLicense represents the license of a module required by a library
+type ModuleLicense struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	License string `json:"license"` // SPDX id, e.g. MIT or Apache-2.0
+	// File the license was detected from
+	File string `json:"file,omitempty"`
+}
+
+// LicenseReport represents the license of a module and every library requiring it
+type LicenseReport struct {
+	ModuleLicense
+	Libraries []string `json:"libraries"`
+	Denied    bool     `json:"denied,omitempty"`
+}
+
+// goModDownload represents a module in the output of go mod download -json
+type goModDownload struct {
+	Path    string `json:"Path"`
+	Version string `json:"Version"`
+	Dir     string `json:"Dir"`
+	Zip     string `json:"Zip"`
+	Error   string `json:"Error"`
+}
+
+// licenseMatchers identify licenses by phrases of their text, more specific licenses first.
+// Text is lowercased with whitespace collapsed before matching
+var licenseMatchers = []struct {
+	license string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"EPL-1.0", []string{"eclipse public license"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+}
+
+// identifyLicense returns the SPDX id of license text, LicenseUnknown if it doesn't match a known license
+func identifyLicense(text []byte) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(string(text))), " ")
+	for _, matcher := range licenseMatchers {
+		matched := true
+		for _, phrase := range matcher.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return matcher.license
+		}
+	}
+
+	return LicenseUnknown
+}
+
+// isLicenseFile returns true if name is a license file at the root of a module, e.g. LICENSE, LICENSE.md or COPYING
+func isLicenseFile(name string) bool {
+	name = strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// licenseFromDir detects the license from files embedded in an extracted module
+func licenseFromDir(dir string) (license, file string) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return LicenseNone, ""
+	}
+
+	license = LicenseNone
+	for _, entry := range entries {
+		if entry.IsDir() || !isLicenseFile(entry.Name()) {
+			continue
+		}
+
+		text, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		if license, file = identifyLicense(text), entry.Name(); license != LicenseUnknown {
+			return
+		}
+	}
+
+	return
+}
+
+// licenseFromZip detects the license from files in a module zip, for modules which weren't extracted
+func licenseFromZip(zipPath string) (license, file string) {
+	reader, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return LicenseNone, ""
+	}
+	defer reader.Close()
+
+	license = LicenseNone
+	for _, entry := range reader.File {
+		// Module zips contain module@version/file, module paths have slashes but versions don't
+		at := strings.Index(entry.Name, "@")
+		if at < 0 {
+			continue
+		}
+
+		comps := strings.SplitN(entry.Name[at+1:], "/", 2)
+		if len(comps) != 2 || strings.Contains(comps[1], "/") || !isLicenseFile(comps[1]) {
+			continue
+		}
+
+		contents, err := entry.Open()
+		if err != nil {
+			continue
+		}
+
+		text, err := ioutil.ReadAll(contents)
+		contents.Close()
+		if err != nil {
+			continue
+		}
+
+		if license, file = identifyLicense(text), comps[1]; license != LicenseUnknown {
+			return
+		}
+	}
+
+	return
+}
+
+// Licenses returns the license of every module in lib's build list, downloading modules which aren't cached
+func (lib *Library) Licenses() (licenses []ModuleLicense, err error) {
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "download", "-json", "all")
+	if err != nil {
+		err = fmt.Errorf("unable to download modules: %v", err)
+		return
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var module goModDownload
+		if err = decoder.Decode(&module); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse go mod download output: %v", err)
+			return
+		}
+
+		if len(module.Error) > 0 {
+			err = fmt.Errorf("unable to download %s@%s: %s", module.Path, module.Version, module.Error)
+			return
+		}
+
+		entry := ModuleLicense{Module: module.Path, Version: module.Version, License: LicenseNone}
+		if len(module.Dir) > 0 {
+			entry.License, entry.File = licenseFromDir(module.Dir)
+		} else if len(module.Zip) > 0 {
+			entry.License, entry.File = licenseFromZip(module.Zip)
+		}
+
+		licenses = append(licenses, entry)
+	}
+
+	return
+}
+
+// licenseDenied returns true if license matches a pattern of deny, ignoring case. Patterns may be globs, e.g. GPL-*
+func licenseDenied(license string, deny []string) bool {
+	for _, pattern := range deny {
+		if strings.EqualFold(pattern, license) {
+			return true
+		}
+
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(license)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (mu *MU) licenses(lib Library) {
+	lib.File.Output("Detecting dependency licenses...")
+
+	licenses, err := lib.Licenses()
+	if err != nil {
+		lib.File.Error("License inventory failed :( " + err.Error())
+		return
+	}
+
+	var denied []string
+	for _, license := range licenses {
+		if mu.addLicense(lib, license) {
+			denied = append(denied, license.Module+"@"+license.Version+" ("+license.License+")")
+		}
+	}
+
+	lib.File.Output("Found " + strconv.Itoa(len(licenses)) + " dependency license(s)")
+	mu.addStat(&mu.Stats.LicenseCount, &mu.Stats.LicenseOutput, lib.File.GetGoURL()+" ("+strconv.Itoa(len(licenses))+" deps)\n")
+
+	if len(denied) > 0 {
+		mu.addStat(&mu.Stats.DeniedCount, &mu.Stats.DeniedOutput, lib.File.GetGoURL()+": "+strings.Join(denied, ", ")+"\n")
+		mu.libraryError(lib, fmt.Errorf("denied license(s) in %s", strings.Join(denied, ", ")))
+	}
+}
+
+// addLicense aggregates license by module in stats, returning true if the license is denied
+func (mu *MU) addLicense(lib Library, license ModuleLicense) (denied bool) {
+	mu.statsMux.Lock()
+	defer mu.statsMux.Unlock()
+
+	if mu.Stats.Licenses == nil {
+		mu.Stats.Licenses = make(map[string]*LicenseReport)
+	}
+
+	key := license.Module + "@" + license.Version
+	report, ok := mu.Stats.Licenses[key]
+	if !ok {
+		report = &LicenseReport{ModuleLicense: license, Denied: licenseDenied(license.License, mu.Options.LicenseDeny)}
+		mu.Stats.Licenses[key] = report
+	}
+
+	report.Libraries = append(report.Libraries, lib.File.GetGoURL())
+	return report.Denied
+}
+
+// licenseReports returns the license of each module, sorted by module then version
+func (stats ActionStats) licenseReports() (reports []LicenseReport) {
+	for _, report := range stats.Licenses {
+		reports = append(reports, *report)
+	}
+
+	gosort.Slice(reports, func(i, j int) bool {
+		if reports[i].Module != reports[j].Module {
+			return reports[i].Module < reports[j].Module
+		}
+		return reports[i].Version < reports[j].Version
+	})
+	return
+}
+
+// formatLicenses returns the number of modules under each license, then a table of every module's license
+func (stats ActionStats) formatLicenses() string {
+	reports := stats.licenseReports()
+
+	counts := make(map[string]int)
+	var names []string
+	for _, report := range reports {
+		if counts[report.License] == 0 {
+			names = append(names, report.License)
+		}
+		counts[report.License]++
+	}
+	gosort.Strings(names)
+
+	var output bytes.Buffer
+	writer := tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LICENSE\tMODULES")
+	for _, name := range names {
+		fmt.Fprintln(writer, name+"\t"+strconv.Itoa(counts[name]))
+	}
+	writer.Flush()
+
+	output.WriteString("\n")
+	writer = tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "MODULE\tVERSION\tLICENSE\tLIBRARIES")
+	for _, report := range reports {
+		license := report.License
+		if report.Denied {
+			license += " (denied)"
+		}
+
+		fmt.Fprintln(writer, report.Module+"\t"+report.Version+"\t"+license+"\t"+strconv.Itoa(len(report.Libraries)))
+	}
+	writer.Flush()
+
+	return output.String()
+}
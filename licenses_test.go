@@ -0,0 +1,124 @@
+package gomu
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+const (
+	mitText    = "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy"
+	apacheText = "Apache License\n  Version 2.0, January 2004"
+	gplText    = "GNU GENERAL PUBLIC LICENSE\n   Version 3, 29 June 2007"
+)
+
+func TestIdentifyLicense(t *testing.T) {
+	for text, expected := range map[string]string{
+		mitText:    "MIT",
+		apacheText: "Apache-2.0",
+		gplText:    "GPL-3.0",
+		"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3":                                                      "LGPL-3.0",
+		"Redistribution and use in source and binary forms, with or without modification. Neither the name": "BSD-3-Clause",
+		"All rights reserved.": LicenseUnknown,
+	} {
+		if license := identifyLicense([]byte(text)); license != expected {
+			t.Errorf("expected %s for %q, got %s", expected, text, license)
+		}
+	}
+}
+
+func TestLicenseDenied(t *testing.T) {
+	deny := []string{"gpl-*", "AGPL-3.0"}
+	for license, expected := range map[string]bool{"GPL-3.0": true, "AGPL-3.0": true, "LGPL-2.1": false, "MIT": false} {
+		if denied := licenseDenied(license, deny); denied != expected {
+			t.Errorf("expected %s denied %v, got %v", license, expected, denied)
+		}
+	}
+}
+
+// downloadedModules writes a module extracted with a license and a zipped one, replying to go mod download with both
+func downloadedModules(t *testing.T, recorder *com.Recorder, zippedLicense string) {
+	dir, err := ioutil.TempDir("", "gomu-licenses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err = ioutil.WriteFile(filepath.Join(dir, "LICENSE.md"), []byte(mitText), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(dir, "v1.0.0.zip")
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(out)
+	for name, text := range map[string]string{"github.com/org/c@v1.0.0/COPYING": zippedLicense, "github.com/org/c@v1.0.0/sub/LICENSE": mitText} {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(text))
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	var output []byte
+	for _, module := range []goModDownload{
+		{Path: "github.com/org/b", Version: "v1.2.0", Dir: dir},
+		{Path: "github.com/org/c", Version: "v1.0.0", Zip: zipPath},
+		{Path: "github.com/org/d", Version: "v0.1.0"},
+	} {
+		data, _ := json.Marshal(module)
+		output = append(output, data...)
+		output = append(output, '\n')
+	}
+	recorder.Reply("go mod download -json all", com.Reply{Stdout: string(output)})
+}
+
+func TestLicenses(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	downloadedModules(t, recorder, apacheText)
+
+	licenses, err := lib.Licenses()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ModuleLicense{
+		{Module: "github.com/org/b", Version: "v1.2.0", License: "MIT", File: "LICENSE.md"},
+		{Module: "github.com/org/c", Version: "v1.0.0", License: "Apache-2.0", File: "COPYING"},
+		{Module: "github.com/org/d", Version: "v0.1.0", License: LicenseNone},
+	}
+	if len(licenses) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, licenses)
+	}
+	for i := range expected {
+		if licenses[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], licenses[i])
+		}
+	}
+}
+
+func TestLicensesDenied(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	downloadedModules(t, recorder, gplText)
+
+	mu := &MU{Options: Options{Action: "licenses", LicenseDeny: []string{"GPL-*"}}}
+	mu.licenses(*lib)
+
+	if mu.Stats.LicenseCount != 1 || mu.Stats.DeniedCount != 1 || len(mu.Errors) != 1 {
+		t.Fatalf("expected a denied license to fail the lib, got %d denied (%v)", mu.Stats.DeniedCount, mu.Errors)
+	}
+	if report := mu.Stats.Licenses["github.com/org/c@v1.0.0"]; report == nil || !report.Denied || report.License != "GPL-3.0" {
+		t.Errorf("expected github.com/org/c to be denied, got %+v", report)
+	}
+}
@@ -0,0 +1,534 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	gosort "sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// majorVersionPattern matches semver versions, capturing the major version, e.g. 2 of v2.0.0 or v2.0.0-beta.1
+var majorVersionPattern = regexp.MustCompile(`^v(\d+)\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// majorImportSuffix matches a major version suffix at the start of an import path below a module's base path,
+// e.g. /v2 of /v2/pkg. Majors below v2 aren't suffixed, so /v1/pkg is a package
+var majorImportSuffix = regexp.MustCompile(`^/v([2-9]|[1-9][0-9]+)(/|$)`)
+
+// gopkgInImportSuffix matches the major version suffix at the start of an import path below a gopkg.in module's base
+// path, e.g. .v2 of .v2/pkg
+var gopkgInImportSuffix = regexp.MustCompile(`^\.v[0-9]+(/|$)`)
+
+// majorRelease represents a lib released at a new major version by the major action
+type majorRelease struct {
+	Base       string // Module path without a major suffix, e.g. github.com/org/lib
+	ModulePath string // Module path of the new major, e.g. github.com/org/lib/v2
+	Version    string
+	Ref        string // Ref go resolves the version with
+	Published  bool   // Tagged and pushed, so dependents can get it
+}
+
+// majorRewrite represents a release a lib requires at previous module paths
+type majorRewrite struct {
+	majorRelease
+	Previous []string
+}
+
+// majorOf returns the major version of a semver version (v2.1.0 -> 2). False if version isn't semver
+func majorOf(version string) (major int, ok bool) {
+	match := majorVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return
+	}
+
+	major, err := strconv.Atoi(match[1])
+	return major, err == nil
+}
+
+// validateMajorLibs checks each sync lib sets the major version it's released at, e.g. github.com/org/lib@v2.0.0
+func validateMajorLibs(libs sort.StringArray) (err error) {
+	if len(libs) == 0 {
+		return fmt.Errorf("no libs to release, set sync libs with their new major version, e.g. github.com/org/lib@v2.0.0")
+	}
+
+	for _, lib := range libs {
+		index := strings.LastIndex(lib, "@")
+		if index < 0 {
+			return fmt.Errorf("no major version set for %s, expected e.g. %s@v2.0.0", lib, lib)
+		}
+
+		if major, ok := majorOf(lib[index+1:]); !ok || major < 2 {
+			return fmt.Errorf("invalid major version %q for %s, expected v2.0.0 or later", lib[index+1:], lib[:index])
+		}
+	}
+
+	return
+}
+
+// readModule returns the module path of lib's go.mod and the modules it requires
+func (lib *Library) readModule() (modulePath string, requires []string, err error) {
+	if _, err = os.Stat(filepath.Join(lib.File.Path, "go.mod")); err != nil {
+		return
+	}
+
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		err = fmt.Errorf("unable to read go.mod: %v", err)
+		return
+	}
+
+	var modFile struct {
+		Module  ModVersion     `json:"Module"`
+		Require []goModRequire `json:"Require"`
+	}
+	if err = json.Unmarshal([]byte(output), &modFile); err != nil {
+		err = fmt.Errorf("unable to parse go.mod: %v", err)
+		return
+	}
+
+	for _, req := range modFile.Require {
+		requires = append(requires, req.Path)
+	}
+
+	return modFile.Module.Path, requires, nil
+}
+
+// rewriteImportPath returns importPath within the module at base, at any major, moved to modulePath.
+// False if importPath isn't within the module or is already moved
+func rewriteImportPath(importPath, base, modulePath string) (rewritten string, ok bool) {
+	if !strings.HasPrefix(importPath, base) {
+		return
+	}
+
+	suffix := majorImportSuffix
+	if strings.HasPrefix(base, com.GopkgInPrefix) {
+		suffix = gopkgInImportSuffix
+	}
+
+	rest := importPath[len(base):]
+	if major := suffix.FindString(rest); len(major) > 0 {
+		// Moving between majors, e.g. /v2/pkg -> /v3/pkg
+		rest = strings.TrimPrefix(rest, strings.TrimSuffix(major, "/"))
+	} else if len(rest) > 0 && rest[0] != '/' {
+		// Another module base is a prefix of, e.g. github.com/org/libs of github.com/org/lib
+		return
+	}
+
+	rewritten = modulePath + rest
+	return rewritten, rewritten != importPath
+}
+
+// RewriteImports rewrites imports of the module at base, at any major, to modulePath in each go file of lib.
+// Vendored packages, test data and nested modules are left as they are. Returns the number of files rewritten
+func (lib *Library) RewriteImports(base, modulePath string) (rewritten int, err error) {
+	root := lib.File.Path
+	err = walkGoFiles(root, func(path string) error {
+		changed, err := lib.rewriteFileImports(root, path, base, modulePath)
+		if changed {
+			rewritten++
+		}
+
+		return err
+	})
+
+	return
+}
+
+// walkGoFiles calls fn with the path of each go file of the module at root, skipping vendored packages, test data,
+// hidden directories and nested modules
+func walkGoFiles(root string, fn func(path string) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path == root {
+				return nil
+			}
+
+			name := info.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				// Nested modules have their own requires
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		return fn(path)
+	})
+}
+
+// rewriteFileImports rewrites imports of the module at base in the go file at path, keeping its formatting
+func (lib *Library) rewriteFileImports(root, path, base, modulePath string) (changed bool, err error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	fileSet := token.NewFileSet()
+	parsed, err := parser.ParseFile(fileSet, path, src, parser.ImportsOnly)
+	if err != nil {
+		err = fmt.Errorf("unable to parse %s: %v", path, err)
+		return
+	}
+
+	type replacement struct {
+		start, end int
+		value      string
+	}
+
+	var replacements []replacement
+	for _, spec := range parsed.Imports {
+		importPath, unquoteErr := strconv.Unquote(spec.Path.Value)
+		if unquoteErr != nil {
+			continue
+		}
+
+		if rewritten, ok := rewriteImportPath(importPath, base, modulePath); ok {
+			replacements = append(replacements, replacement{
+				start: fileSet.Position(spec.Path.Pos()).Offset,
+				end:   fileSet.Position(spec.Path.End()).Offset,
+				value: strconv.Quote(rewritten),
+			})
+		}
+	}
+
+	if len(replacements) == 0 {
+		return
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	gosort.Slice(replacements, func(i, j int) bool { return replacements[i].start > replacements[j].start })
+	for _, r := range replacements {
+		src = append(src[:r.start], append([]byte(r.value), src[r.end:]...)...)
+	}
+
+	name, err := filepath.Rel(root, path)
+	if err != nil {
+		return
+	}
+
+	return true, lib.File.WriteFile(name, src)
+}
+
+// majorRewrites returns releases earlier in the chain which lib still requires at previous module paths
+func (mu *MU) majorRewrites(lib Library, requires []string, fileHead *sort.FileNode) (rewrites []majorRewrite) {
+	for itr := fileHead; itr != nil && itr.File != lib.File; itr = itr.Next {
+		mu.libraryMux.Lock()
+		release, ok := mu.majors[itr.File.GetGoURL()]
+		mu.libraryMux.Unlock()
+		if !ok {
+			continue
+		}
+
+		rewrite := majorRewrite{majorRelease: release}
+		for _, required := range requires {
+			if com.ModuleBase(required) == release.Base && required != release.ModulePath {
+				rewrite.Previous = append(rewrite.Previous, required)
+			}
+		}
+
+		if len(rewrite.Previous) > 0 {
+			rewrites = append(rewrites, rewrite)
+		}
+	}
+
+	return
+}
+
+// chainedDeps returns deps earlier in the chain lib directly imports which were tagged this run, other than releases
+func (mu *MU) chainedDeps(lib Library, fileHead *sort.FileNode) (deps []*com.FileWrapper) {
+	for itr := fileHead; itr != nil && itr.File != lib.File; itr = itr.Next {
+		mu.libraryMux.Lock()
+		_, released := mu.majors[itr.File.GetGoURL()]
+		mu.libraryMux.Unlock()
+
+		if itr.File.Tagged && !released && lib.File.DirectlyImports(itr.File) {
+			deps = append(deps, itr.File)
+		}
+	}
+
+	return
+}
+
+// major releases lib at the major version set for it, moving its module path to the major's suffix (/v2) along with
+// its own imports. Libs requiring majors released earlier in the chain have their imports and requires moved to them.
+// When tagging, updated libs are tagged and required by their dependents, chaining the changes through
+func (mu *MU) major(lib Library, fileHead *sort.FileNode) {
+	modulePath, requires, err := lib.readModule()
+	if os.IsNotExist(err) {
+		lib.File.Output("No mod file found. Skipping.")
+		return
+	} else if err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	var release *majorRelease
+	if major, ok := majorOf(lib.File.Version); ok && major >= 2 {
+		// Version was set by the sync lib
+		base := com.ModuleBase(modulePath)
+		release = &majorRelease{Base: base, ModulePath: com.MajorModulePath(base, major), Version: lib.File.Version}
+		release.Ref = lib.ModRef(release.Version)
+
+		// Dependents fail rather than keep the previous major if it's never published
+		mu.libraryMux.Lock()
+		mu.majors[lib.File.GetGoURL()] = *release
+		mu.libraryMux.Unlock()
+	}
+
+	rewrites := mu.majorRewrites(lib, requires, fileHead)
+	var chained []*com.FileWrapper
+	if mu.Options.Commit {
+		chained = mu.chainedDeps(lib, fileHead)
+	}
+
+	if release == nil && len(rewrites) == 0 && len(chained) == 0 {
+		lib.File.Output("No major versions to require.")
+		return
+	}
+
+	for _, rewrite := range rewrites {
+		if mu.Options.Commit && !rewrite.Published {
+			mu.libraryError(lib, fmt.Errorf("%s isn't tagged %s yet, require it once released", rewrite.ModulePath, rewrite.Version))
+			return
+		}
+	}
+
+	if mu.Options.Commit {
+		if mu.checkoutBranch(lib) != nil {
+			// Don't make changes which can't be pushed
+			return
+		}
+	}
+
+	var changes []string
+	if release != nil && release.ModulePath != modulePath {
+		lib.File.Output("Moving module path to " + release.ModulePath + "...")
+		if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "edit", "-module="+release.ModulePath); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to set module path: %v", err))
+			return
+		}
+
+		if _, err = lib.RewriteImports(release.Base, release.ModulePath); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to rewrite imports: %v", err))
+			return
+		}
+
+		changes = append(changes, modulePath+" -> "+release.ModulePath)
+	} else if release != nil {
+		lib.File.Output("Module path already " + release.ModulePath)
+	}
+
+	rewritten, ok := mu.requireRewrites(lib, rewrites)
+	if !ok {
+		return
+	}
+	changes = append(changes, rewritten...)
+
+	if rewritten, ok = mu.requireChained(lib, chained); !ok {
+		return
+	}
+	changes = append(changes, rewritten...)
+
+	if mu.Options.Commit && (len(rewrites) > 0 || len(chained) > 0) {
+		if err = lib.ModTidy(); err != nil {
+			mu.libraryError(lib, fmt.Errorf("go mod tidy failed: %v", err))
+			return
+		}
+	}
+
+	change := strings.Join(changes, ", ")
+	if len(changes) > 0 {
+		lib.File.Updated = true
+		mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+change+")\n")
+	}
+
+	if !mu.Options.Commit {
+		lib.File.Output("Module paths updated!")
+		return
+	}
+
+	if len(changes) > 0 && !mu.commitMajor(lib, release, rewrites, change) {
+		return
+	}
+
+	if len(lib.File.ProtectedBranch) > 0 {
+		if release != nil {
+			lib.File.Output("Tag " + lib.TagName(release.Version) + " once the pull request is merged.")
+		}
+		return
+	}
+
+	if release != nil {
+		mu.tagMajor(lib, *release)
+	} else {
+		// Dependents require the tagged lib, chaining the changes through
+		mu.tag(lib)
+	}
+}
+
+// requireRewrites drops lib's requires of each rewrite's previous module paths, moving its imports to the rewrite's
+// module path and requiring it. Returns the changes made, false if one failed
+func (mu *MU) requireRewrites(lib Library, rewrites []majorRewrite) (changes []string, ok bool) {
+	for _, rewrite := range rewrites {
+		lib.File.Output("Requiring " + rewrite.ModulePath + " @ " + rewrite.Version + "...")
+		args := []string{lib.File.GoBinary(), "mod", "edit"}
+		for _, previous := range rewrite.Previous {
+			args = append(args, "-droprequire="+previous)
+		}
+		if !mu.Options.Commit {
+			// Unpublished until committed, so only set in go.mod
+			args = append(args, "-require="+rewrite.ModulePath+"@"+rewrite.Version)
+		}
+
+		if err := lib.File.RunCmd(args...); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to drop requires of %s: %v", strings.Join(rewrite.Previous, ", "), err))
+			return changes, false
+		}
+
+		if _, err := lib.RewriteImports(rewrite.Base, rewrite.ModulePath); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to rewrite imports: %v", err))
+			return changes, false
+		}
+
+		if mu.Options.Commit {
+			if err := lib.File.RunCmd(lib.File.GoBinary(), "get", "-d", rewrite.ModulePath+"@"+rewrite.Ref); err != nil {
+				mu.libraryError(lib, fmt.Errorf("unable to get %s @ %s: %v", rewrite.ModulePath, rewrite.Version, err))
+				return changes, false
+			}
+		}
+
+		lib.File.UpdatedDeps = append(lib.File.UpdatedDeps, rewrite.ModulePath+"@"+rewrite.Version)
+		changes = append(changes, strings.Join(rewrite.Previous, ", ")+" -> "+rewrite.ModulePath+" "+rewrite.Version)
+	}
+
+	return changes, true
+}
+
+// requireChained requires deps tagged earlier in the chain in lib. Returns the changes made, false if one failed
+func (mu *MU) requireChained(lib Library, chained []*com.FileWrapper) (changes []string, ok bool) {
+	for _, dep := range chained {
+		tempLib := Library{File: dep}
+		url := dep.GetGoURL()
+		if err := lib.File.RunCmd(lib.File.GoBinary(), "get", "-d", url+"@"+tempLib.ModRef(dep.Version)); err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to get %s @ %s: %v", url, dep.Version, err))
+			return changes, false
+		}
+
+		lib.File.Output("Updated " + url + " @ " + dep.Version)
+		lib.File.UpdatedDeps = append(lib.File.UpdatedDeps, url+"@"+dep.Version)
+		changes = append(changes, url+" "+dep.Version)
+	}
+
+	return changes, true
+}
+
+// commitMajor commits and pushes lib's module path changes of new majors or moved modules, opening a pull request.
+// Returns false if it failed
+func (mu *MU) commitMajor(lib Library, release *majorRelease, rewrites []majorRewrite, change string) (committed bool) {
+	commitTitle := mu.Options.CommitMessage
+	if len(commitTitle) == 0 {
+		if release != nil {
+			commitTitle = "Release " + release.ModulePath
+		} else {
+			var paths []string
+			for _, rewrite := range rewrites {
+				paths = append(paths, rewrite.ModulePath)
+			}
+			if len(paths) == 0 {
+				commitTitle = "Update deps"
+			} else {
+				commitTitle = "Require " + strings.Join(paths, ", ")
+			}
+		}
+	}
+	commitTitle = "gomu: " + commitTitle
+
+	if err := mu.setCommitKey(&lib); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if lib.File.Add(".") != nil || lib.commit(commitTitle+"\n\n"+change) != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to commit module path changes"))
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	if err := lib.File.Push(); err != nil {
+		mu.libraryError(lib, fmt.Errorf("push failed, check local changes and commit status: %v", err))
+		return
+	}
+
+	lib.File.Committed = true
+	mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, lib.File.GetGoURL()+"\n")
+	lib.File.Output("Module path changes committed!")
+
+	if len(lib.File.ProtectedBranch) == 0 {
+		mu.pullRequest(lib, mu.branch(lib), commitTitle, change)
+	} else {
+		// Changes to protected branches are only reviewed through a pull request
+		mu.requestPR(lib, FallbackBranch, commitTitle, change)
+	}
+
+	mu.removeBranchIfUnused(lib)
+	return true
+}
+
+// tagMajor tags lib's head with the release's version, so dependents can require the new major
+func (mu *MU) tagMajor(lib Library, release majorRelease) {
+	var key string
+	var err error
+	if mu.Options.SignTags {
+		if key, err = lib.signingKey("tag", mu.Options.SigningKey); err != nil {
+			mu.libraryError(lib, err)
+			return
+		}
+	}
+
+	previousVersion := lib.GetLatestTag()
+	name := lib.TagName(release.Version)
+	lib.File.Output("Tagging " + name + "...")
+	if err = lib.tagCommit(name, lib.File.HeadCommit(), key); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	mu.recordOperation(lib, Operation{Type: OpTag, Tag: name})
+	lib.File.PreviousVersion = previousVersion
+	lib.File.Tagged = true
+	lib.File.TagSigned = len(key) > 0
+
+	release.Published = true
+	mu.libraryMux.Lock()
+	mu.majors[lib.File.GetGoURL()] = release
+	mu.libraryMux.Unlock()
+
+	line := lib.File.GetGoURL() + " " + orNone(previousVersion) + " -> " + release.Version + " (" + release.ModulePath + ")"
+	if lib.File.TagSigned {
+		line += " (signed)"
+	}
+	mu.addStat(&mu.Stats.MajorCount, &mu.Stats.MajorOutput, line+"\n")
+	lib.File.Output("Released " + release.ModulePath + " @ " + release.Version)
+}
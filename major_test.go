@@ -0,0 +1,118 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestValidateMajorLibs(t *testing.T) {
+	for _, test := range []struct {
+		libs  sort.StringArray
+		valid bool
+	}{
+		{sort.StringArray{"github.com/org/lib@v2.0.0"}, true},
+		{sort.StringArray{"github.com/org/lib@v10.1.0-beta.1"}, true},
+		{sort.StringArray{"github.com/org/lib@v2.0.0", "github.com/org/b@v3.0.0"}, true},
+		{nil, false},
+		{sort.StringArray{"github.com/org/lib"}, false},
+		{sort.StringArray{"github.com/org/lib@v1.2.0"}, false},
+		{sort.StringArray{"github.com/org/lib@v2"}, false},
+		{sort.StringArray{"github.com/org/lib@v2.0.0", "github.com/org/b@latest"}, false},
+	} {
+		if err := validateMajorLibs(test.libs); (err == nil) != test.valid {
+			t.Errorf("%v: expected valid %v, got %v", test.libs, test.valid, err)
+		}
+	}
+}
+
+func TestRewriteImportPath(t *testing.T) {
+	for _, test := range []struct {
+		importPath string
+		base       string
+		modulePath string
+		rewritten  string
+		ok         bool
+	}{
+		// Suffixed majors
+		{"github.com/org/lib", "github.com/org/lib", "github.com/org/lib/v2", "github.com/org/lib/v2", true},
+		{"github.com/org/lib/pkg", "github.com/org/lib", "github.com/org/lib/v2", "github.com/org/lib/v2/pkg", true},
+		{"github.com/org/lib/v2", "github.com/org/lib", "github.com/org/lib/v3", "github.com/org/lib/v3", true},
+		{"github.com/org/lib/v2/pkg", "github.com/org/lib", "github.com/org/lib/v3", "github.com/org/lib/v3/pkg", true},
+		{"github.com/org/lib/v12/pkg", "github.com/org/lib", "github.com/org/lib/v13", "github.com/org/lib/v13/pkg", true},
+		{"github.com/org/lib/v1/pkg", "github.com/org/lib", "github.com/org/lib/v2", "github.com/org/lib/v2/v1/pkg", true},
+		{"github.com/org/lib/v2/pkg", "github.com/org/lib", "github.com/org/lib/v2", "github.com/org/lib/v2/pkg", false},
+		// gopkg.in majors
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml", "gopkg.in/yaml.v3", "gopkg.in/yaml.v3", true},
+		{"gopkg.in/yaml.v2/pkg", "gopkg.in/yaml", "gopkg.in/yaml.v3", "gopkg.in/yaml.v3/pkg", true},
+		{"gopkg.in/yaml.v3/pkg", "gopkg.in/yaml", "gopkg.in/yaml.v3", "gopkg.in/yaml.v3/pkg", false},
+		{"gopkg.in/yamlx.v2", "gopkg.in/yaml", "gopkg.in/yaml.v3", "", false},
+		// Other modules
+		{"github.com/org/library", "github.com/org/lib", "github.com/org/lib/v2", "", false},
+		{"github.com/org/library/v2", "github.com/org/lib", "github.com/org/lib/v3", "", false},
+		{"github.com/org/lib2/pkg", "github.com/org/lib", "github.com/org/lib/v2", "", false},
+		{"github.com/other/lib", "github.com/org/lib", "github.com/org/lib/v2", "", false},
+	} {
+		rewritten, ok := rewriteImportPath(test.importPath, test.base, test.modulePath)
+		if ok != test.ok || (ok && rewritten != test.rewritten) {
+			t.Errorf("%s: expected %q, %v, got %q, %v", test.importPath, test.rewritten, test.ok, rewritten, ok)
+		}
+	}
+}
+
+func TestRewriteImports(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	root := lib.File.Path
+
+	const source = `package a
+
+import (
+	"fmt"
+
+	b "github.com/org/b/pkg"
+	"github.com/org/lib/v2/util"
+)
+
+var _ = fmt.Sprint(b.X, util.Y)
+`
+	for _, name := range []string{"a.go", "sub/a.go", "vendor/a.go", "testdata/a.go", "nested/a.go"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "nested", "go.mod"), []byte("module github.com/org/a/nested\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rewritten, err := lib.RewriteImports("github.com/org/lib", "github.com/org/lib/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewritten != 2 {
+		t.Errorf("expected 2 files rewritten, got %d", rewritten)
+	}
+
+	expected := strings.Replace(source, "github.com/org/lib/v2/util", "github.com/org/lib/v3/util", 1)
+	for name, content := range map[string]string{
+		"a.go":          expected,
+		"sub/a.go":      expected,
+		"vendor/a.go":   source,
+		"testdata/a.go": source,
+		"nested/a.go":   source,
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, content, data)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package gomu
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// ManifestEntry represents a library listed in a manifest
+type ManifestEntry struct {
+	// Path of the library, defaults to its go-style directory in the workspace if URL is set
+	Path string
+	// URL the library is cloned from if Path doesn't exist
+	URL string
+	// Branch checked out after cloning, defaults to the remote's default branch
+	Branch string
+}
+
+// LoadManifest reads the libraries listed in a manifest. Yaml manifests (.yaml or .yml) list libraries by name:
+//
+//	workspace: ~/go/src
+//	libraries:
+//	  lib:
+//	    url: git@github.com:org/lib.git
+//	    path: ../lib
+//	    branch: main
+//
+// Other manifests list a path or clone url on each line, ignoring blank lines and # comments.
+// Relative paths are joined with the directory of the manifest
+func LoadManifest(manifestPath string) (entries []ManifestEntry, workspace string, err error) {
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return
+	}
+
+	switch strings.ToLower(filepath.Ext(manifestPath)) {
+	case ".yaml", ".yml":
+		entries, workspace, err = parseYAMLManifest(data)
+	default:
+		entries = parseManifest(data)
+	}
+
+	if err != nil {
+		err = fmt.Errorf("unable to parse %s: %v", manifestPath, err)
+		return
+	}
+
+	dir := filepath.Dir(manifestPath)
+	for i := range entries {
+		if len(entries[i].Path) > 0 {
+			entries[i].Path = manifestPathFor(dir, entries[i].Path)
+		}
+	}
+	if len(workspace) > 0 {
+		workspace = manifestPathFor(dir, workspace)
+	}
+
+	return
+}
+
+// manifestPathFor expands ~ and joins relative paths with the directory of the manifest
+func manifestPathFor(dir, value string) string {
+	if strings.HasPrefix(value, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, value[2:])
+		}
+	}
+
+	if filepath.IsAbs(value) {
+		return value
+	}
+
+	return filepath.Join(dir, value)
+}
+
+// parseManifest parses a path or clone url on each line
+func parseManifest(data []byte) (entries []ManifestEntry) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		if com.IsRemoteURL(line) {
+			entries = append(entries, ManifestEntry{URL: line})
+		} else {
+			entries = append(entries, ManifestEntry{Path: line})
+		}
+	}
+
+	return
+}
+
+// parseYAMLManifest parses the workspace and libraries of a yaml manifest
+func parseYAMLManifest(data []byte) (entries []ManifestEntry, workspace string, err error) {
+	manifest, err := parseYAML(data)
+	if err != nil {
+		return
+	}
+
+	if value, ok := manifest["workspace"]; ok {
+		if workspace, ok = value.(string); !ok {
+			err = fmt.Errorf("workspace must be a path")
+			return
+		}
+	}
+
+	switch libraries := manifest["libraries"].(type) {
+	case []interface{}:
+		// Paths or clone urls without metadata
+		for _, value := range libraries {
+			entry := fmt.Sprint(value)
+			if com.IsRemoteURL(entry) {
+				entries = append(entries, ManifestEntry{URL: entry})
+			} else {
+				entries = append(entries, ManifestEntry{Path: entry})
+			}
+		}
+	case map[string]interface{}:
+		for name, value := range libraries {
+			fields, _ := value.(map[string]interface{})
+			entry := ManifestEntry{
+				Path:   stringField(fields, "path"),
+				URL:    stringField(fields, "url"),
+				Branch: stringField(fields, "branch"),
+			}
+
+			if len(entry.Path) == 0 && len(entry.URL) == 0 {
+				err = fmt.Errorf("library %s needs a path or url", name)
+				return
+			}
+
+			entries = append(entries, entry)
+		}
+	case nil:
+	default:
+		err = fmt.Errorf("libraries must be a list or map")
+	}
+
+	return
+}
+
+// stringField returns the string value of key in fields, empty if missing
+func stringField(fields map[string]interface{}, key string) string {
+	if value, ok := fields[key]; ok && value != nil {
+		return fmt.Sprint(value)
+	}
+
+	return ""
+}
+
+// populateLibsFromManifest sets mu.AllDirectories to the libraries listed in mu.Options.ManifestPath,
+// cloning any which don't exist. Returns false if the manifest can't be used
+func (mu *MU) populateLibsFromManifest() (ok bool) {
+	entries, workspace, err := LoadManifest(mu.Options.ManifestPath)
+	if err != nil {
+		mu.configError(fmt.Errorf("unable to load manifest: %v", err))
+		return
+	}
+
+	workspace = mu.workspace(workspace)
+	libs := make(sort.StringArray, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.Path) == 0 {
+			dir := com.RemoteDir(entry.URL)
+			if len(dir) == 0 {
+				mu.configError(fmt.Errorf("unable to parse clone url %s", entry.URL))
+				return
+			}
+			entry.Path = filepath.Join(workspace, dir)
+		}
+
+		if _, err = os.Stat(entry.Path); err == nil {
+			libs = append(libs, entry.Path)
+			continue
+		}
+
+		if len(entry.URL) == 0 {
+			mu.configError(fmt.Errorf("%s not found and has no url to clone", entry.Path))
+			return
+		}
+
+		if err = mu.cloneLibrary(entry); err != nil {
+			mu.Errors = append(mu.Errors, err)
+			return
+		}
+
+		libs = append(libs, entry.Path)
+	}
+
+	if len(mu.Options.IncludePatterns) > 0 || len(mu.Options.ExcludePatterns) > 0 {
+		if libs, err = filterLibs(libs, mu.Options.IncludePatterns, mu.Options.ExcludePatterns); err != nil {
+			// Don't act on unintended libs
+			com.Println("Unable to filter libs:", err)
+			mu.configError(err)
+			return
+		}
+	}
+
+	mu.AllDirectories = libs
+	return true
+}
+
+// workspace returns the directory libs are cloned or created in: the workspace option, otherwise the manifest's
+// workspace if set, otherwise $GOPATH/src
+func (mu *MU) workspace(manifestWorkspace string) string {
+	if len(mu.Options.WorkspaceDir) > 0 {
+		return mu.Options.WorkspaceDir
+	}
+
+	if len(manifestWorkspace) > 0 {
+		return manifestWorkspace
+	}
+
+	// Go urls are read from paths within go/src
+	return filepath.Join(build.Default.GOPATH, "src")
+}
+
+// cloneFlags returns flags libs are cloned with. Read-only actions don't need history, so clones are shallow.
+// Others need tags and commits since them, so clones are partial, fetching blobs as needed
+func (mu *MU) cloneFlags() []string {
+	if mu.Options.FullHistory {
+		return nil
+	}
+
+	switch mu.Options.Action {
+	case "audit", "check", "conflicts", "graph", "grep", "licenses", "list", "outdated", "secret":
+		return []string{"--depth=1", "--no-single-branch"}
+	default:
+		return []string{"--filter=blob:none"}
+	}
+}
+
+// cloneLibrary clones entry into its path, checking out its branch if set
+func (mu *MU) cloneLibrary(entry ManifestEntry) (err error) {
+	file := com.FileWrapper{Path: entry.Path}
+	file.SetContext(mu.ctx)
+	file.Output("Cloning " + entry.URL + "...")
+
+	if err = file.Clone(entry.URL, mu.cloneFlags()...); err != nil {
+		return fmt.Errorf("unable to clone %s: %v", entry.URL, err)
+	}
+
+	if len(entry.Branch) > 0 {
+		if err = file.RunCmd("git", "checkout", entry.Branch); err != nil {
+			return fmt.Errorf("unable to checkout %s in %s: %v", entry.Branch, entry.Path, err)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,115 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// manifestDir returns a temporary directory with a manifest named name and an existing lib a
+func manifestDir(t *testing.T, name, manifest string) (dir, manifestPath string) {
+	dir, err := ioutil.TempDir("", "gomu-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err = os.Mkdir(filepath.Join(dir, "a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manifestPath = filepath.Join(dir, name)
+	if err = ioutil.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir, manifestPath := manifestDir(t, "libs.txt", "# Services\na\n/src/b # absolute\n\ngit@github.com:org/c.git\n")
+
+	entries, workspace, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ManifestEntry{{Path: filepath.Join(dir, "a")}, {Path: "/src/b"}, {URL: "git@github.com:org/c.git"}}
+	if !reflect.DeepEqual(entries, expected) || len(workspace) > 0 {
+		t.Errorf("expected %+v, got %+v in workspace %q", expected, entries, workspace)
+	}
+}
+
+func TestLoadYAMLManifest(t *testing.T) {
+	dir, manifestPath := manifestDir(t, "libs.yaml", `workspace: src
+libraries:
+  c:
+    url: git@github.com:org/c.git
+    branch: main
+`)
+
+	entries, workspace, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ManifestEntry{{URL: "git@github.com:org/c.git", Branch: "main"}}
+	if !reflect.DeepEqual(entries, expected) || workspace != filepath.Join(dir, "src") {
+		t.Errorf("expected %+v in src, got %+v in workspace %q", expected, entries, workspace)
+	}
+
+	if err = ioutil.WriteFile(manifestPath, []byte("libraries:\n  c:\n    branch: main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = LoadManifest(manifestPath); err == nil {
+		t.Error("expected library without a path or url to fail")
+	}
+}
+
+func TestPopulateLibsFromManifest(t *testing.T) {
+	dir, manifestPath := manifestDir(t, "libs.txt", "a\ngit@github.com:org/c.git\n")
+	recorder := &com.Recorder{}
+	com.SetRunner(recorder)
+	t.Cleanup(func() { com.SetRunner(nil) })
+
+	workspace := filepath.Join(dir, "src")
+	mu := &MU{Options: Options{Action: "list", ManifestPath: manifestPath, WorkspaceDir: workspace}}
+	if !mu.populateLibsFromManifest() {
+		t.Fatalf("expected manifest to be used, got %v", mu.Errors)
+	}
+
+	cloned := filepath.Join(workspace, "github.com", "org", "c")
+	if expected := (sort.StringArray{filepath.Join(dir, "a"), cloned}); !reflect.DeepEqual(mu.AllDirectories, expected) {
+		t.Errorf("expected %q, got %q", expected, mu.AllDirectories)
+	}
+
+	// Read-only actions clone shallowly
+	commands := recorder.Commands()
+	if len(commands) != 1 || commands[0].String() != "git clone --depth=1 --no-single-branch git@github.com:org/c.git c" || commands[0].Dir != filepath.Dir(cloned) {
+		t.Errorf("expected missing lib to be cloned into the workspace, got %v", commands)
+	}
+}
+
+func TestCloneFlags(t *testing.T) {
+	for _, test := range []struct {
+		options Options
+		flags   []string
+	}{
+		{Options{Action: "list"}, []string{"--depth=1", "--no-single-branch"}},
+		{Options{Action: "grep"}, []string{"--depth=1", "--no-single-branch"}},
+		{Options{Action: "sync"}, []string{"--filter=blob:none"}},
+		{Options{Action: "tag"}, []string{"--filter=blob:none"}},
+		{Options{Action: "list", FullHistory: true}, nil},
+		{Options{Action: "sync", FullHistory: true}, nil},
+	} {
+		mu := &MU{Options: test.options}
+		if flags := mu.cloneFlags(); !reflect.DeepEqual(flags, test.flags) {
+			t.Errorf("%s (full history %v): expected %v, got %v", test.options.Action, test.options.FullHistory, test.flags, flags)
+		}
+	}
+}
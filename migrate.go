@@ -0,0 +1,367 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	gosort "sort"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// How moved modules are detected
+const (
+	// MoveConfig is a move set in Options.ModuleMoves
+	MoveConfig = "config"
+	// MoveModulePath is a lib declaring a different module path than the one it's found at, e.g. after a repo rename
+	MoveModulePath = "module path"
+	// MoveRedirect is a module served with a different path than it's required at
+	MoveRedirect = "redirect"
+	// MoveDeprecated is a module deprecated in favour of another path, e.g. an archived repo
+	MoveDeprecated = "deprecated"
+)
+
+// declaredPathPattern matches go's error for modules redirected to another path, capturing the declared then required path
+var declaredPathPattern = regexp.MustCompile(`module declares its path as: (\S+)\s+but was required as: (\S+)`)
+
+// deprecatedMovePattern matches the module path a deprecation message points to, e.g. "Deprecated: use github.com/org/new"
+var deprecatedMovePattern = regexp.MustCompile(`(?i)\b(?:use|moved to|renamed to|replaced by)\s+([a-z0-9][a-z0-9.-]*\.[a-z]{2,}(?:/[\w.~-]+)+)`)
+
+// ModuleMove represents a module whose path moved, required at From by libs yet to migrate
+type ModuleMove struct {
+	From   string `json:"from"` // Previous module path, without a major suffix
+	To     string `json:"to"`   // New module path, without a major suffix
+	Reason string `json:"reason"`
+
+	// Lib found at the new path, nil if it isn't a sibling
+	lib *com.FileWrapper
+}
+
+// goListQuery represents a module in the output of go list -m -e -json module@version
+type goListQuery struct {
+	Path       string `json:"Path"`
+	Version    string `json:"Version"`
+	Deprecated string `json:"Deprecated"`
+	Error      *struct {
+		Err string `json:"Err"`
+	} `json:"Error"`
+}
+
+// detectMoves returns moves set in the options, then sibling libs declaring a different module path than their go url
+func (mu *MU) detectMoves(fileHead *sort.FileNode) (moves map[string]ModuleMove) {
+	moves = make(map[string]ModuleMove)
+	for from, to := range mu.Options.ModuleMoves {
+		move := ModuleMove{From: com.ModuleBase(from), To: com.ModuleBase(to), Reason: MoveConfig}
+		moves[move.From] = move
+	}
+
+	siblings := make(map[string]*com.FileWrapper)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		if !itr.File.IsGo() {
+			continue
+		}
+
+		declared := com.ModuleBase(itr.File.Manifest().Name)
+		siblings[declared] = itr.File
+
+		url := itr.File.GetGoURL()
+		if url == itr.File.Path || declared == url {
+			// Found outside a go path, or where it's declared
+			continue
+		}
+
+		if _, ok := moves[url]; !ok {
+			moves[url] = ModuleMove{From: url, To: declared, Reason: MoveModulePath}
+		}
+	}
+
+	for from, move := range moves {
+		move.lib = siblings[move.To]
+		moves[from] = move
+	}
+
+	return
+}
+
+// remoteMoves queries the module proxy for modules libs require, other than siblings, returning those redirected to
+// or deprecated in favour of another path. Only modules within the module filter are queried, if set
+func (mu *MU) remoteMoves(fileHead *sort.FileNode, known map[string]ModuleMove) (moves []ModuleMove, err error) {
+	siblings := make(map[string]bool)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		siblings[com.ModuleBase(itr.File.Manifest().Name)] = true
+	}
+
+	var dir *com.FileWrapper
+	queried := make(map[string]bool)
+	var args []string
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		if !itr.File.IsGo() {
+			continue
+		}
+		dir = itr.File
+
+		// Deps list modules from v2 on with and without their major suffix, only the suffixed path is required
+		deps := itr.File.Manifest().Deps
+		suffixed := make(map[string]bool)
+		for _, module := range deps {
+			if base := com.ModuleBase(module); base != module {
+				suffixed[base] = true
+			}
+		}
+
+		for _, module := range deps {
+			base := com.ModuleBase(module)
+			if siblings[base] || queried[module] || suffixed[module] || !matchesPrefix(module, mu.Options.ModuleFilter) {
+				continue
+			}
+			if _, ok := known[base]; ok {
+				continue
+			}
+
+			queried[module] = true
+			args = append(args, module+"@latest")
+		}
+	}
+
+	if len(args) == 0 {
+		return
+	}
+	gosort.Strings(args)
+
+	com.Println("\nChecking", len(args), "required module(s) for moves...")
+	output, err := dir.CmdOutput(append([]string{dir.GoBinary(), "list", "-m", "-e", "-json"}, args...)...)
+	if err != nil {
+		err = fmt.Errorf("unable to query required modules: %v", err)
+		return
+	}
+
+	found := make(map[string]bool)
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var module goListQuery
+		if err = decoder.Decode(&module); err == io.EOF {
+			return moves, nil
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse go list output: %v", err)
+			return
+		}
+
+		move := ModuleMove{From: com.ModuleBase(module.Path)}
+		if module.Error != nil {
+			match := declaredPathPattern.FindStringSubmatch(module.Error.Err)
+			if match == nil {
+				continue
+			}
+			move.From, move.To, move.Reason = com.ModuleBase(match[2]), com.ModuleBase(match[1]), MoveRedirect
+		} else if match := deprecatedMovePattern.FindStringSubmatch(module.Deprecated); match != nil {
+			move.To, move.Reason = com.ModuleBase(strings.TrimRight(match[1], ".")), MoveDeprecated
+		}
+
+		if len(move.To) == 0 || move.To == move.From || found[move.From] {
+			continue
+		}
+
+		found[move.From] = true
+		moves = append(moves, move)
+	}
+}
+
+// findMoves detects moved modules for migrate-module, recording them in the stats. Returns false if none were found
+func (mu *MU) findMoves(fileHead *sort.FileNode) bool {
+	mu.moves = mu.detectMoves(fileHead)
+
+	remote, err := mu.remoteMoves(fileHead, mu.moves)
+	if err != nil {
+		// Moves of siblings and the options can still be migrated
+		com.Println("\nUnable to check required modules for moves :(", err)
+	}
+	for _, move := range remote {
+		mu.moves[move.From] = move
+	}
+
+	var froms []string
+	for from := range mu.moves {
+		froms = append(froms, from)
+	}
+	gosort.Strings(froms)
+
+	for _, from := range froms {
+		move := mu.moves[from]
+		mu.Stats.Moves = append(mu.Stats.Moves, move)
+		mu.addStat(&mu.Stats.MovedCount, &mu.Stats.MovedOutput, move.From+" -> "+move.To+" ("+move.Reason+")\n")
+	}
+
+	return len(froms) > 0
+}
+
+// noticeMoves prints modules which moved while libs still require them at their previous path
+func (mu *MU) noticeMoves(fileHead *sort.FileNode) {
+	moves := mu.detectMoves(fileHead)
+	if len(moves) == 0 {
+		return
+	}
+
+	required := make(map[string]int)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		if !itr.File.IsGo() {
+			continue
+		}
+
+		for from := range moves {
+			if itr.File.Deps().Direct[from] {
+				required[from]++
+			}
+		}
+	}
+
+	var froms []string
+	for from := range required {
+		froms = append(froms, from)
+	}
+	gosort.Strings(froms)
+
+	for _, from := range froms {
+		move := moves[from]
+		count := required[from]
+		com.Println("\nModule", move.From, "moved to", move.To, "("+move.Reason+"), still required by", count, "lib(s). Run migrate-module to move them")
+	}
+}
+
+// moveRewrites returns the moved modules lib requires, at the version of their new path to require
+func (mu *MU) moveRewrites(lib Library, requires []string) (rewrites []majorRewrite, err error) {
+	for _, required := range requires {
+		base := com.ModuleBase(required)
+		move, ok := mu.moves[base]
+		if !ok {
+			continue
+		}
+
+		// Keep the major required, e.g. github.com/org/old/v2 -> github.com/org/new/v2
+		rewrite := majorRewrite{Previous: []string{required}}
+		rewrite.Base = base
+		rewrite.ModulePath = move.To + required[len(base):]
+		rewrite.Published = true
+
+		if move.lib != nil {
+			sibling := Library{File: move.lib}
+			if rewrite.Version = move.lib.Version; len(rewrite.Version) == 0 {
+				rewrite.Version = sibling.GetLatestTag()
+			}
+			if len(rewrite.Version) == 0 {
+				err = fmt.Errorf("%s isn't tagged yet, tag it to require it at %s", move.lib.GetGoURL(), rewrite.ModulePath)
+				return
+			}
+			rewrite.Ref = sibling.ModRef(rewrite.Version)
+		} else if rewrite.Version, err = mu.latestVersion(lib, rewrite.ModulePath); err != nil {
+			return
+		} else {
+			rewrite.Ref = rewrite.Version
+		}
+
+		rewrites = append(rewrites, rewrite)
+	}
+
+	return
+}
+
+// latestVersion returns the latest version of modulePath from the module proxy, queried once per run
+func (mu *MU) latestVersion(lib Library, modulePath string) (version string, err error) {
+	mu.libraryMux.Lock()
+	version, ok := mu.moveVersions[modulePath]
+	mu.libraryMux.Unlock()
+	if ok {
+		return
+	}
+
+	modules, err := lib.listModules([]string{lib.File.GoBinary(), "list", "-m", "-json", modulePath + "@latest"})
+	if err != nil {
+		return
+	}
+
+	if version = modules[modulePath].Version; len(version) == 0 {
+		err = fmt.Errorf("no version of %s found", modulePath)
+		return
+	}
+
+	mu.libraryMux.Lock()
+	if mu.moveVersions == nil {
+		mu.moveVersions = make(map[string]string)
+	}
+	mu.moveVersions[modulePath] = version
+	mu.libraryMux.Unlock()
+	return
+}
+
+// migrateModule moves lib's imports and requires of moved modules to their new paths. When committing, libs tagged
+// earlier in the chain are required too, and updated libs are tagged so their dependents migrate against them
+func (mu *MU) migrateModule(lib Library, fileHead *sort.FileNode) {
+	_, requires, err := lib.readModule()
+	if os.IsNotExist(err) {
+		lib.File.Output("No mod file found. Skipping.")
+		return
+	} else if err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	rewrites, err := mu.moveRewrites(lib, requires)
+	if err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	var chained []*com.FileWrapper
+	if mu.Options.Commit {
+		chained = mu.chainedDeps(lib, fileHead)
+	}
+
+	if len(rewrites) == 0 && len(chained) == 0 {
+		lib.File.Output("No moved modules to migrate.")
+		return
+	}
+
+	if mu.Options.Commit {
+		if mu.checkoutBranch(lib) != nil {
+			// Don't make changes which can't be pushed
+			return
+		}
+	}
+
+	changes, ok := mu.requireRewrites(lib, rewrites)
+	if !ok {
+		return
+	}
+
+	chainedChanges, ok := mu.requireChained(lib, chained)
+	if !ok {
+		return
+	}
+	changes = append(changes, chainedChanges...)
+
+	if mu.Options.Commit {
+		if err = lib.ModTidy(); err != nil {
+			mu.libraryError(lib, fmt.Errorf("go mod tidy failed: %v", err))
+			return
+		}
+	}
+
+	change := strings.Join(changes, ", ")
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+change+")\n")
+
+	if !mu.Options.Commit {
+		lib.File.Output("Module paths updated!")
+		return
+	}
+
+	if !mu.commitMajor(lib, nil, rewrites, change) || len(lib.File.ProtectedBranch) > 0 {
+		return
+	}
+
+	// Dependents require the tagged lib, chaining the migration through
+	mu.tag(lib)
+}
@@ -0,0 +1,151 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// movedLibrary returns a recorded lib found at module within src, declaring its path as declared
+func movedLibrary(t *testing.T, src, module, declared string) (lib *Library) {
+	lib, _ = requiringLibrary(t, src, module)
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, "go.mod"), []byte("module "+declared+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestDetectMoves(t *testing.T) {
+	src := goSrc(t)
+	a, _ := requiringLibrary(t, src, "github.com/org/a", "github.com/org/old v1.0.0")
+	renamed := movedLibrary(t, src, "github.com/org/old", "github.com/org/new")
+	fileHead := &sort.FileNode{File: a.File, Next: &sort.FileNode{File: renamed.File}}
+
+	mu := &MU{Options: Options{ModuleMoves: map[string]string{"github.com/org/legacy/v2": "github.com/org/modern/v3"}}}
+	moves := mu.detectMoves(fileHead)
+	expected := map[string]ModuleMove{
+		"github.com/org/legacy": {From: "github.com/org/legacy", To: "github.com/org/modern", Reason: MoveConfig},
+		"github.com/org/old":    {From: "github.com/org/old", To: "github.com/org/new", Reason: MoveModulePath, lib: renamed.File},
+	}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("expected %+v, got %+v", expected, moves)
+	}
+}
+
+func TestRemoteMoves(t *testing.T) {
+	src := goSrc(t)
+	a, _ := requiringLibrary(t, src, "github.com/org/a", "github.com/org/b v1.0.0", "github.com/ext/x v1.0.0",
+		"github.com/ext/y v1.0.0", "github.com/ext/z/v2 v2.0.0", "github.com/ext/known v1.0.0", "golang.org/x/mod v0.4.0")
+	b, recorder := requiringLibrary(t, src, "github.com/org/b", "github.com/ext/x v1.0.0")
+	fileHead := &sort.FileNode{File: a.File, Next: &sort.FileNode{File: b.File}}
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	recorder.Reply("go list -m -e -json", com.Reply{Stdout: `{"Path": "github.com/ext/x", "Error": {"Err": "github.com/ext/x@v1.1.0: parsing go.mod:\n\tmodule declares its path as: github.com/ext/x2\n\t        but was required as: github.com/ext/x"}}
+{"Path": "github.com/ext/y", "Version": "v1.1.0", "Deprecated": "Moved to github.com/ext/y2."}
+{"Path": "github.com/ext/z/v2", "Version": "v2.1.0"}
+`})
+
+	mu := &MU{Options: Options{ModuleFilter: []string{"github.com/"}}}
+	moves, err := mu.remoteMoves(fileHead, map[string]ModuleMove{"github.com/ext/known": {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Siblings, known moves and modules outside the filter aren't queried, suffixed majors only once
+	if lines := commandLines(recorder); len(lines) != 1 || lines[0] != "go list -m -e -json github.com/ext/x@latest github.com/ext/y@latest github.com/ext/z/v2@latest" {
+		t.Errorf("expected required modules to be queried at once, got %v", lines)
+	}
+
+	expected := []ModuleMove{
+		{From: "github.com/ext/x", To: "github.com/ext/x2", Reason: MoveRedirect},
+		{From: "github.com/ext/y", To: "github.com/ext/y2", Reason: MoveDeprecated},
+	}
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("expected %+v, got %+v", expected, moves)
+	}
+}
+
+func TestMoveRewrites(t *testing.T) {
+	src := goSrc(t)
+	a, recorder := requiringLibrary(t, src, "github.com/org/a")
+	renamed := movedLibrary(t, src, "github.com/org/old", "github.com/org/new")
+	renamed.File.Version = "v1.1.0"
+	recorder.Reply("go list -m -json github.com/ext/x2/v2@latest", com.Reply{Stdout: `{"Path": "github.com/ext/x2/v2", "Version": "v2.3.0"}`})
+
+	mu := &MU{moves: map[string]ModuleMove{
+		"github.com/org/old": {From: "github.com/org/old", To: "github.com/org/new", lib: renamed.File},
+		"github.com/ext/x":   {From: "github.com/ext/x", To: "github.com/ext/x2"},
+	}}
+	rewrites, err := mu.moveRewrites(*a, []string{"github.com/org/old", "github.com/ext/x/v2", "github.com/ext/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rewrites) != 2 {
+		t.Fatalf("expected both moved requires to be rewritten, got %+v", rewrites)
+	}
+	if rewrite := rewrites[0]; rewrite.ModulePath != "github.com/org/new" || rewrite.Version != "v1.1.0" || rewrite.Ref != "v1.1.0" {
+		t.Errorf("expected the sibling's version, got %+v", rewrite)
+	}
+	if rewrite := rewrites[1]; rewrite.ModulePath != "github.com/ext/x2/v2" || rewrite.Version != "v2.3.0" || !reflect.DeepEqual(rewrite.Previous, []string{"github.com/ext/x/v2"}) {
+		t.Errorf("expected the major to be kept at the latest version, got %+v", rewrite)
+	}
+
+	// Latest versions are queried once
+	if _, err = mu.moveRewrites(*a, []string{"github.com/ext/x/v2"}); err != nil || len(recorder.Commands()) != 1 {
+		t.Errorf("expected the latest version to be cached, got %v (%v)", commandLines(recorder), err)
+	}
+
+	renamed.File.Version = ""
+	if _, err = mu.moveRewrites(*a, []string{"github.com/org/old"}); err == nil {
+		t.Error("expected an untagged sibling to fail")
+	}
+}
+
+func TestMigrateModule(t *testing.T) {
+	src := goSrc(t)
+	a, recorder := requiringLibrary(t, src, "github.com/org/a", "github.com/org/old v1.0.0")
+	source := "package a\n\nimport \"github.com/org/old/pkg\"\n\nvar _ = pkg.Name\n"
+	if err := ioutil.WriteFile(filepath.Join(a.File.Path, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Module": {"Path": "github.com/org/a"}, "Require": [{"Path": "github.com/org/old", "Version": "v1.0.0"}]}`})
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	mu := &MU{
+		moves:        map[string]ModuleMove{"github.com/org/old": {From: "github.com/org/old", To: "github.com/org/new"}},
+		moveVersions: map[string]string{"github.com/org/new": "v1.2.0"},
+	}
+	mu.migrateModule(*a, nil)
+	if len(mu.Errors) > 0 {
+		t.Fatal(mu.Errors)
+	}
+
+	if !recorder.Ran("go mod edit -droprequire=github.com/org/old -require=github.com/org/new@v1.2.0") {
+		t.Errorf("expected the require to move, got %v", commandLines(recorder))
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(a.File.Path, "a.go")); !strings.Contains(string(data), `"github.com/org/new/pkg"`) {
+		t.Errorf("expected imports to move, got\n%s", data)
+	}
+	if mu.Stats.UpdateCount != 1 || !a.File.Updated {
+		t.Errorf("expected the lib to be updated, got %d", mu.Stats.UpdateCount)
+	}
+
+	// Libs not requiring moved modules are left alone
+	os.Remove(filepath.Join(a.File.Path, "a.go"))
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Module": {"Path": "github.com/org/a"}}`})
+	mu = &MU{moves: mu.moves}
+	mu.migrateModule(*a, nil)
+	if mu.Stats.UpdateCount != 0 || len(mu.Errors) > 0 {
+		t.Errorf("expected nothing to migrate, got %v", mu.Errors)
+	}
+}
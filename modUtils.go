@@ -1,37 +1,43 @@
 package gomu
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"path"
+	"path/filepath"
 
+	"github.com/gomuserver/mod-utils/com"
 	"github.com/gomuserver/mod-utils/sort"
 )
 
 // CleanModCache calls go clean --modcache from calling directory. No context necessary
 func CleanModCache() error {
-	cmd := exec.Command("go", "clean", "--modcache")
-	return cmd.Run()
+	cmd := com.Command{Name: com.GoBinary(), Args: []string{"clean", "--modcache"}, Env: com.Environ()}
+	return com.GlobalRunner().Run(context.Background(), cmd)
 }
 
 // ModInit calls go mod init on a given lib
 func (lib *Library) ModInit() error {
-	return lib.File.RunCmd("go", "mod", "init")
+	return lib.File.RunCmd(lib.File.GoBinary(), "mod", "init")
 }
 
 // ModTidy calls go mod tidy on a given lib
 func (lib *Library) ModTidy() error {
-	return lib.File.RunCmd("go", "mod", "tidy")
+	return lib.File.RunCmd(lib.File.GoBinary(), "mod", "tidy")
 }
 
-// ModClearFiles calls rm go.mod and rm go.sum, returning the success of both commands
+// Vendor calls go mod vendor on a given lib
+func (lib *Library) Vendor() error {
+	return lib.File.RunCmd(lib.File.GoBinary(), "mod", "vendor")
+}
+
+// ModClearFiles removes go.mod and go.sum, returning the success of both removals
 func (lib *Library) ModClearFiles() (hasModFile, hasSumFile bool) {
-	if lib.File.RunCmd("rm", "go.mod") == nil {
+	if lib.File.RemoveFile("go.mod") == nil {
 		hasModFile = true
 	}
 
-	if lib.File.RunCmd("rm", "go.sum") == nil {
+	if lib.File.RemoveFile("go.sum") == nil {
 		hasSumFile = true
 	}
 
@@ -58,16 +64,20 @@ func (lib *Library) ModAddDeps(listHead *sort.FileNode, shouldForce bool) {
 func (lib *Library) ModSetDeps() (err error) {
 	// Iterate through dep chain
 	for itr := lib.updatedDeps; itr != nil; itr = itr.Next {
+		tempLib := Library{}
+		tempLib.File = itr.File
 		if len(itr.File.Version) == 0 {
-			tempLib := Library{}
-			tempLib.File = itr.File
-			itr.File.Version = tempLib.GetLatestTag()
+			itr.File.Version = tempLib.LatestVersion()
 		}
 
 		url := itr.File.GetGoURL()
+		if lib.pinned(url, itr.File.Version) || !lib.trustedTag(tempLib) {
+			continue
+		}
 
 		// Get dep @ version (-d avoids building)
-		if lib.File.RunCmd("go", "get", "-d", url+"@"+itr.File.Version) == nil {
+		if lib.File.RunCmd(lib.File.GoBinary(), "get", "-d", url+"@"+tempLib.ModRef(itr.File.Version)) == nil {
+			lib.File.UpdatedDeps = append(lib.File.UpdatedDeps, url+"@"+itr.File.Version)
 			if itr.File.Updated || itr.File.Tagged || itr.File.Committed {
 				lib.File.Output("Updated " + url + " @ " + itr.File.Version)
 			} else {
@@ -98,7 +108,7 @@ func (lib *Library) ModReplaceLocal() (updated bool) {
 	if len(localSuffix) > 0 {
 		updated = lib.AppendToModfile("\n\n// Replace Local Deps\n\n" + localSuffix)
 
-		lib.File.RunCmd("rm", "go.sum")
+		lib.File.RemoveFile("go.sum")
 		lib.ModTidy()
 	}
 	return
@@ -106,11 +116,16 @@ func (lib *Library) ModReplaceLocal() (updated bool) {
 
 // AppendToModfile appends provided string to end of mod file
 func (lib *Library) AppendToModfile(text string) bool {
+	if com.IsDryRun() {
+		lib.File.DryRun("Append " + text + " to mod file")
+		return true
+	}
+
 	// Open absolute path to mod file in append mode
-	f, err := os.OpenFile(path.Join(lib.File.AbsPath(), "go.mod"),
+	f, err := os.OpenFile(filepath.Join(lib.File.AbsPath(), "go.mod"),
 		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		lib.File.Output("Unable to open mod file: " + path.Join(lib.File.AbsPath(), "go.mod"))
+		lib.File.Output("Unable to open mod file: " + filepath.Join(lib.File.AbsPath(), "go.mod"))
 		return false
 	}
 
@@ -128,7 +143,7 @@ func (lib *Library) AppendToModfile(text string) bool {
 }
 
 // ModDeploy will commit and push local changes to the current branch before switching to master
-func (lib *Library) ModDeploy(tag, commitMessage string) (deployed bool) {
+func (lib *Library) ModDeploy(tag, commitMessage, signingKey string) (deployed bool) {
 	// Handle saving local changes
 	lib.File.StashPop()
 	lib.File.Add(".")
@@ -153,7 +168,11 @@ func (lib *Library) ModDeploy(tag, commitMessage string) (deployed bool) {
 		message = commitMessage + "\n\n" + message
 	}
 
-	if lib.File.Commit(message) == nil {
+	if len(signingKey) > 0 {
+		lib.commitKey = signingKey
+	}
+
+	if lib.commit(message) == nil {
 		// Successful commit, push changes
 		deployed = true
 		lib.File.Output("Waiting to deploy local changes on sync...")
@@ -167,11 +186,25 @@ func (lib *Library) ModDeploy(tag, commitMessage string) (deployed bool) {
 	return
 }
 
-// ModUpdate will refresh the current dir to master, reset mod files and push changes if there are any
-func (lib *Library) ModUpdate(branch, commitMessage string) (err error) {
+// commit commits staged changes with message, signed if lib has a commit key
+func (lib *Library) commit(message string) (err error) {
+	if len(lib.commitKey) == 0 {
+		return lib.File.Commit(message)
+	}
+
+	if err = lib.File.SignedCommit(message, lib.commitKey); err == nil {
+		lib.File.CommitSigned = true
+	}
+
+	return
+}
+
+// ModUpdate will refresh the current dir to master, reset mod files and push changes if there are any.
+// Local replace directives are handled per replacePolicy
+func (lib *Library) ModUpdate(branch, commitMessage string, replacePolicy ReplacePolicy) (err error) {
 	lib.File.Output("Checking deps...")
 	// Remove go.mod, ignore lib if not found (not a mod tracked lib)
-	if lib.File.RunCmd("rm", "go.mod") != nil {
+	if lib.File.RemoveFile("go.mod") != nil {
 		lib.File.Output("No mod file found. Skipping.")
 		return
 	}
@@ -180,33 +213,60 @@ func (lib *Library) ModUpdate(branch, commitMessage string) (err error) {
 	lib.File.RunCmd("git", "checkout", "go.mod")
 	lib.ModInit()
 
+	var dropped []Replace
+	if replacePolicy.Drop || replacePolicy.Restore {
+		// Local replaces would mask updated versions
+		if dropped, err = lib.DropLocalReplaces(); err != nil {
+			lib.File.Error("Unable to drop replace directives :(")
+			return
+		}
+	}
+
 	// Remove go sum to prevent mess from adding up
-	if lib.File.RunCmd("rm", "go.sum") != nil {
+	if lib.File.RemoveFile("go.sum") != nil {
 		// No dependencies found. If this is unexpected for a given lib, something is out of sync
 		lib.File.Output("No sum file found. No dependencies sorted.")
 	}
 
 	// Set versions from previous libs in chain
 	lib.ModSetDeps()
+	lib.ModAlign()
 
 	if err = lib.ModTidy(); err != nil {
-		lib.File.Output("Mod tidy failed :(")
+		lib.File.Error("Mod tidy failed :(")
 		return
 	}
 
+	if replacePolicy.Restore {
+		if err = lib.RestoreReplaces(dropped); err != nil {
+			lib.File.Error("Unable to restore replace directives :(")
+			return
+		}
+	}
+
+	lib.warnMaskedUpdates()
+	lib.checkPins()
+
+	if lib.verifySums {
+		if err = lib.ModVerify(); err != nil {
+			lib.File.Error("Checksums inconsistent, refusing to commit :(")
+			return
+		}
+	}
+
 	if err = lib.File.Add("go.*"); err != nil {
-		lib.File.Output("Git add failed :(")
+		lib.File.Error("Git add failed :(")
 		return
 	}
 
-	if err = lib.File.Commit(commitMessage); err == nil {
+	if err = lib.commit(commitMessage); err == nil {
 		lib.File.Output("Updating mod files...")
 	} else {
 		lib.File.Output("Deps up to date!")
 	}
 
 	if pushErr := lib.File.Push(); pushErr != nil {
-		lib.File.Output("Push failed :( check local changes and commit status")
+		lib.File.Error("Push failed :( check local changes and commit status")
 		return pushErr
 	}
 
@@ -0,0 +1,42 @@
+package gomu
+
+import (
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// signedCommit is the commit line recorded for a commit signed with the test key
+func signedCommit(message string) string {
+	return "git commit -SABCD1234 -m " + message
+}
+
+func TestSignedCommits(t *testing.T) {
+	options := Options{Commit: true, SignCommits: true, SigningKey: "ABCD1234"}
+	for _, test := range []struct {
+		action  string
+		message string
+		perform func(mu *MU, lib Library)
+	}{
+		{"sync", "Sync deps\nUpdated deps", func(mu *MU, lib Library) { mu.sync(lib, "Sync deps", "Updated deps") }},
+		{"vendor", "gomu: Update vendored deps", func(mu *MU, lib Library) { mu.vendor(lib) }},
+		{"tidy", "gomu: Tidy mod files", func(mu *MU, lib Library) { mu.tidy(lib) }},
+		{"major", "gomu: Release github.com/org/a/v2\n\ngithub.com/org/a -> github.com/org/a/v2", func(mu *MU, lib Library) {
+			release := &majorRelease{Base: "github.com/org/a", ModulePath: "github.com/org/a/v2", Version: "v2.0.0"}
+			mu.commitMajor(lib, release, nil, "github.com/org/a -> github.com/org/a/v2")
+		}},
+	} {
+		lib, recorder := recordedLibrary(t)
+		recorder.Reply("git status --porcelain", com.Reply{Stdout: " M go.mod\n"})
+
+		mu := &MU{Options: options}
+		test.perform(mu, *lib)
+
+		if !recorder.Ran(signedCommit(test.message)) || recorder.Ran("git commit -m") {
+			t.Errorf("%s: expected signed commit, got %q", test.action, commandLines(recorder))
+		}
+		if !lib.File.CommitSigned {
+			t.Errorf("%s: expected commit to be reported signed", test.action)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package gomu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// slackMessage represents the payload of a slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Text returns a short human-readable summary for chat notifications, listing changed libs, pull requests and errors
+func (summary Summary) Text() string {
+	title := "gomu " + summary.Action + " finished"
+	if len(summary.Branch) > 0 {
+		title += " on " + summary.Branch
+	}
+	if summary.DryRun {
+		title += " (dry run)"
+	}
+
+	lines := []string{title + ": " + strconv.Itoa(summary.UpdateCount) + " updated, " + strconv.Itoa(summary.TagCount) + " tagged, " +
+		strconv.Itoa(summary.PRCount) + " pull request(s) in " + strconv.Itoa(summary.DepCount) + " lib(s)"}
+
+	var updated, tagged, prs []string
+	for _, lib := range summary.Libraries {
+		if lib.Updated {
+			updated = append(updated, "- "+lib.Library)
+		}
+		if lib.Tagged {
+			tagged = append(tagged, "- "+lib.Library+" "+lib.Version)
+		}
+		if len(lib.PRURL) > 0 {
+			prs = append(prs, "- "+lib.PRURL)
+		}
+	}
+
+	var errs []string
+	for _, err := range summary.Errors {
+		errs = append(errs, "- "+err)
+	}
+
+	sections := []struct {
+		name  string
+		items []string
+	}{
+		{"Updated", updated},
+		{"Tagged", tagged},
+		{"Pull requests", prs},
+		{"Errors", errs},
+	}
+
+	for _, section := range sections {
+		if len(section.items) > 0 {
+			lines = append(lines, "", section.name+":")
+			lines = append(lines, section.items...)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// notify posts the run summary to the configured slack webhook and notification endpoint
+func (mu *MU) notify() {
+	if len(mu.Options.SlackWebhook) == 0 && len(mu.Options.NotifyURL) == 0 {
+		return
+	}
+
+	summary := mu.Stats.Summary(mu.Errors)
+
+	if len(mu.Options.SlackWebhook) > 0 {
+		if err := com.PostJSON(mu.Options.SlackWebhook, slackMessage{Text: summary.Text()}); err != nil {
+			mu.Errors = append(mu.Errors, fmt.Errorf("unable to notify slack: %v", err))
+		}
+	}
+
+	if len(mu.Options.NotifyURL) > 0 {
+		if err := com.PostJSON(mu.Options.NotifyURL, summary); err != nil {
+			mu.Errors = append(mu.Errors, fmt.Errorf("unable to post run summary: %v", err))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gomu
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestSummaryText(t *testing.T) {
+	summary := Summary{
+		Action: "sync", Branch: "deps", UpdateCount: 1, TagCount: 1, PRCount: 1, DepCount: 2,
+		Libraries: []LibraryResult{
+			{Library: "github.com/org/a", Updated: true, Tagged: true, Version: "v1.0.1", PRURL: "https://github.com/org/a/pull/7"},
+			{Library: "github.com/org/b"},
+		},
+		Errors: []string{"github.com/org/b: go build failed"},
+	}
+
+	expected := "gomu sync finished on deps: 1 updated, 1 tagged, 1 pull request(s) in 2 lib(s)\n" +
+		"\nUpdated:\n- github.com/org/a\n" +
+		"\nTagged:\n- github.com/org/a v1.0.1\n" +
+		"\nPull requests:\n- https://github.com/org/a/pull/7\n" +
+		"\nErrors:\n- github.com/org/b: go build failed"
+	if text := summary.Text(); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var slack slackMessage
+	var summary Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/slack":
+			err = json.NewDecoder(r.Body).Decode(&slack)
+		case "/runs":
+			err = json.NewDecoder(r.Body).Decode(&summary)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	// Posts to the same host aren't spaced out
+	com.SetRateLimitPolicy(com.RateLimitPolicy{Interval: -1})
+	defer com.SetRateLimitPolicy(com.RateLimitPolicy{})
+
+	mu := &MU{Options: Options{Action: "sync", SlackWebhook: server.URL + "/slack", NotifyURL: server.URL + "/runs"}}
+	mu.Stats.Options = &mu.Options
+	mu.Errors = []error{errors.New("github.com/org/b: go build failed")}
+	mu.notify()
+
+	if len(mu.Errors) != 1 {
+		t.Fatalf("expected notifications to be posted, got %v", mu.Errors)
+	}
+	if slack.Text != mu.Stats.Summary(mu.Errors).Text() {
+		t.Errorf("expected slack message to be the summary text, got %q", slack.Text)
+	}
+	if summary.Action != "sync" || len(summary.Errors) != 1 {
+		t.Errorf("expected run summary to be posted, got %+v", summary)
+	}
+
+	mu.Options.NotifyURL = server.URL + "/missing"
+	mu.notify()
+	if len(mu.Errors) != 2 {
+		t.Errorf("expected failed notification to be an error, got %v", mu.Errors)
+	}
+}
@@ -1,7 +1,9 @@
 package gomu
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gomuserver/mod-utils/com"
 	"github.com/gomuserver/mod-utils/sort"
@@ -11,22 +13,253 @@ import (
 type Options struct {
 	Action string `json:"action,-"` // Not supported from server
 
+	// Branch is checked out or created in libs, and may be a go template of BranchTemplateData,
+	// e.g. deps/{{.Date}}-{{.Filter}} or {{.Ticket}}/update-deps. It's resolved per lib if it uses {{.Lib}}
 	Branch        string `json:"branch"`
 	CommitMessage string `json:"message"`
+	// CommitTemplate is a go template of sync commit messages, first line is the title and the rest is the body,
+	// e.g. "chore(deps): update {{join .UpdatedDeps ", "}} [{{.Ticket}}]"
+	CommitTemplate string `json:"commitTemplate"`
+	// TicketPattern is a regex matching ticket ids in branch names, the first group if it has one. Defaults to DefaultTicketPattern
+	TicketPattern string `json:"ticketPattern"`
+	// Ticket id substituted in branch and commit templates
+	Ticket string `json:"ticket"`
 
 	Commit      bool   `json:"commit,-"` // Not supported from server
 	PullRequest bool   `json:"createPR"`
+	PRTemplate  string `json:"prTemplate"` // Path to go template, first line is the title and the rest is the body
+	DraftPR     bool   `json:"draftPR"`
+	BatchPR     bool   `json:"batchPR"`     // Open pull requests once every lib is synced, linking them to each other
+	AutoMerge   bool   `json:"autoMerge"`   // Merge pull requests once checks pass
+	MergeMethod string `json:"mergeMethod"` // "merge", "squash" or "rebase" when auto-merging. Defaults to merge
+	WaitChecks  bool   `json:"waitChecks"`  // Wait for checks of opened pull requests to pass before tagging
+	MergeGreen  bool   `json:"mergeGreen"`  // Merge pull requests once their checks pass, implies WaitChecks
 	Tag         bool   `json:"shouldTag"`
 	SetVersion  string `json:"setVersion"`
+	// BumpStrategy increments tags by "major", "minor", "patch" or "auto" (from conventional commits). Defaults to git-tagger
+	BumpStrategy string           `json:"bumpStrategy"`
+	BumpRules    sort.StringArray `json:"bumpRules"` // Per-lib strategies as "pattern=strategy", first matching pattern wins
+	SignTags     bool             `json:"signTags"`
+	SignCommits  bool             `json:"signCommits"`
+	SigningKey   string           `json:"signingKey"` // Signs tags and commits, defaults to git config user.signingkey
+	Changelog    bool             `json:"changelog"`  // Write release notes to CHANGELOG.md and publish a release when tagging
+	TagPrefix    string           `json:"tagPrefix"`  // Prefix of version tags, e.g. release/ for release/v1.2.3
+	// VersionScheme is "semver" (default), "calver" (v2024.6.0) or a template of {major}, {minor}, {patch}, {year}, {month} and {day}
+	VersionScheme string `json:"versionScheme"`
+	// Prerelease channel tags are released on, e.g. beta tags v1.4.0-beta.1 then v1.4.0-beta.2. Promote only promotes this channel if set
+	Prerelease string `json:"prerelease"`
+	// CreateRelease publishes a release with notes generated from commits after pushing each tag. ReleaseBuild is a shell
+	// command run in each lib before its release is published, e.g. make dist, and files matching ReleaseAssets (globs
+	// relative to the lib, e.g. dist/*) are attached to every release published, including those of Changelog
+	CreateRelease bool             `json:"createRelease"`
+	ReleaseBuild  string           `json:"releaseBuild"`
+	ReleaseAssets sort.StringArray `json:"releaseAssets"`
 
-	SourcePath string `json:"source,-"` // Not supported from server
+	// Workflow template, or directory of templates, synced by workflow. Secret file named as the secret, directory of
+	// them, or store of NAME=value lines (.env, or gpg encrypted .gpg or .asc) set by secret. Directory of templates
+	// init adds to its defaults. Not supported from server
+	SourcePath string `json:"source,-"`
+	// Environment variables set as actions secrets of the same name by secret
+	Secrets sort.StringArray `json:"secrets"`
+
+	// ModulePath of the module created by init in the workspace, e.g. github.com/org/lib
+	ModulePath  string `json:"modulePath"`
+	CreateRepo  bool   `json:"createRepo"`  // Create the github repository of the module created by init, and push to it
+	PrivateRepo bool   `json:"privateRepo"` // Create the repository as private
 
 	DirectImport       bool             `json:"direct"`
 	TargetDirectories  sort.StringArray `json:"searchLibs"` // Not supported from server
+	UseWorkspace       bool             `json:"useWorkspace"`
+	NestedModules      bool             `json:"nestedModules"` // Also search each repo for nested go.mod files
+	SearchDepth        int              `json:"searchDepth"`   // How many directories below each target libs are searched for. Defaults to 1
+	SkipDirs           sort.StringArray `json:"skipDirs"`      // Globs matching names of directories never searched, e.g. node_modules
 	FilterDependencies sort.StringArray `json:"syncLibs"`
+	IncludePatterns    sort.StringArray `json:"include"` // Globs, or regexes prefixed with "re:", matching module or filesystem paths of libs to include
+	ExcludePatterns    sort.StringArray `json:"exclude"` // Globs, or regexes prefixed with "re:", matching module or filesystem paths of libs to skip
+	OnCycle            string           `json:"onCycle"` // "fail" (default) or "break" when libs depend on each other
+	// MaxDepth limits libs to those within this many go.mod requires of a filter dep, e.g. 2 syncs direct dependents
+	// and theirs. Defaults to the whole chain
+	MaxDepth int `json:"maxDepth"`
+
+	// VerifyTags checks dep tags are signed by a trusted key before syncing to them
+	VerifyTags  bool             `json:"verifyTags"`
+	TrustedKeys sort.StringArray `json:"trustedKeys"` // GPG fingerprints or key ids trusted to sign dep tags. Any valid signature if empty
+	// OnUnverifiedTag is "skip" (default) to leave deps whose tags aren't trusted as they are, or "warn" to set them anyway
+	OnUnverifiedTag string `json:"onUnverifiedTag"`
+
+	// DirtyPolicy is "stash" (default) to stash local changes and restore them once finished, "skip" to leave libs with
+	// local changes out of the run, "fail" to abort if any lib has them, or "wip-commit" to commit them to a gomu-wip/ branch
+	DirtyPolicy string `json:"dirtyPolicy"`
+
+	// OnProtected is "pr" (default) to sync on gomu-sync and open a pull request, or "fail" when a branch blocks pushes
+	OnProtected string `json:"onProtected"`
+
+	// Atomic rolls back every branch, commit, tag and pull request made by a sync if any lib fails
+	Atomic bool `json:"atomic"`
+	// DeleteTags lets undo and rollbacks delete pushed version tags. Kept by default, since module proxy and checksum
+	// database consumers may already depend on them
+	DeleteTags bool `json:"deleteTags"`
+
+	// ErrorThreshold is how many libs may fail while the run still exits successfully, e.g. to tolerate flaky libs in CI
+	ErrorThreshold int `json:"errorThreshold"`
+
+	// NonInteractive fails instead of asking for missing credentials on stdin, e.g. when run by a server
+	NonInteractive bool `json:"nonInteractive"`
 
 	LogLevel      com.LogLevel
 	IgnoreWarning bool
+	DryRun        bool   `json:"dryRun"`
+	Output        string `json:"output"`   // "text" (default) or "json"
+	Progress      bool   `json:"progress"` // Render a progress bar with time remaining, or add time remaining to each lib line if not a terminal
+	Report        string `json:"report"`   // Path to write a run report to, html if ending in .html, otherwise markdown
+	LogDir        string `json:"logDir"`   // Directory each lib's full output is written to as <lib>.log, leaving a status line per lib on the console
+	Resume        bool   `json:"resume"`
+	PlanFile      string `json:"planFile"` // Written by plan and read by apply. Defaults to .gomu-plan.json
+	ParallelSync  bool   `json:"parallelSync"`
+	ParallelTest  bool   `json:"parallelTest"` // Test libs within each dependency level concurrently, after the levels they depend on
+	Worktree      bool   `json:"worktree"`     // Perform actions in temporary git worktrees instead of stashing local changes
+	VerifyVendor  bool   `json:"verifyVendor"`
+	VerifyTidy    bool   `json:"verifyTidy"` // Fail libs whose mod files go mod tidy would change when tidying, e.g. in CI
+
+	// Go directive set by the go-version action, e.g. 1.22, and optionally the toolchain directive, e.g. go1.22.3
+	GoVersion string `json:"goVersion"`
+	Toolchain string `json:"toolchain"`
+
+	// GoBinary is the go command run in libs, e.g. a go1.21.5 shim. Defaults to go. GoBinaries overrides it for libs
+	// within module path prefixes, e.g. github.com/org/legacy: go1.19.13, the longest matching prefix winning
+	GoBinary   string            `json:"goBinary"`
+	GoBinaries map[string]string `json:"goBinaries"`
+
+	// BaseBranch pull requests are opened against, e.g. develop. Defaults to the repo's default branch. BaseBranches
+	// overrides it for libs within module path prefixes, e.g. github.com/org/legacy: release-1.x, the longest
+	// matching prefix winning
+	BaseBranch   string            `json:"baseBranch"`
+	BaseBranches map[string]string `json:"baseBranches"`
+
+	// LibraryOptions overrides the branch, whether to tag and pull requests for libs within module path prefixes, e.g.
+	// github.com/org/legacy: {shouldTag: false, createPR: true}, the longest matching prefix winning. Merged onto the
+	// options as each lib is performed
+	LibraryOptions map[string]LibraryOverride `json:"libraryOptions"`
+
+	// Env vars set for all commands run, e.g. GOFLAGS: -mod=mod, without changing the parent process env. LibraryEnv
+	// adds vars for libs within module path prefixes, e.g. github.com/org/private: {GONOSUMDB: github.com/org/*}
+	Env        map[string]string            `json:"env"`
+	LibraryEnv map[string]map[string]string `json:"libraryEnv"`
+
+	// MaxConcurrency limits libs worked on at once, e.g. to avoid ssh agent or rate limit storms. Defaults to GOMAXPROCS
+	MaxConcurrency int `json:"maxConcurrency"`
+	// HostConcurrency limits clones, fetches, pulls and pushes running at once against each remote host on top of
+	// MaxConcurrency, e.g. github.com: 4. "*" limits hosts not listed, 0 is unlimited
+	HostConcurrency map[string]int `json:"hostConcurrency"`
+
+	LibraryTimeout time.Duration `json:"libraryTimeout"` // Max duration of commands for a single lib
+	Deadline       time.Time     `json:"deadline"`       // Stops starting new libs and interrupts commands once passed
+
+	Retries      int           `json:"retries"`      // Max retries of fetch, pull, push and pull request calls
+	RetryBackoff time.Duration `json:"retryBackoff"` // Delay before the first retry, doubled after each attempt. Defaults to 1s
+	RetryBudget  int           `json:"retryBudget"`  // Max retries across all libs, 0 is unlimited
+
+	// APIInterval spaces pull request api calls to a host, so opening many doesn't trip abuse detection. Defaults to 1s, negative disables
+	APIInterval time.Duration `json:"apiInterval"`
+	// ChecksTimeout is the longest WaitChecks waits for a pull request's checks before failing the lib. Defaults to 30m
+	ChecksTimeout time.Duration `json:"checksTimeout"`
+	// ChecksInterval is how often WaitChecks polls pull request checks. Defaults to 30s
+	ChecksInterval time.Duration `json:"checksInterval"`
+	// RateLimitWait is the longest an api call waits for a rate limit to reset before failing. Defaults to 5m
+	RateLimitWait time.Duration `json:"rateLimitWait"`
+
+	// PushAuth is "ssh" to push https remotes with the ssh agent, or "token" to push with GITHUB_TOKEN, gh cli or saved credentials.
+	// Defaults to git's own credentials
+	PushAuth string `json:"pushAuth"`
+	// Remote branches and tags are pushed to, defaults to origin. Pull requests are opened on the upstream remote
+	// instead if it's a different repo, e.g. when pushing to a fork
+	Remote string `json:"remote"`
+
+	// Proxy lists module proxies latest dep versions are resolved from in GOPROXY syntax, e.g. a private Athens instance.
+	// Defaults to go env GOPROXY, modules matching GONOPROXY or GOPRIVATE are only read from tags. Off disables
+	Proxy string `json:"proxy"`
+
+	GraphFormat string `json:"graphFormat"` // "dot" (default), "mermaid" or "json"
+	AuditID     string `json:"auditID"`     // Only report this vulnerability id or CVE when auditing
+
+	ModuleFilter sort.StringArray `json:"filter"` // Only report modules within these path prefixes when checking outdated or stale deps
+
+	// Freshness policy enforced by check: modules within the filter may be at most this many releases, or this many
+	// days since the first newer release, behind. 0 doesn't limit
+	MaxVersionsBehind int `json:"maxVersionsBehind"`
+	MaxDaysBehind     int `json:"maxDaysBehind"`
+
+	Pattern string `json:"pattern"`    // Regex grep searches mod files for, e.g. a deprecated module path
+	Source  bool   `json:"grepSource"` // Also search go files when grepping
+
+	LicenseDeny sort.StringArray `json:"licenseDeny"` // Licenses (SPDX ids or globs, e.g. GPL-*) failing libs requiring them when inventorying licenses
+
+	ExecCommand string `json:"exec"`      // Shell command run in each lib by the exec action, with sh (cmd on Windows)
+	KeepGoing   bool   `json:"keepGoing"` // Run exec command in remaining libs after one fails
+
+	// ChangedRange is a commit range, e.g. origin/master...HEAD, whose changed modules affected lists the dependents of,
+	// read from the repo at ChangedRepo (defaults to the current directory). Changed modules may also be set as sync libs
+	ChangedRange string `json:"changedRange"`
+	ChangedRepo  string `json:"changedRepo"`
+	AffectedTest bool   `json:"affectedTest"` // Also test libs listed by affected
+
+	JUnitReport  string `json:"junit"`        // Path to write combined JUnit XML test results to when testing
+	CoverProfile string `json:"coverProfile"` // Path to write a combined coverage profile to when testing
+
+	ManifestPath string `json:"manifest"`     // File listing exactly which libs to act on, instead of searching target directories
+	WorkspaceDir string `json:"workspaceDir"` // Directory libs missing from the manifest are cloned into. Defaults to $GOPATH/src
+
+	CloneMissing bool `json:"cloneMissing"` // Clone deps matching include patterns or filter deps which aren't found on disk
+	// VerifySums checks go.sum checksums against the module cache before stashing and after updating, refusing to
+	// commit libs whose checksums are inconsistent
+	VerifySums bool `json:"verifySums"`
+	// IgnoreSkipMarkers acts on libs opting out with a .gomuignore file or a gomu.skip go.mod comment
+	IgnoreSkipMarkers bool `json:"ignoreSkipMarkers"`
+	// FullHistory clones libs completely. Otherwise clones are shallow for read-only actions, and partial (blobs are
+	// fetched as needed) for the rest
+	FullHistory bool `json:"fullHistory"`
+
+	SlackWebhook string `json:"slackWebhook"` // Slack incoming webhook posted a run summary once finished
+	NotifyURL    string `json:"notifyURL"`    // Endpoint posted the json run summary once finished
+
+	PinsFile string `json:"pins"` // Modules held at specific versions when syncing. Defaults to gomu.pins in the working directory
+
+	HistoryFile   string `json:"historyFile"`   // File each run's summary is appended to. Defaults to .gomu-history.jsonl
+	NoHistory     bool   `json:"noHistory"`     // Don't record this run
+	HistoryAction string `json:"historyAction"` // Only query past runs of this action
+	HistoryLimit  int    `json:"historyLimit"`  // Max runs and libs listed by history. Defaults to 10
+	DiffRuns      int    `json:"diffRuns"`      // Diff against the run this many recorded runs ago, of the history action if set. Defaults to 1
+
+	DropReplace    bool `json:"dropReplace"`    // Remove local replace directives before syncing deps
+	RestoreReplace bool `json:"restoreReplace"` // Re-add dropped replace directives before committing synced deps
+	Align          bool `json:"align"`          // Update third-party modules required at different versions by libs to the highest when syncing
+
+	NoCache bool `json:"noCache"` // Parse mod files of every lib rather than reading cached deps of unchanged files
+
+	ListOnly bool `json:"listOnly"` // Only list leftover stashes, branches and worktrees when cleaning, or workflow drift
+
+	WatchAction   string        `json:"watchAction"`   // Action watch re-runs on changed libs and their dependents, e.g. test or replace
+	WatchInterval time.Duration `json:"watchInterval"` // How often watch checks for changes. Defaults to 1s
+
+	// OTLPEndpoint is the base url of an otlp http collector runs export traces and metrics to, e.g. http://localhost:4318.
+	// Defaults to OTEL_EXPORTER_OTLP_ENDPOINT. OTLPHeaders are sent with exports, e.g. Authorization of a hosted collector
+	OTLPEndpoint string            `json:"otlpEndpoint"`
+	OTLPHeaders  map[string]string `json:"otlpHeaders"`
+
+	// Hooks are shell commands (sh, cmd on Windows) run in each lib at a hook point ("pre-sync", "post-commit", "pre-tag" or "post-pr")
+	Hooks map[string][]string `json:"hooks"`
+
+	// ModuleMoves maps previous module paths to the paths they moved to, migrated by migrate-module along with the moves
+	// it detects: sibling libs declaring another module path, and modules redirected or deprecated in favour of another
+	ModuleMoves map[string]string `json:"moduleMoves"`
+
+	// Plugins maps action names to shell commands performing them. Commands get the sorted libs and options (without
+	// secrets, see PluginOptions) as json on stdin, and may write json lines naming a lib with a message, error or
+	// updated flag to stdout
+	Plugins map[string]string `json:"plugins"`
+
+	// Providers maps self-hosted git hosts to the provider their pull requests are opened with ("gitlab" or "gitea")
+	Providers map[string]string `json:"providers"`
 }
 
 // New returns new Mod Utils struct
@@ -39,28 +272,125 @@ func New(options Options) *MU {
 
 // Format will wrap options data into a printable output string
 func (o *Options) Format() (output string) {
-	warningActions := []string{"Sync action will:"}
+	msg := o.actionWarning("Sync", o.syncActions("third-party modules")...)
+	msg += "\n\nOn repositories: " + o.FilterDependencies.String()
+	msg += "\nIn directories: " + o.TargetDirectories.String()
+
+	return msg
+}
+
+// actionWarning returns the warning shown before the action, e.g. "Sync action will:" followed by each of actions
+func (o *Options) actionWarning(action string, actions ...string) string {
+	heading := action + " action will:"
+	if o.DryRun {
+		heading = action + " action would (dry run):"
+	}
+
+	return strings.Join(append([]string{heading}, actions...), "\n  ")
+}
+
+// branchActions returns the warning line for checking out the branch, if one is set
+func (o *Options) branchActions() (actions []string) {
 	if o.Branch != "" {
-		warningActions = append(warningActions, "- checkout (or create) branch "+o.Branch)
+		actions = append(actions, "- checkout (or create) branch "+o.Branch)
+	}
+
+	return
+}
+
+// syncActions returns the warning lines of the sync action. Aligned describes the third-party modules to align
+func (o *Options) syncActions(aligned string) (actions []string) {
+	actions = o.branchActions()
+	if o.RestoreReplace {
+		actions = append(actions, "- update mod files without local replace directives, then restore them")
+	} else if o.DropReplace {
+		actions = append(actions, "- update mod files, removing local replace directives")
+	} else {
+		actions = append(actions, "- update mod files")
+	}
+	if o.VerifySums {
+		actions = append(actions, "- verify checksums before and after updating, skipping commits if inconsistent")
+	}
+	if o.Align {
+		actions = append(actions, "- align "+aligned+" required at different versions to the highest")
+	}
+	if o.VerifyTags {
+		if o.OnUnverifiedTag == OnUnverifiedWarn {
+			actions = append(actions, "- warn about deps whose tags aren't signed by a trusted key")
+		} else {
+			actions = append(actions, "- skip deps whose tags aren't signed by a trusted key")
+		}
 	}
-	warningActions = append(warningActions, "- update mod files")
 	if o.Commit {
-		warningActions = append(warningActions, "- commit local changes (if any)")
+		actions = append(actions, "- commit local changes (if any)")
+		if o.SignCommits {
+			actions = append(actions, "- sign commits")
+		}
+	}
+	if o.OnProtected == OnProtectedFail {
+		actions = append(actions, "- fail libs whose branch blocks pushes")
+	} else {
+		actions = append(actions, "- sync on "+FallbackBranch+" and open a pull request where branches block pushes")
 	}
 	if o.PullRequest {
-		warningActions = append(warningActions, "- open pull request for changes (if any)")
+		kind := "pull request"
+		if o.DraftPR {
+			kind = "draft " + kind
+		}
+		if len(o.BaseBranch) > 0 || len(o.BaseBranches) > 0 {
+			kind += " against the base branch"
+		}
+		actions = append(actions, "- open "+kind+" for changes (if any)")
+		if o.BatchPR {
+			actions = append(actions, "- hold pull requests until every lib is synced, linking them to each other")
+		}
+		if o.AutoMerge {
+			actions = append(actions, "- auto-merge pull requests once checks pass")
+		}
+		if o.MergeGreen {
+			actions = append(actions, "- wait for checks and merge pull requests once they pass, before tagging")
+		} else if o.WaitChecks {
+			actions = append(actions, "- wait for pull request checks to pass before tagging")
+		}
 	}
 	if o.Tag {
 		if len(o.SetVersion) > 0 {
-			warningActions = append(warningActions, "- tag all dependencies "+o.SetVersion)
+			actions = append(actions, "- tag all dependencies "+o.SetVersion)
 		} else {
-			warningActions = append(warningActions, "- increment tag version (if updated)")
+			bump := "increment"
+			if len(o.BumpStrategy) > 0 {
+				bump = "bump " + o.BumpStrategy
+			}
+			actions = append(actions, "- "+bump+" tag version (if updated)")
+		}
+		if len(o.Prerelease) > 0 {
+			actions = append(actions, "- tag "+o.Prerelease+" prereleases")
+		}
+		if o.SignTags {
+			actions = append(actions, "- sign tags")
+		}
+		if o.Changelog {
+			actions = append(actions, "- update "+ChangelogName+" and publish release notes")
+		} else if o.CreateRelease {
+			actions = append(actions, "- publish a release for each tag")
+		}
+		if (o.Changelog || o.CreateRelease) && len(o.ReleaseBuild) > 0 {
+			actions = append(actions, "- run `"+o.ReleaseBuild+"` before publishing releases")
+		}
+		if (o.Changelog || o.CreateRelease) && len(o.ReleaseAssets) > 0 {
+			actions = append(actions, "- attach "+strings.Join(o.ReleaseAssets, ", ")+" to releases")
+		}
+	}
+	if len(o.LibraryOptions) > 0 {
+		actions = append(actions, "- override the branch, tagging or pull requests for libs within "+strconv.Itoa(len(o.LibraryOptions))+" module path prefix(es)")
+	}
+	if o.Atomic {
+		if o.Tag && !o.DeleteTags {
+			actions = append(actions, "- roll back all changes if any lib fails, keeping pushed version tags")
+		} else {
+			actions = append(actions, "- roll back all changes if any lib fails")
 		}
 	}
 
-	msg := strings.Join(warningActions, "\n  ")
-	msg += "\n\nOn repositories: " + o.FilterDependencies.String()
-	msg += "\nIn directories: " + o.TargetDirectories.String()
-
-	return msg
+	return
 }
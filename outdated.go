@@ -0,0 +1,220 @@
+package gomu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// OutdatedDep represents a module required by a library with a newer version available
+type OutdatedDep struct {
+	Library string `json:"library"`
+	Module  string `json:"module"`
+
+	Version  string `json:"version"`
+	Latest   string `json:"latest"`
+	Behind   string `json:"behind"` // e.g. "1 major", "2 minor" or "3 patch"
+	Indirect bool   `json:"indirect,omitempty"`
+}
+
+// goModRequire represents a requirement in the output of go mod edit -json
+type goModRequire struct {
+	Path     string `json:"Path"`
+	Version  string `json:"Version"`
+	Indirect bool   `json:"Indirect"`
+}
+
+// goListModule represents a module in the output of go list -m -u -json
+type goListModule struct {
+	Path    string `json:"Path"`
+	Version string `json:"Version"`
+	Update  *struct {
+		Version string `json:"Version"`
+	} `json:"Update"`
+}
+
+// Outdated returns modules required in lib's go.mod which have newer versions available.
+// If prefixes are provided, only modules matching a prefix are checked
+func (lib *Library) Outdated(prefixes []string) (deps []OutdatedDep, err error) {
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		err = fmt.Errorf("unable to read go.mod: %v", err)
+		return
+	}
+
+	var modFile struct {
+		Require []goModRequire `json:"Require"`
+	}
+	if err = json.Unmarshal([]byte(output), &modFile); err != nil {
+		err = fmt.Errorf("unable to parse go.mod: %v", err)
+		return
+	}
+
+	args := []string{lib.File.GoBinary(), "list", "-m", "-u", "-json"}
+	indirect := make(map[string]bool)
+	for _, req := range modFile.Require {
+		if !matchesPrefix(req.Path, prefixes) {
+			continue
+		}
+
+		indirect[req.Path] = req.Indirect
+		args = append(args, req.Path)
+	}
+
+	if len(indirect) == 0 {
+		// Nothing to check
+		return
+	}
+
+	// Queries the module proxy for latest versions
+	if output, err = lib.File.CmdOutput(args...); err != nil {
+		err = fmt.Errorf("unable to list module updates: %v", err)
+		return
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var module goListModule
+		if err = decoder.Decode(&module); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse go list output: %v", err)
+			return
+		}
+
+		if module.Update == nil {
+			// Up to date
+			continue
+		}
+
+		deps = append(deps, OutdatedDep{
+			Library:  lib.File.GetGoURL(),
+			Module:   module.Path,
+			Version:  module.Version,
+			Latest:   module.Update.Version,
+			Behind:   versionsBehind(module.Version, module.Update.Version),
+			Indirect: indirect[module.Path],
+		})
+	}
+
+	return
+}
+
+// matchesPrefix returns true if there are no prefixes or modulePath is within any of them
+func matchesPrefix(modulePath string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if modulePath == prefix || strings.HasPrefix(modulePath, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionsBehind describes the largest difference between two semantic versions (v1.2.3 -> v1.4.0 is "2 minor")
+func versionsBehind(version, latest string) string {
+	current, ok := parseSemver(version)
+	if !ok {
+		return "unknown"
+	}
+
+	next, ok := parseSemver(latest)
+	if !ok {
+		return "unknown"
+	}
+
+	labels := []string{"major", "minor", "patch"}
+	for i := range labels {
+		if next[i] != current[i] {
+			return strconv.Itoa(next[i]-current[i]) + " " + labels[i]
+		}
+	}
+
+	// Pre-release or pseudo-version of the same release
+	return "pre-release"
+}
+
+// parseSemver returns the major, minor and patch of a version, ignoring pre-release and build metadata
+func parseSemver(version string) (parts [3]int, ok bool) {
+	version = strings.TrimPrefix(version, "v")
+	if index := strings.IndexAny(version, "-+"); index >= 0 {
+		version = version[:index]
+	}
+
+	comps := strings.Split(version, ".")
+	if len(comps) != 3 {
+		return
+	}
+
+	for i, comp := range comps {
+		var err error
+		if parts[i], err = strconv.Atoi(comp); err != nil {
+			return
+		}
+	}
+
+	ok = true
+	return
+}
+
+func (mu *MU) outdated(lib Library) {
+	lib.File.Output("Checking for newer dependency versions...")
+
+	deps, err := lib.Outdated(mu.Options.ModuleFilter)
+	if err != nil {
+		lib.File.Error("Outdated check failed :( " + err.Error())
+		return
+	}
+
+	if len(deps) == 0 {
+		lib.File.Output("All deps up to date!")
+		return
+	}
+
+	for _, dep := range deps {
+		lib.File.Output(dep.Module + " " + dep.Version + " -> " + dep.Latest + " (" + dep.Behind + ")")
+	}
+
+	mu.statsMux.Lock()
+	mu.Stats.Outdated = append(mu.Stats.Outdated, deps...)
+	mu.statsMux.Unlock()
+
+	mu.addStat(&mu.Stats.OutdatedCount, &mu.Stats.OutdatedOutput, lib.File.GetGoURL()+" ("+strconv.Itoa(len(deps))+" outdated)\n")
+}
+
+// formatOutdated returns a table of outdated deps, sorted by library then module
+func (stats ActionStats) formatOutdated() string {
+	deps := append([]OutdatedDep(nil), stats.Outdated...)
+	gosort.Slice(deps, func(i, j int) bool {
+		if deps[i].Library != deps[j].Library {
+			return deps[i].Library < deps[j].Library
+		}
+		return deps[i].Module < deps[j].Module
+	})
+
+	var output bytes.Buffer
+	writer := tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LIBRARY\tMODULE\tCURRENT\tLATEST\tBEHIND")
+	for _, dep := range deps {
+		module := dep.Module
+		if dep.Indirect {
+			module += " (indirect)"
+		}
+
+		fmt.Fprintln(writer, dep.Library+"\t"+module+"\t"+dep.Version+"\t"+dep.Latest+"\t"+dep.Behind)
+	}
+	writer.Flush()
+
+	return output.String()
+}
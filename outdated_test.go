@@ -0,0 +1,53 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestOutdated(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Require": [
+		{"Path": "github.com/org/b", "Version": "v1.2.3"},
+		{"Path": "github.com/org/c", "Version": "v0.1.0", "Indirect": true},
+		{"Path": "golang.org/x/text", "Version": "v0.3.7"}
+	]}`})
+	recorder.Reply("go list -m -u -json", com.Reply{Stdout: `{"Path": "github.com/org/b", "Version": "v1.2.3", "Update": {"Version": "v1.4.0"}}
+{"Path": "github.com/org/c", "Version": "v0.1.0"}
+`})
+
+	deps, err := lib.Outdated([]string{"github.com/org/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only modules within the prefix are listed
+	commands := []string{"go mod edit -json", "go list -m -u -json github.com/org/b github.com/org/c"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, commands) {
+		t.Errorf("expected %q, got %q", commands, lines)
+	}
+
+	expected := []OutdatedDep{{Library: lib.File.GetGoURL(), Module: "github.com/org/b", Version: "v1.2.3", Latest: "v1.4.0", Behind: "2 minor"}}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("expected %+v, got %+v", expected, deps)
+	}
+}
+
+func TestVersionsBehind(t *testing.T) {
+	for _, test := range []struct {
+		version, latest, behind string
+	}{
+		{"v1.2.3", "v2.0.0", "1 major"},
+		{"v1.2.3", "v1.4.0", "2 minor"},
+		{"v1.2.3", "v1.2.6", "3 patch"},
+		{"v1.2.3-rc.1", "v1.2.3", "pre-release"},
+		{"v0.0.0-20200101000000-abcdef123456", "v0.1.0", "1 minor"},
+		{"master", "v1.0.0", "unknown"},
+	} {
+		if behind := versionsBehind(test.version, test.latest); behind != test.behind {
+			t.Errorf("expected %s -> %s to be %q behind, got %q", test.version, test.latest, test.behind, behind)
+		}
+	}
+}
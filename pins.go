@@ -0,0 +1,121 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PinsName is the file listing modules held at specific versions, read from the working directory and each lib
+const PinsName = "gomu.pins"
+
+// Pins maps module paths to the versions they're held at
+type Pins map[string]string
+
+// LoadPins reads pins from a file with a module and version on each line, e.g. github.com/org/lib v1.2.3.
+// Blank lines and comments (# or //) are ignored
+func LoadPins(pinsPath string) (pins Pins, err error) {
+	data, err := ioutil.ReadFile(pinsPath)
+	if err != nil {
+		return
+	}
+
+	pins = make(Pins)
+	for i, line := range strings.Split(string(data), "\n") {
+		for _, comment := range []string{"#", "//"} {
+			if index := strings.Index(line, comment); index >= 0 {
+				line = line[:index]
+			}
+		}
+
+		fields := strings.Fields(strings.Replace(line, "@", " ", 1))
+		switch len(fields) {
+		case 0:
+			continue
+		case 2:
+			pins[fields[0]] = fields[1]
+		default:
+			err = fmt.Errorf("%s:%d: expected module and version, found %q", pinsPath, i+1, strings.TrimSpace(line))
+			return
+		}
+	}
+
+	return
+}
+
+// loadPins reads run-wide pins from mu.Options.PinsFile, or PinsName in the working directory if present
+func (mu *MU) loadPins() (err error) {
+	pinsPath := mu.Options.PinsFile
+	if len(pinsPath) == 0 {
+		if _, statErr := os.Stat(PinsName); statErr != nil {
+			// No pins
+			return
+		}
+		pinsPath = PinsName
+	}
+
+	if mu.pins, err = LoadPins(pinsPath); err != nil {
+		err = fmt.Errorf("unable to load pins: %v", err)
+	}
+
+	return
+}
+
+// pinsFor returns run-wide pins, overridden by those in lib's own pins file
+func (mu *MU) pinsFor(lib Library) (pins Pins) {
+	libPins, err := LoadPins(filepath.Join(lib.File.Path, PinsName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			lib.File.Error(err.Error())
+		}
+		return mu.pins
+	}
+
+	pins = make(Pins, len(mu.pins)+len(libPins))
+	for module, version := range mu.pins {
+		pins[module] = version
+	}
+	for module, version := range libPins {
+		pins[module] = version
+	}
+
+	return
+}
+
+// pinned returns the version module is held at in lib, warning if it would otherwise have been updated to version
+func (lib *Library) pinned(module, version string) (held bool) {
+	pin, held := lib.pins[module]
+	if held && pin != version {
+		lib.File.Output("Warning - " + module + " is pinned @ " + pin + ", skipping update to " + version)
+	}
+
+	return
+}
+
+// checkPins warns about pinned modules resolved to another version, e.g. when required at a newer version by another dep
+func (lib *Library) checkPins() {
+	if len(lib.pins) == 0 {
+		return
+	}
+
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		return
+	}
+
+	var modFile struct {
+		Require []goModRequire `json:"Require"`
+	}
+	if json.Unmarshal([]byte(output), &modFile) != nil {
+		return
+	}
+
+	for _, req := range modFile.Require {
+		if pin, ok := lib.pins[req.Path]; ok && pin != req.Version {
+			lib.File.Output("Warning - " + req.Path + " is pinned @ " + pin + " but resolved @ " + req.Version)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestLoadPins(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	pinsPath := filepath.Join(lib.File.Path, PinsName)
+
+	pins := "# Held until the api is migrated\ngithub.com/org/b v1.2.3\n\ngithub.com/org/c@v0.4.0 // breaks c's consumers\n"
+	if err := ioutil.WriteFile(pinsPath, []byte(pins), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadPins(pinsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Pins{"github.com/org/b": "v1.2.3", "github.com/org/c": "v0.4.0"}); !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("expected %v, got %v", expected, loaded)
+	}
+
+	if err = ioutil.WriteFile(pinsPath, []byte("github.com/org/b v1.2.3\ngithub.com/org/c\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadPins(pinsPath); err == nil {
+		t.Error("expected pin without a version to fail")
+	}
+}
+
+func TestPinsFor(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+	if err := ioutil.WriteFile(filepath.Join(lib.File.Path, PinsName), []byte("github.com/org/c v0.5.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mu := &MU{pins: Pins{"github.com/org/b": "v1.2.3", "github.com/org/c": "v0.4.0"}}
+	// The lib's pins override run-wide pins
+	expected := Pins{"github.com/org/b": "v1.2.3", "github.com/org/c": "v0.5.0"}
+	if pins := mu.pinsFor(*lib); !reflect.DeepEqual(pins, expected) {
+		t.Errorf("expected %v, got %v", expected, pins)
+	}
+}
+
+func TestPlanSkipsPinned(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-parse HEAD", com.Reply{Stdout: "abc123\n"})
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Require": [
+		{"Path": "github.com/org/b", "Version": "v1.0.0"},
+		{"Path": "github.com/org/c", "Version": "v1.0.0"}
+	]}`})
+
+	mu := &MU{pins: Pins{"github.com/org/b": "v1.0.0"}}
+	planned, err := mu.planLibrary(*lib, map[string]string{"github.com/org/b": "v1.1.0", "github.com/org/c": "v1.1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PlannedDep{{Module: "github.com/org/c", From: "v1.0.0", To: "v1.1.0"}}
+	if !reflect.DeepEqual(planned.Deps, expected) {
+		t.Errorf("expected pinned dep to be held, got %+v", planned.Deps)
+	}
+}
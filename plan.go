@@ -0,0 +1,281 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// PlanName is the file plans are written to and applied from when no plan file is provided
+const PlanName = ".gomu-plan.json"
+
+// Plan represents every change a sync would make, computed without changing anything
+type Plan struct {
+	Created time.Time `json:"created"`
+
+	// Options the plan was computed with, used when applying
+	Options Options `json:"options"`
+
+	// Libraries in sync order
+	Libraries []PlannedLibrary `json:"libraries"`
+}
+
+// PlannedLibrary represents the changes planned for a single library
+type PlannedLibrary struct {
+	Library string `json:"library"`
+	Path    string `json:"path"`
+
+	// Head is the commit the plan was computed from. Applying fails if it has moved
+	Head string `json:"head"`
+
+	Branch       string `json:"branch,omitempty"`
+	CreateBranch bool   `json:"createBranch,omitempty"`
+
+	// Version is the latest tag when planned, NextVersion is the tag to create (empty if not tagging)
+	Version     string `json:"version,omitempty"`
+	NextVersion string `json:"nextVersion,omitempty"`
+
+	Deps []PlannedDep `json:"deps,omitempty"`
+}
+
+// PlannedDep represents a dependency version change within a library's go.mod
+type PlannedDep struct {
+	Module string `json:"module"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// HasChanges returns true if anything is planned for the library
+func (planned PlannedLibrary) HasChanges() bool {
+	return planned.CreateBranch || len(planned.NextVersion) > 0 || len(planned.Deps) > 0
+}
+
+// describe returns a short description of the planned changes
+func (planned PlannedLibrary) describe() string {
+	var changes []string
+	if planned.CreateBranch {
+		changes = append(changes, "create branch "+planned.Branch)
+	}
+	for _, dep := range planned.Deps {
+		changes = append(changes, "update "+dep.Module+" "+dep.From+" -> "+dep.To)
+	}
+	if len(planned.NextVersion) > 0 {
+		changes = append(changes, "tag "+planned.NextVersion)
+	}
+
+	if len(changes) == 0 {
+		return "no changes"
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// LoadPlan reads a plan from disk
+func LoadPlan(planPath string) (plan Plan, err error) {
+	data, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &plan)
+	return
+}
+
+// Save writes the plan to disk
+func (plan *Plan) Save(planPath string) (err error) {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(planPath, data, 0644)
+}
+
+// library returns the planned changes for the library at libPath
+func (plan *Plan) library(libPath string) (planned PlannedLibrary, ok bool) {
+	for _, planned = range plan.Libraries {
+		if planned.Path == libPath {
+			return planned, true
+		}
+	}
+
+	return PlannedLibrary{}, false
+}
+
+// planFile returns the configured plan file
+func (mu *MU) planFile() string {
+	if len(mu.Options.PlanFile) > 0 {
+		return mu.Options.PlanFile
+	}
+
+	return PlanName
+}
+
+// planSync computes the changes a sync would make to each lib and writes them to the plan file
+func (mu *MU) planSync(fileHead *sort.FileNode) {
+	plan := Plan{Created: time.Now(), Options: mu.Options}
+	plan.Options.Action = "sync"
+
+	// Versions of libs earlier in the chain once the plan is applied
+	versions := make(map[string]string)
+
+	index := 0
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		index++
+		if mu.isClosed() {
+			// Incomplete plans can't be applied
+			return
+		}
+
+		lib := Library{File: itr.File}
+		mu.startLibrary(lib)
+		done := mu.beginLibrary(index, lib)
+
+		planned, err := mu.planLibrary(lib, versions)
+		done()
+		if err != nil {
+			mu.libraryError(lib, fmt.Errorf("unable to plan: %v", err))
+			return
+		}
+
+		plan.Libraries = append(plan.Libraries, planned)
+		lib.File.Output("Plan: " + planned.describe())
+
+		if planned.HasChanges() {
+			mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, planned.Library+": "+planned.describe()+"\n")
+		}
+	}
+
+	if err := plan.Save(mu.planFile()); err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to save plan: %v", err))
+	}
+}
+
+// planLibrary computes the changes a sync would make to lib, given the planned versions of libs earlier in the chain
+func (mu *MU) planLibrary(lib Library, versions map[string]string) (planned PlannedLibrary, err error) {
+	planned.Library = lib.File.GetGoURL()
+	planned.Path = lib.File.Path
+	planned.Branch = mu.branch(lib)
+
+	lib.File.Output("Fetching...")
+	lib.File.Fetch()
+
+	if planned.Head = lib.File.HeadCommit(); len(planned.Head) == 0 {
+		err = fmt.Errorf("no commits found")
+		return
+	}
+
+	if len(planned.Branch) > 0 {
+		_, localErr := lib.File.CmdOutput("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+planned.Branch)
+		_, remoteErr := lib.File.CmdOutput("git", "rev-parse", "--verify", "--quiet", "refs/remotes/"+com.PushRemote()+"/"+planned.Branch)
+		planned.CreateBranch = localErr != nil && remoteErr != nil
+	}
+
+	// Compare required versions with planned versions of earlier libs
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		err = fmt.Errorf("unable to read go.mod: %v", err)
+		return
+	}
+
+	var modFile struct {
+		Require []goModRequire `json:"Require"`
+	}
+	if err = json.Unmarshal([]byte(output), &modFile); err != nil {
+		err = fmt.Errorf("unable to parse go.mod: %v", err)
+		return
+	}
+
+	lib.pins = mu.pinsFor(lib)
+	for _, req := range modFile.Require {
+		if version, ok := versions[req.Path]; ok && len(version) > 0 && version != req.Version && !lib.pinned(req.Path, version) {
+			planned.Deps = append(planned.Deps, PlannedDep{Module: req.Path, From: req.Version, To: version})
+		}
+	}
+
+	planned.Version = lib.GetLatestTag()
+	if mu.libraryOptions(lib).Tag {
+		if len(mu.Options.SetVersion) > 0 {
+			planned.NextVersion = mu.Options.SetVersion
+		} else if len(planned.Deps) > 0 || lib.ShouldTag() {
+			if planned.NextVersion, err = mu.nextVersion(lib, planned.Version); err != nil {
+				return
+			}
+			if len(planned.NextVersion) == 0 {
+				planned.NextVersion = incrementPatch(planned.Version)
+			}
+		}
+	}
+
+	versions[planned.Library] = planned.Version
+	if len(planned.NextVersion) > 0 {
+		versions[planned.Library] = planned.NextVersion
+	}
+
+	return
+}
+
+// loadPlan replaces options with those of the plan file, keeping output and run settings. Returns false if unable to load
+func (mu *MU) loadPlan() bool {
+	plan, err := LoadPlan(mu.planFile())
+	if err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to load plan %s: %v", mu.planFile(), err))
+		return false
+	}
+
+	// Keep settings which don't affect the changes made
+	options := plan.Options
+	options.LogLevel = mu.Options.LogLevel
+	options.IgnoreWarning = mu.Options.IgnoreWarning
+	options.DryRun = mu.Options.DryRun
+	options.Output = mu.Options.Output
+	options.LibraryTimeout = mu.Options.LibraryTimeout
+	options.Deadline = mu.Options.Deadline
+	options.PlanFile = mu.planFile()
+
+	com.Println("\nApplying plan from", plan.Created.Format(time.RFC1123), "for", len(plan.Libraries), "lib(s)...")
+
+	mu.plan = &plan
+	mu.Options = options
+	mu.Stats.Options = &mu.Options
+	return true
+}
+
+// verifyPlan returns false if any planned lib has moved since the plan was computed, or is missing
+func (mu *MU) verifyPlan(fileHead *sort.FileNode) (ok bool) {
+	ok = true
+
+	found := make(map[string]bool)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		planned, inPlan := mu.plan.library(itr.File.Path)
+		if !inPlan {
+			itr.File.Error("Not in plan. Run plan again.")
+			ok = false
+			continue
+		}
+
+		found[planned.Path] = true
+		if head := itr.File.HeadCommit(); head != planned.Head {
+			itr.File.Error("Changed since plan (" + planned.Head + " -> " + head + "). Run plan again.")
+			ok = false
+		}
+	}
+
+	for _, planned := range mu.plan.Libraries {
+		if !found[planned.Path] {
+			com.Logln(com.ERROR, "Planned lib not found. Run plan again.", com.LibraryField, planned.Path)
+			ok = false
+		}
+	}
+
+	if !ok {
+		mu.Errors = append(mu.Errors, fmt.Errorf("plan is out of date"))
+	}
+
+	return
+}
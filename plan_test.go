@@ -0,0 +1,72 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestPlanLibrary(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-parse HEAD", com.Reply{Stdout: "abc123\n"})
+	recorder.Reply("git rev-parse --verify", com.Reply{ExitCode: 1})
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.2.0\n"})
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Require": [
+		{"Path": "github.com/org/b", "Version": "v1.0.0"},
+		{"Path": "github.com/org/c", "Version": "v1.0.0"}
+	]}`})
+
+	mu := &MU{Options: Options{Branch: "deps", Tag: true}}
+	// Planned versions of libs earlier in the chain
+	versions := map[string]string{"github.com/org/b": "v1.1.0", "github.com/org/c": "v1.0.0"}
+	planned, err := mu.planLibrary(*lib, versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := PlannedLibrary{
+		Library:      lib.File.GetGoURL(),
+		Path:         lib.File.Path,
+		Head:         "abc123",
+		Branch:       "deps",
+		CreateBranch: true,
+		Version:      "v1.2.0",
+		NextVersion:  "v1.2.1",
+		Deps:         []PlannedDep{{Module: "github.com/org/b", From: "v1.0.0", To: "v1.1.0"}},
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("expected %+v, got %+v", expected, planned)
+	}
+	if versions[planned.Library] != "v1.2.1" {
+		t.Errorf("expected lib's planned version for later libs, got %q", versions[planned.Library])
+	}
+	if expected := "create branch deps, update github.com/org/b v1.0.0 -> v1.1.0, tag v1.2.1"; planned.describe() != expected {
+		t.Errorf("expected %q, got %q", expected, planned.describe())
+	}
+}
+
+func TestVerifyPlan(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-parse HEAD", com.Reply{Stdout: "abc123\n"})
+	fileHead := &sort.FileNode{File: lib.File}
+
+	mu := &MU{}
+	mu.plan = &Plan{Libraries: []PlannedLibrary{{Path: lib.File.Path, Head: "abc123"}}}
+	if !mu.verifyPlan(fileHead) {
+		t.Errorf("expected plan to be up to date, got %v", mu.Errors)
+	}
+
+	for name, libraries := range map[string][]PlannedLibrary{
+		"moved":   {{Path: lib.File.Path, Head: "def456"}},
+		"missing": {{Path: lib.File.Path, Head: "abc123"}, {Path: "/libs/b", Head: "abc123"}},
+		"new":     {},
+	} {
+		mu = &MU{}
+		mu.plan = &Plan{Libraries: libraries}
+		if mu.verifyPlan(fileHead) || len(mu.Errors) == 0 {
+			t.Errorf("expected plan with a %s lib to be out of date", name)
+		}
+	}
+}
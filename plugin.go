@@ -0,0 +1,317 @@
+package gomu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// PluginActionEnv is set to the action name in the environment of plugin commands
+const PluginActionEnv = "GOMU_ACTION"
+
+// builtinActions can't be replaced by plugins
+var builtinActions = []string{
+	"affected", "apply", "audit", "check", "clean", "conflicts", "diff", "exec", "go-version", "graph", "grep",
+	"history", "init", "licenses", "list", "major", "migrate-module", "outdated", "plan", "promote", "pull", "recover-stash",
+	"replace", "reset", "secret", "status", "sync", "test", "tidy", "undo", "vendor", "watch", "workflow",
+}
+
+// ActionFunc performs a plugin action on libs, sorted so deps come before dependents. Failures of a single lib
+// should be recorded on its File, a returned error fails the run
+type ActionFunc func(mu *MU, libs []Library) error
+
+// PluginRequest is written as json to the stdin of plugin commands
+type PluginRequest struct {
+	Action  string        `json:"action"`
+	Options PluginOptions `json:"options"`
+
+	// Libraries in sync order, deps before dependents
+	Libraries []PluginLibrary `json:"libraries"`
+}
+
+// PluginOptions are the options of the run passed to plugin commands, named as in Options. Secrets and settings which
+// may hold them, e.g. the slack webhook, otlp headers and env vars, aren't passed
+type PluginOptions struct {
+	Branch        string `json:"branch"`
+	CommitMessage string `json:"message"`
+	Ticket        string `json:"ticket"`
+	Commit        bool   `json:"commit"`
+	PullRequest   bool   `json:"createPR"`
+	DraftPR       bool   `json:"draftPR"`
+	BaseBranch    string `json:"baseBranch"`
+	Remote        string `json:"remote"`
+
+	Tag           bool   `json:"shouldTag"`
+	SetVersion    string `json:"setVersion"`
+	BumpStrategy  string `json:"bumpStrategy"`
+	Prerelease    string `json:"prerelease"`
+	TagPrefix     string `json:"tagPrefix"`
+	VersionScheme string `json:"versionScheme"`
+
+	DirectImport       bool             `json:"direct"`
+	FilterDependencies sort.StringArray `json:"syncLibs"`
+	GoBinary           string           `json:"goBinary"`
+	KeepGoing          bool             `json:"keepGoing"`
+	DryRun             bool             `json:"dryRun"`
+	Output             string           `json:"output"`
+}
+
+// pluginOptions returns the options of the run passed to plugin commands
+func pluginOptions(options Options) PluginOptions {
+	return PluginOptions{
+		Branch:        options.Branch,
+		CommitMessage: options.CommitMessage,
+		Ticket:        options.Ticket,
+		Commit:        options.Commit,
+		PullRequest:   options.PullRequest,
+		DraftPR:       options.DraftPR,
+		BaseBranch:    options.BaseBranch,
+		Remote:        options.Remote,
+
+		Tag:           options.Tag,
+		SetVersion:    options.SetVersion,
+		BumpStrategy:  options.BumpStrategy,
+		Prerelease:    options.Prerelease,
+		TagPrefix:     options.TagPrefix,
+		VersionScheme: options.VersionScheme,
+
+		DirectImport:       options.DirectImport,
+		FilterDependencies: options.FilterDependencies,
+		GoBinary:           options.GoBinary,
+		KeepGoing:          options.KeepGoing,
+		DryRun:             options.DryRun,
+		Output:             options.Output,
+	}
+}
+
+// PluginLibrary represents a library passed to a plugin command
+type PluginLibrary struct {
+	Library string `json:"library"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+
+	// Kind of package manifest, e.g. go or npm, and the package name it declares
+	Kind    string `json:"kind"`
+	Package string `json:"package,omitempty"`
+
+	// Go urls of the libraries it depends on
+	Deps []string `json:"deps,omitempty"`
+}
+
+// PluginMessage is read from each json line a plugin command writes to stdout, other lines are printed as they are.
+// Messages without a known library are about the whole run
+type PluginMessage struct {
+	Library string `json:"library"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+
+	// Updated counts the library as changed by the action
+	Updated bool `json:"updated"`
+}
+
+// validateAction returns an error if name can't be used for a plugin action
+func validateAction(name string) error {
+	if len(name) == 0 {
+		return errors.New("plugin action needs a name")
+	}
+
+	for _, builtin := range builtinActions {
+		if name == builtin {
+			return fmt.Errorf("plugin action %q conflicts with the built-in action", name)
+		}
+	}
+
+	return nil
+}
+
+// RegisterAction adds fn as the action name, performed instead of the plugin command in mu.Options.Plugins if both
+// are set
+func (mu *MU) RegisterAction(name string, fn ActionFunc) (err error) {
+	if err = validateAction(name); err != nil {
+		return
+	}
+
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	if mu.actions == nil {
+		mu.actions = make(map[string]ActionFunc)
+	}
+	mu.actions[name] = fn
+	return
+}
+
+// registeredAction returns the function registered as action, nil if there's none
+func (mu *MU) registeredAction(action string) ActionFunc {
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	return mu.actions[action]
+}
+
+// isPlugin is true if the action is performed by a plugin
+func (mu *MU) isPlugin() bool {
+	if mu.registeredAction(mu.Options.Action) != nil {
+		return true
+	}
+
+	_, ok := mu.Options.Plugins[mu.Options.Action]
+	return ok
+}
+
+// validatePlugins returns an error if a plugin command in the options can't be used
+func (mu *MU) validatePlugins() error {
+	for name, command := range mu.Options.Plugins {
+		if err := validateAction(name); err != nil {
+			return err
+		}
+
+		if len(strings.TrimSpace(command)) == 0 {
+			return fmt.Errorf("plugin action %q needs a command", name)
+		}
+	}
+
+	return nil
+}
+
+// runPlugin performs the plugin action on the sorted libs, registered functions take precedence over commands
+func (mu *MU) runPlugin(fileHead *sort.FileNode) {
+	mu.Stats.Plugin = true
+
+	var libs []Library
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		itr.File.Step = mu.Options.Action
+		libs = append(libs, Library{File: itr.File})
+	}
+
+	var err error
+	if fn := mu.registeredAction(mu.Options.Action); fn != nil {
+		err = fn(mu, libs)
+	} else {
+		err = mu.runPluginCommand(fileHead, libs)
+	}
+
+	if err != nil {
+		com.Println("\nPlugin", mu.Options.Action, "failed :(", err)
+		mu.statsMux.Lock()
+		mu.Errors = append(mu.Errors, fmt.Errorf("plugin %s failed: %v", mu.Options.Action, err))
+		mu.statsMux.Unlock()
+	}
+}
+
+// runPluginCommand runs the plugin command with the request on stdin, applying the messages it writes to libs
+// Note: plugins see the dry run option and are trusted to honour it
+func (mu *MU) runPluginCommand(fileHead *sort.FileNode, libs []Library) (err error) {
+	command := mu.Options.Plugins[mu.Options.Action]
+	request := PluginRequest{Action: mu.Options.Action, Options: pluginOptions(mu.Options)}
+
+	deps := make(map[*com.FileWrapper][]string)
+	for _, edge := range sort.GraphFrom(fileHead, mu.Options.DirectImport).Edges {
+		deps[edge.From.File] = append(deps[edge.From.File], edge.To.File.GetGoURL())
+	}
+
+	// Messages name libs by go url or path
+	byURL := make(map[string]Library, 2*len(libs))
+	for _, lib := range libs {
+		byURL[lib.File.GetGoURL()] = lib
+		byURL[lib.File.OriginalPath()] = lib
+		manifest := lib.File.Manifest()
+		request.Libraries = append(request.Libraries, PluginLibrary{
+			Library: lib.File.GetGoURL(),
+			Path:    lib.File.OriginalPath(),
+			Version: lib.File.Version,
+			Branch:  mu.branch(lib),
+			Kind:    manifest.Kind,
+			Package: manifest.Name,
+			Deps:    deps[lib.File],
+		})
+	}
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+
+	com.Println("\nRunning plugin `" + command + "`...")
+	shell := com.ShellArgs(command)
+	stdout, writer := io.Pipe()
+	var stderr bytes.Buffer
+	cmd := com.Command{
+		Name:   shell[0],
+		Args:   shell[1:],
+		Env:    append(com.Environ(), PluginActionEnv+"="+mu.Options.Action),
+		Stdin:  bytes.NewReader(input),
+		Stdout: writer,
+		Stderr: &stderr,
+	}
+
+	finished := make(chan error, 1)
+	go func() {
+		runErr := com.GlobalRunner().Run(mu.ctx, cmd)
+		writer.Close()
+		finished <- runErr
+	}()
+
+	// Messages are applied as they're written so progress shows while the plugin runs
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		mu.pluginMessage(scanner.Text(), byURL)
+	}
+	// Drain output past an overlong line so the plugin isn't blocked writing
+	io.Copy(ioutil.Discard, stdout)
+
+	if err = <-finished; err != nil {
+		if output := strings.TrimSpace(stderr.String()); len(output) > 0 {
+			err = fmt.Errorf("%v: %s", err, output)
+		}
+		return
+	}
+
+	return scanner.Err()
+}
+
+// pluginMessage applies a line written by a plugin command to the library it names
+func (mu *MU) pluginMessage(line string, byURL map[string]Library) {
+	var message PluginMessage
+	if !strings.HasPrefix(strings.TrimSpace(line), "{") || json.Unmarshal([]byte(line), &message) != nil {
+		com.Println(line)
+		return
+	}
+
+	lib, ok := byURL[message.Library]
+	if !ok || lib.File == nil {
+		if len(message.Message) > 0 {
+			com.Println(message.Message)
+		}
+		if len(message.Error) > 0 {
+			com.Println("Error:", message.Error)
+			mu.statsMux.Lock()
+			mu.Errors = append(mu.Errors, fmt.Errorf("plugin %s: %s", mu.Options.Action, message.Error))
+			mu.statsMux.Unlock()
+		}
+		return
+	}
+
+	if len(message.Message) > 0 {
+		lib.File.Output(message.Message)
+	}
+
+	if len(message.Error) > 0 {
+		mu.libraryError(lib, errors.New(message.Error))
+	}
+
+	if message.Updated && !lib.File.Updated {
+		lib.File.Updated = true
+		mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+"\n")
+	}
+}
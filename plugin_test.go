@@ -0,0 +1,37 @@
+package gomu
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPluginOptions(t *testing.T) {
+	options := Options{
+		Branch:       "deps",
+		Tag:          true,
+		DryRun:       true,
+		SlackWebhook: "https://hooks.slack.com/services/secret-webhook",
+		NotifyURL:    "https://example.com/notify?token=secret-notify",
+		OTLPHeaders:  map[string]string{"Authorization": "Bearer secret-otlp"},
+		Env:          map[string]string{"GOPRIVATE_TOKEN": "secret-env"},
+		LibraryEnv:   map[string]map[string]string{"github.com/org": {"TOKEN": "secret-library-env"}},
+	}
+
+	input, err := json.Marshal(PluginRequest{Action: "release", Options: pluginOptions(options)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(input), "secret") {
+		t.Errorf("expected no secrets, got %s", input)
+	}
+
+	var request PluginRequest
+	if err = json.Unmarshal(input, &request); err != nil {
+		t.Fatal(err)
+	}
+	if request.Options.Branch != "deps" || !request.Options.Tag || !request.Options.DryRun {
+		t.Errorf("expected branch, tag and dry run options, got %+v", request.Options)
+	}
+}
@@ -0,0 +1,94 @@
+package gomu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// PRTemplateData represents the values available to pull request templates
+type PRTemplateData struct {
+	// Go url of the library
+	Library string
+	Path    string
+
+	Branch string
+	Target string
+
+	// Deps set by the sync formatted as url@version
+	UpdatedDeps []string
+
+	// Latest tag before syncing, and the tag expected once synced (if tagging)
+	OldVersion string
+	NewVersion string
+
+	// Default commit details
+	CommitTitle   string
+	CommitMessage string
+}
+
+// loadPRTemplate parses the pull request template file set in options
+func (mu *MU) loadPRTemplate() (err error) {
+	data, err := ioutil.ReadFile(mu.Options.PRTemplate)
+	if err != nil {
+		return
+	}
+
+	mu.prTemplate, err = template.New("pr").Parse(string(data))
+	return
+}
+
+// renderPRTemplate returns the pull request title (first line) and body (remaining lines) for lib
+func (mu *MU) renderPRTemplate(lib Library, branch, target, commitTitle, commitMessage string) (title, body string, err error) {
+	mu.libraryMux.Lock()
+	if mu.prTemplate == nil {
+		err = mu.loadPRTemplate()
+	}
+	mu.libraryMux.Unlock()
+
+	if err != nil {
+		err = fmt.Errorf("unable to load pr template %s: %v", mu.Options.PRTemplate, err)
+		return
+	}
+
+	data := PRTemplateData{
+		Library: lib.File.GetGoURL(),
+		Path:    lib.File.OriginalPath(),
+
+		Branch: branch,
+		Target: target,
+
+		OldVersion: lib.GetLatestTag(),
+
+		CommitTitle:   commitTitle,
+		CommitMessage: strings.TrimSpace(commitMessage),
+	}
+
+	for itr := lib.updatedDeps; itr != nil; itr = itr.Next {
+		data.UpdatedDeps = append(data.UpdatedDeps, itr.File.GetGoURL()+"@"+itr.File.Version)
+	}
+
+	if mu.libraryOptions(lib).Tag {
+		if len(mu.Options.SetVersion) > 0 {
+			data.NewVersion = mu.Options.SetVersion
+		} else {
+			data.NewVersion = incrementPatch(data.OldVersion)
+		}
+	}
+
+	var output bytes.Buffer
+	if err = mu.prTemplate.Execute(&output, data); err != nil {
+		err = fmt.Errorf("unable to render pr template %s: %v", mu.Options.PRTemplate, err)
+		return
+	}
+
+	comps := strings.SplitN(strings.TrimSpace(output.String()), "\n", 2)
+	title = strings.TrimSpace(comps[0])
+	if len(comps) > 1 {
+		body = strings.TrimSpace(comps[1])
+	}
+
+	return
+}
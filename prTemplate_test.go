@@ -0,0 +1,52 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestRenderPRTemplate(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.2.0\n"})
+
+	templatePath := filepath.Join(lib.File.Path, "pr.tmpl")
+	template := "{{.CommitTitle}} ({{.OldVersion}} -> {{.NewVersion}})\n\nMerges {{.Branch}} into {{.Target}}\n\n{{.CommitMessage}}\n"
+	if err := ioutil.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mu := &MU{Options: Options{PRTemplate: templatePath, Tag: true}}
+	title, body, err := mu.renderPRTemplate(*lib, "deps", "master", "Sync deps", "Updated deps\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "Sync deps (v1.2.0 -> v1.2.1)"; title != expected {
+		t.Errorf("expected title %q, got %q", expected, title)
+	}
+	if expected := "Merges deps into master\n\nUpdated deps"; body != expected {
+		t.Errorf("expected body %q, got %q", expected, body)
+	}
+}
+
+func TestRenderPRTemplateInvalid(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+
+	templatePath := filepath.Join(lib.File.Path, "pr.tmpl")
+	if err := ioutil.WriteFile(templatePath, []byte("{{.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mu := &MU{Options: Options{PRTemplate: templatePath}}
+	if _, _, err := mu.renderPRTemplate(*lib, "deps", "master", "Sync deps", ""); err == nil {
+		t.Error("expected error rendering a field pull request templates don't have")
+	}
+
+	mu = &MU{Options: Options{PRTemplate: filepath.Join(lib.File.Path, "missing.tmpl")}}
+	if _, _, err := mu.renderPRTemplate(*lib, "deps", "master", "Sync deps", ""); err == nil {
+		t.Error("expected error loading a missing pull request template")
+	}
+}
@@ -0,0 +1,127 @@
+package gomu
+
+import (
+	"fmt"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// LatestPrerelease returns lib's latest prerelease on channel (any channel if empty).
+// Returns an empty version if lib's latest version is a stable release
+func (lib *Library) LatestPrerelease(channel string) (version string, err error) {
+	versions, err := lib.versionTags()
+	if err != nil {
+		return
+	}
+
+	for _, tagged := range versions {
+		_, taggedChannel, _ := com.SplitPrerelease(tagged)
+		if len(taggedChannel) == 0 {
+			// Already released
+			return
+		}
+
+		if len(channel) == 0 || taggedChannel == channel {
+			return tagged, nil
+		}
+	}
+
+	return
+}
+
+// tagCommit creates and pushes tag name on commit, signed with key if provided
+func (lib *Library) tagCommit(name, commit, key string) (err error) {
+	if len(key) > 0 {
+		err = lib.File.RunCmd("git", "tag", "-s", "-u", key, "-m", name, name, commit)
+	} else {
+		err = lib.File.RunCmd("git", "tag", name, commit)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to tag %s: %v", name, err)
+	}
+
+	if err = lib.File.RunCmdWithRetry("git", "push", com.PushRemote(), name); err != nil {
+		return fmt.Errorf("unable to push tag %s", name)
+	}
+
+	return
+}
+
+// promote tags lib's latest prerelease as a stable release. Siblings promoted earlier in the chain are required
+// at their stable versions first, committing on top of the prerelease
+func (mu *MU) promote(lib Library, fileHead *sort.FileNode) {
+	lib.File.Output("Checking for prereleases...")
+
+	prerelease, err := lib.LatestPrerelease(mu.Options.Prerelease)
+	if err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to list tags: %v", err))
+		return
+	} else if len(prerelease) == 0 {
+		lib.File.Output("No prerelease to promote.")
+		return
+	}
+
+	release, _, _ := com.SplitPrerelease(prerelease)
+	commit, err := lib.File.CmdOutput("git", "rev-list", "-n", "1", lib.TagName(prerelease))
+	if err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to find commit of %s: %v", lib.TagName(prerelease), err))
+		return
+	}
+
+	var key string
+	if mu.Options.SignTags {
+		if key, err = lib.signingKey("tag", mu.Options.SigningKey); err != nil {
+			mu.libraryError(lib, err)
+			return
+		}
+	}
+
+	// Aggregate siblings promoted earlier in the chain
+	lib.ModAddDeps(fileHead, false)
+
+	if lib.updatedDeps != nil {
+		head := lib.File.HeadCommit()
+		if head != commit {
+			mu.libraryError(lib, fmt.Errorf("%s has commits since %s, sync them before promoting", lib.File.GetGoURL(), prerelease))
+			return
+		}
+
+		lib.File.Output("Requiring promoted deps...")
+
+		commitTitle, commitMessage := mu.getCommitDetails(lib)
+		lib.pins = mu.pinsFor(lib)
+		lib.trust = mu.tagTrust()
+		if err = mu.setCommitKey(&lib); err != nil {
+			mu.libraryError(lib, err)
+			return
+		}
+		if err = lib.ModUpdate("", commitTitle+"\n"+commitMessage, mu.replacePolicy()); err != nil {
+			mu.libraryError(lib, err)
+			return
+		}
+
+		mu.recordCommit(lib, head)
+		commit = lib.File.HeadCommit()
+	}
+
+	name := lib.TagName(release)
+	lib.File.Output("Promoting " + lib.TagName(prerelease) + " to " + name + "...")
+	if err = lib.tagCommit(name, commit, key); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	mu.recordOperation(lib, Operation{Type: OpTag, Tag: name})
+	lib.File.PreviousVersion = prerelease
+	lib.File.Version = release
+	lib.File.Tagged = true
+	lib.File.TagSigned = len(key) > 0
+
+	line := lib.File.GetGoURL() + " " + prerelease + " -> " + release
+	if lib.File.TagSigned {
+		line += " (signed)"
+	}
+	mu.addStat(&mu.Stats.PromoteCount, &mu.Stats.PromotedOutput, line+"\n")
+	lib.File.Output("Promoted to " + name)
+}
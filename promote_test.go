@@ -0,0 +1,55 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestLatestPrerelease(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git -c versionsort.suffix=- tag --list", com.Reply{Stdout: "v1.4.0-rc.1\nv1.4.0-beta.2\nv1.3.0\n"})
+
+	for channel, expected := range map[string]string{"": "v1.4.0-rc.1", "beta": "v1.4.0-beta.2", "alpha": ""} {
+		if version, err := lib.LatestPrerelease(channel); err != nil || version != expected {
+			t.Errorf("expected %q on channel %q, got %q (%v)", expected, channel, version, err)
+		}
+	}
+
+	// Released since
+	recorder.Reply("git -c versionsort.suffix=- tag --list", com.Reply{Stdout: "v1.4.0\nv1.4.0-beta.2\n"})
+	if version, err := lib.LatestPrerelease("beta"); err != nil || len(version) > 0 {
+		t.Errorf("expected no prerelease after a stable release, got %q (%v)", version, err)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	recorder.Reply("git -c versionsort.suffix=- tag --list", com.Reply{Stdout: "v1.4.0-beta.2\nv1.3.0\n"})
+	recorder.Reply("git rev-list -n 1 v1.4.0-beta.2", com.Reply{Stdout: "abc123\n"})
+
+	mu := &MU{Options: Options{Action: "promote"}}
+	mu.promote(*lib, &sort.FileNode{File: lib.File})
+
+	expected := []string{"git -c versionsort.suffix=- tag --list * --sort=-v:refname", "git rev-list -n 1 v1.4.0-beta.2", "git tag v1.4.0 abc123", "git push origin v1.4.0"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+	if !lib.File.Tagged || lib.File.PreviousVersion != "v1.4.0-beta.2" || lib.File.Version != "v1.4.0" || mu.Stats.PromoteCount != 1 {
+		t.Errorf("expected prerelease to be promoted, got %s -> %s", lib.File.PreviousVersion, lib.File.Version)
+	}
+}
+
+func TestPromoteNoPrerelease(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	recorder.Reply("git -c versionsort.suffix=- tag --list", com.Reply{Stdout: "v1.3.0\n"})
+
+	mu := &MU{Options: Options{Action: "promote"}}
+	mu.promote(*lib, &sort.FileNode{File: lib.File})
+
+	if recorder.Ran("git tag v") || mu.Stats.PromoteCount > 0 || len(mu.Errors) > 0 {
+		t.Errorf("expected nothing to promote, ran %q", commandLines(recorder))
+	}
+}
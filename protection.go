@@ -0,0 +1,114 @@
+package gomu
+
+import (
+	"fmt"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// How syncs handle branches whose protection rules block direct pushes
+const (
+	// OnProtectedPR syncs on FallbackBranch and opens a pull request to the protected branch
+	OnProtectedPR = "pr"
+	// OnProtectedFail fails the lib before making any changes
+	OnProtectedFail = "fail"
+)
+
+// FallbackBranch receives changes meant for protected branches
+const FallbackBranch = "gomu-sync"
+
+// checkProtectedBranch returns the branch lib is synced on if protection rules block pushes to it, checked before the
+// branch is created or anything is pushed. Returns an error if lib shouldn't be synced: the branch is protected and
+// mu.Options.OnProtected is fail
+func (mu *MU) checkProtectedBranch(lib Library) (protected string, err error) {
+	if mu.Options.DryRun {
+		// Nothing is pushed
+		return
+	}
+
+	branch := mu.branch(lib)
+	if len(branch) == 0 {
+		if branch, err = lib.File.CurrentBranch(); err != nil || len(branch) == 0 {
+			// Detached, nothing to protect
+			return "", nil
+		}
+	}
+
+	blocked, err := lib.File.PushBlocked(branch)
+	if err != nil {
+		// Unknown, any rejection is reported by the push
+		lib.File.Debug("Unable to check protection of " + branch + ": " + err.Error())
+		return "", nil
+	} else if !blocked {
+		return
+	}
+
+	if mu.Options.OnProtected == OnProtectedFail {
+		err = fmt.Errorf("%s is protected and blocks pushes, sync on another branch or open a pull request instead", branch)
+		lib.File.Error(err.Error())
+		return
+	}
+
+	return branch, nil
+}
+
+// syncOnFallback switches lib from its protected branch to FallbackBranch, for a pull request to the protected branch
+func (mu *MU) syncOnFallback(lib Library, protected string) (err error) {
+	lib.File.Output(protected + " is protected, syncing on " + FallbackBranch + " for a pull request instead...")
+
+	_, created, err := lib.File.CheckoutOrCreateBranch(FallbackBranch)
+	if err != nil {
+		err = fmt.Errorf("unable to checkout %s: %v", FallbackBranch, err)
+		lib.File.Error(err.Error())
+		return
+	}
+
+	if created {
+		lib.File.RunCmd("git", "push", "-u", com.PushRemote(), FallbackBranch)
+	} else if pullErr := lib.File.Pull(); pullErr != nil {
+		// Left from a previous run
+		lib.File.Output("Failed to pull " + FallbackBranch + " :(")
+	}
+
+	lib.File.Branch = FallbackBranch
+	lib.File.ProtectedBranch = protected
+	return
+}
+
+// checkBranches checks the base and protected branches of lib before its branch is created or anything is pushed,
+// returning the branch if it's protected and synced on FallbackBranch instead. Returns an error if lib shouldn't be synced
+func (mu *MU) checkBranches(lib Library) (protected string, err error) {
+	if err = mu.checkBaseBranch(lib); err != nil {
+		return
+	}
+
+	return mu.checkProtectedBranch(lib)
+}
+
+// checkoutBranch checks out (or creates) lib's branch once its base and protected branches are checked, switching to
+// FallbackBranch if it's protected. Returns an error if lib shouldn't be changed
+func (mu *MU) checkoutBranch(lib Library) (err error) {
+	protected, err := mu.checkBranches(lib)
+	if err != nil {
+		return
+	}
+
+	if _, _, err = mu.updateOrCreateBranch(lib); err != nil {
+		return
+	}
+
+	if len(protected) > 0 {
+		err = mu.syncOnFallback(lib, protected)
+	}
+
+	return
+}
+
+// syncBranch returns the branch lib is synced on, and the branch to return to if it's deleted unused
+func (mu *MU) syncBranch(lib Library) (branch, base string) {
+	if len(lib.File.ProtectedBranch) > 0 {
+		return FallbackBranch, lib.File.ProtectedBranch
+	}
+
+	return mu.branch(lib), "master"
+}
@@ -0,0 +1,57 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestSyncOnFallback(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git checkout "+FallbackBranch, com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{Branch: "master"}}
+	if err := mu.syncOnFallback(*lib, "master"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"git checkout " + FallbackBranch, "git checkout -b " + FallbackBranch, "git push -u origin " + FallbackBranch}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	// Pull requests go from the fallback branch to the protected one
+	if branch, base := mu.syncBranch(*lib); branch != FallbackBranch || base != "master" {
+		t.Errorf("expected sync on %s for master, got %s for %s", FallbackBranch, branch, base)
+	}
+	if lib.File.Branch != FallbackBranch {
+		t.Errorf("expected lib to be recorded on %s, got %q", FallbackBranch, lib.File.Branch)
+	}
+}
+
+func TestSyncOnExistingFallback(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{}
+	if err := mu.syncOnFallback(*lib, "main"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Left from a previous run
+	if recorder.Ran("git push") || !recorder.Ran("git pull") {
+		t.Errorf("expected existing fallback branch to be pulled, ran %q", commandLines(recorder))
+	}
+}
+
+func TestCheckProtectedBranchDryRun(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{Options: Options{Branch: "master", DryRun: true, OnProtected: OnProtectedFail}}
+	if protected, err := mu.checkProtectedBranch(*lib); err != nil || len(protected) > 0 {
+		t.Errorf("expected nothing to be protected without pushing, got %q (%v)", protected, err)
+	}
+	if len(recorder.Commands()) > 0 {
+		t.Errorf("expected no commands, ran %q", commandLines(recorder))
+	}
+}
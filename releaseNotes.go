@@ -0,0 +1,322 @@
+package gomu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// ChangelogName is the file release notes are prepended to when tagging
+const ChangelogName = "CHANGELOG.md"
+
+// ReleaseTagEnv is set to the tag being released in the environment of the release build, with the exec and hook variables
+const ReleaseTagEnv = "GOMU_RELEASE_TAG"
+
+// changelogCommitPrefix marks commits which only update the changelog, excluded from release notes
+const changelogCommitPrefix = "gomu: Update changelog"
+
+// Release note sections, in order
+var releaseSections = []struct {
+	kind  string
+	title string
+}{
+	{"breaking", "Breaking Changes"},
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"", "Other Changes"},
+}
+
+// ChangeEntry represents a single commit within release notes
+type ChangeEntry struct {
+	Commit      string
+	Kind        string // Conventional commit type (feat, fix...), empty if not conventional
+	Scope       string
+	Description string
+	Breaking    bool
+}
+
+// parseChangeEntry parses a commit subject and body, following conventional commits (type(scope)!: description) if possible
+func parseChangeEntry(commit, subject, body string) (entry ChangeEntry) {
+	entry.Commit = commit
+	entry.Description = subject
+	entry.Breaking = strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:")
+
+	index := strings.Index(subject, ": ")
+	if index <= 0 {
+		return
+	}
+
+	kind := subject[:index]
+	breaking := strings.HasSuffix(kind, "!")
+	kind = strings.TrimSuffix(kind, "!")
+
+	var scope string
+	if open := strings.Index(kind, "("); open > 0 && strings.HasSuffix(kind, ")") {
+		scope = kind[open+1 : len(kind)-1]
+		kind = kind[:open]
+	}
+
+	if strings.ContainsAny(kind, " /()") {
+		// Not a conventional commit type
+		return
+	}
+
+	entry.Kind = strings.ToLower(kind)
+	entry.Scope = scope
+	entry.Breaking = entry.Breaking || breaking
+	entry.Description = strings.TrimSpace(subject[index+2:])
+	return
+}
+
+// section returns the kind of release section the entry belongs to
+func (entry ChangeEntry) section() string {
+	if entry.Breaking {
+		return "breaking"
+	}
+
+	switch entry.Kind {
+	case "feat", "fix", "perf":
+		return entry.Kind
+	}
+
+	return ""
+}
+
+// Changes returns commits since lib's latest tag which touched lib's directory, newest first
+func (lib *Library) Changes() (entries []ChangeEntry, err error) {
+	args := []string{"git", "log", "--format=%h%x1f%s%x1f%b%x1e"}
+	if latest := lib.GetLatestTag(); len(latest) > 0 {
+		args = append(args, lib.TagName(latest)+"..HEAD")
+	}
+	args = append(args, "--", ".")
+
+	output, err := lib.File.CmdOutput(args...)
+	if err != nil {
+		return
+	}
+
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[1], changelogCommitPrefix) {
+			// Don't list previous releases
+			continue
+		}
+
+		var body string
+		if len(fields) == 3 {
+			body = fields[2]
+		}
+
+		entries = append(entries, parseChangeEntry(fields[0], fields[1], body))
+	}
+
+	return
+}
+
+// ReleaseNotes returns a markdown section describing changes since lib's latest tag, grouped by conventional commit type
+func (lib *Library) ReleaseNotes(version string) (notes string, err error) {
+	entries, err := lib.Changes()
+	if err != nil {
+		return
+	}
+
+	return formatReleaseNotes(version, entries), nil
+}
+
+// formatReleaseNotes returns a markdown section describing entries released in version, grouped by conventional commit type
+func formatReleaseNotes(version string, entries []ChangeEntry) (notes string) {
+	notes = "## " + version + " (" + time.Now().Format("2006-01-02") + ")\n"
+	if len(entries) == 0 {
+		notes += "\nNo changes.\n"
+		return
+	}
+
+	for _, section := range releaseSections {
+		var lines []string
+		for _, entry := range entries {
+			if entry.section() != section.kind {
+				continue
+			}
+
+			line := "- "
+			if len(entry.Scope) > 0 {
+				line += "**" + entry.Scope + ":** "
+			}
+			lines = append(lines, line+entry.Description+" ("+entry.Commit+")")
+		}
+
+		if len(lines) > 0 {
+			notes += "\n### " + section.title + "\n\n" + strings.Join(lines, "\n") + "\n"
+		}
+	}
+
+	return
+}
+
+// WriteChangelog prepends notes to lib's changelog, creating it if needed, then commits and pushes it
+func (lib *Library) WriteChangelog(version, notes string) (err error) {
+	changelogPath := filepath.Join(lib.File.Path, ChangelogName)
+
+	var existing string
+	if data, readErr := ioutil.ReadFile(changelogPath); readErr == nil {
+		existing = string(data)
+	} else if !os.IsNotExist(readErr) {
+		return readErr
+	}
+
+	// Keep title at the top
+	title := "# Changelog\n"
+	if strings.HasPrefix(existing, "# ") {
+		index := strings.Index(existing, "\n")
+		if index < 0 {
+			index = len(existing) - 1
+		}
+		title, existing = existing[:index+1], existing[index+1:]
+	}
+
+	content := title + "\n" + notes
+	if existing = strings.TrimLeft(existing, "\n"); len(existing) > 0 {
+		content += "\n" + existing
+	}
+
+	if com.IsDryRun() {
+		lib.File.DryRun("Write " + ChangelogName + "\n" + notes)
+	} else if err = ioutil.WriteFile(changelogPath, []byte(content), 0644); err != nil {
+		return
+	}
+
+	if err = lib.File.Add(ChangelogName); err != nil {
+		return
+	}
+
+	if err = lib.commit(changelogCommitPrefix + " for " + version); err != nil {
+		return
+	}
+
+	return lib.File.Push()
+}
+
+// changelog writes release notes for version to lib's changelog, returning the notes
+func (mu *MU) changelog(lib Library, version string) (notes string) {
+	lib.File.Output("Generating release notes for " + version + "...")
+
+	notes, err := lib.ReleaseNotes(version)
+	if err != nil {
+		lib.File.Error("Unable to generate release notes :( " + err.Error())
+		return
+	}
+
+	if err = mu.setCommitKey(&lib); err != nil {
+		lib.File.Error("Unable to sign " + ChangelogName + " commit :( " + err.Error())
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if err = lib.WriteChangelog(version, notes); err != nil {
+		lib.File.Error("Unable to update " + ChangelogName + " :( " + err.Error())
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	lib.File.Output("Updated " + ChangelogName + "!")
+	return
+}
+
+// release publishes notes as the release body for lib's new tag
+func (mu *MU) release(lib Library, notes string) {
+	tag := lib.TagName(lib.File.Version)
+	releaseURL, err := lib.File.CreateRelease(tag, notes)
+	if err != nil {
+		lib.File.Error("Unable to publish release " + tag + " :( " + err.Error())
+		return
+	}
+
+	lib.File.ReleaseURL = releaseURL
+	lib.File.Output("Published release " + tag + "!")
+
+	line := lib.File.GetGoURL() + " " + releaseURL
+	if assets := mu.releaseAssets(lib, tag); assets > 0 {
+		line += " (" + strconv.Itoa(assets) + " asset(s))"
+	}
+	mu.addStat(&mu.Stats.ReleaseCount, &mu.Stats.ReleaseOutput, line+"\n")
+}
+
+// releaseAssets runs the release build in lib, then attaches files matching the release assets to the release of tag.
+// Returns how many were attached, a failed build or upload fails lib
+func (mu *MU) releaseAssets(lib Library, tag string) (attached int) {
+	if len(mu.Options.ReleaseBuild) > 0 {
+		env := []string{
+			ExecLibPathEnv + "=" + lib.File.OriginalPath(),
+			ExecLibURLEnv + "=" + lib.File.GetGoURL(),
+			HookVersionEnv + "=" + lib.File.Version,
+			ReleaseTagEnv + "=" + tag,
+		}
+
+		lib.File.Output("Running release build `" + mu.Options.ReleaseBuild + "`...")
+		output, exitCode, err := lib.File.RunShell(mu.Options.ReleaseBuild, env...)
+		if len(output) > 0 {
+			lib.File.Output(output)
+		}
+
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("exited with code %d", exitCode)
+		}
+
+		if err != nil {
+			mu.libraryError(lib, fmt.Errorf("release build `%s` failed: %v", mu.Options.ReleaseBuild, err))
+			return
+		}
+	}
+
+	for _, pattern := range mu.Options.ReleaseAssets {
+		matches, err := filepath.Glob(filepath.Join(lib.File.Path, filepath.FromSlash(pattern)))
+		if err != nil {
+			mu.libraryError(lib, fmt.Errorf("invalid release asset pattern %s: %v", pattern, err))
+			return
+		}
+
+		var files []string
+		for _, match := range matches {
+			if info, statErr := os.Stat(match); statErr == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+
+		if len(files) == 0 {
+			if com.IsDryRun() {
+				// Nothing was built
+				lib.File.DryRun("Upload " + pattern + " to release " + tag)
+				continue
+			}
+
+			mu.libraryError(lib, fmt.Errorf("no release assets match %s", pattern))
+			return
+		}
+
+		for _, file := range files {
+			assetURL, err := lib.File.UploadReleaseAsset(tag, file)
+			if err != nil {
+				mu.libraryError(lib, fmt.Errorf("unable to attach %s to release %s: %v", filepath.Base(file), tag, err))
+				return
+			}
+
+			if !com.IsDryRun() {
+				lib.File.Output("Attached " + filepath.Base(file) + " - " + assetURL)
+			}
+			attached++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,144 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestParseChangeEntry(t *testing.T) {
+	for _, test := range []struct {
+		subject, body string
+		entry         ChangeEntry
+	}{
+		{"feat(sync): add dry run", "", ChangeEntry{Kind: "feat", Scope: "sync", Description: "add dry run"}},
+		{"fix!: drop go 1.13", "", ChangeEntry{Kind: "fix", Description: "drop go 1.13", Breaking: true}},
+		{"refactor: split utils", "BREAKING CHANGE: renamed Sync", ChangeEntry{Kind: "refactor", Description: "split utils", Breaking: true}},
+		{"Merge branch 'a': conflicts", "", ChangeEntry{Description: "Merge branch 'a': conflicts"}},
+		{"Update readme", "", ChangeEntry{Description: "Update readme"}},
+	} {
+		test.entry.Commit = "abc123"
+		if entry := parseChangeEntry("abc123", test.subject, test.body); entry != test.entry {
+			t.Errorf("expected %q to be %+v, got %+v", test.subject, test.entry, entry)
+		}
+	}
+}
+
+func TestReleaseNotes(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.2.0\n"})
+	recorder.Reply("git log", com.Reply{Stdout: "a1\x1ffeat(api): add Tag\x1f\x1e\n" +
+		"b2\x1fgomu: Update changelog for v1.2.0\x1f\x1e\n" +
+		"c3\x1ffix: retry pushes\x1f\x1e\n" +
+		"d4\x1fUpdate readme\x1f\x1e\n"})
+
+	notes, err := lib.ReleaseNotes("v1.3.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !recorder.Ran("git log --format=%h%x1f%s%x1f%b%x1e v1.2.0..HEAD -- .") {
+		t.Errorf("expected commits since the latest tag, ran %q", commandLines(recorder))
+	}
+
+	expected := "## v1.3.0 (" + time.Now().Format("2006-01-02") + ")\n" +
+		"\n### Features\n\n- **api:** add Tag (a1)\n" +
+		"\n### Bug Fixes\n\n- retry pushes (c3)\n" +
+		"\n### Other Changes\n\n- Update readme (d4)\n"
+	if notes != expected {
+		t.Errorf("expected %q, got %q", expected, notes)
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	changelogPath := filepath.Join(lib.File.Path, ChangelogName)
+	if err := ioutil.WriteFile(changelogPath, []byte("# Changes\n\n## v1.2.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lib.WriteChangelog("v1.3.0", "## v1.3.0\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Notes are prepended below the title
+	data, err := ioutil.ReadFile(changelogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# Changes\n\n## v1.3.0\n\n## v1.2.0\n"; string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	expected := []string{"git add " + ChangelogName, "git commit -m gomu: Update changelog for v1.3.0", "git push -u origin"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestFormatReleaseNotes(t *testing.T) {
+	date := time.Now().Format("2006-01-02")
+	if notes := formatReleaseNotes("v1.3.0", nil); notes != "## v1.3.0 ("+date+")\n\nNo changes.\n" {
+		t.Errorf("expected no changes, got %q", notes)
+	}
+
+	notes := formatReleaseNotes("v1.3.0", []ChangeEntry{{Commit: "a1", Kind: "fix", Description: "retry pushes"}})
+	if expected := "## v1.3.0 (" + date + ")\n\n### Bug Fixes\n\n- retry pushes (a1)\n"; notes != expected {
+		t.Errorf("expected %q, got %q", expected, notes)
+	}
+}
+
+func TestReleaseAssets(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git remote get-url origin", com.Reply{Stdout: "git@github.com:org/a.git\n"})
+	lib.File.Version = "v1.3.0"
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	dist := filepath.Join(lib.File.Path, "dist")
+	if err := os.MkdirAll(filepath.Join(dist, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a_linux.tar.gz", "a_darwin.tar.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dist, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The build gets the tag, and a missing asset fails the lib before anything is uploaded
+	mu := &MU{Options: Options{ReleaseBuild: "make dist", ReleaseAssets: []string{"*.zip"}}}
+	if attached := mu.releaseAssets(*lib, "v1.3.0"); attached != 0 || len(mu.Errors) != 1 {
+		t.Errorf("expected unmatched assets to fail, got %d attached and %v", attached, mu.Errors)
+	}
+	var env []string
+	for _, command := range recorder.Commands() {
+		if strings.HasSuffix(command.String(), "make dist") {
+			env = command.Env
+		}
+	}
+	if !reflect.DeepEqual(env[len(env)-2:], []string{HookVersionEnv + "=v1.3.0", ReleaseTagEnv + "=v1.3.0"}) {
+		t.Errorf("expected the tag in the build's environment, got %v", env)
+	}
+
+	recorder.Reply("sh -c make dist", com.Reply{ExitCode: 2})
+	recorder.Reply("cmd /C make dist", com.Reply{ExitCode: 2})
+	mu = &MU{Options: Options{ReleaseBuild: "make dist", ReleaseAssets: []string{"dist/*"}}}
+	if attached := mu.releaseAssets(*lib, "v1.3.0"); attached != 0 || len(mu.Errors) != 1 || !strings.Contains(mu.Errors[0].Error(), "release build `make dist` failed") {
+		t.Errorf("expected a failed build to fail the lib, got %v", mu.Errors)
+	}
+
+	// Directories aren't uploaded, and assets not built in a dry run are skipped
+	com.SetDryRun(true)
+	defer com.SetDryRun(false)
+	mu = &MU{Options: Options{ReleaseAssets: []string{"dist/*", "build/*"}}}
+	if attached := mu.releaseAssets(*lib, "v1.3.0"); attached != 2 || len(mu.Errors) > 0 {
+		t.Errorf("expected both archives to be attached, got %d and %v", attached, mu.Errors)
+	}
+}
@@ -0,0 +1,126 @@
+package gomu
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Replace represents a replace directive in a go.mod file
+type Replace struct {
+	Old ModVersion
+	New ModVersion
+}
+
+// ModVersion represents a module path and optional version, as output by go mod edit -json
+type ModVersion struct {
+	Path    string
+	Version string
+}
+
+// ReplacePolicy controls how sync handles local replace directives
+type ReplacePolicy struct {
+	// Drop removes local replaces before deps are updated, so they can't mask updates
+	Drop bool
+	// Restore re-adds dropped replaces before committing. Implies Drop
+	Restore bool
+}
+
+// IsLocal returns true if the replacement is a directory rather than a module
+func (replace Replace) IsLocal() bool {
+	target := replace.New.Path
+	return strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") || strings.HasPrefix(target, "/") ||
+		target == "." || target == ".."
+}
+
+// Masks returns true if the replace applies to module at version
+func (replace Replace) Masks(module, version string) bool {
+	return replace.Old.Path == module && (len(replace.Old.Version) == 0 || replace.Old.Version == version)
+}
+
+// String returns the directive as written in go.mod, e.g. github.com/org/lib => ../lib
+func (replace Replace) String() string {
+	return replace.Old.join(" ") + " => " + replace.New.join(" ")
+}
+
+// join returns the path and version joined by sep, or just the path if there's no version
+func (mod ModVersion) join(sep string) string {
+	if len(mod.Version) == 0 {
+		return mod.Path
+	}
+
+	return mod.Path + sep + mod.Version
+}
+
+// Replaces returns the replace directives in lib's go.mod
+func (lib *Library) Replaces() (replaces []Replace, err error) {
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		return
+	}
+
+	var modFile struct {
+		Replace []Replace
+	}
+	if err = json.Unmarshal([]byte(output), &modFile); err != nil {
+		return
+	}
+
+	return modFile.Replace, nil
+}
+
+// DropLocalReplaces removes local replace directives from lib's go.mod, returning those removed
+func (lib *Library) DropLocalReplaces() (dropped []Replace, err error) {
+	replaces, err := lib.Replaces()
+	if err != nil {
+		return
+	}
+
+	for _, replace := range replaces {
+		if !replace.IsLocal() {
+			continue
+		}
+
+		if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "edit", "-dropreplace="+replace.Old.join("@")); err != nil {
+			return
+		}
+
+		lib.File.Output("Dropped replace " + replace.String())
+		dropped = append(dropped, replace)
+	}
+
+	return
+}
+
+// RestoreReplaces re-adds replace directives to lib's go.mod
+func (lib *Library) RestoreReplaces(replaces []Replace) (err error) {
+	for _, replace := range replaces {
+		if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "edit", "-replace="+replace.Old.join("@")+"="+replace.New.join("@")); err != nil {
+			return
+		}
+
+		lib.File.Output("Restored replace " + replace.String())
+	}
+
+	return
+}
+
+// warnMaskedUpdates warns about updated deps which remain replaced, as the update has no effect on builds
+func (lib *Library) warnMaskedUpdates() {
+	if lib.updatedDeps == nil {
+		return
+	}
+
+	replaces, err := lib.Replaces()
+	if err != nil {
+		return
+	}
+
+	for itr := lib.updatedDeps; itr != nil; itr = itr.Next {
+		url := itr.File.GetGoURL()
+		for _, replace := range replaces {
+			if replace.Masks(url, itr.File.Version) {
+				lib.File.Output("Warning - " + url + " @ " + itr.File.Version + " is masked by replace " + replace.String())
+			}
+		}
+	}
+}
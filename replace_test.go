@@ -0,0 +1,66 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+const replacesJSON = `{"Replace": [
+	{"Old": {"Path": "github.com/org/b"}, "New": {"Path": "../b"}},
+	{"Old": {"Path": "github.com/org/c", "Version": "v1.0.0"}, "New": {"Path": "github.com/fork/c", "Version": "v1.0.1"}},
+	{"Old": {"Path": "github.com/org/d", "Version": "v1.2.0"}, "New": {"Path": "/src/d"}}
+]}`
+
+func TestReplace(t *testing.T) {
+	for _, test := range []struct {
+		replace Replace
+		local   bool
+		text    string
+	}{
+		{Replace{ModVersion{"github.com/org/b", ""}, ModVersion{"../b", ""}}, true, "github.com/org/b => ../b"},
+		{Replace{ModVersion{"github.com/org/b", ""}, ModVersion{".", ""}}, true, "github.com/org/b => ."},
+		{Replace{ModVersion{"github.com/org/c", "v1.0.0"}, ModVersion{"github.com/fork/c", "v1.0.1"}}, false, "github.com/org/c v1.0.0 => github.com/fork/c v1.0.1"},
+	} {
+		if test.replace.IsLocal() != test.local || test.replace.String() != test.text {
+			t.Errorf("expected %q to be local %v, got %q local %v", test.text, test.local, test.replace.String(), test.replace.IsLocal())
+		}
+	}
+
+	// Replaces without a version apply to every version
+	unversioned := Replace{Old: ModVersion{Path: "github.com/org/b"}}
+	versioned := Replace{Old: ModVersion{Path: "github.com/org/b", Version: "v1.0.0"}}
+	if !unversioned.Masks("github.com/org/b", "v1.2.0") || versioned.Masks("github.com/org/b", "v1.2.0") ||
+		!versioned.Masks("github.com/org/b", "v1.0.0") || unversioned.Masks("github.com/org/c", "v1.2.0") {
+		t.Error("expected replaces to mask only their module at their version")
+	}
+}
+
+func TestDropLocalReplaces(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: replacesJSON})
+
+	dropped, err := lib.DropLocalReplaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 2 || dropped[0].Old.Path != "github.com/org/b" || dropped[1].Old.Path != "github.com/org/d" {
+		t.Errorf("expected local replaces to be dropped, got %+v", dropped)
+	}
+
+	if err = lib.RestoreReplaces(dropped); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"go mod edit -json",
+		"go mod edit -dropreplace=github.com/org/b",
+		"go mod edit -dropreplace=github.com/org/d@v1.2.0",
+		"go mod edit -replace=github.com/org/b=../b",
+		"go mod edit -replace=github.com/org/d@v1.2.0=/src/d",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
@@ -0,0 +1,165 @@
+package gomu
+
+import (
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// actions returns a short description of what was done to the library
+func (result LibraryResult) actions() string {
+	var actions []string
+	if result.BranchCreated {
+		actions = append(actions, "branched")
+	}
+	if result.Updated {
+		actions = append(actions, "updated")
+	}
+	if result.Committed {
+		actions = append(actions, "committed")
+	}
+	if result.Tagged {
+		actions = append(actions, "tagged")
+	}
+	if result.PROpened {
+		actions = append(actions, "opened PR")
+	}
+	if result.TestFailed {
+		actions = append(actions, "tests failed")
+	}
+	if result.ExitCode != nil {
+		actions = append(actions, "exited "+strconv.Itoa(*result.ExitCode))
+	}
+	if result.TimedOut {
+		actions = append(actions, "timed out")
+	}
+
+	if len(actions) == 0 {
+		return "none"
+	}
+
+	return strings.Join(actions, ", ")
+}
+
+// shortSHA returns the abbreviated form of a commit sha
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+
+	return sha
+}
+
+// reportTitle returns the heading of a run report
+func (stats ActionStats) reportTitle() string {
+	title := "gomu " + stats.Options.Action
+	if branches := stats.branches(); len(branches) > 0 {
+		title += " on " + strings.Join(branches, ", ")
+	}
+	if stats.Options.DryRun {
+		title += " (dry run)"
+	}
+
+	return title
+}
+
+// Markdown returns a human-friendly report of the run with a table of per-library results
+func (stats ActionStats) Markdown(errs []error) (output string) {
+	cell := func(value string) string {
+		// Pipes and newlines break table rows
+		value = strings.Replace(value, "|", "\\|", -1)
+		return strings.Replace(value, "\n", " ", -1)
+	}
+
+	output += "# " + stats.reportTitle() + "\n\n"
+	output += strconv.Itoa(len(stats.Results)) + " lib(s), " +
+		strconv.Itoa(stats.UpdateCount) + " updated, " +
+		strconv.Itoa(stats.TagCount) + " tagged, " +
+		strconv.Itoa(stats.PRCount) + " pull request(s)\n\n"
+
+	output += "| Library | Action | Old Version | New Version | Pull Request | Commit | Errors |\n"
+	output += "| --- | --- | --- | --- | --- | --- | --- |\n"
+	for _, result := range stats.Results {
+		var pr string
+		if len(result.PRURL) > 0 {
+			pr = "[link](" + result.PRURL + ")"
+		}
+
+		row := []string{
+			cell(result.Library),
+			cell(result.actions()),
+			cell(result.PreviousVersion),
+			cell(result.Version),
+			pr,
+			cell(shortSHA(result.Commit)),
+			cell(strings.Join(result.Errors, "; ")),
+		}
+		output += "| " + strings.Join(row, " | ") + " |\n"
+	}
+
+	if len(errs) > 0 {
+		output += "\n## Errors\n\n"
+		for _, err := range errs {
+			output += "- " + err.Error() + "\n"
+		}
+	}
+
+	return
+}
+
+// HTML returns a standalone html page reporting the run with a table of per-library results
+func (stats ActionStats) HTML(errs []error) (output string) {
+	title := html.EscapeString(stats.reportTitle())
+
+	output += "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n"
+	output += "<style>body{font-family:sans-serif}table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left}.error{color:#b00}</style>\n"
+	output += "</head>\n<body>\n<h1>" + title + "</h1>\n"
+	output += "<p>" + strconv.Itoa(len(stats.Results)) + " lib(s), " +
+		strconv.Itoa(stats.UpdateCount) + " updated, " +
+		strconv.Itoa(stats.TagCount) + " tagged, " +
+		strconv.Itoa(stats.PRCount) + " pull request(s)</p>\n"
+
+	output += "<table>\n<tr><th>Library</th><th>Action</th><th>Old Version</th><th>New Version</th><th>Pull Request</th><th>Commit</th><th>Errors</th></tr>\n"
+	for _, result := range stats.Results {
+		var pr string
+		if len(result.PRURL) > 0 {
+			pr = "<a href=\"" + html.EscapeString(result.PRURL) + "\">link</a>"
+		}
+
+		output += "<tr><td>" + html.EscapeString(result.Library) +
+			"</td><td>" + html.EscapeString(result.actions()) +
+			"</td><td>" + html.EscapeString(result.PreviousVersion) +
+			"</td><td>" + html.EscapeString(result.Version) +
+			"</td><td>" + pr +
+			"</td><td>" + html.EscapeString(shortSHA(result.Commit)) +
+			"</td><td class=\"error\">" + html.EscapeString(strings.Join(result.Errors, "; ")) +
+			"</td></tr>\n"
+	}
+	output += "</table>\n"
+
+	if len(errs) > 0 {
+		output += "<h2>Errors</h2>\n<ul>\n"
+		for _, err := range errs {
+			output += "<li class=\"error\">" + html.EscapeString(err.Error()) + "</li>\n"
+		}
+		output += "</ul>\n"
+	}
+
+	output += "</body>\n</html>\n"
+	return
+}
+
+// WriteReport writes a report of the run to reportPath, as html if the extension is .html or .htm, otherwise markdown
+func (stats ActionStats) WriteReport(reportPath string, errs []error) error {
+	var output string
+	switch strings.ToLower(filepath.Ext(reportPath)) {
+	case ".html", ".htm":
+		output = stats.HTML(errs)
+	default:
+		output = stats.Markdown(errs)
+	}
+
+	return ioutil.WriteFile(reportPath, []byte(output), 0644)
+}
@@ -0,0 +1,83 @@
+package gomu
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reportStats returns stats of a sync with a lib which opened a PR and one which failed
+func reportStats() ActionStats {
+	return ActionStats{
+		Options:     &Options{Action: "sync", Branch: "deps"},
+		UpdateCount: 1,
+		TagCount:    1,
+		PRCount:     1,
+		Results: []LibraryResult{
+			{
+				Library: "github.com/org/a", Updated: true, Committed: true, Tagged: true, PROpened: true,
+				PreviousVersion: "v1.0.0", Version: "v1.0.1", PRURL: "https://github.com/org/a/pull/7", Commit: "0123456789abcdef",
+			},
+			{Library: "github.com/org/b", Errors: []string{"go build | failed", "<exit 1>"}},
+		},
+	}
+}
+
+func TestMarkdownReport(t *testing.T) {
+	report := reportStats().Markdown([]error{errors.New("github.com/org/b failed")})
+
+	for _, expected := range []string{
+		"# gomu sync on deps\n\n2 lib(s), 1 updated, 1 tagged, 1 pull request(s)\n",
+		"| github.com/org/a | updated, committed, tagged, opened PR | v1.0.0 | v1.0.1 | [link](https://github.com/org/a/pull/7) | 0123456789ab |  |\n",
+		"| github.com/org/b | none |  |  |  |  | go build \\| failed; <exit 1> |\n",
+		"## Errors\n\n- github.com/org/b failed\n",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	report := reportStats().HTML(nil)
+
+	for _, expected := range []string{
+		"<title>gomu sync on deps</title>",
+		"<td><a href=\"https://github.com/org/a/pull/7\">link</a></td>",
+		"<td class=\"error\">go build | failed; &lt;exit 1&gt;</td>",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+	if strings.Contains(report, "<h2>Errors</h2>") {
+		t.Error("expected no errors section without errors")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stats := reportStats()
+	for name, prefix := range map[string]string{"report.md": "# gomu sync", "report.HTML": "<!DOCTYPE html>"} {
+		reportPath := filepath.Join(dir, name)
+		if err = stats.WriteReport(reportPath, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := ioutil.ReadFile(reportPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(report), prefix) {
+			t.Errorf("expected %s to start with %q, got:\n%s", name, prefix, report)
+		}
+	}
+}
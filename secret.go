@@ -0,0 +1,174 @@
+package gomu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	gosort "sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// secretName matches names github allows for actions secrets
+var secretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// loadSecrets reads the secrets synced by secret, from environment variables named in options and the source path.
+// The source path may be a secret file named as the secret, a directory of them, or a store of NAME=value lines,
+// plain (.env) or encrypted with gpg (.gpg or .asc)
+func (mu *MU) loadSecrets() (err error) {
+	mu.secrets = make(map[string]string)
+	for _, name := range mu.Options.Secrets {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return fmt.Errorf("secret %s is not set in the environment", name)
+		}
+
+		mu.secrets[name] = value
+	}
+
+	if len(mu.Options.SourcePath) > 0 {
+		if err = mu.readSecrets(mu.Options.SourcePath); err != nil {
+			return
+		}
+	}
+
+	if len(mu.secrets) == 0 {
+		return fmt.Errorf("no secrets provided, set secret names or a source path")
+	}
+
+	for name := range mu.secrets {
+		if !secretName.MatchString(name) || strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+			return fmt.Errorf("invalid secret name %q, expected letters, digits and _ without a GITHUB_ prefix", name)
+		}
+	}
+
+	return
+}
+
+// readSecrets reads secrets at source into mu.secrets
+func (mu *MU) readSecrets(source string) (err error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("unable to read secrets: %v", err)
+	}
+
+	if !info.IsDir() {
+		return mu.readSecretFile(source)
+	}
+
+	files, err := ioutil.ReadDir(source)
+	if err != nil {
+		return fmt.Errorf("unable to read secrets: %v", err)
+	}
+
+	for _, file := range files {
+		// Skip nested directories and dotfiles, e.g. .gitignore
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		if err = mu.readSecretFile(filepath.Join(source, file.Name())); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// readSecretFile reads the secret file at path, named as the file, or each secret of a store
+func (mu *MU) readSecretFile(path string) (err error) {
+	switch filepath.Ext(path) {
+	case ".env":
+		var store []byte
+		if store, err = ioutil.ReadFile(path); err != nil {
+			return fmt.Errorf("unable to read secrets: %v", err)
+		}
+
+		return parseSecrets(store, mu.secrets)
+	case ".gpg", ".asc":
+		var store []byte
+		if store, err = decryptSecrets(path); err != nil {
+			return
+		}
+
+		return parseSecrets(store, mu.secrets)
+	}
+
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read secret: %v", err)
+	}
+
+	mu.secrets[filepath.Base(path)] = string(value)
+	return
+}
+
+// decryptSecrets decrypts the gpg encrypted store at path. Output isn't logged, as libs' commands are, so secrets
+// aren't written to logs
+func decryptSecrets(path string) (store []byte, err error) {
+	var stderr bytes.Buffer
+	cmd := com.Command{Name: "gpg", Args: []string{"--batch", "--quiet", "--decrypt", path}, Env: com.Environ(), Stderr: &stderr}
+	if store, err = com.GlobalRunner().Output(context.Background(), cmd); err != nil {
+		err = fmt.Errorf("unable to decrypt secrets %s: %v %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return
+}
+
+// parseSecrets parses NAME=value lines of store into secrets, skipping blank lines and # comments.
+// Quoted values are unquoted, e.g. NAME="two words"
+func parseSecrets(store []byte, secrets map[string]string) (err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(store))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		comps := strings.SplitN(strings.TrimPrefix(text, "export "), "=", 2)
+		if len(comps) != 2 {
+			// Don't include the line, it may be a secret
+			return fmt.Errorf("invalid secret on line %d, expected NAME=value", line)
+		}
+
+		name, value := strings.TrimSpace(comps[0]), strings.TrimSpace(comps[1])
+		if unquoted, unquoteErr := strconv.Unquote(value); unquoteErr == nil {
+			value = unquoted
+		} else if len(value) > 1 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+
+		secrets[name] = value
+	}
+
+	return scanner.Err()
+}
+
+// secretNames returns names of the secrets synced, in order
+func (mu *MU) secretNames() (names []string) {
+	for name := range mu.secrets {
+		names = append(names, name)
+	}
+	gosort.Strings(names)
+
+	return
+}
+
+// secret sets the loaded secrets as actions secrets of lib's repo
+func (mu *MU) secret(lib Library) {
+	lib.File.Output("Setting secrets...")
+	if err := lib.File.AddSecrets(mu.secrets); err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to set secrets: %v", err))
+		return
+	}
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+strconv.Itoa(len(mu.secrets))+" secret(s))\n")
+}
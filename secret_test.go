@@ -0,0 +1,96 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestParseSecrets(t *testing.T) {
+	store := `# Deploy credentials
+DEPLOY_TOKEN=abc123
+export REGISTRY_USER = bot
+QUOTED="two words"
+SINGLE='it''s'
+
+EMPTY=
+`
+	secrets := make(map[string]string)
+	if err := parseSecrets([]byte(store), secrets); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"DEPLOY_TOKEN": "abc123", "REGISTRY_USER": "bot", "QUOTED": "two words", "SINGLE": "it''s", "EMPTY": ""}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("expected %v, got %v", expected, secrets)
+	}
+
+	err := parseSecrets([]byte("A=1\nhunter2\n"), secrets)
+	if err == nil || !strings.Contains(err.Error(), "line 2") || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected an error for line 2 without the line, got %v", err)
+	}
+}
+
+func TestLoadSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"NPM_TOKEN":    "npm-token",
+		"store.env":    "DEPLOY_TOKEN=abc123\n",
+		"vault.gpg":    "encrypted",
+		".gitignore":   "*\n",
+		"nested/OTHER": "skipped",
+	} {
+		if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := &com.Recorder{}
+	recorder.Reply("gpg --batch --quiet --decrypt", com.Reply{Stdout: "SIGNING_KEY=gpg-key\n"})
+	com.SetRunner(recorder)
+	defer com.SetRunner(nil)
+
+	os.Setenv("GOMU_TEST_SECRET", "from-env")
+	defer os.Unsetenv("GOMU_TEST_SECRET")
+
+	mu := &MU{Options: Options{Secrets: []string{"GOMU_TEST_SECRET"}, SourcePath: dir}}
+	if err = mu.loadSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"GOMU_TEST_SECRET": "from-env", "NPM_TOKEN": "npm-token", "DEPLOY_TOKEN": "abc123", "SIGNING_KEY": "gpg-key"}
+	if !reflect.DeepEqual(mu.secrets, expected) {
+		t.Errorf("expected %v, got %v", expected, mu.secrets)
+	}
+	if names := mu.secretNames(); !reflect.DeepEqual(names, []string{"DEPLOY_TOKEN", "GOMU_TEST_SECRET", "NPM_TOKEN", "SIGNING_KEY"}) {
+		t.Errorf("expected names in order, got %v", names)
+	}
+
+	// Reserved by github
+	os.Setenv("GITHUB_GOMU", "x")
+	defer os.Unsetenv("GITHUB_GOMU")
+
+	for _, options := range []Options{
+		{},
+		{Secrets: []string{"GOMU_TEST_UNSET"}},
+		{SourcePath: filepath.Join(dir, "missing")},
+		{Secrets: []string{"GITHUB_GOMU"}},
+	} {
+		mu = &MU{Options: options}
+		if err = mu.loadSecrets(); err == nil {
+			t.Errorf("expected %+v to fail loading secrets", options)
+		}
+	}
+}
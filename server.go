@@ -0,0 +1,642 @@
+package gomu
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// DefaultServerActions are the actions a server may run unless configured otherwise
+var DefaultServerActions = []string{"sync", "list", "status"}
+
+// DefaultServerHistory is the number of finished runs a server keeps unless configured otherwise
+const DefaultServerHistory = 50
+
+// MaxRequestBytes limits the size of request bodies a server reads
+const MaxRequestBytes = 1 << 20
+
+// ErrNoToken is returned by ListenAndServe when no token is set, as any client could otherwise push to every repo
+var ErrNoToken = errors.New("a token is required to serve gomu")
+
+// States of a server run
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunFinished  = "finished"
+	RunCancelled = "cancelled"
+)
+
+// Server exposes gomu over an HTTP/JSON API, running actions on named target sets:
+//
+//	GET  /targets             lists target sets
+//	POST /runs                queues a run, e.g. {"target": "services", "action": "sync"}
+//	GET  /runs                lists queued, running and past runs
+//	GET  /runs/{id}           returns a run and its summary once finished
+//	GET  /runs/{id}/events    streams a run's output as server-sent events until it finishes
+//	POST /runs/{id}/cancel    cancels a queued or running run
+//
+// Note: runs are performed one at a time, as settings and output are global
+type Server struct {
+	// Targets are the option sets runs are performed with, by name
+	Targets map[string]Options
+	// Actions which may be run, DefaultServerActions if empty
+	Actions []string
+	// Token required as a bearer token on all requests. ListenAndServe refuses to start without one
+	Token string
+	// History is the number of finished runs kept, DefaultServerHistory if 0
+	History int
+
+	mux      sync.Mutex
+	runs     []*ServerRun
+	lastID   int
+	queue    chan *ServerRun
+	initOnce sync.Once
+
+	// Set once shutting down, so queued runs are cancelled instead of performed. Guarded by mux
+	closed bool
+	// Run being performed, force quit on a second interrupt. Guarded by mux
+	current *MU
+	// Held while a run is performed, so shutting down waits for it to clean
+	performing sync.Mutex
+}
+
+// ServerRun represents an action queued on a target set
+type ServerRun struct {
+	ID     string `json:"id"`
+	Target string `json:"target"`
+	Action string `json:"action"`
+	Branch string `json:"branch,omitempty"`
+	DryRun bool   `json:"dryRun,omitempty"`
+	State  string `json:"state"`
+
+	Queued   time.Time  `json:"queued"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	// Summary of the run, once finished
+	Summary *Summary `json:"summary,omitempty"`
+
+	events  []RunEvent
+	waiters []chan struct{}
+	cancel  context.CancelFunc
+}
+
+// RunEvent is a message output during a run
+type RunEvent struct {
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	Library string    `json:"library,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// RunRequest is the body of a request to queue a run
+type RunRequest struct {
+	Target string `json:"target"`
+	Action string `json:"action"`
+	// Branch overrides the target's branch, if set
+	Branch string `json:"branch"`
+	DryRun bool   `json:"dryRun"`
+}
+
+// NewServer returns a server running actions on targets
+func NewServer(targets map[string]Options) *Server {
+	return &Server{Targets: targets}
+}
+
+// LoadTargets returns a target set for each profile in the config file at configPath, named after the profile
+func LoadTargets(configPath string) (targets map[string]Options, err error) {
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return
+	}
+
+	config, err := parseYAML(data)
+	if err != nil {
+		err = fmt.Errorf("unable to parse %s: %v", configPath, err)
+		return
+	}
+
+	profiles, _ := config["profiles"].(map[string]interface{})
+	if len(profiles) == 0 {
+		err = fmt.Errorf("no profiles found in %s", configPath)
+		return
+	}
+
+	targets = make(map[string]Options, len(profiles))
+	for profile := range profiles {
+		if targets[profile], err = LoadConfig(configPath, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// ListenAndServe serves the API on addr until it fails or is interrupted. The first interrupt cancels runs, lets
+// the running one clean and shuts down, the second force quits the running one
+func (s *Server) ListenAndServe(addr string) (err error) {
+	if len(s.Token) == 0 {
+		return ErrNoToken
+	}
+
+	server := &http.Server{Addr: addr, Handler: s}
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-signals
+		com.Println("\nInterrupted! Cancelling runs before shutting down, interrupt again to quit immediately...")
+
+		go func() {
+			<-signals
+			s.forceQuit()
+		}()
+
+		s.shutdown()
+		server.Shutdown(context.Background())
+	}()
+
+	com.Println("Serving gomu on", addr+"...")
+	if err = server.ListenAndServe(); err != http.ErrServerClosed {
+		return
+	}
+
+	<-stopped
+	return nil
+}
+
+// shutdown cancels queued and running runs, then waits for the running one to clean
+func (s *Server) shutdown() {
+	s.mux.Lock()
+	s.closed = true
+	for _, run := range s.runs {
+		switch run.State {
+		case RunQueued:
+			s.finishLocked(run, RunCancelled, nil)
+		case RunRunning:
+			run.cancel()
+		}
+	}
+	s.mux.Unlock()
+
+	s.performing.Lock()
+	s.performing.Unlock()
+}
+
+// forceQuit quits immediately, leaving the running run's recovery file if any
+func (s *Server) forceQuit() {
+	s.mux.Lock()
+	current := s.current
+	s.mux.Unlock()
+
+	if current != nil {
+		current.forceQuit()
+	}
+
+	com.Println("\nQuitting immediately!")
+	os.Exit(ExitAborted)
+}
+
+// ServeHTTP routes API requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.initOnce.Do(s.start)
+
+	if !s.authorized(r) {
+		writeJSONError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+
+	comps := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(comps) == 1 && comps[0] == "targets" && r.Method == http.MethodGet:
+		s.listTargets(w)
+	case len(comps) == 1 && comps[0] == "runs" && r.Method == http.MethodGet:
+		s.listRuns(w)
+	case len(comps) == 1 && comps[0] == "runs" && r.Method == http.MethodPost:
+		s.queueRun(w, r)
+	case len(comps) == 2 && comps[0] == "runs" && r.Method == http.MethodGet:
+		s.getRun(w, comps[1])
+	case len(comps) == 3 && comps[0] == "runs" && comps[2] == "events" && r.Method == http.MethodGet:
+		s.streamEvents(w, r, comps[1])
+	case len(comps) == 3 && comps[0] == "runs" && comps[2] == "cancel" && r.Method == http.MethodPost:
+		s.cancelRun(w, comps[1])
+	default:
+		writeJSONError(w, http.StatusNotFound, "no route for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// authorized returns true if no token is required, or r has the bearer token
+// Note: ListenAndServe requires a token, so only handlers served otherwise, e.g. in tests, may skip it
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.Token) == 0 {
+		return true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// start begins performing queued runs
+func (s *Server) start() {
+	s.queue = make(chan *ServerRun, 256)
+	go func() {
+		for run := range s.queue {
+			s.perform(run)
+		}
+	}()
+}
+
+func (s *Server) listTargets(w http.ResponseWriter) {
+	type target struct {
+		Name              string   `json:"name"`
+		TargetDirectories []string `json:"dirs"`
+	}
+
+	targets := []target{}
+	for name, options := range s.Targets {
+		targets = append(targets, target{Name: name, TargetDirectories: options.TargetDirectories})
+	}
+	gosort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+
+	writeJSON(w, http.StatusOK, targets)
+}
+
+func (s *Server) listRuns(w http.ResponseWriter) {
+	s.mux.Lock()
+	runs := make([]ServerRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, *run)
+	}
+	s.mux.Unlock()
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) getRun(w http.ResponseWriter, id string) {
+	s.mux.Lock()
+	run := s.find(id)
+	var response ServerRun
+	if run != nil {
+		response = *run
+	}
+	s.mux.Unlock()
+
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "run "+id+" not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) queueRun(w http.ResponseWriter, r *http.Request) {
+	var request RunRequest
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBytes)
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid run request: "+err.Error())
+		return
+	}
+
+	if _, ok := s.Targets[request.Target]; !ok {
+		writeJSONError(w, http.StatusBadRequest, "unknown target "+strconv.Quote(request.Target))
+		return
+	}
+
+	if !s.allowed(request.Action) {
+		writeJSONError(w, http.StatusBadRequest, "action "+strconv.Quote(request.Action)+" can't be run, expected one of "+strings.Join(s.actions(), ", "))
+		return
+	}
+
+	s.mux.Lock()
+	s.lastID++
+	run := &ServerRun{
+		ID:     strconv.Itoa(s.lastID),
+		Target: request.Target,
+		Action: request.Action,
+		Branch: request.Branch,
+		DryRun: request.DryRun,
+		State:  RunQueued,
+		Queued: time.Now(),
+	}
+	s.runs = append(s.runs, run)
+	s.prune()
+	response := *run
+	s.mux.Unlock()
+
+	select {
+	case s.queue <- run:
+		writeJSON(w, http.StatusAccepted, response)
+	default:
+		s.finish(run, RunCancelled, nil)
+		writeJSONError(w, http.StatusServiceUnavailable, "too many queued runs")
+	}
+}
+
+func (s *Server) cancelRun(w http.ResponseWriter, id string) {
+	s.mux.Lock()
+	run := s.find(id)
+	if run == nil {
+		s.mux.Unlock()
+		writeJSONError(w, http.StatusNotFound, "run "+id+" not found")
+		return
+	}
+
+	// State is changed under the lock, so perform can't start the run in between
+	switch state := run.State; state {
+	case RunQueued:
+		// Skipped once dequeued
+		s.finishLocked(run, RunCancelled, nil)
+	case RunRunning:
+		// Finished as cancelled once closed
+		run.cancel()
+	default:
+		s.mux.Unlock()
+		writeJSONError(w, http.StatusConflict, "run "+id+" already "+state)
+		return
+	}
+
+	response := *run
+	s.mux.Unlock()
+
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// streamEvents writes a run's events as server-sent events, from the start of the run until it finishes
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	s.mux.Lock()
+	run := s.find(id)
+	s.mux.Unlock()
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "run "+id+" not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		s.mux.Lock()
+		events := run.events[sent:]
+		done := run.State == RunFinished || run.State == RunCancelled
+		wake := make(chan struct{}, 1)
+		if !done {
+			run.waiters = append(run.waiters, wake)
+		}
+		final := *run
+		s.mux.Unlock()
+
+		for _, event := range events {
+			writeEvent(w, "output", event)
+		}
+		sent += len(events)
+
+		if done {
+			writeEvent(w, "done", final)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-wake:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// perform runs run with its target's options, capturing output as events
+func (s *Server) perform(run *ServerRun) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.performing.Lock()
+	defer s.performing.Unlock()
+
+	options := s.Targets[run.Target]
+	options.Merge(Options{Action: run.Action, Branch: run.Branch, DryRun: run.DryRun})
+	// Requesting the run is the confirmation, and the summary is returned instead of printed. Nobody can answer
+	// prompts, so missing credentials fail the run
+	options.IgnoreWarning = true
+	options.NonInteractive = true
+	options.Progress = false
+	options.Output = ""
+
+	mu := New(options)
+	// Interrupts are handled once by ListenAndServe, cancelling every run
+	mu.signalsHandled = true
+
+	s.mux.Lock()
+	if run.State != RunQueued {
+		// Cancelled while queued
+		s.mux.Unlock()
+		return
+	}
+
+	if s.closed {
+		s.finishLocked(run, RunCancelled, nil)
+		s.mux.Unlock()
+		return
+	}
+
+	started := time.Now()
+	run.State = RunRunning
+	run.Started = &started
+	run.cancel = cancel
+	s.current = mu
+	s.mux.Unlock()
+
+	previous := com.GetLogger()
+	com.SetLogger(runLogger{server: s, run: run, next: previous})
+	mu.RunContext(ctx)
+	com.SetLogger(previous)
+
+	summary := mu.Stats.Summary(mu.Errors)
+	state := RunFinished
+	if ctx.Err() != nil {
+		state = RunCancelled
+	}
+
+	s.mux.Lock()
+	s.current = nil
+	s.finishLocked(run, state, &summary)
+	s.mux.Unlock()
+}
+
+// finish marks run done, waking event streams
+func (s *Server) finish(run *ServerRun, state string, summary *Summary) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.finishLocked(run, state, summary)
+}
+
+// finishLocked marks run done as finish does
+// Note: call with s.mux held
+func (s *Server) finishLocked(run *ServerRun, state string, summary *Summary) {
+	finished := time.Now()
+	run.State = state
+	run.Finished = &finished
+	run.Summary = summary
+	run.cancel = nil
+	s.wake(run)
+	s.prune()
+}
+
+// record appends event to run's events, waking event streams
+func (s *Server) record(run *ServerRun, event RunEvent) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	run.events = append(run.events, event)
+	s.wake(run)
+}
+
+// wake signals each waiting event stream of run
+// Note: call with s.mux held
+func (s *Server) wake(run *ServerRun) {
+	for _, waiter := range run.waiters {
+		waiter <- struct{}{}
+	}
+	run.waiters = nil
+}
+
+// prune drops the oldest finished runs beyond the history limit
+// Note: call with s.mux held
+func (s *Server) prune() {
+	history := s.History
+	if history <= 0 {
+		history = DefaultServerHistory
+	}
+
+	finished := 0
+	for _, run := range s.runs {
+		if run.Finished != nil {
+			finished++
+		}
+	}
+
+	kept := s.runs[:0]
+	for _, run := range s.runs {
+		if run.Finished != nil && finished > history {
+			finished--
+			continue
+		}
+
+		kept = append(kept, run)
+	}
+	s.runs = kept
+}
+
+// find returns the run with id, nil if not found
+// Note: call with s.mux held
+func (s *Server) find(id string) *ServerRun {
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) actions() []string {
+	if len(s.Actions) > 0 {
+		return s.Actions
+	}
+
+	return DefaultServerActions
+}
+
+func (s *Server) allowed(action string) bool {
+	for _, allowed := range s.actions() {
+		if action == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runLogger records output as events of run, passing it on to the next logger
+type runLogger struct {
+	server *Server
+	run    *ServerRun
+	next   com.Logger
+}
+
+func (l runLogger) Debug(message string, fields ...interface{}) {
+	l.record("debug", message, fields)
+	l.next.Debug(message, fields...)
+}
+
+func (l runLogger) Info(message string, fields ...interface{}) {
+	l.record("info", message, fields)
+	l.next.Info(message, fields...)
+}
+
+func (l runLogger) Warn(message string, fields ...interface{}) {
+	l.record("warn", message, fields)
+	l.next.Warn(message, fields...)
+}
+
+func (l runLogger) Error(message string, fields ...interface{}) {
+	l.record("error", message, fields)
+	l.next.Error(message, fields...)
+}
+
+func (l runLogger) record(level, message string, fields []interface{}) {
+	event := RunEvent{Level: level, Message: message, Time: time.Now()}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fmt.Sprint(fields[i]) == com.LibraryField {
+			event.Library = fmt.Sprint(fields[i+1])
+		}
+	}
+
+	l.server.record(l.run, event)
+}
+
+// writeEvent writes value as a server-sent event named name
+func writeEvent(w http.ResponseWriter, name string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,169 @@
+package gomu
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// queuedServer returns a server with the token whose runs stay queued, as nothing performs them
+func queuedServer(token string) *Server {
+	s := NewServer(map[string]Options{"services": {}})
+	s.Token = token
+	s.initOnce.Do(func() { s.queue = make(chan *ServerRun, 256) })
+	return s
+}
+
+// serve sends a request with the bearer token to s, returning the response
+func serve(s *Server, method, target, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if len(token) > 0 {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+const syncRequest = `{"target": "services", "action": "sync"}`
+
+func TestServerRequiresToken(t *testing.T) {
+	s := queuedServer("secret")
+	for _, token := range []string{"", "wrong"} {
+		if w := serve(s, http.MethodPost, "/runs", token, syncRequest); w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %d with token %q, got %d", http.StatusUnauthorized, token, w.Code)
+		}
+	}
+
+	if len(s.runs) > 0 {
+		t.Errorf("expected no runs to be queued, got %d", len(s.runs))
+	}
+
+	if err := (&Server{}).ListenAndServe("127.0.0.1:0"); err != ErrNoToken {
+		t.Errorf("expected %v serving without a token, got %v", ErrNoToken, err)
+	}
+}
+
+func TestServerQueuesRun(t *testing.T) {
+	s := queuedServer("secret")
+	w := serve(s, http.MethodPost, "/runs", "secret", syncRequest)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+	}
+
+	var run ServerRun
+	if err := json.NewDecoder(w.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "1" || run.Target != "services" || run.Action != "sync" || run.State != RunQueued {
+		t.Errorf("expected queued sync run 1, got %+v", run)
+	}
+
+	w = serve(s, http.MethodGet, "/runs", "secret", "")
+	var runs []ServerRun
+	if err := json.NewDecoder(w.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(runs) != 1 || runs[0].State != RunQueued {
+		t.Errorf("expected %d listing the queued run, got %d: %+v", http.StatusOK, w.Code, runs)
+	}
+	if len(s.queue) != 1 {
+		t.Errorf("expected run to be queued, got %d queued", len(s.queue))
+	}
+}
+
+func TestServerQueueFull(t *testing.T) {
+	s := queuedServer("secret")
+	for i := 0; i < cap(s.queue); i++ {
+		if w := serve(s, http.MethodPost, "/runs", "secret", syncRequest); w.Code != http.StatusAccepted {
+			t.Fatalf("expected run %d to be queued, got %d", i+1, w.Code)
+		}
+	}
+
+	if w := serve(s, http.MethodPost, "/runs", "secret", syncRequest); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected %d once the queue is full, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	rejected := s.find(strconv.Itoa(cap(s.queue) + 1))
+	if rejected == nil || rejected.State != RunCancelled {
+		t.Errorf("expected rejected run to be cancelled, got %+v", rejected)
+	}
+}
+
+func TestServerHistory(t *testing.T) {
+	s := queuedServer("secret")
+	for i := 0; i < DefaultServerHistory+5; i++ {
+		serve(s, http.MethodPost, "/runs", "secret", syncRequest)
+		s.finish(s.runs[len(s.runs)-1], RunFinished, nil)
+	}
+	serve(s, http.MethodPost, "/runs", "secret", syncRequest)
+
+	var runs []ServerRun
+	if err := json.NewDecoder(serve(s, http.MethodGet, "/runs", "secret", "").Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != DefaultServerHistory+1 {
+		t.Fatalf("expected %d finished runs and the queued one, got %d", DefaultServerHistory, len(runs))
+	}
+	if runs[0].ID != "6" || runs[len(runs)-1].State != RunQueued {
+		t.Errorf("expected the oldest finished runs to be dropped, got runs %s to %s", runs[0].ID, runs[len(runs)-1].ID)
+	}
+}
+
+func TestServerPerformsOneRunAtATime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Runs are recorded to the history in the working directory
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}()
+
+	s := queuedServer("secret")
+	s.Targets["services"] = Options{TargetDirectories: []string{dir}}
+	serve(s, http.MethodPost, "/runs", "secret", `{"target": "services", "action": "list"}`)
+	run := s.find("1")
+
+	// Held by a run being performed
+	s.performing.Lock()
+	done := make(chan struct{})
+	go func() {
+		s.perform(run)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	s.mux.Lock()
+	state := run.State
+	s.mux.Unlock()
+	if state != RunQueued {
+		t.Errorf("expected run to wait for the one being performed, got %s", state)
+	}
+
+	s.performing.Unlock()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected run to be performed once the previous one finished")
+	}
+
+	if run.State != RunFinished || run.Summary == nil {
+		t.Errorf("expected run to finish with a summary, got %+v", run)
+	}
+}
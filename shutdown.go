@@ -0,0 +1,152 @@
+package gomu
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	gosort "sort"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// RecoveryName is the file written when a run is force quit, describing what was left behind
+const RecoveryName = ".gomu-recovery.json"
+
+// Recovery represents the state of a run when it was force quit
+type Recovery struct {
+	Action      string    `json:"action"`
+	Branch      string    `json:"branch,omitempty"`
+	Interrupted time.Time `json:"interrupted"`
+
+	// Libs being worked on when quitting, possibly left mid-step
+	InFlight []string `json:"inFlight,omitempty"`
+	// Branch of each lib in flight keyed by path, if it differs from Branch
+	Branches map[string]string `json:"branches,omitempty"`
+	// Libs whose stashes weren't restored, run recover-stash to restore them
+	Stashed []string `json:"stashed,omitempty"`
+	// Temporary directory containing lib worktrees, safe to delete
+	WorktreeDir string `json:"worktreeDir,omitempty"`
+
+	// Files recording progress: resume continues from the checkpoint, undo reverses the operation log
+	StashJournal string `json:"stashJournal,omitempty"`
+	Checkpoint   string `json:"checkpoint,omitempty"`
+	OperationLog string `json:"operationLog,omitempty"`
+}
+
+// Save writes the recovery to disk
+func (recovery *Recovery) Save(recoveryPath string) (err error) {
+	data, err := json.MarshalIndent(recovery, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(recoveryPath, data, 0644)
+}
+
+// handleSignals closes the run on the first interrupt, letting in-flight libs finish before cleaning, then force
+// quits on the second. Returns a func to stop handling once cleaned
+func (mu *MU) handleSignals() (stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+			}
+
+			if atomic.AddInt32(&mu.interrupts, 1) > 1 {
+				mu.forceQuit()
+				return
+			}
+
+			com.Println("\nInterrupted! Finishing in-flight libs before cleaning, interrupt again to quit immediately...")
+			mu.closer.Close(nil)
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// interrupted is true once the run has been interrupted by a signal
+func (mu *MU) interrupted() bool {
+	return atomic.LoadInt32(&mu.interrupts) > 0
+}
+
+// recovery returns what the run would leave behind if quit now
+func (mu *MU) recovery() (recovery Recovery) {
+	recovery.Action = mu.Options.Action
+	recovery.Branch = mu.runBranch()
+	recovery.Interrupted = time.Now()
+
+	mu.libraryMux.Lock()
+	for path, branch := range mu.inFlight {
+		recovery.InFlight = append(recovery.InFlight, path)
+		if branch != recovery.Branch {
+			if recovery.Branches == nil {
+				recovery.Branches = make(map[string]string)
+			}
+			recovery.Branches[path] = branch
+		}
+	}
+	if mu.operations != nil {
+		recovery.OperationLog = OperationLogName
+	}
+	mu.libraryMux.Unlock()
+	gosort.Strings(recovery.InFlight)
+
+	recovery.WorktreeDir = mu.worktreeDir
+
+	if records, err := com.LoadStashRecords(StashJournalName); err == nil && len(records) > 0 {
+		recovery.StashJournal = StashJournalName
+		for _, record := range records {
+			recovery.Stashed = append(recovery.Stashed, record.Path)
+		}
+	}
+
+	if mu.checkpoint != nil {
+		recovery.Checkpoint = CheckpointName
+	}
+
+	return
+}
+
+// forceQuit exits immediately without cleaning, writing what was left behind to the recovery file
+func (mu *MU) forceQuit() {
+	recovery := mu.recovery()
+	com.Println("\nQuitting immediately! Nothing was cleaned.")
+	if err := recovery.Save(RecoveryName); err != nil {
+		com.Println("Unable to save", RecoveryName+":", err)
+	} else {
+		com.Println("Recovery details written to", RecoveryName)
+	}
+
+	if len(recovery.InFlight) > 0 {
+		com.Println("In-flight lib(s) may be left mid-step:", recovery.InFlight)
+	}
+	if len(recovery.Stashed) > 0 {
+		com.Println("Run recover-stash to restore", len(recovery.Stashed), "stash(es)")
+	}
+	if len(recovery.WorktreeDir) > 0 {
+		com.Println("Worktrees were left in", recovery.WorktreeDir)
+	}
+	if len(recovery.Checkpoint) > 0 {
+		com.Println("Run sync with resume to continue from", recovery.Checkpoint)
+	}
+	if len(recovery.OperationLog) > 0 {
+		com.Println("Run undo to reverse changes recorded in", recovery.OperationLog)
+	}
+
+	com.CloseLibraryLogs()
+	os.Exit(ExitAborted)
+}
@@ -0,0 +1,82 @@
+package gomu
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestRecovery(t *testing.T) {
+	lib, _ := loggedLibrary(t)
+	journaledStash(t, lib)
+
+	mu := &MU{Options: Options{Action: "sync", Branch: "gomu"}, worktreeDir: "/tmp/gomu-worktrees"}
+	mu.Stats.DepCount = 1
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+	done := mu.beginLibrary(1, *lib)
+
+	recovery := mu.recovery()
+	expected := Recovery{
+		Action:       "sync",
+		Branch:       "gomu",
+		Interrupted:  recovery.Interrupted,
+		InFlight:     []string{lib.File.Path},
+		Stashed:      []string{lib.File.Path},
+		WorktreeDir:  "/tmp/gomu-worktrees",
+		StashJournal: StashJournalName,
+	}
+	if !reflect.DeepEqual(recovery, expected) {
+		t.Errorf("expected %+v, got %+v", expected, recovery)
+	}
+
+	if err := recovery.Save(RecoveryName); err != nil {
+		t.Fatal(err)
+	}
+	var saved Recovery
+	if data, err := ioutil.ReadFile(RecoveryName); err != nil || json.Unmarshal(data, &saved) != nil || saved.InFlight[0] != lib.File.Path {
+		t.Errorf("expected recovery to be saved, got %+v (%v)", saved, err)
+	}
+
+	if done(); len(mu.recovery().InFlight) > 0 {
+		t.Error("expected finished lib to no longer be in flight")
+	}
+}
+
+func TestIsAborted(t *testing.T) {
+	mu := &MU{}
+	if mu.isClosed() || mu.isAborted() {
+		t.Error("expected libs used without a run to never be closed")
+	}
+
+	var cancel context.CancelFunc
+	mu.ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if !mu.isClosed() || mu.isAborted() {
+		t.Error("expected cancelled run to finish in-flight libs")
+	}
+
+	mu.ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if !mu.isClosed() || !mu.isAborted() {
+		t.Error("expected run past its deadline to stop in-flight libs")
+	}
+}
+
+func TestInterruptedExitCode(t *testing.T) {
+	recorder := &com.Recorder{}
+	mu := New(Options{Action: "list", TargetDirectories: []string{targetLibrary(t)}})
+	mu.SetRunner(recorder)
+	mu.signalsHandled = true
+	mu.interrupts = 1
+
+	runContext(t, mu, context.Background())
+	if code := mu.ExitCode(); code != ExitAborted || mu.Errors[len(mu.Errors)-1] != ErrInterrupted {
+		t.Errorf("expected interrupted run to exit %d, got %d (%v)", ExitAborted, code, mu.Errors)
+	}
+}
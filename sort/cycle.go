@@ -0,0 +1,151 @@
+package sort
+
+import (
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// Policies when libs depend on each other
+const (
+	// OnCycleFail stops before sorting, reporting each cycle
+	OnCycleFail = "fail"
+	// OnCycleBreak ignores the dependency closing each cycle and sorts the rest
+	OnCycleBreak = "break"
+)
+
+// Cycle represents libs which depend on each other. Each lib depends on the next, and the last depends on the first
+type Cycle []*FileNode
+
+// String returns the cycle path, e.g. a -> b -> a
+func (cycle Cycle) String() string {
+	urls := make([]string, 0, len(cycle)+1)
+	for _, node := range cycle {
+		urls = append(urls, node.File.GetGoURL())
+	}
+
+	if len(cycle) > 0 {
+		urls = append(urls, cycle[0].File.GetGoURL())
+	}
+
+	return strings.Join(urls, " -> ")
+}
+
+// CycleError is returned when libs depend on each other and cycles aren't broken
+type CycleError struct {
+	Cycles []Cycle
+}
+
+func (err *CycleError) Error() string {
+	paths := make([]string, len(err.Cycles))
+	for i, cycle := range err.Cycles {
+		paths[i] = cycle.String()
+	}
+
+	return "dependency cycle found: " + strings.Join(paths, "; ") + ". Remove the dependency or set on cycle to " + OnCycleBreak
+}
+
+// findCycles returns each cycle between nodes, and the dependencies of each node without the dependency closing each cycle.
+// If direct is true only go.mod imports are used, otherwise go.sum dependencies are used
+func findCycles(nodes []*FileNode, direct bool) (cycles []Cycle, deps map[*FileNode][]*FileNode) {
+	deps = make(map[*FileNode][]*FileNode, len(nodes))
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[*FileNode]int, len(nodes))
+
+	// Depth-first, cycles close on a node still being visited
+	var stack []*FileNode
+	var visit func(node *FileNode)
+	visit = func(node *FileNode) {
+		states[node] = visiting
+		stack = append(stack, node)
+
+		for _, dep := range nodes {
+			if dep == node || !dependsOn(node, dep, direct) {
+				continue
+			}
+
+			switch states[dep] {
+			case visiting:
+				// Path from dep back to node closes the cycle, leave out the closing dependency
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == dep {
+						cycles = append(cycles, append(Cycle(nil), stack[i:]...))
+						break
+					}
+				}
+				continue
+			case unvisited:
+				visit(dep)
+			}
+
+			deps[node] = append(deps[node], dep)
+		}
+
+		stack = stack[:len(stack)-1]
+		states[node] = visited
+	}
+
+	for _, node := range nodes {
+		if states[node] == unvisited {
+			visit(node)
+		}
+	}
+
+	return
+}
+
+// sortNodes links nodes into a list with dependencies before dependents, handling cycles per onCycle. Cycles are found
+// through go.mod imports only if direct is true
+func sortNodes(nodes []*FileNode, onCycle string, direct bool) (listHead *FileNode, err error) {
+	cycles, deps := findCycles(nodes, direct)
+	if len(cycles) == 0 {
+		for _, node := range nodes {
+			node.InsertInto(&listHead)
+		}
+
+		return
+	}
+
+	if onCycle != OnCycleBreak {
+		err = &CycleError{Cycles: cycles}
+		return
+	}
+
+	for _, cycle := range cycles {
+		last := cycle[len(cycle)-1].File.GetGoURL()
+		com.Warnln("Breaking dependency cycle " + cycle.String() + ", " + last + " will be sorted before " + cycle[0].File.GetGoURL())
+	}
+
+	// Insertion order can't be used, dependencies in cycles are ignored
+	sorted := make(map[*FileNode]bool, len(nodes))
+	var tail *FileNode
+	var insert func(node *FileNode)
+	insert = func(node *FileNode) {
+		if sorted[node] {
+			return
+		}
+		sorted[node] = true
+
+		for _, dep := range deps[node] {
+			insert(dep)
+		}
+
+		if tail == nil {
+			listHead = node
+		} else {
+			node.insertAfter(tail)
+		}
+		tail = node
+	}
+
+	for _, node := range nodes {
+		insert(node)
+	}
+
+	return
+}
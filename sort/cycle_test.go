@@ -0,0 +1,136 @@
+package sort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// testLib describes the modules a test lib requires in go.mod, and those only listed in go.sum
+type testLib struct {
+	requires []string
+	sums     []string
+}
+
+// testNodes returns a node for each lib, in order, with go urls example.com/<name>
+func testNodes(t *testing.T, names []string, libs map[string]testLib) (nodes []*FileNode) {
+	dir, err := ioutil.TempDir("", "gomu-sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, name := range names {
+		libDir := filepath.Join(dir, "go", "src", "example.com", name)
+		if err = os.MkdirAll(libDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		lib := libs[name]
+		mod := "module example.com/" + name + "\n"
+		var sum string
+		for _, dep := range lib.requires {
+			mod += "require example.com/" + dep + " v1.0.0\n"
+			sum += "example.com/" + dep + " v1.0.0 h1:hash=\n"
+		}
+		for _, dep := range lib.sums {
+			sum += "example.com/" + dep + " v1.0.0 h1:hash=\n"
+		}
+
+		if err = ioutil.WriteFile(filepath.Join(libDir, "go.mod"), []byte(mod), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(libDir, "go.sum"), []byte(sum), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		nodes = append(nodes, &FileNode{File: &com.FileWrapper{Path: libDir}})
+	}
+
+	return
+}
+
+// urls returns the short names of nodes, e.g. a for example.com/a
+func urls(nodes []*FileNode) (names []string) {
+	for _, node := range nodes {
+		names = append(names, strings.TrimPrefix(node.File.GetGoURL(), "example.com/"))
+	}
+
+	return
+}
+
+func TestFindCyclesDirect(t *testing.T) {
+	// a requires b, b only depends on a through go.sum
+	nodes := testNodes(t, []string{"a", "b"}, map[string]testLib{
+		"a": {requires: []string{"b"}},
+		"b": {sums: []string{"a"}},
+	})
+
+	if cycles, _ := findCycles(nodes, false); len(cycles) != 1 {
+		t.Errorf("expected a go.sum cycle, got %v", cycles)
+	}
+
+	if cycles, deps := findCycles(nodes, true); len(cycles) != 0 || len(deps[nodes[0]]) != 1 || len(deps[nodes[1]]) != 0 {
+		t.Errorf("expected no direct cycle, got %v", cycles)
+	}
+}
+
+// cycleLibs has a and b requiring each other, c requiring a and d on its own
+var cycleLibs = map[string]testLib{
+	"a": {requires: []string{"b"}},
+	"b": {requires: []string{"a"}},
+	"c": {requires: []string{"a"}},
+}
+
+func TestFindCycles(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, cycleLibs)
+
+	cycles, deps := findCycles(nodes, true)
+	if len(cycles) != 1 || !reflect.DeepEqual(urls(cycles[0]), []string{"a", "b"}) {
+		t.Fatalf("expected cycle a -> b -> a, got %v", cycles)
+	}
+
+	// The dependency closing the cycle, b on a, is left out
+	for node, expected := range map[*FileNode][]string{nodes[0]: {"b"}, nodes[1]: nil, nodes[2]: {"a"}, nodes[3]: nil} {
+		if got := urls(deps[node]); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected deps %q, got %q", urls([]*FileNode{node})[0], expected, got)
+		}
+	}
+}
+
+func TestSortNodesCycleFail(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, cycleLibs)
+
+	listHead, err := sortNodes(nodes, OnCycleFail, true)
+	cycleErr, ok := err.(*CycleError)
+	if !ok || listHead != nil {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	if len(cycleErr.Cycles) != 1 || cycleErr.Cycles[0].String() != "example.com/a -> example.com/b -> example.com/a" {
+		t.Errorf("expected cycle a -> b -> a, got %v", cycleErr)
+	}
+}
+
+func TestSortNodesCycleBreak(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, cycleLibs)
+
+	listHead, err := sortNodes(nodes, OnCycleBreak, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sorted []*FileNode
+	for itr := listHead; itr != nil; itr = itr.Next {
+		sorted = append(sorted, itr)
+	}
+
+	if expected := []string{"b", "a", "c", "d"}; !reflect.DeepEqual(urls(sorted), expected) {
+		t.Errorf("expected %q, got %q", expected, urls(sorted))
+	}
+}
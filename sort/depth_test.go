@@ -0,0 +1,34 @@
+package sort
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithinDepth(t *testing.T) {
+	// b requires a, c requires b, d requires c, each summing the rest of the chain
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, map[string]testLib{
+		"b": {requires: []string{"a"}},
+		"c": {requires: []string{"b"}, sums: []string{"a"}},
+		"d": {requires: []string{"c"}, sums: []string{"a", "b"}},
+	})
+	filters := parseFilters(StringArray{"example.com/a"})
+
+	for maxDepth, expected := range map[int][]string{1: {"a", "b"}, 2: {"a", "b", "c"}, 5: {"a", "b", "c", "d"}} {
+		if within := urls(withinDepth(nodes, filters, maxDepth)); !reflect.DeepEqual(within, expected) {
+			t.Errorf("expected %v within depth %d, got %v", expected, maxDepth, within)
+		}
+	}
+}
+
+func TestWithinDepthSkipsSumOnly(t *testing.T) {
+	// c only depends on a through go.sum, so is as far from a as b
+	nodes := testNodes(t, []string{"b", "c"}, map[string]testLib{
+		"b": {requires: []string{"a"}},
+		"c": {requires: []string{"b"}, sums: []string{"a"}},
+	})
+
+	if within := urls(withinDepth(nodes, parseFilters(StringArray{"example.com/a"}), 1)); !reflect.DeepEqual(within, []string{"b"}) {
+		t.Errorf("expected only b within depth 1, got %v", within)
+	}
+}
@@ -0,0 +1,214 @@
+package sort
+
+import (
+	"encoding/json"
+	gosort "sort"
+	"strconv"
+	"strings"
+)
+
+// Edge represents a dependency between two files in a sorted list
+type Edge struct {
+	// Dependent file
+	From *FileNode
+	// Dependency of From
+	To *FileNode
+}
+
+// Graph represents every file in a sorted list and all dependencies between them
+type Graph struct {
+	Nodes []*FileNode
+	Edges []Edge
+}
+
+// GraphFrom returns the full edge set of the provided sorted list.
+// If direct is true only go.mod imports are used, otherwise go.sum dependencies are used
+func GraphFrom(listHead *FileNode, direct bool) (graph Graph) {
+	for itr := listHead; itr != nil; itr = itr.Next {
+		graph.Nodes = append(graph.Nodes, itr)
+	}
+
+	// Every pair is checked, as dependencies broken out of a cycle come after their dependents
+	for i, node := range graph.Nodes {
+		for j, dep := range graph.Nodes {
+			if i != j && dependsOn(node, dep, direct) {
+				graph.Edges = append(graph.Edges, Edge{From: node, To: dep})
+			}
+		}
+	}
+
+	return
+}
+
+// DOT returns the graph formatted for graphviz
+func (graph Graph) DOT() string {
+	var lines = []string{"digraph deps {"}
+	for _, node := range graph.Nodes {
+		lines = append(lines, "  "+strconv.Quote(node.File.GetGoURL())+";")
+	}
+
+	for _, edge := range graph.Edges {
+		lines = append(lines, "  "+strconv.Quote(edge.From.File.GetGoURL())+" -> "+strconv.Quote(edge.To.File.GetGoURL())+";")
+	}
+
+	lines = append(lines, "}")
+	return strings.Join(lines, "\n")
+}
+
+// Mermaid returns the graph formatted as a mermaid flowchart
+func (graph Graph) Mermaid() string {
+	var ids = make(map[*FileNode]string, len(graph.Nodes))
+	var lines = []string{"graph TD"}
+	for i, node := range graph.Nodes {
+		ids[node] = "n" + strconv.Itoa(i)
+		lines = append(lines, "  "+ids[node]+"["+strconv.Quote(node.File.GetGoURL())+"]")
+	}
+
+	for _, edge := range graph.Edges {
+		lines = append(lines, "  "+ids[edge.From]+" --> "+ids[edge.To])
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// JSON returns the graph formatted as an adjacency list of each file to its dependencies
+func (graph Graph) JSON() (output string, err error) {
+	var adjacency = make(map[string][]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		adjacency[node.File.GetGoURL()] = []string{}
+	}
+
+	for _, edge := range graph.Edges {
+		url := edge.From.File.GetGoURL()
+		adjacency[url] = append(adjacency[url], edge.To.File.GetGoURL())
+	}
+
+	data, err := json.MarshalIndent(adjacency, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return string(data), nil
+}
+
+// Levels groups the sorted list by dependency depth. Files only depend on files in earlier levels,
+// so files within a level are independent of each other. Every dependency is checked rather than relying on the sorted
+// order, which doesn't hold once a cycle is broken. Files in a cycle are put in sequential levels, in sorted order
+func Levels(listHead *FileNode, direct bool) (levels [][]*FileNode) {
+	var nodes []*FileNode
+	for itr := listHead; itr != nil; itr = itr.Next {
+		nodes = append(nodes, itr)
+	}
+
+	deps := make([][]int, len(nodes))
+	for i, node := range nodes {
+		for j, dep := range nodes {
+			if i != j && dependsOn(node, dep, direct) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	// Components come after the components they depend on, so their dependencies' depths are final
+	depths := make([]int, len(nodes))
+	components, componentOf := stronglyConnected(deps)
+	for c, component := range components {
+		depth := 0
+		for _, i := range component {
+			for _, j := range deps[i] {
+				if componentOf[j] != c && depths[j]+1 > depth {
+					depth = depths[j] + 1
+				}
+			}
+		}
+
+		for k, i := range component {
+			depths[i] = depth + k
+		}
+	}
+
+	for i, node := range nodes {
+		depth := depths[i]
+		for len(levels) <= depth {
+			levels = append(levels, nil)
+		}
+
+		levels[depth] = append(levels[depth], node)
+	}
+
+	return
+}
+
+// dependsOn returns true if node depends on dep, only through go.mod imports if direct is true
+func dependsOn(node, dep *FileNode, direct bool) bool {
+	if direct {
+		return node.File.DirectlyImports(dep.File)
+	}
+
+	return node.File.DependsOn(dep.File)
+}
+
+// stronglyConnected returns the strongly connected components of the graph of deps, indexes of the nodes each index
+// depends on. Components are ordered after the components they depend on, with members in ascending order.
+// componentOf holds the component of each index
+func stronglyConnected(deps [][]int) (components [][]int, componentOf []int) {
+	const unvisited = -1
+
+	count := len(deps)
+	order, lowest := make([]int, count), make([]int, count)
+	componentOf = make([]int, count)
+	onStack := make([]bool, count)
+	for i := range order {
+		order[i], componentOf[i] = unvisited, unvisited
+	}
+
+	// Tarjan's algorithm, components complete once every component they reach has
+	var stack []int
+	next := 0
+	var visit func(i int)
+	visit = func(i int) {
+		order[i], lowest[i] = next, next
+		next++
+		stack = append(stack, i)
+		onStack[i] = true
+
+		for _, j := range deps[i] {
+			if order[j] == unvisited {
+				visit(j)
+				if lowest[j] < lowest[i] {
+					lowest[i] = lowest[j]
+				}
+			} else if onStack[j] && order[j] < lowest[i] {
+				lowest[i] = order[j]
+			}
+		}
+
+		if lowest[i] != order[i] {
+			return
+		}
+
+		var component []int
+		for {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[j] = false
+			componentOf[j] = len(components)
+			component = append(component, j)
+			if j == i {
+				break
+			}
+		}
+
+		// Members are placed in sorted order
+		gosort.Ints(component)
+		components = append(components, component)
+	}
+
+	for i := range deps {
+		if order[i] == unvisited {
+			visit(i)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,114 @@
+package sort
+
+import (
+	"reflect"
+	"testing"
+)
+
+// edges returns the edges of graph as from>to short names
+func edges(graph Graph) (lines []string) {
+	for _, edge := range graph.Edges {
+		lines = append(lines, urls([]*FileNode{edge.From})[0]+">"+urls([]*FileNode{edge.To})[0])
+	}
+
+	return
+}
+
+func TestGraphFromBrokenCycle(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c"}, map[string]testLib{
+		"a": {requires: []string{"b"}},
+		"b": {requires: []string{"a"}},
+		"c": {requires: []string{"a"}},
+	})
+
+	listHead, err := sortNodes(nodes, OnCycleBreak, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The dependency broken out of the cycle is still an edge, though it comes after its dependent
+	graph := GraphFrom(listHead, true)
+	expected := []string{"b>a", "a>b", "c>a"}
+	if lines := edges(graph); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, cycleLibs)
+
+	// Fails on the cycle, so nothing to group
+	if listHead, err := sortNodes(nodes, OnCycleFail, true); err == nil || Levels(listHead, true) != nil {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	listHead, err := sortNodes(nodes, OnCycleBreak, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cycle members are in sequential levels, before their dependents
+	var levels [][]string
+	for _, level := range Levels(listHead, true) {
+		levels = append(levels, urls(level))
+	}
+
+	expected := [][]string{{"b", "d"}, {"a"}, {"c"}}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("expected %q, got %q", expected, levels)
+	}
+}
+
+func TestGraphFormats(t *testing.T) {
+	nodes := testNodes(t, []string{"a", "b", "c"}, map[string]testLib{
+		"b": {requires: []string{"a"}},
+		"c": {requires: []string{"a", "b"}},
+	})
+
+	listHead, err := sortNodes(nodes, OnCycleFail, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := GraphFrom(listHead, true)
+	if expected := []string{"b>a", "c>a", "c>b"}; !reflect.DeepEqual(edges(graph), expected) {
+		t.Fatalf("expected %q, got %q", expected, edges(graph))
+	}
+
+	dot := `digraph deps {
+  "example.com/a";
+  "example.com/b";
+  "example.com/c";
+  "example.com/b" -> "example.com/a";
+  "example.com/c" -> "example.com/a";
+  "example.com/c" -> "example.com/b";
+}`
+	if output := graph.DOT(); output != dot {
+		t.Errorf("expected dot %s, got %s", dot, output)
+	}
+
+	mermaid := `graph TD
+  n0["example.com/a"]
+  n1["example.com/b"]
+  n2["example.com/c"]
+  n1 --> n0
+  n2 --> n0
+  n2 --> n1`
+	if output := graph.Mermaid(); output != mermaid {
+		t.Errorf("expected mermaid %s, got %s", mermaid, output)
+	}
+
+	adjacency := `{
+  "example.com/a": [],
+  "example.com/b": [
+    "example.com/a"
+  ],
+  "example.com/c": [
+    "example.com/a",
+    "example.com/b"
+  ]
+}`
+	if output, err := graph.JSON(); err != nil || output != adjacency {
+		t.Errorf("expected json %s, got %s (%v)", adjacency, output, err)
+	}
+}
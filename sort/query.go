@@ -0,0 +1,58 @@
+package sort
+
+import "github.com/gomuserver/mod-utils/com"
+
+// Find returns the node of module in the list starting at listHead, or nil if not found
+func (listHead *FileNode) Find(module string) *FileNode {
+	for itr := listHead; itr != nil; itr = itr.Next {
+		if itr.File.GetGoURL() == module {
+			return itr
+		}
+	}
+
+	return nil
+}
+
+// Paths returns the path of each file in the list starting at listHead, in order
+func (listHead *FileNode) Paths() (paths StringArray) {
+	for itr := listHead; itr != nil; itr = itr.Next {
+		paths = append(paths, itr.File.Path)
+	}
+
+	return
+}
+
+// DependentsOf returns files in the list starting at listHead which directly or indirectly depend on module, in sorted order.
+// Module doesn't need to be in the list, e.g. a third party dep
+func (listHead *FileNode) DependentsOf(module string) (dependents []*FileNode) {
+	dep := &com.FileWrapper{Path: module}
+	if node := listHead.Find(module); node != nil {
+		dep = node.File
+	}
+
+	for itr := listHead; itr != nil; itr = itr.Next {
+		if itr.File != dep && itr.File.DependsOn(dep) {
+			dependents = append(dependents, itr)
+		}
+	}
+
+	return
+}
+
+// DependenciesOf returns files in the list starting at listHead which module directly or indirectly depends on, in sorted order.
+// Returns nil if module isn't in the list
+func (listHead *FileNode) DependenciesOf(module string) (dependencies []*FileNode) {
+	node := listHead.Find(module)
+	if node == nil {
+		return
+	}
+
+	// Cycles may have been broken, dependencies aren't always sorted before dependents
+	for itr := listHead; itr != nil; itr = itr.Next {
+		if itr != node && node.File.DependsOn(itr.File) {
+			dependencies = append(dependencies, itr)
+		}
+	}
+
+	return
+}
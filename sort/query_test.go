@@ -0,0 +1,51 @@
+package sort
+
+import (
+	"reflect"
+	"testing"
+)
+
+// queryLibs returns sorted libs where c requires b, which requires a. D only requires a third party module
+func queryLibs(t *testing.T) *FileNode {
+	nodes := testNodes(t, []string{"a", "b", "c", "d"}, map[string]testLib{
+		"b": {requires: []string{"a"}},
+		"c": {requires: []string{"b"}, sums: []string{"a"}},
+		"d": {requires: []string{"yaml"}},
+	})
+
+	listHead, err := sortNodes(nodes, OnCycleFail, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listHead
+}
+
+func TestDependentsOf(t *testing.T) {
+	listHead := queryLibs(t)
+
+	if dependents := urls(listHead.DependentsOf("example.com/a")); !reflect.DeepEqual(dependents, []string{"b", "c"}) {
+		t.Errorf("expected b and c to depend on a, got %q", dependents)
+	}
+	// Modules outside the list
+	if dependents := urls(listHead.DependentsOf("example.com/yaml")); !reflect.DeepEqual(dependents, []string{"d"}) {
+		t.Errorf("expected d to depend on yaml, got %q", dependents)
+	}
+	if dependents := listHead.DependentsOf("example.com/c"); len(dependents) > 0 {
+		t.Errorf("expected nothing to depend on c, got %q", urls(dependents))
+	}
+}
+
+func TestDependenciesOf(t *testing.T) {
+	listHead := queryLibs(t)
+
+	if dependencies := urls(listHead.DependenciesOf("example.com/c")); !reflect.DeepEqual(dependencies, []string{"a", "b"}) {
+		t.Errorf("expected c to depend on a and b, got %q", dependencies)
+	}
+	if dependencies := listHead.DependenciesOf("example.com/yaml"); dependencies != nil {
+		t.Errorf("expected no dependencies of a module outside the list, got %q", urls(dependencies))
+	}
+	if node := listHead.Find("example.com/d"); node == nil || node.File.GetGoURL() != "example.com/d" {
+		t.Errorf("expected to find d, got %v", node)
+	}
+}
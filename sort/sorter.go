@@ -2,64 +2,91 @@ package sort
 
 import (
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/gomuserver/mod-utils/com"
+	"github.com/remeh/sizedwaitgroup"
 )
 
-// SortedRecursiveDeps returns a linked list of FileNodes directly or indirectly depending on provided filters
-// Note returns all libs if no filters provided
-func (libs StringArray) SortedRecursiveDeps(subDeps StringArray) (listHead *FileNode, count int) {
-	// Parse filters
-	filters := make([]*com.FileWrapper, len(subDeps))
-	for i := range subDeps {
-		var f com.FileWrapper
-		filterComps := strings.Split(subDeps[i], "@")
-		if len(filterComps) > 1 {
-			f.Path = filterComps[0]
-			f.Version = filterComps[1]
-		} else {
-			f.Path = subDeps[i]
-		}
-		filters[i] = &f
+// SortedRecursiveDeps returns a linked list of FileNodes directly or indirectly depending on provided filters.
+// If maxDepth is set, only libs within maxDepth requires of a filter dep are included (1 for libs requiring one directly)
+// Note returns all libs if no filters provided. Libs depending on each other are handled per onCycle
+func (libs StringArray) SortedRecursiveDeps(subDeps StringArray, onCycle string, maxDepth int) (listHead *FileNode, count int, err error) {
+	filters := parseFilters(subDeps)
+
+	// Add file to list if no filters are provided, or if file depends on any of the filter deps
+	nodes := libs.nodes(func(file *com.FileWrapper) bool {
+		return len(filters) == 0 || file.MatchesAny(filters) || file.DependsOnAny(filters)
+	})
+
+	if len(filters) > 0 && maxDepth > 0 {
+		nodes = withinDepth(nodes, filters, maxDepth)
 	}
 
-	// Parse each lib and add if included by a filter or if no filters provided
-	for i := range libs {
-		var node FileNode
-		var file com.FileWrapper
-		node.File = &file
-		node.File.Path = strings.TrimSpace(libs[i])
+	listHead, err = sortNodes(nodes, onCycle, false)
+	count = len(nodes)
+	return
+}
 
-		if len(node.File.Path) == 0 {
-			// Ignore if no file name
-			continue
+// SortedDirectDeps returns a linked list of FileNodes depending on provided filters
+// Note returns all libs if no filters provided. Libs depending on each other are handled per onCycle
+func (libs StringArray) SortedDirectDeps(subDeps StringArray, onCycle string) (listHead *FileNode, count int, err error) {
+	filters := parseFilters(subDeps)
+
+	// Add file to list if no filters are provided, or if file directly imports any of the filter deps
+	nodes := libs.nodes(func(file *com.FileWrapper) bool {
+		return len(filters) == 0 || file.MatchesAny(filters) || file.DirectlyImportsAny(filters)
+	})
+
+	listHead, err = sortNodes(nodes, onCycle, true)
+	count = len(nodes)
+	return
+}
+
+// withinDepth returns nodes which match a filter, or require one through at most maxDepth go.mod requires of other nodes.
+// Indirect requires don't count, go lists every transitive dep in go.mod since 1.17
+func withinDepth(nodes []*FileNode, filters []*com.FileWrapper, maxDepth int) (within []*FileNode) {
+	reached := make(map[*FileNode]bool, len(nodes))
+	frontier := append([]*com.FileWrapper(nil), filters...)
+	for _, node := range nodes {
+		if node.File.MatchesAny(filters) {
+			reached[node] = true
+			frontier = append(frontier, node.File)
 		}
+	}
 
-		f, err := os.Open(path.Join(node.File.Path, ".git"))
-		if err != nil {
-			// Ignore if not a repo
-			continue
+	for depth := 1; depth <= maxDepth && len(frontier) > 0; depth++ {
+		var next []*com.FileWrapper
+		for _, node := range nodes {
+			if reached[node] {
+				continue
+			}
+
+			for _, dep := range frontier {
+				if node.File.DirectlyRequires(dep) {
+					reached[node] = true
+					next = append(next, node.File)
+					break
+				}
+			}
 		}
-		defer f.Close()
 
-		// Add file to list if no filters are provided, or if file depends on any of the filter deps
-		if len(filters) == 0 || node.File.MatchesAny(filters) || node.File.DependsOnAny(filters) {
-			// Insert file
-			node.InsertInto(&listHead)
-			count++
+		frontier = next
+	}
+
+	for _, node := range nodes {
+		if reached[node] {
+			within = append(within, node)
 		}
 	}
 
 	return
 }
 
-// SortedDirectDeps returns a linked list of FileNodes depending on provided filters
-// Note returns all libs if no filters provided
-func (libs StringArray) SortedDirectDeps(subDeps StringArray) (listHead *FileNode, count int) {
-	// Parse filters
-	filters := make([]*com.FileWrapper, len(subDeps))
+// parseFilters parses filter deps, optionally with a version (path@version)
+func parseFilters(subDeps StringArray) (filters []*com.FileWrapper) {
+	filters = make([]*com.FileWrapper, len(subDeps))
 	for i := range subDeps {
 		var f com.FileWrapper
 		filterComps := strings.Split(subDeps[i], "@")
@@ -69,33 +96,94 @@ func (libs StringArray) SortedDirectDeps(subDeps StringArray) (listHead *FileNod
 		} else {
 			f.Path = subDeps[i]
 		}
+		// Cached before filters are shared between goroutines
+		f.GetGoURL()
 		filters[i] = &f
 	}
 
-	// Parse each lib and add if included by a filter or if no filters provided
+	return
+}
+
+// InRepo returns true if dir is a repo, or a nested module within one at or below root. The walk stops at root, so repos
+// containing it (e.g. a home directory kept in git) don't count. Only dir itself is checked if it isn't within root
+func InRepo(dir, root string) bool {
+	dir, root = filepath.Clean(dir), filepath.Clean(root)
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		root = dir
+	}
+
+	for ; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return true
+		}
+
+		if dir == root || filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// listedRoot returns the nearest of listed containing dir, the lib it was found in, or dir if none do
+func listedRoot(dir string, listed map[string]bool) string {
+	for parent := filepath.Dir(filepath.Clean(dir)); ; parent = filepath.Dir(parent) {
+		if listed[parent] {
+			return parent
+		}
+
+		if parent == "." || filepath.Dir(parent) == parent {
+			return dir
+		}
+	}
+}
+
+// nodes returns unsorted FileNodes for each lib which is a repo and is included, in the order of libs.
+// Libs are scanned concurrently, parsing their mod files once
+func (libs StringArray) nodes(included func(file *com.FileWrapper) bool) (nodes []*FileNode) {
+	found := make(map[string]bool, len(libs))
+	listed := make(map[string]bool, len(libs))
+	for _, lib := range libs {
+		listed[filepath.Clean(strings.TrimSpace(lib))] = true
+	}
+
+	scanned := make([]*FileNode, len(libs))
+	waiter := sizedwaitgroup.New(com.MaxConcurrency())
 	for i := range libs {
 		var node FileNode
 		var file com.FileWrapper
 		node.File = &file
 		node.File.Path = strings.TrimSpace(libs[i])
 
-		if len(node.File.Path) == 0 {
-			// Ignore if no file name
+		if len(node.File.Path) == 0 || found[node.File.Path] {
+			// Ignore if no file name or already added
 			continue
 		}
+		found[node.File.Path] = true
 
-		f, err := os.Open(path.Join(node.File.Path, ".git"))
-		if err != nil {
-			// Ignore if not a repo
-			continue
-		}
-		defer f.Close()
+		waiter.Add()
+		go func(i int, node *FileNode) {
+			defer waiter.Done()
+
+			if !InRepo(node.File.Path, listedRoot(node.File.Path, listed)) {
+				// Ignore if not a repo, or a nested module within a listed one
+				return
+			}
+
+			// Cache values read while sorting
+			node.File.GetGoURL()
+			node.File.LoadDeps()
+			node.File.Manifest()
+
+			if included(node.File) {
+				scanned[i] = node
+			}
+		}(i, &node)
+	}
+
+	waiter.Wait()
 
-		// Add file to list if no filters are provided, or if file depends on any of the filter deps
-		if len(filters) == 0 || node.File.MatchesAny(filters) || node.File.DirectlyImportsAny(filters) {
-			// Insert file
-			node.InsertInto(&listHead)
-			count++
+	for _, node := range scanned {
+		if node != nil {
+			nodes = append(nodes, node)
 		}
 	}
 
@@ -0,0 +1,61 @@
+package sort
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInRepo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// A home directory kept in git, containing a plain module and a repo with a nested module
+	home := filepath.Join(dir, "home")
+	src := filepath.Join(home, "src")
+	lib := filepath.Join(src, "lib")
+	repo := filepath.Join(src, "repo")
+	nested := filepath.Join(repo, "services", "a")
+	for _, path := range []string{filepath.Join(home, ".git"), lib, filepath.Join(repo, ".git"), nested} {
+		if err = os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		dir    string
+		root   string
+		inRepo bool
+	}{
+		{repo, repo, true},
+		{repo, src, true},
+		{nested, src, true},
+		{nested, repo, true},
+		{lib, src, false},
+		{lib, home, true},
+		// Outside root, only the dir itself counts
+		{nested, lib, false},
+		{repo, lib, true},
+	} {
+		if inRepo := InRepo(test.dir, test.root); inRepo != test.inRepo {
+			t.Errorf("%s in %s: expected %v, got %v", test.dir, test.root, test.inRepo, inRepo)
+		}
+	}
+}
+
+func TestListedRoot(t *testing.T) {
+	listed := map[string]bool{"/src/repo": true, "/src/other": true}
+	for dir, expected := range map[string]string{
+		"/src/repo/services/a": "/src/repo",
+		"/src/repo":            "/src/repo",
+		"/src/lib":             "/src/lib",
+	} {
+		if root := listedRoot(dir, listed); root != expected {
+			t.Errorf("%s: expected %s, got %s", dir, expected, root)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package gomu
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// StashJournalName is the file stashes created during a run are recorded to, until they're verified restored
+const StashJournalName = ".gomu-stashes.json"
+
+// verifyStashes checks every recorded stash was popped, keeping orphans in the journal for recover-stash
+func (mu *MU) verifyStashes() {
+	if mu.Options.DryRun {
+		// Nothing was stashed
+		return
+	}
+
+	records, err := com.LoadStashRecords(StashJournalName)
+	if err != nil {
+		com.Println("Warning - Unable to read", StashJournalName+":", err)
+		return
+	}
+
+	var orphans []com.StashRecord
+	for _, record := range records {
+		file := com.FileWrapper{Path: record.Path}
+		if ref, err := file.StashListed(record.Commit); err != nil || len(ref) > 0 {
+			orphans = append(orphans, record)
+		}
+	}
+
+	if len(orphans) > 0 {
+		com.Println("\nWarning -", len(orphans), "stash(es) weren't restored, run recover-stash to restore them:")
+		for _, record := range orphans {
+			com.Println(" ", record.Path, record.Commit)
+		}
+	}
+
+	if err = com.SaveStashRecords(StashJournalName, orphans); err != nil {
+		com.Println("Warning - Unable to save", StashJournalName+":", err)
+	}
+}
+
+// recoverStashes restores stashes recorded in the journal which were never restored
+func (mu *MU) recoverStashes() {
+	records, err := com.LoadStashRecords(StashJournalName)
+	if err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to read %s: %v", StashJournalName, err))
+		return
+	} else if len(records) == 0 {
+		com.Println("\nNo recorded stashes to recover.")
+		return
+	}
+
+	com.Println("\nRecovering", len(records), "recorded stash(es)...")
+
+	var remaining []com.StashRecord
+	for i, record := range records {
+		if mu.isClosed() {
+			// Keep the rest to retry
+			remaining = append(remaining, records[i:]...)
+			break
+		}
+
+		lib := LibraryFromPath(record.Path)
+		restored, err := mu.recoverStash(*lib, record)
+		if err != nil {
+			lib.File.Error(err.Error())
+			remaining = append(remaining, record)
+			continue
+		}
+
+		if restored {
+			mu.addStat(&mu.Stats.RecoveredCount, &mu.Stats.RecoveredOutput, record.Path+" "+record.Commit+"\n")
+		}
+	}
+
+	if mu.Options.DryRun {
+		// Keep records to recover for real
+		return
+	}
+
+	if err = com.SaveStashRecords(StashJournalName, remaining); err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to save %s: %v", StashJournalName, err))
+	}
+}
+
+// recoverStash pops record's stash if still listed, or applies its commit if it was dropped without being restored.
+// Returns false without an error if there's nothing left to recover
+func (mu *MU) recoverStash(lib Library, record com.StashRecord) (restored bool, err error) {
+	if _, statErr := os.Stat(record.Path); statErr != nil {
+		lib.File.Output("Warning - Working copy no longer exists, dropping stash " + record.Commit)
+		return
+	}
+
+	ref, err := lib.File.StashListed(record.Commit)
+	if err != nil {
+		return
+	}
+
+	if len(ref) > 0 {
+		if lib.File.HasChanges() {
+			err = fmt.Errorf("local changes would conflict, restore it with git stash pop %s", ref)
+			return
+		}
+
+		if err = lib.File.RunCmd("git", "stash", "pop", ref); err != nil {
+			err = fmt.Errorf("unable to pop %s: %v", ref, err)
+			return
+		}
+
+		lib.File.Output("Restored stash " + ref)
+		return true, nil
+	}
+
+	// Dropped without being restored, or restored before it could be verified
+	tree, treeErr := lib.File.CmdOutput("git", "rev-parse", record.Commit+"^{tree}")
+	if treeErr != nil {
+		lib.File.Output("Warning - Stash " + record.Commit + " was garbage collected, it can't be recovered")
+		return
+	}
+
+	if tree != record.Hash {
+		err = fmt.Errorf("stash %s doesn't match its recorded content, inspect it with git stash show -p %s", record.Commit, record.Commit)
+		return
+	}
+
+	if lib.stashApplied(record.Commit) {
+		lib.File.Output("Stash " + record.Commit + " already restored")
+		return
+	}
+
+	if lib.File.HasChanges() {
+		err = fmt.Errorf("local changes would conflict, restore it with git stash apply %s", record.Commit)
+		return
+	}
+
+	if err = lib.File.RunCmd("git", "stash", "apply", record.Commit); err != nil {
+		err = fmt.Errorf("unable to apply %s: %v", record.Commit, err)
+		return
+	}
+
+	lib.File.Output("Restored dropped stash " + record.Commit)
+	return true, nil
+}
+
+// stashApplied returns true if the working copy already has the changes of stash commit.
+// Mod files are ignored, they aren't restored when popping
+func (lib *Library) stashApplied(commit string) bool {
+	output, err := lib.File.CmdOutput("git", "diff", "--name-only", commit+"^1", commit)
+	if err != nil {
+		return false
+	}
+
+	args := []string{"git", "diff", "--quiet", commit, "--"}
+	for _, name := range strings.Split(output, "\n") {
+		if len(name) > 0 && name != "go.mod" && name != "go.sum" {
+			args = append(args, name)
+		}
+	}
+
+	if len(args) == 5 {
+		// Only mod files were stashed
+		return true
+	}
+
+	_, exitCode, err := lib.File.CmdResult(args...)
+	return err == nil && exitCode == 0
+}
@@ -0,0 +1,71 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// journaledStash records a stash of lib in the journal of the working directory
+func journaledStash(t *testing.T, lib *Library) {
+	record := com.StashRecord{Path: lib.File.Path, Commit: "abc123", Hash: "def456"}
+	if err := com.SaveStashRecords(StashJournalName, []com.StashRecord{record}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverListedStash(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	journaledStash(t, lib)
+	recorder.Reply("git stash list", com.Reply{Stdout: "stash@{0}\x1fother\nstash@{1}\x1fabc123\n"})
+	// No local changes to commit
+	recorder.Reply("git commit", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{Action: "recover-stash"}}
+	mu.recoverStashes()
+
+	if !recorder.Ran("git stash pop stash@{1}") || mu.Stats.RecoveredCount != 1 || len(mu.Errors) > 0 {
+		t.Fatalf("expected listed stash to be popped, ran %q (%v)", commandLines(recorder), mu.Errors)
+	}
+	if records, err := com.LoadStashRecords(StashJournalName); err != nil || len(records) > 0 {
+		t.Errorf("expected recovered stash to leave the journal, got %+v (%v)", records, err)
+	}
+}
+
+func TestRecoverDroppedStash(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	journaledStash(t, lib)
+	recorder.Reply("git rev-parse abc123^{tree}", com.Reply{Stdout: "def456\n"})
+	recorder.Reply("git diff --name-only", com.Reply{Stdout: "go.mod\nmain.go\n"})
+	// main.go differs from the stash, so it wasn't restored
+	recorder.Reply("git diff --quiet", com.Reply{ExitCode: 1})
+	recorder.Reply("git commit", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{Action: "recover-stash"}}
+	mu.recoverStashes()
+
+	if !recorder.Ran("git diff --quiet abc123 -- main.go") || !recorder.Ran("git stash apply abc123") || mu.Stats.RecoveredCount != 1 {
+		t.Errorf("expected dropped stash to be applied, ran %q", commandLines(recorder))
+	}
+}
+
+func TestRecoverStashConflicts(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	journaledStash(t, lib)
+	recorder.Reply("git stash list", com.Reply{Stdout: "stash@{0}\x1fabc123\n"})
+
+	mu := &MU{Options: Options{Action: "recover-stash"}}
+	mu.recoverStashes()
+
+	if recorder.Ran("git stash pop") || mu.Stats.RecoveredCount > 0 {
+		t.Errorf("expected stash not to be popped over local changes, ran %q", commandLines(recorder))
+	}
+	records, err := com.LoadStashRecords(StashJournalName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []com.StashRecord{{Path: lib.File.Path, Commit: "abc123", Hash: "def456"}}; !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected stash to stay in the journal to retry, got %+v", records)
+	}
+}
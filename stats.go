@@ -1,6 +1,14 @@
 package gomu
 
-import "strconv"
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
 
 // ActionStats contain stats related to the current action
 type ActionStats struct {
@@ -13,17 +21,161 @@ type ActionStats struct {
 	TagCount     int
 	TaggedOutput string
 
+	// Prereleases tagged as stable releases
+	PromoteCount   int
+	PromotedOutput string
+
+	// Libs released at a new major version
+	MajorCount  int
+	MajorOutput string
+
+	// Dep versions changed since the run diffed against
+	DiffCount  int
+	DiffOutput string
+	DiffSince  string
+	Changes    []DepChange
+
+	ConflictCount  int
+	ConflictOutput string
+	Conflicts      []VersionConflict
+
+	// Actions re-run by watch on changed libs
+	WatchCount  int
+	WatchOutput string
+
+	// Libs depending on the modules changed, listed by affected
+	AffectedCount  int
+	AffectedOutput string
+
+	// Past runs listed by history, with each action's durations and the libs failing most often
+	HistoryCount          int
+	HistoryOutput         string
+	HistoryDurationOutput string
+	HistoryFailureOutput  string
+	History               []HistoryRecord
+
+	// Orphaned stashes restored by recover-stash
+	RecoveredCount  int
+	RecoveredOutput string
+
 	CommitCount    int
 	DeployedOutput string
 
 	PRCount  int
 	PROutput string
 
+	// Pull requests whose checks were waited for
+	ChecksPassedCount int
+	ChecksFailedCount int
+	ChecksOutput      string
+
+	ReleaseCount  int
+	ReleaseOutput string
+
 	CreatedCount  int
 	CreatedOutput string
 
+	ClonedCount  int
+	ClonedOutput string
+
+	// Libs opting out of the action with skip markers
+	IgnoredCount  int
+	IgnoredOutput string
+
 	TestFailedCount  int
 	TestFailedOutput string
+
+	ExecCount        int
+	ExecOutput       string
+	ExecFailedCount  int
+	ExecFailedOutput string
+
+	TimedOutCount  int
+	TimedOutOutput string
+
+	// Total retries of network operations, and libs which retried
+	RetryCount   int
+	RetriedCount int
+	RetryOutput  string
+
+	// Api requests sent and time spent waiting on rate limits
+	RateLimit com.RateLimitStats
+
+	// Operations reversed after a failed atomic sync
+	RolledBackCount  int
+	RolledBackOutput string
+
+	VulnerableCount  int
+	VulnerableOutput string
+	Vulnerabilities  map[string]*VulnerabilityReport
+
+	OutdatedCount  int
+	OutdatedOutput string
+	Outdated       []OutdatedDep
+
+	// Moved modules found by migrate-module
+	MovedCount  int
+	MovedOutput string
+	Moves       []ModuleMove
+
+	// Deps violating the freshness policy of check
+	StaleCount  int
+	StaleOutput string
+	Stale       []StaleDep
+
+	GrepCount  int
+	GrepOutput string
+	Matches    []GrepMatch
+
+	// Workflows differing from their templates
+	Drift []WorkflowDrift
+
+	// Libs inventoried, libs requiring denied licenses, and the license of each module
+	LicenseCount  int
+	LicenseOutput string
+	DeniedCount   int
+	DeniedOutput  string
+	Licenses      map[string]*LicenseReport
+
+	// Test results of each package, and the blocks of each lib's coverage profile
+	TestPackages []PackageResult
+	CoverMode    string
+	CoverBlocks  []string
+
+	// Libs needing attention, and the status of every lib
+	StatusCount  int
+	StatusOutput string
+	Statuses     []LibraryStatus
+
+	// Errors of every lib, attributed to the step which failed
+	LibraryErrors []*com.LibraryError
+
+	// Leftover stashes, branches and worktrees found or removed by clean
+	CleanCount  int
+	CleanOutput string
+
+	// Performed by a plugin action instead of a built-in one
+	Plugin bool
+
+	Results []LibraryResult
+}
+
+// formatTests returns which libs failed tests, then totals of every package tested
+func (stats ActionStats) formatTests() (output string) {
+	if stats.TestFailedCount == 0 {
+		output += "All tests passed in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+	} else {
+		output += "Tests failed in " + strconv.Itoa(stats.TestFailedCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s) :(\n"
+		output += stats.TestFailedOutput
+	}
+
+	passed, failed, skipped := stats.testTotals()
+	output += "\n" + strconv.Itoa(passed) + " passed, " + strconv.Itoa(failed) + " failed, " + strconv.Itoa(skipped) + " skipped in " + strconv.Itoa(len(stats.TestPackages)) + " package(s)"
+	if coverage, ok := stats.Coverage(); ok {
+		output += ", " + strconv.FormatFloat(coverage, 'f', 1, 64) + "% of statements covered"
+	}
+	output += "\n"
+	return
 }
 
 type toString int
@@ -34,30 +186,274 @@ func (i toString) string() {
 
 // Format returns an formatted output string to print stat report
 func (stats ActionStats) Format() (output string) {
-	if stats.Options.Action == "list" {
+	if stats.Options.Action == "list" || stats.Options.Action == "graph" || stats.Options.Output == "json" {
 		// Already printed
 		return
 	}
 
-	branch := stats.Options.Branch
+	branch := strings.Join(stats.branches(), ", ")
 	if len(branch) == 0 {
 		branch = "Current Branch"
 	}
 
+	if stats.Options.DryRun {
+		output += "Dry run: no changes were made\n\n"
+	}
+
+	if stats.Plugin {
+		output += "Ran plugin " + stats.Options.Action + " on " + strconv.Itoa(stats.DepCount) + " lib(s)"
+		if stats.UpdateCount > 0 {
+			output += ", updated " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		} else {
+			output += "\n"
+		}
+	}
+
 	switch stats.Options.Action {
 	case "pull":
 		output += "Pulled latest version of <" + branch + "> in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
 		output += stats.UpdatedOutput
 	case "test":
-		if stats.TestFailedCount == 0 {
-			output += "All tests passed in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		output += stats.formatTests()
+	case "affected":
+		if stats.AffectedCount == 0 {
+			output += "No libs affected by changes.\n"
 		} else {
-			output += "Tests failed in " + strconv.Itoa(stats.TestFailedCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s) :(\n"
-			output += stats.TestFailedOutput
+			output += "Found " + strconv.Itoa(stats.AffectedCount) + " lib(s) affected by changes:\n"
+			output += stats.AffectedOutput
+		}
+
+		if stats.Options.AffectedTest && stats.AffectedCount > 0 {
+			output += "\n"
+			output += stats.formatTests()
+		}
+	case "exec":
+		command := "`" + stats.Options.ExecCommand + "`"
+		if stats.ExecFailedCount == 0 {
+			output += "Ran " + command + " successfully in " + strconv.Itoa(stats.ExecCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Ran " + command + " successfully in " + strconv.Itoa(stats.ExecCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s), failed in " + strconv.Itoa(stats.ExecFailedCount) + " :(\n"
+			output += stats.ExecFailedOutput
 		}
 	case "replace":
 		output += "Replaced local dependencies in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
 		output += stats.UpdatedOutput
+	case "vendor":
+		if stats.Options.VerifyVendor {
+			if stats.UpdateCount == 0 {
+				output += "All " + strconv.Itoa(stats.DepCount) + " lib vendor directories up to date!\n"
+			} else {
+				output += "Vendor directories out of date in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+				output += stats.UpdatedOutput
+			}
+		} else {
+			output += "Updated vendor directories in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+	case "tidy":
+		if stats.UpdateCount == 0 {
+			output += "Mod files already tidy in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else if stats.Options.VerifyTidy {
+			output += "Mod files not tidy in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		} else {
+			output += "Tidied mod files in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+	case "go-version":
+		if stats.UpdateCount == 0 {
+			output += "Go directives already up to date in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Set go directives in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+	case "major":
+		if stats.MajorCount == 0 {
+			output += "No new majors released in " + strconv.Itoa(stats.DepCount) + " lib(s).\n"
+		} else {
+			output += "Released new majors of " + strconv.Itoa(stats.MajorCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.MajorOutput
+		}
+
+		if stats.UpdateCount > 0 {
+			output += "\nMoved module paths in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+	case "migrate-module":
+		if stats.MovedCount == 0 {
+			output += "No moved modules found for " + strconv.Itoa(stats.DepCount) + " lib(s).\n"
+		} else {
+			output += "Found " + strconv.Itoa(stats.MovedCount) + " moved module(s):\n"
+			output += stats.MovedOutput
+
+			if stats.UpdateCount == 0 {
+				output += "\nNo libs require moved modules.\n"
+			} else {
+				output += "\nMigrated module paths in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+				output += stats.UpdatedOutput
+			}
+		}
+	case "diff":
+		if len(stats.DiffSince) == 0 {
+			output += "No previous run to compare " + strconv.Itoa(stats.DepCount) + " lib(s) with.\n"
+		} else if stats.DiffCount == 0 {
+			output += "No dependency changes in " + strconv.Itoa(stats.DepCount) + " lib(s) since " + stats.DiffSince + "\n"
+		} else {
+			output += "Dependency changes since " + stats.DiffSince + ":\n"
+			output += stats.DiffOutput
+		}
+	case "conflicts":
+		if stats.ConflictCount == 0 {
+			output += "No version conflicts between " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Third-party modules required at different versions:\n"
+			output += stats.ConflictOutput
+		}
+	case "clean":
+		if stats.CleanCount == 0 {
+			output += "Nothing to clean in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else if stats.Options.ListOnly {
+			output += "Found " + strconv.Itoa(stats.CleanCount) + " leftover stash(es), branch(es) and worktree(s):\n"
+			output += stats.CleanOutput
+		} else {
+			output += "Cleaned " + strconv.Itoa(stats.CleanCount) + " leftover stash(es), branch(es) and worktree(s):\n"
+			output += stats.CleanOutput
+		}
+	case "status":
+		if stats.StatusCount == 0 {
+			output += "All " + strconv.Itoa(stats.DepCount) + " lib(s) clean and up to date!\n"
+		} else {
+			output += "Found " + strconv.Itoa(stats.StatusCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s) needing attention:\n"
+			output += stats.StatusOutput
+		}
+		output += "\n"
+		output += stats.formatStatuses()
+	case "watch":
+		if stats.WatchCount == 0 {
+			output += "No changes in " + strconv.Itoa(stats.DepCount) + " lib(s) while watching.\n"
+		} else {
+			output += "Re-ran " + strconv.Itoa(stats.WatchCount) + " time(s) while watching:\n"
+			output += stats.WatchOutput
+		}
+	case "promote":
+		if stats.PromoteCount == 0 {
+			output += "No prereleases to promote in " + strconv.Itoa(stats.DepCount) + " lib(s).\n"
+		} else {
+			output += "Promoted prereleases to stable releases in " + strconv.Itoa(stats.PromoteCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.PromotedOutput
+		}
+	case "audit":
+		if stats.VulnerableCount == 0 {
+			output += "No vulnerabilities found in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Vulnerabilities found in " + strconv.Itoa(stats.VulnerableCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.VulnerableOutput
+			output += "\nBy dependency:\n"
+			output += stats.formatVulnerabilities()
+		}
+	case "outdated":
+		if stats.OutdatedCount == 0 {
+			output += "All deps up to date in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Outdated deps found in " + strconv.Itoa(stats.OutdatedCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.OutdatedOutput
+			output += "\n"
+			output += stats.formatOutdated()
+		}
+	case "check":
+		if stats.StaleCount == 0 {
+			output += "All deps within freshness policy in " + strconv.Itoa(stats.DepCount) + " lib(s)!\n"
+		} else {
+			output += "Deps behind freshness policy found in " + strconv.Itoa(stats.StaleCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.StaleOutput
+			output += "\n"
+			output += stats.formatStale()
+		}
+	case "secret":
+		output += "Set secrets in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+		output += stats.UpdatedOutput
+	case "workflow":
+		if stats.UpdateCount == 0 {
+			output += "All " + strconv.Itoa(stats.DepCount) + " lib(s) have up to date workflows!\n"
+		} else if stats.Options.ListOnly {
+			output += "Workflows out of date in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		} else {
+			output += "Updated workflows in " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+	case "grep":
+		if stats.GrepCount == 0 {
+			output += "No matches for " + strconv.Quote(stats.Options.Pattern) + " in " + strconv.Itoa(stats.DepCount) + " lib(s)\n"
+		} else {
+			output += "Matches for " + strconv.Quote(stats.Options.Pattern) + " found in " + strconv.Itoa(stats.GrepCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.GrepOutput
+			output += stats.formatMatches()
+		}
+	case "licenses":
+		output += "Inventoried dependency licenses of " + strconv.Itoa(stats.LicenseCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+		output += stats.LicenseOutput
+		output += "\n"
+		output += stats.formatLicenses()
+		if stats.DeniedCount > 0 {
+			output += "\nDenied licenses found in " + strconv.Itoa(stats.DeniedCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.DeniedOutput
+		}
+	case "plan":
+		if stats.UpdateCount == 0 {
+			output += "No changes planned for " + strconv.Itoa(stats.DepCount) + " lib(s).\n"
+		} else {
+			output += "Planned changes for " + strconv.Itoa(stats.UpdateCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+			output += stats.UpdatedOutput
+		}
+
+		planFile := stats.Options.PlanFile
+		if len(planFile) == 0 {
+			planFile = PlanName
+		}
+		output += "\nPlan written to " + planFile + ". Review, then run apply to make these changes.\n"
+
+		// Nothing else was done
+		return
+	case "init":
+		if stats.UpdateCount == 0 {
+			output += "No module initialized.\n"
+		} else {
+			output += "Initialized module:\n"
+			output += stats.UpdatedOutput
+		}
+
+		// Nothing else was done
+		return
+	case "history":
+		if stats.HistoryCount == 0 {
+			output += "No past runs recorded.\n"
+		} else {
+			output += "Last " + strconv.Itoa(stats.HistoryCount) + " run(s):\n"
+			output += stats.HistoryOutput
+			output += "\nDurations by action:\n"
+			output += stats.HistoryDurationOutput
+		}
+
+		if len(stats.HistoryFailureOutput) > 0 {
+			output += "\nMost failing lib(s):\n"
+			output += stats.HistoryFailureOutput
+		}
+	case "recover-stash":
+		if stats.RecoveredCount == 0 {
+			output += "No stashes were recovered.\n"
+		} else {
+			output += "Recovered " + strconv.Itoa(stats.RecoveredCount) + " stash(es):\n"
+			output += stats.RecoveredOutput
+		}
+	case "undo":
+		if stats.UpdateCount == 0 {
+			output += "Nothing was undone.\n"
+		} else {
+			output += "Undid " + strconv.Itoa(stats.UpdateCount) + " operation(s):\n"
+			output += stats.UpdatedOutput
+		}
 	case "reset":
 		output += "Reset mod files in " + strconv.Itoa(stats.DepCount) + " lib(s)\n"
 		// TODO: Count libs with changes here?
@@ -87,6 +483,12 @@ func (stats ActionStats) Format() (output string) {
 		}
 	}
 
+	if stats.Options.Tag && (stats.Options.Changelog || stats.Options.CreateRelease) && stats.ReleaseCount > 0 {
+		output += "\n"
+		output += "Published release notes for " + strconv.Itoa(stats.ReleaseCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+		output += stats.ReleaseOutput
+	}
+
 	if stats.Options.Commit {
 		// Print commit status
 		output += "\n"
@@ -104,6 +506,42 @@ func (stats ActionStats) Format() (output string) {
 		output += stats.CreatedOutput
 	}
 
+	if stats.ClonedCount > 0 {
+		output += "\n"
+		output += "Cloned " + strconv.Itoa(stats.ClonedCount) + " missing dep(s):\n"
+		output += stats.ClonedOutput
+	}
+
+	if stats.IgnoredCount > 0 {
+		output += "\n"
+		output += "Skipped " + strconv.Itoa(stats.IgnoredCount) + " lib(s) opting out of " + stats.Options.Action + ":\n"
+		output += stats.IgnoredOutput
+	}
+
+	if stats.TimedOutCount > 0 {
+		output += "\n"
+		output += "Timed out in " + strconv.Itoa(stats.TimedOutCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+		output += stats.TimedOutOutput
+	}
+
+	if stats.RetryCount > 0 {
+		output += "\n"
+		output += "Retried network operations " + strconv.Itoa(stats.RetryCount) + " time(s) in " + strconv.Itoa(stats.RetriedCount) + "/" + strconv.Itoa(stats.DepCount) + " lib(s):\n"
+		output += stats.RetryOutput
+	}
+
+	if stats.RateLimit.Waits > 0 || stats.RateLimit.Backoffs > 0 {
+		output += "\n"
+		output += "Waited " + stats.RateLimit.Waited.Round(time.Second).String() + " on api rate limits across " + strconv.Itoa(stats.RateLimit.Requests) + " request(s): "
+		output += strconv.Itoa(stats.RateLimit.Waits) + " wait(s), " + strconv.Itoa(stats.RateLimit.Backoffs) + " retried after being limited\n"
+	}
+
+	if stats.RolledBackCount > 0 {
+		output += "\n"
+		output += "Rolled back " + strconv.Itoa(stats.RolledBackCount) + " operation(s) after a lib failed to sync:\n"
+		output += stats.RolledBackOutput
+	}
+
 	if stats.Options.PullRequest {
 		// Print pr status
 		output += "\n"
@@ -115,5 +553,252 @@ func (stats ActionStats) Format() (output string) {
 		}
 	}
 
+	if stats.ChecksPassedCount > 0 || stats.ChecksFailedCount > 0 {
+		output += "\n"
+		output += "Checks passed on " + strconv.Itoa(stats.ChecksPassedCount) + "/" + strconv.Itoa(stats.ChecksPassedCount+stats.ChecksFailedCount) + " pull request(s):\n"
+		output += stats.ChecksOutput
+	}
+
 	return
 }
+
+// branches returns each branch libs were synced on, or the branch option if no lib recorded one and it isn't a
+// template rendered for each lib
+func (stats ActionStats) branches() (branches []string) {
+	seen := make(map[string]bool)
+	for _, result := range stats.Results {
+		if len(result.Branch) > 0 && !seen[result.Branch] {
+			seen[result.Branch] = true
+			branches = append(branches, result.Branch)
+		}
+	}
+
+	if len(branches) == 0 && len(stats.Options.Branch) > 0 && !strings.Contains(stats.Options.Branch, "{{") {
+		branches = append(branches, stats.Options.Branch)
+	}
+
+	return
+}
+
+// LibraryResult represents the outcome of an action for a single library
+type LibraryResult struct {
+	Library string `json:"library"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind"` // Package manifest kind, e.g. go or npm
+
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	Commit          string `json:"commit,omitempty"`
+	Branch          string `json:"branch,omitempty"`
+	ProtectedBranch string `json:"protectedBranch,omitempty"`
+
+	Updated       bool `json:"updated"`
+	Tagged        bool `json:"tagged"`
+	TagSigned     bool `json:"tagSigned"`
+	Committed     bool `json:"committed"`
+	CommitSigned  bool `json:"commitSigned"`
+	PROpened      bool `json:"prOpened"`
+	BranchCreated bool `json:"branchCreated"`
+	TestFailed    bool `json:"testFailed"`
+	TimedOut      bool `json:"timedOut"`
+	Retries       int  `json:"retries,omitempty"`
+
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// ExitCode of the exec command, if run
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	PRURL           string   `json:"prURL,omitempty"`
+	ReleaseURL      string   `json:"releaseURL,omitempty"`
+	UpdatedDeps     []string `json:"updatedDeps,omitempty"`
+	Vulnerabilities []string `json:"vulnerabilities,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+
+	Failures []*com.LibraryError `json:"failures,omitempty"`
+}
+
+// Summary represents a machine-readable report of an action
+type Summary struct {
+	Action string `json:"action"`
+	Branch string `json:"branch,omitempty"`
+	DryRun bool   `json:"dryRun,omitempty"`
+
+	DepCount        int `json:"depCount"`
+	UpdateCount     int `json:"updateCount"`
+	TagCount        int `json:"tagCount"`
+	CommitCount     int `json:"commitCount"`
+	PRCount         int `json:"prCount"`
+	ChecksPassed    int `json:"checksPassed"`
+	ChecksFailed    int `json:"checksFailed"`
+	CreatedCount    int `json:"createdCount"`
+	TestFailedCount int `json:"testFailedCount"`
+	ExecFailedCount int `json:"execFailedCount"`
+	TimedOutCount   int `json:"timedOutCount"`
+	RetryCount      int `json:"retryCount"`
+	RolledBackCount int `json:"rolledBackCount"`
+
+	// Api requests and rate limit waits, if any requests were sent
+	RateLimit *com.RateLimitStats `json:"rateLimit,omitempty"`
+
+	// Percent of statements covered by tests across all libs
+	Coverage *float64 `json:"coverage,omitempty"`
+
+	Libraries       []LibraryResult       `json:"libraries"`
+	Vulnerabilities []VulnerabilityReport `json:"vulnerabilities,omitempty"`
+	Outdated        []OutdatedDep         `json:"outdated,omitempty"`
+	Stale           []StaleDep            `json:"stale,omitempty"`
+	Moves           []ModuleMove          `json:"moves,omitempty"`
+	Matches         []GrepMatch           `json:"matches,omitempty"`
+	Drift           []WorkflowDrift       `json:"drift,omitempty"`
+	Licenses        []LicenseReport       `json:"licenses,omitempty"`
+	Statuses        []LibraryStatus       `json:"statuses,omitempty"`
+	TestPackages    []PackageResult       `json:"testPackages,omitempty"`
+	History         []HistoryRecord       `json:"history,omitempty"`
+	Changes         []DepChange           `json:"changes,omitempty"`
+	Conflicts       []VersionConflict     `json:"conflicts,omitempty"`
+	Errors          []string              `json:"errors,omitempty"`
+
+	LibraryErrors []*com.LibraryError `json:"libraryErrors,omitempty"`
+	ExitCode      int                 `json:"exitCode"`
+}
+
+// collectResults aggregates status of each file in the sorted list
+func (stats *ActionStats) collectResults(listHead *sort.FileNode) {
+	stats.Results = stats.Results[:0]
+	stats.TimedOutCount = 0
+	stats.TimedOutOutput = ""
+	stats.RetryCount = 0
+	stats.RetriedCount = 0
+	stats.RetryOutput = ""
+	stats.LibraryErrors = stats.LibraryErrors[:0]
+	stats.RateLimit = com.GetRateLimitStats()
+	for itr := listHead; itr != nil; itr = itr.Next {
+		file := itr.File
+		if file.TimedOut {
+			stats.TimedOutCount++
+			stats.TimedOutOutput += strconv.Itoa(stats.TimedOutCount) + ") " + file.OriginalPath() + "\n"
+		}
+
+		if file.Retries > 0 {
+			stats.RetryCount += file.Retries
+			stats.RetriedCount++
+			stats.RetryOutput += strconv.Itoa(stats.RetriedCount) + ") " + file.OriginalPath() + " (" + strconv.Itoa(file.Retries) + " retries)\n"
+		}
+
+		stats.Results = append(stats.Results, libraryResult(file))
+		stats.LibraryErrors = append(stats.LibraryErrors, file.Failures...)
+	}
+}
+
+// libraryResult returns the outcome of the action for file
+func libraryResult(file *com.FileWrapper) LibraryResult {
+	var exitCode *int
+	if file.Executed {
+		exitCode = &file.ExitCode
+	}
+
+	return LibraryResult{
+		Library: file.GetGoURL(),
+		Path:    file.OriginalPath(),
+		Version: file.Version,
+		Kind:    file.Manifest().Kind,
+
+		PreviousVersion: file.PreviousVersion,
+		Commit:          file.CommitSHA,
+		Branch:          file.Branch,
+		ProtectedBranch: file.ProtectedBranch,
+
+		Updated:       file.Updated,
+		Tagged:        file.Tagged,
+		TagSigned:     file.TagSigned,
+		Committed:     file.Committed,
+		CommitSigned:  file.CommitSigned,
+		PROpened:      file.PROpened,
+		BranchCreated: file.BranchCreated,
+		TestFailed:    file.TestFailed,
+		TimedOut:      file.TimedOut,
+		Retries:       file.Retries,
+		Duration:      file.Duration,
+		ExitCode:      exitCode,
+
+		PRURL:           file.PRURL,
+		ReleaseURL:      file.ReleaseURL,
+		UpdatedDeps:     file.UpdatedDeps,
+		Vulnerabilities: file.Vulnerabilities,
+		Errors:          file.Errors,
+
+		Failures: file.Failures,
+	}
+}
+
+// Summary returns a machine-readable report of the stats and provided errors
+func (stats ActionStats) Summary(errs []error) (summary Summary) {
+	summary.Action = stats.Options.Action
+	if branches := stats.branches(); len(branches) == 1 {
+		// Otherwise each lib's result has its branch
+		summary.Branch = branches[0]
+	}
+	summary.DryRun = stats.Options.DryRun
+
+	summary.DepCount = stats.DepCount
+	summary.UpdateCount = stats.UpdateCount
+	summary.TagCount = stats.TagCount
+	summary.CommitCount = stats.CommitCount
+	summary.PRCount = stats.PRCount
+	summary.ChecksPassed = stats.ChecksPassedCount
+	summary.ChecksFailed = stats.ChecksFailedCount
+	summary.CreatedCount = stats.CreatedCount
+	summary.TestFailedCount = stats.TestFailedCount
+	summary.ExecFailedCount = stats.ExecFailedCount
+	summary.RolledBackCount = stats.RolledBackCount
+	summary.TimedOutCount = stats.TimedOutCount
+	summary.RetryCount = stats.RetryCount
+	if stats.RateLimit.Requests > 0 {
+		rateLimit := stats.RateLimit
+		summary.RateLimit = &rateLimit
+	}
+
+	summary.Libraries = stats.Results
+	if summary.Libraries == nil {
+		summary.Libraries = []LibraryResult{}
+	}
+
+	for _, report := range stats.Vulnerabilities {
+		summary.Vulnerabilities = append(summary.Vulnerabilities, *report)
+	}
+
+	summary.Outdated = stats.Outdated
+	summary.Stale = stats.Stale
+	summary.Moves = stats.Moves
+	summary.Matches = stats.Matches
+	summary.Drift = stats.Drift
+	summary.Licenses = stats.licenseReports()
+	summary.Statuses = stats.Statuses
+
+	summary.TestPackages = stats.TestPackages
+	summary.History = stats.History
+	summary.Changes = stats.Changes
+	summary.Conflicts = stats.Conflicts
+	if coverage, ok := stats.Coverage(); ok {
+		summary.Coverage = &coverage
+	}
+
+	for _, err := range errs {
+		summary.Errors = append(summary.Errors, err.Error())
+	}
+
+	summary.LibraryErrors = stats.LibraryErrors
+	summary.ExitCode = stats.exitCode(errs)
+
+	return
+}
+
+// JSON returns the summary of the stats as an indented json string
+func (stats ActionStats) JSON(errs []error) (output string, err error) {
+	data, err := json.MarshalIndent(stats.Summary(errs), "", "  ")
+	if err != nil {
+		return
+	}
+
+	return string(data), nil
+}
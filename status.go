@@ -0,0 +1,203 @@
+package gomu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// LibraryStatus represents the state of a library's working copy and mod files
+type LibraryStatus struct {
+	Library string `json:"library"`
+	Path    string `json:"path"`
+
+	// Branch is empty if HEAD is detached
+	Branch string `json:"branch,omitempty"`
+	Dirty  bool   `json:"dirty"`
+
+	// Commits ahead of and behind the upstream branch as of the last fetch, nil if there's no upstream
+	Ahead  *int `json:"ahead,omitempty"`
+	Behind *int `json:"behind,omitempty"`
+
+	LatestTag string `json:"latestTag,omitempty"`
+
+	// Drift lists sibling libs required at older versions than their latest tag
+	Drift []ModDrift `json:"drift,omitempty"`
+}
+
+// ModDrift represents a sibling lib required at an older version than its latest tag
+type ModDrift struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Latest  string `json:"latest"`
+}
+
+// NeedsAttention is true if the library has local changes, diverged from its upstream or requires outdated siblings
+func (status LibraryStatus) NeedsAttention() bool {
+	return status.Dirty || (status.Ahead != nil && *status.Ahead > 0) || (status.Behind != nil && *status.Behind > 0) || len(status.Drift) > 0
+}
+
+// Status reports lib's branch, local changes, upstream divergence and latest tag without changing anything.
+// Sibling libs found in the list starting at fileHead are checked for drift
+func (lib *Library) Status(fileHead *sort.FileNode) (status LibraryStatus, err error) {
+	status.Library = lib.File.GetGoURL()
+	status.Path = lib.File.OriginalPath()
+
+	if status.Branch, err = lib.File.CurrentBranch(); err != nil {
+		err = fmt.Errorf("unable to get current branch: %v", err)
+		return
+	}
+
+	status.Dirty = lib.File.HasChangesIn(".")
+
+	if ahead, behind, upstreamErr := lib.File.AheadBehind(); upstreamErr == nil {
+		status.Ahead = &ahead
+		status.Behind = &behind
+	}
+
+	status.LatestTag = lib.GetLatestTag()
+	status.Drift, err = lib.drift(fileHead)
+	return
+}
+
+// drift returns sibling libs lib's go.mod requires at older versions than their latest tag
+func (lib *Library) drift(fileHead *sort.FileNode) (drift []ModDrift, err error) {
+	output, err := lib.File.CmdOutput(lib.File.GoBinary(), "mod", "edit", "-json")
+	if err != nil {
+		err = fmt.Errorf("unable to read go.mod: %v", err)
+		return
+	}
+
+	var modFile struct {
+		Require []goModRequire `json:"Require"`
+	}
+	if err = json.Unmarshal([]byte(output), &modFile); err != nil {
+		err = fmt.Errorf("unable to parse go.mod: %v", err)
+		return
+	}
+
+	for _, req := range modFile.Require {
+		node := fileHead.Find(req.Path)
+		if node == nil || node.File == lib.File {
+			// Not a sibling
+			continue
+		}
+
+		sibling := Library{File: node.File}
+		if latest := sibling.GetLatestTag(); semverLess(req.Version, latest) {
+			drift = append(drift, ModDrift{Module: req.Path, Version: req.Version, Latest: latest})
+		}
+	}
+
+	return
+}
+
+// semverLess is true if version is an older release than latest. Pre-releases of the same release are equal
+func semverLess(version, latest string) bool {
+	current, ok := parseSemver(version)
+	if !ok {
+		return false
+	}
+
+	next, ok := parseSemver(latest)
+	if !ok {
+		return false
+	}
+
+	for i := range current {
+		if current[i] != next[i] {
+			return current[i] < next[i]
+		}
+	}
+
+	return false
+}
+
+func (mu *MU) status(lib Library, fileHead *sort.FileNode) {
+	lib.File.Output("Checking status...")
+
+	status, err := lib.Status(fileHead)
+	if err != nil {
+		lib.File.Error("Status check failed :( " + err.Error())
+		return
+	}
+
+	mu.statsMux.Lock()
+	mu.Stats.Statuses = append(mu.Stats.Statuses, status)
+	mu.statsMux.Unlock()
+
+	if status.NeedsAttention() {
+		mu.addStat(&mu.Stats.StatusCount, &mu.Stats.StatusOutput, status.Library+" ("+status.summary()+")\n")
+	}
+}
+
+// summary describes what needs attention, e.g. "dirty, 2 behind, 1 drifted dep"
+func (status LibraryStatus) summary() string {
+	var parts []string
+	if status.Dirty {
+		parts = append(parts, "dirty")
+	}
+	if status.Ahead != nil && *status.Ahead > 0 {
+		parts = append(parts, strconv.Itoa(*status.Ahead)+" ahead")
+	}
+	if status.Behind != nil && *status.Behind > 0 {
+		parts = append(parts, strconv.Itoa(*status.Behind)+" behind")
+	}
+	if len(status.Drift) > 0 {
+		parts = append(parts, strconv.Itoa(len(status.Drift))+" drifted dep(s)")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatStatuses returns a table of each lib's status, sorted by library
+func (stats ActionStats) formatStatuses() string {
+	statuses := append([]LibraryStatus(nil), stats.Statuses...)
+	gosort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Library < statuses[j].Library
+	})
+
+	var output bytes.Buffer
+	writer := tabwriter.NewWriter(&output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "LIBRARY\tBRANCH\tSTATE\tAHEAD/BEHIND\tTAG\tDRIFT")
+	for _, status := range statuses {
+		branch := status.Branch
+		if len(branch) == 0 {
+			branch = "(detached)"
+		}
+
+		state := "clean"
+		if status.Dirty {
+			state = "dirty"
+		}
+
+		divergence := "-"
+		if status.Ahead != nil && status.Behind != nil {
+			divergence = strconv.Itoa(*status.Ahead) + "/" + strconv.Itoa(*status.Behind)
+		}
+
+		tag := status.LatestTag
+		if len(tag) == 0 {
+			tag = "-"
+		}
+
+		drift := make([]string, 0, len(status.Drift))
+		for _, dep := range status.Drift {
+			drift = append(drift, dep.Module+" "+dep.Version+" -> "+dep.Latest)
+		}
+		if len(drift) == 0 {
+			drift = append(drift, "-")
+		}
+
+		fmt.Fprintln(writer, status.Library+"\t"+branch+"\t"+state+"\t"+divergence+"\t"+tag+"\t"+strings.Join(drift, ", "))
+	}
+	writer.Flush()
+
+	return output.String()
+}
@@ -0,0 +1,78 @@
+package gomu
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestStatus(t *testing.T) {
+	sibling, siblingRecorder := recordedLibrary(t)
+	siblingRecorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.3.0\n"})
+
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git branch --show-current", com.Reply{Stdout: "master\n"})
+	recorder.Reply("git status --porcelain", com.Reply{Stdout: " M go.mod\n"})
+	recorder.Reply("git rev-list --left-right --count", com.Reply{Stdout: "0\t2\n"})
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v0.4.0\n"})
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{"Require": [
+		{"Path": "` + sibling.File.GetGoURL() + `", "Version": "v1.2.0"},
+		{"Path": "github.com/other/yaml", "Version": "v1.0.0"}
+	]}`})
+
+	fileHead := &sort.FileNode{File: sibling.File}
+	fileHead.Next = &sort.FileNode{File: lib.File, Last: fileHead}
+	status, err := lib.Status(fileHead)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Branch != "master" || !status.Dirty || *status.Ahead != 0 || *status.Behind != 2 || status.LatestTag != "v0.4.0" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if len(status.Drift) != 1 || status.Drift[0] != (ModDrift{Module: sibling.File.GetGoURL(), Version: "v1.2.0", Latest: "v1.3.0"}) {
+		t.Errorf("expected sibling to have drifted, got %+v", status.Drift)
+	}
+	if !status.NeedsAttention() || status.summary() != "dirty, 2 behind, 1 drifted dep(s)" {
+		t.Errorf("expected status to need attention, got %q", status.summary())
+	}
+}
+
+func TestStatusNoUpstream(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-list --left-right --count", com.Reply{ExitCode: 128})
+	recorder.Reply("go mod edit -json", com.Reply{Stdout: `{}`})
+
+	status, err := lib.Status(&sort.FileNode{File: lib.File})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Ahead != nil || status.Behind != nil || status.NeedsAttention() {
+		t.Errorf("expected clean lib without an upstream, got %+v", status)
+	}
+
+	table := ActionStats{Statuses: []LibraryStatus{status}}.formatStatuses()
+	if lines := strings.Split(strings.TrimSpace(table), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "(detached)  clean  -") {
+		t.Errorf("expected detached clean lib without divergence, got:\n%s", table)
+	}
+}
+
+func TestSemverLess(t *testing.T) {
+	for _, test := range []struct {
+		version, latest string
+		less            bool
+	}{
+		{"v1.2.0", "v1.3.0", true},
+		{"v1.10.0", "v1.9.0", false},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.2.0-rc.1", "v1.2.0", false},
+		{"v0.0.0-20200101000000-abcdef123456", "v0.1.0", true},
+		{"v1.2.0", "", false},
+	} {
+		if less := semverLess(test.version, test.latest); less != test.less {
+			t.Errorf("expected %s < %s to be %v", test.version, test.latest, test.less)
+		}
+	}
+}
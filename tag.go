@@ -1,11 +1,19 @@
 package gomu
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
 )
 
 // TagLib updates the lib to the provided tag, or increments if git-tagger is able to
 func (lib *Library) TagLib(tag string) (newTag string) {
+	if lib.prefixed() {
+		// git-tagger is unaware of prefixes and only increments semver
+		return lib.tagPrefixed(tag)
+	}
+
 	if len(tag) == 0 {
 		lib.File.Output("Updating tag...")
 
@@ -27,8 +35,8 @@ func (lib *Library) TagLib(tag string) (newTag string) {
 			return
 		}
 
-		// Push new tag
-		if lib.File.RunCmd("git", "push", "--tag") != nil {
+		// Push only the new tag, to the remote pushes go to
+		if lib.File.RunCmdWithRetry("git", "push", com.PushRemote(), tag) != nil {
 			lib.File.Output("Unable to push tag.")
 			return
 		}
@@ -40,16 +48,222 @@ func (lib *Library) TagLib(tag string) (newTag string) {
 	return
 }
 
+// signingKey returns key if provided, otherwise the configured user.signingkey. Kind is what's being signed
+func (lib *Library) signingKey(kind, key string) (string, error) {
+	if len(key) > 0 {
+		return key, nil
+	}
+
+	key, err := lib.File.CmdOutput("git", "config", "user.signingkey")
+	if key = strings.TrimSpace(key); err != nil || len(key) == 0 {
+		return "", fmt.Errorf("%s signing requested but no signing key is configured (set user.signingkey or provide a key)", kind)
+	}
+
+	return key, nil
+}
+
+// SignTag creates and pushes a signed annotated tag, or increments the latest tag's patch version if tag is empty.
+// Uses key if provided, otherwise the configured user.signingkey
+func (lib *Library) SignTag(tag, key string) (newTag string, err error) {
+	if key, err = lib.signingKey("tag", key); err != nil {
+		return
+	}
+
+	if len(tag) == 0 {
+		latestTag := lib.GetLatestTag()
+		if tag = incrementPatch(latestTag); len(tag) == 0 {
+			err = fmt.Errorf("unable to increment tag %q", latestTag)
+			return
+		}
+	}
+
+	name := lib.TagName(tag)
+	lib.File.Output("Signing tag " + name + "...")
+
+	if err = lib.File.RunCmd("git", "tag", "-s", "-u", key, "-m", name, name); err != nil {
+		err = fmt.Errorf("unable to sign tag %s: %v", name, err)
+		return
+	}
+
+	// Push new tag
+	if err = lib.File.RunCmdWithRetry("git", "push", com.PushRemote(), name); err != nil {
+		err = fmt.Errorf("unable to push tag %s", name)
+		return
+	}
+
+	newTag = tag
+	lib.File.Output("Signed Tag - " + name)
+	return
+}
+
+// prefixed returns true if lib's tags are prefixed or versioned with a custom scheme, rather than managed by git-tagger
+func (lib *Library) prefixed() bool {
+	return len(lib.File.ModuleDir()) > 0 || com.CustomVersioning()
+}
+
+// tagPrefixed tags a nested module or a lib with custom versioning, or increments its latest tag if version is empty.
+// Tags are prefixed with the module directory (services/a/v1.2.3) per go's module rules, then the tag prefix.
+// Returns the unprefixed version
+func (lib *Library) tagPrefixed(version string) (newVersion string) {
+	if len(version) == 0 {
+		lib.File.Output("Updating tag...")
+
+		if version = incrementPatch(lib.GetLatestTag()); len(version) == 0 {
+			lib.File.Output("Unable to increment tag.")
+			return
+		}
+	} else {
+		lib.File.Output("Setting tag...")
+	}
+
+	name := lib.TagName(version)
+	if lib.File.RunCmd("git", "tag", name) != nil {
+		lib.File.Output("Unable to set tag.")
+		return
+	}
+
+	// Push new tag
+	if lib.File.RunCmdWithRetry("git", "push", com.PushRemote(), name) != nil {
+		lib.File.Output("Unable to push tag.")
+		return
+	}
+
+	newVersion = version
+	lib.File.Output("Set Tag - " + name)
+	return
+}
+
+// TagName returns the git tag for version, prefixed with the module directory for nested modules (services/a/v1.2.3)
+// and the tag prefix (release/v1.2.3)
+func (lib *Library) TagName(version string) string {
+	if dir := lib.File.ModuleDir(); len(dir) > 0 {
+		return dir + "/" + com.TagPrefix() + version
+	}
+
+	return com.TagPrefix() + version
+}
+
+// ModRef returns the ref go resolves lib's version with. Prefixed tags aren't module versions, so their tag is used
+func (lib *Library) ModRef(version string) string {
+	if len(com.TagPrefix()) > 0 {
+		return lib.TagName(version)
+	}
+
+	return version
+}
+
+// versionTags returns the versions tagged for lib, newest first. Prereleases sort before their release
+func (lib *Library) versionTags() (versions []string, err error) {
+	prefix := lib.TagName("")
+	dir := strings.TrimSuffix(prefix, com.TagPrefix())
+
+	output, err := lib.File.CmdOutput("git", "-c", "versionsort.suffix=-", "tag", "--list", prefix+"*", "--sort=-v:refname")
+	if err != nil {
+		return
+	}
+
+	for _, tag := range strings.Split(output, "\n") {
+		if version, ok := com.ParseTag(strings.TrimPrefix(tag, dir)); ok {
+			versions = append(versions, version)
+		}
+	}
+
+	return
+}
+
+// latestPrefixedTag returns the latest version tagged for a nested module or with the tag prefix, without prefixes.
+// Prereleases are only considered when releasing on a prerelease channel
+func (lib *Library) latestPrefixedTag() (version string, err error) {
+	versions, err := lib.versionTags()
+	if err != nil {
+		return
+	}
+
+	for _, tagged := range versions {
+		if _, channel, _ := com.SplitPrerelease(tagged); len(channel) == 0 || len(com.Prerelease()) > 0 {
+			return tagged, nil
+		}
+	}
+
+	err = fmt.Errorf("no version tags found for %s", lib.TagName(""))
+	return
+}
+
+// Version bump strategies
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+	// BumpAuto derives the bump from conventional commits since the latest tag
+	BumpAuto = "auto"
+)
+
+// incrementPatch returns tag with its patch version incremented (v1.2.3 -> v1.2.4)
+func incrementPatch(tag string) string {
+	return incrementVersion(tag, BumpPatch)
+}
+
+// incrementVersion returns tag with its major, minor or patch version incremented per the version scheme,
+// resetting lower versions (v1.2.3 -> v1.3.0). Returns an empty version if tag can't be incremented
+func incrementVersion(tag, bump string) string {
+	if len(tag) == 0 {
+		return ""
+	}
+
+	version, err := com.NextVersion(tag, bump)
+	if err != nil {
+		return ""
+	}
+
+	return version
+}
+
+// AutoBump returns the bump implied by conventional commits since the latest tag:
+// major for breaking changes (minor before v1), minor for features, otherwise patch
+func (lib *Library) AutoBump(latest string) (bump string, err error) {
+	entries, err := lib.Changes()
+	if err != nil {
+		return
+	}
+
+	bump = BumpPatch
+	for _, entry := range entries {
+		if entry.Breaking {
+			if strings.HasPrefix(latest, "v0.") {
+				// Breaking changes are expected before v1
+				bump = BumpMinor
+				continue
+			}
+
+			return BumpMajor, nil
+		}
+
+		if entry.Kind == "feat" {
+			bump = BumpMinor
+		}
+	}
+
+	return
+}
+
 // ShouldTag returns true if not a plugin and has a tag that is out of date
 func (lib *Library) ShouldTag() (shouldTag bool) {
 	// Check if tag is up to date
-	stdout, err := lib.File.CmdOutput("git-tagger", "--action=get")
+	var stdout, tag string
+	var err error
+	nested := len(lib.File.ModuleDir()) > 0
+	if lib.prefixed() {
+		tag, err = lib.latestPrefixedTag()
+		tag = lib.TagName(tag)
+	} else {
+		stdout, err = lib.File.CmdOutput("git-tagger", "--action=get")
+		tag = strings.TrimSpace(string(stdout))
+	}
 	if err != nil {
 		// No tag set. skip tag
 		lib.File.Output("No tag set. Skipping tag.")
 		return
 	}
-	tag := strings.TrimSpace(string(stdout))
 
 	stdout, err = lib.File.CmdOutput("git", "rev-list", "-n", "1", tag)
 	if err != nil {
@@ -67,6 +281,14 @@ func (lib *Library) ShouldTag() (shouldTag bool) {
 	}
 	headCommit := string(stdout)
 
+	if tagCommit != headCommit && nested {
+		// Other modules in the repo may have changed, only check the module directory
+		if changes, err := lib.File.CmdOutput("git", "diff", "--name-only", tag, "HEAD", "--", "."); err == nil && len(changes) == 0 {
+			lib.File.Output("Tag up to date @ " + tag + "!")
+			return
+		}
+	}
+
 	if tagCommit != headCommit {
 		// Tag out of date
 		lib.File.Output("Tag outdated...")
@@ -81,6 +303,14 @@ func (lib *Library) ShouldTag() (shouldTag bool) {
 // TODO: create GetLatestTag for this functinoality
 // TODO: use git-tagger --action=current to return current tag rather than latest tag
 func (lib *Library) GetLatestTag() (currentTag string) {
+	if lib.prefixed() {
+		var err error
+		if currentTag, err = lib.latestPrefixedTag(); err != nil {
+			lib.File.Output("Unable to fetch tag.")
+		}
+		return
+	}
+
 	output, err := lib.File.CmdOutput("git-tagger", "--action=get")
 	if err != nil {
 		// No tag set. skip tag
@@ -90,3 +320,25 @@ func (lib *Library) GetLatestTag() (currentTag string) {
 
 	return output
 }
+
+// LatestVersion returns the newer of lib's latest tag and the latest version known to module proxies, so deps are
+// set to released versions even if the local clone is behind. Custom versioning is only read from tags
+func (lib *Library) LatestVersion() (version string) {
+	version = lib.GetLatestTag()
+	if com.CustomVersioning() {
+		return
+	}
+
+	latest, err := com.ProxyLatest(lib.File.GetGoURL())
+	if err != nil {
+		lib.File.Debug("Not resolved from module proxy: " + err.Error())
+		return
+	}
+
+	if len(version) == 0 || semverLess(version, latest) {
+		lib.File.Output("Module proxy has " + latest + ", newer than local tag " + orNone(version))
+		version = latest
+	}
+
+	return
+}
@@ -0,0 +1,77 @@
+package gomu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How syncs handle deps whose tags aren't signed by a trusted key
+const (
+	// OnUnverifiedSkip leaves the dep at its current version
+	OnUnverifiedSkip = "skip"
+	// OnUnverifiedWarn sets the dep anyway, with a warning
+	OnUnverifiedWarn = "warn"
+)
+
+// TagTrust represents the keys trusted to sign the tags of deps set by a sync
+type TagTrust struct {
+	// GPG fingerprints or key ids. Any valid signature is trusted if empty
+	Keys []string
+	// Set deps whose tags aren't trusted anyway, warning
+	Warn bool
+}
+
+// tagTrust returns the tag trust of syncs, nil if tags aren't verified
+func (mu *MU) tagTrust() *TagTrust {
+	if !mu.Options.VerifyTags {
+		return nil
+	}
+
+	return &TagTrust{Keys: mu.Options.TrustedKeys, Warn: mu.Options.OnUnverifiedTag == OnUnverifiedWarn}
+}
+
+// trusts returns true if any of keys (fingerprints or key ids of a signature) is trusted
+func (trust *TagTrust) trusts(keys []string) bool {
+	if len(trust.Keys) == 0 {
+		return len(keys) > 0
+	}
+
+	for _, trusted := range trust.Keys {
+		// Key ids are the fingerprint's suffix
+		trusted = strings.ToUpper(strings.TrimPrefix(strings.Replace(trusted, " ", "", -1), "0x"))
+		for _, key := range keys {
+			if len(trusted) > 0 && strings.HasSuffix(strings.ToUpper(key), trusted) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// trustedTag returns true if dep may be set at its version, verifying the tag's signature if required.
+// Tags created this run are trusted
+func (lib *Library) trustedTag(dep Library) bool {
+	if lib.trust == nil || dep.File.Tagged {
+		return true
+	}
+
+	tag := dep.TagName(dep.File.Version)
+	keys, err := dep.File.VerifyTag(tag)
+	if err == nil && !lib.trust.trusts(keys) {
+		err = fmt.Errorf("tag %s is signed by untrusted key %s", tag, keys[0])
+	}
+
+	if err == nil {
+		return true
+	}
+
+	url := dep.File.GetGoURL()
+	if lib.trust.Warn {
+		lib.File.Output("Warning - " + url + " " + err.Error() + ", setting @ " + dep.File.Version + " anyway")
+		return true
+	}
+
+	lib.File.Output("Warning - " + url + " " + err.Error() + ", skipping update to " + dep.File.Version)
+	return false
+}
@@ -0,0 +1,55 @@
+package gomu
+
+import (
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestTrusts(t *testing.T) {
+	keys := []string{"89ABCDEF01234567", "0123456789ABCDEF0123456789ABCDEF01234567"}
+	for _, test := range []struct {
+		trusted  []string
+		keys     []string
+		expected bool
+	}{
+		{nil, keys, true},
+		{nil, nil, false},
+		{[]string{"0x89abcdef01234567"}, keys, true},
+		{[]string{"0123 4567 89AB CDEF 0123  4567 89AB CDEF 0123 4567"}, keys, true},
+		{[]string{"FEDCBA9876543210"}, keys, false},
+	} {
+		if trusts := (&TagTrust{Keys: test.trusted}).trusts(test.keys); trusts != test.expected {
+			t.Errorf("expected %v trusting %q signed by %q, got %v", test.expected, test.trusted, test.keys, trusts)
+		}
+	}
+}
+
+func TestTrustedTag(t *testing.T) {
+	dep, recorder := recordedLibrary(t)
+	dep.File.Version = "v1.2.0"
+	recorder.Reply("git verify-tag --raw v1.2.0", com.Reply{Stdout: "[GNUPG:] GOODSIG 89ABCDEF01234567 Dev\n"})
+	lib, _ := recordedLibrary(t)
+
+	for _, test := range []struct {
+		trust    *TagTrust
+		expected bool
+	}{
+		{nil, true},
+		{&TagTrust{}, true},
+		{&TagTrust{Keys: []string{"FEDCBA9876543210"}}, false},
+		{&TagTrust{Keys: []string{"FEDCBA9876543210"}, Warn: true}, true},
+	} {
+		lib.trust = test.trust
+		if trusted := lib.trustedTag(*dep); trusted != test.expected {
+			t.Errorf("expected trusted %v with %+v, got %v", test.expected, test.trust, trusted)
+		}
+	}
+
+	// Tagged this run
+	dep.File.Tagged = true
+	ran := len(recorder.Commands())
+	if lib.trust = (&TagTrust{Keys: []string{"FEDCBA9876543210"}}); !lib.trustedTag(*dep) || len(recorder.Commands()) > ran {
+		t.Error("expected tag created this run to be trusted without verifying it")
+	}
+}
@@ -0,0 +1,169 @@
+package gomu
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestTagLib(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	if tag := lib.TagLib("v1.2.0"); tag != "v1.2.0" {
+		t.Errorf("expected v1.2.0, got %q", tag)
+	}
+
+	// Only the new tag is pushed, other local tags stay local
+	expected := []string{"git tag v1.2.0", "git push origin v1.2.0"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestTagLibIncrements(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.2.1\n"})
+
+	if tag := lib.TagLib(""); tag != "v1.2.1" {
+		t.Errorf("expected v1.2.1, got %q", tag)
+	}
+
+	expected := []string{"git-tagger", "git-tagger --action=get"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestTagLibFails(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git tag", com.Reply{ExitCode: 128})
+
+	if tag := lib.TagLib("v1.2.0"); len(tag) > 0 {
+		t.Errorf("expected no tag, got %q", tag)
+	}
+
+	if recorder.Ran("git push") {
+		t.Errorf("expected nothing to be pushed, ran %q", commandLines(recorder))
+	}
+}
+
+func TestSignTag(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git config user.signingkey", com.Reply{Stdout: "ABCD1234\n"})
+
+	tag, err := lib.SignTag("v1.2.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "v1.2.0" {
+		t.Errorf("expected v1.2.0, got %q", tag)
+	}
+
+	// Signed with the configured key when none is provided
+	expected := []string{"git config user.signingkey", "git tag -s -u ABCD1234 -m v1.2.0 v1.2.0", "git push origin v1.2.0"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestSignTagNoKey(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git config user.signingkey", com.Reply{ExitCode: 1})
+
+	if _, err := lib.SignTag("v1.2.0", ""); err == nil {
+		t.Error("expected error signing without a key")
+	}
+	if recorder.Ran("git tag") {
+		t.Errorf("expected no tag without a key, ran %q", commandLines(recorder))
+	}
+}
+
+func TestIncrementVersion(t *testing.T) {
+	for _, test := range []struct{ tag, bump, expected string }{
+		{"v1.2.3", BumpMajor, "v2.0.0"},
+		{"v1.2.3", BumpMinor, "v1.3.0"},
+		{"v1.2.3", BumpPatch, "v1.2.4"},
+		{"", BumpPatch, ""},
+		{"v1.2.3", "huge", ""},
+	} {
+		if version := incrementVersion(test.tag, test.bump); version != test.expected {
+			t.Errorf("expected %s bump of %q to be %q, got %q", test.bump, test.tag, test.expected, version)
+		}
+	}
+}
+
+func TestAutoBump(t *testing.T) {
+	for _, test := range []struct{ latest, commits, expected string }{
+		{"v1.2.0", "a1\x1ffix: retry pushes\x1f\x1e", BumpPatch},
+		{"v1.2.0", "a1\x1ffix: retry pushes\x1f\x1e\nb2\x1ffeat: add Tag\x1f\x1e", BumpMinor},
+		{"v1.2.0", "a1\x1ffeat!: remove Tag\x1f\x1e\nb2\x1ffeat: add Tag\x1f\x1e", BumpMajor},
+		{"v0.2.0", "a1\x1ffeat!: remove Tag\x1f\x1e", BumpMinor},
+	} {
+		lib, recorder := recordedLibrary(t)
+		recorder.Reply("git log", com.Reply{Stdout: test.commits})
+
+		if bump, err := lib.AutoBump(test.latest); err != nil || bump != test.expected {
+			t.Errorf("expected %s bump from %s for %q, got %s (%v)", test.expected, test.latest, test.commits, bump, err)
+		}
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	lib, _ := recordedLibrary(t)
+
+	mu := &MU{}
+	if version, err := mu.nextVersion(*lib, "v1.2.3"); err != nil || len(version) > 0 {
+		t.Errorf("expected git-tagger to increment without a strategy, got %q (%v)", version, err)
+	}
+
+	mu.Options.BumpStrategy = BumpMinor
+	if version, err := mu.nextVersion(*lib, ""); err != nil || len(version) > 0 {
+		t.Errorf("expected git-tagger to tag an untagged lib, got %q (%v)", version, err)
+	}
+
+	// The first matching rule wins over the default strategy
+	mu.Options.BumpRules = []string{"github.com/other/*=patch", "re:gomu-lib=major", "re:gomu=patch"}
+	if version, err := mu.nextVersion(*lib, "v1.2.3"); err != nil || version != "v2.0.0" {
+		t.Errorf("expected major bump from the matching rule, got %q (%v)", version, err)
+	}
+
+	for _, rules := range [][]string{{"re:gomu-lib"}, {"re:gomu-lib=huge"}} {
+		mu.Options.BumpRules = rules
+		if _, err := mu.nextVersion(*lib, "v1.2.3"); err == nil {
+			t.Errorf("expected bump rules %q to fail", rules)
+		}
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/@latest") {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"Version": "v1.3.0"}`))
+	}))
+	defer server.Close()
+
+	// Proxies are read from go env
+	global := &com.Recorder{}
+	global.Reply("go env GOPROXY GONOPROXY GOPRIVATE", com.Reply{Stdout: server.URL + ",direct\n\n\n"})
+	com.SetRunner(global)
+	defer com.SetRunner(nil)
+	com.SetProxy("")
+	defer com.SetProxy(com.ProxyOff)
+
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.2.0\n"})
+	if version := lib.LatestVersion(); version != "v1.3.0" {
+		t.Errorf("expected the proxy's newer v1.3.0, got %q", version)
+	}
+
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.5.0\n"})
+	if version := lib.LatestVersion(); version != "v1.5.0" {
+		t.Errorf("expected the local tag newer than the proxy's, got %q", version)
+	}
+}
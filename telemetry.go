@@ -0,0 +1,366 @@
+package gomu
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// OTLPEndpointEnv is read for the otlp endpoint if none is set in options, as otel sdks do
+const OTLPEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// Outcomes of traced operations, counted by metrics
+const (
+	outcomeOK     = "ok"
+	outcomeFailed = "failed"
+)
+
+// span represents a traced operation of a run, a library, a sync step or a command
+type span struct {
+	id       string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      string
+}
+
+// counterKey identifies a counter by metric name and attributes, e.g. gomu.commands with command=git push
+type counterKey struct {
+	name    string
+	attr    string
+	value   string
+	outcome string
+}
+
+// telemetry records spans and counters of a run, exported to an otlp collector once finished.
+// Spans of libs and their current step are keyed by file, so commands run for a lib are nested within them
+type telemetry struct {
+	mux sync.Mutex
+
+	endpoint string
+	headers  map[string]string
+	traceID  string
+	run      *span
+
+	libraries map[*com.FileWrapper]*span
+	steps     map[*com.FileWrapper]*span
+	// Failures of each lib when its current step started, so the step's own failures are known
+	stepFailures map[*com.FileWrapper]int
+
+	spans    []*span
+	counters map[counterKey]int64
+	started  time.Time
+}
+
+// newTelemetry returns telemetry exported to the otlp endpoint set in options or the environment, nil if neither is set
+func newTelemetry(options Options, started time.Time) *telemetry {
+	endpoint := options.OTLPEndpoint
+	if len(endpoint) == 0 {
+		endpoint = os.Getenv(OTLPEndpointEnv)
+	}
+	if len(endpoint) == 0 {
+		return nil
+	}
+
+	t := &telemetry{
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		headers:      options.OTLPHeaders,
+		traceID:      randomID(16),
+		libraries:    make(map[*com.FileWrapper]*span),
+		steps:        make(map[*com.FileWrapper]*span),
+		stepFailures: make(map[*com.FileWrapper]int),
+		counters:     make(map[counterKey]int64),
+		started:      started,
+	}
+	t.run = &span{id: randomID(8), name: "gomu " + options.Action, start: started, attrs: map[string]string{
+		"gomu.action":  options.Action,
+		"gomu.branch":  options.Branch,
+		"gomu.dry_run": strconv.FormatBool(options.DryRun),
+	}}
+
+	return t
+}
+
+// randomID returns a random hex id of size bytes
+func randomID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// outcome returns the outcome counted for an operation
+func outcome(failed bool) string {
+	if failed {
+		return outcomeFailed
+	}
+
+	return outcomeOK
+}
+
+// startLibrary opens a span for lib, nested within the run
+func (t *telemetry) startLibrary(lib Library, action string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.libraries[lib.File] = &span{id: randomID(8), parentID: t.run.id, name: action + " " + lib.File.GetGoURL(), start: time.Now(), attrs: map[string]string{
+		"gomu.action":  action,
+		"gomu.library": lib.File.GetGoURL(),
+		"gomu.path":    lib.File.OriginalPath(),
+	}}
+}
+
+// step ends lib's current step span, if any, and opens one for step nested within the lib
+func (t *telemetry) step(lib Library, step string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.endStep(lib)
+
+	parent, ok := t.libraries[lib.File]
+	if !ok {
+		return
+	}
+
+	t.steps[lib.File] = &span{id: randomID(8), parentID: parent.id, name: step, start: time.Now(), attrs: map[string]string{
+		"gomu.step":    step,
+		"gomu.library": lib.File.GetGoURL(),
+	}}
+	t.stepFailures[lib.File] = len(lib.File.Failures)
+}
+
+// endStep ends lib's current step span, failed if lib failed since it started
+// Note: t.mux must be held
+func (t *telemetry) endStep(lib Library) {
+	step, ok := t.steps[lib.File]
+	if !ok {
+		return
+	}
+	delete(t.steps, lib.File)
+
+	if failures := lib.File.Failures[t.stepFailures[lib.File]:]; len(failures) > 0 {
+		step.err = failures[0].Cause.Error()
+	}
+
+	step.end = time.Now()
+	t.spans = append(t.spans, step)
+	t.counters[counterKey{name: "gomu.steps", attr: "gomu.step", value: step.name, outcome: outcome(len(step.err) > 0)}]++
+}
+
+// finishLibrary ends lib's spans, failed if lib has failures, and counts it
+func (t *telemetry) finishLibrary(lib Library, failed bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.endStep(lib)
+
+	library, ok := t.libraries[lib.File]
+	if !ok {
+		return
+	}
+	delete(t.libraries, lib.File)
+
+	if len(lib.File.Failures) > 0 {
+		library.err = lib.File.Failures[0].Error()
+	} else if failed {
+		library.err = "failed"
+	}
+
+	library.end = time.Now()
+	library.attrs["gomu.updated"] = strconv.FormatBool(lib.File.Updated)
+	library.attrs["gomu.committed"] = strconv.FormatBool(lib.File.Committed)
+	library.attrs["gomu.tagged"] = strconv.FormatBool(lib.File.Tagged)
+	if len(lib.File.Version) > 0 {
+		library.attrs["gomu.version"] = lib.File.Version
+	}
+	if len(lib.File.PRURL) > 0 {
+		library.attrs["gomu.pr_url"] = lib.File.PRURL
+	}
+
+	t.spans = append(t.spans, library)
+	action := library.attrs["gomu.action"]
+	t.counters[counterKey{name: "gomu.libraries", attr: "gomu.action", value: action, outcome: outcome(len(library.err) > 0)}]++
+	t.counters[counterKey{name: "gomu.library.duration", attr: "gomu.action", value: action}] += library.end.Sub(library.start).Milliseconds()
+}
+
+// command records a finished command as a span nested within the current step or lib of file, or the run if none,
+// e.g. stashing before libs are started
+func (t *telemetry) command(file *com.FileWrapper, command string, started time.Time, err error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	parent, ok := t.steps[file]
+	if !ok {
+		if parent, ok = t.libraries[file]; !ok {
+			parent = t.run
+		}
+	}
+
+	// Name by the command and subcommand, e.g. git push, so spans group well
+	fields := strings.Fields(command)
+	if len(fields) > 2 {
+		fields = fields[:2]
+	}
+	name := strings.Join(fields, " ")
+
+	cmd := &span{id: randomID(8), parentID: parent.id, name: name, start: started, end: time.Now(), attrs: map[string]string{
+		"gomu.command": command,
+		"gomu.path":    file.Path,
+	}}
+	if err != nil {
+		cmd.err = err.Error()
+	}
+
+	t.spans = append(t.spans, cmd)
+	t.counters[counterKey{name: "gomu.commands", attr: "gomu.command", value: name, outcome: outcome(err != nil)}]++
+}
+
+// export ends the run span, failed if the run had errors, and sends spans and counters to the otlp collector
+func (t *telemetry) export(errs []error) (err error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.run.end = time.Now()
+	if len(errs) > 0 {
+		t.run.err = errs[0].Error()
+	}
+	t.spans = append(t.spans, t.run)
+
+	if err = com.PostJSONWithHeaders(t.endpoint+"/v1/traces", t.headers, t.traces()); err != nil {
+		return fmt.Errorf("unable to export traces to %s: %v", t.endpoint, err)
+	}
+
+	if err = com.PostJSONWithHeaders(t.endpoint+"/v1/metrics", t.headers, t.metrics()); err != nil {
+		return fmt.Errorf("unable to export metrics to %s: %v", t.endpoint, err)
+	}
+
+	return
+}
+
+// otlpAttribute represents a string attribute in otlp json
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+// otlpAttributes returns attrs in otlp json, with keys in order
+func otlpAttributes(attrs map[string]string) (attributes []otlpAttribute) {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	gosort.Strings(keys)
+
+	for _, key := range keys {
+		attribute := otlpAttribute{Key: key}
+		attribute.Value.StringValue = attrs[key]
+		attributes = append(attributes, attribute)
+	}
+
+	return
+}
+
+// otlpResource identifies gomu as the service of exported telemetry
+var otlpResource = map[string]interface{}{
+	"attributes": otlpAttributes(map[string]string{"service.name": "gomu"}),
+}
+
+// otlpScope names the instrumentation of exported telemetry
+var otlpScope = map[string]string{"name": "github.com/gomuserver/mod-utils"}
+
+// unixNano formats t as otlp json encodes 64 bit ints
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// traces returns the run's spans as an otlp json export request
+func (t *telemetry) traces() interface{} {
+	spans := make([]map[string]interface{}, len(t.spans))
+	for i, s := range t.spans {
+		// Unset, or error with the message
+		status := map[string]interface{}{}
+		if len(s.err) > 0 {
+			status = map[string]interface{}{"code": 2, "message": s.err}
+		}
+
+		spans[i] = map[string]interface{}{
+			"traceId":           t.traceID,
+			"spanId":            s.id,
+			"parentSpanId":      s.parentID,
+			"name":              s.name,
+			"kind":              1, // Internal
+			"startTimeUnixNano": unixNano(s.start),
+			"endTimeUnixNano":   unixNano(s.end),
+			"attributes":        otlpAttributes(s.attrs),
+			"status":            status,
+		}
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource":   otlpResource,
+			"scopeSpans": []interface{}{map[string]interface{}{"scope": otlpScope, "spans": spans}},
+		}},
+	}
+}
+
+// metrics returns the run's counters as an otlp json export request. Counts are deltas of this run, so backends
+// sum them across scheduled runs
+func (t *telemetry) metrics() interface{} {
+	units := map[string]string{"gomu.library.duration": "ms"}
+	points := make(map[string][]map[string]interface{})
+	var names []string
+	for key, count := range t.counters {
+		attrs := map[string]string{key.attr: key.value}
+		if len(key.outcome) > 0 {
+			attrs["gomu.outcome"] = key.outcome
+		}
+
+		if _, ok := points[key.name]; !ok {
+			names = append(names, key.name)
+		}
+		points[key.name] = append(points[key.name], map[string]interface{}{
+			"attributes":        otlpAttributes(attrs),
+			"startTimeUnixNano": unixNano(t.started),
+			"timeUnixNano":      unixNano(t.run.end),
+			"asInt":             strconv.FormatInt(count, 10),
+		})
+	}
+	gosort.Strings(names)
+
+	metrics := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		unit := units[name]
+		if len(unit) == 0 {
+			unit = "1"
+		}
+
+		metrics[i] = map[string]interface{}{
+			"name": name,
+			"unit": unit,
+			"sum": map[string]interface{}{
+				"aggregationTemporality": 1, // Delta
+				"isMonotonic":            true,
+				"dataPoints":             points[name],
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource":     otlpResource,
+			"scopeMetrics": []interface{}{map[string]interface{}{"scope": otlpScope, "metrics": metrics}},
+		}},
+	}
+}
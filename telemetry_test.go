@@ -0,0 +1,139 @@
+package gomu
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// otlpExport is the part of otlp json export requests checked by tests
+type otlpExport struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []struct {
+				SpanID       string `json:"spanId"`
+				ParentSpanID string `json:"parentSpanId"`
+				Name         string `json:"name"`
+				Status       struct {
+					Code int `json:"code"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+	ResourceMetrics []struct {
+		ScopeMetrics []struct {
+			Metrics []struct {
+				Name string `json:"name"`
+			} `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+func TestNewTelemetry(t *testing.T) {
+	if endpoint, ok := os.LookupEnv(OTLPEndpointEnv); ok {
+		os.Unsetenv(OTLPEndpointEnv)
+		defer os.Setenv(OTLPEndpointEnv, endpoint)
+	}
+
+	if tel := newTelemetry(Options{Action: "sync"}, time.Now()); tel != nil {
+		t.Errorf("expected no telemetry without an endpoint, got %+v", tel)
+	}
+
+	os.Setenv(OTLPEndpointEnv, "http://collector:4318/")
+	defer os.Unsetenv(OTLPEndpointEnv)
+	if tel := newTelemetry(Options{Action: "sync"}, time.Now()); tel == nil || tel.endpoint != "http://collector:4318" {
+		t.Errorf("expected the endpoint to be read from the environment, got %+v", tel)
+	}
+	if tel := newTelemetry(Options{Action: "sync", OTLPEndpoint: "http://other:4318"}, time.Now()); tel == nil || tel.endpoint != "http://other:4318" {
+		t.Errorf("expected the endpoint of options to be preferred, got %+v", tel)
+	}
+}
+
+func TestTelemetryExport(t *testing.T) {
+	exports := make(map[string]otlpExport)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer collector" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var export otlpExport
+		if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+			t.Error(err)
+		}
+		exports[r.URL.Path] = export
+	}))
+	defer server.Close()
+
+	com.SetRateLimitPolicy(com.RateLimitPolicy{Interval: -1})
+	defer com.SetRateLimitPolicy(com.RateLimitPolicy{})
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	options := Options{Action: "sync", OTLPEndpoint: server.URL, OTLPHeaders: map[string]string{"Authorization": "Bearer collector"}}
+	tel := newTelemetry(options, time.Now())
+	com.SetCommandObserver(tel.command)
+	defer com.SetCommandObserver(nil)
+
+	lib, _ := recordedLibrary(t)
+	tel.startLibrary(*lib, "sync")
+	tel.step(*lib, "update")
+	lib.File.RunCmd("go", "get", "-d", "github.com/org/b@v1.0.1")
+	lib.File.Fail(errors.New("go build failed"))
+	tel.step(*lib, "commit")
+	lib.File.RunCmd("git", "commit", "-m", "Update deps")
+	tel.finishLibrary(*lib, true)
+
+	if err := tel.export([]error{errors.New("1 lib failed")}); err != nil {
+		t.Fatal(err)
+	}
+
+	traces := exports["/v1/traces"]
+	if len(traces.ResourceSpans) != 1 || len(traces.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("expected traces to be exported, got %+v", exports)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	ids := make(map[string]string)
+	for _, s := range spans {
+		ids[s.Name] = s.SpanID
+	}
+	libSpan := "sync " + lib.File.GetGoURL()
+	parent := map[string]string{"go get": "update", "update": libSpan, "git commit": "commit", "commit": libSpan, libSpan: "gomu sync", "gomu sync": ""}
+	for _, s := range spans {
+		if expected, ok := parent[s.Name]; !ok || s.ParentSpanID != ids[expected] {
+			t.Errorf("expected %q to be nested within %q, got parent %q", s.Name, expected, s.ParentSpanID)
+		}
+		if failed := s.Status.Code == 2; failed != (s.Name == "update" || s.Name == libSpan || s.Name == "gomu sync") {
+			t.Errorf("unexpected status of %q: %d", s.Name, s.Status.Code)
+		}
+	}
+	if len(spans) != 6 {
+		t.Errorf("expected spans of the run, lib, steps and commands, got %d", len(spans))
+	}
+
+	metrics := exports["/v1/metrics"]
+	if len(metrics.ResourceMetrics) != 1 || len(metrics.ResourceMetrics[0].ScopeMetrics) != 1 {
+		t.Fatalf("expected metrics to be exported, got %+v", exports)
+	}
+	var names []string
+	for _, metric := range metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		names = append(names, metric.Name)
+	}
+	expected := []string{"gomu.commands", "gomu.libraries", "gomu.library.duration", "gomu.steps"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected metrics %v, got %v", expected, names)
+	}
+
+	tel.headers = nil
+	if err := tel.export(nil); err == nil {
+		t.Error("expected a rejected export to fail")
+	}
+}
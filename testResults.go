@@ -0,0 +1,412 @@
+package gomu
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Results of a test or package
+const (
+	TestPass = "pass"
+	TestFail = "fail"
+	TestSkip = "skip"
+)
+
+// Name of the test reported for packages failing outside of a test, e.g. build failures
+const packageTestName = "(package)"
+
+// TestCase represents the result of a single test
+type TestCase struct {
+	Name    string  `json:"name"`
+	Result  string  `json:"result"`
+	Elapsed float64 `json:"elapsed"`
+
+	// Output is only kept for failed tests
+	Output string `json:"output,omitempty"`
+}
+
+// PackageResult represents the test results of a package within a library
+type PackageResult struct {
+	Library string `json:"library"`
+	Package string `json:"package"`
+	Result  string `json:"result"`
+
+	Passed  int     `json:"passed"`
+	Failed  int     `json:"failed"`
+	Skipped int     `json:"skipped"`
+	Elapsed float64 `json:"elapsed"`
+
+	// Percent of statements covered, nil if coverage wasn't reported
+	Coverage *float64 `json:"coverage,omitempty"`
+
+	Tests []TestCase `json:"tests,omitempty"`
+}
+
+// testEvent represents a single event of go test -json output
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// Matches coverage reported by go test -cover
+var coveragePattern = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+
+// Test runs go test with coverage on each package in lib, returning per package results and the coverage profile.
+// Failed is true if any package failed, including build failures
+func (lib *Library) Test() (packages []PackageResult, coverProfile string, failed bool, err error) {
+	profile, err := ioutil.TempFile("", "gomu-cover-")
+	if err != nil {
+		return
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	output, exitCode, err := lib.File.CmdResult(lib.File.GoBinary(), "test", "-json", "-cover", "-coverprofile="+profile.Name(), "./...")
+	if err != nil {
+		return
+	}
+
+	if packages, err = parseTestEvents(lib.File.GetGoURL(), output); err != nil {
+		return
+	}
+
+	data, _ := ioutil.ReadFile(profile.Name())
+	coverProfile = string(data)
+
+	failed = exitCode != 0
+	for _, pkg := range packages {
+		failed = failed || pkg.Result == TestFail
+	}
+
+	return
+}
+
+// parseTestEvents aggregates go test -json output into results per package, in the order packages were first reported
+func parseTestEvents(library, output string) (packages []PackageResult, err error) {
+	indexes := make(map[string]int)
+	outputs := make(map[string]*strings.Builder)
+	tests := make(map[string]int)
+
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var event testEvent
+		if err = decoder.Decode(&event); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			err = fmt.Errorf("unable to parse go test output: %v", err)
+			return
+		}
+
+		if len(event.Package) == 0 {
+			continue
+		}
+
+		index, ok := indexes[event.Package]
+		if !ok {
+			index = len(packages)
+			indexes[event.Package] = index
+			packages = append(packages, PackageResult{Library: library, Package: event.Package})
+		}
+		pkg := &packages[index]
+
+		key := event.Package + " " + event.Test
+		if event.Action == "output" {
+			if outputs[key] == nil {
+				outputs[key] = &strings.Builder{}
+			}
+			outputs[key].WriteString(event.Output)
+
+			if match := coveragePattern.FindStringSubmatch(event.Output); len(event.Test) == 0 && match != nil {
+				if coverage, parseErr := strconv.ParseFloat(match[1], 64); parseErr == nil {
+					pkg.Coverage = &coverage
+				}
+			}
+			continue
+		}
+
+		switch event.Action {
+		case TestPass, TestFail, TestSkip:
+		default:
+			// Run, pause, cont, etc.
+			continue
+		}
+
+		if len(event.Test) == 0 {
+			pkg.Result = event.Action
+			pkg.Elapsed = event.Elapsed
+			continue
+		}
+
+		testCase := TestCase{Name: event.Test, Result: event.Action, Elapsed: event.Elapsed}
+		if event.Action == TestFail && outputs[key] != nil {
+			testCase.Output = outputs[key].String()
+		}
+
+		switch event.Action {
+		case TestPass:
+			pkg.Passed++
+		case TestFail:
+			pkg.Failed++
+		case TestSkip:
+			pkg.Skipped++
+		}
+
+		if previous, ok := tests[key]; ok {
+			// Reported again, e.g. with -count
+			pkg.Tests[previous] = testCase
+			continue
+		}
+		tests[key] = len(pkg.Tests)
+		pkg.Tests = append(pkg.Tests, testCase)
+	}
+
+	for i := range packages {
+		pkg := &packages[i]
+		if pkg.Result == TestFail && pkg.Failed == 0 {
+			// Failed outside of a test, e.g. to build
+			pkg.Failed++
+			testCase := TestCase{Name: packageTestName, Result: TestFail, Elapsed: pkg.Elapsed}
+			if out := outputs[pkg.Package+" "]; out != nil {
+				testCase.Output = out.String()
+			}
+			pkg.Tests = append(pkg.Tests, testCase)
+		}
+	}
+
+	return
+}
+
+// buildFailure returns a failed result for lib, reported when it doesn't build so it can't be tested
+func buildFailure(lib Library) PackageResult {
+	url := lib.File.GetGoURL()
+	return PackageResult{
+		Library: url,
+		Package: url,
+		Result:  TestFail,
+		Failed:  1,
+		Tests:   []TestCase{{Name: packageTestName, Result: TestFail, Output: "build failed"}},
+	}
+}
+
+// String returns a one line summary of the package's results, e.g. ok github.com/org/lib 3 passed, coverage 71.4%
+func (pkg PackageResult) String() string {
+	status := "ok  "
+	switch pkg.Result {
+	case TestFail:
+		status = "FAIL"
+	case TestSkip:
+		status = "?   "
+	}
+
+	line := status + " " + pkg.Package + " " + strconv.Itoa(pkg.Passed) + " passed"
+	if pkg.Failed > 0 {
+		line += ", " + strconv.Itoa(pkg.Failed) + " failed"
+	}
+	if pkg.Skipped > 0 {
+		line += ", " + strconv.Itoa(pkg.Skipped) + " skipped"
+	}
+	if pkg.Coverage != nil {
+		line += ", coverage " + strconv.FormatFloat(*pkg.Coverage, 'f', 1, 64) + "%"
+	}
+
+	return line
+}
+
+// recordTests adds a library's test results and coverage profile to the stats
+func (mu *MU) recordTests(packages []PackageResult, coverProfile string) {
+	mu.statsMux.Lock()
+	defer mu.statsMux.Unlock()
+
+	mu.Stats.TestPackages = append(mu.Stats.TestPackages, packages...)
+
+	for _, line := range strings.Split(coverProfile, "\n") {
+		if strings.HasPrefix(line, "mode: ") {
+			mu.Stats.CoverMode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			mu.Stats.CoverBlocks = append(mu.Stats.CoverBlocks, line)
+		}
+	}
+}
+
+// failedPackages returns a line per failed package listing its failed tests, attributing failures to their lib
+func failedPackages(packages []PackageResult) (output string) {
+	for _, pkg := range packages {
+		if pkg.Result != TestFail {
+			continue
+		}
+
+		var tests []string
+		for _, test := range pkg.Tests {
+			if test.Result == TestFail && test.Name != packageTestName {
+				tests = append(tests, test.Name)
+			}
+		}
+
+		output += "   - " + pkg.Package
+		if len(tests) > 0 {
+			output += " (" + strings.Join(tests, ", ") + ")"
+		}
+		output += "\n"
+	}
+
+	return
+}
+
+// testTotals returns test counts across all packages
+func (stats ActionStats) testTotals() (passed, failed, skipped int) {
+	for _, pkg := range stats.TestPackages {
+		passed += pkg.Passed
+		failed += pkg.Failed
+		skipped += pkg.Skipped
+	}
+
+	return
+}
+
+// Coverage returns the percent of statements covered across all libraries, false if no coverage was collected
+func (stats ActionStats) Coverage() (coverage float64, ok bool) {
+	var statements, covered int
+	for _, block := range stats.CoverBlocks {
+		// file:start.col,end.col statements count
+		fields := strings.Fields(block)
+		if len(fields) != 3 {
+			continue
+		}
+
+		count, countErr := strconv.Atoi(fields[2])
+		numStatements, statementsErr := strconv.Atoi(fields[1])
+		if countErr != nil || statementsErr != nil {
+			continue
+		}
+
+		statements += numStatements
+		if count > 0 {
+			covered += numStatements
+		}
+	}
+
+	if statements == 0 {
+		return
+	}
+
+	return 100 * float64(covered) / float64(statements), true
+}
+
+// WriteCoverProfile writes the coverage profiles of all libraries as a single profile, readable by go tool cover
+func (stats ActionStats) WriteCoverProfile(profilePath string) error {
+	mode := stats.CoverMode
+	if len(mode) == 0 {
+		mode = "set"
+	}
+
+	output := "mode: " + mode + "\n"
+	for _, block := range stats.CoverBlocks {
+		output += block + "\n"
+	}
+
+	return ioutil.WriteFile(profilePath, []byte(output), 0644)
+}
+
+// junitTestSuites represents the root of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite represents the tests of a package in a JUnit XML report
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase represents a single test in a JUnit XML report
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+// junitFailure represents a failed test's output in a JUnit XML report
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// junitTime formats seconds as JUnit expects
+func junitTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+// JUnit returns test results of all libraries as a JUnit XML report, with a test suite per package
+func (stats ActionStats) JUnit() (output string, err error) {
+	var report junitTestSuites
+	var elapsed float64
+	for _, pkg := range stats.TestPackages {
+		suite := junitTestSuite{
+			Name:     pkg.Package,
+			Tests:    len(pkg.Tests),
+			Failures: pkg.Failed,
+			Skipped:  pkg.Skipped,
+			Time:     junitTime(pkg.Elapsed),
+		}
+
+		for _, test := range pkg.Tests {
+			testCase := junitTestCase{ClassName: pkg.Package, Name: test.Name, Time: junitTime(test.Elapsed)}
+			switch test.Result {
+			case TestFail:
+				testCase.Failure = &junitFailure{Message: "Failed", Output: test.Output}
+			case TestSkip:
+				testCase.Skipped = &struct{}{}
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		elapsed += pkg.Elapsed
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = junitTime(elapsed)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return xml.Header + string(data) + "\n", nil
+}
+
+// WriteJUnit writes test results of all libraries to a JUnit XML file
+func (stats ActionStats) WriteJUnit(junitPath string) error {
+	output, err := stats.JUnit()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(junitPath, []byte(output), 0644)
+}
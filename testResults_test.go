@@ -0,0 +1,119 @@
+package gomu
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+const testEvents = `{"Action":"run","Package":"github.com/org/a","Test":"TestSync"}
+{"Action":"output","Package":"github.com/org/a","Test":"TestSync","Output":"=== RUN   TestSync\n"}
+{"Action":"pass","Package":"github.com/org/a","Test":"TestSync","Elapsed":0.5}
+{"Action":"run","Package":"github.com/org/a","Test":"TestTag"}
+{"Action":"output","Package":"github.com/org/a","Test":"TestTag","Output":"    tag_test.go:10: expected v1.2.0\n"}
+{"Action":"fail","Package":"github.com/org/a","Test":"TestTag","Elapsed":0.25}
+{"Action":"skip","Package":"github.com/org/a","Test":"TestPush","Elapsed":0}
+{"Action":"output","Package":"github.com/org/a","Output":"coverage: 71.4% of statements\n"}
+{"Action":"fail","Package":"github.com/org/a","Elapsed":1}
+{"Action":"output","Package":"github.com/org/a/cmd","Output":"cmd/main.go:3:2: undefined: gomu\n"}
+{"Action":"fail","Package":"github.com/org/a/cmd","Elapsed":0.1}
+`
+
+func TestParseTestEvents(t *testing.T) {
+	packages, err := parseTestEvents("github.com/org/a", testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %+v", packages)
+	}
+
+	if line := packages[0].String(); line != "FAIL github.com/org/a 1 passed, 1 failed, 1 skipped, coverage 71.4%" {
+		t.Errorf("unexpected package result %q", line)
+	}
+	if failed := packages[0].Tests[1]; failed.Name != "TestTag" || failed.Output != "    tag_test.go:10: expected v1.2.0\n" {
+		t.Errorf("expected failed test's output to be kept, got %+v", failed)
+	}
+
+	// Failed to build
+	build := packages[1]
+	if build.Failed != 1 || len(build.Tests) != 1 || build.Tests[0].Name != packageTestName || !strings.Contains(build.Tests[0].Output, "undefined: gomu") {
+		t.Errorf("expected build failure to be reported as a failed test, got %+v", build)
+	}
+
+	expected := "   - github.com/org/a (TestTag)\n   - github.com/org/a/cmd\n"
+	if output := failedPackages(packages); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	if _, err = parseTestEvents("github.com/org/a", "not json"); err == nil {
+		t.Error("expected invalid output to fail")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	mu := &MU{}
+	mu.recordTests(nil, "mode: set\ngithub.com/org/a/a.go:1.1,3.2 3 1\ngithub.com/org/a/a.go:4.1,5.2 1 0\n")
+	mu.recordTests(nil, "mode: set\ngithub.com/org/b/b.go:1.1,3.2 4 0\n")
+
+	if coverage, ok := mu.Stats.Coverage(); !ok || coverage != 37.5 {
+		t.Errorf("expected 3 of 8 statements covered, got %v", coverage)
+	}
+	if mu.Stats.CoverMode != "set" || len(mu.Stats.CoverBlocks) != 3 {
+		t.Errorf("expected profiles to be merged, got %s %q", mu.Stats.CoverMode, mu.Stats.CoverBlocks)
+	}
+
+	if _, ok := (ActionStats{}).Coverage(); ok {
+		t.Error("expected no coverage without profiles")
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	packages, err := parseTestEvents("github.com/org/a", testEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ActionStats{TestPackages: packages}.JUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		`<testsuites tests="4" failures="2" skipped="1" time="1.100">`,
+		`<testsuite name="github.com/org/a" tests="3" failures="1" skipped="1" time="1.000">`,
+		`<testcase classname="github.com/org/a" name="TestTag" time="0.250">`,
+		`<failure message="Failed">    tag_test.go:10: expected v1.2.0&#xA;</failure>`,
+		`<testcase classname="github.com/org/a/cmd" name="(package)" time="0.100">`,
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, report)
+		}
+	}
+}
+
+func TestTestLevels(t *testing.T) {
+	a, aRecorder := recordedLibrary(t)
+	b, bRecorder := recordedLibrary(t)
+	bRecorder.Reply("go test -json", com.Reply{Stdout: testEvents, ExitCode: 1})
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	mu := &MU{Options: Options{Action: "test", ParallelTest: true}, ctx: context.Background()}
+	mu.Stats.DepCount = 2
+	if !mu.testLevels(&sort.FileNode{File: a.File, Next: &sort.FileNode{File: b.File}}) {
+		t.Fatal("expected every level to be tested")
+	}
+
+	if !aRecorder.Ran("go test -json") || !bRecorder.Ran("go test -json") {
+		t.Errorf("expected both libs to be tested, ran %q and %q", commandLines(aRecorder), commandLines(bRecorder))
+	}
+	expected := "1) " + b.File.OriginalPath() + "\n   - github.com/org/a (TestTag)\n   - github.com/org/a/cmd\n"
+	if a.File.TestFailed || !b.File.TestFailed || mu.Stats.TestFailedOutput != expected {
+		t.Errorf("expected only b's failures attributed to it, got %q", mu.Stats.TestFailedOutput)
+	}
+}
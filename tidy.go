@@ -0,0 +1,95 @@
+package gomu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// tidy runs go mod tidy in lib, committing the changes if set. When verifying, libs whose mod files aren't tidy
+// fail and are restored instead, e.g. in CI
+func (mu *MU) tidy(lib Library) {
+	if _, err := os.Stat(filepath.Join(lib.File.Path, "go.mod")); err != nil {
+		lib.File.Output("No mod file found. Skipping.")
+		return
+	}
+
+	commit := mu.Options.Commit && !mu.Options.VerifyTidy
+	if commit {
+		if mu.checkoutBranch(lib) != nil {
+			// Don't make changes which can't be pushed
+			return
+		}
+	}
+
+	lib.File.Output("Tidying mod files...")
+	if err := lib.ModTidy(); err != nil {
+		mu.libraryError(lib, fmt.Errorf("go mod tidy failed: %v", err))
+		return
+	}
+
+	if !lib.File.HasChangesIn("go.mod") && !lib.File.HasChangesIn("go.sum") {
+		lib.File.Output("Mod files already tidy!")
+		return
+	}
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+"\n")
+
+	if mu.Options.VerifyTidy {
+		// Leave the lib as it was, removing go.sum if tidy created it
+		lib.File.RunCmd("git", "checkout", "--", "go.mod")
+		if lib.File.RunCmd("git", "checkout", "--", "go.sum") != nil {
+			lib.File.RemoveFile("go.sum")
+		}
+		mu.libraryError(lib, fmt.Errorf("go mod tidy changes mod files"))
+		return
+	}
+
+	if !commit {
+		lib.File.Output("Mod files tidied!")
+		return
+	}
+
+	commitTitle := mu.Options.CommitMessage
+	if len(commitTitle) == 0 {
+		commitTitle = "Tidy mod files"
+	}
+	commitTitle = "gomu: " + commitTitle
+
+	files := []string{"go.mod"}
+	if _, err := os.Stat(filepath.Join(lib.File.Path, "go.sum")); err == nil {
+		// Libs without deps have no sum file
+		files = append(files, "go.sum")
+	}
+
+	if err := mu.setCommitKey(&lib); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if lib.File.Add(files...) != nil || lib.commit(commitTitle) != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to commit mod files"))
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	if err := lib.File.Push(); err != nil {
+		mu.libraryError(lib, fmt.Errorf("push failed, check local changes and commit status: %v", err))
+		return
+	}
+
+	lib.File.Committed = true
+	mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, lib.File.GetGoURL()+"\n")
+	lib.File.Output("Mod files committed!")
+
+	if len(lib.File.ProtectedBranch) == 0 {
+		mu.pullRequest(lib, mu.branch(lib), commitTitle, "")
+	} else {
+		// Changes to protected branches are only reviewed through a pull request
+		mu.requestPR(lib, FallbackBranch, commitTitle, "")
+	}
+
+	mu.removeBranchIfUnused(lib)
+}
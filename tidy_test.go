@@ -0,0 +1,75 @@
+package gomu
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+func TestTidy(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{}
+	mu.tidy(*lib)
+
+	expected := []string{"go mod tidy", "git status --porcelain -- go.mod", "git status --porcelain -- go.sum"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if lib.File.Updated || mu.Stats.UpdateCount != 0 {
+		t.Errorf("expected tidy mod files, got %d updates", mu.Stats.UpdateCount)
+	}
+}
+
+func TestTidyCommit(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git status --porcelain -- go.mod", com.Reply{Stdout: " M go.mod\n"})
+
+	mu := &MU{Options: Options{Commit: true, Branch: "tidy"}}
+	mu.tidy(*lib)
+
+	// Protection of the branch is checked before it's checked out
+	expected := []string{
+		"git remote get-url origin",
+		"git-tagger --action=get",
+		"git checkout tidy",
+		"git pull",
+		"go mod tidy",
+		"git status --porcelain -- go.mod",
+		"git add go.mod go.sum",
+		"git commit -m gomu: Tidy mod files",
+		"git push -u origin",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if !lib.File.Committed || mu.Stats.CommitCount != 1 {
+		t.Errorf("expected mod files to be committed, got %d commits", mu.Stats.CommitCount)
+	}
+}
+
+func TestVerifyTidy(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git status --porcelain -- go.mod", com.Reply{Stdout: " M go.mod\n"})
+
+	mu := &MU{Options: Options{Commit: true, VerifyTidy: true}}
+	mu.tidy(*lib)
+
+	// Mod files are restored instead of committed
+	expected := []string{
+		"go mod tidy",
+		"git status --porcelain -- go.mod",
+		"git checkout -- go.mod",
+		"git checkout -- go.sum",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if len(lib.File.Errors) == 0 {
+		t.Error("expected untidy mod files to fail")
+	}
+}
@@ -0,0 +1,368 @@
+package gomu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// OperationLogName is the file mutations are recorded to so the last run can be undone
+const OperationLogName = ".gomu-oplog.json"
+
+// Operation types
+const (
+	// OpBranch is recorded when a branch is created and pushed
+	OpBranch = "branch"
+	// OpCommit is recorded when a commit is created
+	OpCommit = "commit"
+	// OpTag is recorded when a tag is pushed
+	OpTag = "tag"
+	// OpPullRequest is recorded when a pull request is opened
+	OpPullRequest = "pr"
+)
+
+// Operation represents a single mutation made to a library
+type Operation struct {
+	Type string `json:"type"`
+
+	// Path of the library
+	Library string `json:"library"`
+
+	Branch   string `json:"branch,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	PRNumber int    `json:"prNumber,omitempty"`
+	PRURL    string `json:"prURL,omitempty"`
+}
+
+// OperationLog represents every mutation made by a run, in order
+type OperationLog struct {
+	Action string `json:"action"`
+	// Branch of the run, empty if rendered for each lib. Operations record the branch of their lib
+	Branch     string      `json:"branch"`
+	Operations []Operation `json:"operations"`
+}
+
+// LoadOperationLog reads an operation log from disk
+func LoadOperationLog(logPath string) (log OperationLog, err error) {
+	data, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &log)
+	return
+}
+
+// Save writes the operation log to disk
+func (log *OperationLog) Save(logPath string) (err error) {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(logPath, data, 0644)
+}
+
+// recordOperation appends op to this run's operation log, after any operations earlier runs left to undo
+func (mu *MU) recordOperation(lib Library, op Operation) {
+	if mu.Options.DryRun {
+		return
+	}
+
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	if mu.operations == nil {
+		mu.operations = &OperationLog{Action: mu.Options.Action, Branch: mu.runBranch()}
+		if previous, err := LoadOperationLog(OperationLogName); err == nil {
+			mu.previousOperations = previous.Operations
+		}
+	}
+
+	op.Library = lib.File.OriginalPath()
+	mu.operations.Operations = append(mu.operations.Operations, op)
+
+	if err := mu.saveOperations(); err != nil {
+		lib.File.Output("Unable to save operation log :( " + err.Error())
+	}
+}
+
+// saveOperations writes the operations left to undo to the operation log, removing it if there are none
+// Note: call with mu.libraryMux held
+func (mu *MU) saveOperations() error {
+	log := OperationLog{Action: mu.Options.Action, Branch: mu.runBranch()}
+	log.Operations = append(log.Operations, mu.previousOperations...)
+	if mu.operations != nil {
+		log.Operations = append(log.Operations, mu.operations.Operations...)
+	}
+
+	if len(log.Operations) == 0 {
+		if err := os.Remove(OperationLogName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return log.Save(OperationLogName)
+}
+
+// recordCommit records the current commit if it differs from previousHead
+func (mu *MU) recordCommit(lib Library, previousHead string) {
+	head := lib.File.HeadCommit()
+	if len(head) == 0 || head == previousHead {
+		// Nothing was committed
+		return
+	}
+
+	lib.File.CommitSHA = head
+	branch, _ := lib.File.CurrentBranch()
+	mu.recordOperation(lib, Operation{Type: OpCommit, Branch: branch, Commit: head})
+}
+
+// undo reverses every operation recorded by the last run, once the planned operations are confirmed
+func (mu *MU) undo() {
+	log, err := LoadOperationLog(OperationLogName)
+	if err != nil {
+		com.Println("\nNo operation log found. Nothing to undo.")
+		return
+	}
+
+	planned := undoPlan(log.Operations)
+	var warningActions []string
+	for _, op := range planned {
+		warningActions = append(warningActions, "- "+op.plan(mu.Options.DeleteTags)+" in "+op.Library)
+	}
+
+	if !mu.confirm("Undo", warningActions) {
+		return
+	}
+
+	com.Println("\nUndoing", len(planned), "operation(s) from last", log.Action, "...")
+
+	undone, ok := mu.undoOperations(log.Operations, nil)
+	for _, op := range undone {
+		mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, op.Library+" "+op.describe(mu.Options.DeleteTags)+"\n")
+	}
+
+	if mu.Options.DryRun {
+		return
+	}
+
+	if !ok {
+		// Keep the operations left to retry
+		log.Operations = remainingOperations(log.Operations, undone)
+		if err = log.Save(OperationLogName); err != nil {
+			com.Println("\nUnable to save operation log :(", err)
+		}
+		return
+	}
+
+	os.Remove(OperationLogName)
+}
+
+// undoPlan returns the operations of ops to reverse, newest first. Commits on deleted branches don't need to be reverted
+func undoPlan(ops []Operation) (planned []Operation) {
+	createdBranches := make(map[string]bool)
+	for _, op := range ops {
+		if op.Type == OpBranch {
+			createdBranches[op.Library+"#"+op.Branch] = true
+		}
+	}
+
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Type == OpCommit && createdBranches[op.Library+"#"+op.Branch] {
+			continue
+		}
+
+		planned = append(planned, op)
+	}
+
+	return
+}
+
+// remainingOperations returns ops which weren't undone, in order
+func remainingOperations(ops, undone []Operation) (remaining []Operation) {
+	counts := make(map[Operation]int, len(undone))
+	for _, op := range undone {
+		counts[op]++
+	}
+
+	for _, op := range ops {
+		if counts[op] > 0 {
+			counts[op]--
+			continue
+		}
+
+		remaining = append(remaining, op)
+	}
+
+	return
+}
+
+// undoOperations reverses ops newest first, returning those undone and false if any couldn't be.
+// Libraries are undone at their path in paths if set (e.g. a worktree), otherwise their original path
+func (mu *MU) undoOperations(ops []Operation, paths map[string]string) (undone []Operation, ok bool) {
+	ok = true
+	for _, op := range undoPlan(ops) {
+		if mu.isClosed() {
+			// Keep log so undo can be finished
+			return undone, false
+		}
+
+		libPath := op.Library
+		if current, found := paths[op.Library]; found {
+			libPath = current
+		}
+
+		lib := LibraryFromPath(libPath)
+		if err := mu.undoOperation(*lib, op); err != nil {
+			lib.File.Error(err.Error())
+			ok = false
+			continue
+		}
+
+		undone = append(undone, op)
+	}
+
+	return
+}
+
+// rollback reverses every operation made this run after lib failed to sync
+func (mu *MU) rollback(lib Library, fileHead *sort.FileNode) {
+	mu.libraryMux.Lock()
+	var ops []Operation
+	if mu.operations != nil {
+		ops = append(ops, mu.operations.Operations...)
+	}
+	mu.libraryMux.Unlock()
+
+	com.Println("\n"+lib.File.GetGoURL(), "failed to sync. Rolling back", len(ops), "operation(s)...")
+
+	// Libs may be in worktrees
+	paths := make(map[string]string)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		paths[itr.File.OriginalPath()] = itr.File.Path
+	}
+
+	undone, ok := mu.undoOperations(ops, paths)
+	for _, op := range undone {
+		mu.addStat(&mu.Stats.RolledBackCount, &mu.Stats.RolledBackOutput, op.Library+" "+op.describe(mu.Options.DeleteTags)+"\n")
+	}
+
+	if !ok {
+		mu.libraryError(lib, fmt.Errorf("sync failed, unable to roll back every operation. Run undo to retry"))
+		return
+	}
+
+	mu.libraryError(lib, fmt.Errorf("sync failed, rolled back %d operation(s)", len(undone)))
+
+	// Nothing of this run left to undo, and rolled back libs must sync again rather than resume. Operations of
+	// earlier runs are kept
+	mu.libraryMux.Lock()
+	mu.operations = nil
+	if err := mu.saveOperations(); err != nil {
+		com.Println("\nUnable to save operation log :(", err)
+	}
+	mu.libraryMux.Unlock()
+	if mu.checkpoint != nil {
+		os.Remove(CheckpointName)
+	}
+}
+
+// syncFailed returns true if lib encountered an error or timed out while syncing
+func syncFailed(lib Library) bool {
+	return len(lib.File.Errors) > 0 || lib.File.TimedOut
+}
+
+// undoOperation reverses a single operation. Pushed version tags are only deleted if mu.Options.DeleteTags is set,
+// otherwise they're kept and count as undone
+func (mu *MU) undoOperation(lib Library, op Operation) (err error) {
+	switch op.Type {
+	case OpTag:
+		if op.versionTag() && !mu.Options.DeleteTags {
+			// Handled by keeping it, so the rest of the log can still be cleared
+			lib.File.Output("Keeping pushed version tag " + op.Tag + ", module consumers may depend on it. Set deleteTags to delete it")
+			return
+		}
+
+		lib.File.Output("Deleting tag " + op.Tag + "...")
+		lib.File.RunCmd("git", "tag", "-d", op.Tag)
+		err = lib.File.RunCmd("git", "push", com.PushRemote(), ":refs/tags/"+op.Tag)
+	case OpPullRequest:
+		lib.File.Output("Closing pull request " + op.PRURL + "...")
+		err = lib.File.ClosePullRequest(op.PRNumber)
+	case OpCommit:
+		lib.File.Output("Reverting commit " + op.Commit + "...")
+		if err = lib.File.CheckoutBranch(op.Branch); err != nil {
+			return
+		}
+		if err = lib.File.RunCmd("git", "revert", "--no-edit", op.Commit); err != nil {
+			return
+		}
+		err = lib.File.Push()
+	case OpBranch:
+		lib.File.Output("Deleting branch " + op.Branch + "...")
+		lib.File.CheckoutBranch(lib.File.DefaultBranch())
+		lib.File.RunCmd("git", "branch", "-D", op.Branch)
+		err = lib.File.RunCmd("git", "push", com.PushRemote(), "--delete", op.Branch)
+	}
+
+	return
+}
+
+// versionTag returns true if the operation's tag is a module version, e.g. v1.2.3 or sub/v1.2.3
+func (op Operation) versionTag() bool {
+	version := path.Base(op.Tag)
+	if !strings.HasPrefix(version, "v") {
+		return false
+	}
+
+	_, ok := parseSemver(version)
+	return ok
+}
+
+// plan returns a short description of undoing the operation
+func (op Operation) plan(deleteTags bool) string {
+	switch op.Type {
+	case OpTag:
+		if op.versionTag() && !deleteTags {
+			return "keep pushed version tag " + op.Tag + " (set deleteTags to delete it)"
+		}
+		return "delete tag " + op.Tag + " locally and on the remote"
+	case OpPullRequest:
+		return "close pull request " + op.PRURL
+	case OpCommit:
+		return "revert commit " + op.Commit + " and push it to " + op.Branch
+	case OpBranch:
+		return "delete branch " + op.Branch + " locally and on the remote"
+	}
+
+	return op.Type
+}
+
+// describe returns a short description of the undone operation
+func (op Operation) describe(deleteTags bool) string {
+	switch op.Type {
+	case OpTag:
+		if op.versionTag() && !deleteTags {
+			return "kept pushed version tag " + op.Tag
+		}
+		return "deleted tag " + op.Tag
+	case OpPullRequest:
+		return "closed pull request " + op.PRURL
+	case OpCommit:
+		return "reverted commit " + op.Commit
+	case OpBranch:
+		return "deleted branch " + op.Branch
+	}
+
+	return op.Type
+}
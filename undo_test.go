@@ -0,0 +1,163 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// loggedLibrary returns a recorded lib, with the working directory changed to a temporary one holding an operation
+// log of ops made in it
+func loggedLibrary(t *testing.T, ops ...Operation) (lib *Library, recorder *com.Recorder) {
+	lib, recorder = recordedLibrary(t)
+	// Undo reads libs from the log, so they use the global runner
+	com.SetRunner(recorder)
+	t.Cleanup(func() { com.SetRunner(nil) })
+
+	dir, err := ioutil.TempDir("", "gomu-oplog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	})
+
+	log := OperationLog{Action: "sync", Branch: "gomu-sync"}
+	for _, op := range ops {
+		op.Library = lib.File.Path
+		log.Operations = append(log.Operations, op)
+	}
+	if err = log.Save(OperationLogName); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestUndo(t *testing.T) {
+	_, recorder := loggedLibrary(t,
+		Operation{Type: OpBranch, Branch: "gomu-sync"},
+		Operation{Type: OpCommit, Branch: "gomu-sync", Commit: "abc"},
+		Operation{Type: OpCommit, Branch: "master", Commit: "def"},
+		Operation{Type: OpTag, Tag: "release"},
+	)
+
+	mu := &MU{Options: Options{IgnoreWarning: true}}
+	mu.undo()
+
+	// Newest first, commits on deleted branches aren't reverted
+	expected := []string{
+		"git tag -d release",
+		"git push origin :refs/tags/release",
+		"git checkout master",
+		"git revert --no-edit def",
+		"git push -u origin",
+		"git remote get-url origin",
+		"git remote get-url upstream",
+		"git checkout master",
+		"git branch -D gomu-sync",
+		"git push origin --delete gomu-sync",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if _, err := os.Stat(OperationLogName); !os.IsNotExist(err) {
+		t.Errorf("expected operation log to be removed, got %v", err)
+	}
+}
+
+func TestUndoKeepsVersionTags(t *testing.T) {
+	_, recorder := loggedLibrary(t, Operation{Type: OpTag, Tag: "v1.2.0"})
+
+	mu := &MU{Options: Options{IgnoreWarning: true}}
+	mu.undo()
+
+	if recorder.Ran("git tag -d") || recorder.Ran("git push") {
+		t.Errorf("expected version tag to be kept, ran %q", commandLines(recorder))
+	}
+
+	// Kept tags are handled, so nothing is left to retry
+	if _, err := os.Stat(OperationLogName); !os.IsNotExist(err) {
+		t.Errorf("expected operation log to be removed, got %v", err)
+	}
+	if !strings.Contains(mu.Stats.UpdatedOutput, "kept pushed version tag v1.2.0") {
+		t.Errorf("expected kept tag to be reported, got %q", mu.Stats.UpdatedOutput)
+	}
+}
+
+func TestUndoFails(t *testing.T) {
+	_, recorder := loggedLibrary(t,
+		Operation{Type: OpTag, Tag: "release"},
+		Operation{Type: OpTag, Tag: "nightly"},
+	)
+	recorder.Reply("git push origin :refs/tags/release", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{IgnoreWarning: true}}
+	mu.undo()
+
+	// The operation which failed is kept to retry
+	log, err := LoadOperationLog(OperationLogName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Operations) != 1 || log.Operations[0].Tag != "release" {
+		t.Errorf("expected release tag to be left, got %+v", log.Operations)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	lib.File.Error("go build failed")
+
+	mu := &MU{Options: Options{Atomic: true}}
+	mu.operations = &OperationLog{Operations: []Operation{
+		{Library: lib.File.Path, Type: OpCommit, Branch: "master", Commit: "abc"},
+		{Library: lib.File.Path, Type: OpTag, Tag: "release"},
+	}}
+	if !syncFailed(*lib) {
+		t.Fatal("expected lib with errors to have failed")
+	}
+	mu.rollback(*lib, &sort.FileNode{File: lib.File})
+
+	if !recorder.Ran("git tag -d release") || !recorder.Ran("git revert --no-edit abc") {
+		t.Errorf("expected the run's operations to be undone, ran %q", commandLines(recorder))
+	}
+	if mu.Stats.RolledBackCount != 2 || len(mu.Errors) != 1 {
+		t.Errorf("expected 2 operations rolled back and the failure reported, got %d and %v", mu.Stats.RolledBackCount, mu.Errors)
+	}
+	if mu.operations != nil {
+		t.Errorf("expected nothing left to undo, got %+v", mu.operations)
+	}
+	if _, err := os.Stat(OperationLogName); !os.IsNotExist(err) {
+		t.Errorf("expected operation log to be removed, got %v", err)
+	}
+}
+
+func TestRollbackFails(t *testing.T) {
+	lib, recorder := loggedLibrary(t)
+	recorder.Reply("git push origin :refs/tags/release", com.Reply{ExitCode: 1})
+	lib.File.TimedOut = true
+
+	mu := &MU{Options: Options{Atomic: true}}
+	mu.operations = &OperationLog{Operations: []Operation{{Library: lib.File.Path, Type: OpTag, Tag: "release"}}}
+	mu.rollback(*lib, &sort.FileNode{File: lib.File})
+
+	// Kept to undo later
+	if mu.operations == nil || len(mu.Errors) != 1 || !strings.Contains(mu.Errors[0].Error(), "Run undo to retry") {
+		t.Errorf("expected failed rollback to be left to undo, got %+v and %v", mu.operations, mu.Errors)
+	}
+}
@@ -2,13 +2,12 @@ package gomu
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path"
-	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gomuserver/mod-utils/com"
 	"github.com/gomuserver/mod-utils/sort"
@@ -18,7 +17,7 @@ import (
 // exitWithErrorMessage prints message and exits
 func exitWithErrorMessage(message string) {
 	com.Println(message)
-	exit(1)
+	exit(ExitConfigError)
 }
 
 // exit shows help then exits with status prints message and exits
@@ -30,7 +29,7 @@ func exit(status int) {
 func showWarningOrQuit(message string) {
 	if !ShowWarning(message) {
 		com.Println("Exiting...")
-		exit(0)
+		exit(ExitAborted)
 	}
 }
 
@@ -66,11 +65,188 @@ func ShowWarning(message string) (ok bool) {
 	return
 }
 
+// showWarning shows the warning message like ShowWarning. Json output can't show the warning or wait for an answer,
+// so the warning is declined unless mu.Options.IgnoreWarning is set
+func (mu *MU) showWarning(message string) (ok bool) {
+	if mu.Options.Output == "json" {
+		com.Println(strings.TrimSpace(message), ErrUnconfirmed)
+		return false
+	}
+
+	return ShowWarning(message)
+}
+
+// declinedError returns the error recorded when the warning of an action isn't confirmed
+func (mu *MU) declinedError() error {
+	if mu.Options.Output == "json" {
+		return ErrUnconfirmed
+	}
+
+	return ErrAborted
+}
+
+// confirm shows the action's warning listing actions, then waits for the user to confirm unless warnings are ignored
+// or it's a dry run. A declined warning is recorded as an error
+func (mu *MU) confirm(action string, actions []string) (ok bool) {
+	com.Println("\n" + mu.Options.actionWarning(action, actions...))
+	if mu.Options.IgnoreWarning || mu.Options.DryRun || mu.showWarning("\nIs this ok?") {
+		return true
+	}
+
+	// Stashes are restored while cleaning
+	mu.Errors = append(mu.Errors, mu.declinedError())
+	return false
+}
+
+// isClosed returns true once the run has been cancelled or finished, so no more libs are started
+func (mu *MU) isClosed() bool {
+	// Libraries may be used without a run
+	return mu.ctx != nil && mu.ctx.Err() != nil
+}
+
+// isAborted returns true once the run's deadline has passed, so in-flight libs stop between steps.
+// Libs in flight when the run is otherwise closed are finished
+func (mu *MU) isAborted() bool {
+	return mu.ctx != nil && mu.ctx.Err() == context.DeadlineExceeded
+}
+
+// addStat increments count and appends a numbered line to output. Safe to call from concurrent libs
+func (mu *MU) addStat(count *int, output *string, line string) {
+	mu.statsMux.Lock()
+	defer mu.statsMux.Unlock()
+
+	*count++
+	*output += strconv.Itoa(*count) + ") " + line
+}
+
+// startLibrary attributes lib's errors to the action, and bounds commands run for lib by the library timeout and run deadline
+// Note: cancelling the run does not interrupt in-flight commands, only deadlines do
+func (mu *MU) startLibrary(lib Library) {
+	lib.File.Step = mu.Options.Action
+
+	var deadline time.Time
+	if runDeadline, ok := mu.ctx.Deadline(); ok {
+		deadline = runDeadline
+	}
+
+	if mu.Options.LibraryTimeout > 0 {
+		libraryDeadline := time.Now().Add(mu.Options.LibraryTimeout)
+		if deadline.IsZero() || libraryDeadline.Before(deadline) {
+			deadline = libraryDeadline
+		}
+	}
+
+	if deadline.IsZero() {
+		// Unbounded
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	lib.File.SetContext(ctx)
+
+	mu.libraryMux.Lock()
+	mu.libraryCancels = append(mu.libraryCancels, cancel)
+	mu.libraryMux.Unlock()
+}
+
+// setStep attributes lib's errors to step, tracing it if exporting telemetry
+func (mu *MU) setStep(lib Library, step string) {
+	lib.File.Step = step
+	if mu.telemetry != nil {
+		mu.telemetry.step(lib, step)
+	}
+}
+
+// beginLibrary prints the position of lib within the run, returning a func to call once lib is finished which records
+// its duration
+func (mu *MU) beginLibrary(index int, lib Library) (done func()) {
+	started := time.Now()
+	if mu.telemetry != nil {
+		mu.telemetry.startLibrary(lib, mu.Options.Action)
+	}
+
+	branch := mu.branch(lib)
+	mu.libraryMux.Lock()
+	if mu.inFlight == nil {
+		mu.inFlight = make(map[string]string)
+	}
+	mu.inFlight[lib.File.Path] = branch
+	mu.libraryMux.Unlock()
+
+	if logPath := lib.File.LogPath(); len(logPath) > 0 && mu.progress == nil {
+		// Output is in the lib's log, print one status line once finished
+		return func() {
+			mu.finishLibrary(lib, started)
+			status := "done"
+			if syncFailed(lib) || lib.File.TestFailed {
+				status = "failed"
+			}
+			com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path, status, "->", logPath)
+		}
+	}
+
+	if mu.progress == nil {
+		// Separate output
+		com.Println("")
+		com.Println("(", index, "/", mu.Stats.DepCount, ")", lib.File.Path)
+		return func() {
+			mu.finishLibrary(lib, started)
+		}
+	}
+
+	mu.progress.Start(index, lib.File.Path)
+	return func() {
+		mu.finishLibrary(lib, started)
+		mu.progress.Done(lib.File.Path, syncFailed(lib) || lib.File.TestFailed)
+	}
+}
+
+// finishLibrary records lib's duration and that it's no longer in flight
+func (mu *MU) finishLibrary(lib Library, started time.Time) {
+	lib.File.Duration = time.Since(started)
+	if mu.telemetry != nil {
+		mu.telemetry.finishLibrary(lib, syncFailed(lib) || lib.File.TestFailed)
+	}
+
+	mu.libraryMux.Lock()
+	delete(mu.inFlight, lib.File.Path)
+	mu.libraryMux.Unlock()
+}
+
+// performConcurrently calls perform with lib once waiter has a free worker, for actions whose libs don't depend on
+// each other's results. Skipped if cancelled while waiting or closed before starting
+func (mu *MU) performConcurrently(waiter *sizedwaitgroup.SizedWaitGroup, index int, lib Library, perform func(lib Library)) {
+	if waiter.AddWithContext(mu.ctx) != nil {
+		// Cancelled while waiting for a worker
+		return
+	}
+
+	go func() {
+		defer waiter.Done()
+		if mu.isClosed() {
+			return
+		}
+		mu.startLibrary(lib)
+		defer mu.beginLibrary(index, lib)()
+
+		perform(lib)
+	}()
+}
+
+// cancelLibraries releases all library timeouts
+func (mu *MU) cancelLibraries() {
+	mu.libraryMux.Lock()
+	defer mu.libraryMux.Unlock()
+
+	for _, cancel := range mu.libraryCancels {
+		cancel()
+	}
+	mu.libraryCancels = nil
+}
+
 // Then handles cleanup after func
 func cleanupStash(libs sort.StringArray) {
-	closed = true
-
-	waiter := sizedwaitgroup.New(runtime.GOMAXPROCS(0))
+	waiter := sizedwaitgroup.New(com.MaxConcurrency())
 
 	// Resume working directory
 	var f com.FileWrapper
@@ -90,77 +266,325 @@ func cleanupStash(libs sort.StringArray) {
 	waiter.Wait()
 }
 
-func (mu *MU) sync(lib Library, commitTitle, commitMessage string) {
+func (mu *MU) sync(lib Library, commitTitle, commitMessage string) (result SyncResult, err error) {
+	head := lib.File.HeadCommit()
+	lib.pins = mu.pinsFor(lib)
+	lib.trust = mu.tagTrust()
+	lib.align = mu.alignments
+	lib.verifySums = mu.Options.VerifySums
+	if err = mu.setCommitKey(&lib); err != nil {
+		// Don't commit unsigned when signing was requested
+		mu.libraryError(lib, err)
+		return
+	}
+
 	// Update the dep if necessary
-	if err := lib.ModUpdate(mu.Options.Branch, commitTitle+"\n"+commitMessage); err == nil {
-		// Dep was updated
-		lib.File.Updated = true
-		mu.Stats.UpdateCount++
-		mu.Stats.UpdatedOutput += strconv.Itoa(mu.Stats.UpdateCount) + ") " + lib.File.GetGoURL() + "\n"
+	if err = lib.ModUpdate(mu.branch(lib), commitTitle+"\n"+commitMessage, mu.replacePolicy()); err != nil {
+		return
+	}
+
+	// Dep was updated
+	mu.recordCommit(lib, head)
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+"\n")
+
+	result.Updated = true
+	if commit := lib.File.HeadCommit(); commit != head {
+		result.Commit = commit
 	}
+
+	return
+}
+
+// replacePolicy returns how local replace directives are handled when syncing
+func (mu *MU) replacePolicy() ReplacePolicy {
+	return ReplacePolicy{Drop: mu.Options.DropReplace, Restore: mu.Options.RestoreReplace}
 }
 
 func (mu *MU) pullRequest(lib Library, branch, commitTitle, commitMessage string) (err error) {
-	if mu.Options.PullRequest {
-		if len(branch) == 0 {
-			branch, err = lib.File.CurrentBranch()
-			if err != nil {
-				return
-			}
+	if mu.libraryOptions(lib).PullRequest {
+		err = mu.requestPR(lib, branch, commitTitle, commitMessage)
+	}
+
+	return
+}
+
+func (mu *MU) openPR(lib Library, branch, commitTitle, commitMessage string) (result PRResult, err error) {
+	if len(branch) == 0 {
+		if branch, err = lib.File.CurrentBranch(); err != nil {
+			return
 		}
+	}
 
-		lib.File.Output("Attempting Pull Request " + branch + " to master...")
+	target := "master"
+	if len(lib.File.ProtectedBranch) > 0 {
+		// Changes were redirected from the protected branch
+		target = lib.File.ProtectedBranch
+	} else if base := mu.baseBranch(lib); len(base) > 0 {
+		target = base
+	} else if !mu.Options.DryRun {
+		target = lib.File.DefaultBranch()
+	}
 
-		resp, err := lib.File.PullRequest(commitTitle, commitMessage, branch, "master")
-		if err == nil {
-			mu.Stats.PRCount++
-			mu.Stats.PROutput += resp.URL + "\n"
-			lib.File.PROpened = true
-			lib.File.Output("PR Created!")
-		} else {
-			if resp == nil || len(resp.Errors) == 0 {
-				lib.File.Output("Failed to create PR :( " + err.Error())
-
-			} else if strings.HasPrefix(resp.Errors[0].Message, "No commits between master and") {
-				// No PR to create
-			} else if strings.HasPrefix(resp.Errors[0].Message, "A pull request already exists for") {
-				// PR Exists
-			} else {
-				lib.File.Output("Failed to create PR :(")
+	lib.File.Output("Attempting Pull Request " + branch + " to " + target + "...")
+
+	if len(mu.Options.PRTemplate) > 0 {
+		var title, body string
+		if title, body, err = mu.renderPRTemplate(lib, branch, target, commitTitle, commitMessage); err != nil {
+			lib.File.Error(err.Error())
+			return
+		}
+
+		commitTitle, commitMessage = title, body
+	}
+
+	batched := mu.batchedFor(lib)
+	if batched != nil {
+		batched.title, batched.body = commitTitle, commitMessage
+		if len(mu.batch) > 1 {
+			// Linked to the rest once every pull request is open
+			commitMessage += mu.batchSection(batched, mu.batch)
+		}
+	}
+
+	options := mu.libraryOptions(lib)
+	resp, err := lib.File.PullRequest(commitTitle, commitMessage, branch, target, options.DraftPR)
+	if err == nil {
+		result = PRResult{Opened: true, Draft: options.DraftPR, URL: resp.URL, Number: resp.Number}
+		if batched != nil {
+			batched.url, batched.number = resp.URL, resp.Number
+		}
+		lib.File.PROpened = true
+		lib.File.PRURL = resp.URL
+		mu.recordOperation(lib, Operation{Type: OpPullRequest, PRNumber: resp.Number, PRURL: resp.URL})
+		lib.File.Output("PR Created!")
+		mu.runHooks(HookPostPR, lib, "")
+
+		if options.AutoMerge {
+			result.AutoMerge = mu.autoMerge(lib, options, *resp)
+		}
+
+		if mu.waitsForChecks() && !result.Draft {
+			result.Checks = mu.waitChecks(lib, *resp)
+			if mu.Options.MergeGreen && !result.AutoMerge && result.Checks == com.CheckSuccess {
+				result.Merged = mu.mergeGreen(lib, *resp)
 			}
 		}
+
+		line := resp.URL
+		if result.Draft {
+			line += " (draft)"
+		} else if result.Merged {
+			line += " (merged)"
+		} else if result.AutoMerge {
+			line += " (auto-merge)"
+		}
+
+		mu.statsMux.Lock()
+		mu.Stats.PRCount++
+		mu.Stats.PROutput += line + "\n"
+		mu.statsMux.Unlock()
+		return
+	}
+
+	if resp == nil || len(resp.Errors) == 0 {
+		lib.File.Output("Failed to create PR :( " + err.Error())
+
+	} else if strings.HasPrefix(resp.Errors[0].Message, "No commits between "+target+" and") {
+		// No PR to create
+		err = nil
+	} else if strings.HasPrefix(resp.Errors[0].Message, "A pull request already exists for") ||
+		strings.HasPrefix(resp.Errors[0].Message, "Another open merge request already exists") {
+		// PR Exists
+		result.Exists = true
+		err = nil
+	} else {
+		lib.File.Output("Failed to create PR :(")
 	}
 
 	return
 }
 
+// autoMerge enables auto-merge on lib's new pull request, opened with lib's options, returning false if unable
+func (mu *MU) autoMerge(lib Library, options Options, pr com.PRResponse) bool {
+	if options.DraftPR {
+		lib.File.Output("Draft pull requests can't auto-merge, skipping.")
+		return false
+	}
+
+	method, ok := mu.mergeMethod(lib)
+	if !ok {
+		return false
+	}
+
+	if err := lib.File.EnableAutoMerge(pr, method); err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to enable auto-merge: %v", err))
+		return false
+	}
+
+	lib.File.Output("Auto-merge enabled (" + method + ")!")
+	return true
+}
+
 func (mu *MU) tag(lib Library) {
-	if !mu.Options.Tag {
+	if !mu.libraryOptions(lib).Tag {
 		// Ignore tagging entirely
 		return
 	}
 
+	mu.tagLibrary(lib)
+}
+
+func (mu *MU) tagLibrary(lib Library) (result TagResult, err error) {
 	if lib.File.Version != "" {
 		// Tag already set
+		result.Tag = lib.File.Version
 		return
 	}
 
+	version := mu.Options.SetVersion
+	shouldTag := len(version) > 0
+	if mu.plan != nil {
+		// Tag exactly as planned
+		planned, _ := mu.plan.library(lib.File.OriginalPath())
+		version = planned.NextVersion
+		shouldTag = len(version) > 0
+	} else if !shouldTag {
+		shouldTag = lib.ShouldTag()
+	}
+
 	// Tag if forced or if able to increment
-	if mu.Options.Tag && (len(mu.Options.SetVersion) > 0 || lib.ShouldTag()) {
-		newTag := lib.TagLib(mu.Options.SetVersion)
+	if shouldTag {
+		previousVersion := lib.GetLatestTag()
+		if len(version) == 0 && mu.plan == nil {
+			if version, err = mu.nextVersion(lib, previousVersion); err != nil {
+				mu.libraryError(lib, err)
+				return
+			}
+		}
+
+		if err = mu.runHooks(HookPreTag, lib, version); err != nil {
+			return
+		}
+
+		var notes string
+		if mu.Options.Changelog {
+			if len(version) == 0 {
+				// Version is needed before tagging so the changelog is included in the tag
+				version = incrementPatch(previousVersion)
+			}
+
+			if len(version) > 0 {
+				notes = mu.changelog(lib, version)
+			}
+		}
+
+		// Without a changelog, notes list the changes since the previous tag once the new tag is known
+		var changes []ChangeEntry
+		releasing := mu.Options.CreateRelease && !mu.Options.Changelog
+		if releasing {
+			var changesErr error
+			if changes, changesErr = lib.Changes(); changesErr != nil {
+				lib.File.Error("Unable to generate release notes :( " + changesErr.Error())
+				releasing = false
+			}
+		}
+
+		var newTag string
+		if mu.Options.SignTags {
+			if newTag, err = lib.SignTag(version, mu.Options.SigningKey); err != nil {
+				mu.libraryError(lib, err)
+			}
+		} else {
+			newTag = lib.TagLib(version)
+		}
 
 		if len(newTag) > 0 {
+			mu.recordOperation(lib, Operation{Type: OpTag, Tag: lib.TagName(newTag)})
+			lib.File.PreviousVersion = previousVersion
 			lib.File.Version = newTag
 			lib.File.Tagged = true
-			mu.Stats.TagCount++
-			mu.Stats.TaggedOutput += strconv.Itoa(mu.Stats.TagCount) + ") " + lib.File.GetGoURL() + " " + lib.File.Version + "\n"
+			lib.File.TagSigned = mu.Options.SignTags
+
+			line := lib.File.GetGoURL() + " " + lib.File.Version
+			if lib.File.TagSigned {
+				line += " (signed)"
+			}
+			mu.addStat(&mu.Stats.TagCount, &mu.Stats.TaggedOutput, line+"\n")
+
+			if releasing {
+				notes = formatReleaseNotes(lib.File.Version, changes)
+			}
+
+			if len(notes) > 0 {
+				mu.release(lib, notes)
+			}
+
+			result.Created = true
+			result.Signed = lib.File.TagSigned
 		}
 	}
 
 	// Set tag for next lib if not set
 	if len(lib.File.Version) == 0 {
-		lib.File.Version = lib.GetLatestTag()
+		lib.File.Version = lib.LatestVersion()
+	}
+
+	result.Tag = lib.File.Version
+	return
+}
+
+// bumpStrategy returns the bump strategy for lib, from the first matching bump rule or the default strategy
+func (mu *MU) bumpStrategy(lib Library) (strategy string, err error) {
+	values := []string{lib.File.GetGoURL(), lib.File.OriginalPath()}
+	for _, rule := range mu.Options.BumpRules {
+		index := strings.LastIndex(rule, "=")
+		if index <= 0 {
+			err = fmt.Errorf("invalid bump rule %q, expected pattern=strategy", rule)
+			return
+		}
+
+		var patterns []libPattern
+		if patterns, err = compilePatterns([]string{rule[:index]}); err != nil {
+			return
+		}
+
+		if patterns[0].matches(values...) {
+			return rule[index+1:], nil
+		}
+	}
+
+	return mu.Options.BumpStrategy, nil
+}
+
+// nextVersion returns the version lib should be tagged with when incrementing from latest.
+// Returns an empty version if no strategy is set or lib is untagged, leaving git-tagger to increment
+func (mu *MU) nextVersion(lib Library, latest string) (version string, err error) {
+	strategy, err := mu.bumpStrategy(lib)
+	if err != nil || len(strategy) == 0 || len(latest) == 0 {
+		// Initial tags are left to git-tagger
+		return
+	}
+
+	switch strategy {
+	case BumpAuto:
+		if strategy, err = lib.AutoBump(latest); err != nil {
+			err = fmt.Errorf("unable to determine version bump: %v", err)
+			return
+		}
+	case BumpMajor, BumpMinor, BumpPatch:
+	default:
+		err = fmt.Errorf("unknown bump strategy %q, expected major, minor, patch or auto", strategy)
+		return
+	}
+
+	if version = incrementVersion(latest, strategy); len(version) == 0 {
+		err = fmt.Errorf("unable to increment tag %q", latest)
+		return
 	}
+
+	lib.File.Output("Bumping " + strategy + " version " + latest + " -> " + version)
+	return
 }
 
 func (mu *MU) removeBranchIfUnused(lib Library) {
@@ -169,31 +593,43 @@ func (mu *MU) removeBranchIfUnused(lib Library) {
 		return
 	}
 
+	branch, base := mu.syncBranch(lib)
+
 	// Check if created a branch we didn't need
 	if !lib.File.Updated && !lib.File.Committed && !lib.File.PROpened {
-		switch mu.Options.Branch {
+		switch branch {
 		case "master", "develop", "staging", "beta", "prod", "":
 			// Ignore protected branches and empty branch
 		default:
 			// Delete branch
-			lib.File.CheckoutBranch("master")
-			if lib.File.RunCmd("git", "branch", "-D", mu.Options.Branch) == nil {
+			lib.File.CheckoutBranch(base)
+			if lib.File.RunCmd("git", "branch", "-D", branch) == nil {
 				// No longer needed
 				lib.File.BranchCreated = false
 
-				lib.File.RunCmd("git", "push", "origin", "--delete", mu.Options.Branch)
-				if !closed {
+				lib.File.RunCmd("git", "push", com.PushRemote(), "--delete", branch)
+				if !mu.isClosed() {
 					lib.File.Output("Newly created branch did not update. Deleted unused branch")
 				}
 			}
 		}
 	} else {
-		mu.Stats.CreatedCount++
-		mu.Stats.CreatedOutput += strconv.Itoa(mu.Stats.CreatedCount) + ") " + lib.File.Path + "#" + mu.Options.Branch + "\n"
+		mu.recordOperation(lib, Operation{Type: OpBranch, Branch: branch})
+		mu.addStat(&mu.Stats.CreatedCount, &mu.Stats.CreatedOutput, lib.File.OriginalPath()+"#"+branch+"\n")
 	}
 }
 
+// getCommitDetails returns the commit title and message of lib's synced deps, rendered from the commit template if set
 func (mu *MU) getCommitDetails(lib Library) (commitTitle, commitMessage string) {
+	if len(mu.Options.CommitTemplate) > 0 {
+		title, message, err := mu.renderCommitTemplate(lib)
+		if err == nil {
+			return title, message
+		}
+
+		lib.File.Error(err.Error() + ", using default commit message")
+	}
+
 	commitTitle = mu.Options.CommitMessage
 	if len(commitTitle) == 0 {
 		commitTitle = "Update Mod Files"
@@ -214,16 +650,49 @@ func (mu *MU) getCommitDetails(lib Library) (commitTitle, commitMessage string)
 	return
 }
 
+// setCommitKey resolves the key lib's commits are signed with if commits are signed
+func (mu *MU) setCommitKey(lib *Library) (err error) {
+	if mu.Options.SignCommits {
+		lib.commitKey, err = lib.signingKey("commit", mu.Options.SigningKey)
+	}
+
+	return
+}
+
 func (mu *MU) commit(lib Library) {
 	if mu.Options.Commit {
-		lib.File.Output("Checking for local changes...")
-		lib.File.Committed = lib.ModDeploy("", mu.Options.CommitMessage)
+		mu.commitLibrary(lib)
+	}
+}
+
+func (mu *MU) commitLibrary(lib Library) (result CommitResult) {
+	lib.File.Output("Checking for local changes...")
 
-		if lib.File.Committed {
-			mu.Stats.CommitCount++
-			mu.Stats.DeployedOutput += strconv.Itoa(mu.Stats.CommitCount) + ") " + lib.File.GetGoURL() + "\n"
+	if err := mu.setCommitKey(&lib); err != nil {
+		// Don't commit unsigned when signing was requested
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	lib.File.Committed = lib.ModDeploy("", mu.Options.CommitMessage, lib.commitKey)
+
+	if lib.File.Committed {
+		lib.File.CommitSigned = mu.Options.SignCommits
+		mu.recordCommit(lib, head)
+
+		line := lib.File.GetGoURL()
+		if lib.File.CommitSigned {
+			line += " (signed)"
 		}
+		mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, line+"\n")
+
+		result.Committed = true
+		result.Commit = lib.File.HeadCommit()
+		result.Signed = lib.File.CommitSigned
 	}
+
+	return
 }
 
 func (mu *MU) replace(lib Library, fileHead *sort.FileNode) {
@@ -240,8 +709,7 @@ func (mu *MU) replace(lib Library, fileHead *sort.FileNode) {
 		// Append local replacements for all libs in lib.updatedDeps
 		if lib.ModReplaceLocal() {
 			lib.File.Updated = true
-			mu.Stats.UpdateCount++
-			mu.Stats.UpdatedOutput += strconv.Itoa(mu.Stats.UpdateCount) + ") " + lib.File.Path + "\n"
+			mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.OriginalPath()+"\n")
 
 			lib.File.Output("Local replacements set!")
 		} else {
@@ -250,30 +718,6 @@ func (mu *MU) replace(lib Library, fileHead *sort.FileNode) {
 	}
 }
 
-func (mu *MU) addSecret(lib Library) (err error) {
-	// Get secret name from filepath
-	_, secretName := path.Split(mu.Options.SourcePath)
-	secretFile, err := os.Open(mu.Options.SourcePath)
-	if err != nil {
-		return err
-	}
-	defer secretFile.Close()
-
-	// Read secret from file
-	var body []byte
-	if body, err = ioutil.ReadAll(secretFile); err != nil {
-		return
-	}
-
-	// Set secret with filename on repo
-	if err = lib.File.AddSecret(secretName, string(body)); err != nil {
-		lib.File.Output("Unable to add secret :(")
-		return
-	}
-
-	return
-}
-
 func (mu *MU) test(lib Library, fileHead *sort.FileNode) (err error) {
 	if lib.File.StashPop() {
 		// Local changes exist
@@ -290,28 +734,41 @@ func (mu *MU) test(lib Library, fileHead *sort.FileNode) (err error) {
 
 	lib.File.Output("Building...")
 	// Try building
-	if err = lib.File.RunCmd("go", "build", "-o", "test-out.o"); err != nil {
+	if err = lib.File.RunCmd(lib.File.GoBinary(), "build", "-o", "test-out.o"); err != nil {
 		err = nil
 		// Try plugin mode
-		if err = lib.File.RunCmd("go", "build", "-buildmode=plugin", "-o", "test-out.o"); err != nil {
+		if err = lib.File.RunCmd(lib.File.GoBinary(), "build", "-buildmode=plugin", "-o", "test-out.o"); err != nil {
 			lib.File.Output("Build failed :(")
+			mu.recordTests([]PackageResult{buildFailure(lib)}, "")
 			lib.File.TestFailed = true
-			mu.Stats.TestFailedCount++
-			mu.Stats.TestFailedOutput += strconv.Itoa(mu.Stats.TestFailedCount) + ") " + lib.File.Path
-
-			mu.Stats.TestFailedOutput += "\n"
+			mu.addStat(&mu.Stats.TestFailedCount, &mu.Stats.TestFailedOutput, lib.File.OriginalPath()+"\n   - build failed\n")
 			return
 		}
 	}
 
 	lib.File.Output("Build Succeeded!")
-	lib.File.RunCmd("rm", "test-out.o")
+	lib.File.RemoveFile("test-out.o")
 
 	lib.File.Output("Testing...")
-	output, err := lib.File.CmdOutput("go", "test")
+	packages, coverProfile, failed, err := lib.Test()
+	if err != nil {
+		lib.File.Error("Unable to run tests :( " + err.Error())
+		failed = true
+		err = nil
+	}
 
-	if err == nil {
-		if strings.Contains(output, "PASS") {
+	for _, pkg := range packages {
+		lib.File.Output(pkg.String())
+	}
+	mu.recordTests(packages, coverProfile)
+
+	if !failed {
+		passed := 0
+		for _, pkg := range packages {
+			passed += pkg.Passed
+		}
+
+		if passed > 0 {
 			lib.File.Output("Test Passed!")
 		} else {
 			lib.File.Output("No tests to run.")
@@ -322,27 +779,126 @@ func (mu *MU) test(lib Library, fileHead *sort.FileNode) (err error) {
 
 		// Tag failures as updated for stats
 		lib.File.TestFailed = true
-		mu.Stats.TestFailedCount++
-		mu.Stats.TestFailedOutput += strconv.Itoa(mu.Stats.TestFailedCount) + ") " + lib.File.Path
-
-		mu.Stats.TestFailedOutput += "\n"
+		mu.addStat(&mu.Stats.TestFailedCount, &mu.Stats.TestFailedOutput, lib.File.OriginalPath()+"\n"+failedPackages(packages))
 	}
 
 	return
 }
 
+// testLevels tests each dependency level in order, testing libs within a level concurrently.
+// Returns false if the run was closed before finishing
+func (mu *MU) testLevels(fileHead *sort.FileNode) (finished bool) {
+	levels := sort.Levels(fileHead, mu.Options.DirectImport)
+
+	index := 0
+	for depth, level := range levels {
+		com.Println("\nTesting level", depth+1, "/", len(levels), "with", len(level), "lib(s)...")
+
+		// Aggregate deps before starting so libs in this level aren't read while testing
+		libs := make([]Library, len(level))
+		for i, node := range level {
+			libs[i].File = node.File
+			libs[i].ModAddDeps(fileHead, false)
+		}
+
+		waiter := sizedwaitgroup.New(com.MaxConcurrency())
+		for _, lib := range libs {
+			index++
+			if waiter.AddWithContext(mu.ctx) != nil {
+				// Cancelled while waiting for a worker
+				break
+			}
+
+			go func(index int, lib Library) {
+				defer waiter.Done()
+				defer mu.beginLibrary(index, lib)()
+
+				mu.startLibrary(lib)
+				mu.test(lib, fileHead)
+			}(index, lib)
+		}
+
+		waiter.Wait()
+
+		if mu.isClosed() {
+			return
+		}
+	}
+
+	return true
+}
+
+func (mu *MU) vendor(lib Library) {
+	lib.File.Output("Vendoring deps...")
+
+	if err := lib.Vendor(); err != nil {
+		lib.File.Error("Vendor failed :(")
+		return
+	}
+
+	if !lib.File.HasChangesIn("vendor") {
+		lib.File.Output("Vendor directory up to date!")
+		return
+	}
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.OriginalPath()+"\n")
+
+	if mu.Options.VerifyVendor {
+		lib.File.Error("Vendor directory out of date!")
+		return
+	}
+
+	if !mu.Options.Commit {
+		lib.File.Output("Vendor directory updated!")
+		return
+	}
+
+	message := mu.Options.CommitMessage
+	if len(message) == 0 {
+		message = "Update vendored deps"
+	}
+
+	if err := mu.setCommitKey(&lib); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if lib.File.Add("vendor") != nil || lib.commit("gomu: "+message) != nil {
+		lib.File.Error("Failed to commit vendor directory :(")
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	if lib.File.Push() != nil {
+		lib.File.Error("Push failed :( check local changes and commit status")
+		return
+	}
+
+	lib.File.Committed = true
+	mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, lib.File.GetGoURL()+"\n")
+	lib.File.Output("Vendor directory committed!")
+}
+
 func (mu *MU) reset(lib Library) {
-	if len(mu.Options.Branch) > 0 {
-		lib.File.Output("Reverting mod files to <" + mu.Options.Branch + "> ref...")
+	branch := mu.branch(lib)
+	if len(branch) > 0 {
+		lib.File.Output("Reverting mod files to <" + branch + "> ref...")
 	} else {
 		lib.File.Output("Reverting mod files to last-committed ref...")
 	}
 
 	lib.File.StashPop()
 
-	// Revert any changes to mod files
-	lib.File.RunCmd("git", "checkout", mu.Options.Branch, "go.mod")
-	lib.File.RunCmd("git", "checkout", mu.Options.Branch, "go.sum")
+	// Revert any changes to mod files, from the index if no branch is set as an empty ref isn't valid
+	for _, name := range []string{"go.mod", "go.sum"} {
+		if len(branch) > 0 {
+			lib.File.RunCmd("git", "checkout", branch, "--", name)
+		} else {
+			lib.File.RunCmd("git", "checkout", "--", name)
+		}
+	}
 
 	lib.File.Output("Reverted mod files!")
 
@@ -351,13 +907,41 @@ func (mu *MU) reset(lib Library) {
 	}
 }
 
+func (mu *MU) graph(fileHead *sort.FileNode) {
+	graph := sort.GraphFrom(fileHead, mu.Options.DirectImport)
+
+	var output string
+	var err error
+	switch mu.Options.GraphFormat {
+	case "", "dot":
+		output = graph.DOT()
+	case "mermaid":
+		output = graph.Mermaid()
+	case "json":
+		output, err = graph.JSON()
+	default:
+		err = fmt.Errorf("unsupported graph format %s", mu.Options.GraphFormat)
+	}
+
+	if err != nil {
+		mu.Errors = append(mu.Errors, err)
+		return
+	}
+
+	// Print regardless of log level so graph can be piped, status lines are on stderr
+	fmt.Println(output)
+}
+
 func (mu *MU) pull(lib Library) {
 	// Check out branch if provided
-	if len(mu.Options.Branch) > 0 {
-		lib.File.Output("Checking out " + mu.Options.Branch + "...")
+	branch := mu.branch(lib)
+	if len(branch) > 0 {
+		lib.File.Output("Checking out " + branch + "...")
 
-		if lib.File.CheckoutBranch(mu.Options.Branch) != nil {
+		if lib.File.CheckoutBranch(branch) != nil {
 			lib.File.Output("Failed to check out branch :(")
+		} else {
+			lib.File.Branch = branch
 		}
 	}
 
@@ -367,18 +951,25 @@ func (mu *MU) pull(lib Library) {
 		lib.File.Output("Updated successfully!")
 
 		lib.File.Updated = true
-		mu.Stats.UpdateCount++
-		mu.Stats.UpdatedOutput += strconv.Itoa(mu.Stats.UpdateCount) + ") " + lib.File.Path
-
-		mu.Stats.UpdatedOutput += "\n"
+		mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.OriginalPath()+"\n")
 	} else {
 		lib.File.Output("Failed to update :(")
 	}
 }
 
 func (mu *MU) updateOrCreateBranch(lib Library) (switched, created bool, err error) {
+	branch := mu.branch(lib)
+	if lib.File.IsShallow() {
+		// Cloned for a read-only action, tags and commits since them are needed to change it
+		lib.File.Output("Fetching complete history of shallow clone...")
+		if err = lib.File.Unshallow(); err != nil {
+			lib.File.Error("Failed to fetch complete history :(")
+			return
+		}
+	}
+
 	lib.File.Output("Updating refs...")
-	if len(lib.File.Version) == 0 && !mu.Options.Tag {
+	if len(lib.File.Version) == 0 && !mu.libraryOptions(lib).Tag {
 		// TODO: Improve the performance of this check by explicitly looking at commit tag?
 		oldTag := lib.GetLatestTag()
 		if len(oldTag) > 0 {
@@ -398,23 +989,25 @@ func (mu *MU) updateOrCreateBranch(lib Library) (switched, created bool, err err
 		lib.File.Fetch()
 	}
 
-	if len(mu.Options.Branch) > 0 {
-		switched, created, err = lib.File.CheckoutOrCreateBranch(mu.Options.Branch)
+	if len(branch) > 0 {
+		switched, created, err = lib.File.CheckoutOrCreateBranch(branch)
 		if err != nil {
-			lib.File.Error("Failed to checkout " + mu.Options.Branch + " :(")
+			lib.File.Error("Failed to checkout " + branch + " :(")
 			return
-		} else if !switched {
-			lib.File.Output("Already on " + mu.Options.Branch)
+		}
+
+		lib.File.Branch = branch
+		if !switched {
+			lib.File.Output("Already on " + branch)
 		} else if !created {
-			lib.File.Output("Switched to " + mu.Options.Branch)
+			lib.File.Output("Switched to " + branch)
 		} else {
-			lib.File.Output("Created branch " + mu.Options.Branch + "!")
-			lib.File.RunCmd("git", "push", "-u", "origin", mu.Options.Branch)
+			lib.File.Output("Created branch " + branch + "!")
+			lib.File.RunCmd("git", "push", "-u", com.PushRemote(), branch)
 
 			if mu.Options.Action == "pull" {
 				// This won't be deleted
-				mu.Stats.CreatedCount++
-				mu.Stats.CreatedOutput += strconv.Itoa(mu.Stats.CreatedCount) + ") " + lib.File.Path + "#" + mu.Options.Branch + "\n"
+				mu.addStat(&mu.Stats.CreatedCount, &mu.Stats.CreatedOutput, lib.File.OriginalPath()+"#"+branch+"\n")
 			}
 		}
 	}
@@ -422,7 +1015,7 @@ func (mu *MU) updateOrCreateBranch(lib Library) (switched, created bool, err err
 	lib.File.Output("Pulling latest changes...")
 
 	if err = lib.File.Pull(); err != nil {
-		lib.File.Output("Failed to pull " + mu.Options.Branch + " :(")
+		lib.File.Output("Failed to pull " + branch + " :(")
 	}
 
 	return
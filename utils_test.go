@@ -0,0 +1,344 @@
+package gomu
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// recordedLibrary returns a lib with mod files whose commands are recorded instead of run
+func recordedLibrary(t *testing.T) (lib *Library, recorder *com.Recorder) {
+	dir, err := ioutil.TempDir("", "gomu-lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range map[string]string{
+		"go.mod": "module github.com/org/a\n\ngo 1.14\n",
+		"go.sum": "",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder = &com.Recorder{}
+	lib = LibraryFromPath(dir)
+	lib.File.SetRunner(recorder)
+	return
+}
+
+// commandLines returns the command lines recorded, excluding git rev-parse reads
+func commandLines(recorder *com.Recorder) (lines []string) {
+	for _, cmd := range recorder.Commands() {
+		if line := cmd.String(); !strings.HasPrefix(line, "git rev-parse ") {
+			lines = append(lines, line)
+		}
+	}
+
+	return
+}
+
+func TestSync(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{}
+	result, err := mu.sync(*lib, "Sync deps", "Updated deps")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"git checkout go.mod",
+		"go mod init",
+		"go mod tidy",
+		"git add go.*",
+		"git commit -m Sync deps\nUpdated deps",
+		"git push -u origin",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if !result.Updated || !lib.File.Updated || mu.Stats.UpdateCount != 1 {
+		t.Errorf("expected lib to be updated, got %+v and %d updates", result, mu.Stats.UpdateCount)
+	}
+}
+
+func TestSyncUpToDate(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	// Nothing to commit
+	recorder.Reply("git commit", com.Reply{ExitCode: 1})
+
+	mu := &MU{}
+	if result, _ := mu.sync(*lib, "Sync deps", "Updated deps"); result.Updated || mu.Stats.UpdateCount != 0 {
+		t.Errorf("expected lib not to be updated, got %+v and %d updates", result, mu.Stats.UpdateCount)
+	}
+
+	// The branch is still pushed, it may be behind
+	if !recorder.Ran("git push -u origin") {
+		t.Errorf("expected branch to be pushed, ran %q", commandLines(recorder))
+	}
+}
+
+func TestSyncPushFails(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git push", com.Reply{ExitCode: 1})
+
+	mu := &MU{}
+	result, err := mu.sync(*lib, "Sync deps", "Updated deps")
+	if err == nil {
+		t.Fatal("expected error when changes can't be pushed")
+	}
+
+	if result.Updated || lib.File.Updated || mu.Stats.UpdateCount != 0 {
+		t.Errorf("expected lib not to be updated, got %+v and %d updates", result, mu.Stats.UpdateCount)
+	}
+}
+
+func TestSyncTidyFails(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("go mod tidy", com.Reply{ExitCode: 1})
+
+	mu := &MU{}
+	if _, err := mu.sync(*lib, "Sync deps", "Updated deps"); err == nil {
+		t.Fatal("expected error when mod files can't be tidied")
+	}
+
+	if recorder.Ran("git commit") || recorder.Ran("git push") {
+		t.Errorf("expected nothing to be committed, ran %q", commandLines(recorder))
+	}
+}
+
+func TestPull(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{}
+	mu.pull(*lib)
+
+	expected := []string{"git pull"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if !lib.File.Updated || mu.Stats.UpdateCount != 1 {
+		t.Errorf("expected lib to be updated, got %d updates", mu.Stats.UpdateCount)
+	}
+}
+
+func TestPullBranch(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git pull", com.Reply{ExitCode: 1})
+
+	mu := &MU{Options: Options{Branch: "feature"}}
+	mu.pull(*lib)
+
+	expected := []string{"git checkout feature", "git pull"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if lib.File.Updated || mu.Stats.UpdateCount != 0 {
+		t.Errorf("expected lib not to be updated, got %d updates", mu.Stats.UpdateCount)
+	}
+}
+
+func TestReset(t *testing.T) {
+	for branch, checkouts := range map[string][]string{
+		// Mod files are restored from the index without a branch
+		"":        {"git checkout -- go.mod", "git checkout -- go.sum"},
+		"feature": {"git checkout feature -- go.mod", "git checkout feature -- go.sum"},
+	} {
+		lib, recorder := recordedLibrary(t)
+		// No local changes to commit
+		recorder.Reply("git commit", com.Reply{ExitCode: 1})
+
+		mu := &MU{Options: Options{Branch: branch}}
+		mu.reset(*lib)
+
+		// Nothing was stashed, so the user's own stash isn't popped. Local changes are checked before and after
+		expected := []string{"git stash list -1 --format=%s", "git add .", "git commit -m revert me"}
+		expected = append(expected, checkouts...)
+		expected = append(expected, "git add .", "git commit -m revert me")
+		if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+			t.Errorf("%q: expected %q, got %q", branch, expected, lines)
+		}
+	}
+}
+
+func TestVendor(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+
+	mu := &MU{}
+	mu.vendor(*lib)
+
+	expected := []string{"go mod vendor", "git status --porcelain -- vendor"}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if lib.File.Updated || mu.Stats.UpdateCount != 0 {
+		t.Errorf("expected up to date vendor directory, got %d updates", mu.Stats.UpdateCount)
+	}
+}
+
+func TestVendorCommit(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git status --porcelain -- vendor", com.Reply{Stdout: " M vendor/modules.txt\n"})
+
+	mu := &MU{Options: Options{Commit: true}}
+	mu.vendor(*lib)
+
+	expected := []string{
+		"go mod vendor",
+		"git status --porcelain -- vendor",
+		"git add vendor",
+		"git commit -m gomu: Update vendored deps",
+		"git push -u origin",
+	}
+	if lines := commandLines(recorder); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	if !lib.File.Committed || mu.Stats.CommitCount != 1 {
+		t.Errorf("expected vendor directory to be committed, got %d commits", mu.Stats.CommitCount)
+	}
+}
+
+func TestVerifyVendor(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git status --porcelain -- vendor", com.Reply{Stdout: " M vendor/modules.txt\n"})
+
+	mu := &MU{Options: Options{Commit: true, VerifyVendor: true}}
+	mu.vendor(*lib)
+
+	if recorder.Ran("git add") || recorder.Ran("git commit") || recorder.Ran("git push") {
+		t.Errorf("expected nothing to be committed, ran %q", commandLines(recorder))
+	}
+	if len(lib.File.Errors) == 0 {
+		t.Error("expected out of date vendor directory to fail")
+	}
+}
+
+func TestShowWarningSilenced(t *testing.T) {
+	previous := com.GetLogLevel()
+	t.Cleanup(func() { com.SetLogLevel(previous) })
+
+	// Silent and name-only output confirm without asking, as they always have
+	for _, level := range []com.LogLevel{com.SILENT, com.NAMEONLY} {
+		com.SetLogLevel(level)
+		if !ShowWarning("Is this ok?") {
+			t.Errorf("%s: expected warning to be confirmed", level)
+		}
+	}
+
+	// Json output can't be answered, so it's declined
+	mu := &MU{Options: Options{Output: "json"}}
+	if mu.showWarning("Is this ok?") {
+		t.Error("expected warning to be declined for json output")
+	}
+	if err := mu.declinedError(); err != ErrUnconfirmed {
+		t.Errorf("expected %v, got %v", ErrUnconfirmed, err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	// Dry runs and ignored warnings don't ask
+	for _, options := range []Options{{Output: "json", DryRun: true}, {Output: "json", IgnoreWarning: true}} {
+		mu := &MU{Options: options}
+		if !mu.confirm("Tidy", []string{"- run go mod tidy"}) || len(mu.Errors) > 0 {
+			t.Errorf("%+v: expected confirmed, got %v", options, mu.Errors)
+		}
+	}
+
+	mu := &MU{Options: Options{Output: "json"}}
+	if mu.confirm("Tidy", []string{"- run go mod tidy"}) || len(mu.Errors) != 1 || mu.Errors[0] != ErrUnconfirmed {
+		t.Errorf("expected declined, got %v", mu.Errors)
+	}
+}
+
+func TestActionWarning(t *testing.T) {
+	options := Options{Branch: "feature", Align: true, DryRun: true}
+	expected := "Sync action would (dry run):\n  - checkout (or create) branch feature\n  - update mod files\n" +
+		"  - align 2 third-party module(s) required at different versions to the highest"
+	if warning := options.actionWarning("Sync", options.syncActions("2 third-party module(s)")...); !strings.HasPrefix(warning, expected) {
+		t.Errorf("expected %q, got %q", expected, warning)
+	}
+
+	// Options are formatted with the same warning
+	if format := options.Format(); !strings.HasPrefix(format, "Sync action would (dry run):\n  - checkout (or create) branch feature") {
+		t.Errorf("expected sync warning, got %q", format)
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	com.SetDryRun(true)
+	t.Cleanup(func() { com.SetDryRun(false) })
+
+	mu := &MU{Options: Options{DryRun: true}}
+	if _, err := mu.sync(*lib, "Sync deps", "Updated deps"); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := commandLines(recorder); len(lines) > 0 {
+		t.Errorf("expected commands to be printed instead of run, ran %q", lines)
+	}
+}
+
+// expiredContext returns a context whose deadline has passed
+func expiredContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestLibraryTimeout(t *testing.T) {
+	for name, mu := range map[string]*MU{
+		"library timeout": {Options: Options{LibraryTimeout: time.Nanosecond}, ctx: context.Background()},
+		"run deadline":    {Options: Options{LibraryTimeout: time.Hour}, ctx: expiredContext(t)},
+	} {
+		lib, recorder := recordedLibrary(t)
+		mu.startLibrary(*lib)
+		time.Sleep(time.Millisecond)
+
+		if _, err := mu.sync(*lib, "Sync deps", "Updated deps"); err == nil || !lib.File.TimedOut {
+			t.Errorf("expected sync to time out with a %s, got %v", name, err)
+		}
+		if recorder.Ran("git push") {
+			t.Errorf("expected timed out sync not to push with a %s, ran %q", name, commandLines(recorder))
+		}
+		mu.cancelLibraries()
+	}
+}
+
+func TestUnshallowBeforeBranching(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("git rev-parse --is-shallow-repository", com.Reply{Stdout: "true\n"})
+
+	mu := &MU{Options: Options{Branch: "deps"}}
+	if _, _, err := mu.updateOrCreateBranch(*lib); err != nil {
+		t.Fatal(err)
+	}
+	if lines := commandLines(recorder); len(lines) == 0 || lines[0] != "git fetch --unshallow --tags" {
+		t.Errorf("expected shallow clone to be unshallowed first, got %v", lines)
+	}
+
+	recorder = &com.Recorder{}
+	recorder.Reply("git rev-parse --is-shallow-repository", com.Reply{Stdout: "false\n"})
+	lib.File.SetRunner(recorder)
+	if _, _, err := mu.updateOrCreateBranch(*lib); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Ran("git fetch --unshallow") {
+		t.Errorf("expected complete clone not to be unshallowed, got %v", commandLines(recorder))
+	}
+}
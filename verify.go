@@ -0,0 +1,58 @@
+package gomu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+	"github.com/remeh/sizedwaitgroup"
+)
+
+// ModVerify checks lib's go.sum against the module cache. Downloading fails if a module doesn't match its go.sum
+// checksum, and go mod verify fails if cached modules were modified since they were downloaded
+func (lib *Library) ModVerify() (err error) {
+	if err = lib.File.RunCmd(lib.File.GoBinary(), "mod", "download", "-x"); err != nil {
+		return fmt.Errorf("go mod download failed: %v", err)
+	}
+
+	output, exitCode, err := lib.File.CmdCombinedOutput(lib.File.GoBinary(), "mod", "verify")
+	if err == nil && exitCode != 0 {
+		err = fmt.Errorf("go mod verify failed: %s", strings.TrimSpace(output))
+	}
+
+	return
+}
+
+// verifySums verifies checksums of libs before local changes are stashed, so a corrupted module cache fails the run
+// before any lib is changed
+func (mu *MU) verifySums(libs sort.StringArray) (ok bool) {
+	com.Println("\nVerifying checksums of", len(libs), "lib(s)...")
+
+	failed := 0
+	waiter := sizedwaitgroup.New(com.MaxConcurrency())
+	for _, path := range libs {
+		waiter.Add()
+		go func(path string) {
+			defer waiter.Done()
+
+			lib := Library{File: &com.FileWrapper{Path: path}}
+			lib.File.SetContext(mu.ctx)
+			if err := lib.ModVerify(); err != nil {
+				mu.libraryError(lib, err)
+
+				mu.statsMux.Lock()
+				failed++
+				mu.statsMux.Unlock()
+			}
+		}(path)
+	}
+	waiter.Wait()
+
+	if failed > 0 {
+		com.Println("\nChecksums inconsistent in", failed, "lib(s), check the module cache (go clean -modcache) before syncing")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,63 @@
+package gomu
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestModVerify(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	if err := lib.ModVerify(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := commandLines(recorder); len(lines) != 2 || lines[0] != "go mod download -x" || lines[1] != "go mod verify" {
+		t.Errorf("expected modules to be downloaded then verified, got %v", lines)
+	}
+
+	recorder.Reply("go mod verify", com.Reply{Stdout: "github.com/org/b v1.0.0: dir has been modified\n", ExitCode: 1})
+	if err := lib.ModVerify(); err == nil || err.Error() != "go mod verify failed: github.com/org/b v1.0.0: dir has been modified" {
+		t.Errorf("expected verify to fail with its output, got %v", err)
+	}
+
+	recorder.Reply("go mod download", com.Reply{ExitCode: 1})
+	if err := lib.ModVerify(); err == nil {
+		t.Error("expected a checksum mismatch downloading to fail")
+	}
+}
+
+func TestVerifySums(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	com.SetRunner(recorder)
+	com.SetConsoleOutput(ioutil.Discard)
+	defer func() {
+		com.SetRunner(nil)
+		com.SetConsoleOutput(nil)
+	}()
+
+	mu := &MU{ctx: context.Background()}
+	if !mu.verifySums(sort.StringArray{lib.File.Path}) {
+		t.Fatalf("expected checksums to be consistent, got %v", mu.Errors)
+	}
+
+	recorder.Reply("go mod verify", com.Reply{ExitCode: 1})
+	if mu.verifySums(sort.StringArray{lib.File.Path}) || len(mu.Errors) != 1 {
+		t.Errorf("expected inconsistent checksums to stop the run, got %v", mu.Errors)
+	}
+}
+
+func TestModUpdateVerifiesSums(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	recorder.Reply("go mod verify", com.Reply{ExitCode: 1})
+	lib.verifySums = true
+
+	if err := lib.ModUpdate("deps", "Update deps", ReplacePolicy{}); err == nil {
+		t.Error("expected update to fail with inconsistent checksums")
+	}
+	if recorder.Ran("git commit") || recorder.Ran("git push") {
+		t.Errorf("expected nothing to be committed, got %v", commandLines(recorder))
+	}
+}
@@ -0,0 +1,157 @@
+package gomu
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	gosort "sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// DefaultWatchInterval is how often watched libs are checked for changes
+const DefaultWatchInterval = time.Second
+
+// watchedFile returns true if changes to the file named name re-run the watch action
+func watchedFile(name string) bool {
+	return name == "go.mod" || name == "go.sum" || strings.HasSuffix(name, ".go")
+}
+
+// snapshot returns the modification time and size of each watched file in dir.
+// Hidden directories, vendor and nested modules are skipped
+func snapshot(dir string) (files map[string]string) {
+	files = make(map[string]string)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Removed while walking
+			return nil
+		}
+
+		if info.IsDir() {
+			if path == dir {
+				return nil
+			}
+
+			name := info.Name()
+			if strings.HasPrefix(name, ".") || name == "vendor" {
+				return filepath.SkipDir
+			}
+
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				// Watched as its own lib
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if watchedFile(info.Name()) {
+			files[path] = strconv.FormatInt(info.ModTime().UnixNano(), 10) + ":" + strconv.FormatInt(info.Size(), 10)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// changedSince returns true if files differ from previous
+func changedSince(previous, files map[string]string) bool {
+	if len(previous) != len(files) {
+		return true
+	}
+
+	for path, stamp := range files {
+		if previous[path] != stamp {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watch polls libs for mod file and source changes, re-running mu.Options.WatchAction on changed libs and their
+// dependents until closed
+func (mu *MU) watch(fileHead *sort.FileNode) {
+	switch mu.Options.WatchAction {
+	case "":
+		mu.configError(fmt.Errorf("no action provided to watch"))
+		return
+	case "watch":
+		mu.configError(fmt.Errorf("watch can't re-run itself"))
+		return
+	}
+
+	interval := mu.Options.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	snapshots := make(map[*sort.FileNode]map[string]string)
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		snapshots[itr] = snapshot(itr.File.Path)
+	}
+
+	com.Println("\nWatching", mu.Stats.DepCount, "lib(s) for changes, running", mu.Options.WatchAction, "on change...")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-mu.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var changed sort.StringArray
+		for itr := fileHead; itr != nil; itr = itr.Next {
+			if changedSince(snapshots[itr], snapshot(itr.File.Path)) {
+				changed = append(changed, itr.File.GetGoURL())
+			}
+		}
+
+		if len(changed) == 0 {
+			continue
+		}
+
+		gosort.Strings(changed)
+		mu.rerun(changed)
+
+		// Changes made by the action don't trigger another run
+		for itr := fileHead; itr != nil; itr = itr.Next {
+			snapshots[itr] = snapshot(itr.File.Path)
+		}
+	}
+}
+
+// rerun runs the watch action on changed libs and their dependents
+func (mu *MU) rerun(changed sort.StringArray) {
+	com.Println("\nChanged:", strings.Join(changed, ", "))
+
+	options := mu.Options
+	options.Action = mu.Options.WatchAction
+	options.FilterDependencies = changed
+	options.IgnoreWarning = true
+
+	// Only notify once watching stops
+	options.SlackWebhook = ""
+	options.NotifyURL = ""
+
+	run := New(options)
+	run.RunContext(mu.ctx)
+	com.Println(run.Stats.Format())
+
+	line := options.Action + " on " + strings.Join(changed, ", ")
+	if len(run.Errors) > 0 {
+		line += " (failed)"
+		for _, err := range run.Errors {
+			com.Println("Error:", err)
+		}
+	}
+
+	mu.addStat(&mu.Stats.WatchCount, &mu.Stats.WatchOutput, line+"\n")
+}
@@ -0,0 +1,69 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	gosort "sort"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"go.mod", "go.sum", "main.go", "README.md", "pkg/util.go", ".git/hook.go", "vendor/dep.go", "nested/go.mod", "nested/nested.go"} {
+		file := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(file, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := snapshot(dir)
+	var names []string
+	for path := range files {
+		name, _ := filepath.Rel(dir, path)
+		names = append(names, filepath.ToSlash(name))
+	}
+	gosort.Strings(names)
+
+	expected := []string{"go.mod", "go.sum", "main.go", "pkg/util.go"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v to be watched, got %v", expected, names)
+	}
+
+	if changedSince(files, snapshot(dir)) {
+		t.Error("expected no change without writes")
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !changedSince(files, snapshot(dir)) {
+		t.Error("expected a change after main.go was written")
+	}
+	if err = os.Remove(filepath.Join(dir, "pkg", "util.go")); err != nil {
+		t.Fatal(err)
+	}
+	if !changedSince(files, snapshot(dir)) {
+		t.Error("expected a change after pkg/util.go was removed")
+	}
+}
+
+func TestWatchInvalidAction(t *testing.T) {
+	for _, action := range []string{"", "watch"} {
+		mu := &MU{Options: Options{Action: "watch", WatchAction: action}}
+		mu.watch(nil)
+		if len(mu.Errors) != 1 {
+			t.Errorf("expected a config error watching %q, got %v", action, mu.Errors)
+		} else if _, ok := mu.Errors[0].(*ConfigError); !ok {
+			t.Errorf("expected a config error watching %q, got %T", action, mu.Errors[0])
+		}
+	}
+}
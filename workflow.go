@@ -0,0 +1,200 @@
+package gomu
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	gosort "sort"
+	"strings"
+)
+
+// WorkflowDir is the directory of each lib workflow templates are synced to
+const WorkflowDir = ".github/workflows"
+
+// States of workflows which differ from their templates
+const (
+	WorkflowMissing  = "missing"
+	WorkflowOutdated = "outdated"
+)
+
+// WorkflowDrift represents a lib workflow missing or differing from its canonical template
+type WorkflowDrift struct {
+	Library  string `json:"library"`
+	Workflow string `json:"workflow"`
+	State    string `json:"state"`
+}
+
+// workflowTemplates returns absolute paths of the workflow templates at source, the file itself or each yml file in
+// the directory
+func workflowTemplates(source string) (templates []string, err error) {
+	if len(source) == 0 {
+		return nil, fmt.Errorf("no workflow template or directory provided")
+	}
+
+	if source, err = filepath.Abs(source); err != nil {
+		return
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read workflow templates: %v", err)
+	}
+
+	if !info.IsDir() {
+		return []string{source}, nil
+	}
+
+	files, err := ioutil.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read workflow templates: %v", err)
+	}
+
+	for _, file := range files {
+		if ext := filepath.Ext(file.Name()); !file.IsDir() && (ext == ".yml" || ext == ".yaml") {
+			templates = append(templates, filepath.Join(source, file.Name()))
+		}
+	}
+
+	if len(templates) == 0 {
+		err = fmt.Errorf("no workflow templates found in %s", source)
+	}
+
+	gosort.Strings(templates)
+	return
+}
+
+// WorkflowDrift returns lib workflows which are missing or differ from templates.
+// The auto-tag workflow is only expected in tagged libs
+func (lib *Library) WorkflowDrift(templates []string) (drift []WorkflowDrift, err error) {
+	for _, template := range templates {
+		name := filepath.Base(template)
+		if name == "auto-tag.yml" && len(lib.GetLatestTag()) == 0 {
+			continue
+		}
+
+		var canonical, current []byte
+		if canonical, err = ioutil.ReadFile(template); err != nil {
+			return
+		}
+
+		state := WorkflowOutdated
+		current, err = ioutil.ReadFile(filepath.Join(lib.File.Path, filepath.FromSlash(WorkflowDir), name))
+		if os.IsNotExist(err) {
+			state = WorkflowMissing
+		} else if err != nil {
+			return
+		} else if bytes.Equal(current, canonical) {
+			continue
+		}
+		err = nil
+
+		drift = append(drift, WorkflowDrift{Library: lib.File.GetGoURL(), Workflow: name, State: state})
+	}
+
+	return
+}
+
+// describeDrift lists drifted workflows, e.g. add ci.yml, update lint.yml
+func describeDrift(drift []WorkflowDrift) string {
+	changes := make([]string, len(drift))
+	for i, workflow := range drift {
+		if workflow.State == WorkflowMissing {
+			changes[i] = "add " + workflow.Workflow
+		} else {
+			changes[i] = "update " + workflow.Workflow
+		}
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// workflow syncs lib's workflows with the templates at the source path, committing and pushing changes and opening
+// a pull request if enabled. Drift is only reported if mu.Options.ListOnly is set
+func (mu *MU) workflow(lib Library) {
+	lib.File.Output("Checking workflows...")
+
+	drift, err := lib.WorkflowDrift(mu.workflowTemplates)
+	if err != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to check workflows: %v", err))
+		return
+	}
+
+	if len(drift) == 0 {
+		lib.File.Output("Workflows up to date!")
+		return
+	}
+
+	change := describeDrift(drift)
+	mu.statsMux.Lock()
+	mu.Stats.Drift = append(mu.Stats.Drift, drift...)
+	mu.statsMux.Unlock()
+
+	if mu.Options.ListOnly {
+		lib.File.Output("Workflows out of date: " + change)
+		mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+change+")\n")
+		return
+	}
+
+	if mu.checkoutBranch(lib) != nil {
+		// Don't make changes which can't be pushed
+		return
+	}
+
+	lib.File.MakeDir(WorkflowDir)
+	for _, template := range mu.workflowTemplates {
+		name := filepath.Base(template)
+		for _, workflow := range drift {
+			if workflow.Workflow != name {
+				continue
+			}
+
+			lib.File.Output("Copying " + template + " to " + WorkflowDir + "...")
+			if err = lib.File.CopyFile(template, path.Join(WorkflowDir, name)); err != nil {
+				mu.libraryError(lib, fmt.Errorf("unable to copy %s to %s: %v", template, WorkflowDir, err))
+				return
+			}
+		}
+	}
+
+	lib.File.Updated = true
+	mu.addStat(&mu.Stats.UpdateCount, &mu.Stats.UpdatedOutput, lib.File.GetGoURL()+" ("+change+")\n")
+
+	commitTitle := mu.Options.CommitMessage
+	if len(commitTitle) == 0 {
+		commitTitle = "Sync workflows"
+	}
+	commitTitle = "gomu: " + commitTitle
+
+	if err = mu.setCommitKey(&lib); err != nil {
+		mu.libraryError(lib, err)
+		return
+	}
+
+	head := lib.File.HeadCommit()
+	if lib.File.Add(WorkflowDir) != nil || lib.commit(commitTitle+"\n\n"+change) != nil {
+		mu.libraryError(lib, fmt.Errorf("unable to commit workflows"))
+		return
+	}
+	mu.recordCommit(lib, head)
+
+	if err = lib.File.Push(); err != nil {
+		mu.libraryError(lib, fmt.Errorf("push failed, check local changes and commit status: %v", err))
+		return
+	}
+
+	lib.File.Committed = true
+	mu.addStat(&mu.Stats.CommitCount, &mu.Stats.DeployedOutput, lib.File.GetGoURL()+"\n")
+	lib.File.Output("Workflows committed!")
+
+	if len(lib.File.ProtectedBranch) == 0 {
+		mu.pullRequest(lib, mu.branch(lib), commitTitle, change)
+	} else {
+		// Changes to protected branches are only reviewed through a pull request
+		mu.requestPR(lib, FallbackBranch, commitTitle, change)
+	}
+
+	mu.removeBranchIfUnused(lib)
+}
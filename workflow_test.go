@@ -0,0 +1,124 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/com"
+)
+
+// workflowSource returns a temporary directory of workflow templates
+func workflowSource(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gomu-workflows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range map[string]string{
+		"ci.yml":       "name: ci\n",
+		"lint.yaml":    "name: lint\n",
+		"auto-tag.yml": "name: auto-tag\n",
+		"README.md":    "Templates\n",
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestWorkflowTemplates(t *testing.T) {
+	source := workflowSource(t)
+	templates, err := workflowTemplates(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(source, "auto-tag.yml"), filepath.Join(source, "ci.yml"), filepath.Join(source, "lint.yaml")}
+	if !reflect.DeepEqual(templates, expected) {
+		t.Errorf("expected yml files of the directory, got %v", templates)
+	}
+
+	if templates, err = workflowTemplates(expected[1]); err != nil || !reflect.DeepEqual(templates, expected[1:2]) {
+		t.Errorf("expected a single template, got %v (%v)", templates, err)
+	}
+
+	for _, source := range []string{"", filepath.Join(source, "missing")} {
+		if _, err = workflowTemplates(source); err == nil {
+			t.Errorf("expected no templates at %q", source)
+		}
+	}
+}
+
+func TestWorkflowDrift(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	templates, err := workflowTemplates(workflowSource(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(lib.File.Path, filepath.FromSlash(WorkflowDir))
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"ci.yml": "name: ci\n", "lint.yaml": "name: old lint\n"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Untagged, so auto-tag isn't expected
+	drift, err := lib.WorkflowDrift(templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []WorkflowDrift{{Library: lib.File.GetGoURL(), Workflow: "lint.yaml", State: WorkflowOutdated}}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("expected %+v, got %+v", expected, drift)
+	}
+
+	recorder.Reply("git-tagger --action=get", com.Reply{Stdout: "v1.0.0\n"})
+	if drift, err = lib.WorkflowDrift(templates); err != nil {
+		t.Fatal(err)
+	}
+	expected = append([]WorkflowDrift{{Library: lib.File.GetGoURL(), Workflow: "auto-tag.yml", State: WorkflowMissing}}, expected...)
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("expected %+v once tagged, got %+v", expected, drift)
+	}
+	if description := describeDrift(drift); description != "add auto-tag.yml, update lint.yaml" {
+		t.Errorf("unexpected description %q", description)
+	}
+}
+
+func TestWorkflowSync(t *testing.T) {
+	lib, recorder := recordedLibrary(t)
+	com.SetConsoleOutput(ioutil.Discard)
+	defer com.SetConsoleOutput(nil)
+
+	templates, err := workflowTemplates(workflowSource(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu := &MU{Options: Options{ListOnly: true}, workflowTemplates: templates}
+	mu.workflow(*lib)
+	if len(mu.Stats.Drift) != 2 || mu.Stats.UpdateCount != 1 || recorder.Ran("git commit") {
+		t.Errorf("expected drift to be listed only, got %+v after %v", mu.Stats.Drift, commandLines(recorder))
+	}
+
+	mu = &MU{workflowTemplates: templates}
+	mu.workflow(*lib)
+	for _, name := range []string{"ci.yml", "lint.yaml"} {
+		if _, err = os.Stat(filepath.Join(lib.File.Path, filepath.FromSlash(WorkflowDir), name)); err != nil {
+			t.Errorf("expected %s to be copied: %v", name, err)
+		}
+	}
+	if !recorder.Ran("git add "+WorkflowDir) || !recorder.Ran("git commit -m gomu: Sync workflows") || !recorder.Ran("git push") {
+		t.Errorf("expected workflows to be committed and pushed, got %v", commandLines(recorder))
+	}
+}
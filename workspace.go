@@ -0,0 +1,58 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// WorkspaceName is the name of a go workspace file
+const WorkspaceName = "go.work"
+
+// ParseWorkspace returns the module directories listed by use directives in a go.work file.
+// Relative directories are joined with the directory of the workspace file
+func ParseWorkspace(workPath string) (libs sort.StringArray, err error) {
+	data, err := ioutil.ReadFile(workPath)
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(workPath)
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		// Strip comments
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case len(line) == 0:
+			continue
+		case inBlock:
+			if line == ")" {
+				inBlock = false
+				continue
+			}
+		case line == "use (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "use "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "use "))
+		default:
+			// Ignore go, toolchain and replace directives
+			continue
+		}
+
+		lib := strings.Trim(line, "\"`")
+		if !filepath.IsAbs(lib) {
+			lib = filepath.Join(dir, lib)
+		}
+
+		libs = append(libs, lib)
+	}
+
+	return
+}
@@ -0,0 +1,54 @@
+package gomu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+func TestParseWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gomu-workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	work := `go 1.18
+
+toolchain go1.21.0
+
+use ./a // the first lib
+use (
+	./b
+	"c/d"
+	// ./commented
+	/abs/e
+)
+
+replace github.com/org/f => ./f
+`
+	workPath := filepath.Join(dir, WorkspaceName)
+	if err = ioutil.WriteFile(workPath, []byte(work), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	libs, err := ParseWorkspace(workPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := sort.StringArray{filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c", "d"), "/abs/e"}
+	if !reflect.DeepEqual(libs, expected) {
+		t.Errorf("expected %q, got %q", expected, libs)
+	}
+}
+
+func TestParseWorkspaceMissing(t *testing.T) {
+	if _, err := ParseWorkspace(filepath.Join(os.TempDir(), "gomu-missing", WorkspaceName)); err == nil {
+		t.Error("expected error parsing a missing workspace")
+	}
+}
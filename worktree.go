@@ -0,0 +1,57 @@
+package gomu
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gomuserver/mod-utils/com"
+	"github.com/gomuserver/mod-utils/sort"
+)
+
+// createWorktrees moves each lib into a temporary worktree so the original working copies are left untouched.
+// Returns false if any worktree could not be created
+func (mu *MU) createWorktrees(fileHead *sort.FileNode) (ok bool) {
+	dir, err := ioutil.TempDir("", com.WorktreePrefix)
+	if err != nil {
+		mu.Errors = append(mu.Errors, fmt.Errorf("unable to create worktree directory: %v", err))
+		return
+	}
+	mu.worktreeDir = dir
+
+	com.Println("\nCreating worktrees in", dir, "...")
+
+	index := 0
+	for itr := fileHead; itr != nil; itr = itr.Next {
+		index++
+		worktree := filepath.Join(dir, strconv.Itoa(index)+"-"+path.Base(itr.File.GetGoURL()))
+		if err = itr.File.AddWorktree(worktree); err != nil {
+			itr.File.Error("Unable to create worktree :( " + err.Error())
+			mu.Errors = append(mu.Errors, err)
+			return
+		}
+	}
+
+	return true
+}
+
+// removeWorktrees deletes the temporary worktrees and restores each lib's original path
+func (mu *MU) removeWorktrees() {
+	if len(mu.worktreeDir) == 0 {
+		return
+	}
+
+	if mu.SortedLibraries != nil {
+		for itr := *mu.SortedLibraries; itr != nil; itr = itr.Next {
+			if err := itr.File.RemoveWorktree(); err != nil {
+				itr.File.Output("Unable to remove worktree :( " + err.Error())
+			}
+		}
+	}
+
+	os.RemoveAll(mu.worktreeDir)
+	mu.worktreeDir = ""
+}